@@ -0,0 +1,44 @@
+package events
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog/log"
+)
+
+type Broker interface {
+	EventPublisher
+	EventSubscriber
+}
+
+// Connect возвращает JetStream брокер, если задан natsURL, иначе MemoryBroker: события тогда
+// доставляются только подписчикам внутри процесса. closeConn закрывает соединение с NATS.
+func Connect(ctx context.Context, natsURL, stream, clientName string) (broker Broker, closeConn func(), err error) {
+	if natsURL == "" {
+		log.Warn().Msg("events: NATS URL is not set, domain events stay in-process")
+		return NewMemoryBroker(), func() {}, nil
+	}
+
+	nc, err := nats.Connect(natsURL,
+		nats.Name(clientName),
+		nats.MaxReconnects(-1), // Переподключаемся, пока сервис работает
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("events: failed to connect to NATS: %w", err)
+	}
+
+	js, err := NewJetStream(ctx, nc, stream)
+	if err != nil {
+		nc.Close()
+		return nil, nil, err
+	}
+
+	log.Info().Str("stream", stream).Msg("events: connected to NATS JetStream")
+	return js, func() {
+		if err := nc.Drain(); err != nil {
+			log.Warn().Err(err).Msg("events: failed to drain NATS connection")
+		}
+	}, nil
+}
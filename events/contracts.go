@@ -0,0 +1,55 @@
+package events
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Источники событий (атрибут source)
+const (
+	SourceUserService  = "/user-service"
+	SourceOrderService = "/order-service"
+)
+
+// Типы событий сервисов. Data каждого типа описана структурой ниже.
+const (
+	TypeUserCreated        = "user.created"         // UserData
+	TypeUserUpdated        = "user.updated"         // UserData
+	TypeUserStatusChanged  = "user.status_changed"  // UserStatusChangedData
+	TypeUserDeleted        = "user.deleted"         // UserDeletedData, публикуется при окончательном удалении
+	TypeOrderCreated       = "order.created"        // OrderCreatedData
+	TypeOrderStatusChanged = "order.status_changed" // OrderStatusChangedData
+)
+
+type UserData struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+}
+
+// UserStatusChangedData - деактивация, повторная активация, мягкое удаление или восстановление учётной записи.
+type UserStatusChangedData struct {
+	ID   uuid.UUID `json:"id"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+}
+
+type UserDeletedData struct {
+	ID uuid.UUID `json:"id"`
+}
+
+type OrderCreatedData struct {
+	OrderID     uuid.UUID `json:"order_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Status      string    `json:"status"`
+	TotalAmount float64   `json:"total_amount"`
+}
+
+type OrderStatusChangedData struct {
+	OrderID   uuid.UUID `json:"order_id"`
+	From      string    `json:"from,omitempty"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changed_at"`
+}
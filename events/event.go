@@ -0,0 +1,93 @@
+// Package events - общий для сервисов контракт доменных событий: конверт в стиле CloudEvents 1.0
+// и интерфейсы публикации и подписки, не зависящие от брокера.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// SpecVersion - версия спецификации CloudEvents, которой соответствует конверт.
+const SpecVersion = "1.0"
+
+var ErrInvalidEvent = errors.New("invalid event")
+
+// Event - конверт события. Поля и их JSON имена совпадают с атрибутами CloudEvents
+// (structured content mode), поэтому события можно передавать в любые CloudEvents-совместимые системы.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`            // Сервис-источник, например "/order-service"
+	Type            string          `json:"type"`              // Тип события через точку, например "order.created"
+	Subject         string          `json:"subject,omitempty"` // ID сущности, к которой относится событие
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// New создаёт событие с новым ID и data, сериализованной в JSON.
+func New(source, eventType, subject string, data any) (Event, error) {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return Event{}, fmt.Errorf("events: failed to generate event ID: %w", err)
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("events: failed to marshal %s data: %w", eventType, err)
+	}
+
+	return Event{
+		SpecVersion:     SpecVersion,
+		ID:              id.String(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            payload,
+	}, nil
+}
+
+// Validate проверяет обязательные атрибуты конверта.
+func (e Event) Validate() error {
+	switch {
+	case e.SpecVersion != SpecVersion:
+		return fmt.Errorf("%w: unsupported specversion %q", ErrInvalidEvent, e.SpecVersion)
+	case e.ID == "":
+		return fmt.Errorf("%w: id is required", ErrInvalidEvent)
+	case e.Source == "":
+		return fmt.Errorf("%w: source is required", ErrInvalidEvent)
+	case !validType(e.Type):
+		return fmt.Errorf("%w: type %q must be dot-separated tokens without wildcards", ErrInvalidEvent, e.Type)
+	}
+	return nil
+}
+
+// DecodeData разбирает data события в v.
+func (e Event) DecodeData(v any) error {
+	if err := json.Unmarshal(e.Data, v); err != nil {
+		return fmt.Errorf("events: failed to decode %s data: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Handler обрабатывает событие. Ошибка означает, что событие нужно доставить повторно.
+type Handler func(ctx context.Context, event Event) error
+
+type EventPublisher interface {
+	// Publish сохраняет событие в брокере. После успешного возврата событие не будет потеряно.
+	Publish(ctx context.Context, event Event) error
+}
+
+type EventSubscriber interface {
+	// Subscribe передаёт в handler события, тип которых подходит под pattern, пока не отменён ctx.
+	// В pattern "*" заменяет одну часть типа, ">" - все оставшиеся: "order.*", "user.>".
+	// Подписчики с одним consumer делят поток событий между собой (каждое событие получает один из них),
+	// подписчики с разными consumer получают каждый свою копию.
+	Subscribe(ctx context.Context, consumer, pattern string, handler Handler) error
+}
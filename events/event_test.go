@@ -0,0 +1,83 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+)
+
+func TestNew_BuildsCloudEventsEnvelope(t *testing.T) {
+	event, err := events.New("/user-service", "user.created", "42", map[string]string{"email": "a@example.com"})
+	require.NoError(t, err)
+	require.NoError(t, event.Validate())
+
+	raw, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var attributes map[string]any
+	require.NoError(t, json.Unmarshal(raw, &attributes))
+	assert.Equal(t, "1.0", attributes["specversion"])
+	assert.Equal(t, "/user-service", attributes["source"])
+	assert.Equal(t, "user.created", attributes["type"])
+	assert.Equal(t, "42", attributes["subject"])
+	assert.Equal(t, "application/json", attributes["datacontenttype"])
+	assert.NotEmpty(t, attributes["id"])
+	assert.NotEmpty(t, attributes["time"])
+
+	var data map[string]string
+	require.NoError(t, event.DecodeData(&data))
+	assert.Equal(t, "a@example.com", data["email"])
+}
+
+func TestEvent_Validate(t *testing.T) {
+	valid, err := events.New("/order-service", "order.created", "", nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		modify func(e *events.Event)
+	}{
+		{name: "specversion", modify: func(e *events.Event) { e.SpecVersion = "0.3" }},
+		{name: "id", modify: func(e *events.Event) { e.ID = "" }},
+		{name: "source", modify: func(e *events.Event) { e.Source = "" }},
+		{name: "empty type", modify: func(e *events.Event) { e.Type = "" }},
+		{name: "wildcard type", modify: func(e *events.Event) { e.Type = "order.*" }},
+		{name: "empty token", modify: func(e *events.Event) { e.Type = "order..created" }},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := valid
+			tc.modify(&event)
+			require.ErrorIs(t, event.Validate(), events.ErrInvalidEvent)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := []struct {
+		pattern   string
+		eventType string
+		expected  bool
+	}{
+		{"user.created", "user.created", true},
+		{"user.created", "user.deleted", false},
+		{"user.*", "user.deleted", true},
+		{"user.*", "user", false},
+		{"user.*", "user.profile.updated", false},
+		{"user.>", "user.profile.updated", true},
+		{"user.>", "user", false},
+		{">", "order.created", true},
+		{"*.created", "order.created", true},
+		{"*.created", "order.status_changed", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+"/"+tc.eventType, func(t *testing.T) {
+			assert.Equal(t, tc.expected, events.Match(tc.pattern, tc.eventType))
+		})
+	}
+}
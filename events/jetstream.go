@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/zerolog/log"
+)
+
+// subjectPrefix - общий префикс субъектов NATS: событие типа "user.created" публикуется в "events.user.created".
+const subjectPrefix = "events"
+
+const (
+	jetStreamAckWait    = 30 * time.Second
+	jetStreamMaxDeliver = 10
+	jetStreamRetryDelay = 5 * time.Second
+)
+
+// JetStream публикует события в NATS JetStream и доставляет их через durable consumers,
+// поэтому события переживают перезапуск подписчика и доставляются как минимум один раз.
+type JetStream struct {
+	js     jetstream.JetStream
+	stream string
+}
+
+// NewJetStream создаёт или обновляет поток stream для всех событий и возвращает брокер поверх него.
+// Повторная публикация события с тем же ID в течение окна дедупликации (2 минуты) игнорируется.
+func NewJetStream(ctx context.Context, nc *nats.Conn, stream string) (*JetStream, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("events: failed to create JetStream context: %w", err)
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:       stream,
+		Subjects:   []string{subjectPrefix + ".>"},
+		Storage:    jetstream.FileStorage,
+		Duplicates: 2 * time.Minute,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("events: failed to create stream %s: %w", stream, err)
+	}
+
+	return &JetStream{js: js, stream: stream}, nil
+}
+
+func (b *JetStream) Publish(ctx context.Context, event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("events: failed to marshal event %s: %w", event.ID, err)
+	}
+
+	if _, err := b.js.Publish(ctx, subjectPrefix+"."+event.Type, data, jetstream.WithMsgID(event.ID)); err != nil {
+		return fmt.Errorf("events: failed to publish %s event %s: %w", event.Type, event.ID, err)
+	}
+	return nil
+}
+
+// Subscribe создаёт durable consumer с именем consumer. Имя не может содержать '.', '*', '>' и пробелы.
+// Если обработчик вернул ошибку, событие доставляется повторно с паузой, всего до 10 раз.
+func (b *JetStream) Subscribe(ctx context.Context, consumer, pattern string, handler Handler) error {
+	if !validPattern(pattern) {
+		return fmt.Errorf("events: invalid subscription pattern %q", pattern)
+	}
+	if consumer == "" || strings.ContainsAny(consumer, ".*> \t\r\n") {
+		return fmt.Errorf("events: invalid consumer name %q", consumer)
+	}
+
+	cons, err := b.js.CreateOrUpdateConsumer(ctx, b.stream, jetstream.ConsumerConfig{
+		Durable:       consumer,
+		FilterSubject: subjectPrefix + "." + pattern,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       jetStreamAckWait,
+		MaxDeliver:    jetStreamMaxDeliver,
+	})
+	if err != nil {
+		return fmt.Errorf("events: failed to create consumer %s: %w", consumer, err)
+	}
+
+	consumeCtx, err := cons.Consume(func(msg jetstream.Msg) {
+		b.handle(ctx, consumer, msg, handler)
+	})
+	if err != nil {
+		return fmt.Errorf("events: failed to start consumer %s: %w", consumer, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		consumeCtx.Stop()
+	}()
+	return nil
+}
+
+func (b *JetStream) handle(ctx context.Context, consumer string, msg jetstream.Msg, handler Handler) {
+	var event Event
+	if err := json.Unmarshal(msg.Data(), &event); err != nil || event.Validate() != nil {
+		// Повторная доставка не поможет - отбрасываем сообщение
+		log.Error().Err(err).Str("consumer", consumer).Str("subject", msg.Subject()).Msg("events: dropping malformed message")
+		if termErr := msg.Term(); termErr != nil {
+			log.Warn().Err(termErr).Str("consumer", consumer).Msg("events: failed to terminate message")
+		}
+		return
+	}
+
+	if err := handler(ctx, event); err != nil {
+		log.Error().Err(err).Str("consumer", consumer).Str("event_id", event.ID).Str("event_type", event.Type).Msg("events: handler failed, event will be redelivered")
+		if nakErr := msg.NakWithDelay(jetStreamRetryDelay); nakErr != nil {
+			log.Warn().Err(nakErr).Str("consumer", consumer).Str("event_id", event.ID).Msg("events: failed to nak message")
+		}
+		return
+	}
+
+	if err := msg.Ack(); err != nil {
+		log.Warn().Err(err).Str("consumer", consumer).Str("event_id", event.ID).Msg("events: failed to ack message")
+	}
+}
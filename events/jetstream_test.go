@@ -0,0 +1,140 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+)
+
+// Тесты JetStream работают с локальным nats-server, запущенным с JetStream: nats-server -js
+func connectNATS(t *testing.T) *nats.Conn {
+	t.Helper()
+	url := os.Getenv("NATS_URL_TEST")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Timeout(time.Second))
+	if err != nil {
+		t.Skipf("nats-server is not available at %s: %v", url, err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// newTestJetStream создаёт брокер с отдельным потоком, который удаляется после теста.
+func newTestJetStream(t *testing.T, nc *nats.Conn) *events.JetStream {
+	t.Helper()
+	ctx := context.Background()
+	stream := "EVENTS_TEST"
+
+	broker, err := events.NewJetStream(ctx, nc, stream)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		js, err := nc.JetStream()
+		if err == nil {
+			_ = js.DeleteStream(stream)
+		}
+	})
+	return broker
+}
+
+func waitFor(t *testing.T, ch <-chan events.Event) events.Event {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+		return events.Event{}
+	}
+}
+
+func TestJetStream_PublishSubscribe(t *testing.T) {
+	nc := connectNATS(t)
+	broker := newTestJetStream(t, nc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan events.Event, 10)
+	require.NoError(t, broker.Subscribe(ctx, "test-users", "user.*", func(ctx context.Context, e events.Event) error {
+		received <- e
+		return nil
+	}))
+
+	published := mustEvent(t, "user.created")
+	require.NoError(t, broker.Publish(ctx, published))
+	require.NoError(t, broker.Publish(ctx, published)) // Дубликат по ID отбрасывается брокером
+	require.NoError(t, broker.Publish(ctx, mustEvent(t, "order.created")))
+
+	got := waitFor(t, received)
+	assert.Equal(t, published.ID, got.ID)
+	assert.Equal(t, published.Type, got.Type)
+	assert.JSONEq(t, string(published.Data), string(got.Data))
+
+	select {
+	case extra := <-received:
+		t.Fatalf("unexpected event %s %s", extra.Type, extra.ID)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestJetStream_RedeliversOnHandlerError(t *testing.T) {
+	nc := connectNATS(t)
+	broker := newTestJetStream(t, nc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var attempts atomic.Int32
+	received := make(chan events.Event, 1)
+	require.NoError(t, broker.Subscribe(ctx, "test-retry", "order.created", func(ctx context.Context, e events.Event) error {
+		if attempts.Add(1) == 1 {
+			return errors.New("temporary failure")
+		}
+		received <- e
+		return nil
+	}))
+
+	published := mustEvent(t, "order.created")
+	require.NoError(t, broker.Publish(ctx, published))
+
+	select {
+	case got := <-received:
+		assert.Equal(t, published.ID, got.ID)
+		assert.Equal(t, int32(2), attempts.Load())
+	case <-time.After(10 * time.Second):
+		t.Fatal("event was not redelivered")
+	}
+}
+
+func TestJetStream_DurableConsumerGetsEventsPublishedWhileStopped(t *testing.T) {
+	nc := connectNATS(t)
+	broker := newTestJetStream(t, nc)
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	require.NoError(t, broker.Subscribe(firstCtx, "test-durable", "user.deleted", func(ctx context.Context, e events.Event) error {
+		return nil
+	}))
+	stopFirst()
+	time.Sleep(100 * time.Millisecond)
+
+	published := mustEvent(t, "user.deleted")
+	require.NoError(t, broker.Publish(context.Background(), published))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan events.Event, 1)
+	require.NoError(t, broker.Subscribe(ctx, "test-durable", "user.deleted", func(ctx context.Context, e events.Event) error {
+		received <- e
+		return nil
+	}))
+
+	assert.Equal(t, published.ID, waitFor(t, received).ID)
+}
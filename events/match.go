@@ -0,0 +1,50 @@
+package events
+
+import "strings"
+
+// Match сообщает, подходит ли тип события под шаблон подписки. Правила совпадают с субъектами NATS.
+func Match(pattern, eventType string) bool {
+	patternTokens := strings.Split(pattern, ".")
+	typeTokens := strings.Split(eventType, ".")
+
+	for i, token := range patternTokens {
+		if token == ">" {
+			return i == len(patternTokens)-1 && len(typeTokens) > i
+		}
+		if i >= len(typeTokens) {
+			return false
+		}
+		if token != "*" && token != typeTokens[i] {
+			return false
+		}
+	}
+	return len(patternTokens) == len(typeTokens)
+}
+
+func validType(eventType string) bool {
+	if eventType == "" {
+		return false
+	}
+	for _, token := range strings.Split(eventType, ".") {
+		if token == "" || token == "*" || token == ">" || strings.ContainsAny(token, " \t\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+func validPattern(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		if token == "" || strings.ContainsAny(token, " \t\r\n") {
+			return false
+		}
+		if token == ">" && i != len(tokens)-1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// MemoryBroker - брокер внутри процесса для тестов и локального запуска без NATS.
+// Publish вызывает обработчики синхронно; ошибки обработчиков только логируются, повторной доставки нет.
+type MemoryBroker struct {
+	mu        sync.Mutex
+	groups    map[string]*memoryGroup // Ключ - consumer
+	published []Event
+}
+
+type memoryGroup struct {
+	subscribers []*memorySubscriber
+	next        int // Подписчик группы, который получит следующее событие
+}
+
+type memorySubscriber struct {
+	pattern string
+	handler Handler
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{groups: make(map[string]*memoryGroup)}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.published = append(b.published, event)
+	var handlers []Handler
+	for _, group := range b.groups {
+		if handler := group.pick(event.Type); handler != nil {
+			handlers = append(handlers, handler)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			log.Error().Err(err).Str("event_id", event.ID).Str("event_type", event.Type).Msg("events: in-memory handler failed")
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, consumer, pattern string, handler Handler) error {
+	if !validPattern(pattern) {
+		return fmt.Errorf("events: invalid subscription pattern %q", pattern)
+	}
+
+	sub := &memorySubscriber{pattern: pattern, handler: handler}
+	b.mu.Lock()
+	group, ok := b.groups[consumer]
+	if !ok {
+		group = &memoryGroup{}
+		b.groups[consumer] = group
+	}
+	group.subscribers = append(group.subscribers, sub)
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		group.subscribers = slices.DeleteFunc(group.subscribers, func(s *memorySubscriber) bool { return s == sub })
+	}()
+	return nil
+}
+
+// Published возвращает все опубликованные события в порядке публикации.
+func (b *MemoryBroker) Published() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.published)
+}
+
+// PublishedOfType возвращает опубликованные события типа eventType.
+func (b *MemoryBroker) PublishedOfType(eventType string) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var found []Event
+	for _, event := range b.published {
+		if event.Type == eventType {
+			found = append(found, event)
+		}
+	}
+	return found
+}
+
+// pick выбирает подписчика группы по кругу среди подходящих под тип события.
+func (g *memoryGroup) pick(eventType string) Handler {
+	for i := range g.subscribers {
+		idx := (g.next + i) % len(g.subscribers)
+		if Match(g.subscribers[idx].pattern, eventType) {
+			g.next = idx + 1
+			return g.subscribers[idx].handler
+		}
+	}
+	return nil
+}
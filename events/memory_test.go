@@ -0,0 +1,84 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+)
+
+func mustEvent(t *testing.T, eventType string) events.Event {
+	t.Helper()
+	event, err := events.New("/test", eventType, "", map[string]int{"n": 1})
+	require.NoError(t, err)
+	return event
+}
+
+func TestMemoryBroker_DeliversToMatchingConsumers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := events.NewMemoryBroker()
+
+	var audit, deletions []string
+	require.NoError(t, broker.Subscribe(ctx, "audit", "user.>", func(ctx context.Context, e events.Event) error {
+		audit = append(audit, e.Type)
+		return nil
+	}))
+	require.NoError(t, broker.Subscribe(ctx, "orders", "user.deleted", func(ctx context.Context, e events.Event) error {
+		deletions = append(deletions, e.Type)
+		return nil
+	}))
+
+	require.NoError(t, broker.Publish(ctx, mustEvent(t, "user.created")))
+	require.NoError(t, broker.Publish(ctx, mustEvent(t, "user.deleted")))
+	require.NoError(t, broker.Publish(ctx, mustEvent(t, "order.created")))
+
+	assert.Equal(t, []string{"user.created", "user.deleted"}, audit)
+	assert.Equal(t, []string{"user.deleted"}, deletions)
+	assert.Len(t, broker.Published(), 3)
+	assert.Len(t, broker.PublishedOfType("user.deleted"), 1)
+}
+
+func TestMemoryBroker_ConsumerGroupReceivesEachEventOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := events.NewMemoryBroker()
+
+	counts := make([]int, 2)
+	for i := range counts {
+		require.NoError(t, broker.Subscribe(ctx, "workers", "order.*", func(ctx context.Context, e events.Event) error {
+			counts[i]++
+			return nil
+		}))
+	}
+
+	for range 4 {
+		require.NoError(t, broker.Publish(ctx, mustEvent(t, "order.created")))
+	}
+	assert.Equal(t, []int{2, 2}, counts)
+}
+
+func TestMemoryBroker_HandlerErrorDoesNotFailPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	broker := events.NewMemoryBroker()
+
+	require.NoError(t, broker.Subscribe(ctx, "failing", ">", func(ctx context.Context, e events.Event) error {
+		return errors.New("boom")
+	}))
+	assert.NoError(t, broker.Publish(ctx, mustEvent(t, "order.created")))
+}
+
+func TestMemoryBroker_RejectsInvalidInput(t *testing.T) {
+	broker := events.NewMemoryBroker()
+
+	err := broker.Publish(context.Background(), events.Event{Type: "order.created"})
+	require.ErrorIs(t, err, events.ErrInvalidEvent)
+
+	err = broker.Subscribe(context.Background(), "c", "order.>.x", func(context.Context, events.Event) error { return nil })
+	require.Error(t, err)
+	assert.Empty(t, broker.Published())
+}
@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultRelayBatchSize - сколько событий Relay отправляет за один проход.
+const DefaultRelayBatchSize = 100
+
+// OutboxStore - таблица исходящих событий сервиса. Событие записывается в неё в одной транзакции
+// с изменением, которое оно описывает, поэтому не теряется, даже если брокер недоступен.
+type OutboxStore interface {
+	// PublishPending берёт до limit неотправленных событий в порядке записи, передаёт их в publish
+	// и помечает отправленными те, что publish принял. На первой ошибке publish останавливается и возвращает её.
+	// Реплики не получают одни и те же события одновременно. Возвращает число отправленных событий.
+	PublishPending(ctx context.Context, limit int, publish func(ctx context.Context, event Event) error) (int, error)
+}
+
+// Relay переносит события из OutboxStore в брокер. Событие, отправленное перед падением, но не помеченное,
+// уйдёт повторно с тем же ID, поэтому подписчики должны быть готовы к повторам.
+type Relay struct {
+	store        OutboxStore
+	publisher    EventPublisher
+	batchSize    int
+	pollInterval time.Duration
+}
+
+func NewRelay(store OutboxStore, publisher EventPublisher, batchSize int, pollInterval time.Duration) *Relay {
+	return &Relay{store: store, publisher: publisher, batchSize: batchSize, pollInterval: pollInterval}
+}
+
+// Run отправляет события каждые pollInterval и блокируется до отмены ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Полная пачка значит, что в таблице могут остаться неотправленные события
+		for {
+			published, err := r.RelayOnce(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Error().Err(err).Int("published", published).Msg("events: failed to relay outbox events")
+				}
+				break
+			}
+			if published < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// RelayOnce отправляет очередную пачку событий и возвращает их число.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	return r.store.PublishPending(ctx, r.batchSize, r.publisher.Publish)
+}
@@ -0,0 +1,100 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+)
+
+// sliceOutbox хранит события в срезе и помечает отправленными те, что принял publish.
+type sliceOutbox struct {
+	mu      sync.Mutex
+	pending []events.Event
+}
+
+func (o *sliceOutbox) PublishPending(ctx context.Context, limit int, publish func(context.Context, events.Event) error) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	published := 0
+	for _, event := range o.pending[:min(limit, len(o.pending))] {
+		if err := publish(ctx, event); err != nil {
+			o.pending = o.pending[published:]
+			return published, err
+		}
+		published++
+	}
+	o.pending = o.pending[published:]
+	return published, nil
+}
+
+func (o *sliceOutbox) remaining() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.pending)
+}
+
+// failingPublisher отклоняет каждое событие после первых ok.
+type failingPublisher struct {
+	ok        int
+	published []events.Event
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, event events.Event) error {
+	if len(p.published) >= p.ok {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, event)
+	return nil
+}
+
+func TestRelay_RelayOnce_KeepsUnpublishedEvents(t *testing.T) {
+	outbox := &sliceOutbox{pending: []events.Event{
+		mustEvent(t, "order.created"),
+		mustEvent(t, "order.status_changed"),
+		mustEvent(t, "order.status_changed"),
+	}}
+	publisher := &failingPublisher{ok: 1}
+	relay := events.NewRelay(outbox, publisher, 10, time.Second)
+
+	published, err := relay.RelayOnce(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 1, published)
+	assert.Equal(t, 2, outbox.remaining())
+
+	// Брокер снова доступен - оставшиеся события уходят в исходном порядке
+	publisher.ok = 3
+	published, err = relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+	require.Len(t, publisher.published, 3)
+	assert.Equal(t, "order.created", publisher.published[0].Type)
+}
+
+func TestRelay_Run_DrainsOutbox(t *testing.T) {
+	pending := make([]events.Event, 0, 5)
+	for range 5 {
+		pending = append(pending, mustEvent(t, "order.status_changed"))
+	}
+	outbox := &sliceOutbox{pending: pending}
+	broker := events.NewMemoryBroker()
+	relay := events.NewRelay(outbox, broker, 2, 10*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(stopped)
+	}()
+
+	require.Eventually(t, func() bool { return outbox.remaining() == 0 }, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	<-stopped
+	assert.Len(t, broker.Published(), 5)
+}
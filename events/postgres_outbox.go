@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+// TxBeginner начинает транзакцию. Подходит *pgxpool.Pool.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// PostgresOutbox читает таблицу исходящих событий с колонками
+// id BIGSERIAL, event_id UUID, event_type, subject, data JSONB, occurred_at и published_at.
+type PostgresOutbox struct {
+	db     TxBeginner
+	table  string
+	source string
+}
+
+// NewPostgresOutbox создаёт OutboxStore над таблицей table. source становится атрибутом source всех событий.
+func NewPostgresOutbox(db TxBeginner, table pgx.Identifier, source string) *PostgresOutbox {
+	return &PostgresOutbox{db: db, table: table.Sanitize(), source: source}
+}
+
+func (o *PostgresOutbox) PublishPending(ctx context.Context, limit int, publish func(ctx context.Context, event Event) error) (published int, err error) {
+	tx, err := o.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("events: failed to begin outbox transaction: %w", err)
+	}
+	var ids []int64
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback(ctx)
+			panic(p)
+		}
+		if len(ids) == 0 {
+			_ = tx.Rollback(ctx)
+			return
+		}
+		// Отправленные до ошибки события тоже помечаем, чтобы не слать их повторно
+		if _, markErr := tx.Exec(ctx, "UPDATE "+o.table+" SET published_at = NOW() WHERE id = ANY($1)", ids); markErr != nil {
+			_ = tx.Rollback(ctx)
+			published, err = 0, errors.Join(err, fmt.Errorf("events: failed to mark outbox events as published: %w", markErr))
+			return
+		}
+		if cErr := tx.Commit(ctx); cErr != nil {
+			published, err = 0, errors.Join(err, fmt.Errorf("events: failed to commit outbox transaction: %w", cErr))
+		}
+	}()
+
+	// Другая реплика пропускает взятые строки и берёт следующие
+	rows, err := tx.Query(ctx, `
+		SELECT id, event_id, event_type, subject, data, occurred_at
+		FROM `+o.table+`
+		WHERE published_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("events: failed to query outbox: %w", err)
+	}
+	type pendingEvent struct {
+		id    int64
+		event Event
+	}
+	var pending []pendingEvent
+	for rows.Next() {
+		entry := pendingEvent{event: Event{SpecVersion: SpecVersion, Source: o.source, DataContentType: "application/json"}}
+		if err := rows.Scan(&entry.id, &entry.event.ID, &entry.event.Type, &entry.event.Subject, &entry.event.Data, &entry.event.Time); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("events: failed to scan outbox event: %w", err)
+		}
+		entry.event.Time = entry.event.Time.UTC()
+		pending = append(pending, entry)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("events: failed iterating outbox: %w", err)
+	}
+
+	for _, entry := range pending {
+		if err := publish(ctx, entry.event); err != nil {
+			log.Warn().Err(err).Str("event_id", entry.event.ID).Str("event_type", entry.event.Type).Msg("events: failed to publish outbox event, will retry")
+			return len(ids), fmt.Errorf("events: failed to publish outbox event %s: %w", entry.event.ID, err)
+		}
+		ids = append(ids, entry.id)
+	}
+	return len(ids), nil
+}
@@ -2,16 +2,33 @@ package main
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
 	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/config"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/consumer"
 	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/db"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/export"
+	orderGrpc "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/grpc"
+	orderHttp "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/returns"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/scheduler"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipping"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/tracking"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/userclient"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/webhook"
 )
 
 func main() {
@@ -34,15 +51,108 @@ func main() {
 	}
 	defer dbConn.Close()
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	broker, closeBroker, err := events.Connect(context.Background(), cfg.Events.NATSURL, cfg.Events.Stream, "order-service")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to event broker")
+	}
+	defer closeBroker()
+
+	orderRepository := order.NewRepository(dbConn.Pool)
+	statusBroadcaster := order.NewBroadcaster(order.DefaultSubscriptionBuffer)
+	webhookRepository := webhook.NewRepository(dbConn.Pool)
+	// Без подтверждённого email покупатель не может оформить заказ; проверку можно отключить для локальной разработки
+	var customerVerifier order.CustomerVerifier
+	if cfg.UserService.RequireVerifiedEmail {
+		customerVerifier = userclient.New(cfg.UserService.URL, cfg.Internal.Token, cfg.UserService.Timeout)
+	} else {
+		log.Warn().Msg("REQUIRE_VERIFIED_EMAIL is false, orders are accepted from customers with unverified email")
+	}
+	orderSvc := order.NewService(orderRepository, customerVerifier, statusBroadcaster)
+	shipmentRepository := shipment.NewRepository(dbConn.Pool)
+	shipmentSvc := shipment.NewService(shipmentRepository, orderSvc)
+	returnsRepository := returns.NewRepository(dbConn.Pool)
+	returnsSvc := returns.NewService(returnsRepository, orderSvc, shipmentSvc, returns.NewLogRefundHook(), cfg.Returns.Window)
+
+	rateProviders := []shipping.ShippingRateProvider{
+		shipping.NewFlatRateProvider(cfg.Shipping.FlatRate, cfg.Shipping.FlatRateFreeThreshold),
+	}
+	if cfg.Shipping.RateTablePath != "" {
+		rateTable, err := shipping.LoadRateTable(cfg.Shipping.RateTablePath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load shipping rate table")
+		}
+		tableProvider, err := shipping.NewTableProvider(rateTable)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid shipping rate table")
+		}
+		rateProviders = append(rateProviders, tableProvider)
+	}
+	shippingSvc := shipping.NewService(orderSvc, rateProviders...)
+	trackingHub := tracking.NewHub()
+	trackingSvc := tracking.NewService(tracking.NewRepository(dbConn.Pool), trackingHub, orderSvc)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	if cfg.StaleOrders.Timeout > 0 {
+		staleCanceller := scheduler.NewStaleOrderCanceller(
+			orderSvc,
+			scheduler.NewAdvisoryLocker(dbConn.Pool),
+			cfg.StaleOrders.Timeout,
+			cfg.StaleOrders.CheckInterval,
+			cfg.StaleOrders.BatchSize,
+		)
+		go staleCanceller.Run(backgroundCtx)
+	}
+	go tracking.NewListener(dbConn.Pool, trackingHub).Run(backgroundCtx)
+	// Брокер в памяти не виден другим сервисам: без NATS события ждут в event_outbox, а не теряются
+	if cfg.Events.NATSURL != "" {
+		go events.NewRelay(
+			events.NewPostgresOutbox(dbConn.Pool, order.OutboxTable, events.SourceOrderService),
+			broker,
+			events.DefaultRelayBatchSize,
+			cfg.Events.RelayInterval,
+		).Run(backgroundCtx)
+	} else {
+		log.Warn().Msg("NATS_URL is not set, domain events are kept in event_outbox until a broker is configured")
+	}
+	go webhook.NewRelay(webhookRepository, webhook.DefaultRelayBatchSize, cfg.Webhooks.PollInterval).Run(backgroundCtx)
+	if err := consumer.NewUserEvents(orderSvc).Subscribe(backgroundCtx, broker); err != nil {
+		log.Fatal().Err(err).Msg("Failed to subscribe to user events")
+	}
+	go webhook.NewWorkerPool(webhookRepository, nil, webhook.WorkerConfig{
+		Workers:      cfg.Webhooks.Workers,
+		BatchSize:    cfg.Webhooks.Workers * 4,
+		PollInterval: cfg.Webhooks.PollInterval,
+		Timeout:      cfg.Webhooks.Timeout,
+		MaxAttempts:  cfg.Webhooks.MaxAttempts,
+		BackoffBase:  cfg.Webhooks.BackoffBase,
+		BackoffMax:   cfg.Webhooks.BackoffMax,
+	}).Run(backgroundCtx)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(middleware.RealIP)
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK")) // Игнорируем ошибку для простоты health check
 	})
+	// Метрики фоновых задач отдаются JSON'ом только по внутреннему токену
+	orderHttp.NewDebugHandler(cfg.Internal.Token).RegisterRoutes(router)
+	accessVerifier := authz.NewVerifier(cfg.AccessToken.Secret)
+	orderHttp.NewOrderHandler(orderSvc, accessVerifier, cfg.Internal.Token).RegisterRoutes(router)
+	orderHttp.NewShipmentHandler(shipmentSvc, accessVerifier, orderSvc).RegisterRoutes(router)
+	orderHttp.NewShippingHandler(shippingSvc, accessVerifier, orderSvc).RegisterRoutes(router)
+	orderHttp.NewReturnsHandler(returnsSvc, accessVerifier, orderSvc).RegisterRoutes(router)
+	orderHttp.NewTrackingHandler(trackingSvc, accessVerifier, cfg.Tracking.HeartbeatInterval).RegisterRoutes(router)
+	orderHttp.NewWebhookHandler(webhook.NewService(webhookRepository, nil), cfg.Internal.Token).RegisterRoutes(router)
+	orderHttp.NewExportHandler(export.NewService(export.NewRepository(dbConn.Pool), export.DefaultPageSize), cfg.Internal.Token).RegisterRoutes(router)
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.App.Port,
-		Handler:      mux,
+		Handler:      router,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -55,10 +165,25 @@ func main() {
 		}
 	}()
 
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		log.Fatal().Err(err).Str("port", cfg.GRPC.Port).Msg("Failed to listen for gRPC")
+	}
+	grpcServer := orderGrpc.NewServer(orderSvc, statusBroadcaster, cfg.GRPC.AuthToken)
+	go func() {
+		log.Info().Str("port", cfg.GRPC.Port).Msg("Starting gRPC server")
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal().Err(err).Msg("gRPC server failed")
+		}
+	}()
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 	<-sigChan
 	log.Info().Msg("Shutting down...")
+	// Открытые SSE потоки иначе не дадут Shutdown завершиться; клиенты переподключатся к другой реплике
+	trackingHub.DisconnectAll()
+	stopBackground()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -66,5 +191,16 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal().Err(err).Msg("Shutdown failed")
 	}
+	// GracefulStop ждёт завершения открытых потоков WatchOrder, поэтому ограничиваем его тем же таймаутом
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
 	log.Info().Msg("Server stopped")
 }
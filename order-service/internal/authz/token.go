@@ -0,0 +1,158 @@
+// Package authz проверяет токены доступа, которые выпускает user-service, и решает, к чьим заказам есть доступ.
+// Токен - JWT с подписью HS256 общим секретом сервисов (ACCESS_TOKEN_SECRET).
+package authz
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// Issuer и Audience - значения iss и aud токенов user-service для order-service
+	Issuer   = "user-service"
+	Audience = "order-service"
+
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+
+	// clockLeeway - допустимое расхождение часов сервисов
+	clockLeeway = 30 * time.Second
+)
+
+var ErrInvalidToken = errors.New("invalid access token")
+
+// Principal - владелец токена.
+type Principal struct {
+	UserID uuid.UUID
+	Roles  []string
+	// ActorID - сотрудник поддержки, который действует от имени UserID. uuid.Nil для обычного токена
+	ActorID uuid.UUID
+	// ImpersonationID - запись журнала user-service, по которой выпущен токен поддержки
+	ImpersonationID string
+}
+
+// IsImpersonated сообщает, что запрос от имени пользователя выполняет сотрудник поддержки.
+func (p *Principal) IsImpersonated() bool {
+	return p.ActorID != uuid.Nil
+}
+
+// IsStaff сообщает, что владелец - сотрудник поддержки или администратор с доступом ко всем заказам.
+func (p *Principal) IsStaff() bool {
+	return slices.Contains(p.Roles, RoleSupport) || slices.Contains(p.Roles, RoleAdmin)
+}
+
+// CanAccess сообщает, можно ли владельцу токена видеть заказы пользователя userID.
+func (p *Principal) CanAccess(userID uuid.UUID) bool {
+	return p.IsStaff() || p.UserID == userID
+}
+
+// Verifier проверяет подпись и срок действия токенов.
+type Verifier struct {
+	secret []byte
+	now    func() time.Time
+}
+
+func NewVerifier(secret []byte) *Verifier {
+	return &Verifier{secret: secret, now: time.Now}
+}
+
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  string   `json:"aud"`
+	Roles     []string `json:"roles"`
+	ExpiresAt int64    `json:"exp"`
+	IssuedAt  int64    `json:"iat"`
+	ID        string   `json:"jti"`
+	Actor     *struct {
+		Subject string `json:"sub"`
+	} `json:"act"`
+}
+
+// Verify возвращает владельца действующего токена. ErrInvalidToken, если токен подделан, истёк или выпущен не для order-service.
+func (v *Verifier) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	// Алгоритм фиксирован, чтобы токен с "alg": "none" не прошёл без подписи
+	if header.Algorithm != "HS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	now := v.now()
+	switch {
+	case c.Issuer != Issuer || c.Audience != Audience:
+		return nil, fmt.Errorf("%w: token is not issued for order-service", ErrInvalidToken)
+	case c.ExpiresAt == 0 || !now.Before(time.Unix(c.ExpiresAt, 0).Add(clockLeeway)):
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case time.Unix(c.IssuedAt, 0).After(now.Add(clockLeeway)):
+		return nil, fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	}
+	userID, err := uuid.FromString(c.Subject)
+	if err != nil || userID == uuid.Nil {
+		return nil, fmt.Errorf("%w: subject is not a user id", ErrInvalidToken)
+	}
+	principal := &Principal{UserID: userID, Roles: c.Roles}
+	if c.Actor != nil {
+		actorID, err := uuid.FromString(c.Actor.Subject)
+		if err != nil || actorID == uuid.Nil {
+			return nil, fmt.Errorf("%w: actor is not a user id", ErrInvalidToken)
+		}
+		principal.ActorID, principal.ImpersonationID = actorID, c.ID
+	}
+	return principal, nil
+}
+
+type principalKey struct{}
+
+// NewContext возвращает контекст с владельцем токена запроса.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext возвращает владельца токена, которого положил NewContext.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,101 @@
+package authz
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+func sign(t *testing.T, secret []byte, header, payload map[string]any) string {
+	t.Helper()
+	headerJSON, err := json.Marshal(header)
+	require.NoError(t, err)
+	payloadJSON, err := json.Marshal(payload)
+	require.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payloadJSON)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	verifier := NewVerifier(testSecret)
+	verifier.now = func() time.Time { return now }
+	userID := uuid.Must(uuid.NewV4())
+
+	claimsWith := func(key string, value any) map[string]any {
+		c := map[string]any{
+			"iss":   Issuer,
+			"aud":   Audience,
+			"sub":   userID.String(),
+			"roles": []string{RoleSupport},
+			"iat":   now.Unix(),
+			"exp":   now.Add(15 * time.Minute).Unix(),
+		}
+		if key != "" {
+			c[key] = value
+		}
+		return c
+	}
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+
+	principal, err := verifier.Verify(sign(t, testSecret, hs256, claimsWith("", nil)))
+	require.NoError(t, err)
+	assert.Equal(t, userID, principal.UserID)
+	assert.True(t, principal.IsStaff())
+	assert.False(t, principal.IsImpersonated())
+
+	actorID := uuid.Must(uuid.NewV4())
+	impersonation := claimsWith("act", map[string]any{"sub": actorID.String()})
+	impersonation["roles"], impersonation["jti"] = []string{}, "imp-1"
+	principal, err = verifier.Verify(sign(t, testSecret, hs256, impersonation))
+	require.NoError(t, err)
+	assert.True(t, principal.IsImpersonated())
+	assert.Equal(t, actorID, principal.ActorID)
+	assert.Equal(t, "imp-1", principal.ImpersonationID)
+	assert.False(t, principal.CanAccess(uuid.Must(uuid.NewV4())), "support acting as a customer sees only that customer's orders")
+	assert.True(t, principal.CanAccess(userID))
+
+	tests := map[string]string{
+		"wrong secret":     sign(t, []byte("another-secret-another-secret-00"), hs256, claimsWith("", nil)),
+		"alg none":         sign(t, testSecret, map[string]any{"alg": "none"}, claimsWith("", nil)),
+		"expired":          sign(t, testSecret, hs256, claimsWith("exp", now.Add(-time.Minute).Unix())),
+		"issued in future": sign(t, testSecret, hs256, claimsWith("iat", now.Add(time.Hour).Unix())),
+		"foreign audience": sign(t, testSecret, hs256, claimsWith("aud", "billing-service")),
+		"foreign issuer":   sign(t, testSecret, hs256, claimsWith("iss", "someone")),
+		"subject not uuid": sign(t, testSecret, hs256, claimsWith("sub", "admin")),
+		"actor not uuid":   sign(t, testSecret, hs256, claimsWith("act", map[string]any{"sub": "support"})),
+		"malformed":        "abc.def",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := verifier.Verify(token)
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+}
+
+func TestPrincipal_CanAccess(t *testing.T) {
+	owner := uuid.Must(uuid.NewV4())
+	other := uuid.Must(uuid.NewV4())
+
+	customer := &Principal{UserID: owner}
+	assert.True(t, customer.CanAccess(owner))
+	assert.False(t, customer.CanAccess(other))
+
+	for _, role := range []string{RoleSupport, RoleAdmin} {
+		staff := &Principal{UserID: owner, Roles: []string{role}}
+		assert.True(t, staff.CanAccess(other), role)
+	}
+	assert.False(t, (&Principal{UserID: owner, Roles: []string{"auditor"}}).CanAccess(other))
+}
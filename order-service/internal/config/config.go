@@ -25,9 +25,83 @@ type PostgresConfig struct {
 	MigrationsPath  string
 }
 
+// ShippingConfig задаёт провайдеров тарифов доставки.
+type ShippingConfig struct {
+	RateTablePath         string  // Путь к JSON тарифной сетке; пустой путь отключает табличного провайдера
+	FlatRate              float64 // Фиксированная цена доставки
+	FlatRateFreeThreshold float64 // Сумма заказа, начиная с которой фиксированная доставка бесплатна (0 - без порога)
+}
+
+// ReturnsConfig задаёт правила приёма возвратов.
+type ReturnsConfig struct {
+	Window time.Duration // Срок после доставки, в течение которого покупатель может оформить возврат
+}
+
+// StaleOrdersConfig задаёт автоматическую отмену неоплаченных заказов.
+type StaleOrdersConfig struct {
+	Timeout       time.Duration // Возраст заказа в NEW/PROCESSING, после которого он отменяется; 0 отключает задачу
+	CheckInterval time.Duration
+	BatchSize     int
+}
+
+// TrackingConfig задаёт потоки событий заказа (SSE).
+type TrackingConfig struct {
+	HeartbeatInterval time.Duration // Как часто в открытый поток отправляется heartbeat
+}
+
+// WebhookConfig задаёт доставку событий заказов партнёрам.
+type WebhookConfig struct {
+	Workers      int
+	MaxAttempts  int           // После стольких неудачных попыток доставка переходит в DEAD
+	BackoffBase  time.Duration // Пауза после первой неудачи, дальше удваивается
+	BackoffMax   time.Duration
+	Timeout      time.Duration // Таймаут запроса к получателю
+	PollInterval time.Duration // Как часто проверять историю статусов и очередь доставок
+}
+
+// GRPCConfig задаёт gRPC сервер, который работает рядом с HTTP API на отдельном порту.
+type GRPCConfig struct {
+	Port      string
+	AuthToken string `json:"-"` // Общий токен внутренних клиентов, обязателен. Не попадает в лог конфигурации
+}
+
+// EventsConfig задаёт брокер доменных событий.
+type EventsConfig struct {
+	NATSURL       string        `json:"-"` // Пустой - события доставляются только внутри процесса. Может содержать учётные данные
+	Stream        string        // Поток JetStream, общий для всех сервисов
+	RelayInterval time.Duration // Как часто отправлять в брокер события из event_outbox
+}
+
+// InternalAPIConfig задаёт HTTP маршруты /internal, которые вызывают другие сервисы.
+type InternalAPIConfig struct {
+	Token string `json:"-"` // Общий токен сервисов, обязателен. Не попадает в лог конфигурации
+}
+
+// AccessTokenConfig задаёт проверку токенов доступа покупателей и сотрудников, которые выпускает user-service.
+type AccessTokenConfig struct {
+	Secret []byte `json:"-"` // Общий с user-service ключ подписи HS256, обязателен. Не попадает в лог конфигурации
+}
+
+// UserServiceConfig задаёт HTTP API user-service, у которого перед оформлением заказа проверяется покупатель.
+type UserServiceConfig struct {
+	URL                  string
+	Timeout              time.Duration
+	RequireVerifiedEmail bool // false отключает проверку подтверждения email, например для локальной разработки
+}
+
 type Config struct {
-	App      AppConfig
-	Postgres PostgresConfig
+	App         AppConfig
+	Postgres    PostgresConfig
+	Shipping    ShippingConfig
+	Returns     ReturnsConfig
+	StaleOrders StaleOrdersConfig
+	GRPC        GRPCConfig
+	Tracking    TrackingConfig
+	Webhooks    WebhookConfig
+	Events      EventsConfig
+	Internal    InternalAPIConfig
+	UserService UserServiceConfig
+	AccessToken AccessTokenConfig
 }
 
 func NewConfig() (*Config, error) {
@@ -115,5 +189,192 @@ func NewConfig() (*Config, error) {
 		cfg.Postgres.MigrationsPath = "/app/migrations"
 	}
 
+	// Настройки расчёта доставки
+	cfg.Shipping.RateTablePath = os.Getenv("SHIPPING_RATE_TABLE_PATH")
+
+	flatRateStr := os.Getenv("SHIPPING_FLAT_RATE")
+	if flatRateStr == "" {
+		cfg.Shipping.FlatRate = 5.00
+	} else {
+		cfg.Shipping.FlatRate, err = strconv.ParseFloat(flatRateStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SHIPPING_FLAT_RATE '%s': %w", flatRateStr, err)
+		}
+	}
+
+	freeThresholdStr := os.Getenv("SHIPPING_FREE_THRESHOLD")
+	if freeThresholdStr != "" {
+		cfg.Shipping.FlatRateFreeThreshold, err = strconv.ParseFloat(freeThresholdStr, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SHIPPING_FREE_THRESHOLD '%s': %w", freeThresholdStr, err)
+		}
+	}
+
+	// Окно возврата
+	returnWindowStr := os.Getenv("RETURN_WINDOW")
+	if returnWindowStr == "" {
+		cfg.Returns.Window = 30 * 24 * time.Hour
+	} else {
+		cfg.Returns.Window, err = time.ParseDuration(returnWindowStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse RETURN_WINDOW '%s': %w", returnWindowStr, err)
+		}
+	}
+
+	// Автоотмена неоплаченных заказов
+	staleTimeoutStr := os.Getenv("STALE_ORDER_TIMEOUT")
+	if staleTimeoutStr == "" {
+		cfg.StaleOrders.Timeout = 24 * time.Hour
+	} else {
+		cfg.StaleOrders.Timeout, err = time.ParseDuration(staleTimeoutStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STALE_ORDER_TIMEOUT '%s': %w", staleTimeoutStr, err)
+		}
+	}
+
+	staleIntervalStr := os.Getenv("STALE_ORDER_CHECK_INTERVAL")
+	if staleIntervalStr == "" {
+		cfg.StaleOrders.CheckInterval = 5 * time.Minute
+	} else {
+		cfg.StaleOrders.CheckInterval, err = time.ParseDuration(staleIntervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STALE_ORDER_CHECK_INTERVAL '%s': %w", staleIntervalStr, err)
+		}
+		if cfg.StaleOrders.CheckInterval <= 0 {
+			return nil, fmt.Errorf("STALE_ORDER_CHECK_INTERVAL must be positive, got '%s'", staleIntervalStr)
+		}
+	}
+
+	staleBatchStr := os.Getenv("STALE_ORDER_BATCH_SIZE")
+	if staleBatchStr == "" {
+		cfg.StaleOrders.BatchSize = 100
+	} else {
+		cfg.StaleOrders.BatchSize, err = strconv.Atoi(staleBatchStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse STALE_ORDER_BATCH_SIZE '%s': %w", staleBatchStr, err)
+		}
+		if cfg.StaleOrders.BatchSize <= 0 {
+			return nil, fmt.Errorf("STALE_ORDER_BATCH_SIZE must be positive, got '%s'", staleBatchStr)
+		}
+	}
+
+	// gRPC API
+	cfg.GRPC.Port = os.Getenv("GRPC_PORT")
+	if cfg.GRPC.Port == "" {
+		cfg.GRPC.Port = "9080"
+	}
+	cfg.GRPC.AuthToken = os.Getenv("GRPC_AUTH_TOKEN")
+	if cfg.GRPC.AuthToken == "" {
+		return nil, errors.New("GRPC_AUTH_TOKEN must be set")
+	}
+
+	// Потоки событий заказа
+	heartbeatStr := os.Getenv("SSE_HEARTBEAT_INTERVAL")
+	if heartbeatStr == "" {
+		cfg.Tracking.HeartbeatInterval = 15 * time.Second
+	} else {
+		cfg.Tracking.HeartbeatInterval, err = time.ParseDuration(heartbeatStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SSE_HEARTBEAT_INTERVAL '%s': %w", heartbeatStr, err)
+		}
+		if cfg.Tracking.HeartbeatInterval <= 0 {
+			return nil, fmt.Errorf("SSE_HEARTBEAT_INTERVAL must be positive, got '%s'", heartbeatStr)
+		}
+	}
+
+	// Вебхуки
+	if cfg.Webhooks.Workers, err = positiveIntEnv("WEBHOOK_WORKERS", 4); err != nil {
+		return nil, err
+	}
+	if cfg.Webhooks.MaxAttempts, err = positiveIntEnv("WEBHOOK_MAX_ATTEMPTS", 8); err != nil {
+		return nil, err
+	}
+	if cfg.Webhooks.BackoffBase, err = positiveDurationEnv("WEBHOOK_BACKOFF_BASE", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Webhooks.BackoffMax, err = positiveDurationEnv("WEBHOOK_BACKOFF_MAX", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.Webhooks.Timeout, err = positiveDurationEnv("WEBHOOK_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Webhooks.PollInterval, err = positiveDurationEnv("WEBHOOK_POLL_INTERVAL", 2*time.Second); err != nil {
+		return nil, err
+	}
+
+	// Брокер событий
+	cfg.Events.NATSURL = os.Getenv("NATS_URL")
+	cfg.Events.Stream = os.Getenv("EVENTS_STREAM")
+	if cfg.Events.Stream == "" {
+		cfg.Events.Stream = "ECOMMERCE"
+	}
+	if cfg.Events.RelayInterval, err = positiveDurationEnv("EVENTS_RELAY_INTERVAL", time.Second); err != nil {
+		return nil, err
+	}
+
+	cfg.Internal.Token = os.Getenv("INTERNAL_API_TOKEN")
+	if cfg.Internal.Token == "" {
+		return nil, errors.New("INTERNAL_API_TOKEN must be set")
+	}
+
+	cfg.AccessToken.Secret = []byte(os.Getenv("ACCESS_TOKEN_SECRET"))
+	if len(cfg.AccessToken.Secret) == 0 {
+		return nil, errors.New("ACCESS_TOKEN_SECRET must be set")
+	}
+	if len(cfg.AccessToken.Secret) < 32 {
+		return nil, errors.New("ACCESS_TOKEN_SECRET must be at least 32 bytes long")
+	}
+
+	// user-service
+	cfg.UserService.URL = os.Getenv("USER_SERVICE_URL")
+	if cfg.UserService.URL == "" {
+		cfg.UserService.URL = "http://user-service:8080"
+	}
+	if cfg.UserService.Timeout, err = positiveDurationEnv("USER_SERVICE_TIMEOUT", 3*time.Second); err != nil {
+		return nil, err
+	}
+
+	requireVerifiedStr := os.Getenv("REQUIRE_VERIFIED_EMAIL")
+	if requireVerifiedStr == "" {
+		cfg.UserService.RequireVerifiedEmail = true
+	} else {
+		cfg.UserService.RequireVerifiedEmail, err = strconv.ParseBool(requireVerifiedStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse REQUIRE_VERIFIED_EMAIL '%s': %w", requireVerifiedStr, err)
+		}
+	}
+
 	return cfg, nil
 }
+
+// positiveIntEnv читает положительное целое из переменной окружения name, def - значение по умолчанию.
+func positiveIntEnv(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s '%s': %w", name, raw, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got '%s'", name, raw)
+	}
+	return value, nil
+}
+
+// positiveDurationEnv читает положительную длительность из переменной окружения name, def - значение по умолчанию.
+func positiveDurationEnv(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s '%s': %w", name, raw, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got '%s'", name, raw)
+	}
+	return value, nil
+}
@@ -0,0 +1,43 @@
+package consumer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+// userEventsConsumer - durable consumer order-service для событий пользователей.
+const userEventsConsumer = "order-service-users"
+
+// UserEvents реагирует на события user-service.
+type UserEvents struct {
+	orderSvc order.Service
+}
+
+func NewUserEvents(orderSvc order.Service) *UserEvents {
+	return &UserEvents{orderSvc: orderSvc}
+}
+
+// Subscribe подписывается на удаление пользователей. Обработка продолжается до отмены ctx.
+func (c *UserEvents) Subscribe(ctx context.Context, subscriber events.EventSubscriber) error {
+	return subscriber.Subscribe(ctx, userEventsConsumer, events.TypeUserDeleted, c.HandleUserDeleted)
+}
+
+// HandleUserDeleted обезличивает завершённые заказы удалённого пользователя.
+// Ошибка возвращается брокеру, чтобы событие было доставлено повторно.
+func (c *UserEvents) HandleUserDeleted(ctx context.Context, event events.Event) error {
+	var data events.UserDeletedData
+	if err := event.DecodeData(&data); err != nil {
+		// Повтор не исправит битое событие
+		log.Error().Err(err).Str("event_id", event.ID).Msg("consumer: skipping malformed user.deleted event")
+		return nil
+	}
+
+	if _, err := c.orderSvc.AnonymizeUserOrders(ctx, data.ID); err != nil {
+		return fmt.Errorf("consumer: failed to anonymize orders of user %s: %w", data.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+func TestUserEvents_AnonymizesOrdersOnUserDeleted(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	broker := events.NewMemoryBroker()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(t, NewUserEvents(mockOrders).Subscribe(ctx, broker))
+
+	userID := uuid.Must(uuid.NewV4())
+	mockOrders.On("AnonymizeUserOrders", mock.Anything, userID).Return(3, nil).Once()
+
+	deleted, err := events.New(events.SourceUserService, events.TypeUserDeleted, userID.String(), events.UserDeletedData{ID: userID})
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(ctx, deleted))
+
+	// Другие события пользователей не обрабатываются
+	updated, err := events.New(events.SourceUserService, events.TypeUserUpdated, userID.String(), events.UserData{ID: userID})
+	require.NoError(t, err)
+	require.NoError(t, broker.Publish(ctx, updated))
+
+	mockOrders.AssertExpectations(t)
+}
+
+func TestUserEvents_HandleUserDeleted_ReturnsErrorForRedelivery(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	userID := uuid.Must(uuid.NewV4())
+	mockOrders.On("AnonymizeUserOrders", mock.Anything, userID).Return(0, errors.New("connection reset")).Once()
+
+	event, err := events.New(events.SourceUserService, events.TypeUserDeleted, userID.String(), events.UserDeletedData{ID: userID})
+	require.NoError(t, err)
+
+	err = NewUserEvents(mockOrders).HandleUserDeleted(context.Background(), event)
+	require.Error(t, err)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestUserEvents_HandleUserDeleted_SkipsMalformedEvent(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	event := events.Event{
+		SpecVersion: events.SpecVersion,
+		ID:          "broken",
+		Source:      events.SourceUserService,
+		Type:        events.TypeUserDeleted,
+		Time:        time.Now(),
+		Data:        json.RawMessage(`"not an object"`),
+	}
+
+	assert.NoError(t, NewUserEvents(mockOrders).HandleUserDeleted(context.Background(), event))
+	mockOrders.AssertNotCalled(t, "AnonymizeUserOrders", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,26 @@
+package export
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+// OrderRecord - заказ покупателя со всеми связанными данными для выгрузки по запросу субъекта данных.
+type OrderRecord struct {
+	order.Order
+	StatusHistory []StatusChange `json:"status_history"`
+}
+
+type StatusChange struct {
+	From      order.OrderStatus `json:"from,omitempty"` // Пусто для создания заказа
+	To        order.OrderStatus `json:"to"`
+	ChangedAt time.Time         `json:"changed_at"`
+}
+
+// Cursor - позиция в выгрузке: заказы идут по возрастанию (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	OrderID   uuid.UUID
+}
@@ -0,0 +1,199 @@
+package export
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type Repository interface {
+	// ListUserOrders возвращает до limit заказов userID после курсора after (nil - с начала) с позициями, доставкой и историей статусов.
+	ListUserOrders(ctx context.Context, userID uuid.UUID, after *Cursor, limit int) ([]OrderRecord, error)
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) ListUserOrders(ctx context.Context, userID uuid.UUID, after *Cursor, limit int) ([]OrderRecord, error) {
+	query := `
+		SELECT id, user_id, status, total_amount, shipping_address_text, COALESCE(cancellation_reason, ''), anonymized_at, created_at, updated_at
+		FROM order_service.orders
+		WHERE user_id = $1 AND ($2::timestamptz IS NULL OR (created_at, id) > ($2, $3))
+		ORDER BY created_at, id
+		LIMIT $4
+	`
+
+	var afterCreatedAt any
+	afterID := uuid.Nil
+	if after != nil {
+		afterCreatedAt = after.CreatedAt
+		afterID = after.OrderID
+	}
+
+	rows, err := r.db.Query(ctx, query, userID, afterCreatedAt, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query orders for export of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	records := make([]OrderRecord, 0, limit)
+	index := make(map[uuid.UUID]int, limit)
+	orderIDs := make([]uuid.UUID, 0, limit)
+	for rows.Next() {
+		var record OrderRecord
+		err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.Status,
+			&record.TotalAmount,
+			&record.ShippingAddressText,
+			&record.CancellationReason,
+			&record.AnonymizedAt,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan order for export of user %s: %w", userID, err)
+		}
+		record.OrderItems = make([]order.OrderItem, 0)
+		record.StatusHistory = make([]StatusChange, 0)
+		index[record.ID] = len(records)
+		orderIDs = append(orderIDs, record.ID)
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating orders for export of user %s: %w", userID, err)
+	}
+
+	if len(records) == 0 {
+		return records, nil
+	}
+
+	if err := r.loadItems(ctx, records, index, orderIDs); err != nil {
+		return nil, err
+	}
+	if err := r.loadShippingLines(ctx, records, index, orderIDs); err != nil {
+		return nil, err
+	}
+	if err := r.loadStatusHistory(ctx, records, index, orderIDs); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (r *postgresRepository) loadItems(ctx context.Context, records []OrderRecord, index map[uuid.UUID]int, orderIDs []uuid.UUID) error {
+	query := `
+		SELECT id, order_id, product_id, quantity, price_per_unit, weight_grams, created_at, updated_at
+		FROM order_service.order_items
+		WHERE order_id = ANY($1)
+		ORDER BY created_at, id
+	`
+	rows, err := r.db.Query(ctx, query, orderIDs)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query order items for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item order.OrderItem
+		err := rows.Scan(
+			&item.ID,
+			&item.OrderID,
+			&item.ProductID,
+			&item.Quantity,
+			&item.PricePerUnit,
+			&item.WeightGrams,
+			&item.CreatedAt,
+			&item.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("repository: failed to scan order item for export: %w", err)
+		}
+		if i, ok := index[item.OrderID]; ok {
+			records[i].OrderItems = append(records[i].OrderItems, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating order items for export: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) loadShippingLines(ctx context.Context, records []OrderRecord, index map[uuid.UUID]int, orderIDs []uuid.UUID) error {
+	query := `
+		SELECT order_id, option_id, provider, carrier, service_name, price, estimated_days, created_at, updated_at
+		FROM order_service.order_shipping_lines
+		WHERE order_id = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, orderIDs)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query shipping lines for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line order.ShippingLine
+		err := rows.Scan(
+			&line.OrderID,
+			&line.OptionID,
+			&line.Provider,
+			&line.Carrier,
+			&line.ServiceName,
+			&line.Price,
+			&line.EstimatedDays,
+			&line.CreatedAt,
+			&line.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("repository: failed to scan shipping line for export: %w", err)
+		}
+		if i, ok := index[line.OrderID]; ok {
+			records[i].ShippingLine = &line
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating shipping lines for export: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) loadStatusHistory(ctx context.Context, records []OrderRecord, index map[uuid.UUID]int, orderIDs []uuid.UUID) error {
+	query := `
+		SELECT order_id, COALESCE(from_status, ''), to_status, changed_at
+		FROM order_service.order_status_history
+		WHERE order_id = ANY($1)
+		ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, query, orderIDs)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query status history for export: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID uuid.UUID
+		var change StatusChange
+		if err := rows.Scan(&orderID, &change.From, &change.To, &change.ChangedAt); err != nil {
+			return fmt.Errorf("repository: failed to scan status history for export: %w", err)
+		}
+		if i, ok := index[orderID]; ok {
+			records[i].StatusHistory = append(records[i].StatusHistory, change)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating status history for export: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gofrs/uuid"
+)
+
+// DefaultPageSize - сколько заказов читается из базы за один запрос при выгрузке.
+const DefaultPageSize = 100
+
+type Service interface {
+	// WriteUserOrders пишет все заказы userID в w по одному JSON объекту OrderRecord на строку (NDJSON).
+	// Заказы читаются страницами, поэтому память не зависит от их числа. После каждой страницы вызывается flush.
+	WriteUserOrders(ctx context.Context, userID uuid.UUID, w io.Writer, flush func()) (int, error)
+}
+
+type service struct {
+	repo     Repository
+	pageSize int
+}
+
+func NewService(repo Repository, pageSize int) Service {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &service{repo: repo, pageSize: pageSize}
+}
+
+func (s *service) WriteUserOrders(ctx context.Context, userID uuid.UUID, w io.Writer, flush func()) (int, error) {
+	encoder := json.NewEncoder(w)
+	written := 0
+	var after *Cursor
+
+	for {
+		records, err := s.repo.ListUserOrders(ctx, userID, after, s.pageSize)
+		if err != nil {
+			return written, fmt.Errorf("service: failed to load orders for export of user %s: %w", userID, err)
+		}
+
+		for i := range records {
+			if err := encoder.Encode(&records[i]); err != nil {
+				return written, fmt.Errorf("service: failed to write order %s to export: %w", records[i].ID, err)
+			}
+			written++
+		}
+		if flush != nil {
+			flush()
+		}
+
+		if len(records) < s.pageSize {
+			return written, nil
+		}
+		last := records[len(records)-1]
+		after = &Cursor{CreatedAt: last.CreatedAt, OrderID: last.ID}
+	}
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) ListUserOrders(ctx context.Context, userID uuid.UUID, after *Cursor, limit int) ([]OrderRecord, error) {
+	args := m.Called(ctx, userID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]OrderRecord), args.Error(1)
+}
+
+func newRecord(userID uuid.UUID, createdAt time.Time) OrderRecord {
+	return OrderRecord{
+		Order: order.Order{
+			ID:                  uuid.Must(uuid.NewV4()),
+			UserID:              userID,
+			Status:              order.StatusDelivered,
+			ShippingAddressText: "Moscow, Tverskaya 1",
+			OrderItems:          []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1}},
+			CreatedAt:           createdAt,
+		},
+		StatusHistory: []StatusChange{{To: order.StatusNew, ChangedAt: createdAt}},
+	}
+}
+
+func TestService_WriteUserOrders_Paginates(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, 2)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	now := time.Now().UTC()
+
+	first := []OrderRecord{newRecord(userID, now), newRecord(userID, now.Add(time.Minute))}
+	second := []OrderRecord{newRecord(userID, now.Add(2*time.Minute))}
+
+	mockRepo.On("ListUserOrders", ctx, userID, (*Cursor)(nil), 2).Return(first, nil).Once()
+	mockRepo.On("ListUserOrders", ctx, userID, &Cursor{CreatedAt: first[1].CreatedAt, OrderID: first[1].ID}, 2).Return(second, nil).Once()
+
+	var buf bytes.Buffer
+	flushes := 0
+	written, err := svc.WriteUserOrders(ctx, userID, &buf, func() { flushes++ })
+	require.NoError(t, err)
+	assert.Equal(t, 3, written)
+	assert.Equal(t, 2, flushes)
+
+	scanner := bufio.NewScanner(&buf)
+	var lines []OrderRecord
+	for scanner.Scan() {
+		var record OrderRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		lines = append(lines, record)
+	}
+	require.Len(t, lines, 3)
+	assert.Equal(t, second[0].ID, lines[2].ID)
+	assert.Equal(t, "Moscow, Tverskaya 1", lines[0].ShippingAddressText)
+	assert.Len(t, lines[0].StatusHistory, 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_WriteUserOrders_RepositoryError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, 2)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	repoErr := errors.New("db is down")
+	mockRepo.On("ListUserOrders", ctx, userID, (*Cursor)(nil), 2).Return(nil, repoErr).Once()
+
+	_, err := svc.WriteUserOrders(ctx, userID, &bytes.Buffer{}, nil)
+	require.ErrorIs(t, err, repoErr)
+}
@@ -0,0 +1,116 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptor пишет в лог метод, код ответа и длительность каждого вызова.
+func LoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logCall(info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamLoggingInterceptor - то же для потоковых вызовов; запись появляется, когда поток завершился.
+func StreamLoggingInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		logCall(info.FullMethod, start, err)
+		return err
+	}
+}
+
+func logCall(method string, start time.Time, err error) {
+	code := status.Code(err)
+	event := log.Info()
+	if code == codes.Internal || code == codes.Unknown {
+		event = log.Error().Err(err)
+	} else if err != nil {
+		event = log.Warn().Err(err)
+	}
+	event.
+		Str("grpc_method", method).
+		Str("grpc_code", code.String()).
+		Dur("duration", time.Since(start)).
+		Msg("gRPC request handled")
+}
+
+// RecoveryInterceptor превращает панику в обработчике в ответ codes.Internal, не роняя сервер.
+func RecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor - то же для потоковых вызовов.
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+func recoverPanic(method string, err *error) {
+	if p := recover(); p != nil {
+		log.Error().
+			Interface("panic_value", p).
+			Str("grpc_method", method).
+			Bytes("stack", debug.Stack()).
+			Msg("Panic recovered in gRPC handler")
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// AuthInterceptor пропускает только вызовы с метаданными "authorization: Bearer <token>".
+// Пустой token не отключает проверку: такие вызовы отклоняются все.
+func AuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := authorize(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor - то же для потоковых вызовов.
+func StreamAuthInterceptor(token string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorize(ctx context.Context, token string) error {
+	if token == "" {
+		return status.Error(codes.Unavailable, "authentication is not configured")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return status.Error(codes.Unauthenticated, "missing authorization metadata")
+	}
+
+	provided, found := strings.CutPrefix(values[0], "Bearer ")
+	if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	orderv1 "github.com/vasiliy-maslov/ecommerce-microservices/order-service/pkg/api/order/v1"
+)
+
+// StatusWatcher выдаёт подписку на смены статуса одного заказа. Реализуется order.Broadcaster.
+type StatusWatcher interface {
+	Subscribe(orderID uuid.UUID) (events <-chan order.StatusChange, unsubscribe func())
+}
+
+// finalStatuses - статусы, после которых заказ больше не меняется и WatchOrder закрывает поток.
+var finalStatuses = map[order.OrderStatus]bool{
+	order.StatusDelivered: true,
+	order.StatusCancelled: true,
+}
+
+type OrderServer struct {
+	orderv1.UnimplementedOrderServiceServer
+	service order.Service
+	watcher StatusWatcher
+}
+
+func NewOrderServer(service order.Service, watcher StatusWatcher) *OrderServer {
+	return &OrderServer{service: service, watcher: watcher}
+}
+
+// NewServer создаёт gRPC сервер с интерцепторами и зарегистрированным OrderService.
+// Каждый вызов должен нести authToken; без него сервер отклоняет все вызовы.
+func NewServer(service order.Service, watcher StatusWatcher, authToken string) *grpc.Server {
+	// Recovery внутри логирования, чтобы паника попала в лог как codes.Internal
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(LoggingInterceptor(), RecoveryInterceptor(), AuthInterceptor(authToken)),
+		grpc.ChainStreamInterceptor(StreamLoggingInterceptor(), StreamRecoveryInterceptor(), StreamAuthInterceptor(authToken)),
+	)
+	orderv1.RegisterOrderServiceServer(server, NewOrderServer(service, watcher))
+	return server
+}
+
+func (s *OrderServer) CreateOrder(ctx context.Context, req *orderv1.CreateOrderRequest) (*orderv1.Order, error) {
+	userID, err := parseID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetItems()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "order must contain at least one item")
+	}
+
+	items := make([]order.OrderItem, 0, len(req.GetItems()))
+	for _, item := range req.GetItems() {
+		productID, err := parseID("product_id", item.GetProductId())
+		if err != nil {
+			return nil, err
+		}
+		if item.GetQuantity() <= 0 || item.GetPricePerUnit() < 0 || item.GetWeightGrams() < 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid quantity, price or weight for product %s", productID)
+		}
+		items = append(items, order.OrderItem{
+			ProductID:    productID,
+			Quantity:     int(item.GetQuantity()),
+			PricePerUnit: item.GetPricePerUnit(),
+			WeightGrams:  int(item.GetWeightGrams()),
+		})
+	}
+
+	created, err := s.service.CreateOrder(ctx, &order.Order{
+		UserID:              userID,
+		OrderItems:          items,
+		ShippingAddressText: req.GetShippingAddressText(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoOrder(created), nil
+}
+
+func (s *OrderServer) GetOrder(ctx context.Context, req *orderv1.GetOrderRequest) (*orderv1.Order, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.service.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoOrder(found), nil
+}
+
+func (s *OrderServer) ListOrders(ctx context.Context, req *orderv1.ListOrdersRequest) (*orderv1.ListOrdersResponse, error) {
+	userID, err := parseID("user_id", req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := s.service.GetOrdersByUserID(ctx, userID)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	resp := &orderv1.ListOrdersResponse{Orders: make([]*orderv1.Order, 0, len(orders))}
+	for i := range orders {
+		resp.Orders = append(resp.Orders, toProtoOrder(&orders[i]))
+	}
+	return resp, nil
+}
+
+func (s *OrderServer) UpdateOrderStatus(ctx context.Context, req *orderv1.UpdateOrderStatusRequest) (*orderv1.Order, error) {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if req.GetStatus() == "" {
+		return nil, status.Error(codes.InvalidArgument, "status is required")
+	}
+
+	if err := s.service.UpdateOrderStatus(ctx, id, order.OrderStatus(req.GetStatus())); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	updated, err := s.service.GetOrderByID(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoOrder(updated), nil
+}
+
+func (s *OrderServer) WatchOrder(req *orderv1.WatchOrderRequest, stream grpc.ServerStreamingServer[orderv1.OrderStatusEvent]) error {
+	id, err := parseID("id", req.GetId())
+	if err != nil {
+		return err
+	}
+	ctx := stream.Context()
+
+	// Подписываемся до чтения заказа, чтобы не потерять смену статуса между чтением и подпиской
+	events, unsubscribe := s.watcher.Subscribe(id)
+	defer unsubscribe()
+
+	current, err := s.service.GetOrderByID(ctx, id)
+	if err != nil {
+		return toStatusError(err)
+	}
+
+	lastStatus := current.Status
+	if err := stream.Send(&orderv1.OrderStatusEvent{
+		OrderId:   id.String(),
+		Status:    string(current.Status),
+		ChangedAt: timestamppb.New(current.UpdatedAt),
+	}); err != nil {
+		return err
+	}
+
+	for !finalStatuses[lastStatus] {
+		select {
+		case <-ctx.Done():
+			return toStatusError(ctx.Err())
+		case change, ok := <-events:
+			if !ok {
+				return status.Error(codes.Unavailable, "watcher fell behind, subscribe again")
+			}
+			if change.To == lastStatus {
+				continue // Уже отправлено вместе с текущим статусом
+			}
+			lastStatus = change.To
+			if err := stream.Send(&orderv1.OrderStatusEvent{
+				OrderId:        id.String(),
+				PreviousStatus: string(change.From),
+				Status:         string(change.To),
+				ChangedAt:      timestamppb.New(change.ChangedAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseID(field, raw string) (uuid.UUID, error) {
+	id, err := uuid.FromString(raw)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s %q", field, raw)
+	}
+	return id, nil
+}
+
+// toStatusError сопоставляет доменные ошибки с кодами gRPC. Внутренние ошибки не раскрываются клиенту.
+func toStatusError(err error) error {
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound):
+		return status.Error(codes.NotFound, "order not found")
+	case errors.Is(err, order.ErrInvalidStatusTransition):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, order.ErrUnknownStatus):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, order.ErrEmailNotVerified):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, order.ErrCustomerNotFound):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, order.ErrCustomerCheckFailed):
+		// Проверяется до context.DeadlineExceeded: таймаут запроса к user-service - это недоступность, а не дедлайн клиента
+		return status.Error(codes.Unavailable, order.ErrCustomerCheckFailed.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	default:
+		log.Error().Err(err).Msg("Unhandled error in gRPC order server")
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toProtoOrder(o *order.Order) *orderv1.Order {
+	items := make([]*orderv1.OrderItem, 0, len(o.OrderItems))
+	for _, item := range o.OrderItems {
+		items = append(items, &orderv1.OrderItem{
+			Id:           item.ID.String(),
+			ProductId:    item.ProductID.String(),
+			Quantity:     int32(item.Quantity),
+			PricePerUnit: item.PricePerUnit,
+			WeightGrams:  int32(item.WeightGrams),
+		})
+	}
+
+	return &orderv1.Order{
+		Id:                  o.ID.String(),
+		UserId:              o.UserID.String(),
+		Status:              string(o.Status),
+		Items:               items,
+		TotalAmount:         o.TotalAmount,
+		ShippingAddressText: o.ShippingAddressText,
+		CancellationReason:  o.CancellationReason,
+		CreatedAt:           timestamppb.New(o.CreatedAt),
+		UpdatedAt:           timestamppb.New(o.UpdatedAt),
+	}
+}
@@ -0,0 +1,299 @@
+package grpc_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	orderGrpc "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/grpc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	orderv1 "github.com/vasiliy-maslov/ecommerce-microservices/order-service/pkg/api/order/v1"
+)
+
+const testToken = "test-token"
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+// newTestClient поднимает gRPC сервер поверх bufconn и возвращает клиента к нему.
+func newTestClient(t *testing.T, service order.Service, watcher orderGrpc.StatusWatcher) orderv1.OrderServiceClient {
+	t.Helper()
+	return newTestClientWithToken(t, service, watcher, testToken)
+}
+
+func newTestClientWithToken(t *testing.T, service order.Service, watcher orderGrpc.StatusWatcher, authToken string) orderv1.OrderServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := orderGrpc.NewServer(service, watcher, authToken)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return orderv1.NewOrderServiceClient(conn)
+}
+
+func authContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+testToken)
+}
+
+func TestOrderServer_Auth(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClient(t, mockService, order.NewBroadcaster(0))
+
+	_, err := client.GetOrder(context.Background(), &orderv1.GetOrderRequest{Id: uuid.Must(uuid.NewV4()).String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	badCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.GetOrder(badCtx, &orderv1.GetOrderRequest{Id: uuid.Must(uuid.NewV4()).String()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err := client.WatchOrder(context.Background(), &orderv1.WatchOrderRequest{Id: uuid.Must(uuid.NewV4()).String()})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	mockService.AssertNotCalled(t, "GetOrderByID", mock.Anything, mock.Anything)
+}
+
+func TestOrderServer_EmptyTokenRejectsEverything(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClientWithToken(t, mockService, order.NewBroadcaster(0), "")
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer ")
+	_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: uuid.Must(uuid.NewV4()).String()})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	mockService.AssertNotCalled(t, "GetOrderByID", mock.Anything, mock.Anything)
+}
+
+func TestOrderServer_GetOrder_Success(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClient(t, mockService, order.NewBroadcaster(0))
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{
+		ID:          orderID,
+		UserID:      uuid.Must(uuid.NewV4()),
+		Status:      order.StatusPaid,
+		TotalAmount: 42.5,
+		OrderItems:  []order.OrderItem{{ID: uuid.Must(uuid.NewV4()), ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, PricePerUnit: 21.25}},
+	}, nil).Once()
+
+	resp, err := client.GetOrder(authContext(t), &orderv1.GetOrderRequest{Id: orderID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, orderID.String(), resp.GetId())
+	assert.Equal(t, "PAID", resp.GetStatus())
+	assert.Equal(t, 42.5, resp.GetTotalAmount())
+	require.Len(t, resp.GetItems(), 1)
+	assert.Equal(t, int32(2), resp.GetItems()[0].GetQuantity())
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderServer_ErrorMapping(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClient(t, mockService, order.NewBroadcaster(0))
+	ctx := authContext(t)
+
+	missingID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, missingID).Return(nil, order.ErrOrderNotFound).Once()
+	_, err := client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: missingID.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("UpdateOrderStatus", mock.Anything, orderID, order.StatusNew).
+		Return(order.ErrInvalidStatusTransition).Once()
+	_, err = client.UpdateOrderStatus(ctx, &orderv1.UpdateOrderStatusRequest{Id: orderID.String(), Status: "NEW"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = client.GetOrder(ctx, &orderv1.GetOrderRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.CreateOrder(ctx, &orderv1.CreateOrderRequest{UserId: uuid.Must(uuid.NewV4()).String()})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	unverifiedID := uuid.Must(uuid.NewV4())
+	mockService.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *order.Order) bool { return o.UserID == unverifiedID })).
+		Return(nil, order.ErrEmailNotVerified).Once()
+	_, err = client.CreateOrder(ctx, &orderv1.CreateOrderRequest{
+		UserId: unverifiedID.String(),
+		Items:  []*orderv1.CreateOrderItem{{ProductId: uuid.Must(uuid.NewV4()).String(), Quantity: 1}},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderServer_WatchOrder_StreamsChangesUntilFinalStatus(t *testing.T) {
+	mockService := new(MockOrderService)
+	broadcaster := order.NewBroadcaster(0)
+	client := newTestClient(t, mockService, broadcaster)
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, orderID).
+		Return(&order.Order{ID: orderID, Status: order.StatusPaid}, nil).Once()
+
+	stream, err := client.WatchOrder(authContext(t), &orderv1.WatchOrderRequest{Id: orderID.String()})
+	require.NoError(t, err)
+
+	snapshot, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "PAID", snapshot.GetStatus())
+	assert.Empty(t, snapshot.GetPreviousStatus())
+
+	// Подписка создаётся до отправки текущего статуса, поэтому события ниже не потеряются
+	broadcaster.OnStatusChange(order.StatusChange{OrderID: uuid.Must(uuid.NewV4()), To: order.StatusShipped})
+	broadcaster.OnStatusChange(order.StatusChange{OrderID: orderID, From: order.StatusPaid, To: order.StatusShipped, ChangedAt: time.Now()})
+	broadcaster.OnStatusChange(order.StatusChange{OrderID: orderID, From: order.StatusShipped, To: order.StatusDelivered, ChangedAt: time.Now()})
+
+	shipped, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "PAID", shipped.GetPreviousStatus())
+	assert.Equal(t, "SHIPPED", shipped.GetStatus())
+
+	delivered, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "DELIVERED", delivered.GetStatus())
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF, "stream must end after a final status")
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderServer_WatchOrder_FinalOrderEndsImmediately(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClient(t, mockService, order.NewBroadcaster(0))
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, orderID).
+		Return(&order.Order{ID: orderID, Status: order.StatusCancelled}, nil).Once()
+
+	stream, err := client.WatchOrder(authContext(t), &orderv1.WatchOrderRequest{Id: orderID.String()})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, "CANCELLED", event.GetStatus())
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestOrderServer_WatchOrder_RecoversFromPanic(t *testing.T) {
+	mockService := new(MockOrderService)
+	client := newTestClient(t, mockService, order.NewBroadcaster(0))
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, orderID).Run(func(mock.Arguments) {
+		panic("boom")
+	}).Once()
+
+	stream, err := client.WatchOrder(authContext(t), &orderv1.WatchOrderRequest{Id: orderID.String()})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// Сервер продолжает обслуживать вызовы после паники
+	mockService.On("GetOrderByID", mock.Anything, orderID).Return(nil, order.ErrOrderNotFound).Once()
+	_, err = client.GetOrder(authContext(t), &orderv1.GetOrderRequest{Id: orderID.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/export"
+)
+
+// ExportHandler отдаёт внутренним сервисам данные покупателя для выгрузки по запросу субъекта данных.
+type ExportHandler struct {
+	service export.Service
+	token   string
+}
+
+// NewExportHandler создаёт обработчик внутренних маршрутов. С пустым token маршруты отвечают 503.
+func NewExportHandler(service export.Service, token string) *ExportHandler {
+	return &ExportHandler{service: service, token: token}
+}
+
+func (h *ExportHandler) RegisterRoutes(router chi.Router) {
+	router.With(requireBearerToken(h.token)).Get("/internal/users/{userID}/orders/export", h.handleExportUserOrders)
+}
+
+// requireBearerToken пропускает только запросы с заголовком "Authorization: Bearer <token>".
+// Пустой token не отключает проверку: такие маршруты отвечают 503.
+func requireBearerToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				respondWithError(w, http.StatusServiceUnavailable, "Authentication is not configured")
+				return
+			}
+			provided, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or missing token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleExportUserOrders передаёт заказы покупателя потоком NDJSON, не собирая их в памяти.
+func (h *ExportHandler) handleExportUserOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUUIDParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// Выгрузка большого числа заказов может идти дольше WriteTimeout сервера
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Warn().Err(err).Msg("Failed to disable write deadline for orders export")
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	flush := func() {
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Warn().Err(err).Msg("Failed to flush orders export")
+		}
+	}
+
+	written, err := h.service.WriteUserOrders(r.Context(), userID, w, flush)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Int("orders_written", written).Msg("Orders export failed mid-stream")
+		// Статус уже отправлен: обрываем соединение, чтобы клиент не принял неполную выгрузку за полную
+		panic(http.ErrAbortHandler)
+	}
+
+	log.Info().Stringer("user_id", userID).Int("orders_written", written).Msg("Orders export completed")
+}
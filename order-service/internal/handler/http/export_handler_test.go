@@ -0,0 +1,66 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/export"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+)
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) WriteUserOrders(ctx context.Context, userID uuid.UUID, w io.Writer, flush func()) (int, error) {
+	args := m.Called(ctx, userID, w, flush)
+	return args.Int(0), args.Error(1)
+}
+
+const testInternalToken = "internal-secret"
+
+func newExportRouter(service export.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewExportHandler(service, testInternalToken).RegisterRoutes(router)
+	return router
+}
+
+func TestExportHandler_StreamsOrders(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("WriteUserOrders", mock.Anything, userID, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			_, _ = args.Get(2).(io.Writer).Write([]byte("{\"id\":\"1\"}\n"))
+		}).Return(1, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/"+userID.String()+"/orders/export", nil)
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"id\":\"1\"}\n", rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestExportHandler_RequiresToken(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/"+userID.String()+"/orders/export", nil)
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "WriteUserOrders", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,174 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/returns"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipping"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/webhook"
+)
+
+type ValidationErrorResponse struct {
+	Error   string            `json:"error"`   // Общее сообщение
+	Details map[string]string `json:"details"` // Детали по полям
+}
+
+// respondWithError отправляет JSON ошибку
+func respondWithError(w http.ResponseWriter, code int, message string) {
+	respondWithJSON(w, code, map[string]string{"error": message})
+}
+
+// respondWithJSON отправляет JSON ответ
+func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
+	response, err := json.Marshal(payload)
+	if err != nil {
+		log.Error().Err(err).Type("payload_type", payload).Msg("ERROR: Failed to marshal JSON response")
+		w.WriteHeader(http.StatusInternalServerError)
+		if _, writeErr := w.Write([]byte(`{"error":"Failed to marshal JSON response"}`)); writeErr != nil {
+			log.Warn().Err(writeErr).Msg("Failed to write fallback error response")
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if _, err := w.Write(response); err != nil {
+		log.Error().Err(err).Int("status_code", code).Msg("Failed to write JSON response")
+	}
+}
+
+func mapErrorToStatusCode(err error) int {
+	switch {
+	case errors.Is(err, order.ErrOrderNotFound),
+		errors.Is(err, shipment.ErrShipmentNotFound),
+		errors.Is(err, returns.ErrReturnNotFound),
+		errors.Is(err, webhook.ErrSubscriptionNotFound),
+		errors.Is(err, webhook.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, order.ErrInvalidStatusTransition),
+		errors.Is(err, shipment.ErrOrderNotShippable),
+		errors.Is(err, shipment.ErrTrackingNumberExists),
+		errors.Is(err, shipment.ErrShipmentDelivered),
+		errors.Is(err, order.ErrShippingLocked),
+		errors.Is(err, returns.ErrOrderNotReturnable),
+		errors.Is(err, returns.ErrReturnWindowExpired),
+		errors.Is(err, returns.ErrInvalidReturnTransition),
+		errors.Is(err, returns.ErrStatusChanged):
+		return http.StatusConflict
+	case errors.Is(err, shipment.ErrInvalidShipment),
+		errors.Is(err, shipment.ErrInvalidEvent),
+		errors.Is(err, shipment.ErrQuantityExceeded),
+		errors.Is(err, shipment.ErrUnknownOrderItem),
+		errors.Is(err, shipping.ErrInvalidAddress),
+		errors.Is(err, shipping.ErrEmptyCart),
+		errors.Is(err, shipping.ErrInvalidCart),
+		errors.Is(err, shipping.ErrNoRates),
+		errors.Is(err, shipping.ErrUnknownOption),
+		errors.Is(err, returns.ErrInvalidReturn),
+		errors.Is(err, returns.ErrUnknownOrderItem),
+		errors.Is(err, returns.ErrQuantityExceeded),
+		errors.Is(err, order.ErrUnknownStatus),
+		errors.Is(err, webhook.ErrInvalidSubscription),
+		errors.Is(err, webhook.ErrUnknownEventType),
+		errors.Is(err, order.ErrCustomerNotFound):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, order.ErrEmailNotVerified):
+		return http.StatusForbidden
+	case errors.Is(err, returns.ErrRefundFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, order.ErrCustomerCheckFailed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// clientErrorMessage возвращает текст ошибки для клиента: доменные ошибки отдаются как есть, внутренние скрываются.
+func clientErrorMessage(err error, fallback string) string {
+	if mapErrorToStatusCode(err) == http.StatusInternalServerError {
+		return fallback
+	}
+	// Причина недоступности user-service остаётся только в логе
+	if errors.Is(err, order.ErrCustomerCheckFailed) {
+		return order.ErrCustomerCheckFailed.Error()
+	}
+	return err.Error()
+}
+
+// decodeAndValidate читает JSON тело запроса в payload и проверяет его теги validate.
+// При ошибке ответ клиенту уже отправлен и возвращается false.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validator.Validate, payload interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return false
+	}
+
+	if err := validate.Struct(payload); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			respondWithJSON(w, http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "Validation failed",
+				Details: formatValidationErrors(validationErrors),
+			})
+		} else {
+			log.Error().Err(err).Type("validation_error_type", err).Msg("Unexpected error type during validation")
+			respondWithError(w, http.StatusInternalServerError, "Internal validation error")
+		}
+		return false
+	}
+
+	return true
+}
+
+// parseUUIDParam достаёт UUID из параметра маршрута. При ошибке отвечает 400 и возвращает false.
+func parseUUIDParam(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	param := chi.URLParam(r, name)
+	id, err := uuid.FromString(param)
+	if err != nil {
+		log.Warn().Err(err).Str(name, param).Msg("Failed to parse uuid parameter from URL")
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s parameter", name))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func formatValidationErrors(errs validator.ValidationErrors) map[string]string {
+	errorDetails := make(map[string]string)
+	for _, err := range errs {
+		var msg string
+		field := err.Field()
+		switch err.Tag() {
+		case "required":
+			msg = fmt.Sprintf("Field '%s' is required", err.Field())
+		case "min":
+			msg = fmt.Sprintf("Field '%s' must be at least %s", err.Field(), err.Param())
+		case "max":
+			msg = fmt.Sprintf("Field '%s' must be at most %s", err.Field(), err.Param())
+		case "gt":
+			msg = fmt.Sprintf("Field '%s' must be greater than %s", err.Field(), err.Param())
+		case "gte":
+			msg = fmt.Sprintf("Field '%s' must be greater than or equal to %s", err.Field(), err.Param())
+		case "len":
+			msg = fmt.Sprintf("Field '%s' must be exactly %s characters long", err.Field(), err.Param())
+		case "url":
+			msg = fmt.Sprintf("Field '%s' must be a valid URL", err.Field())
+		case "oneof":
+			msg = fmt.Sprintf("Field '%s' must be one of: %s", err.Field(), err.Param())
+		default:
+			msg = fmt.Sprintf("Field '%s' failed validation on '%s'", err.Field(), err.Tag())
+		}
+		errorDetails[field] = msg
+	}
+	return errorDetails
+}
@@ -0,0 +1,192 @@
+package http_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var testAccessSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// accessToken выпускает токен доступа так же, как user-service.
+func accessToken(t *testing.T, userID uuid.UUID, roles ...string) string {
+	t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(map[string]any{
+		"iss":   authz.Issuer,
+		"aud":   authz.Audience,
+		"sub":   userID.String(),
+		"roles": roles,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(15 * time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, testAccessSecret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// staffToken выпускает токен сотрудника поддержки, которому доступны любые заказы.
+func staffToken(t *testing.T) string {
+	t.Helper()
+	return accessToken(t, uuid.Must(uuid.NewV4()), authz.RoleSupport)
+}
+
+func requestWithToken(method, path, token string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+func TestOrderHandler_GetOrderByID_Ownership(t *testing.T) {
+	mockService := new(MockOrderService)
+	owner := uuid.Must(uuid.NewV4())
+	stranger := uuid.Must(uuid.NewV4())
+	found := &order.Order{ID: uuid.Must(uuid.NewV4()), UserID: owner, Status: order.StatusNew}
+	mockService.On("GetOrderByID", mock.Anything, found.ID).Return(found, nil)
+	router := newOrderRouter(mockService)
+	path := "/orders/" + found.ID.String()
+
+	tests := map[string]struct {
+		token string
+		want  int
+	}{
+		"owner":          {token: accessToken(t, owner), want: http.StatusOK},
+		"support":        {token: accessToken(t, stranger, authz.RoleSupport), want: http.StatusOK},
+		"admin":          {token: accessToken(t, stranger, authz.RoleAdmin), want: http.StatusOK},
+		"other customer": {token: accessToken(t, stranger), want: http.StatusNotFound},
+		"no token":       {want: http.StatusUnauthorized},
+		"forged token":   {token: accessToken(t, owner) + "x", want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, requestWithToken(http.MethodGet, path, tt.token))
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.want == http.StatusNotFound {
+				assert.NotContains(t, rr.Body.String(), owner.String(), "another customer's order is not revealed")
+			}
+		})
+	}
+}
+
+func TestOrderHandler_GetOrdersByUserID_Ownership(t *testing.T) {
+	mockService := new(MockOrderService)
+	owner := uuid.Must(uuid.NewV4())
+	stranger := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrdersByUserID", mock.Anything, owner).Return([]order.Order{{ID: uuid.Must(uuid.NewV4()), UserID: owner}}, nil)
+	router := newOrderRouter(mockService)
+	path := "/users/" + owner.String() + "/orders"
+
+	tests := map[string]struct {
+		token string
+		want  int
+	}{
+		"owner":          {token: accessToken(t, owner), want: http.StatusOK},
+		"support":        {token: accessToken(t, stranger, authz.RoleSupport), want: http.StatusOK},
+		"other customer": {token: accessToken(t, stranger), want: http.StatusForbidden},
+		"no token":       {want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, requestWithToken(http.MethodGet, path, tt.token))
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "GetOrdersByUserID", 2)
+}
+
+func TestOrderHandler_UpdateOrderStatus_StaffOnly(t *testing.T) {
+	mockService := new(MockOrderService)
+	owner := uuid.Must(uuid.NewV4())
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("UpdateOrderStatus", mock.Anything, orderID, order.StatusCancelled).Return(nil)
+	router := newOrderRouter(mockService)
+
+	tests := map[string]struct {
+		token string
+		want  int
+	}{
+		"support":  {token: accessToken(t, uuid.Must(uuid.NewV4()), authz.RoleSupport), want: http.StatusNoContent},
+		"owner":    {token: accessToken(t, owner), want: http.StatusForbidden},
+		"no token": {want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := requestWithToken(http.MethodPatch, "/orders/"+orderID.String()+"/status", tt.token)
+			req.Body = io.NopCloser(strings.NewReader(`{"status":"CANCELLED"}`))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "UpdateOrderStatus", 1)
+}
+
+func TestRequireAccessToken_NoVerifierFailsClosed(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := chi.NewRouter()
+	orderHandler.NewOrderHandler(mockService, nil, testInternalToken).RegisterRoutes(router)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, requestWithToken(http.MethodGet, "/orders/"+uuid.Must(uuid.NewV4()).String(), staffToken(t)))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	mockService.AssertNotCalled(t, "GetOrderByID", mock.Anything, mock.Anything)
+}
+
+func TestOrderHandler_CreateOrder_Ownership(t *testing.T) {
+	customer := uuid.Must(uuid.NewV4())
+	item := `"order_items":[{"product_id":"` + uuid.Must(uuid.NewV4()).String() + `","quantity":1,"price_per_unit":5}]`
+
+	tests := map[string]struct {
+		body      string
+		token     string
+		want      int
+		wantOwner uuid.UUID
+	}{
+		"owner without user_id":      {body: `{` + item + `}`, token: accessToken(t, customer), want: http.StatusCreated, wantOwner: customer},
+		"owner with own user_id":     {body: `{"user_id":"` + customer.String() + `",` + item + `}`, token: accessToken(t, customer), want: http.StatusCreated, wantOwner: customer},
+		"staff for a customer":       {body: `{"user_id":"` + customer.String() + `",` + item + `}`, token: staffToken(t), want: http.StatusCreated, wantOwner: customer},
+		"another customer's user_id": {body: `{"user_id":"` + customer.String() + `",` + item + `}`, token: accessToken(t, uuid.Must(uuid.NewV4())), want: http.StatusForbidden},
+		"no token":                   {body: `{"user_id":"` + customer.String() + `",` + item + `}`, want: http.StatusUnauthorized},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockOrderService)
+			mockService.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *order.Order) bool { return o.UserID == tt.wantOwner })).
+				Return(&order.Order{ID: uuid.Must(uuid.NewV4()), UserID: tt.wantOwner}, nil).Maybe()
+
+			req := requestWithToken(http.MethodPost, "/orders", tt.token)
+			req.Body = io.NopCloser(strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			newOrderRouter(mockService).ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.want, rr.Code)
+			if tt.want != http.StatusCreated {
+				mockService.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
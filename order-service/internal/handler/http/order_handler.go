@@ -0,0 +1,215 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type CreateOrderItemRequest struct {
+	ProductID    uuid.UUID `json:"product_id" validate:"required"`
+	Quantity     int       `json:"quantity" validate:"gt=0"`
+	PricePerUnit float64   `json:"price_per_unit" validate:"gte=0"`
+	WeightGrams  int       `json:"weight_grams" validate:"gte=0"`
+}
+
+type CreateOrderRequest struct {
+	UserID              uuid.UUID                `json:"user_id"` // Покупатель; по умолчанию владелец токена. Чужой ID может указать только поддержка
+	OrderItems          []CreateOrderItemRequest `json:"order_items" validate:"required,min=1,dive"`
+	ShippingAddressText string                   `json:"shipping_address_text"`
+}
+
+type UpdateOrderStatusRequest struct {
+	Status order.OrderStatus `json:"status" validate:"required"`
+}
+
+type BulkUpdateOrderStatusRequest struct {
+	OrderIDs []uuid.UUID       `json:"order_ids" validate:"required,min=1,max=1000"`
+	Status   order.OrderStatus `json:"status" validate:"required"`
+}
+
+type BulkUpdateOrderStatusResponse struct {
+	Results []order.BulkUpdateResult        `json:"results"`
+	Summary map[order.BulkUpdateOutcome]int `json:"summary"` // Количество заказов по каждому исходу
+}
+
+// ActiveOrdersResponse - ответ на проверку user-service перед удалением пользователя.
+type ActiveOrdersResponse struct {
+	UserID       uuid.UUID `json:"user_id"`
+	ActiveOrders int       `json:"active_orders"` // Заказы в статусах от NEW до SHIPPED
+}
+
+// OrderHandler обслуживает заказы. Покупатель читает только свои заказы, поддержка и администраторы - любые.
+type OrderHandler struct {
+	service       order.Service
+	verifier      *authz.Verifier
+	internalToken string // Токен маршрутов /internal, которые вызывает user-service
+	validate      *validator.Validate
+}
+
+// NewOrderHandler создаёт обработчик заказов. С nil verifier маршруты под токеном доступа отвечают 503.
+func NewOrderHandler(service order.Service, verifier *authz.Verifier, internalToken string) *OrderHandler {
+	return &OrderHandler{
+		service:       service,
+		verifier:      verifier,
+		internalToken: internalToken,
+		validate:      validator.New(),
+	}
+}
+
+func (h *OrderHandler) RegisterRoutes(router chi.Router) {
+	authenticated := router.With(requireAccessToken(h.verifier))
+	authenticated.Post("/orders", h.handleCreateOrder)
+	authenticated.Get("/orders/{id}", h.handleGetOrderByID)
+	authenticated.With(requireStaff).Patch("/orders/{id}/status", h.handleUpdateOrderStatus)
+	authenticated.With(requireStaff).Patch("/orders/status", h.handleBulkUpdateOrderStatus)
+	authenticated.Get("/users/{userID}/orders", h.handleGetOrdersByUserID)
+	router.With(requireBearerToken(h.internalToken)).Get("/internal/users/{userID}/active-orders", h.handleGetActiveOrders)
+}
+
+func (h *OrderHandler) handleCreateOrder(w http.ResponseWriter, r *http.Request) {
+	var requestPayload CreateOrderRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	principal, _ := authz.FromContext(r.Context())
+	customerID := principal.UserID
+	if requestPayload.UserID != uuid.Nil && requestPayload.UserID != principal.UserID {
+		if !principal.IsStaff() {
+			log.Warn().Stringer("user_id", principal.UserID).Stringer("customer_id", requestPayload.UserID).Msg("Attempt to create an order for another customer")
+			respondWithError(w, http.StatusForbidden, "Orders can only be created for yourself")
+			return
+		}
+		customerID = requestPayload.UserID
+	}
+
+	domainOrder := order.Order{
+		UserID:              customerID,
+		ShippingAddressText: requestPayload.ShippingAddressText,
+		OrderItems:          make([]order.OrderItem, 0, len(requestPayload.OrderItems)),
+	}
+	for _, item := range requestPayload.OrderItems {
+		domainOrder.OrderItems = append(domainOrder.OrderItems, order.OrderItem{
+			ProductID:    item.ProductID,
+			Quantity:     item.Quantity,
+			PricePerUnit: item.PricePerUnit,
+			WeightGrams:  item.WeightGrams,
+		})
+	}
+
+	createdOrder, err := h.service.CreateOrder(r.Context(), &domainOrder)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create order via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to create order"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, createdOrder)
+}
+
+func (h *OrderHandler) handleGetOrderByID(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	foundOrder, err := h.service.GetOrderByID(r.Context(), orderID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to get order by id via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get order"))
+		return
+	}
+	// Чужой заказ неотличим от несуществующего: ответ не подтверждает, что заказ с таким ID есть
+	if !canAccess(r, foundOrder.UserID) {
+		log.Warn().Stringer("order_id", orderID).Msg("Access to another customer's order denied")
+		respondWithError(w, http.StatusNotFound, "Order not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, foundOrder)
+}
+
+func (h *OrderHandler) handleGetOrdersByUserID(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUUIDParam(w, r, "userID")
+	if !ok {
+		return
+	}
+	if !canAccess(r, userID) {
+		log.Warn().Stringer("user_id", userID).Msg("Access to another customer's orders denied")
+		respondWithError(w, http.StatusForbidden, "Access to orders of another user is forbidden")
+		return
+	}
+
+	orders, err := h.service.GetOrdersByUserID(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to get user orders via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get user orders"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, orders)
+}
+
+func (h *OrderHandler) handleGetActiveOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseUUIDParam(w, r, "userID")
+	if !ok {
+		return
+	}
+
+	count, err := h.service.CountActiveOrders(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to count active orders via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to count active orders"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ActiveOrdersResponse{UserID: userID, ActiveOrders: count})
+}
+
+func (h *OrderHandler) handleUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload UpdateOrderStatusRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	err := h.service.UpdateOrderStatus(r.Context(), orderID, requestPayload.Status)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to update order status via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to update order status"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *OrderHandler) handleBulkUpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var requestPayload BulkUpdateOrderStatusRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	results, err := h.service.BulkUpdateStatus(r.Context(), requestPayload.OrderIDs, requestPayload.Status)
+	if err != nil {
+		log.Error().Err(err).Int("orders_count", len(requestPayload.OrderIDs)).Msg("Failed to bulk update order status via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to update order statuses"))
+		return
+	}
+
+	summary := make(map[order.BulkUpdateOutcome]int)
+	for _, result := range results {
+		summary[result.Outcome]++
+	}
+
+	respondWithJSON(w, http.StatusOK, BulkUpdateOrderStatusResponse{Results: results, Summary: summary})
+}
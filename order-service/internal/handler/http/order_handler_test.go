@@ -0,0 +1,364 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+func newOrderRouter(service order.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewOrderHandler(service, authz.NewVerifier(testAccessSecret), testInternalToken).RegisterRoutes(router)
+	return router
+}
+
+func TestOrderHandler_handleCreateOrder_Success(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	requestDTO := orderHandler.CreateOrderRequest{
+		UserID: uuid.Must(uuid.NewV4()),
+		OrderItems: []orderHandler.CreateOrderItemRequest{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, PricePerUnit: 5},
+		},
+		ShippingAddressText: "Test Address",
+	}
+
+	createdOrder := &order.Order{
+		ID:          uuid.Must(uuid.NewV4()),
+		UserID:      requestDTO.UserID,
+		Status:      order.StatusNew,
+		TotalAmount: 10,
+	}
+
+	mockService.On("CreateOrder", mock.Anything, mock.MatchedBy(func(o *order.Order) bool {
+		return o.UserID == requestDTO.UserID &&
+			len(o.OrderItems) == 1 &&
+			o.OrderItems[0].Quantity == 2 &&
+			o.ShippingAddressText == requestDTO.ShippingAddressText
+	})).Return(createdOrder, nil).Once()
+
+	jsonBody, err := json.Marshal(requestDTO)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, requestDTO.UserID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var actualResponse order.Order
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, createdOrder.ID, actualResponse.ID)
+	assert.Equal(t, order.StatusNew, actualResponse.Status)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleCreateOrder_CustomerRejected(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "email not verified", err: order.ErrEmailNotVerified, wantCode: http.StatusForbidden, wantMessage: "customer email is not verified"},
+		{name: "unknown customer", err: order.ErrCustomerNotFound, wantCode: http.StatusUnprocessableEntity, wantMessage: "customer not found"},
+		{
+			name:        "user-service unavailable",
+			err:         fmt.Errorf("%w: %w", order.ErrCustomerCheckFailed, errors.New("dial tcp 10.0.0.7:8080: connection refused")),
+			wantCode:    http.StatusServiceUnavailable,
+			wantMessage: "failed to check customer",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockOrderService)
+			mockService.On("CreateOrder", mock.Anything, mock.Anything).Return(nil, tt.err).Once()
+
+			body := `{"order_items":[{"product_id":"` + uuid.Must(uuid.NewV4()).String() + `","quantity":1,"price_per_unit":5}]}`
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+accessToken(t, uuid.Must(uuid.NewV4())))
+			rr := httptest.NewRecorder()
+			newOrderRouter(mockService).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantCode, rr.Code)
+			assert.JSONEq(t, `{"error":"`+tt.wantMessage+`"}`, rr.Body.String())
+		})
+	}
+}
+
+func TestOrderHandler_handleCreateOrder_ValidationError(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	userID := uuid.Must(uuid.NewV4())
+	reqBody := []byte(`{"user_id":"` + userID.String() + `","order_items":[]}`)
+	req := httptest.NewRequest(http.MethodPost, "/orders", bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var errorResponse orderHandler.ValidationErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errorResponse))
+	assert.Equal(t, "Validation failed", errorResponse.Error)
+	assert.Contains(t, errorResponse.Details, "OrderItems")
+	mockService.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+}
+
+func TestOrderHandler_handleGetOrderByID_NotFound(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("GetOrderByID", mock.Anything, orderID).Return(nil, order.ErrOrderNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"order not found"}`, rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleGetOrderByID_InvalidUUID(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/not-a-uuid", nil)
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "GetOrderByID", mock.Anything, mock.Anything)
+}
+
+func TestOrderHandler_handleGetOrdersByUserID_Success(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	userID := uuid.Must(uuid.NewV4())
+	orders := []order.Order{
+		{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: order.StatusPaid},
+		{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: order.StatusNew},
+	}
+	mockService.On("GetOrdersByUserID", mock.Anything, userID).Return(orders, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var actualResponse []order.Order
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Len(t, actualResponse, 2)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleGetActiveOrders(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("CountActiveOrders", mock.Anything, userID).Return(2, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/"+userID.String()+"/active-orders", nil)
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"user_id":"`+userID.String()+`","active_orders":2}`, rr.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleGetActiveOrders_RequiresInternalToken(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+	userID := uuid.Must(uuid.NewV4())
+
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/"+userID.String()+"/active-orders", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	// Публичного маршрута больше нет
+	req = httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/active-orders", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertNotCalled(t, "CountActiveOrders", mock.Anything, mock.Anything)
+}
+
+func TestOrderHandler_handleUpdateOrderStatus_InvalidTransition(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("UpdateOrderStatus", mock.Anything, orderID, order.StatusNew).
+		Return(fmt.Errorf("wrapped: %w", order.ErrInvalidStatusTransition)).Once()
+
+	req := httptest.NewRequest(http.MethodPatch, "/orders/"+orderID.String()+"/status", bytes.NewBufferString(`{"status":"NEW"}`))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleUpdateOrderStatus_Success(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("UpdateOrderStatus", mock.Anything, orderID, order.StatusProcessing).Return(nil).Once()
+
+	req := httptest.NewRequest(http.MethodPatch, "/orders/"+orderID.String()+"/status", bytes.NewBufferString(`{"status":"PROCESSING"}`))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleBulkUpdateOrderStatus_Success(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	updatedID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+	results := []order.BulkUpdateResult{
+		{OrderID: updatedID, Outcome: order.BulkUpdated, PreviousStatus: order.StatusPaid},
+		{OrderID: missingID, Outcome: order.BulkNotFound},
+	}
+	mockService.On("BulkUpdateStatus", mock.Anything, []uuid.UUID{updatedID, missingID}, order.StatusShipped).Return(results, nil).Once()
+
+	reqBody := `{"order_ids":["` + updatedID.String() + `","` + missingID.String() + `"],"status":"SHIPPED"}`
+	req := httptest.NewRequest(http.MethodPatch, "/orders/status", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var actualResponse orderHandler.BulkUpdateOrderStatusResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, results, actualResponse.Results)
+	assert.Equal(t, map[order.BulkUpdateOutcome]int{order.BulkUpdated: 1, order.BulkNotFound: 1}, actualResponse.Summary)
+	mockService.AssertExpectations(t)
+}
+
+func TestOrderHandler_handleBulkUpdateOrderStatus_EmptyIDs(t *testing.T) {
+	mockService := new(MockOrderService)
+	router := newOrderRouter(mockService)
+
+	req := httptest.NewRequest(http.MethodPatch, "/orders/status", bytes.NewBufferString(`{"order_ids":[],"status":"SHIPPED"}`))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "BulkUpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
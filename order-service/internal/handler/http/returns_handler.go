@@ -0,0 +1,187 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/returns"
+)
+
+type ReturnItemRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+	Quantity    int       `json:"quantity" validate:"gt=0"`
+}
+
+type CreateReturnRequest struct {
+	Reason string              `json:"reason" validate:"required"`
+	Items  []ReturnItemRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+type ResolveReturnRequest struct {
+	Note string `json:"note"`
+}
+
+// ReturnsHandler обслуживает возвраты. Покупатель оформляет и видит возвраты своих заказов, решения принимают сотрудники.
+type ReturnsHandler struct {
+	service  returns.Service
+	verifier *authz.Verifier
+	orders   OrderOwners
+	validate *validator.Validate
+}
+
+func NewReturnsHandler(service returns.Service, verifier *authz.Verifier, orders OrderOwners) *ReturnsHandler {
+	return &ReturnsHandler{
+		service:  service,
+		verifier: verifier,
+		orders:   orders,
+		validate: validator.New(),
+	}
+}
+
+func (h *ReturnsHandler) RegisterRoutes(router chi.Router) {
+	authenticated := router.With(requireAccessToken(h.verifier))
+	authenticated.With(requireOrderAccess(h.orders, "id")).Post("/orders/{id}/returns", h.handleRequestReturn)
+	authenticated.With(requireOrderAccess(h.orders, "id")).Get("/orders/{id}/returns", h.handleGetReturnsByOrder)
+	authenticated.Get("/returns/{id}", h.handleGetReturn)
+	// Действия сотрудников склада и поддержки
+	staff := authenticated.With(requireStaff)
+	staff.Post("/returns/{id}/approve", h.handleApprove)
+	staff.Post("/returns/{id}/reject", h.handleReject)
+	staff.Post("/returns/{id}/receive", h.handleReceive)
+	staff.Post("/returns/{id}/refund", h.handleRefund)
+}
+
+func (h *ReturnsHandler) handleRequestReturn(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload CreateReturnRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	input := returns.Return{
+		OrderID: orderID,
+		Reason:  requestPayload.Reason,
+		Items:   make([]returns.Item, 0, len(requestPayload.Items)),
+	}
+	for _, item := range requestPayload.Items {
+		input.Items = append(input.Items, returns.Item{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	created, err := h.service.RequestReturn(r.Context(), &input)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to request return via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to request return"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *ReturnsHandler) handleGetReturnsByOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := h.service.GetReturnsByOrderID(r.Context(), orderID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to get returns via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get returns"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, found)
+}
+
+func (h *ReturnsHandler) handleGetReturn(w http.ResponseWriter, r *http.Request) {
+	returnID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	found, err := h.service.GetReturn(r.Context(), returnID)
+	if err != nil {
+		log.Error().Err(err).Stringer("return_id", returnID).Msg("Failed to get return via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get return"))
+		return
+	}
+	if !canAccess(r, found.UserID) {
+		log.Warn().Stringer("return_id", returnID).Msg("Access to another customer's return denied")
+		respondWithError(w, http.StatusNotFound, "Return not found")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, found)
+}
+
+func (h *ReturnsHandler) handleApprove(w http.ResponseWriter, r *http.Request) {
+	returnID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload ResolveReturnRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	updated, err := h.service.Approve(r.Context(), returnID, requestPayload.Note)
+	h.respondWithReturn(w, returnID, "approve", updated, err)
+}
+
+func (h *ReturnsHandler) handleReject(w http.ResponseWriter, r *http.Request) {
+	returnID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload ResolveReturnRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	updated, err := h.service.Reject(r.Context(), returnID, requestPayload.Note)
+	h.respondWithReturn(w, returnID, "reject", updated, err)
+}
+
+func (h *ReturnsHandler) handleReceive(w http.ResponseWriter, r *http.Request) {
+	returnID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.Receive(r.Context(), returnID)
+	h.respondWithReturn(w, returnID, "receive", updated, err)
+}
+
+func (h *ReturnsHandler) handleRefund(w http.ResponseWriter, r *http.Request) {
+	returnID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	updated, err := h.service.Refund(r.Context(), returnID)
+	h.respondWithReturn(w, returnID, "refund", updated, err)
+}
+
+// respondWithReturn отправляет результат действия над возвратом: обновлённый возврат или ошибку.
+func (h *ReturnsHandler) respondWithReturn(w http.ResponseWriter, returnID uuid.UUID, action string, updated *returns.Return, err error) {
+	if err != nil {
+		log.Error().Err(err).Stringer("return_id", returnID).Str("action", action).Msg("Failed to update return via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to "+action+" return"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
@@ -0,0 +1,207 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/returns"
+)
+
+type MockReturnsService struct {
+	mock.Mock
+}
+
+func (m *MockReturnsService) RequestReturn(ctx context.Context, input *returns.Return) (*returns.Return, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) GetReturn(ctx context.Context, id uuid.UUID) (*returns.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) GetReturnsByOrderID(ctx context.Context, orderID uuid.UUID) ([]returns.Return, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) Approve(ctx context.Context, id uuid.UUID, note string) (*returns.Return, error) {
+	args := m.Called(ctx, id, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) Reject(ctx context.Context, id uuid.UUID, note string) (*returns.Return, error) {
+	args := m.Called(ctx, id, note)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) Receive(ctx context.Context, id uuid.UUID) (*returns.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func (m *MockReturnsService) Refund(ctx context.Context, id uuid.UUID) (*returns.Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*returns.Return), args.Error(1)
+}
+
+func newReturnsRouter(service returns.Service, orders order.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewReturnsHandler(service, authz.NewVerifier(testAccessSecret), orders).RegisterRoutes(router)
+	return router
+}
+
+func TestReturnsHandler_handleRequestReturn_Success(t *testing.T) {
+	mockService := new(MockReturnsService)
+	mockOrders := new(MockOrderService)
+	router := newReturnsRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil).Once()
+	itemID := uuid.Must(uuid.NewV4())
+	created := &returns.Return{ID: uuid.Must(uuid.NewV4()), OrderID: orderID, Status: returns.StatusRequested}
+
+	mockService.On("RequestReturn", mock.Anything, mock.MatchedBy(func(r *returns.Return) bool {
+		return r.OrderID == orderID && r.Reason == "damaged" && len(r.Items) == 1 && r.Items[0].OrderItemID == itemID
+	})).Return(created, nil).Once()
+
+	reqBody := `{"reason":"damaged","items":[{"order_item_id":"` + itemID.String() + `","quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID.String()+"/returns", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, ownerID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var actualResponse returns.Return
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, created.ID, actualResponse.ID)
+	mockService.AssertExpectations(t)
+}
+
+func TestReturnsHandler_handleRequestReturn_WindowExpired(t *testing.T) {
+	mockService := new(MockReturnsService)
+	mockOrders := new(MockOrderService)
+	router := newReturnsRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil).Once()
+	mockService.On("RequestReturn", mock.Anything, mock.Anything).Return(nil, returns.ErrReturnWindowExpired).Once()
+
+	reqBody := `{"reason":"late","items":[{"order_item_id":"` + uuid.Must(uuid.NewV4()).String() + `","quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID.String()+"/returns", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, ownerID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error":"return window has expired"}`, rr.Body.String())
+}
+
+func TestReturnsHandler_handleReceive_RefundFailed(t *testing.T) {
+	mockService := new(MockReturnsService)
+	router := newReturnsRouter(mockService, new(MockOrderService))
+
+	returnID := uuid.Must(uuid.NewV4())
+	mockService.On("Receive", mock.Anything, returnID).Return(nil, returns.ErrRefundFailed).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/returns/"+returnID.String()+"/receive", nil)
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadGateway, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestReturnsHandler_handleApprove_Success(t *testing.T) {
+	mockService := new(MockReturnsService)
+	router := newReturnsRouter(mockService, new(MockOrderService))
+
+	returnID := uuid.Must(uuid.NewV4())
+	mockService.On("Approve", mock.Anything, returnID, "label sent").
+		Return(&returns.Return{ID: returnID, Status: returns.StatusApproved}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/returns/"+returnID.String()+"/approve", bytes.NewBufferString(`{"note":"label sent"}`))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestReturnsHandler_Access(t *testing.T) {
+	mockService := new(MockReturnsService)
+	mockOrders := new(MockOrderService)
+	router := newReturnsRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	found := &returns.Return{ID: uuid.Must(uuid.NewV4()), OrderID: orderID, UserID: ownerID, Status: returns.StatusRequested}
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil)
+	mockService.On("GetReturnsByOrderID", mock.Anything, orderID).Return([]returns.Return{*found}, nil)
+	mockService.On("GetReturn", mock.Anything, found.ID).Return(found, nil)
+	stranger := accessToken(t, uuid.Must(uuid.NewV4()))
+
+	tests := map[string]struct {
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		"owner lists returns":          {method: http.MethodGet, path: "/orders/" + orderID.String() + "/returns", token: accessToken(t, ownerID), want: http.StatusOK},
+		"other customer lists returns": {method: http.MethodGet, path: "/orders/" + orderID.String() + "/returns", token: stranger, want: http.StatusNotFound},
+		"owner reads return":           {method: http.MethodGet, path: "/returns/" + found.ID.String(), token: accessToken(t, ownerID), want: http.StatusOK},
+		"support reads return":         {method: http.MethodGet, path: "/returns/" + found.ID.String(), token: staffToken(t), want: http.StatusOK},
+		"other customer reads return":  {method: http.MethodGet, path: "/returns/" + found.ID.String(), token: stranger, want: http.StatusNotFound},
+		"no token":                     {method: http.MethodGet, path: "/returns/" + found.ID.String(), want: http.StatusUnauthorized},
+		"owner cannot refund":          {method: http.MethodPost, path: "/returns/" + found.ID.String() + "/refund", token: accessToken(t, ownerID), want: http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, requestWithToken(tt.method, tt.path, tt.token))
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "GetReturnsByOrderID", 1)
+	mockService.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything)
+}
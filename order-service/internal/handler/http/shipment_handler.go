@@ -0,0 +1,164 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+)
+
+type ShipmentItemRequest struct {
+	OrderItemID uuid.UUID `json:"order_item_id" validate:"required"`
+	Quantity    int       `json:"quantity" validate:"gt=0"`
+}
+
+type CreateShipmentRequest struct {
+	Carrier        shipment.Carrier      `json:"carrier" validate:"required"`
+	TrackingNumber string                `json:"tracking_number" validate:"required"`
+	ShippedAt      *time.Time            `json:"shipped_at,omitempty"`
+	Items          []ShipmentItemRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+type ShipmentEventRequest struct {
+	Type        shipment.EventType `json:"type" validate:"required"`
+	Location    string             `json:"location,omitempty"`
+	Description string             `json:"description,omitempty"`
+	OccurredAt  *time.Time         `json:"occurred_at,omitempty"`
+}
+
+// ShipmentHandler обслуживает отправления. Покупатель видит отправления своих заказов, создают их и отмечают события сотрудники.
+type ShipmentHandler struct {
+	service  shipment.Service
+	verifier *authz.Verifier
+	orders   OrderOwners
+	validate *validator.Validate
+}
+
+func NewShipmentHandler(service shipment.Service, verifier *authz.Verifier, orders OrderOwners) *ShipmentHandler {
+	return &ShipmentHandler{
+		service:  service,
+		verifier: verifier,
+		orders:   orders,
+		validate: validator.New(),
+	}
+}
+
+func (h *ShipmentHandler) RegisterRoutes(router chi.Router) {
+	authenticated := router.With(requireAccessToken(h.verifier))
+	authenticated.With(requireStaff).Post("/orders/{id}/shipments", h.handleCreateShipment)
+	authenticated.With(requireOrderAccess(h.orders, "id")).Get("/orders/{id}/shipments", h.handleGetShipments)
+	authenticated.With(requireOrderAccess(h.orders, "id")).Get("/orders/{id}/shipments/{shipmentID}", h.handleGetShipment)
+	authenticated.With(requireStaff).Post("/orders/{id}/shipments/{shipmentID}/events", h.handleRecordEvent)
+}
+
+func (h *ShipmentHandler) handleCreateShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload CreateShipmentRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	input := shipment.Shipment{
+		OrderID:        orderID,
+		Carrier:        requestPayload.Carrier,
+		TrackingNumber: requestPayload.TrackingNumber,
+		Items:          make([]shipment.Item, 0, len(requestPayload.Items)),
+	}
+	if requestPayload.ShippedAt != nil {
+		input.ShippedAt = requestPayload.ShippedAt.UTC()
+	}
+	for _, item := range requestPayload.Items {
+		input.Items = append(input.Items, shipment.Item{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	created, err := h.service.CreateShipment(r.Context(), &input)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to create shipment via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to create shipment"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *ShipmentHandler) handleGetShipments(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	shipments, err := h.service.GetShipmentsByOrderID(r.Context(), orderID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to get shipments via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get shipments"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, shipments)
+}
+
+func (h *ShipmentHandler) handleGetShipment(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	shipmentID, ok := parseUUIDParam(w, r, "shipmentID")
+	if !ok {
+		return
+	}
+
+	found, err := h.service.GetShipment(r.Context(), orderID, shipmentID)
+	if err != nil {
+		log.Error().Err(err).Stringer("shipment_id", shipmentID).Msg("Failed to get shipment via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get shipment"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, found)
+}
+
+func (h *ShipmentHandler) handleRecordEvent(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	shipmentID, ok := parseUUIDParam(w, r, "shipmentID")
+	if !ok {
+		return
+	}
+
+	var requestPayload ShipmentEventRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	event := shipment.Event{
+		Type:        requestPayload.Type,
+		Location:    requestPayload.Location,
+		Description: requestPayload.Description,
+	}
+	if requestPayload.OccurredAt != nil {
+		event.OccurredAt = requestPayload.OccurredAt.UTC()
+	}
+
+	updated, err := h.service.RecordEvent(r.Context(), orderID, shipmentID, &event)
+	if err != nil {
+		log.Error().Err(err).Stringer("shipment_id", shipmentID).Msg("Failed to record shipment event via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to record shipment event"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, updated)
+}
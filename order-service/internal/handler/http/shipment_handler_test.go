@@ -0,0 +1,193 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+)
+
+type MockShipmentService struct {
+	mock.Mock
+}
+
+func (m *MockShipmentService) CreateShipment(ctx context.Context, input *shipment.Shipment) (*shipment.Shipment, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) GetShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*shipment.Shipment, error) {
+	args := m.Called(ctx, orderID, shipmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) GetShipmentsByOrderID(ctx context.Context, orderID uuid.UUID) ([]shipment.Shipment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) RecordEvent(ctx context.Context, orderID, shipmentID uuid.UUID, event *shipment.Event) (*shipment.Shipment, error) {
+	args := m.Called(ctx, orderID, shipmentID, event)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+func newShipmentRouter(service shipment.Service, orders order.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewShipmentHandler(service, authz.NewVerifier(testAccessSecret), orders).RegisterRoutes(router)
+	return router
+}
+
+func TestShipmentHandler_handleCreateShipment_Success(t *testing.T) {
+	mockService := new(MockShipmentService)
+	router := newShipmentRouter(mockService, new(MockOrderService))
+
+	orderID := uuid.Must(uuid.NewV4())
+	orderItemID := uuid.Must(uuid.NewV4())
+	requestDTO := orderHandler.CreateShipmentRequest{
+		Carrier:        shipment.CarrierDHL,
+		TrackingNumber: "JD0001",
+		Items:          []orderHandler.ShipmentItemRequest{{OrderItemID: orderItemID, Quantity: 1}},
+	}
+
+	created := &shipment.Shipment{
+		ID:             uuid.Must(uuid.NewV4()),
+		OrderID:        orderID,
+		Carrier:        shipment.CarrierDHL,
+		TrackingNumber: "JD0001",
+		Status:         shipment.StatusInTransit,
+	}
+	mockService.On("CreateShipment", mock.Anything, mock.MatchedBy(func(s *shipment.Shipment) bool {
+		return s.OrderID == orderID && len(s.Items) == 1 && s.Items[0].OrderItemID == orderItemID
+	})).Return(created, nil).Once()
+
+	jsonBody, err := json.Marshal(requestDTO)
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID.String()+"/shipments", bytes.NewBuffer(jsonBody))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	var actualResponse shipment.Shipment
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, created.ID, actualResponse.ID)
+	mockService.AssertExpectations(t)
+}
+
+func TestShipmentHandler_handleCreateShipment_QuantityExceeded(t *testing.T) {
+	mockService := new(MockShipmentService)
+	router := newShipmentRouter(mockService, new(MockOrderService))
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockService.On("CreateShipment", mock.Anything, mock.Anything).Return(nil, shipment.ErrQuantityExceeded).Once()
+
+	reqBody := `{"carrier":"DHL","tracking_number":"JD1","items":[{"order_item_id":"` + uuid.Must(uuid.NewV4()).String() + `","quantity":5}]}`
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID.String()+"/shipments", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestShipmentHandler_handleRecordEvent_Success(t *testing.T) {
+	mockService := new(MockShipmentService)
+	router := newShipmentRouter(mockService, new(MockOrderService))
+
+	orderID := uuid.Must(uuid.NewV4())
+	shipmentID := uuid.Must(uuid.NewV4())
+	updated := &shipment.Shipment{ID: shipmentID, OrderID: orderID, Status: shipment.StatusDelivered}
+
+	mockService.On("RecordEvent", mock.Anything, orderID, shipmentID, mock.MatchedBy(func(e *shipment.Event) bool {
+		return e.Type == shipment.EventDelivered && e.Location == "Berlin"
+	})).Return(updated, nil).Once()
+
+	reqBody := `{"type":"DELIVERED","location":"Berlin"}`
+	req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID.String()+"/shipments/"+shipmentID.String()+"/events", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+staffToken(t))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestShipmentHandler_handleGetShipment_NotFound(t *testing.T) {
+	mockService := new(MockShipmentService)
+	mockOrders := new(MockOrderService)
+	router := newShipmentRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	shipmentID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil).Once()
+	mockService.On("GetShipment", mock.Anything, orderID, shipmentID).Return(nil, shipment.ErrShipmentNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String()+"/shipments/"+shipmentID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, ownerID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestShipmentHandler_Access(t *testing.T) {
+	mockService := new(MockShipmentService)
+	mockOrders := new(MockOrderService)
+	router := newShipmentRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil)
+	mockService.On("GetShipmentsByOrderID", mock.Anything, orderID).Return([]shipment.Shipment{}, nil)
+	listPath := "/orders/" + orderID.String() + "/shipments"
+
+	tests := map[string]struct {
+		method string
+		token  string
+		want   int
+	}{
+		"owner lists shipments":         {method: http.MethodGet, token: accessToken(t, ownerID), want: http.StatusOK},
+		"support lists shipments":       {method: http.MethodGet, token: staffToken(t), want: http.StatusOK},
+		"other customer lists":          {method: http.MethodGet, token: accessToken(t, uuid.Must(uuid.NewV4())), want: http.StatusNotFound},
+		"no token":                      {method: http.MethodGet, want: http.StatusUnauthorized},
+		"owner cannot create shipments": {method: http.MethodPost, token: accessToken(t, ownerID), want: http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, requestWithToken(tt.method, listPath, tt.token))
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	mockService.AssertNumberOfCalls(t, "GetShipmentsByOrderID", 2)
+	mockService.AssertNotCalled(t, "CreateShipment", mock.Anything, mock.Anything)
+}
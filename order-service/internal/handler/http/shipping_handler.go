@@ -0,0 +1,140 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipping"
+)
+
+type AddressRequest struct {
+	Country    string `json:"country" validate:"required,len=2"`
+	PostalCode string `json:"postal_code"`
+	City       string `json:"city"`
+}
+
+type CartItemRequest struct {
+	Quantity     int     `json:"quantity" validate:"gt=0"`
+	WeightGrams  int     `json:"weight_grams" validate:"gte=0"`
+	PricePerUnit float64 `json:"price_per_unit" validate:"gte=0"`
+}
+
+type CartQuoteRequest struct {
+	Address AddressRequest    `json:"address" validate:"required"`
+	Items   []CartItemRequest `json:"items" validate:"required,min=1,dive"`
+}
+
+type OrderQuoteRequest struct {
+	Address AddressRequest `json:"address" validate:"required"`
+}
+
+type SelectShippingRequest struct {
+	OptionID string         `json:"option_id" validate:"required"`
+	Address  AddressRequest `json:"address" validate:"required"`
+}
+
+type ShippingQuoteResponse struct {
+	Options []shipping.Option `json:"options"`
+}
+
+type ShippingHandler struct {
+	service  shipping.Service
+	verifier *authz.Verifier
+	orders   OrderOwners
+	validate *validator.Validate
+}
+
+func NewShippingHandler(service shipping.Service, verifier *authz.Verifier, orders OrderOwners) *ShippingHandler {
+	return &ShippingHandler{
+		service:  service,
+		verifier: verifier,
+		orders:   orders,
+		validate: validator.New(),
+	}
+}
+
+func (h *ShippingHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/shipping/quotes", h.handleQuoteCart)
+	// Доставку заказа выбирает его владелец или поддержка
+	owner := router.With(requireAccessToken(h.verifier), requireOrderAccess(h.orders, "id"))
+	owner.Post("/orders/{id}/shipping/quotes", h.handleQuoteOrder)
+	owner.Put("/orders/{id}/shipping", h.handleSelectOption)
+}
+
+func (r AddressRequest) toDomain() shipping.Address {
+	return shipping.Address{
+		Country:    r.Country,
+		PostalCode: r.PostalCode,
+		City:       r.City,
+	}
+}
+
+func (h *ShippingHandler) handleQuoteCart(w http.ResponseWriter, r *http.Request) {
+	var requestPayload CartQuoteRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	items := make([]shipping.CartItem, 0, len(requestPayload.Items))
+	for _, item := range requestPayload.Items {
+		items = append(items, shipping.CartItem{
+			Quantity:     item.Quantity,
+			WeightGrams:  item.WeightGrams,
+			PricePerUnit: item.PricePerUnit,
+		})
+	}
+
+	options, err := h.service.QuoteCart(r.Context(), requestPayload.Address.toDomain(), items)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to quote cart shipping via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to quote shipping"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ShippingQuoteResponse{Options: options})
+}
+
+func (h *ShippingHandler) handleQuoteOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload OrderQuoteRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	options, err := h.service.QuoteOrder(r.Context(), orderID, requestPayload.Address.toDomain())
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to quote order shipping via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to quote shipping"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ShippingQuoteResponse{Options: options})
+}
+
+func (h *ShippingHandler) handleSelectOption(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload SelectShippingRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	updatedOrder, err := h.service.SelectOption(r.Context(), orderID, requestPayload.OptionID, requestPayload.Address.toDomain())
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("Failed to select shipping option via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to select shipping option"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updatedOrder)
+}
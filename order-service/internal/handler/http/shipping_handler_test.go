@@ -0,0 +1,129 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipping"
+)
+
+type MockShippingService struct {
+	mock.Mock
+}
+
+func (m *MockShippingService) QuoteCart(ctx context.Context, address shipping.Address, items []shipping.CartItem) ([]shipping.Option, error) {
+	args := m.Called(ctx, address, items)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]shipping.Option), args.Error(1)
+}
+
+func (m *MockShippingService) QuoteOrder(ctx context.Context, orderID uuid.UUID, address shipping.Address) ([]shipping.Option, error) {
+	args := m.Called(ctx, orderID, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]shipping.Option), args.Error(1)
+}
+
+func (m *MockShippingService) SelectOption(ctx context.Context, orderID uuid.UUID, optionID string, address shipping.Address) (*order.Order, error) {
+	args := m.Called(ctx, orderID, optionID, address)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func newShippingRouter(service shipping.Service, orders order.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewShippingHandler(service, authz.NewVerifier(testAccessSecret), orders).RegisterRoutes(router)
+	return router
+}
+
+func TestShippingHandler_handleQuoteCart_Success(t *testing.T) {
+	mockService := new(MockShippingService)
+	router := newShippingRouter(mockService, new(MockOrderService))
+
+	options := []shipping.Option{{ID: "flat:standard", Provider: "flat", Price: 4.99}}
+	mockService.On("QuoteCart", mock.Anything, shipping.Address{Country: "DE", PostalCode: "10115"}, []shipping.CartItem{
+		{Quantity: 2, WeightGrams: 300, PricePerUnit: 12.5},
+	}).Return(options, nil).Once()
+
+	reqBody := `{"address":{"country":"DE","postal_code":"10115"},"items":[{"quantity":2,"weight_grams":300,"price_per_unit":12.5}]}`
+	req := httptest.NewRequest(http.MethodPost, "/shipping/quotes", bytes.NewBufferString(reqBody))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var actualResponse orderHandler.ShippingQuoteResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, options, actualResponse.Options)
+	mockService.AssertExpectations(t)
+}
+
+func TestShippingHandler_handleQuoteCart_InvalidCountry(t *testing.T) {
+	mockService := new(MockShippingService)
+	router := newShippingRouter(mockService, new(MockOrderService))
+
+	reqBody := `{"address":{"country":"Germany"},"items":[{"quantity":1}]}`
+	req := httptest.NewRequest(http.MethodPost, "/shipping/quotes", bytes.NewBufferString(reqBody))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "QuoteCart", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestShippingHandler_handleSelectOption_LockedOrder(t *testing.T) {
+	mockService := new(MockShippingService)
+	mockOrders := new(MockOrderService)
+	router := newShippingRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	ownerID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: ownerID}, nil).Once()
+	mockService.On("SelectOption", mock.Anything, orderID, "flat:standard", shipping.Address{Country: "DE"}).
+		Return(nil, order.ErrShippingLocked).Once()
+
+	reqBody := `{"option_id":"flat:standard","address":{"country":"DE"}}`
+	req := httptest.NewRequest(http.MethodPut, "/orders/"+orderID.String()+"/shipping", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, ownerID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestShippingHandler_handleSelectOption_ForeignOrder(t *testing.T) {
+	mockService := new(MockShippingService)
+	mockOrders := new(MockOrderService)
+	router := newShippingRouter(mockService, mockOrders)
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", mock.Anything, orderID).Return(&order.Order{ID: orderID, UserID: uuid.Must(uuid.NewV4())}, nil).Once()
+
+	reqBody := `{"option_id":"flat:standard","address":{"country":"DE"}}`
+	req := httptest.NewRequest(http.MethodPut, "/orders/"+orderID.String()+"/shipping", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, uuid.Must(uuid.NewV4())))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertNotCalled(t, "SelectOption", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
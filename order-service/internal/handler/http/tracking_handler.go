@@ -0,0 +1,133 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/tracking"
+)
+
+// sseRetryMillis - через сколько браузер переподключится после разрыва потока.
+const sseRetryMillis = 3000
+
+type TrackingHandler struct {
+	service           tracking.Service
+	verifier          *authz.Verifier
+	heartbeatInterval time.Duration
+}
+
+func NewTrackingHandler(service tracking.Service, verifier *authz.Verifier, heartbeatInterval time.Duration) *TrackingHandler {
+	return &TrackingHandler{service: service, verifier: verifier, heartbeatInterval: heartbeatInterval}
+}
+
+func (h *TrackingHandler) RegisterRoutes(router chi.Router) {
+	router.With(requireAccessToken(h.verifier)).Get("/orders/{id}/events", h.handleOrderEvents)
+}
+
+// handleOrderEvents отдаёт смены статуса заказа как Server-Sent Events.
+// Клиент, переподключаясь с заголовком Last-Event-ID, получает только пропущенные события.
+func (h *TrackingHandler) handleOrderEvents(w http.ResponseWriter, r *http.Request) {
+	orderID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	principal, ok := authz.FromContext(r.Context())
+	if !ok {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing access token")
+		return
+	}
+
+	var lastEventID int64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		var err error
+		lastEventID, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil || lastEventID < 0 {
+			respondWithError(w, http.StatusBadRequest, "Invalid Last-Event-ID header")
+			return
+		}
+	}
+
+	sub, err := h.service.Subscribe(r.Context(), orderID, principal, lastEventID)
+	if err != nil {
+		if r.Context().Err() != nil {
+			return // Клиент ушёл, пока мы готовили подписку
+		}
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to subscribe to order events"))
+		return
+	}
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	// Поток живёт дольше WriteTimeout сервера, поэтому снимаем дедлайн записи для этого запроса
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Warn().Err(err).Msg("Failed to disable write deadline for SSE stream")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Отключает буферизацию в nginx
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", sseRetryMillis); err != nil {
+		return
+	}
+	for _, event := range sub.Backlog {
+		if err := writeSSEEvent(w, event); err != nil {
+			return
+		}
+		lastEventID = event.ID
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Debug().Stringer("order_id", orderID).Msg("SSE client disconnected")
+			return
+		case <-heartbeat.C:
+			// Комментарий SSE не виден клиенту, но держит соединение открытым через прокси
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case event, open := <-sub.Live:
+			if !open {
+				// Клиент переподключится сам и дочитает пропущенное по Last-Event-ID
+				return
+			}
+			if event.ID <= lastEventID {
+				continue
+			}
+			if err := writeSSEEvent(w, event); err != nil {
+				return
+			}
+			lastEventID = event.ID
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event tracking.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal order event: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: status\ndata: %s\n\n", event.ID, data)
+	return err
+}
@@ -0,0 +1,167 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/tracking"
+)
+
+type MockTrackingService struct {
+	mock.Mock
+}
+
+func (m *MockTrackingService) Subscribe(ctx context.Context, orderID uuid.UUID, principal *authz.Principal, afterID int64) (*tracking.Subscription, error) {
+	args := m.Called(ctx, orderID, principal, afterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*tracking.Subscription), args.Error(1)
+}
+
+func newTrackingRouter(service tracking.Service, heartbeat time.Duration) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewTrackingHandler(service, authz.NewVerifier(testAccessSecret), heartbeat).RegisterRoutes(router)
+	return router
+}
+
+// principalOf сопоставляет владельца токена, переданного в сервис.
+func principalOf(userID uuid.UUID) any {
+	return mock.MatchedBy(func(p *authz.Principal) bool { return p.UserID == userID })
+}
+
+func TestTrackingHandler_handleOrderEvents_StreamsBacklogAndLiveEvents(t *testing.T) {
+	mockService := new(MockTrackingService)
+	router := newTrackingRouter(mockService, time.Minute)
+
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+
+	live := make(chan tracking.Event, 2)
+	live <- tracking.Event{ID: 4, OrderID: orderID, To: order.StatusShipped} // Уже отправлено из истории
+	live <- tracking.Event{ID: 5, OrderID: orderID, From: order.StatusShipped, To: order.StatusDelivered}
+	close(live) // Закрытый канал завершает поток, как при отключении отстающего подписчика
+
+	closed := false
+	mockService.On("Subscribe", mock.Anything, orderID, principalOf(userID), int64(3)).Return(&tracking.Subscription{
+		Backlog: []tracking.Event{{ID: 4, OrderID: orderID, From: order.StatusPaid, To: order.StatusShipped}},
+		Live:    live,
+		Close:   func() { closed = true },
+	}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String()+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, userID))
+	req.Header.Set("Last-Event-ID", "3")
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+
+	body := rr.Body.String()
+	assert.Equal(t, 1, strings.Count(body, "id: 4\n"), "duplicate live event must be skipped")
+	assert.Contains(t, body, "id: 5\nevent: status\ndata: {")
+	assert.Contains(t, body, `"to":"DELIVERED"`)
+	assert.Less(t, strings.Index(body, "id: 4\n"), strings.Index(body, "id: 5\n"))
+	assert.True(t, closed, "subscription must be released")
+	mockService.AssertExpectations(t)
+}
+
+func TestTrackingHandler_handleOrderEvents_SendsHeartbeatsUntilClientLeaves(t *testing.T) {
+	mockService := new(MockTrackingService)
+	router := newTrackingRouter(mockService, 10*time.Millisecond)
+
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("Subscribe", mock.Anything, orderID, principalOf(userID), int64(0)).Return(&tracking.Subscription{
+		Live:  make(chan tracking.Event),
+		Close: func() {},
+	}, nil).Once()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String()+"/events", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, userID))
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req) // Возвращается после отмены контекста клиента
+
+	assert.Contains(t, rr.Body.String(), ": heartbeat\n\n")
+	mockService.AssertExpectations(t)
+}
+
+func TestTrackingHandler_handleOrderEvents_Errors(t *testing.T) {
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+
+	testCases := []struct {
+		name         string
+		token        string
+		lastEventID  string
+		serviceErr   error
+		expectedCode int
+	}{
+		{name: "missing token", expectedCode: http.StatusUnauthorized},
+		{name: "forged token", token: accessToken(t, userID) + "x", expectedCode: http.StatusUnauthorized},
+		{name: "invalid Last-Event-ID", token: accessToken(t, userID), lastEventID: "abc", expectedCode: http.StatusBadRequest},
+		{name: "foreign or missing order", token: accessToken(t, userID), serviceErr: order.ErrOrderNotFound, expectedCode: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockTrackingService)
+			router := newTrackingRouter(mockService, time.Minute)
+			if tc.serviceErr != nil {
+				mockService.On("Subscribe", mock.Anything, orderID, principalOf(userID), int64(0)).Return(nil, tc.serviceErr).Once()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String()+"/events", nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedCode, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTrackingHandler_handleOrderEvents_IgnoresUserIDHeader(t *testing.T) {
+	mockService := new(MockTrackingService)
+	router := newTrackingRouter(mockService, time.Minute)
+
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("Subscribe", mock.Anything, orderID, principalOf(userID), int64(0)).Return(nil, order.ErrOrderNotFound).Once()
+
+	// Подписка идёт от владельца токена, а не от пользователя из заголовка
+	req := httptest.NewRequest(http.MethodGet, "/orders/"+orderID.String()+"/events", nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken(t, userID))
+	req.Header.Set("X-User-ID", uuid.Must(uuid.NewV4()).String())
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
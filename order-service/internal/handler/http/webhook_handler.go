@@ -0,0 +1,156 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/webhook"
+)
+
+type CreateWebhookSubscriptionRequest struct {
+	URL        string   `json:"url" validate:"required,url"`
+	Secret     string   `json:"secret" validate:"required"`
+	EventTypes []string `json:"event_types" validate:"required,min=1"`
+}
+
+type WebhookHandler struct {
+	service  webhook.Service
+	validate *validator.Validate
+	token    string
+}
+
+// NewWebhookHandler создаёт обработчик подписок. Подписка получает события всех заказов,
+// поэтому маршруты доступны только с внутренним токеном; с пустым token они отвечают 503.
+func NewWebhookHandler(service webhook.Service, token string) *WebhookHandler {
+	return &WebhookHandler{
+		service:  service,
+		validate: validator.New(),
+		token:    token,
+	}
+}
+
+func (h *WebhookHandler) RegisterRoutes(router chi.Router) {
+	router.Group(func(r chi.Router) {
+		r.Use(requireBearerToken(h.token))
+		r.Post("/webhooks/subscriptions", h.handleCreateSubscription)
+		r.Get("/webhooks/subscriptions", h.handleListSubscriptions)
+		r.Get("/webhooks/subscriptions/{id}", h.handleGetSubscription)
+		r.Delete("/webhooks/subscriptions/{id}", h.handleDeleteSubscription)
+		r.Get("/webhooks/subscriptions/{id}/deliveries", h.handleListDeliveries)
+		r.Get("/webhooks/deliveries/{id}", h.handleGetDelivery)
+		r.Post("/webhooks/deliveries/{id}/redeliver", h.handleRedeliver)
+	})
+}
+
+func (h *WebhookHandler) handleCreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var requestPayload CreateWebhookSubscriptionRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	created, err := h.service.CreateSubscription(r.Context(), &webhook.Subscription{
+		URL:        requestPayload.URL,
+		Secret:     requestPayload.Secret,
+		EventTypes: requestPayload.EventTypes,
+	})
+	if err != nil {
+		log.Error().Err(err).Str("url", requestPayload.URL).Msg("Failed to create webhook subscription via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to create webhook subscription"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, created)
+}
+
+func (h *WebhookHandler) handleListSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.service.ListSubscriptions(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to list webhook subscriptions via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to list webhook subscriptions"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, subs)
+}
+
+func (h *WebhookHandler) handleGetSubscription(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	sub, err := h.service.GetSubscription(r.Context(), subID)
+	if err != nil {
+		log.Error().Err(err).Stringer("subscription_id", subID).Msg("Failed to get webhook subscription via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get webhook subscription"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, sub)
+}
+
+func (h *WebhookHandler) handleDeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSubscription(r.Context(), subID); err != nil {
+		log.Error().Err(err).Stringer("subscription_id", subID).Msg("Failed to delete webhook subscription via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to delete webhook subscription"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) handleListDeliveries(w http.ResponseWriter, r *http.Request) {
+	subID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), subID)
+	if err != nil {
+		log.Error().Err(err).Stringer("subscription_id", subID).Msg("Failed to list webhook deliveries via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to list webhook deliveries"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) handleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	deliveryID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(r.Context(), deliveryID)
+	if err != nil {
+		log.Error().Err(err).Stringer("delivery_id", deliveryID).Msg("Failed to get webhook delivery via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to get webhook delivery"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, delivery)
+}
+
+func (h *WebhookHandler) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	deliveryID, ok := parseUUIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.Redeliver(r.Context(), deliveryID)
+	if err != nil {
+		log.Error().Err(err).Stringer("delivery_id", deliveryID).Msg("Failed to redeliver webhook via service")
+		respondWithError(w, mapErrorToStatusCode(err), clientErrorMessage(err, "Failed to redeliver webhook"))
+		return
+	}
+
+	// Доставка выполнится воркером асинхронно
+	respondWithJSON(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,199 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	orderHandler "github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/webhook"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateSubscription(ctx context.Context, sub *webhook.Subscription) (*webhook.Subscription, error) {
+	args := m.Called(ctx, sub)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*webhook.Subscription), args.Error(1)
+}
+
+func (m *MockWebhookService) GetSubscription(ctx context.Context, id uuid.UUID) (*webhook.Subscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*webhook.Subscription), args.Error(1)
+}
+
+func (m *MockWebhookService) ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]webhook.Subscription), args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]webhook.Delivery, error) {
+	args := m.Called(ctx, subscriptionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]webhook.Delivery), args.Error(1)
+}
+
+func (m *MockWebhookService) GetDelivery(ctx context.Context, id uuid.UUID) (*webhook.DeliveryDetails, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*webhook.DeliveryDetails), args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, id uuid.UUID) (*webhook.Delivery, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*webhook.Delivery), args.Error(1)
+}
+
+func newWebhookRouter(service webhook.Service) chi.Router {
+	router := chi.NewRouter()
+	orderHandler.NewWebhookHandler(service, testInternalToken).RegisterRoutes(router)
+	return router
+}
+
+func TestWebhookHandler_handleCreateSubscription_Success(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := newWebhookRouter(mockService)
+
+	created := &webhook.Subscription{
+		ID:         uuid.Must(uuid.NewV4()),
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"order.paid"},
+		Active:     true,
+	}
+	mockService.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(sub *webhook.Subscription) bool {
+		return sub.URL == created.URL && sub.Secret == created.Secret
+	})).Return(created, nil).Once()
+
+	reqBody := `{"url":"https://partner.example.com/hooks","secret":"0123456789abcdef","event_types":["order.paid"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "0123456789abcdef", "secret must not be returned")
+
+	var actualResponse webhook.Subscription
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	assert.Equal(t, created.ID, actualResponse.ID)
+	mockService.AssertExpectations(t)
+}
+
+func TestWebhookHandler_handleCreateSubscription_UnknownEventType(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := newWebhookRouter(mockService)
+
+	mockService.On("CreateSubscription", mock.Anything, mock.Anything).Return(nil, webhook.ErrUnknownEventType).Once()
+
+	reqBody := `{"url":"https://partner.example.com/hooks","secret":"0123456789abcdef","event_types":["order.lost"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+}
+
+func TestWebhookHandler_handleCreateSubscription_InvalidURL(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := newWebhookRouter(mockService)
+
+	reqBody := `{"url":"not a url","secret":"0123456789abcdef","event_types":["order.paid"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", bytes.NewBufferString(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestWebhookHandler_handleRedeliver(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := newWebhookRouter(mockService)
+
+	deliveryID := uuid.Must(uuid.NewV4())
+	mockService.On("Redeliver", mock.Anything, deliveryID).Return(&webhook.Delivery{ID: deliveryID, Status: webhook.DeliveryPending}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/"+deliveryID.String()+"/redeliver", nil)
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWebhookHandler_handleRedeliver_NotFound(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := newWebhookRouter(mockService)
+
+	deliveryID := uuid.Must(uuid.NewV4())
+	mockService.On("Redeliver", mock.Anything, deliveryID).Return(nil, webhook.ErrDeliveryNotFound).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/deliveries/"+deliveryID.String()+"/redeliver", nil)
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":"webhook delivery not found"}`, rr.Body.String())
+}
+
+func TestWebhookHandler_RequiresToken(t *testing.T) {
+	mockService := new(MockWebhookService)
+
+	reqBody := `{"url":"https://partner.example.com/hooks","secret":"0123456789abcdef","event_types":["order.paid"]}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/subscriptions", bytes.NewBufferString(reqBody))
+	rr := httptest.NewRecorder()
+	newWebhookRouter(mockService).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestWebhookHandler_EmptyTokenFailsClosed(t *testing.T) {
+	mockService := new(MockWebhookService)
+	router := chi.NewRouter()
+	orderHandler.NewWebhookHandler(mockService, "").RegisterRoutes(router)
+
+	req := httptest.NewRequest(http.MethodGet, "/webhooks/subscriptions", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	mockService.AssertNotCalled(t, "ListSubscriptions", mock.Anything)
+}
@@ -0,0 +1,85 @@
+package order
+
+import (
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// StatusListener получает уведомления о сменах статуса заказов после того, как они записаны в базу.
+// OnStatusChange вызывается синхронно из сервиса, поэтому не должен блокироваться.
+type StatusListener interface {
+	OnStatusChange(change StatusChange)
+}
+
+// DefaultSubscriptionBuffer - сколько событий может накопиться у подписчика, пока он их не прочитал.
+const DefaultSubscriptionBuffer = 16
+
+// Broadcaster раздаёт смены статуса подписчикам конкретного заказа внутри процесса.
+// Подписчик, который не успевает читать события, отключается: его канал закрывается,
+// и он должен переподписаться и заново прочитать текущий статус.
+type Broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan StatusChange]struct{}
+	bufferSize  int
+}
+
+func NewBroadcaster(bufferSize int) *Broadcaster {
+	if bufferSize <= 0 {
+		bufferSize = DefaultSubscriptionBuffer
+	}
+	return &Broadcaster{
+		subscribers: make(map[uuid.UUID]map[chan StatusChange]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// Subscribe подписывает на смены статуса заказа orderID. Вызов unsubscribe освобождает подписку
+// и закрывает канал; повторный вызов безопасен.
+func (b *Broadcaster) Subscribe(orderID uuid.UUID) (events <-chan StatusChange, unsubscribe func()) {
+	ch := make(chan StatusChange, b.bufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[orderID] == nil {
+		b.subscribers[orderID] = make(map[chan StatusChange]struct{})
+	}
+	b.subscribers[orderID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(orderID, ch)
+	}
+}
+
+func (b *Broadcaster) OnStatusChange(change StatusChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[change.OrderID] {
+		select {
+		case ch <- change:
+		default:
+			log.Warn().Stringer("order_id", change.OrderID).Msg("broadcaster: subscriber is too slow, dropping subscription")
+			b.remove(change.OrderID, ch)
+		}
+	}
+}
+
+// remove удаляет и закрывает канал подписчика. Вызывается под b.mu.
+func (b *Broadcaster) remove(orderID uuid.UUID, ch chan StatusChange) {
+	subs, ok := b.subscribers[orderID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(b.subscribers, orderID)
+	}
+}
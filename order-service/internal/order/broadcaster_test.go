@@ -0,0 +1,61 @@
+package order
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBroadcaster_DeliversOnlyToSubscribersOfOrder(t *testing.T) {
+	broadcaster := NewBroadcaster(4)
+	orderID := uuid.Must(uuid.NewV4())
+	otherID := uuid.Must(uuid.NewV4())
+
+	first, unsubscribeFirst := broadcaster.Subscribe(orderID)
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := broadcaster.Subscribe(orderID)
+	defer unsubscribeSecond()
+	other, unsubscribeOther := broadcaster.Subscribe(otherID)
+	defer unsubscribeOther()
+
+	broadcaster.OnStatusChange(StatusChange{OrderID: orderID, From: StatusNew, To: StatusProcessing})
+
+	assert.Equal(t, StatusProcessing, (<-first).To)
+	assert.Equal(t, StatusProcessing, (<-second).To)
+	assert.Empty(t, other)
+}
+
+func TestBroadcaster_UnsubscribeClosesChannel(t *testing.T) {
+	broadcaster := NewBroadcaster(1)
+	orderID := uuid.Must(uuid.NewV4())
+
+	events, unsubscribe := broadcaster.Subscribe(orderID)
+	unsubscribe()
+	unsubscribe() // Повторный вызов не должен паниковать
+
+	_, open := <-events
+	assert.False(t, open)
+
+	broadcaster.OnStatusChange(StatusChange{OrderID: orderID, To: StatusPaid})
+	assert.Empty(t, broadcaster.subscribers)
+}
+
+func TestBroadcaster_DropsSlowSubscriber(t *testing.T) {
+	broadcaster := NewBroadcaster(1)
+	orderID := uuid.Must(uuid.NewV4())
+
+	events, unsubscribe := broadcaster.Subscribe(orderID)
+	defer unsubscribe()
+
+	broadcaster.OnStatusChange(StatusChange{OrderID: orderID, To: StatusProcessing})
+	broadcaster.OnStatusChange(StatusChange{OrderID: orderID, To: StatusPaid}) // Буфер полон
+
+	change, open := <-events
+	require.True(t, open)
+	assert.Equal(t, StatusProcessing, change.To)
+
+	_, open = <-events
+	assert.False(t, open, "slow subscriber must be disconnected")
+}
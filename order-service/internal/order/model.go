@@ -9,35 +9,82 @@ import (
 type OrderStatus string
 
 const (
-	StatusNew        OrderStatus = "NEW"
-	StatusProcessing OrderStatus = "PROCESSING"
-	StatusPaid       OrderStatus = "PAID"
-	StatusShipped    OrderStatus = "SHIPPED"
-	StatusDelivered  OrderStatus = "DELIVERED"
-	StatusCancelled  OrderStatus = "CANCELLED"
+	StatusNew              OrderStatus = "NEW"
+	StatusProcessing       OrderStatus = "PROCESSING"
+	StatusPaid             OrderStatus = "PAID"
+	StatusPartiallyShipped OrderStatus = "PARTIALLY_SHIPPED" // Часть позиций отгружена, остальные ждут отправки
+	StatusShipped          OrderStatus = "SHIPPED"
+	StatusDelivered        OrderStatus = "DELIVERED"
+	StatusCancelled        OrderStatus = "CANCELLED"
 )
 
 func (os OrderStatus) String() string {
 	return string(os)
 }
 
+// Причины автоматической отмены заказа
+const (
+	CancellationReasonPaymentTimeout = "payment_timeout" // Заказ не был оплачен вовремя
+)
+
 type OrderItem struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	OrderID      uuid.UUID `json:"order_id" db:"order_id"`
 	ProductID    uuid.UUID `json:"product_id" db:"product_id"`
 	Quantity     int       `json:"quantity" db:"quantity"`
 	PricePerUnit float64   `json:"price_per_unit" db:"price_per_unit"` // Используем float64 для денег, или специальный тип decimal
+	WeightGrams  int       `json:"weight_grams" db:"weight_grams"`     // Вес одной единицы товара, нужен для расчёта доставки
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type Order struct {
-	ID                  uuid.UUID   `json:"id" db:"id"`
-	UserID              uuid.UUID   `json:"user_id" db:"user_id"`
-	Status              OrderStatus `json:"status" db:"status"`
-	OrderItems          []OrderItem `json:"order_items" db:"-"` // Не хранится напрямую в таблице orders, а получается JOIN'ом
-	TotalAmount         float64     `json:"total_amount" db:"total_amount"`
-	ShippingAddressText string      `json:"shipping_address_text,omitempty" db:"shipping_address_text"`
-	CreatedAt           time.Time   `json:"created_at" db:"created_at"`
-	UpdatedAt           time.Time   `json:"updated_at" db:"updated_at"`
+	ID                  uuid.UUID     `json:"id" db:"id"`
+	UserID              uuid.UUID     `json:"user_id" db:"user_id"`
+	Status              OrderStatus   `json:"status" db:"status"`
+	OrderItems          []OrderItem   `json:"order_items" db:"-"` // Не хранится напрямую в таблице orders, а получается JOIN'ом
+	TotalAmount         float64       `json:"total_amount" db:"total_amount"`
+	ShippingAddressText string        `json:"shipping_address_text,omitempty" db:"shipping_address_text"`
+	ShippingLine        *ShippingLine `json:"shipping_line,omitempty" db:"-"` // Выбранный способ доставки, хранится в order_shipping_lines
+	CancellationReason  string        `json:"cancellation_reason,omitempty" db:"cancellation_reason"`
+	AnonymizedAt        *time.Time    `json:"anonymized_at,omitempty" db:"anonymized_at"` // Персональные данные стёрты после удаления покупателя
+	CreatedAt           time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// ShippingLine - выбранный для заказа вариант доставки. Его цена входит в TotalAmount заказа.
+type ShippingLine struct {
+	OrderID       uuid.UUID `json:"order_id" db:"order_id"`
+	OptionID      string    `json:"option_id" db:"option_id"` // Идентификатор варианта у провайдера, например "table:standard"
+	Provider      string    `json:"provider" db:"provider"`
+	Carrier       string    `json:"carrier" db:"carrier"`
+	ServiceName   string    `json:"service_name" db:"service_name"`
+	Price         float64   `json:"price" db:"price"`
+	EstimatedDays int       `json:"estimated_days" db:"estimated_days"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// BulkUpdateOutcome - результат массовой смены статуса для одного заказа.
+type BulkUpdateOutcome string
+
+const (
+	BulkUpdated           BulkUpdateOutcome = "updated"
+	BulkAlreadySet        BulkUpdateOutcome = "already_set"
+	BulkInvalidTransition BulkUpdateOutcome = "invalid_transition"
+	BulkNotFound          BulkUpdateOutcome = "not_found"
+)
+
+type BulkUpdateResult struct {
+	OrderID        uuid.UUID         `json:"order_id"`
+	Outcome        BulkUpdateOutcome `json:"outcome"`
+	PreviousStatus OrderStatus       `json:"previous_status,omitempty"` // Пусто, если заказ не найден
+}
+
+// StatusChange - событие смены статуса заказа.
+type StatusChange struct {
+	OrderID   uuid.UUID   `json:"order_id"`
+	From      OrderStatus `json:"from,omitempty"` // Пусто для только что созданного заказа
+	To        OrderStatus `json:"to"`
+	ChangedAt time.Time   `json:"changed_at"`
 }
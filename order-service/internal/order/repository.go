@@ -17,11 +17,27 @@ var (
 	ErrProductNotFound = errors.New("product not found")
 )
 
+// OutboxTable - таблица, в которую триггеры на orders пишут события order.created и order.status_changed.
+var OutboxTable = pgx.Identifier{"order_service", "event_outbox"}
+
 type Repository interface {
 	CreateOrder(ctx context.Context, order *Order) (uuid.UUID, error)
 	GetOrderByID(ctx context.Context, id uuid.UUID) (*Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus OrderStatus) error
 	GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]Order, error)
+	// SetShippingLine сохраняет доставку и пересчитывает сумму заказа, только если статус заказа входит в editableStatuses.
+	// Иначе возвращает ErrShippingLocked.
+	SetShippingLine(ctx context.Context, line *ShippingLine, editableStatuses []OrderStatus) (float64, error)
+	FindStaleOrderIDs(ctx context.Context, statuses []OrderStatus, createdBefore time.Time, limit int) ([]uuid.UUID, error)
+	// CancelOrder отменяет заказ, если его статус входит в fromStatuses, и возвращает статус до отмены.
+	// cancelled = false, если заказа нет или он в другом статусе.
+	CancelOrder(ctx context.Context, orderID uuid.UUID, reason string, fromStatuses []OrderStatus) (previous OrderStatus, cancelled bool, err error)
+	BulkUpdateOrderStatus(ctx context.Context, orderIDs []uuid.UUID, newStatus OrderStatus, fromStatuses []OrderStatus) (map[uuid.UUID]OrderStatus, error)
+	CountUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error)
+	// AnonymizeUserOrders стирает адрес доставки в ещё не обезличенных заказах userID со статусом из statuses.
+	// Суммы, позиции и статусы остаются. Остальные заказы userID обезличит триггер, когда они завершатся.
+	// Возвращает число обезличенных заказов.
+	AnonymizeUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error)
 }
 
 type postgresRepository struct {
@@ -114,8 +130,8 @@ func (r *postgresRepository) CreateOrder(ctx context.Context, orderInput *Order)
 		itemUpdatedAt := itemCreatedAt
 
 		queryItem := `
-			INSERT INTO order_service.order_items (id, order_id, product_id, quantity, price_per_unit, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			INSERT INTO order_service.order_items (id, order_id, product_id, quantity, price_per_unit, weight_grams, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		`
 		_, err = tx.Exec(ctx, queryItem,
 			itemInput.ID,
@@ -123,6 +139,7 @@ func (r *postgresRepository) CreateOrder(ctx context.Context, orderInput *Order)
 			itemInput.ProductID,
 			itemInput.Quantity,
 			itemInput.PricePerUnit,
+			itemInput.WeightGrams,
 			itemCreatedAt,
 			itemUpdatedAt,
 		)
@@ -139,7 +156,7 @@ func (r *postgresRepository) CreateOrder(ctx context.Context, orderInput *Order)
 
 func (r *postgresRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID) (*Order, error) {
 	queryOrder := `
-		SELECT id, user_id, status, total_amount, shipping_address_text, created_at, updated_at 
+		SELECT id, user_id, status, total_amount, shipping_address_text, COALESCE(cancellation_reason, ''), anonymized_at, created_at, updated_at
 		FROM order_service.orders
 		WHERE id = $1
 	`
@@ -151,6 +168,8 @@ func (r *postgresRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID
 		&order.Status,
 		&order.TotalAmount,
 		&order.ShippingAddressText,
+		&order.CancellationReason,
+		&order.AnonymizedAt,
 		&order.CreatedAt,
 		&order.UpdatedAt,
 	)
@@ -163,7 +182,7 @@ func (r *postgresRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID
 	}
 
 	queryOrderItems := `
-		SELECT id, order_id, product_id, quantity, price_per_unit, created_at, updated_at
+		SELECT id, order_id, product_id, quantity, price_per_unit, weight_grams, created_at, updated_at
 		FROM order_service.order_items
 		WHERE order_id = $1
 	`
@@ -182,6 +201,7 @@ func (r *postgresRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID
 			&orderItem.ProductID,
 			&orderItem.Quantity,
 			&orderItem.PricePerUnit,
+			&orderItem.WeightGrams,
 			&orderItem.CreatedAt,
 			&orderItem.UpdatedAt,
 		)
@@ -197,6 +217,10 @@ func (r *postgresRepository) GetOrderByID(ctx context.Context, orderID uuid.UUID
 
 	order.OrderItems = orderItems
 
+	if err := r.loadShippingLines(ctx, map[uuid.UUID]*Order{order.ID: &order}, []uuid.UUID{order.ID}); err != nil {
+		return nil, err
+	}
+
 	return &order, nil
 }
 
@@ -227,7 +251,7 @@ func (r *postgresRepository) UpdateOrderStatus(ctx context.Context, orderID uuid
 
 func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]Order, error) {
 	userOrdersQuery := `
-		SELECT id, user_id, status, total_amount, shipping_address_text, created_at, updated_at
+		SELECT id, user_id, status, total_amount, shipping_address_text, COALESCE(cancellation_reason, ''), anonymized_at, created_at, updated_at
 		FROM order_service.orders
 		WHERE user_id = $1
 		ORDER BY created_at DESC
@@ -250,6 +274,8 @@ func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.
 			&order.Status,
 			&order.TotalAmount,
 			&order.ShippingAddressText,
+			&order.CancellationReason,
+			&order.AnonymizedAt,
 			&order.CreatedAt,
 			&order.UpdatedAt,
 		)
@@ -270,7 +296,7 @@ func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.
 	}
 
 	userOrderItemsQuery := `
-		SELECT id, order_id, product_id, quantity, price_per_unit, created_at, updated_at
+		SELECT id, order_id, product_id, quantity, price_per_unit, weight_grams, created_at, updated_at
 		FROM order_service.order_items
 		WHERE order_id = ANY($1)
 	`
@@ -288,6 +314,7 @@ func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.
 			&item.ProductID,
 			&item.Quantity,
 			&item.PricePerUnit,
+			&item.WeightGrams,
 			&item.CreatedAt,
 			&item.UpdatedAt,
 		)
@@ -304,6 +331,10 @@ func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.
 		return nil, fmt.Errorf("repository: failed iterating order itens by user id %s: %w", userID, err)
 	}
 
+	if err := r.loadShippingLines(ctx, ordersMap, orderIDs); err != nil {
+		return nil, err
+	}
+
 	resultOrders := make([]Order, 0, len(ordersMap))
 
 	for _, id := range orderIDs {
@@ -314,3 +345,312 @@ func (r *postgresRepository) GetOrdersByUserID(ctx context.Context, userID uuid.
 
 	return resultOrders, nil
 }
+
+// FindStaleOrderIDs возвращает самые старые заказы в указанных статусах, созданные раньше createdBefore.
+func (r *postgresRepository) FindStaleOrderIDs(ctx context.Context, statuses []OrderStatus, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		SELECT id
+		FROM order_service.orders
+		WHERE status = ANY($1) AND created_at < $2
+		ORDER BY created_at ASC
+		LIMIT $3
+	`
+
+	rawStatuses := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		rawStatuses = append(rawStatuses, string(status))
+	}
+
+	rows, err := r.db.Query(ctx, query, rawStatuses, createdBefore, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query stale orders: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan stale order id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating stale orders: %w", err)
+	}
+
+	return ids, nil
+}
+
+// CancelOrder отменяет заказ с указанной причиной, только если его текущий статус входит в fromStatuses.
+// Проверка и обновление выполняются одним UPDATE, поэтому заказ, оплаченный параллельно, не будет отменён.
+// Возвращает false, если заказ не найден или его статус не подошёл.
+func (r *postgresRepository) CancelOrder(ctx context.Context, orderID uuid.UUID, reason string, fromStatuses []OrderStatus) (OrderStatus, bool, error) {
+	// Подзапрос блокирует строку и отдаёт статус до обновления: RETURNING сам по себе видит только новый
+	query := `
+		UPDATE order_service.orders o
+		SET status = $1, cancellation_reason = $2, updated_at = $3
+		FROM (
+			SELECT id, status
+			FROM order_service.orders
+			WHERE id = $4
+			FOR UPDATE
+		) prev
+		WHERE o.id = prev.id AND prev.status = ANY($5)
+		RETURNING prev.status
+	`
+
+	var previous OrderStatus
+	err := r.db.QueryRow(ctx, query, string(StatusCancelled), reason, time.Now(), orderID, statusStrings(fromStatuses)).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("repository: failed to cancel order %s: %w", orderID, err)
+	}
+
+	return previous, true, nil
+}
+
+// BulkUpdateOrderStatus в одной транзакции блокирует заказы orderIDs и переводит в newStatus те из них,
+// чей статус входит в fromStatuses. Возвращает статусы найденных заказов до обновления.
+func (r *postgresRepository) BulkUpdateOrderStatus(ctx context.Context, orderIDs []uuid.UUID, newStatus OrderStatus, fromStatuses []OrderStatus) (previous map[uuid.UUID]OrderStatus, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Int("orders_count", len(orderIDs)).Msg("Panic recovered during BulkUpdateOrderStatus, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Msg("Failed to rollback transaction")
+			}
+		} else if commitErr := tx.Commit(ctx); commitErr != nil {
+			log.Error().Err(commitErr).Msg("Failed to commit transaction")
+			err = fmt.Errorf("repository: failed to commit transaction: %w", commitErr)
+		}
+	}()
+
+	// ORDER BY id - одинаковый порядок блокировок в параллельных пачках, чтобы не получить deadlock
+	querySelect := `
+		SELECT id, status
+		FROM order_service.orders
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, querySelect, orderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to lock orders for bulk status update: %w", err)
+	}
+
+	allowed := make(map[OrderStatus]bool, len(fromStatuses))
+	for _, status := range fromStatuses {
+		allowed[status] = true
+	}
+
+	previous = make(map[uuid.UUID]OrderStatus, len(orderIDs))
+	toUpdate := make([]uuid.UUID, 0, len(orderIDs))
+	for rows.Next() {
+		var id uuid.UUID
+		var status OrderStatus
+		if err = rows.Scan(&id, &status); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("repository: failed to scan order for bulk status update: %w", err)
+		}
+		previous[id] = status
+		if allowed[status] {
+			toUpdate = append(toUpdate, id)
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating orders for bulk status update: %w", err)
+	}
+
+	if len(toUpdate) == 0 {
+		return previous, nil
+	}
+
+	queryUpdate := `
+		UPDATE order_service.orders
+		SET status = $1, updated_at = $2
+		WHERE id = ANY($3)
+	`
+	if _, err = tx.Exec(ctx, queryUpdate, string(newStatus), time.Now(), toUpdate); err != nil {
+		return nil, fmt.Errorf("repository: failed to bulk update order status: %w", err)
+	}
+
+	return previous, nil
+}
+
+// SetShippingLine сохраняет (или заменяет) выбранную доставку и пересчитывает total_amount заказа
+// как сумму позиций плюс цена доставки. Возвращает новую сумму заказа.
+func (r *postgresRepository) SetShippingLine(ctx context.Context, line *ShippingLine, editableStatuses []OrderStatus) (totalAmount float64, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("order_id", line.OrderID).Msg("Failed to rollback shipping line transaction")
+			}
+			return
+		}
+		if commitErr := tx.Commit(ctx); commitErr != nil {
+			err = fmt.Errorf("repository: failed to commit transaction: %w", commitErr)
+		}
+	}()
+
+	now := time.Now().UTC()
+
+	// Статус проверяется в том же UPDATE, который блокирует строку заказа: оплата, пришедшая параллельно,
+	// либо дождётся этой транзакции, либо заказ уже не пройдёт условие.
+	queryTotal := `
+		UPDATE order_service.orders
+		SET total_amount = COALESCE((
+				SELECT SUM(quantity * price_per_unit)
+				FROM order_service.order_items
+				WHERE order_id = $1
+			), 0) + $2,
+			updated_at = $3
+		WHERE id = $1 AND status = ANY($4)
+		RETURNING total_amount
+	`
+	err = tx.QueryRow(ctx, queryTotal, line.OrderID, line.Price, now, statusStrings(editableStatuses)).Scan(&totalAmount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if existsErr := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM order_service.orders WHERE id = $1)`, line.OrderID).Scan(&exists); existsErr != nil {
+				return 0, fmt.Errorf("repository: failed to check order %s existence: %w", line.OrderID, existsErr)
+			}
+			if !exists {
+				return 0, ErrOrderNotFound
+			}
+			return 0, ErrShippingLocked
+		}
+		return 0, fmt.Errorf("repository: failed to recalculate total for order %s: %w", line.OrderID, err)
+	}
+
+	queryLine := `
+		INSERT INTO order_service.order_shipping_lines (order_id, option_id, provider, carrier, service_name, price, estimated_days, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (order_id) DO UPDATE SET
+			option_id = EXCLUDED.option_id,
+			provider = EXCLUDED.provider,
+			carrier = EXCLUDED.carrier,
+			service_name = EXCLUDED.service_name,
+			price = EXCLUDED.price,
+			estimated_days = EXCLUDED.estimated_days,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at
+	`
+	err = tx.QueryRow(ctx, queryLine,
+		line.OrderID,
+		line.OptionID,
+		line.Provider,
+		line.Carrier,
+		line.ServiceName,
+		line.Price,
+		line.EstimatedDays,
+		now,
+	).Scan(&line.CreatedAt, &line.UpdatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to upsert shipping line for order %s: %w", line.OrderID, err)
+	}
+
+	return totalAmount, nil
+}
+
+func (r *postgresRepository) loadShippingLines(ctx context.Context, ordersMap map[uuid.UUID]*Order, orderIDs []uuid.UUID) error {
+	if len(orderIDs) == 0 {
+		return nil
+	}
+
+	query := `
+		SELECT order_id, option_id, provider, carrier, service_name, price, estimated_days, created_at, updated_at
+		FROM order_service.order_shipping_lines
+		WHERE order_id = ANY($1)
+	`
+	rows, err := r.db.Query(ctx, query, orderIDs)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query shipping lines: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var line ShippingLine
+		err := rows.Scan(
+			&line.OrderID,
+			&line.OptionID,
+			&line.Provider,
+			&line.Carrier,
+			&line.ServiceName,
+			&line.Price,
+			&line.EstimatedDays,
+			&line.CreatedAt,
+			&line.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("repository: failed to scan shipping line: %w", err)
+		}
+		if order, ok := ordersMap[line.OrderID]; ok {
+			order.ShippingLine = &line
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating shipping lines: %w", err)
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) CountUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM order_service.orders
+		WHERE user_id = $1 AND status = ANY($2)
+	`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID, statusStrings(statuses)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("repository: failed to count orders for user id %s: %w", userID, err)
+	}
+	return count, nil
+}
+
+func (r *postgresRepository) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error) {
+	// Отметку сохраняем отдельным запросом до обезличивания, чтобы заказ, завершившийся в это время, увидел её в триггере
+	if _, err := r.db.Exec(ctx, `
+		INSERT INTO order_service.deleted_customers (user_id) VALUES ($1)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID); err != nil {
+		return 0, fmt.Errorf("repository: failed to record deleted customer %s: %w", userID, err)
+	}
+
+	query := `
+		UPDATE order_service.orders
+		SET shipping_address_text = '', anonymized_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1 AND status = ANY($2) AND anonymized_at IS NULL
+	`
+
+	cmdTag, err := r.db.Exec(ctx, query, userID, statusStrings(statuses))
+	if err != nil {
+		return 0, fmt.Errorf("repository: failed to anonymize orders for user id %s: %w", userID, err)
+	}
+	return int(cmdTag.RowsAffected()), nil
+}
+
+func statusStrings(statuses []OrderStatus) []string {
+	raw := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		raw = append(raw, string(status))
+	}
+	return raw
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
 	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
 )
 
@@ -105,6 +106,8 @@ func truncateOrderTables(tb testing.TB, pool *pgxpool.Pool) {
 	require.NoError(tb, err, "failed to truncate order_tems table")
 	_, err = pool.Exec(context.Background(), "TRUNCATE TABLE order_service.orders CASCADE")
 	require.NoError(tb, err, "failed to truncate orders table")
+	_, err = pool.Exec(context.Background(), "TRUNCATE TABLE order_service.event_outbox, order_service.deleted_customers")
+	require.NoError(tb, err, "failed to truncate event_outbox table")
 }
 func TestOrderRepository_CreateOrder_Success(t *testing.T) {
 	repo := order.NewRepository(testDB)
@@ -556,3 +559,294 @@ func TestOrderRepository_GetOrdersByUserID_Success_NoOrders(t *testing.T) {
 	require.NotNil(t, returnedOrders)
 	require.Len(t, returnedOrders, 0)
 }
+
+func TestOrderRepository_SetShippingLine_RecalculatesTotal(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	orderToCreate := order.Order{
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: order.StatusNew,
+		OrderItems: []order.OrderItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, PricePerUnit: 10.00, WeightGrams: 250},
+		},
+		TotalAmount: 20.00,
+	}
+	orderID, err := repo.CreateOrder(ctx, &orderToCreate)
+	require.NoError(t, err)
+
+	line := &order.ShippingLine{
+		OrderID:       orderID,
+		OptionID:      "flat:standard",
+		Provider:      "flat",
+		Carrier:       "ANY",
+		ServiceName:   "Flat rate",
+		Price:         4.99,
+		EstimatedDays: 5,
+	}
+	total, err := repo.SetShippingLine(ctx, line, []order.OrderStatus{order.StatusNew, order.StatusProcessing})
+	require.NoError(t, err)
+	assert.InDelta(t, 24.99, total, 0.001)
+
+	// Повторный выбор заменяет доставку, а не прибавляет её ещё раз
+	line.OptionID = "table:express"
+	line.Price = 14.90
+	total, err = repo.SetShippingLine(ctx, line, []order.OrderStatus{order.StatusNew, order.StatusProcessing})
+	require.NoError(t, err)
+	assert.InDelta(t, 34.90, total, 0.001)
+
+	fetchedOrder, err := repo.GetOrderByID(ctx, orderID)
+	require.NoError(t, err)
+	require.NotNil(t, fetchedOrder.ShippingLine)
+	assert.Equal(t, "table:express", fetchedOrder.ShippingLine.OptionID)
+	assert.InDelta(t, 34.90, fetchedOrder.TotalAmount, 0.001)
+	require.Len(t, fetchedOrder.OrderItems, 1)
+	assert.Equal(t, 250, fetchedOrder.OrderItems[0].WeightGrams)
+}
+
+func TestOrderRepository_SetShippingLine_OrderNotFound(t *testing.T) {
+	repo := order.NewRepository(testDB)
+
+	_, err := repo.SetShippingLine(context.Background(), &order.ShippingLine{
+		OrderID:  uuid.Must(uuid.NewV4()),
+		OptionID: "flat:standard",
+		Price:    4.99,
+	}, []order.OrderStatus{order.StatusNew})
+	require.ErrorIs(t, err, order.ErrOrderNotFound)
+}
+
+func TestOrderRepository_SetShippingLine_LockedStatus(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	orderToCreate := order.Order{
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: order.StatusPaid,
+		OrderItems: []order.OrderItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 10.00},
+		},
+		TotalAmount: 10.00,
+	}
+	orderID, err := repo.CreateOrder(ctx, &orderToCreate)
+	require.NoError(t, err)
+
+	_, err = repo.SetShippingLine(ctx, &order.ShippingLine{OrderID: orderID, OptionID: "flat:standard", Price: 4.99},
+		[]order.OrderStatus{order.StatusNew, order.StatusProcessing})
+	require.ErrorIs(t, err, order.ErrShippingLocked)
+
+	fetchedOrder, err := repo.GetOrderByID(ctx, orderID)
+	require.NoError(t, err)
+	assert.Nil(t, fetchedOrder.ShippingLine)
+	assert.InDelta(t, 10.00, fetchedOrder.TotalAmount, 0.001)
+}
+
+func TestOrderRepository_FindStaleOrderIDsAndCancel(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	newOrder := func(status order.OrderStatus) uuid.UUID {
+		o := order.Order{
+			UserID:     uuid.Must(uuid.NewV4()),
+			Status:     status,
+			OrderItems: []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 5}},
+		}
+		id, err := repo.CreateOrder(ctx, &o)
+		require.NoError(t, err)
+		return id
+	}
+	unpaidID := newOrder(order.StatusNew)
+	paidID := newOrder(order.StatusPaid)
+	unpaid := []order.OrderStatus{order.StatusNew, order.StatusProcessing}
+
+	ids, err := repo.FindStaleOrderIDs(ctx, unpaid, time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{unpaidID}, ids)
+
+	ids, err = repo.FindStaleOrderIDs(ctx, unpaid, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+
+	_, cancelled, err := repo.CancelOrder(ctx, paidID, order.CancellationReasonPaymentTimeout, unpaid)
+	require.NoError(t, err)
+	assert.False(t, cancelled, "paid order must not be cancelled")
+
+	previous, cancelled, err := repo.CancelOrder(ctx, unpaidID, order.CancellationReasonPaymentTimeout, unpaid)
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+	assert.Equal(t, order.StatusNew, previous)
+
+	fetchedOrder, err := repo.GetOrderByID(ctx, unpaidID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, fetchedOrder.Status)
+	assert.Equal(t, order.CancellationReasonPaymentTimeout, fetchedOrder.CancellationReason)
+}
+
+func TestOrderRepository_BulkUpdateOrderStatus(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	newOrder := func(status order.OrderStatus) uuid.UUID {
+		o := order.Order{
+			UserID:     uuid.Must(uuid.NewV4()),
+			Status:     status,
+			OrderItems: []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 5}},
+		}
+		id, err := repo.CreateOrder(ctx, &o)
+		require.NoError(t, err)
+		return id
+	}
+	paidID := newOrder(order.StatusPaid)
+	cancelledID := newOrder(order.StatusCancelled)
+	missingID := uuid.Must(uuid.NewV4())
+
+	previous, err := repo.BulkUpdateOrderStatus(ctx, []uuid.UUID{paidID, cancelledID, missingID}, order.StatusShipped, []order.OrderStatus{order.StatusPaid})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]order.OrderStatus{
+		paidID:      order.StatusPaid,
+		cancelledID: order.StatusCancelled,
+	}, previous)
+
+	shipped, err := repo.GetOrderByID(ctx, paidID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusShipped, shipped.Status)
+
+	untouched, err := repo.GetOrderByID(ctx, cancelledID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusCancelled, untouched.Status)
+}
+
+func TestOrderRepository_CountAndAnonymizeUserOrders(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	newOrder := func(status order.OrderStatus) uuid.UUID {
+		o := order.Order{
+			UserID:              userID,
+			Status:              status,
+			ShippingAddressText: "Moscow, Tverskaya 1",
+			OrderItems:          []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 5}},
+		}
+		id, err := repo.CreateOrder(ctx, &o)
+		require.NoError(t, err)
+		return id
+	}
+	deliveredID := newOrder(order.StatusDelivered)
+	shippedID := newOrder(order.StatusShipped)
+
+	active, err := repo.CountUserOrders(ctx, userID, []order.OrderStatus{order.StatusShipped, order.StatusPaid})
+	require.NoError(t, err)
+	assert.Equal(t, 1, active)
+
+	finalStatuses := []order.OrderStatus{order.StatusDelivered, order.StatusCancelled}
+	anonymized, err := repo.AnonymizeUserOrders(ctx, userID, finalStatuses)
+	require.NoError(t, err)
+	assert.Equal(t, 1, anonymized)
+
+	delivered, err := repo.GetOrderByID(ctx, deliveredID)
+	require.NoError(t, err)
+	assert.Empty(t, delivered.ShippingAddressText)
+	assert.NotNil(t, delivered.AnonymizedAt)
+	assert.InDelta(t, 5.0, delivered.TotalAmount, 0.001, "financial data must be kept")
+	assert.Len(t, delivered.OrderItems, 1)
+
+	shipped, err := repo.GetOrderByID(ctx, shippedID)
+	require.NoError(t, err)
+	assert.Equal(t, "Moscow, Tverskaya 1", shipped.ShippingAddressText)
+	assert.Nil(t, shipped.AnonymizedAt)
+
+	// Повторная обработка события ничего не меняет
+	anonymized, err = repo.AnonymizeUserOrders(ctx, userID, finalStatuses)
+	require.NoError(t, err)
+	assert.Zero(t, anonymized)
+
+	// Активный заказ удалённого покупателя обезличивается, как только завершается
+	require.NoError(t, repo.UpdateOrderStatus(ctx, shippedID, order.StatusDelivered))
+	finished, err := repo.GetOrderByID(ctx, shippedID)
+	require.NoError(t, err)
+	assert.Empty(t, finished.ShippingAddressText)
+	assert.NotNil(t, finished.AnonymizedAt)
+
+	// Заказы других покупателей триггер не трогает
+	otherOrder := order.Order{
+		UserID:              uuid.Must(uuid.NewV4()),
+		Status:              order.StatusShipped,
+		ShippingAddressText: "Moscow, Arbat 2",
+		OrderItems:          []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 5}},
+	}
+	otherID, err := repo.CreateOrder(ctx, &otherOrder)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateOrderStatus(ctx, otherID, order.StatusDelivered))
+	other, err := repo.GetOrderByID(ctx, otherID)
+	require.NoError(t, err)
+	assert.Equal(t, "Moscow, Arbat 2", other.ShippingAddressText)
+	assert.Nil(t, other.AnonymizedAt)
+}
+
+func TestOrderRepository_WritesDomainEventsToOutbox(t *testing.T) {
+	repo := order.NewRepository(testDB)
+	ctx := context.Background()
+	truncateOrderTables(t, testDB)
+	t.Cleanup(func() {
+		truncateOrderTables(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	created := &order.Order{
+		UserID:      userID,
+		Status:      order.StatusNew,
+		TotalAmount: 10,
+		OrderItems:  []order.OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, PricePerUnit: 5}},
+	}
+	orderID, err := repo.CreateOrder(ctx, created)
+	require.NoError(t, err)
+	require.NoError(t, repo.UpdateOrderStatus(ctx, orderID, order.StatusProcessing))
+
+	broker := events.NewMemoryBroker()
+	outbox := events.NewPostgresOutbox(testDB, order.OutboxTable, events.SourceOrderService)
+	published, err := outbox.PublishPending(ctx, 10, broker.Publish)
+	require.NoError(t, err)
+	require.Equal(t, 2, published)
+
+	sent := broker.Published()
+	require.Len(t, sent, 2)
+
+	var createdData events.OrderCreatedData
+	assert.Equal(t, events.TypeOrderCreated, sent[0].Type)
+	assert.Equal(t, events.SourceOrderService, sent[0].Source)
+	assert.Equal(t, orderID.String(), sent[0].Subject)
+	require.NoError(t, sent[0].DecodeData(&createdData))
+	assert.Equal(t, userID, createdData.UserID)
+	assert.InDelta(t, 10.0, createdData.TotalAmount, 0.001)
+
+	var changed events.OrderStatusChangedData
+	assert.Equal(t, events.TypeOrderStatusChanged, sent[1].Type)
+	require.NoError(t, sent[1].DecodeData(&changed))
+	assert.Equal(t, string(order.StatusNew), changed.From)
+	assert.Equal(t, string(order.StatusProcessing), changed.To)
+
+	// Отправленные события не уходят повторно
+	published, err = outbox.PublishPending(ctx, 10, broker.Publish)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+}
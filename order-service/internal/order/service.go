@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/rs/zerolog/log"
@@ -15,11 +17,17 @@ var allowedTransitions = map[OrderStatus]map[OrderStatus]bool{
 		StatusCancelled:  true,
 	},
 	StatusProcessing: {
-		StatusPaid:      true,
-		StatusShipped:   true,
-		StatusCancelled: true,
+		StatusPaid:             true,
+		StatusPartiallyShipped: true,
+		StatusShipped:          true,
+		StatusCancelled:        true,
 	},
 	StatusPaid: {
+		StatusPartiallyShipped: true,
+		StatusShipped:          true,
+		StatusCancelled:        true,
+	},
+	StatusPartiallyShipped: {
 		StatusShipped:   true,
 		StatusCancelled: true,
 	},
@@ -34,23 +42,99 @@ var allowedTransitions = map[OrderStatus]map[OrderStatus]bool{
 var (
 	ErrStatusAlreadySet        = errors.New("status is already set to the desired value")
 	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrShippingLocked          = errors.New("shipping can no longer be changed for this order")
+	ErrUnknownStatus           = errors.New("unknown order status")
+	ErrEmailNotVerified        = errors.New("customer email is not verified")
+	ErrCustomerNotFound        = errors.New("customer not found")
+	ErrCustomerCheckFailed     = errors.New("failed to check customer")
 )
 
+// CustomerVerifier сообщает, подтвердил ли покупатель email. ErrCustomerNotFound, если покупателя нет.
+type CustomerVerifier interface {
+	IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error)
+}
+
+// bulkUpdateBatchSize - сколько заказов обновляется в одной транзакции при массовой смене статуса.
+const bulkUpdateBatchSize = 100
+
+// shippingEditableStatuses - статусы, в которых ещё можно выбрать или сменить доставку (до оплаты).
+var shippingEditableStatuses = []OrderStatus{StatusNew, StatusProcessing}
+
+// unpaidStatuses - статусы заказа до оплаты. Такие заказы отменяются автоматически по таймауту оплаты.
+var unpaidStatuses = []OrderStatus{StatusNew, StatusProcessing}
+
+// activeStatuses - статусы незавершённых заказов. Пока такие заказы есть, аккаунт покупателя удалять нельзя.
+var activeStatuses = []OrderStatus{StatusNew, StatusProcessing, StatusPaid, StatusPartiallyShipped, StatusShipped}
+
+// finalStatuses - статусы завершённых заказов, которые обезличиваются после удаления покупателя.
+var finalStatuses = []OrderStatus{StatusDelivered, StatusCancelled}
+
+// transitionError описывает конкретный запрещённый переход и сопоставляется с ErrInvalidStatusTransition через errors.Is.
+type transitionError struct {
+	from OrderStatus
+	to   OrderStatus
+}
+
+func (e *transitionError) Error() string {
+	return fmt.Sprintf("service: invalid status transition from %s to %s (or no rules for %s)", e.from, e.to, e.from)
+}
+
+func (e *transitionError) Is(target error) bool {
+	return target == ErrInvalidStatusTransition
+}
+
+// CheckTransition возвращает ошибку ErrInvalidStatusTransition, если из from нельзя перейти в to.
+func CheckTransition(from, to OrderStatus) error {
+	if !allowedTransitions[from][to] {
+		return &transitionError{from: from, to: to}
+	}
+	return nil
+}
+
 type Service interface {
 	CreateOrder(ctx context.Context, orderInput *Order) (*Order, error)
 	GetOrderByID(ctx context.Context, id uuid.UUID) (*Order, error)
 	GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]Order, error)
 	UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus OrderStatus) error
+	SetShippingLine(ctx context.Context, line *ShippingLine) (*Order, error)
+	// FindStaleUnpaidOrderIDs возвращает до limit неоплаченных заказов, созданных раньше createdBefore.
+	FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error)
+	// CancelUnpaidOrder отменяет заказ с причиной reason, если он всё ещё не оплачен.
+	CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error
+	// BulkUpdateStatus переводит заказы в newStatus пачками и возвращает результат по каждому заказу в порядке ids.
+	// Каждая пачка - отдельная транзакция: при ошибке уже обработанные пачки остаются применёнными.
+	BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus OrderStatus) ([]BulkUpdateResult, error)
+	// CountActiveOrders возвращает число незавершённых заказов пользователя (от NEW до SHIPPED).
+	CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error)
+	// AnonymizeUserOrders стирает персональные данные из завершённых заказов удалённого пользователя.
+	// Повторный вызов безопасен. Возвращает число обезличенных заказов.
+	AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error)
+	// NotifyStatusChange сообщает слушателям о смене статуса, которую другой сервис записал в своей транзакции.
+	NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to OrderStatus)
 }
 
 type service struct {
 	orderRepo Repository // Наша зависимость от репозитория заказов
 	// productRepo ProductRepository // Пример будущей зависимости
+	customers CustomerVerifier // Проверка покупателя перед оформлением заказа; nil отключает проверку
+	listeners []StatusListener // Получают события о каждой записанной смене статуса
 }
 
-func NewService(orderRepo Repository) Service {
+// NewService создаёт сервис заказов. Доменные события для других сервисов пишет в event_outbox триггер базы
+// в транзакции изменения, сервис их не публикует.
+func NewService(orderRepo Repository, customers CustomerVerifier, listeners ...StatusListener) Service {
 	return &service{
 		orderRepo: orderRepo,
+		customers: customers,
+		listeners: listeners,
+	}
+}
+
+// NotifyStatusChange сообщает слушателям внутри процесса о смене статуса, уже сохранённой в репозитории.
+func (s *service) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to OrderStatus) {
+	change := StatusChange{OrderID: orderID, From: from, To: to, ChangedAt: time.Now().UTC()}
+	for _, listener := range s.listeners {
+		listener.OnStatusChange(change)
 	}
 }
 
@@ -75,6 +159,10 @@ func (s *service) CreateOrder(ctx context.Context, orderInput *Order) (*Order, e
 			return nil, fmt.Errorf("service: order item price per unit for product %s cannot be negative", item.ProductID)
 		}
 
+		if item.WeightGrams < 0 {
+			return nil, fmt.Errorf("service: order item weight for product %s cannot be negative", item.ProductID)
+		}
+
 		if item.ProductID == uuid.Nil {
 			return nil, errors.New("service: product id in order item cannot be nil")
 		}
@@ -85,6 +173,10 @@ func (s *service) CreateOrder(ctx context.Context, orderInput *Order) (*Order, e
 		totalAmount += float64(item.Quantity) * item.PricePerUnit
 	}
 
+	if err := s.checkCustomer(ctx, orderInput.UserID); err != nil {
+		return nil, err
+	}
+
 	orderInput.Status = StatusNew
 	orderInput.TotalAmount = totalAmount
 
@@ -99,6 +191,29 @@ func (s *service) CreateOrder(ctx context.Context, orderInput *Order) (*Order, e
 	return orderInput, nil
 }
 
+// checkCustomer пускает к оформлению заказа только покупателей с подтверждённым email.
+// Если user-service недоступен, заказ не создаётся.
+func (s *service) checkCustomer(ctx context.Context, userID uuid.UUID) error {
+	if s.customers == nil {
+		return nil
+	}
+
+	verified, err := s.customers.IsEmailVerified(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrCustomerNotFound) {
+			return ErrCustomerNotFound
+		}
+		log.Error().Err(err).Stringer("user_id", userID).Msg("service: failed to check customer email verification")
+		return fmt.Errorf("%w: %w", ErrCustomerCheckFailed, err)
+	}
+	if !verified {
+		log.Info().Stringer("user_id", userID).Msg("service: order rejected, customer email is not verified")
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
+
 func (s *service) GetOrderByID(ctx context.Context, id uuid.UUID) (*Order, error) {
 	order, err := s.orderRepo.GetOrderByID(ctx, id)
 	if err != nil {
@@ -160,7 +275,7 @@ func (s *service) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newS
 			Stringer("current_status", currentOrder.Status).
 			Stringer("new_status", newStatus).
 			Msg(logMessage)
-		return fmt.Errorf("service: invalid status transition from %s to %s (or no rules for %s)", currentOrder.Status, newStatus, currentOrder.Status)
+		return &transitionError{from: currentOrder.Status, to: newStatus}
 	}
 
 	// 5. Обновление статуса в репозитории
@@ -174,7 +289,172 @@ func (s *service) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newS
 		return fmt.Errorf("service: failed to update order status: %w", err)
 	}
 
-	// 6. Логирование успеха
+	// 6. Логирование успеха и уведомление слушателей
 	log.Info().Stringer("order_id", orderID).Stringer("old_status", currentOrder.Status).Stringer("new_status", newStatus).Msg("service: order status updated successfully")
+	s.NotifyStatusChange(ctx, orderID, currentOrder.Status, newStatus)
 	return nil
 }
+
+func (s *service) SetShippingLine(ctx context.Context, line *ShippingLine) (*Order, error) {
+	if line.Price < 0 {
+		return nil, fmt.Errorf("service: shipping price for order %s cannot be negative", line.OrderID)
+	}
+
+	totalAmount, err := s.orderRepo.SetShippingLine(ctx, line, shippingEditableStatuses)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			return nil, ErrOrderNotFound
+		case errors.Is(err, ErrShippingLocked):
+			log.Warn().Stringer("order_id", line.OrderID).Msg("service: attempt to change shipping after payment")
+			return nil, ErrShippingLocked
+		}
+		log.Error().Err(err).Stringer("order_id", line.OrderID).Msg("service: failed to save shipping line in repository")
+		return nil, fmt.Errorf("service: failed to save shipping line: %w", err)
+	}
+
+	log.Info().Stringer("order_id", line.OrderID).Str("option_id", line.OptionID).Float64("total_amount", totalAmount).Msg("service: shipping line set")
+
+	return s.GetOrderByID(ctx, line.OrderID)
+}
+
+func (s *service) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	ids, err := s.orderRepo.FindStaleOrderIDs(ctx, unpaidStatuses, createdBefore, limit)
+	if err != nil {
+		log.Error().Err(err).Time("created_before", createdBefore).Msg("service: failed to find stale unpaid orders in repository")
+		return nil, fmt.Errorf("service: failed to find stale unpaid orders: %w", err)
+	}
+
+	return ids, nil
+}
+
+func (s *service) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	previous, cancelled, err := s.orderRepo.CancelOrder(ctx, orderID, reason, unpaidStatuses)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("service: failed to cancel order in repository")
+		return fmt.Errorf("service: failed to cancel order: %w", err)
+	}
+
+	if !cancelled {
+		// Заказ не найден или уже ушёл из неоплаченных статусов - выясняем, что именно произошло
+		currentOrder, err := s.GetOrderByID(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if currentOrder.Status == StatusCancelled {
+			return ErrStatusAlreadySet
+		}
+		log.Info().Stringer("order_id", orderID).Stringer("status", currentOrder.Status).Msg("service: order left unpaid statuses before cancellation")
+		return &transitionError{from: currentOrder.Status, to: StatusCancelled}
+	}
+
+	log.Info().Stringer("order_id", orderID).Stringer("previous_status", previous).Str("reason", reason).Msg("service: unpaid order cancelled")
+	s.NotifyStatusChange(ctx, orderID, previous, StatusCancelled)
+	return nil
+}
+
+func (s *service) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus OrderStatus) ([]BulkUpdateResult, error) {
+	if _, known := allowedTransitions[newStatus]; !known {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownStatus, newStatus)
+	}
+
+	// Статусы, из которых разрешён переход в newStatus
+	fromStatuses := make([]OrderStatus, 0)
+	for from, transitions := range allowedTransitions {
+		if transitions[newStatus] {
+			fromStatuses = append(fromStatuses, from)
+		}
+	}
+	slices.Sort(fromStatuses)
+
+	// Повторяющиеся ID обрабатываем один раз, но в ответе оставляем каждое вхождение с одинаковым результатом
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	previous := make(map[uuid.UUID]OrderStatus, len(unique))
+	for start := 0; start < len(unique); start += bulkUpdateBatchSize {
+		end := min(start+bulkUpdateBatchSize, len(unique))
+
+		batchPrevious, err := s.orderRepo.BulkUpdateOrderStatus(ctx, unique[start:end], newStatus, fromStatuses)
+		if err != nil {
+			log.Error().Err(err).Stringer("new_status", newStatus).Int("batch_start", start).Msg("service: failed to bulk update order status in repository")
+			return nil, fmt.Errorf("service: failed to bulk update order status: %w", err)
+		}
+		for id, status := range batchPrevious {
+			previous[id] = status
+		}
+	}
+
+	outcomes := make(map[uuid.UUID]BulkUpdateOutcome, len(unique))
+	for _, id := range unique {
+		status, found := previous[id]
+		switch {
+		case !found:
+			outcomes[id] = BulkNotFound
+		case status == newStatus:
+			outcomes[id] = BulkAlreadySet
+		case allowedTransitions[status][newStatus]:
+			outcomes[id] = BulkUpdated
+			s.NotifyStatusChange(ctx, id, status, newStatus)
+		default:
+			outcomes[id] = BulkInvalidTransition
+		}
+	}
+
+	results := make([]BulkUpdateResult, 0, len(ids))
+	counts := make(map[BulkUpdateOutcome]int)
+	for _, id := range ids {
+		results = append(results, BulkUpdateResult{
+			OrderID:        id,
+			Outcome:        outcomes[id],
+			PreviousStatus: previous[id],
+		})
+		counts[outcomes[id]]++
+	}
+
+	log.Info().
+		Stringer("new_status", newStatus).
+		Int("requested", len(ids)).
+		Int("updated", counts[BulkUpdated]).
+		Int("already_set", counts[BulkAlreadySet]).
+		Int("invalid_transition", counts[BulkInvalidTransition]).
+		Int("not_found", counts[BulkNotFound]).
+		Msg("service: bulk order status update finished")
+
+	return results, nil
+}
+
+func (s *service) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	count, err := s.orderRepo.CountUserOrders(ctx, userID, activeStatuses)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("service: failed to count active orders in repository")
+		return 0, fmt.Errorf("service: failed to count active orders: %w", err)
+	}
+	return count, nil
+}
+
+func (s *service) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	anonymized, err := s.orderRepo.AnonymizeUserOrders(ctx, userID, finalStatuses)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("service: failed to anonymize orders in repository")
+		return 0, fmt.Errorf("service: failed to anonymize user orders: %w", err)
+	}
+
+	// user-service не даёт удалить покупателя с активными заказами, но заказ мог появиться между проверкой и удалением.
+	// Такие заказы ещё нужно доставить: адрес в них сотрёт триггер, когда заказ будет доставлен или отменён.
+	active, err := s.orderRepo.CountUserOrders(ctx, userID, activeStatuses)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("service: failed to count active orders in repository")
+	} else if active > 0 {
+		log.Info().Stringer("user_id", userID).Int("active_orders", active).Msg("service: deleted user still has active orders, they will be anonymized once finished")
+	}
+
+	log.Info().Stringer("user_id", userID).Int("anonymized_orders", anonymized).Msg("service: user orders anonymized")
+	return anonymized, nil
+}
@@ -52,9 +52,51 @@ func (m *MockOrderRepository) UpdateOrderStatus(ctx context.Context, orderID uui
 	return args.Error(0)
 }
 
+func (m *MockOrderRepository) SetShippingLine(ctx context.Context, line *ShippingLine, editableStatuses []OrderStatus) (float64, error) {
+	args := m.Called(ctx, line, editableStatuses)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+func (m *MockOrderRepository) FindStaleOrderIDs(ctx context.Context, statuses []OrderStatus, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, statuses, createdBefore, limit)
+
+	var idsToReturn []uuid.UUID
+	if args.Get(0) != nil {
+		idsToReturn = args.Get(0).([]uuid.UUID)
+	}
+
+	return idsToReturn, args.Error(1)
+}
+
+func (m *MockOrderRepository) CancelOrder(ctx context.Context, orderID uuid.UUID, reason string, fromStatuses []OrderStatus) (OrderStatus, bool, error) {
+	args := m.Called(ctx, orderID, reason, fromStatuses)
+	return args.Get(0).(OrderStatus), args.Bool(1), args.Error(2)
+}
+
+func (m *MockOrderRepository) BulkUpdateOrderStatus(ctx context.Context, orderIDs []uuid.UUID, newStatus OrderStatus, fromStatuses []OrderStatus) (map[uuid.UUID]OrderStatus, error) {
+	args := m.Called(ctx, orderIDs, newStatus, fromStatuses)
+
+	var previousToReturn map[uuid.UUID]OrderStatus
+	if args.Get(0) != nil {
+		previousToReturn = args.Get(0).(map[uuid.UUID]OrderStatus)
+	}
+
+	return previousToReturn, args.Error(1)
+}
+
+func (m *MockOrderRepository) CountUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error) {
+	args := m.Called(ctx, userID, statuses)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderRepository) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID, statuses []OrderStatus) (int, error) {
+	args := m.Called(ctx, userID, statuses)
+	return args.Int(0), args.Error(1)
+}
+
 func TestService_CreateOrder_Success(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo) // Используем твою фабричную функцию
+	orderService := NewService(mockRepo, nil) // Используем твою фабричную функцию
 
 	ctx := context.Background()
 
@@ -122,7 +164,7 @@ func TestService_CreateOrder_Success(t *testing.T) {
 
 func TestService_CreateOrder_Error_RepositoryFails(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 
 	ctx := context.Background()
 
@@ -162,7 +204,7 @@ func TestService_CreateOrder_Error_RepositoryFails(t *testing.T) {
 
 func TestService_CreateOrder_Error_EmptyOrderItems(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 
 	ctx := context.Background()
 
@@ -182,7 +224,7 @@ func TestService_CreateOrder_Error_EmptyOrderItems(t *testing.T) {
 
 func TestService_CreateOrder_Error_InvalidOrderItem_ZeroQuantity(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 
 	ctx := context.Background()
 
@@ -205,7 +247,7 @@ func TestService_CreateOrder_Error_InvalidOrderItem_ZeroQuantity(t *testing.T) {
 
 func TestService_CreateOrder_Error_InvalidOrderItem_NegativePrice(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 
 	ctx := context.Background()
 
@@ -228,7 +270,7 @@ func TestService_CreateOrder_Error_InvalidOrderItem_NegativePrice(t *testing.T)
 
 func TestService_CreateOrder_Error_InvalidOrderItem_NilProductID(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 
 	ctx := context.Background()
 
@@ -250,7 +292,7 @@ func TestService_CreateOrder_Error_InvalidOrderItem_NilProductID(t *testing.T) {
 
 func TestService_GetOrderByID_Success(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	expectedOrderID := uuid.Must(uuid.NewV4())
@@ -313,7 +355,7 @@ func TestService_GetOrderByID_Success(t *testing.T) {
 
 func TestService_GetOrderByID_NotFound(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	searchOrderID := uuid.Must(uuid.NewV4())
@@ -333,7 +375,7 @@ func TestService_GetOrderByID_NotFound(t *testing.T) {
 
 func TestService_GetOrderByID_RepoError(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	searchOrderID := uuid.Must(uuid.NewV4())
@@ -353,7 +395,7 @@ func TestService_GetOrderByID_RepoError(t *testing.T) {
 }
 func TestService_GetOrdersByUserID_Success(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	userID := uuid.Must(uuid.NewV4())
@@ -445,7 +487,7 @@ func TestService_GetOrdersByUserID_Success(t *testing.T) {
 
 func TestService_GetOrdersByUserID_RepoError(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	userID := uuid.Must(uuid.NewV4())
@@ -468,7 +510,7 @@ func TestService_GetOrdersByUserID_RepoError(t *testing.T) {
 
 func TestService_UpdateOrderStatus_Success(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	orderID := uuid.Must(uuid.NewV4())
@@ -497,7 +539,7 @@ func TestService_UpdateOrderStatus_Success(t *testing.T) {
 
 func TestService_UpdateOrderStatus_OrderNotFound(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	nonExistingOrderID := uuid.Must(uuid.NewV4())
@@ -515,7 +557,7 @@ func TestService_UpdateOrderStatus_OrderNotFound(t *testing.T) {
 
 func TestService_UpdateOrderStatus_StatusAlreadySet(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	orderID := uuid.Must(uuid.NewV4())
@@ -540,7 +582,7 @@ func TestService_UpdateOrderStatus_StatusAlreadySet(t *testing.T) {
 
 func TestService_UpdateOrderStatus_InvalidTransition(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	orderID := uuid.Must(uuid.NewV4())
@@ -566,7 +608,7 @@ func TestService_UpdateOrderStatus_InvalidTransition(t *testing.T) {
 
 func TestService_UpdateOrderStatus_RepoUpdateError(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	orderID := uuid.Must(uuid.NewV4())
@@ -586,7 +628,7 @@ func TestService_UpdateOrderStatus_RepoUpdateError(t *testing.T) {
 
 func TestService_UpdateOrderStatus_RepoFailsOnUpdateCall(t *testing.T) {
 	mockRepo := new(MockOrderRepository)
-	orderService := NewService(mockRepo)
+	orderService := NewService(mockRepo, nil)
 	ctx := context.Background()
 
 	orderID := uuid.Must(uuid.NewV4())
@@ -615,3 +657,344 @@ func TestService_UpdateOrderStatus_RepoFailsOnUpdateCall(t *testing.T) {
 	require.ErrorIs(t, err, repoUpdateErr)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestService_UpdateOrderStatus_PartiallyShippedTransitions(t *testing.T) {
+	testCases := []struct {
+		name      string
+		current   OrderStatus
+		newStatus OrderStatus
+		allowed   bool
+	}{
+		{name: "paid to partially shipped", current: StatusPaid, newStatus: StatusPartiallyShipped, allowed: true},
+		{name: "partially shipped to shipped", current: StatusPartiallyShipped, newStatus: StatusShipped, allowed: true},
+		{name: "partially shipped to delivered", current: StatusPartiallyShipped, newStatus: StatusDelivered, allowed: false},
+		{name: "new to partially shipped", current: StatusNew, newStatus: StatusPartiallyShipped, allowed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockOrderRepository)
+			orderService := NewService(mockRepo, nil)
+			ctx := context.Background()
+			orderID := uuid.Must(uuid.NewV4())
+
+			mockRepo.On("GetOrderByID", ctx, orderID).
+				Return(&Order{ID: orderID, Status: tc.current}, nil).
+				Once()
+			if tc.allowed {
+				mockRepo.On("UpdateOrderStatus", ctx, orderID, tc.newStatus).Return(nil).Once()
+			}
+
+			err := orderService.UpdateOrderStatus(ctx, orderID, tc.newStatus)
+			if tc.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, ErrInvalidStatusTransition)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_SetShippingLine_Success(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	line := &ShippingLine{OrderID: orderID, OptionID: "flat:standard", Provider: "flat", Price: 4.99}
+	mockRepo.On("SetShippingLine", ctx, line, []OrderStatus{StatusNew, StatusProcessing}).Return(24.99, nil).Once()
+	mockRepo.On("GetOrderByID", ctx, orderID).
+		Return(&Order{ID: orderID, Status: StatusNew, TotalAmount: 24.99, ShippingLine: line}, nil).
+		Once()
+
+	updated, err := orderService.SetShippingLine(ctx, line)
+	require.NoError(t, err)
+	assert.InDelta(t, 24.99, updated.TotalAmount, 0.001)
+	assert.Equal(t, line, updated.ShippingLine)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_SetShippingLine_LockedAfterPayment(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	ctx := context.Background()
+
+	line := &ShippingLine{OrderID: uuid.Must(uuid.NewV4()), Price: 4.99}
+	mockRepo.On("SetShippingLine", ctx, line, mock.Anything).Return(0.0, ErrShippingLocked).Once()
+
+	_, err := orderService.SetShippingLine(ctx, line)
+	require.ErrorIs(t, err, ErrShippingLocked)
+	mockRepo.AssertNotCalled(t, "GetOrderByID", mock.Anything, mock.Anything)
+}
+
+func TestService_CancelUnpaidOrder(t *testing.T) {
+	ctx := context.Background()
+	orderID := uuid.Must(uuid.NewV4())
+	unpaid := []OrderStatus{StatusNew, StatusProcessing}
+
+	testCases := []struct {
+		name          string
+		cancelled     bool
+		currentStatus OrderStatus
+		expectedErr   error
+	}{
+		{name: "cancelled", cancelled: true},
+		{name: "paid meanwhile", cancelled: false, currentStatus: StatusPaid, expectedErr: ErrInvalidStatusTransition},
+		{name: "already cancelled", cancelled: false, currentStatus: StatusCancelled, expectedErr: ErrStatusAlreadySet},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockOrderRepository)
+			orderService := NewService(mockRepo, nil)
+
+			previous := OrderStatus("")
+			if tc.cancelled {
+				previous = StatusProcessing
+			}
+			mockRepo.On("CancelOrder", ctx, orderID, CancellationReasonPaymentTimeout, unpaid).Return(previous, tc.cancelled, nil).Once()
+			if !tc.cancelled {
+				mockRepo.On("GetOrderByID", ctx, orderID).Return(&Order{ID: orderID, Status: tc.currentStatus}, nil).Once()
+			}
+
+			err := orderService.CancelUnpaidOrder(ctx, orderID, CancellationReasonPaymentTimeout)
+			if tc.expectedErr == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.expectedErr)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_CancelUnpaidOrder_NotFound(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	ctx := context.Background()
+	orderID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("CancelOrder", ctx, orderID, CancellationReasonPaymentTimeout, mock.Anything).Return(OrderStatus(""), false, nil).Once()
+	mockRepo.On("GetOrderByID", ctx, orderID).Return(nil, ErrOrderNotFound).Once()
+
+	err := orderService.CancelUnpaidOrder(ctx, orderID, CancellationReasonPaymentTimeout)
+	require.ErrorIs(t, err, ErrOrderNotFound)
+}
+
+func TestService_CancelUnpaidOrder_NotifiesPreviousStatus(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	broadcaster := NewBroadcaster(1)
+	orderService := NewService(mockRepo, nil, broadcaster)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	changes, unsubscribe := broadcaster.Subscribe(orderID)
+	defer unsubscribe()
+
+	mockRepo.On("CancelOrder", ctx, orderID, CancellationReasonPaymentTimeout, mock.Anything).Return(StatusProcessing, true, nil).Once()
+
+	require.NoError(t, orderService.CancelUnpaidOrder(ctx, orderID, CancellationReasonPaymentTimeout))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, StatusProcessing, change.From)
+		assert.Equal(t, StatusCancelled, change.To)
+	default:
+		t.Fatal("expected a status change event")
+	}
+}
+
+func TestService_BulkUpdateStatus_PerOrderResults(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	ctx := context.Background()
+
+	updatedID := uuid.Must(uuid.NewV4())
+	alreadySetID := uuid.Must(uuid.NewV4())
+	invalidID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+	ids := []uuid.UUID{updatedID, alreadySetID, invalidID, missingID, updatedID}
+
+	expectedFrom := []OrderStatus{StatusPaid, StatusPartiallyShipped, StatusProcessing}
+	mockRepo.On("BulkUpdateOrderStatus", ctx, []uuid.UUID{updatedID, alreadySetID, invalidID, missingID}, StatusShipped, expectedFrom).
+		Return(map[uuid.UUID]OrderStatus{
+			updatedID:    StatusPaid,
+			alreadySetID: StatusShipped,
+			invalidID:    StatusCancelled,
+		}, nil).Once()
+
+	results, err := orderService.BulkUpdateStatus(ctx, ids, StatusShipped)
+	require.NoError(t, err)
+	assert.Equal(t, []BulkUpdateResult{
+		{OrderID: updatedID, Outcome: BulkUpdated, PreviousStatus: StatusPaid},
+		{OrderID: alreadySetID, Outcome: BulkAlreadySet, PreviousStatus: StatusShipped},
+		{OrderID: invalidID, Outcome: BulkInvalidTransition, PreviousStatus: StatusCancelled},
+		{OrderID: missingID, Outcome: BulkNotFound},
+		{OrderID: updatedID, Outcome: BulkUpdated, PreviousStatus: StatusPaid},
+	}, results)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_BulkUpdateStatus_SplitsIntoBatches(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	ctx := context.Background()
+
+	ids := make([]uuid.UUID, bulkUpdateBatchSize+1)
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV4())
+	}
+
+	mockRepo.On("BulkUpdateOrderStatus", ctx, ids[:bulkUpdateBatchSize], StatusDelivered, []OrderStatus{StatusShipped}).
+		Return(map[uuid.UUID]OrderStatus{}, nil).Once()
+	mockRepo.On("BulkUpdateOrderStatus", ctx, ids[bulkUpdateBatchSize:], StatusDelivered, []OrderStatus{StatusShipped}).
+		Return(map[uuid.UUID]OrderStatus{}, nil).Once()
+
+	results, err := orderService.BulkUpdateStatus(ctx, ids, StatusDelivered)
+	require.NoError(t, err)
+	assert.Len(t, results, len(ids))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_BulkUpdateStatus_UnknownStatus(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+
+	_, err := orderService.BulkUpdateStatus(context.Background(), []uuid.UUID{uuid.Must(uuid.NewV4())}, OrderStatus("LOST"))
+	require.ErrorIs(t, err, ErrUnknownStatus)
+	mockRepo.AssertNotCalled(t, "BulkUpdateOrderStatus", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_UpdateOrderStatus_NotifiesListeners(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	broadcaster := NewBroadcaster(1)
+	orderService := NewService(mockRepo, nil, broadcaster)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	changes, unsubscribe := broadcaster.Subscribe(orderID)
+	defer unsubscribe()
+
+	mockRepo.On("GetOrderByID", ctx, orderID).Return(&Order{ID: orderID, Status: StatusPaid}, nil)
+	mockRepo.On("UpdateOrderStatus", ctx, orderID, StatusShipped).Return(nil).Once()
+
+	require.NoError(t, orderService.UpdateOrderStatus(ctx, orderID, StatusShipped))
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, orderID, change.OrderID)
+		assert.Equal(t, StatusPaid, change.From)
+		assert.Equal(t, StatusShipped, change.To)
+	default:
+		t.Fatal("expected a status change event")
+	}
+
+	// Запрещённый переход не должен порождать событие
+	err := orderService.UpdateOrderStatus(ctx, orderID, StatusNew)
+	require.ErrorIs(t, err, ErrInvalidStatusTransition)
+	assert.Empty(t, changes)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CountActiveOrders(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	userID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("CountUserOrders", mock.Anything, userID, []OrderStatus{StatusNew, StatusProcessing, StatusPaid, StatusPartiallyShipped, StatusShipped}).
+		Return(2, nil).Once()
+
+	count, err := orderService.CountActiveOrders(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_AnonymizeUserOrders_OnlyFinishedOrders(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	userID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("AnonymizeUserOrders", mock.Anything, userID, []OrderStatus{StatusDelivered, StatusCancelled}).Return(4, nil).Once()
+	mockRepo.On("CountUserOrders", mock.Anything, userID, mock.Anything).Return(1, nil).Once()
+
+	anonymized, err := orderService.AnonymizeUserOrders(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, anonymized)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_AnonymizeUserOrders_RepositoryError(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	orderService := NewService(mockRepo, nil)
+	userID := uuid.Must(uuid.NewV4())
+	dbErr := errors.New("connection reset")
+
+	mockRepo.On("AnonymizeUserOrders", mock.Anything, userID, mock.Anything).Return(0, dbErr).Once()
+
+	_, err := orderService.AnonymizeUserOrders(context.Background(), userID)
+	require.ErrorIs(t, err, dbErr)
+	mockRepo.AssertNotCalled(t, "CountUserOrders", mock.Anything, mock.Anything, mock.Anything)
+}
+
+type MockCustomerVerifier struct {
+	mock.Mock
+}
+
+func (m *MockCustomerVerifier) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestService_CreateOrder_VerifiedCustomer(t *testing.T) {
+	mockRepo := new(MockOrderRepository)
+	mockCustomers := new(MockCustomerVerifier)
+	orderService := NewService(mockRepo, mockCustomers)
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	mockCustomers.On("IsEmailVerified", ctx, userID).Return(true, nil).Once()
+	mockRepo.On("CreateOrder", ctx, mock.AnythingOfType("*order.Order")).Return(uuid.Must(uuid.NewV4()), nil).Once()
+
+	_, err := orderService.CreateOrder(ctx, &Order{
+		UserID:     userID,
+		OrderItems: []OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 10}},
+	})
+	require.NoError(t, err)
+	mockCustomers.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateOrder_CustomerCheckRejects(t *testing.T) {
+	tests := []struct {
+		name      string
+		verified  bool
+		verifyErr error
+		wantErr   error
+	}{
+		{name: "email not verified", verified: false, wantErr: ErrEmailNotVerified},
+		{name: "unknown customer", verifyErr: ErrCustomerNotFound, wantErr: ErrCustomerNotFound},
+		{name: "user-service unavailable", verifyErr: errors.New("connection refused"), wantErr: ErrCustomerCheckFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockOrderRepository)
+			mockCustomers := new(MockCustomerVerifier)
+			orderService := NewService(mockRepo, mockCustomers)
+			ctx := context.Background()
+
+			userID := uuid.Must(uuid.NewV4())
+			mockCustomers.On("IsEmailVerified", ctx, userID).Return(tt.verified, tt.verifyErr).Once()
+
+			createdOrder, err := orderService.CreateOrder(ctx, &Order{
+				UserID:     userID,
+				OrderItems: []OrderItem{{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 10}},
+			})
+			require.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, createdOrder)
+			mockRepo.AssertNotCalled(t, "CreateOrder", mock.Anything, mock.Anything)
+		})
+	}
+}
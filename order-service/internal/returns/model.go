@@ -0,0 +1,46 @@
+package returns
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type Status string
+
+const (
+	StatusRequested Status = "REQUESTED"
+	StatusApproved  Status = "APPROVED"
+	StatusRejected  Status = "REJECTED"
+	StatusReceived  Status = "RECEIVED"
+	StatusRefunded  Status = "REFUNDED"
+)
+
+func (s Status) String() string {
+	return string(s)
+}
+
+// Item - позиция заказа (или её часть), которую покупатель возвращает.
+type Item struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ReturnID    uuid.UUID `json:"return_id" db:"return_id"`
+	OrderItemID uuid.UUID `json:"order_item_id" db:"order_item_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+}
+
+type Return struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrderID        uuid.UUID  `json:"order_id" db:"order_id"`
+	UserID         uuid.UUID  `json:"user_id" db:"user_id"`
+	Status         Status     `json:"status" db:"status"`
+	Reason         string     `json:"reason" db:"reason"`
+	ResolutionNote string     `json:"resolution_note,omitempty" db:"resolution_note"` // Комментарий сотрудника при одобрении/отказе
+	RefundAmount   float64    `json:"refund_amount" db:"refund_amount"`
+	Items          []Item     `json:"items" db:"-"` // Хранятся в return_items
+	RequestedAt    time.Time  `json:"requested_at" db:"requested_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty" db:"resolved_at"` // Время одобрения или отказа
+	ReceivedAt     *time.Time `json:"received_at,omitempty" db:"received_at"`
+	RefundedAt     *time.Time `json:"refunded_at,omitempty" db:"refunded_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
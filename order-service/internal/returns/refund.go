@@ -0,0 +1,29 @@
+package returns
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RefundHook вызывается после приёмки возвращённых товаров на склад.
+// Реализация должна быть идемпотентной по ret.ID: при ошибке сотрудник может повторить возврат средств.
+type RefundHook interface {
+	Refund(ctx context.Context, ret *Return) error
+}
+
+// LogRefundHook только пишет в лог. Используется, пока к сервису не подключена платёжная система.
+type LogRefundHook struct{}
+
+func NewLogRefundHook() *LogRefundHook {
+	return &LogRefundHook{}
+}
+
+func (LogRefundHook) Refund(_ context.Context, ret *Return) error {
+	log.Info().
+		Stringer("return_id", ret.ID).
+		Stringer("order_id", ret.OrderID).
+		Float64("amount", ret.RefundAmount).
+		Msg("refund: refund requested")
+	return nil
+}
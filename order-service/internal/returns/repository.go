@@ -0,0 +1,317 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var (
+	ErrReturnNotFound = errors.New("return not found")
+	// ErrStatusChanged возвращается, если возврат успел перейти в другой статус параллельным запросом.
+	ErrStatusChanged = errors.New("return status was changed concurrently")
+)
+
+type Repository interface {
+	// Create блокирует заказ, передаёт в check уже созданные по нему возвраты и сохраняет ret,
+	// если check не вернул ошибку. Всё выполняется в одной транзакции.
+	Create(ctx context.Context, ret *Return, check func(existing []Return) error) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Return, error)
+	GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Return, error)
+	// UpdateStatus сохраняет новый статус и отметки времени, только если текущий статус в БД равен from.
+	UpdateStatus(ctx context.Context, ret *Return, from Status) error
+	// OrderDeliveredAt возвращает время последнего перехода заказа в DELIVERED по истории статусов.
+	// Нулевое время, если такого перехода в истории нет.
+	OrderDeliveredAt(ctx context.Context, orderID uuid.UUID) (time.Time, error)
+}
+
+// querier - общее подмножество pgxpool.Pool и pgx.Tx для чтения внутри и вне транзакции.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{db: db}
+}
+
+const selectReturnColumns = `
+	SELECT id, order_id, user_id, status, reason, resolution_note, refund_amount,
+	       requested_at, resolved_at, received_at, refunded_at, created_at, updated_at
+	FROM order_service.returns
+`
+
+func (r *postgresRepository) Create(ctx context.Context, ret *Return, check func(existing []Return) error) (err error) {
+	if ret.ID == uuid.Nil {
+		genID, genErr := uuid.NewV4()
+		if genErr != nil {
+			return fmt.Errorf("repository: failed to generate return ID: %w", genErr)
+		}
+		ret.ID = genID
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Stringer("return_id", ret.ID).Msg("Panic recovered during return Create, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("return_id", ret.ID).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("return_id", ret.ID).Msg("Failed to rollback transaction")
+			}
+		} else if commitErr := tx.Commit(ctx); commitErr != nil {
+			log.Error().Err(commitErr).Stringer("return_id", ret.ID).Msg("Failed to commit transaction")
+			err = fmt.Errorf("repository: failed to commit transaction: %w", commitErr)
+		}
+	}()
+
+	// Блокировка заказа выстраивает параллельные заявки в очередь, чтобы вместе они не превысили доставленное.
+	var locked uuid.UUID
+	err = tx.QueryRow(ctx, `SELECT id FROM order_service.orders WHERE id = $1 FOR UPDATE`, ret.OrderID).Scan(&locked)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return order.ErrOrderNotFound
+		}
+		return fmt.Errorf("repository: failed to lock order %s: %w", ret.OrderID, err)
+	}
+
+	existing, err := selectByOrderID(ctx, tx, ret.OrderID)
+	if err != nil {
+		return err
+	}
+	if err = check(existing); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	ret.RequestedAt = now
+
+	queryReturn := `
+		INSERT INTO order_service.returns (id, order_id, user_id, status, reason, resolution_note, refund_amount, requested_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err = tx.Exec(ctx, queryReturn,
+		ret.ID,
+		ret.OrderID,
+		ret.UserID,
+		string(ret.Status),
+		ret.Reason,
+		ret.ResolutionNote,
+		ret.RefundAmount,
+		ret.RequestedAt,
+		now,
+		now,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: failed to insert return: %w", err)
+	}
+	ret.CreatedAt = now
+	ret.UpdatedAt = now
+
+	queryItem := `
+		INSERT INTO order_service.return_items (id, return_id, order_item_id, quantity)
+		VALUES ($1, $2, $3, $4)
+	`
+	for i := range ret.Items {
+		item := &ret.Items[i]
+
+		itemID, genErr := uuid.NewV4()
+		if genErr != nil {
+			return fmt.Errorf("repository: failed to generate return item ID: %w", genErr)
+		}
+		item.ID = itemID
+		item.ReturnID = ret.ID
+
+		_, err = tx.Exec(ctx, queryItem, item.ID, item.ReturnID, item.OrderItemID, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("repository: failed to insert return item for return %s: %w", ret.ID, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Return, error) {
+	query := selectReturnColumns + `WHERE id = $1`
+
+	ret, err := scanReturn(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrReturnNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to select return by id %s: %w", id, err)
+	}
+
+	if err := loadItems(ctx, r.db, []*Return{ret}); err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func (r *postgresRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Return, error) {
+	return selectByOrderID(ctx, r.db, orderID)
+}
+
+func selectByOrderID(ctx context.Context, q querier, orderID uuid.UUID) ([]Return, error) {
+	query := selectReturnColumns + `WHERE order_id = $1 ORDER BY requested_at ASC`
+
+	rows, err := q.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query returns for order id %s: %w", orderID, err)
+	}
+	defer rows.Close()
+
+	returns := make([]*Return, 0)
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan return for order id %s: %w", orderID, err)
+		}
+		returns = append(returns, ret)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating returns for order id %s: %w", orderID, err)
+	}
+
+	if err := loadItems(ctx, q, returns); err != nil {
+		return nil, err
+	}
+
+	result := make([]Return, 0, len(returns))
+	for _, ret := range returns {
+		result = append(result, *ret)
+	}
+
+	return result, nil
+}
+
+func (r *postgresRepository) UpdateStatus(ctx context.Context, ret *Return, from Status) error {
+	query := `
+		UPDATE order_service.returns
+		SET status = $1, resolution_note = $2, resolved_at = $3, received_at = $4, refunded_at = $5, updated_at = $6
+		WHERE id = $7 AND status = $8
+	`
+
+	now := time.Now().UTC()
+	cmdTag, err := r.db.Exec(ctx, query,
+		string(ret.Status),
+		ret.ResolutionNote,
+		ret.ResolvedAt,
+		ret.ReceivedAt,
+		ret.RefundedAt,
+		now,
+		ret.ID,
+		string(from),
+	)
+	if err != nil {
+		return fmt.Errorf("repository: failed to update return %s status: %w", ret.ID, err)
+	}
+	if cmdTag.RowsAffected() == 0 {
+		if _, err := r.GetByID(ctx, ret.ID); err != nil {
+			return err
+		}
+		return ErrStatusChanged
+	}
+	ret.UpdatedAt = now
+
+	return nil
+}
+
+func (r *postgresRepository) OrderDeliveredAt(ctx context.Context, orderID uuid.UUID) (time.Time, error) {
+	query := `
+		SELECT MAX(changed_at)
+		FROM order_service.order_status_history
+		WHERE order_id = $1 AND to_status = $2
+	`
+
+	var deliveredAt *time.Time
+	if err := r.db.QueryRow(ctx, query, orderID, string(order.StatusDelivered)).Scan(&deliveredAt); err != nil {
+		return time.Time{}, fmt.Errorf("repository: failed to select delivery time for order %s: %w", orderID, err)
+	}
+	if deliveredAt == nil {
+		return time.Time{}, nil
+	}
+
+	return *deliveredAt, nil
+}
+
+func scanReturn(row pgx.Row) (*Return, error) {
+	var ret Return
+	err := row.Scan(
+		&ret.ID,
+		&ret.OrderID,
+		&ret.UserID,
+		&ret.Status,
+		&ret.Reason,
+		&ret.ResolutionNote,
+		&ret.RefundAmount,
+		&ret.RequestedAt,
+		&ret.ResolvedAt,
+		&ret.ReceivedAt,
+		&ret.RefundedAt,
+		&ret.CreatedAt,
+		&ret.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func loadItems(ctx context.Context, q querier, returns []*Return) error {
+	if len(returns) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*Return, len(returns))
+	ids := make([]uuid.UUID, 0, len(returns))
+	for _, ret := range returns {
+		ret.Items = make([]Item, 0)
+		byID[ret.ID] = ret
+		ids = append(ids, ret.ID)
+	}
+
+	query := `
+		SELECT id, return_id, order_item_id, quantity
+		FROM order_service.return_items
+		WHERE return_id = ANY($1)
+	`
+	rows, err := q.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query return items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.ReturnID, &item.OrderItemID, &item.Quantity); err != nil {
+			return fmt.Errorf("repository: failed to scan return item: %w", err)
+		}
+		if ret, ok := byID[item.ReturnID]; ok {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating return items: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package returns_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/returns"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=order_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func truncateTables(tb testing.TB, pool *pgxpool.Pool) {
+	tb.Helper()
+	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE order_service.returns, order_service.order_items, order_service.orders CASCADE")
+	require.NoError(tb, err, "failed to truncate tables")
+}
+
+func createTestOrder(t *testing.T) *order.Order {
+	t.Helper()
+	o := &order.Order{
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: order.StatusDelivered,
+		OrderItems: []order.OrderItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 2, PricePerUnit: 10},
+		},
+	}
+	_, err := order.NewRepository(testDB).CreateOrder(context.Background(), o)
+	require.NoError(t, err)
+	return o
+}
+
+func TestReturnRepository_CreateAndGet(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := returns.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t)
+
+	input := &returns.Return{
+		OrderID:      o.ID,
+		UserID:       o.UserID,
+		Status:       returns.StatusRequested,
+		Reason:       "damaged",
+		RefundAmount: 10,
+		Items:        []returns.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+	}
+	require.NoError(t, repo.Create(ctx, input, func([]returns.Return) error { return nil }))
+	require.NotEqual(t, uuid.Nil, input.ID)
+
+	found, err := repo.GetByID(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, returns.StatusRequested, found.Status)
+	assert.InDelta(t, 10.0, found.RefundAmount, 0.001)
+	require.Len(t, found.Items, 1)
+	assert.Nil(t, found.ResolvedAt)
+
+	byOrder, err := repo.GetByOrderID(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Len(t, byOrder, 1)
+
+	_, err = repo.GetByID(ctx, uuid.Must(uuid.NewV4()))
+	require.ErrorIs(t, err, returns.ErrReturnNotFound)
+}
+
+func TestReturnRepository_UpdateStatus_ChecksCurrentStatus(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := returns.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t)
+
+	input := &returns.Return{
+		OrderID: o.ID,
+		UserID:  o.UserID,
+		Status:  returns.StatusRequested,
+		Reason:  "wrong size",
+		Items:   []returns.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 2}},
+	}
+	require.NoError(t, repo.Create(ctx, input, func([]returns.Return) error { return nil }))
+
+	resolvedAt := time.Now().UTC()
+	input.Status = returns.StatusApproved
+	input.ResolutionNote = "ok"
+	input.ResolvedAt = &resolvedAt
+	require.NoError(t, repo.UpdateStatus(ctx, input, returns.StatusRequested))
+
+	input.Status = returns.StatusRejected
+	err := repo.UpdateStatus(ctx, input, returns.StatusRequested)
+	require.ErrorIs(t, err, returns.ErrStatusChanged)
+
+	found, err := repo.GetByID(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, returns.StatusApproved, found.Status)
+	assert.Equal(t, "ok", found.ResolutionNote)
+	require.NotNil(t, found.ResolvedAt)
+}
+
+func TestReturnRepository_OrderDeliveredAt(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := returns.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t)
+
+	deliveredAt, err := repo.OrderDeliveredAt(ctx, o.ID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), deliveredAt, time.Minute)
+
+	deliveredAt, err = repo.OrderDeliveredAt(ctx, uuid.Must(uuid.NewV4()))
+	require.NoError(t, err)
+	assert.True(t, deliveredAt.IsZero())
+}
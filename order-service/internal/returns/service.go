@@ -0,0 +1,303 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+)
+
+var (
+	ErrInvalidReturn           = errors.New("invalid return request")
+	ErrOrderNotReturnable      = errors.New("order is not delivered")
+	ErrReturnWindowExpired     = errors.New("return window has expired")
+	ErrUnknownOrderItem        = errors.New("order item does not belong to order")
+	ErrQuantityExceeded        = errors.New("returned quantity exceeds ordered quantity")
+	ErrInvalidReturnTransition = errors.New("invalid return status transition")
+	ErrRefundFailed            = errors.New("refund failed")
+)
+
+// allowedTransitions - статусная модель возврата. REJECTED и REFUNDED - конечные статусы.
+var allowedTransitions = map[Status][]Status{
+	StatusRequested: {StatusApproved, StatusRejected},
+	StatusApproved:  {StatusReceived},
+	StatusReceived:  {StatusRefunded},
+}
+
+type Service interface {
+	RequestReturn(ctx context.Context, input *Return) (*Return, error)
+	GetReturn(ctx context.Context, id uuid.UUID) (*Return, error)
+	GetReturnsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Return, error)
+	Approve(ctx context.Context, id uuid.UUID, note string) (*Return, error)
+	Reject(ctx context.Context, id uuid.UUID, note string) (*Return, error)
+	// Receive фиксирует приёмку товаров и сразу запускает возврат средств.
+	Receive(ctx context.Context, id uuid.UUID) (*Return, error)
+	// Refund повторяет возврат средств для принятого возврата, если RefundHook ранее завершился ошибкой.
+	Refund(ctx context.Context, id uuid.UUID) (*Return, error)
+}
+
+type service struct {
+	repo        Repository
+	orderSvc    order.Service
+	shipmentSvc shipment.Service
+	refundHook  RefundHook
+	window      time.Duration
+}
+
+// NewService создаёт сервис возвратов. window - срок после доставки, в течение которого принимаются заявки.
+func NewService(repo Repository, orderSvc order.Service, shipmentSvc shipment.Service, refundHook RefundHook, window time.Duration) Service {
+	return &service{
+		repo:        repo,
+		orderSvc:    orderSvc,
+		shipmentSvc: shipmentSvc,
+		refundHook:  refundHook,
+		window:      window,
+	}
+}
+
+func (s *service) RequestReturn(ctx context.Context, input *Return) (*Return, error) {
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrInvalidReturn)
+	}
+	if len(input.Items) == 0 {
+		return nil, fmt.Errorf("%w: return must contain at least one item", ErrInvalidReturn)
+	}
+
+	currentOrder, err := s.orderSvc.GetOrderByID(ctx, input.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	if currentOrder.Status != order.StatusDelivered {
+		return nil, fmt.Errorf("%w: order status is %s", ErrOrderNotReturnable, currentOrder.Status)
+	}
+
+	deliveredAt, err := s.deliveredAt(ctx, currentOrder)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(deliveredAt) > s.window {
+		log.Warn().Stringer("order_id", currentOrder.ID).Time("delivered_at", deliveredAt).Msg("service: return requested after window expired")
+		return nil, ErrReturnWindowExpired
+	}
+
+	items := make(map[uuid.UUID]order.OrderItem, len(currentOrder.OrderItems))
+	for _, item := range currentOrder.OrderItems {
+		items[item.ID] = item
+	}
+
+	var refundAmount float64
+	for _, item := range input.Items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for order item %s must be greater than zero", ErrInvalidReturn, item.OrderItemID)
+		}
+		orderItem, ok := items[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOrderItem, item.OrderItemID)
+		}
+		refundAmount += float64(item.Quantity) * orderItem.PricePerUnit
+	}
+
+	input.ID = uuid.Nil
+	input.UserID = currentOrder.UserID
+	input.Status = StatusRequested
+	input.ResolutionNote = ""
+	input.RefundAmount = math.Round(refundAmount*100) / 100
+	input.ResolvedAt = nil
+	input.ReceivedAt = nil
+	input.RefundedAt = nil
+
+	// Уже заявленные количества проверяются под блокировкой заказа, в транзакции вставки.
+	var checkErr error
+	err = s.repo.Create(ctx, input, func(existing []Return) error {
+		returned := returnedQuantities(existing)
+		for _, item := range input.Items {
+			returned[item.OrderItemID] += item.Quantity
+			if ordered := items[item.OrderItemID].Quantity; returned[item.OrderItemID] > ordered {
+				checkErr = fmt.Errorf("%w: order item %s ordered %d, returned %d", ErrQuantityExceeded, item.OrderItemID, ordered, returned[item.OrderItemID])
+				return checkErr
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if checkErr != nil || errors.Is(err, order.ErrOrderNotFound) {
+			return nil, err
+		}
+		log.Error().Err(err).Stringer("order_id", input.OrderID).Msg("service: failed to create return in repository")
+		return nil, fmt.Errorf("service: failed to create return: %w", err)
+	}
+
+	log.Info().Stringer("return_id", input.ID).Stringer("order_id", input.OrderID).Float64("refund_amount", input.RefundAmount).Msg("service: return requested")
+	return input, nil
+}
+
+func (s *service) GetReturn(ctx context.Context, id uuid.UUID) (*Return, error) {
+	found, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrReturnNotFound) {
+			return nil, ErrReturnNotFound
+		}
+		log.Error().Err(err).Stringer("return_id", id).Msg("service: failed to fetch return")
+		return nil, fmt.Errorf("service: failed to fetch return: %w", err)
+	}
+
+	return found, nil
+}
+
+func (s *service) GetReturnsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Return, error) {
+	if _, err := s.orderSvc.GetOrderByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	returns, err := s.repo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("service: failed to fetch returns for order")
+		return nil, fmt.Errorf("service: failed to fetch returns: %w", err)
+	}
+
+	return returns, nil
+}
+
+func (s *service) Approve(ctx context.Context, id uuid.UUID, note string) (*Return, error) {
+	return s.transition(ctx, id, StatusApproved, func(ret *Return, now time.Time) {
+		ret.ResolutionNote = strings.TrimSpace(note)
+		ret.ResolvedAt = &now
+	})
+}
+
+func (s *service) Reject(ctx context.Context, id uuid.UUID, note string) (*Return, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: rejection note is required", ErrInvalidReturn)
+	}
+
+	return s.transition(ctx, id, StatusRejected, func(ret *Return, now time.Time) {
+		ret.ResolutionNote = note
+		ret.ResolvedAt = &now
+	})
+}
+
+func (s *service) Receive(ctx context.Context, id uuid.UUID) (*Return, error) {
+	received, err := s.transition(ctx, id, StatusReceived, func(ret *Return, now time.Time) {
+		ret.ReceivedAt = &now
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refund(ctx, received)
+}
+
+func (s *service) Refund(ctx context.Context, id uuid.UUID) (*Return, error) {
+	current, err := s.GetReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Status != StatusReceived {
+		return nil, fmt.Errorf("%w: from %s to %s", ErrInvalidReturnTransition, current.Status, StatusRefunded)
+	}
+
+	return s.refund(ctx, current)
+}
+
+// refund вызывает RefundHook и переводит возврат в REFUNDED.
+// При ошибке хука возврат остаётся в RECEIVED, чтобы его можно было повторить через Refund.
+func (s *service) refund(ctx context.Context, ret *Return) (*Return, error) {
+	if err := s.refundHook.Refund(ctx, ret); err != nil {
+		log.Error().Err(err).Stringer("return_id", ret.ID).Msg("service: refund hook failed")
+		return nil, fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+
+	return s.transition(ctx, ret.ID, StatusRefunded, func(ret *Return, now time.Time) {
+		ret.RefundedAt = &now
+	})
+}
+
+func (s *service) transition(ctx context.Context, id uuid.UUID, to Status, apply func(ret *Return, now time.Time)) (*Return, error) {
+	current, err := s.GetReturn(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := current.Status
+	if !isTransitionAllowed(from, to) {
+		log.Warn().Stringer("return_id", id).Stringer("from", from).Stringer("to", to).Msg("service: invalid return status transition")
+		return nil, fmt.Errorf("%w: from %s to %s", ErrInvalidReturnTransition, from, to)
+	}
+
+	current.Status = to
+	apply(current, time.Now().UTC())
+
+	if err := s.repo.UpdateStatus(ctx, current, from); err != nil {
+		if errors.Is(err, ErrReturnNotFound) || errors.Is(err, ErrStatusChanged) {
+			return nil, err
+		}
+		log.Error().Err(err).Stringer("return_id", id).Stringer("to", to).Msg("service: failed to update return status")
+		return nil, fmt.Errorf("service: failed to update return status: %w", err)
+	}
+
+	log.Info().Stringer("return_id", id).Stringer("from", from).Stringer("to", to).Msg("service: return status updated")
+	return current, nil
+}
+
+// deliveredAt возвращает момент доставки последнего отправления.
+// Если заказ переведён в DELIVERED вручную без отправлений, используется время перехода в DELIVERED из истории статусов.
+func (s *service) deliveredAt(ctx context.Context, o *order.Order) (time.Time, error) {
+	shipments, err := s.shipmentSvc.GetShipmentsByOrderID(ctx, o.ID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest time.Time
+	for _, sh := range shipments {
+		if sh.DeliveredAt != nil && sh.DeliveredAt.After(latest) {
+			latest = *sh.DeliveredAt
+		}
+	}
+	if !latest.IsZero() {
+		return latest, nil
+	}
+
+	latest, err = s.repo.OrderDeliveredAt(ctx, o.ID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", o.ID).Msg("service: failed to fetch order delivery time")
+		return time.Time{}, fmt.Errorf("service: failed to fetch order delivery time: %w", err)
+	}
+	if latest.IsZero() {
+		log.Warn().Stringer("order_id", o.ID).Msg("service: delivered order has no recorded delivery time")
+		return time.Time{}, fmt.Errorf("%w: delivery time is unknown", ErrOrderNotReturnable)
+	}
+
+	return latest, nil
+}
+
+func isTransitionAllowed(from, to Status) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// returnedQuantities считает уже заявленные к возврату количества; отклонённые заявки не учитываются.
+func returnedQuantities(returns []Return) map[uuid.UUID]int {
+	result := make(map[uuid.UUID]int)
+	for _, ret := range returns {
+		if ret.Status == StatusRejected {
+			continue
+		}
+		for _, item := range ret.Items {
+			result[item.OrderItemID] += item.Quantity
+		}
+	}
+	return result
+}
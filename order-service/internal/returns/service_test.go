@@ -0,0 +1,413 @@
+package returns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+)
+
+type MockReturnRepository struct {
+	mock.Mock
+}
+
+// Create эмулирует транзакцию: передаёт в check возвраты заказа, заданные в ожидании.
+func (m *MockReturnRepository) Create(ctx context.Context, ret *Return, check func(existing []Return) error) error {
+	args := m.Called(ctx, ret)
+	if err := args.Error(1); err != nil {
+		return err
+	}
+	return check(args.Get(0).([]Return))
+}
+
+func (m *MockReturnRepository) GetByID(ctx context.Context, id uuid.UUID) (*Return, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Return), args.Error(1)
+}
+
+func (m *MockReturnRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Return, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Return), args.Error(1)
+}
+
+func (m *MockReturnRepository) UpdateStatus(ctx context.Context, ret *Return, from Status) error {
+	args := m.Called(ctx, ret, from)
+	return args.Error(0)
+}
+
+func (m *MockReturnRepository) OrderDeliveredAt(ctx context.Context, orderID uuid.UUID) (time.Time, error) {
+	args := m.Called(ctx, orderID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+type MockShipmentService struct {
+	mock.Mock
+}
+
+func (m *MockShipmentService) CreateShipment(ctx context.Context, input *shipment.Shipment) (*shipment.Shipment, error) {
+	args := m.Called(ctx, input)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) GetShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*shipment.Shipment, error) {
+	args := m.Called(ctx, orderID, shipmentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) GetShipmentsByOrderID(ctx context.Context, orderID uuid.UUID) ([]shipment.Shipment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]shipment.Shipment), args.Error(1)
+}
+
+func (m *MockShipmentService) RecordEvent(ctx context.Context, orderID, shipmentID uuid.UUID, event *shipment.Event) (*shipment.Shipment, error) {
+	args := m.Called(ctx, orderID, shipmentID, event)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*shipment.Shipment), args.Error(1)
+}
+
+type MockRefundHook struct {
+	mock.Mock
+}
+
+func (m *MockRefundHook) Refund(ctx context.Context, ret *Return) error {
+	args := m.Called(ctx, ret)
+	return args.Error(0)
+}
+
+const testWindow = 14 * 24 * time.Hour
+
+type testDeps struct {
+	repo      *MockReturnRepository
+	orders    *MockOrderService
+	shipments *MockShipmentService
+	refunds   *MockRefundHook
+	svc       Service
+}
+
+func newTestDeps() testDeps {
+	d := testDeps{
+		repo:      new(MockReturnRepository),
+		orders:    new(MockOrderService),
+		shipments: new(MockShipmentService),
+		refunds:   new(MockRefundHook),
+	}
+	d.svc = NewService(d.repo, d.orders, d.shipments, d.refunds, testWindow)
+	return d
+}
+
+func deliveredOrder(quantities ...int) *order.Order {
+	o := &order.Order{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    uuid.Must(uuid.NewV4()),
+		Status:    order.StatusDelivered,
+		UpdatedAt: time.Now().UTC(),
+	}
+	for _, qty := range quantities {
+		o.OrderItems = append(o.OrderItems, order.OrderItem{
+			ID:           uuid.Must(uuid.NewV4()),
+			OrderID:      o.ID,
+			Quantity:     qty,
+			PricePerUnit: 12.5,
+		})
+	}
+	return o
+}
+
+func deliveredShipment(deliveredAt time.Time) shipment.Shipment {
+	return shipment.Shipment{Status: shipment.StatusDelivered, DeliveredAt: &deliveredAt}
+}
+
+func TestService_RequestReturn_Success(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	currentOrder := deliveredOrder(3)
+	itemID := currentOrder.OrderItems[0].ID
+	existing := []Return{
+		{Status: StatusRejected, Items: []Item{{OrderItemID: itemID, Quantity: 3}}},
+		{Status: StatusApproved, Items: []Item{{OrderItemID: itemID, Quantity: 1}}},
+	}
+
+	d.orders.On("GetOrderByID", ctx, currentOrder.ID).Return(currentOrder, nil).Once()
+	d.shipments.On("GetShipmentsByOrderID", ctx, currentOrder.ID).
+		Return([]shipment.Shipment{deliveredShipment(time.Now().Add(-48 * time.Hour))}, nil).Once()
+	d.repo.On("Create", ctx, mock.MatchedBy(func(r *Return) bool {
+		return r.Status == StatusRequested && r.UserID == currentOrder.UserID && r.RefundAmount == 25
+	})).Return(existing, nil).Once()
+
+	created, err := d.svc.RequestReturn(ctx, &Return{
+		OrderID: currentOrder.ID,
+		Reason:  " wrong size ",
+		Items:   []Item{{OrderItemID: itemID, Quantity: 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "wrong size", created.Reason)
+	assert.InDelta(t, 25.0, created.RefundAmount, 0.001)
+
+	d.repo.AssertExpectations(t)
+	d.orders.AssertExpectations(t)
+	d.shipments.AssertExpectations(t)
+}
+
+func TestService_RequestReturn_WindowExpired(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	currentOrder := deliveredOrder(1)
+	d.orders.On("GetOrderByID", ctx, currentOrder.ID).Return(currentOrder, nil).Once()
+	d.shipments.On("GetShipmentsByOrderID", ctx, currentOrder.ID).
+		Return([]shipment.Shipment{deliveredShipment(time.Now().Add(-testWindow - time.Hour))}, nil).Once()
+
+	_, err := d.svc.RequestReturn(ctx, &Return{
+		OrderID: currentOrder.ID,
+		Reason:  "broken",
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	})
+	require.ErrorIs(t, err, ErrReturnWindowExpired)
+	d.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_RequestReturn_DeliveryTimeFromHistory(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	// Заказ доставлен давно, но недавно обновлён (например, обезличен): окно считается от доставки.
+	currentOrder := deliveredOrder(1)
+	d.orders.On("GetOrderByID", ctx, currentOrder.ID).Return(currentOrder, nil).Once()
+	d.shipments.On("GetShipmentsByOrderID", ctx, currentOrder.ID).Return([]shipment.Shipment{}, nil).Once()
+	d.repo.On("OrderDeliveredAt", ctx, currentOrder.ID).Return(time.Now().Add(-testWindow-time.Hour), nil).Once()
+
+	_, err := d.svc.RequestReturn(ctx, &Return{
+		OrderID: currentOrder.ID,
+		Reason:  "broken",
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	})
+	require.ErrorIs(t, err, ErrReturnWindowExpired)
+	d.repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_RequestReturn_UnknownDeliveryTime(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	currentOrder := deliveredOrder(1)
+	d.orders.On("GetOrderByID", ctx, currentOrder.ID).Return(currentOrder, nil).Once()
+	d.shipments.On("GetShipmentsByOrderID", ctx, currentOrder.ID).Return([]shipment.Shipment{}, nil).Once()
+	d.repo.On("OrderDeliveredAt", ctx, currentOrder.ID).Return(time.Time{}, nil).Once()
+
+	_, err := d.svc.RequestReturn(ctx, &Return{
+		OrderID: currentOrder.ID,
+		Reason:  "broken",
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	})
+	require.ErrorIs(t, err, ErrOrderNotReturnable)
+}
+
+func TestService_RequestReturn_Validation(t *testing.T) {
+	currentOrder := deliveredOrder(2)
+	itemID := currentOrder.OrderItems[0].ID
+
+	testCases := []struct {
+		name        string
+		order       *order.Order
+		input       *Return
+		expectedErr error
+	}{
+		{
+			name:        "reason is required",
+			order:       currentOrder,
+			input:       &Return{OrderID: currentOrder.ID, Items: []Item{{OrderItemID: itemID, Quantity: 1}}},
+			expectedErr: ErrInvalidReturn,
+		},
+		{
+			name:        "order not delivered",
+			order:       &order.Order{ID: currentOrder.ID, Status: order.StatusShipped},
+			input:       &Return{OrderID: currentOrder.ID, Reason: "broken", Items: []Item{{OrderItemID: itemID, Quantity: 1}}},
+			expectedErr: ErrOrderNotReturnable,
+		},
+		{
+			name:        "unknown order item",
+			order:       currentOrder,
+			input:       &Return{OrderID: currentOrder.ID, Reason: "broken", Items: []Item{{OrderItemID: uuid.Must(uuid.NewV4()), Quantity: 1}}},
+			expectedErr: ErrUnknownOrderItem,
+		},
+		{
+			name:        "quantity exceeds ordered",
+			order:       currentOrder,
+			input:       &Return{OrderID: currentOrder.ID, Reason: "broken", Items: []Item{{OrderItemID: itemID, Quantity: 3}}},
+			expectedErr: ErrQuantityExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := newTestDeps()
+			ctx := context.Background()
+			d.orders.On("GetOrderByID", ctx, currentOrder.ID).Return(tc.order, nil).Maybe()
+			d.shipments.On("GetShipmentsByOrderID", ctx, currentOrder.ID).
+				Return([]shipment.Shipment{deliveredShipment(time.Now().Add(-time.Hour))}, nil).Maybe()
+			d.repo.On("Create", ctx, mock.Anything).Return([]Return{}, nil).Maybe()
+
+			_, err := d.svc.RequestReturn(ctx, tc.input)
+			require.ErrorIs(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestService_Approve_InvalidTransition(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	returnID := uuid.Must(uuid.NewV4())
+	d.repo.On("GetByID", ctx, returnID).Return(&Return{ID: returnID, Status: StatusRejected}, nil).Once()
+
+	_, err := d.svc.Approve(ctx, returnID, "")
+	require.ErrorIs(t, err, ErrInvalidReturnTransition)
+	d.repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Reject_RequiresNote(t *testing.T) {
+	d := newTestDeps()
+
+	_, err := d.svc.Reject(context.Background(), uuid.Must(uuid.NewV4()), "  ")
+	require.ErrorIs(t, err, ErrInvalidReturn)
+	d.repo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestService_Receive_TriggersRefund(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	returnID := uuid.Must(uuid.NewV4())
+	d.repo.On("GetByID", ctx, returnID).Return(&Return{ID: returnID, Status: StatusApproved, RefundAmount: 25}, nil).Once()
+	d.repo.On("UpdateStatus", ctx, mock.MatchedBy(func(r *Return) bool {
+		return r.Status == StatusReceived && r.ReceivedAt != nil
+	}), StatusApproved).Return(nil).Once()
+	d.refunds.On("Refund", ctx, mock.MatchedBy(func(r *Return) bool { return r.ID == returnID })).Return(nil).Once()
+	d.repo.On("GetByID", ctx, returnID).Return(&Return{ID: returnID, Status: StatusReceived, RefundAmount: 25}, nil).Once()
+	d.repo.On("UpdateStatus", ctx, mock.MatchedBy(func(r *Return) bool {
+		return r.Status == StatusRefunded && r.RefundedAt != nil
+	}), StatusReceived).Return(nil).Once()
+
+	result, err := d.svc.Receive(ctx, returnID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusRefunded, result.Status)
+
+	d.repo.AssertExpectations(t)
+	d.refunds.AssertExpectations(t)
+}
+
+func TestService_Receive_RefundFailureKeepsReceived(t *testing.T) {
+	d := newTestDeps()
+	ctx := context.Background()
+
+	returnID := uuid.Must(uuid.NewV4())
+	d.repo.On("GetByID", ctx, returnID).Return(&Return{ID: returnID, Status: StatusApproved}, nil).Once()
+	d.repo.On("UpdateStatus", ctx, mock.Anything, StatusApproved).Return(nil).Once()
+	d.refunds.On("Refund", ctx, mock.Anything).Return(errors.New("payment gateway timeout")).Once()
+
+	_, err := d.svc.Receive(ctx, returnID)
+	require.ErrorIs(t, err, ErrRefundFailed)
+	d.repo.AssertNotCalled(t, "UpdateStatus", mock.Anything, mock.Anything, StatusReceived)
+	d.repo.AssertExpectations(t)
+}
@@ -0,0 +1,54 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// Locker даёт эксклюзивное право на запуск задачи среди всех реплик сервиса.
+type Locker interface {
+	// TryLock не ждёт освобождения блокировки: если её держит другая реплика, возвращает acquired = false.
+	TryLock(ctx context.Context, key int64) (release func(), acquired bool, err error)
+}
+
+type advisoryLocker struct {
+	db *pgxpool.Pool
+}
+
+// NewAdvisoryLocker создаёт Locker на сессионных advisory-блокировках Postgres.
+// Блокировка живёт, пока открыто соединение, поэтому упавшая реплика освобождает её автоматически.
+func NewAdvisoryLocker(db *pgxpool.Pool) Locker {
+	return &advisoryLocker{db: db}
+}
+
+func (l *advisoryLocker) TryLock(ctx context.Context, key int64) (func(), bool, error) {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("scheduler: failed to acquire connection for advisory lock: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Release()
+		return nil, false, fmt.Errorf("scheduler: failed to take advisory lock %d: %w", key, err)
+	}
+	if !acquired {
+		conn.Release()
+		return nil, false, nil
+	}
+
+	release := func() {
+		// Контекст задачи к этому моменту может быть отменён, а блокировку нужно снять в любом случае
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Error().Err(err).Int64("lock_key", key).Msg("scheduler: failed to release advisory lock")
+			// Закрываем соединение, чтобы Postgres снял блокировку вместе с сессией
+			_ = conn.Conn().Close(context.Background())
+		}
+		conn.Release()
+	}
+
+	return release, true, nil
+}
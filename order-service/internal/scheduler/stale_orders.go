@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+// staleOrdersLockKey - ключ advisory-блокировки задачи отмены неоплаченных заказов.
+const staleOrdersLockKey int64 = 0x6f72645f73746c // "ord_stl"
+
+var staleOrdersMetrics = expvar.NewMap("stale_order_canceller")
+
+// StaleOrderResult - итог одного прохода задачи.
+type StaleOrderResult struct {
+	Found     int
+	Cancelled int
+	Skipped   int // Заказ успели оплатить или отменить между поиском и отменой
+	Failed    int
+}
+
+// StaleOrderCanceller периодически отменяет заказы, которые слишком долго ждут оплаты.
+type StaleOrderCanceller struct {
+	orderSvc  order.Service
+	locker    Locker
+	timeout   time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+func NewStaleOrderCanceller(orderSvc order.Service, locker Locker, timeout, interval time.Duration, batchSize int) *StaleOrderCanceller {
+	return &StaleOrderCanceller{
+		orderSvc:  orderSvc,
+		locker:    locker,
+		timeout:   timeout,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run запускает задачу по таймеру и блокируется до отмены ctx.
+func (c *StaleOrderCanceller) Run(ctx context.Context) {
+	log.Info().Dur("timeout", c.timeout).Dur("interval", c.interval).Msg("scheduler: stale order canceller started")
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("scheduler: stale order canceller stopped")
+			return
+		case <-ticker.C:
+			if _, err := c.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msg("scheduler: stale order canceller run failed")
+			}
+		}
+	}
+}
+
+// RunOnce выполняет один проход, если эту задачу сейчас не выполняет другая реплика.
+func (c *StaleOrderCanceller) RunOnce(ctx context.Context) (StaleOrderResult, error) {
+	var result StaleOrderResult
+
+	release, acquired, err := c.locker.TryLock(ctx, staleOrdersLockKey)
+	if err != nil {
+		staleOrdersMetrics.Add("errors", 1)
+		return result, err
+	}
+	if !acquired {
+		staleOrdersMetrics.Add("runs_skipped_locked", 1)
+		log.Debug().Msg("scheduler: stale order canceller is running on another replica, skipping")
+		return result, nil
+	}
+	defer release()
+
+	staleOrdersMetrics.Add("runs", 1)
+	createdBefore := time.Now().Add(-c.timeout)
+
+	for {
+		ids, err := c.orderSvc.FindStaleUnpaidOrderIDs(ctx, createdBefore, c.batchSize)
+		if err != nil {
+			staleOrdersMetrics.Add("errors", 1)
+			return result, err
+		}
+		result.Found += len(ids)
+
+		cancelledInBatch := 0
+		for _, id := range ids {
+			err := c.orderSvc.CancelUnpaidOrder(ctx, id, order.CancellationReasonPaymentTimeout)
+			switch {
+			case err == nil:
+				cancelledInBatch++
+			case errors.Is(err, order.ErrInvalidStatusTransition),
+				errors.Is(err, order.ErrStatusAlreadySet),
+				errors.Is(err, order.ErrOrderNotFound):
+				result.Skipped++
+			default:
+				result.Failed++
+				log.Error().Err(err).Stringer("order_id", id).Msg("scheduler: failed to cancel stale order")
+			}
+		}
+		result.Cancelled += cancelledInBatch
+
+		// Неполная пачка - больше нечего отменять. Если в пачке ничего не отменилось,
+		// следующий запрос вернёт те же заказы, поэтому тоже выходим до следующего тика.
+		if len(ids) < c.batchSize || cancelledInBatch == 0 || ctx.Err() != nil {
+			break
+		}
+	}
+
+	staleOrdersMetrics.Add("orders_cancelled", int64(result.Cancelled))
+	staleOrdersMetrics.Add("orders_skipped", int64(result.Skipped))
+	staleOrdersMetrics.Add("orders_failed", int64(result.Failed))
+
+	if result.Found > 0 {
+		log.Info().
+			Int("found", result.Found).
+			Int("cancelled", result.Cancelled).
+			Int("skipped", result.Skipped).
+			Int("failed", result.Failed).
+			Time("created_before", createdBefore).
+			Msg("scheduler: stale unpaid orders processed")
+	}
+
+	return result, nil
+}
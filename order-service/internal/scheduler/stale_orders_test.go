@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+// fakeLocker эмулирует advisory-блокировку, которую может держать другая реплика.
+type fakeLocker struct {
+	heldElsewhere bool
+	released      int
+}
+
+func (l *fakeLocker) TryLock(context.Context, int64) (func(), bool, error) {
+	if l.heldElsewhere {
+		return nil, false, nil
+	}
+	return func() { l.released++ }, true, nil
+}
+
+func TestStaleOrderCanceller_RunOnce_CancelsInBatches(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	locker := &fakeLocker{}
+	canceller := NewStaleOrderCanceller(mockOrders, locker, time.Hour, time.Minute, 2)
+	ctx := context.Background()
+
+	first := []uuid.UUID{uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())}
+	second := []uuid.UUID{uuid.Must(uuid.NewV4())}
+
+	mockOrders.On("FindStaleUnpaidOrderIDs", ctx, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= time.Hour
+	}), 2).Return(first, nil).Once()
+	mockOrders.On("FindStaleUnpaidOrderIDs", ctx, mock.Anything, 2).Return(second, nil).Once()
+	mockOrders.On("CancelUnpaidOrder", ctx, first[0], order.CancellationReasonPaymentTimeout).Return(nil).Once()
+	// Заказ оплатили между поиском и отменой
+	mockOrders.On("CancelUnpaidOrder", ctx, first[1], order.CancellationReasonPaymentTimeout).
+		Return(order.ErrInvalidStatusTransition).Once()
+	mockOrders.On("CancelUnpaidOrder", ctx, second[0], order.CancellationReasonPaymentTimeout).Return(nil).Once()
+
+	result, err := canceller.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, StaleOrderResult{Found: 3, Cancelled: 2, Skipped: 1}, result)
+	assert.Equal(t, 1, locker.released)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestStaleOrderCanceller_RunOnce_LockHeldByAnotherReplica(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	canceller := NewStaleOrderCanceller(mockOrders, &fakeLocker{heldElsewhere: true}, time.Hour, time.Minute, 10)
+
+	result, err := canceller.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, result)
+	mockOrders.AssertNotCalled(t, "FindStaleUnpaidOrderIDs", mock.Anything, mock.Anything, mock.Anything)
+}
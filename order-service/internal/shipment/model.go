@@ -0,0 +1,103 @@
+package shipment
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type Carrier string
+
+const (
+	CarrierDHL   Carrier = "DHL"
+	CarrierUPS   Carrier = "UPS"
+	CarrierFedEx Carrier = "FEDEX"
+	CarrierUSPS  Carrier = "USPS"
+	CarrierDPD   Carrier = "DPD"
+)
+
+var knownCarriers = map[Carrier]bool{
+	CarrierDHL:   true,
+	CarrierUPS:   true,
+	CarrierFedEx: true,
+	CarrierUSPS:  true,
+	CarrierDPD:   true,
+}
+
+func (c Carrier) String() string {
+	return string(c)
+}
+
+// IsValid сообщает, поддерживается ли перевозчик.
+func (c Carrier) IsValid() bool {
+	return knownCarriers[c]
+}
+
+type Status string
+
+const (
+	StatusInTransit Status = "IN_TRANSIT"
+	StatusDelivered Status = "DELIVERED"
+)
+
+func (s Status) String() string {
+	return string(s)
+}
+
+type EventType string
+
+const (
+	EventInTransit      EventType = "IN_TRANSIT"
+	EventOutForDelivery EventType = "OUT_FOR_DELIVERY"
+	EventDelivered      EventType = "DELIVERED"
+	EventException      EventType = "EXCEPTION"
+)
+
+var knownEventTypes = map[EventType]bool{
+	EventInTransit:      true,
+	EventOutForDelivery: true,
+	EventDelivered:      true,
+	EventException:      true,
+}
+
+func (t EventType) String() string {
+	return string(t)
+}
+
+// IsValid сообщает, известен ли тип события доставки.
+func (t EventType) IsValid() bool {
+	return knownEventTypes[t]
+}
+
+// Item - позиция заказа (или её часть), вложенная в отправление.
+type Item struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ShipmentID  uuid.UUID `json:"shipment_id" db:"shipment_id"`
+	OrderItemID uuid.UUID `json:"order_item_id" db:"order_item_id"`
+	Quantity    int       `json:"quantity" db:"quantity"`
+}
+
+// Event - событие трекинга, полученное от перевозчика или внесённое вручную.
+type Event struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ShipmentID  uuid.UUID `json:"shipment_id" db:"shipment_id"`
+	Type        EventType `json:"type" db:"event_type"`
+	Location    string    `json:"location,omitempty" db:"location"`
+	Description string    `json:"description,omitempty" db:"description"`
+	OccurredAt  time.Time `json:"occurred_at" db:"occurred_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type Shipment struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	OrderID        uuid.UUID  `json:"order_id" db:"order_id"`
+	Carrier        Carrier    `json:"carrier" db:"carrier"`
+	TrackingNumber string     `json:"tracking_number" db:"tracking_number"`
+	Status         Status     `json:"status" db:"status"`
+	Items          []Item     `json:"items" db:"-"`  // Хранятся в shipment_items
+	Events         []Event    `json:"events" db:"-"` // Хранятся в shipment_events
+	ShippedAt      time.Time  `json:"shipped_at" db:"shipped_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
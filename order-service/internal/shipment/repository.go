@@ -0,0 +1,484 @@
+package shipment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var (
+	ErrShipmentNotFound     = errors.New("shipment not found")
+	ErrTrackingNumberExists = errors.New("tracking number already registered for carrier")
+)
+
+// Plan вызывается внутри транзакции, пока строка заказа заблокирована, и получает заказ с позициями
+// и его отправления в том виде, в каком они будут сохранены. Возвращает статусы, через которые
+// нужно провести заказ. Ошибка отменяет всю запись.
+type Plan func(o *order.Order, shipments []Shipment) ([]order.OrderStatus, error)
+
+type Repository interface {
+	// Create сохраняет отправление и переводит заказ по статусам из plan в одной транзакции.
+	Create(ctx context.Context, shipment *Shipment, plan Plan) error
+	GetByID(ctx context.Context, id uuid.UUID) (*Shipment, error)
+	GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
+	// AddEvent сохраняет событие отправления заказа orderID. Событие delivered отмечает отправление доставленным.
+	// Статусы заказа из plan применяются в той же транзакции.
+	AddEvent(ctx context.Context, orderID uuid.UUID, event *Event, plan Plan) error
+}
+
+// querier - общее подмножество pgxpool.Pool и pgx.Tx для чтения внутри и вне транзакции.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) Create(ctx context.Context, shipment *Shipment, plan Plan) (err error) {
+	if shipment.ID == uuid.Nil {
+		genID, genErr := uuid.NewV4()
+		if genErr != nil {
+			return fmt.Errorf("repository: failed to generate shipment ID: %w", genErr)
+		}
+		shipment.ID = genID
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Stringer("shipment_id", shipment.ID).Msg("Panic recovered during shipment Create, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("shipment_id", shipment.ID).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("shipment_id", shipment.ID).Msg("Failed to rollback transaction")
+			}
+		} else if commitErr := tx.Commit(ctx); commitErr != nil {
+			log.Error().Err(commitErr).Stringer("shipment_id", shipment.ID).Msg("Failed to commit transaction")
+			err = fmt.Errorf("repository: failed to commit transaction: %w", commitErr)
+		}
+	}()
+
+	currentOrder, err := lockOrder(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+	existing, err := selectByOrderID(ctx, tx, shipment.OrderID)
+	if err != nil {
+		return err
+	}
+	steps, err := plan(currentOrder, append(existing, *shipment))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	if shipment.ShippedAt.IsZero() {
+		shipment.ShippedAt = now
+	}
+
+	queryShipment := `
+		INSERT INTO order_service.shipments (id, order_id, carrier, tracking_number, status, shipped_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err = tx.Exec(ctx, queryShipment,
+		shipment.ID,
+		shipment.OrderID,
+		string(shipment.Carrier),
+		shipment.TrackingNumber,
+		string(shipment.Status),
+		shipment.ShippedAt,
+		now,
+		now,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrTrackingNumberExists
+		}
+		return fmt.Errorf("repository: failed to insert shipment: %w", err)
+	}
+	shipment.CreatedAt = now
+	shipment.UpdatedAt = now
+
+	queryItem := `
+		INSERT INTO order_service.shipment_items (id, shipment_id, order_item_id, quantity)
+		VALUES ($1, $2, $3, $4)
+	`
+	for i := range shipment.Items {
+		item := &shipment.Items[i]
+
+		itemID, genErr := uuid.NewV4()
+		if genErr != nil {
+			return fmt.Errorf("repository: failed to generate shipment item ID: %w", genErr)
+		}
+		item.ID = itemID
+		item.ShipmentID = shipment.ID
+
+		_, err = tx.Exec(ctx, queryItem, item.ID, item.ShipmentID, item.OrderItemID, item.Quantity)
+		if err != nil {
+			return fmt.Errorf("repository: failed to insert shipment item for shipment %s: %w", shipment.ID, err)
+		}
+	}
+
+	return applyOrderStatuses(ctx, tx, shipment.OrderID, steps)
+}
+
+func (r *postgresRepository) GetByID(ctx context.Context, id uuid.UUID) (*Shipment, error) {
+	query := `
+		SELECT id, order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at
+		FROM order_service.shipments
+		WHERE id = $1
+	`
+
+	var shipment Shipment
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&shipment.ID,
+		&shipment.OrderID,
+		&shipment.Carrier,
+		&shipment.TrackingNumber,
+		&shipment.Status,
+		&shipment.ShippedAt,
+		&shipment.DeliveredAt,
+		&shipment.CreatedAt,
+		&shipment.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrShipmentNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to select shipment by id %s: %w", id, err)
+	}
+
+	shipments := []*Shipment{&shipment}
+	if err := loadItems(ctx, r.db, shipments); err != nil {
+		return nil, err
+	}
+	if err := loadEvents(ctx, r.db, shipments); err != nil {
+		return nil, err
+	}
+
+	return &shipment, nil
+}
+
+func (r *postgresRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error) {
+	return selectByOrderID(ctx, r.db, orderID)
+}
+
+func selectByOrderID(ctx context.Context, q querier, orderID uuid.UUID) ([]Shipment, error) {
+	query := `
+		SELECT id, order_id, carrier, tracking_number, status, shipped_at, delivered_at, created_at, updated_at
+		FROM order_service.shipments
+		WHERE order_id = $1
+		ORDER BY shipped_at ASC
+	`
+
+	rows, err := q.Query(ctx, query, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query shipments for order id %s: %w", orderID, err)
+	}
+	defer rows.Close()
+
+	shipments := make([]*Shipment, 0)
+	for rows.Next() {
+		var shipment Shipment
+		err := rows.Scan(
+			&shipment.ID,
+			&shipment.OrderID,
+			&shipment.Carrier,
+			&shipment.TrackingNumber,
+			&shipment.Status,
+			&shipment.ShippedAt,
+			&shipment.DeliveredAt,
+			&shipment.CreatedAt,
+			&shipment.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan shipment for order id %s: %w", orderID, err)
+		}
+		shipments = append(shipments, &shipment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating shipments for order id %s: %w", orderID, err)
+	}
+
+	if err := loadItems(ctx, q, shipments); err != nil {
+		return nil, err
+	}
+	if err := loadEvents(ctx, q, shipments); err != nil {
+		return nil, err
+	}
+
+	result := make([]Shipment, 0, len(shipments))
+	for _, s := range shipments {
+		result = append(result, *s)
+	}
+
+	return result, nil
+}
+
+func (r *postgresRepository) AddEvent(ctx context.Context, orderID uuid.UUID, event *Event, plan Plan) (err error) {
+	if event.ID == uuid.Nil {
+		genID, genErr := uuid.NewV4()
+		if genErr != nil {
+			return fmt.Errorf("repository: failed to generate shipment event ID: %w", genErr)
+		}
+		event.ID = genID
+	}
+
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Stringer("shipment_id", event.ShipmentID).Msg("Panic recovered during shipment AddEvent, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("shipment_id", event.ShipmentID).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("shipment_id", event.ShipmentID).Msg("Failed to rollback transaction")
+			}
+		} else if commitErr := tx.Commit(ctx); commitErr != nil {
+			log.Error().Err(commitErr).Stringer("shipment_id", event.ShipmentID).Msg("Failed to commit transaction")
+			err = fmt.Errorf("repository: failed to commit transaction: %w", commitErr)
+		}
+	}()
+
+	// Заказ блокируется первым, как и в Create, чтобы параллельные записи по одному заказу шли по очереди.
+	currentOrder, err := lockOrder(ctx, tx, orderID)
+	if err != nil {
+		if errors.Is(err, order.ErrOrderNotFound) {
+			return ErrShipmentNotFound
+		}
+		return err
+	}
+	shipments, err := selectByOrderID(ctx, tx, orderID)
+	if err != nil {
+		return err
+	}
+
+	var current *Shipment
+	for i := range shipments {
+		if shipments[i].ID == event.ShipmentID {
+			current = &shipments[i]
+			break
+		}
+	}
+	if current == nil {
+		return ErrShipmentNotFound
+	}
+	if current.Status == StatusDelivered {
+		return ErrShipmentDelivered
+	}
+	if event.Type == EventDelivered {
+		current.Status = StatusDelivered
+		current.DeliveredAt = &event.OccurredAt
+	}
+
+	steps, err := plan(currentOrder, shipments)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	event.CreatedAt = now
+
+	query := `
+		INSERT INTO order_service.shipment_events (id, shipment_id, event_type, location, description, occurred_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(ctx, query,
+		event.ID,
+		event.ShipmentID,
+		string(event.Type),
+		event.Location,
+		event.Description,
+		event.OccurredAt,
+		event.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: failed to insert event for shipment %s: %w", event.ShipmentID, err)
+	}
+
+	if event.Type == EventDelivered {
+		queryDelivered := `
+			UPDATE order_service.shipments
+			SET status = $1, delivered_at = $2, updated_at = $3
+			WHERE id = $4
+		`
+		_, err = tx.Exec(ctx, queryDelivered, string(StatusDelivered), event.OccurredAt, now, event.ShipmentID)
+		if err != nil {
+			return fmt.Errorf("repository: failed to mark shipment %s delivered: %w", event.ShipmentID, err)
+		}
+	}
+
+	return applyOrderStatuses(ctx, tx, orderID, steps)
+}
+
+// lockOrder читает заказ с позициями и блокирует его строку до конца транзакции.
+func lockOrder(ctx context.Context, tx pgx.Tx, orderID uuid.UUID) (*order.Order, error) {
+	o := order.Order{ID: orderID}
+	err := tx.QueryRow(ctx, `
+		SELECT user_id, status
+		FROM order_service.orders
+		WHERE id = $1
+		FOR UPDATE
+	`, orderID).Scan(&o.UserID, &o.Status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, order.ErrOrderNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to lock order %s: %w", orderID, err)
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT id, order_id, product_id, quantity, price_per_unit
+		FROM order_service.order_items
+		WHERE order_id = $1
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query items for order %s: %w", orderID, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item order.OrderItem
+		if err := rows.Scan(&item.ID, &item.OrderID, &item.ProductID, &item.Quantity, &item.PricePerUnit); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan item for order %s: %w", orderID, err)
+		}
+		o.OrderItems = append(o.OrderItems, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating items for order %s: %w", orderID, err)
+	}
+
+	return &o, nil
+}
+
+// applyOrderStatuses последовательно переводит заказ по статусам. История пишется триггером на каждый шаг.
+func applyOrderStatuses(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, steps []order.OrderStatus) error {
+	for _, step := range steps {
+		_, err := tx.Exec(ctx, `
+			UPDATE order_service.orders
+			SET status = $1, updated_at = $2
+			WHERE id = $3
+		`, string(step), time.Now().UTC(), orderID)
+		if err != nil {
+			return fmt.Errorf("repository: failed to set order %s status to %s: %w", orderID, step, err)
+		}
+	}
+	return nil
+}
+
+func loadItems(ctx context.Context, q querier, shipments []*Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*Shipment, len(shipments))
+	ids := make([]uuid.UUID, 0, len(shipments))
+	for _, s := range shipments {
+		s.Items = make([]Item, 0)
+		byID[s.ID] = s
+		ids = append(ids, s.ID)
+	}
+
+	query := `
+		SELECT id, shipment_id, order_item_id, quantity
+		FROM order_service.shipment_items
+		WHERE shipment_id = ANY($1)
+	`
+	rows, err := q.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query shipment items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.ID, &item.ShipmentID, &item.OrderItemID, &item.Quantity); err != nil {
+			return fmt.Errorf("repository: failed to scan shipment item: %w", err)
+		}
+		if s, ok := byID[item.ShipmentID]; ok {
+			s.Items = append(s.Items, item)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating shipment items: %w", err)
+	}
+
+	return nil
+}
+
+func loadEvents(ctx context.Context, q querier, shipments []*Shipment) error {
+	if len(shipments) == 0 {
+		return nil
+	}
+
+	byID := make(map[uuid.UUID]*Shipment, len(shipments))
+	ids := make([]uuid.UUID, 0, len(shipments))
+	for _, s := range shipments {
+		s.Events = make([]Event, 0)
+		byID[s.ID] = s
+		ids = append(ids, s.ID)
+	}
+
+	query := `
+		SELECT id, shipment_id, event_type, COALESCE(location, ''), COALESCE(description, ''), occurred_at, created_at
+		FROM order_service.shipment_events
+		WHERE shipment_id = ANY($1)
+		ORDER BY occurred_at ASC
+	`
+	rows, err := q.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("repository: failed to query shipment events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event Event
+		err := rows.Scan(
+			&event.ID,
+			&event.ShipmentID,
+			&event.Type,
+			&event.Location,
+			&event.Description,
+			&event.OccurredAt,
+			&event.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("repository: failed to scan shipment event: %w", err)
+		}
+		if s, ok := byID[event.ShipmentID]; ok {
+			s.Events = append(s.Events, event)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repository: failed iterating shipment events: %w", err)
+	}
+
+	return nil
+}
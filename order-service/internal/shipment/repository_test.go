@@ -0,0 +1,268 @@
+package shipment_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/shipment"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=order_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func truncateTables(tb testing.TB, pool *pgxpool.Pool) {
+	tb.Helper()
+	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE order_service.shipments, order_service.order_items, order_service.orders CASCADE")
+	require.NoError(tb, err, "failed to truncate tables")
+}
+
+func createTestOrder(t *testing.T, quantity int) *order.Order {
+	t.Helper()
+	o := &order.Order{
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: order.StatusPaid,
+		OrderItems: []order.OrderItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: quantity, PricePerUnit: 10},
+		},
+	}
+	_, err := order.NewRepository(testDB).CreateOrder(context.Background(), o)
+	require.NoError(t, err)
+	return o
+}
+
+// noStatusChange - Plan, который ничего не проверяет и не меняет статус заказа.
+func noStatusChange(*order.Order, []shipment.Shipment) ([]order.OrderStatus, error) {
+	return nil, nil
+}
+
+func TestShipmentRepository_CreateAndGet(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := shipment.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t, 2)
+
+	input := &shipment.Shipment{
+		OrderID:        o.ID,
+		Carrier:        shipment.CarrierDHL,
+		TrackingNumber: "JD0001",
+		Status:         shipment.StatusInTransit,
+		Items:          []shipment.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+	}
+	require.NoError(t, repo.Create(ctx, input, noStatusChange))
+	require.NotEqual(t, uuid.Nil, input.ID)
+
+	require.NoError(t, repo.AddEvent(ctx, o.ID, &shipment.Event{
+		ShipmentID: input.ID,
+		Type:       shipment.EventInTransit,
+		Location:   "Leipzig",
+		OccurredAt: time.Now().UTC(),
+	}, noStatusChange))
+
+	found, err := repo.GetByID(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, o.ID, found.OrderID)
+	assert.Equal(t, shipment.StatusInTransit, found.Status)
+	require.Len(t, found.Items, 1)
+	assert.Equal(t, 1, found.Items[0].Quantity)
+	require.Len(t, found.Events, 1)
+	assert.Equal(t, "Leipzig", found.Events[0].Location)
+
+	byOrder, err := repo.GetByOrderID(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Len(t, byOrder, 1)
+}
+
+func TestShipmentRepository_Create_DuplicateTrackingNumber(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := shipment.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t, 2)
+
+	newShipment := func() *shipment.Shipment {
+		return &shipment.Shipment{
+			OrderID:        o.ID,
+			Carrier:        shipment.CarrierUPS,
+			TrackingNumber: "1Z999",
+			Status:         shipment.StatusInTransit,
+			Items:          []shipment.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+		}
+	}
+	require.NoError(t, repo.Create(ctx, newShipment(), noStatusChange))
+
+	err := repo.Create(ctx, newShipment(), noStatusChange)
+	require.ErrorIs(t, err, shipment.ErrTrackingNumberExists)
+}
+
+func TestShipmentRepository_AddEvent_DeliveredUpdatesOrder(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := shipment.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t, 1)
+
+	input := &shipment.Shipment{
+		OrderID:        o.ID,
+		Carrier:        shipment.CarrierDPD,
+		TrackingNumber: "DPD42",
+		Status:         shipment.StatusInTransit,
+		Items:          []shipment.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+	}
+	require.NoError(t, repo.Create(ctx, input, noStatusChange))
+
+	deliveredAt := time.Now().UTC().Truncate(time.Microsecond)
+	err := repo.AddEvent(ctx, o.ID, &shipment.Event{ShipmentID: input.ID, Type: shipment.EventDelivered, OccurredAt: deliveredAt},
+		func(locked *order.Order, shipments []shipment.Shipment) ([]order.OrderStatus, error) {
+			require.Len(t, shipments, 1)
+			assert.Equal(t, shipment.StatusDelivered, shipments[0].Status)
+			return []order.OrderStatus{order.StatusShipped, order.StatusDelivered}, nil
+		})
+	require.NoError(t, err)
+
+	found, err := repo.GetByID(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shipment.StatusDelivered, found.Status)
+	require.NotNil(t, found.DeliveredAt)
+	assert.WithinDuration(t, deliveredAt, *found.DeliveredAt, time.Millisecond)
+
+	updatedOrder, err := order.NewRepository(testDB).GetOrderByID(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Equal(t, order.StatusDelivered, updatedOrder.Status)
+
+	err = repo.AddEvent(ctx, o.ID, &shipment.Event{ShipmentID: input.ID, Type: shipment.EventDelivered, OccurredAt: deliveredAt}, noStatusChange)
+	require.ErrorIs(t, err, shipment.ErrShipmentDelivered)
+
+	err = repo.AddEvent(ctx, o.ID, &shipment.Event{ShipmentID: uuid.Must(uuid.NewV4()), Type: shipment.EventInTransit, OccurredAt: deliveredAt}, noStatusChange)
+	require.ErrorIs(t, err, shipment.ErrShipmentNotFound)
+}
+
+func TestShipmentRepository_AddEvent_PlanErrorDiscardsEvent(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := shipment.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t, 1)
+
+	input := &shipment.Shipment{
+		OrderID:        o.ID,
+		Carrier:        shipment.CarrierDHL,
+		TrackingNumber: "JD0042",
+		Status:         shipment.StatusInTransit,
+		Items:          []shipment.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+	}
+	require.NoError(t, repo.Create(ctx, input, noStatusChange))
+
+	err := repo.AddEvent(ctx, o.ID, &shipment.Event{ShipmentID: input.ID, Type: shipment.EventDelivered, OccurredAt: time.Now().UTC()},
+		func(*order.Order, []shipment.Shipment) ([]order.OrderStatus, error) {
+			return nil, order.ErrInvalidStatusTransition
+		})
+	require.ErrorIs(t, err, order.ErrInvalidStatusTransition)
+
+	found, err := repo.GetByID(ctx, input.ID)
+	require.NoError(t, err)
+	assert.Equal(t, shipment.StatusInTransit, found.Status)
+	assert.Empty(t, found.Events)
+}
+
+func TestShipmentRepository_Create_ConcurrentPlansSeeEachOther(t *testing.T) {
+	truncateTables(t, testDB)
+	t.Cleanup(func() { truncateTables(t, testDB) })
+
+	repo := shipment.NewRepository(testDB)
+	ctx := context.Background()
+	o := createTestOrder(t, 1)
+
+	// Заказана одна единица: из двух параллельных отправлений пройти должно только одно.
+	onlyOneUnit := func(_ *order.Order, shipments []shipment.Shipment) ([]order.OrderStatus, error) {
+		total := 0
+		for _, sh := range shipments {
+			for _, item := range sh.Items {
+				total += item.Quantity
+			}
+		}
+		if total > 1 {
+			return nil, shipment.ErrQuantityExceeded
+		}
+		return nil, nil
+	}
+
+	errs := make(chan error, 2)
+	for i := range 2 {
+		go func() {
+			errs <- repo.Create(ctx, &shipment.Shipment{
+				OrderID:        o.ID,
+				Carrier:        shipment.CarrierDHL,
+				TrackingNumber: fmt.Sprintf("RACE%d", i),
+				Status:         shipment.StatusInTransit,
+				Items:          []shipment.Item{{OrderItemID: o.OrderItems[0].ID, Quantity: 1}},
+			}, onlyOneUnit)
+		}()
+	}
+
+	var exceeded int
+	for range 2 {
+		if err := <-errs; err != nil {
+			require.ErrorIs(t, err, shipment.ErrQuantityExceeded)
+			exceeded++
+		}
+	}
+	assert.Equal(t, 1, exceeded)
+
+	byOrder, err := repo.GetByOrderID(ctx, o.ID)
+	require.NoError(t, err)
+	assert.Len(t, byOrder, 1)
+}
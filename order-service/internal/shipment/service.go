@@ -0,0 +1,266 @@
+package shipment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var (
+	ErrInvalidShipment   = errors.New("invalid shipment")
+	ErrInvalidEvent      = errors.New("invalid shipment event")
+	ErrOrderNotShippable = errors.New("order is not in a shippable state")
+	ErrQuantityExceeded  = errors.New("shipped quantity exceeds ordered quantity")
+	ErrUnknownOrderItem  = errors.New("order item does not belong to order")
+	ErrShipmentDelivered = errors.New("shipment is already delivered")
+)
+
+var shippableOrderStatuses = map[order.OrderStatus]bool{
+	order.StatusProcessing:       true,
+	order.StatusPaid:             true,
+	order.StatusPartiallyShipped: true,
+}
+
+type Service interface {
+	CreateShipment(ctx context.Context, input *Shipment) (*Shipment, error)
+	GetShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*Shipment, error)
+	GetShipmentsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
+	RecordEvent(ctx context.Context, orderID, shipmentID uuid.UUID, event *Event) (*Shipment, error)
+}
+
+type service struct {
+	repo     Repository
+	orderSvc order.Service
+}
+
+func NewService(repo Repository, orderSvc order.Service) Service {
+	return &service{
+		repo:     repo,
+		orderSvc: orderSvc,
+	}
+}
+
+func (s *service) CreateShipment(ctx context.Context, input *Shipment) (*Shipment, error) {
+	if !input.Carrier.IsValid() {
+		return nil, fmt.Errorf("%w: unsupported carrier %q", ErrInvalidShipment, input.Carrier)
+	}
+	input.TrackingNumber = strings.TrimSpace(input.TrackingNumber)
+	if input.TrackingNumber == "" {
+		return nil, fmt.Errorf("%w: tracking number is required", ErrInvalidShipment)
+	}
+	if len(input.Items) == 0 {
+		return nil, fmt.Errorf("%w: shipment must contain at least one item", ErrInvalidShipment)
+	}
+
+	for _, item := range input.Items {
+		if item.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: quantity for order item %s must be greater than zero", ErrInvalidShipment, item.OrderItemID)
+		}
+	}
+
+	input.ID = uuid.Nil
+	input.Status = StatusInTransit
+	input.DeliveredAt = nil
+	input.Events = []Event{}
+
+	// Остатки к отгрузке проверяются в транзакции под блокировкой заказа, иначе параллельные отправления
+	// могут вместе превысить заказанное количество.
+	var (
+		fromStatus order.OrderStatus
+		steps      []order.OrderStatus
+		planErr    error
+	)
+	err := s.repo.Create(ctx, input, func(o *order.Order, shipments []Shipment) ([]order.OrderStatus, error) {
+		fromStatus = o.Status
+		steps, planErr = planShipment(o, shipments, input.Items)
+		return steps, planErr
+	})
+	if err != nil {
+		if planErr != nil || errors.Is(err, ErrTrackingNumberExists) || errors.Is(err, order.ErrOrderNotFound) {
+			return nil, err
+		}
+		log.Error().Err(err).Stringer("order_id", input.OrderID).Msg("service: failed to create shipment in repository")
+		return nil, fmt.Errorf("service: failed to create shipment: %w", err)
+	}
+
+	log.Info().Stringer("shipment_id", input.ID).Stringer("order_id", input.OrderID).Stringer("carrier", input.Carrier).Msg("service: shipment created")
+	s.notifyStatusSteps(ctx, input.OrderID, fromStatus, steps)
+
+	return input, nil
+}
+
+func (s *service) GetShipment(ctx context.Context, orderID, shipmentID uuid.UUID) (*Shipment, error) {
+	found, err := s.repo.GetByID(ctx, shipmentID)
+	if err != nil {
+		if errors.Is(err, ErrShipmentNotFound) {
+			return nil, ErrShipmentNotFound
+		}
+		log.Error().Err(err).Stringer("shipment_id", shipmentID).Msg("service: failed to fetch shipment")
+		return nil, fmt.Errorf("service: failed to fetch shipment: %w", err)
+	}
+	if found.OrderID != orderID {
+		return nil, ErrShipmentNotFound
+	}
+
+	return found, nil
+}
+
+func (s *service) GetShipmentsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error) {
+	if _, err := s.orderSvc.GetOrderByID(ctx, orderID); err != nil {
+		return nil, err
+	}
+
+	shipments, err := s.repo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("service: failed to fetch shipments for order")
+		return nil, fmt.Errorf("service: failed to fetch shipments: %w", err)
+	}
+
+	return shipments, nil
+}
+
+func (s *service) RecordEvent(ctx context.Context, orderID, shipmentID uuid.UUID, event *Event) (*Shipment, error) {
+	if !event.Type.IsValid() {
+		return nil, fmt.Errorf("%w: unsupported event type %q", ErrInvalidEvent, event.Type)
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+	event.ShipmentID = shipmentID
+
+	// Событие доставки и смена статуса заказа пишутся одной транзакцией: если заказ уже нельзя
+	// перевести дальше (например, он отменён), событие не сохраняется.
+	var (
+		fromStatus order.OrderStatus
+		steps      []order.OrderStatus
+		planErr    error
+	)
+	err := s.repo.AddEvent(ctx, orderID, event, func(o *order.Order, shipments []Shipment) ([]order.OrderStatus, error) {
+		if event.Type != EventDelivered {
+			return nil, nil
+		}
+		fromStatus = o.Status
+		steps, planErr = statusSteps(o, shipments)
+		return steps, planErr
+	})
+	if err != nil {
+		if planErr != nil || errors.Is(err, ErrShipmentNotFound) || errors.Is(err, ErrShipmentDelivered) {
+			return nil, err
+		}
+		log.Error().Err(err).Stringer("shipment_id", shipmentID).Msg("service: failed to record shipment event")
+		return nil, fmt.Errorf("service: failed to record shipment event: %w", err)
+	}
+
+	s.notifyStatusSteps(ctx, orderID, fromStatus, steps)
+
+	return s.GetShipment(ctx, orderID, shipmentID)
+}
+
+// notifyStatusSteps сообщает сервису заказов о каждом шаге смены статуса, уже записанном в транзакции отправления.
+func (s *service) notifyStatusSteps(ctx context.Context, orderID uuid.UUID, from order.OrderStatus, steps []order.OrderStatus) {
+	for _, step := range steps {
+		s.orderSvc.NotifyStatusChange(ctx, orderID, from, step)
+		from = step
+	}
+}
+
+// planShipment проверяет новое отправление против заказа и уже существующих отправлений
+// (shipments включает новое) и возвращает шаги смены статуса заказа.
+func planShipment(o *order.Order, shipments []Shipment, items []Item) ([]order.OrderStatus, error) {
+	if !shippableOrderStatuses[o.Status] {
+		log.Warn().Stringer("order_id", o.ID).Stringer("status", o.Status).Msg("service: attempt to ship order in non-shippable status")
+		return nil, fmt.Errorf("%w: order status is %s", ErrOrderNotShippable, o.Status)
+	}
+
+	ordered := orderedQuantities(o)
+	shipped := shippedQuantities(shipments)
+	for _, item := range items {
+		orderedQty, ok := ordered[item.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownOrderItem, item.OrderItemID)
+		}
+		if shipped[item.OrderItemID] > orderedQty {
+			return nil, fmt.Errorf("%w: order item %s ordered %d, shipped %d", ErrQuantityExceeded, item.OrderItemID, orderedQty, shipped[item.OrderItemID])
+		}
+	}
+
+	return statusSteps(o, shipments)
+}
+
+// statusSteps возвращает статусы, через которые нужно провести заказ, чтобы он соответствовал покрытию
+// позиций отправлениями. Промежуточные статусы проходятся по очереди, чтобы не обходить правила allowedTransitions.
+func statusSteps(o *order.Order, shipments []Shipment) ([]order.OrderStatus, error) {
+	target := fulfillmentStatus(o, shipments)
+	if target == "" || target == o.Status {
+		return nil, nil
+	}
+
+	var steps []order.OrderStatus
+	if target == order.StatusDelivered && o.Status != order.StatusShipped {
+		steps = append(steps, order.StatusShipped)
+	}
+	steps = append(steps, target)
+
+	from := o.Status
+	for _, step := range steps {
+		if err := order.CheckTransition(from, step); err != nil {
+			log.Warn().Stringer("order_id", o.ID).Stringer("current_status", from).Stringer("target_status", step).Msg("service: shipments require a forbidden order status transition")
+			return nil, err
+		}
+		from = step
+	}
+
+	return steps, nil
+}
+
+// fulfillmentStatus вычисляет статус заказа по отправлениям. Пустая строка означает, что ничего не отгружено.
+func fulfillmentStatus(o *order.Order, shipments []Shipment) order.OrderStatus {
+	shipped := shippedQuantities(shipments)
+	if len(shipped) == 0 {
+		return ""
+	}
+
+	fullyShipped := true
+	for itemID, qty := range orderedQuantities(o) {
+		if shipped[itemID] < qty {
+			fullyShipped = false
+			break
+		}
+	}
+	if !fullyShipped {
+		return order.StatusPartiallyShipped
+	}
+
+	for _, sh := range shipments {
+		if sh.Status != StatusDelivered {
+			return order.StatusShipped
+		}
+	}
+
+	return order.StatusDelivered
+}
+
+func orderedQuantities(o *order.Order) map[uuid.UUID]int {
+	result := make(map[uuid.UUID]int, len(o.OrderItems))
+	for _, item := range o.OrderItems {
+		result[item.ID] += item.Quantity
+	}
+	return result
+}
+
+func shippedQuantities(shipments []Shipment) map[uuid.UUID]int {
+	result := make(map[uuid.UUID]int)
+	for _, sh := range shipments {
+		for _, item := range sh.Items {
+			result[item.OrderItemID] += item.Quantity
+		}
+	}
+	return result
+}
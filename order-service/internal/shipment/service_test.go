@@ -0,0 +1,398 @@
+package shipment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockShipmentRepository struct {
+	mock.Mock
+}
+
+// Create эмулирует транзакцию: передаёт в plan заказ и уже существующие отправления из ожидания.
+func (m *MockShipmentRepository) Create(ctx context.Context, shipment *Shipment, plan Plan) error {
+	args := m.Called(ctx, shipment)
+	if err := args.Error(2); err != nil {
+		return err
+	}
+	if _, err := plan(args.Get(0).(*order.Order), append(args.Get(1).([]Shipment), *shipment)); err != nil {
+		return err
+	}
+	shipment.ID = uuid.Must(uuid.NewV4())
+	return nil
+}
+
+func (m *MockShipmentRepository) GetByID(ctx context.Context, id uuid.UUID) (*Shipment, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Shipment), args.Error(1)
+}
+
+func (m *MockShipmentRepository) GetByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error) {
+	args := m.Called(ctx, orderID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Shipment), args.Error(1)
+}
+
+// AddEvent эмулирует транзакцию: передаёт в plan заказ и отправления в том виде, в каком они будут сохранены.
+func (m *MockShipmentRepository) AddEvent(ctx context.Context, orderID uuid.UUID, event *Event, plan Plan) error {
+	args := m.Called(ctx, orderID, event)
+	if err := args.Error(2); err != nil {
+		return err
+	}
+	_, err := plan(args.Get(0).(*order.Order), args.Get(1).([]Shipment))
+	return err
+}
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+func newTestOrder(status order.OrderStatus, quantities ...int) *order.Order {
+	o := &order.Order{
+		ID:     uuid.Must(uuid.NewV4()),
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: status,
+	}
+	for _, qty := range quantities {
+		o.OrderItems = append(o.OrderItems, order.OrderItem{
+			ID:           uuid.Must(uuid.NewV4()),
+			OrderID:      o.ID,
+			ProductID:    uuid.Must(uuid.NewV4()),
+			Quantity:     qty,
+			PricePerUnit: 10,
+		})
+	}
+	return o
+}
+
+func TestService_CreateShipment_PartialCoverage(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusPaid, 2, 1)
+	input := &Shipment{
+		OrderID:        currentOrder.ID,
+		Carrier:        CarrierDHL,
+		TrackingNumber: " JD0001 ",
+		Items:          []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 2}},
+	}
+
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(s *Shipment) bool {
+		return s.TrackingNumber == "JD0001" && s.Status == StatusInTransit
+	})).Return(currentOrder, []Shipment{}, nil).Once()
+	mockOrders.On("NotifyStatusChange", ctx, currentOrder.ID, order.StatusPaid, order.StatusPartiallyShipped).Return().Once()
+
+	created, err := svc.CreateShipment(ctx, input)
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.Equal(t, StatusInTransit, created.Status)
+
+	mockRepo.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestService_CreateShipment_FullCoverageMarksShipped(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusPartiallyShipped, 2, 1)
+	existing := []Shipment{{
+		ID:      uuid.Must(uuid.NewV4()),
+		OrderID: currentOrder.ID,
+		Status:  StatusInTransit,
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 2}},
+	}}
+	input := &Shipment{
+		OrderID:        currentOrder.ID,
+		Carrier:        CarrierUPS,
+		TrackingNumber: "1Z999",
+		Items:          []Item{{OrderItemID: currentOrder.OrderItems[1].ID, Quantity: 1}},
+	}
+
+	mockRepo.On("Create", ctx, input).Return(currentOrder, existing, nil).Once()
+	mockOrders.On("NotifyStatusChange", ctx, currentOrder.ID, order.StatusPartiallyShipped, order.StatusShipped).Return().Once()
+
+	_, err := svc.CreateShipment(ctx, input)
+	require.NoError(t, err)
+
+	mockRepo.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestService_CreateShipment_QuantityExceeded(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusPartiallyShipped, 2)
+	existing := []Shipment{{
+		OrderID: currentOrder.ID,
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	}}
+	input := &Shipment{
+		OrderID:        currentOrder.ID,
+		Carrier:        CarrierDHL,
+		TrackingNumber: "JD0002",
+		Items:          []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 2}},
+	}
+
+	mockRepo.On("Create", ctx, input).Return(currentOrder, existing, nil).Once()
+
+	_, err := svc.CreateShipment(ctx, input)
+	require.ErrorIs(t, err, ErrQuantityExceeded)
+
+	mockOrders.AssertNotCalled(t, "NotifyStatusChange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_CreateShipment_UnknownOrderItem(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusPaid, 1)
+	input := &Shipment{
+		OrderID:        currentOrder.ID,
+		Carrier:        CarrierDHL,
+		TrackingNumber: "JD0003",
+		Items:          []Item{{OrderItemID: uuid.Must(uuid.NewV4()), Quantity: 1}},
+	}
+
+	mockRepo.On("Create", ctx, input).Return(currentOrder, []Shipment{}, nil).Once()
+
+	_, err := svc.CreateShipment(ctx, input)
+	require.ErrorIs(t, err, ErrUnknownOrderItem)
+}
+
+func TestService_CreateShipment_OrderNotShippable(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusNew, 1)
+	input := &Shipment{
+		OrderID:        currentOrder.ID,
+		Carrier:        CarrierDHL,
+		TrackingNumber: "JD0004",
+		Items:          []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	}
+
+	mockRepo.On("Create", ctx, input).Return(currentOrder, []Shipment{}, nil).Once()
+
+	_, err := svc.CreateShipment(ctx, input)
+	require.ErrorIs(t, err, ErrOrderNotShippable)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateShipment_InvalidCarrier(t *testing.T) {
+	svc := NewService(new(MockShipmentRepository), new(MockOrderService))
+
+	_, err := svc.CreateShipment(context.Background(), &Shipment{
+		Carrier:        "PIGEON",
+		TrackingNumber: "123",
+		Items:          []Item{{OrderItemID: uuid.Must(uuid.NewV4()), Quantity: 1}},
+	})
+	require.ErrorIs(t, err, ErrInvalidShipment)
+}
+
+func TestService_RecordEvent_DeliveredCompletesOrder(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusPartiallyShipped, 1)
+	deliveredAt := time.Now().UTC().Truncate(time.Second)
+	delivered := Shipment{
+		ID:          uuid.Must(uuid.NewV4()),
+		OrderID:     currentOrder.ID,
+		Status:      StatusDelivered,
+		DeliveredAt: &deliveredAt,
+		Items:       []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	}
+	event := &Event{Type: EventDelivered, OccurredAt: deliveredAt}
+
+	mockRepo.On("AddEvent", ctx, currentOrder.ID, event).Return(currentOrder, []Shipment{delivered}, nil).Once()
+	mockOrders.On("NotifyStatusChange", ctx, currentOrder.ID, order.StatusPartiallyShipped, order.StatusShipped).Return().Once()
+	mockOrders.On("NotifyStatusChange", ctx, currentOrder.ID, order.StatusShipped, order.StatusDelivered).Return().Once()
+	mockRepo.On("GetByID", ctx, delivered.ID).Return(&delivered, nil).Once()
+
+	updated, err := svc.RecordEvent(ctx, currentOrder.ID, delivered.ID, event)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDelivered, updated.Status)
+	assert.Equal(t, delivered.ID, event.ShipmentID)
+
+	mockRepo.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestService_RecordEvent_DeliveredOnCancelledOrderRejected(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusCancelled, 1)
+	delivered := Shipment{
+		ID:      uuid.Must(uuid.NewV4()),
+		OrderID: currentOrder.ID,
+		Status:  StatusDelivered,
+		Items:   []Item{{OrderItemID: currentOrder.OrderItems[0].ID, Quantity: 1}},
+	}
+	event := &Event{Type: EventDelivered}
+
+	mockRepo.On("AddEvent", ctx, currentOrder.ID, event).Return(currentOrder, []Shipment{delivered}, nil).Once()
+
+	_, err := svc.RecordEvent(ctx, currentOrder.ID, delivered.ID, event)
+	require.ErrorIs(t, err, order.ErrInvalidStatusTransition)
+
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	mockOrders.AssertNotCalled(t, "NotifyStatusChange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_RecordEvent_InTransitDoesNotTouchOrder(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockRepo, mockOrders)
+	ctx := context.Background()
+
+	currentOrder := newTestOrder(order.StatusCancelled, 1)
+	current := &Shipment{ID: uuid.Must(uuid.NewV4()), OrderID: currentOrder.ID, Status: StatusInTransit}
+	event := &Event{Type: EventOutForDelivery, Location: "Berlin"}
+
+	mockRepo.On("AddEvent", ctx, currentOrder.ID, event).Return(currentOrder, []Shipment{*current}, nil).Once()
+	mockRepo.On("GetByID", ctx, current.ID).Return(current, nil).Once()
+
+	_, err := svc.RecordEvent(ctx, currentOrder.ID, current.ID, event)
+	require.NoError(t, err)
+	assert.False(t, event.OccurredAt.IsZero(), "OccurredAt should default to now")
+
+	mockRepo.AssertExpectations(t)
+	mockOrders.AssertNotCalled(t, "NotifyStatusChange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_RecordEvent_ShipmentOfAnotherOrder(t *testing.T) {
+	mockRepo := new(MockShipmentRepository)
+	svc := NewService(mockRepo, new(MockOrderService))
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	event := &Event{Type: EventInTransit}
+	mockRepo.On("AddEvent", ctx, orderID, event).Return(nil, nil, ErrShipmentNotFound).Once()
+
+	_, err := svc.RecordEvent(ctx, orderID, uuid.Must(uuid.NewV4()), event)
+	require.ErrorIs(t, err, ErrShipmentNotFound)
+}
+
+func TestFulfillmentStatus(t *testing.T) {
+	o := newTestOrder(order.StatusPaid, 2, 1)
+	first := Item{OrderItemID: o.OrderItems[0].ID, Quantity: 2}
+	second := Item{OrderItemID: o.OrderItems[1].ID, Quantity: 1}
+
+	testCases := []struct {
+		name      string
+		shipments []Shipment
+		expected  order.OrderStatus
+	}{
+		{name: "nothing shipped", shipments: nil, expected: ""},
+		{name: "partial", shipments: []Shipment{{Status: StatusDelivered, Items: []Item{first}}}, expected: order.StatusPartiallyShipped},
+		{name: "all shipped", shipments: []Shipment{{Status: StatusDelivered, Items: []Item{first}}, {Status: StatusInTransit, Items: []Item{second}}}, expected: order.StatusShipped},
+		{name: "all delivered", shipments: []Shipment{{Status: StatusDelivered, Items: []Item{first, second}}}, expected: order.StatusDelivered},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, fulfillmentStatus(o, tc.shipments))
+		})
+	}
+}
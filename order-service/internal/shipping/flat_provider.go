@@ -0,0 +1,39 @@
+package shipping
+
+import "context"
+
+const FlatProviderName = "flat"
+
+type flatRateProvider struct {
+	price         float64
+	freeThreshold float64
+}
+
+// NewFlatRateProvider создаёт провайдера с фиксированной ценой доставки.
+// Если freeThreshold больше нуля, заказы на эту сумму и выше доставляются бесплатно.
+func NewFlatRateProvider(price, freeThreshold float64) ShippingRateProvider {
+	return &flatRateProvider{
+		price:         price,
+		freeThreshold: freeThreshold,
+	}
+}
+
+func (p *flatRateProvider) Name() string {
+	return FlatProviderName
+}
+
+func (p *flatRateProvider) Quote(_ context.Context, req QuoteRequest) ([]Option, error) {
+	price := p.price
+	if p.freeThreshold > 0 && req.Parcel.Subtotal >= p.freeThreshold {
+		price = 0
+	}
+
+	return []Option{{
+		ID:            optionID(FlatProviderName, "standard"),
+		Provider:      FlatProviderName,
+		Carrier:       "ANY",
+		ServiceName:   "Flat rate",
+		Price:         roundPrice(price),
+		EstimatedDays: 5,
+	}}, nil
+}
@@ -0,0 +1,44 @@
+package shipping
+
+import "strings"
+
+// Address - адрес доставки в объёме, достаточном для расчёта тарифа.
+type Address struct {
+	Country    string `json:"country"`     // Код страны ISO 3166-1 alpha-2
+	PostalCode string `json:"postal_code"` // Индекс, используется провайдерами с более точной тарификацией
+	City       string `json:"city"`
+}
+
+// NormalizedCountry возвращает код страны в верхнем регистре без пробелов.
+func (a Address) NormalizedCountry() string {
+	return strings.ToUpper(strings.TrimSpace(a.Country))
+}
+
+// Parcel - агрегированные параметры отправления.
+type Parcel struct {
+	WeightGrams int     `json:"weight_grams"`
+	Subtotal    float64 `json:"subtotal"` // Стоимость товаров, нужна для порога бесплатной доставки
+	ItemCount   int     `json:"item_count"`
+}
+
+type QuoteRequest struct {
+	Address Address
+	Parcel  Parcel
+}
+
+// Option - вариант доставки, предложенный провайдером.
+type Option struct {
+	ID            string  `json:"id"` // "<provider>:<service code>"
+	Provider      string  `json:"provider"`
+	Carrier       string  `json:"carrier"`
+	ServiceName   string  `json:"service_name"`
+	Price         float64 `json:"price"`
+	EstimatedDays int     `json:"estimated_days"`
+}
+
+// CartItem - позиция корзины, для которой ещё нет заказа.
+type CartItem struct {
+	Quantity     int     `json:"quantity"`
+	WeightGrams  int     `json:"weight_grams"`
+	PricePerUnit float64 `json:"price_per_unit"`
+}
@@ -0,0 +1,21 @@
+package shipping
+
+import (
+	"context"
+	"math"
+)
+
+// ShippingRateProvider рассчитывает варианты доставки для отправления.
+// Провайдер, который не обслуживает направление, возвращает пустой список без ошибки.
+type ShippingRateProvider interface {
+	Name() string
+	Quote(ctx context.Context, req QuoteRequest) ([]Option, error)
+}
+
+func optionID(provider, serviceCode string) string {
+	return provider + ":" + serviceCode
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,130 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var (
+	ErrInvalidAddress = errors.New("shipping address must contain a country")
+	ErrEmptyCart      = errors.New("cart must contain at least one item")
+	ErrInvalidCart    = errors.New("invalid cart item")
+	ErrNoRates        = errors.New("no shipping options available for destination")
+	ErrUnknownOption  = errors.New("shipping option is not available")
+)
+
+type Service interface {
+	QuoteCart(ctx context.Context, address Address, items []CartItem) ([]Option, error)
+	QuoteOrder(ctx context.Context, orderID uuid.UUID, address Address) ([]Option, error)
+	SelectOption(ctx context.Context, orderID uuid.UUID, optionID string, address Address) (*order.Order, error)
+}
+
+type service struct {
+	providers []ShippingRateProvider
+	orderSvc  order.Service
+}
+
+func NewService(orderSvc order.Service, providers ...ShippingRateProvider) Service {
+	return &service{
+		providers: providers,
+		orderSvc:  orderSvc,
+	}
+}
+
+func (s *service) QuoteCart(ctx context.Context, address Address, items []CartItem) ([]Option, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	var parcel Parcel
+	for _, item := range items {
+		if item.Quantity <= 0 || item.WeightGrams < 0 || item.PricePerUnit < 0 {
+			return nil, fmt.Errorf("%w: quantity must be positive, weight and price cannot be negative", ErrInvalidCart)
+		}
+		parcel.ItemCount += item.Quantity
+		parcel.WeightGrams += item.Quantity * item.WeightGrams
+		parcel.Subtotal += float64(item.Quantity) * item.PricePerUnit
+	}
+
+	return s.quote(ctx, QuoteRequest{Address: address, Parcel: parcel})
+}
+
+func (s *service) QuoteOrder(ctx context.Context, orderID uuid.UUID, address Address) ([]Option, error) {
+	currentOrder, err := s.orderSvc.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.quote(ctx, QuoteRequest{Address: address, Parcel: parcelFromOrder(currentOrder)})
+}
+
+// SelectOption заново рассчитывает тарифы для заказа и сохраняет выбранный вариант.
+// Цена берётся из расчёта, а не от клиента, поэтому подменить её нельзя.
+func (s *service) SelectOption(ctx context.Context, orderID uuid.UUID, optionID string, address Address) (*order.Order, error) {
+	options, err := s.QuoteOrder(ctx, orderID, address)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		if option.ID != optionID {
+			continue
+		}
+
+		return s.orderSvc.SetShippingLine(ctx, &order.ShippingLine{
+			OrderID:       orderID,
+			OptionID:      option.ID,
+			Provider:      option.Provider,
+			Carrier:       option.Carrier,
+			ServiceName:   option.ServiceName,
+			Price:         option.Price,
+			EstimatedDays: option.EstimatedDays,
+		})
+	}
+
+	log.Warn().Stringer("order_id", orderID).Str("option_id", optionID).Msg("service: requested shipping option not offered")
+	return nil, fmt.Errorf("%w: %s", ErrUnknownOption, optionID)
+}
+
+func (s *service) quote(ctx context.Context, req QuoteRequest) ([]Option, error) {
+	if req.Address.NormalizedCountry() == "" {
+		return nil, ErrInvalidAddress
+	}
+
+	options := make([]Option, 0)
+	for _, provider := range s.providers {
+		providerOptions, err := provider.Quote(ctx, req)
+		if err != nil {
+			// Один недоступный провайдер не должен ломать весь расчёт
+			log.Error().Err(err).Str("provider", provider.Name()).Msg("service: shipping rate provider failed")
+			continue
+		}
+		options = append(options, providerOptions...)
+	}
+
+	if len(options) == 0 {
+		return nil, ErrNoRates
+	}
+
+	sort.SliceStable(options, func(i, j int) bool {
+		return options[i].Price < options[j].Price
+	})
+
+	return options, nil
+}
+
+func parcelFromOrder(o *order.Order) Parcel {
+	var parcel Parcel
+	for _, item := range o.OrderItems {
+		parcel.ItemCount += item.Quantity
+		parcel.WeightGrams += item.Quantity * item.WeightGrams
+		parcel.Subtotal += float64(item.Quantity) * item.PricePerUnit
+	}
+	return parcel
+}
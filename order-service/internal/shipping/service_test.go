@@ -0,0 +1,268 @@
+package shipping
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+type failingProvider struct{}
+
+func (failingProvider) Name() string { return "failing" }
+
+func (failingProvider) Quote(context.Context, QuoteRequest) ([]Option, error) {
+	return nil, errors.New("carrier api unavailable")
+}
+
+func testRateTable() RateTable {
+	return RateTable{
+		Zones: map[string]string{"de": "domestic", "FR": "eu"},
+		Services: []RateService{
+			{
+				Code:          "standard",
+				Name:          "Standard",
+				Carrier:       "DHL",
+				EstimatedDays: 3,
+				Bands: []RateBand{
+					{Zone: "domestic", MaxWeightGrams: 10000, Price: 7.49},
+					{Zone: "domestic", MaxWeightGrams: 2000, Price: 4.99},
+					{Zone: "eu", MaxWeightGrams: 2000, Price: 13.99},
+				},
+			},
+		},
+	}
+}
+
+func TestTableProvider_Quote(t *testing.T) {
+	provider, err := NewTableProvider(testRateTable())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		country       string
+		weightGrams   int
+		expectedPrice float64
+		expectOption  bool
+	}{
+		{name: "light domestic parcel uses cheapest band", country: "DE", weightGrams: 1500, expectedPrice: 4.99, expectOption: true},
+		{name: "heavier domestic parcel uses next band", country: "de", weightGrams: 2001, expectedPrice: 7.49, expectOption: true},
+		{name: "eu parcel", country: "FR", weightGrams: 500, expectedPrice: 13.99, expectOption: true},
+		{name: "too heavy for eu", country: "FR", weightGrams: 5000, expectOption: false},
+		{name: "unknown zone without default", country: "US", weightGrams: 100, expectOption: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, err := provider.Quote(context.Background(), QuoteRequest{
+				Address: Address{Country: tc.country},
+				Parcel:  Parcel{WeightGrams: tc.weightGrams},
+			})
+			require.NoError(t, err)
+			if !tc.expectOption {
+				assert.Empty(t, options)
+				return
+			}
+			require.Len(t, options, 1)
+			assert.Equal(t, "table:standard", options[0].ID)
+			assert.InDelta(t, tc.expectedPrice, options[0].Price, 0.001)
+		})
+	}
+}
+
+func TestNewTableProvider_InvalidTable(t *testing.T) {
+	_, err := NewTableProvider(RateTable{})
+	require.Error(t, err)
+
+	table := testRateTable()
+	table.Services[0].Bands = append(table.Services[0].Bands, RateBand{Zone: "eu", MaxWeightGrams: 0, Price: 1})
+	_, err = NewTableProvider(table)
+	require.Error(t, err)
+}
+
+func TestLoadRateTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"zones":{"*":"world"},"services":[{"code":"std","bands":[{"zone":"world","max_weight_grams":1000,"price":9.5}]}]}`), 0o600))
+
+	table, err := LoadRateTable(path)
+	require.NoError(t, err)
+	assert.Equal(t, "world", table.Zones["*"])
+	require.Len(t, table.Services, 1)
+
+	provider, err := NewTableProvider(table)
+	require.NoError(t, err)
+	options, err := provider.Quote(context.Background(), QuoteRequest{Address: Address{Country: "JP"}, Parcel: Parcel{WeightGrams: 10}})
+	require.NoError(t, err)
+	require.Len(t, options, 1)
+	assert.InDelta(t, 9.5, options[0].Price, 0.001)
+}
+
+func TestFlatRateProvider_FreeThreshold(t *testing.T) {
+	provider := NewFlatRateProvider(4.99, 50)
+
+	options, err := provider.Quote(context.Background(), QuoteRequest{Parcel: Parcel{Subtotal: 49.99}})
+	require.NoError(t, err)
+	assert.InDelta(t, 4.99, options[0].Price, 0.001)
+
+	options, err = provider.Quote(context.Background(), QuoteRequest{Parcel: Parcel{Subtotal: 50}})
+	require.NoError(t, err)
+	assert.Zero(t, options[0].Price)
+}
+
+func TestService_QuoteCart_SortsByPriceAndSkipsFailingProvider(t *testing.T) {
+	tableProvider, err := NewTableProvider(testRateTable())
+	require.NoError(t, err)
+	svc := NewService(new(MockOrderService), NewFlatRateProvider(5.5, 0), failingProvider{}, tableProvider)
+
+	options, err := svc.QuoteCart(context.Background(), Address{Country: "DE"}, []CartItem{
+		{Quantity: 2, WeightGrams: 500, PricePerUnit: 10},
+	})
+	require.NoError(t, err)
+	require.Len(t, options, 2)
+	assert.Equal(t, "table:standard", options[0].ID)
+	assert.Equal(t, "flat:standard", options[1].ID)
+}
+
+func TestService_QuoteCart_Errors(t *testing.T) {
+	svc := NewService(new(MockOrderService), NewFlatRateProvider(5.5, 0))
+	ctx := context.Background()
+
+	_, err := svc.QuoteCart(ctx, Address{Country: "DE"}, nil)
+	require.ErrorIs(t, err, ErrEmptyCart)
+
+	_, err = svc.QuoteCart(ctx, Address{Country: "DE"}, []CartItem{{Quantity: 0}})
+	require.ErrorIs(t, err, ErrInvalidCart)
+
+	_, err = svc.QuoteCart(ctx, Address{}, []CartItem{{Quantity: 1}})
+	require.ErrorIs(t, err, ErrInvalidAddress)
+
+	tableOnly, err := NewTableProvider(testRateTable())
+	require.NoError(t, err)
+	_, err = NewService(new(MockOrderService), tableOnly).QuoteCart(ctx, Address{Country: "US"}, []CartItem{{Quantity: 1}})
+	require.ErrorIs(t, err, ErrNoRates)
+}
+
+func TestService_SelectOption_PersistsQuotedPrice(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	tableProvider, err := NewTableProvider(testRateTable())
+	require.NoError(t, err)
+	svc := NewService(mockOrders, tableProvider)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	currentOrder := &order.Order{
+		ID:     orderID,
+		Status: order.StatusNew,
+		OrderItems: []order.OrderItem{
+			{Quantity: 3, WeightGrams: 1000, PricePerUnit: 10},
+		},
+	}
+	updatedOrder := &order.Order{ID: orderID, TotalAmount: 37.49}
+
+	mockOrders.On("GetOrderByID", ctx, orderID).Return(currentOrder, nil).Once()
+	mockOrders.On("SetShippingLine", ctx, mock.MatchedBy(func(line *order.ShippingLine) bool {
+		return line.OrderID == orderID && line.OptionID == "table:standard" && line.Price == 7.49 && line.Carrier == "DHL"
+	})).Return(updatedOrder, nil).Once()
+
+	result, err := svc.SelectOption(ctx, orderID, "table:standard", Address{Country: "DE"})
+	require.NoError(t, err)
+	assert.Equal(t, updatedOrder, result)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestService_SelectOption_UnknownOption(t *testing.T) {
+	mockOrders := new(MockOrderService)
+	svc := NewService(mockOrders, NewFlatRateProvider(5, 0))
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", ctx, orderID).Return(&order.Order{ID: orderID}, nil).Once()
+
+	_, err := svc.SelectOption(ctx, orderID, "table:express", Address{Country: "DE"})
+	require.ErrorIs(t, err, ErrUnknownOption)
+	mockOrders.AssertNotCalled(t, "SetShippingLine", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,124 @@
+package shipping
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+const TableProviderName = "table"
+
+// RateBand - цена для зоны при весе отправления до MaxWeightGrams включительно.
+type RateBand struct {
+	Zone           string  `json:"zone"`
+	MaxWeightGrams int     `json:"max_weight_grams"`
+	Price          float64 `json:"price"`
+}
+
+type RateService struct {
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Carrier       string     `json:"carrier"`
+	EstimatedDays int        `json:"estimated_days"`
+	Bands         []RateBand `json:"bands"`
+}
+
+// RateTable описывает тарифную сетку. Zones сопоставляет код страны с зоной, ключ "*" задаёт зону по умолчанию.
+type RateTable struct {
+	Zones    map[string]string `json:"zones"`
+	Services []RateService     `json:"services"`
+}
+
+// LoadRateTable читает тарифную сетку из JSON файла.
+func LoadRateTable(path string) (RateTable, error) {
+	var table RateTable
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return table, fmt.Errorf("failed to read rate table %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &table); err != nil {
+		return table, fmt.Errorf("failed to parse rate table %s: %w", path, err)
+	}
+
+	return table, nil
+}
+
+type tableProvider struct {
+	zones    map[string]string
+	services []RateService
+}
+
+// NewTableProvider создаёт провайдера по тарифной сетке вес/зона/цена.
+func NewTableProvider(table RateTable) (ShippingRateProvider, error) {
+	if len(table.Zones) == 0 {
+		return nil, errors.New("rate table must define at least one zone")
+	}
+	if len(table.Services) == 0 {
+		return nil, errors.New("rate table must define at least one service")
+	}
+
+	zones := make(map[string]string, len(table.Zones))
+	for country, zone := range table.Zones {
+		zones[strings.ToUpper(strings.TrimSpace(country))] = zone
+	}
+
+	services := make([]RateService, 0, len(table.Services))
+	for _, svc := range table.Services {
+		if svc.Code == "" {
+			return nil, errors.New("rate table service code cannot be empty")
+		}
+		bands := make([]RateBand, len(svc.Bands))
+		copy(bands, svc.Bands)
+		for _, band := range bands {
+			if band.MaxWeightGrams <= 0 || band.Price < 0 {
+				return nil, fmt.Errorf("rate table service %s has invalid band for zone %s", svc.Code, band.Zone)
+			}
+		}
+		sort.Slice(bands, func(i, j int) bool {
+			return bands[i].MaxWeightGrams < bands[j].MaxWeightGrams
+		})
+		svc.Bands = bands
+		services = append(services, svc)
+	}
+
+	return &tableProvider{zones: zones, services: services}, nil
+}
+
+func (p *tableProvider) Name() string {
+	return TableProviderName
+}
+
+func (p *tableProvider) Quote(_ context.Context, req QuoteRequest) ([]Option, error) {
+	zone, ok := p.zones[req.Address.NormalizedCountry()]
+	if !ok {
+		zone, ok = p.zones["*"]
+	}
+	if !ok {
+		return []Option{}, nil
+	}
+
+	options := make([]Option, 0, len(p.services))
+	for _, svc := range p.services {
+		for _, band := range svc.Bands {
+			if band.Zone != zone || req.Parcel.WeightGrams > band.MaxWeightGrams {
+				continue
+			}
+			options = append(options, Option{
+				ID:            optionID(TableProviderName, svc.Code),
+				Provider:      TableProviderName,
+				Carrier:       svc.Carrier,
+				ServiceName:   svc.Name,
+				Price:         roundPrice(band.Price),
+				EstimatedDays: svc.EstimatedDays,
+			})
+			break
+		}
+	}
+
+	return options, nil
+}
@@ -0,0 +1,84 @@
+package tracking
+
+import (
+	"sync"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// subscriptionBuffer - сколько событий может накопиться у подписчика, пока он их не прочитал.
+const subscriptionBuffer = 16
+
+// Hub раздаёт события истории статусов подписчикам внутри процесса.
+// Подписчик, который не успевает читать, отключается: его канал закрывается,
+// и клиент переподключается с Last-Event-ID, дочитывая пропущенное из истории.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan Event]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: make(map[uuid.UUID]map[chan Event]struct{})}
+}
+
+// Subscribe подписывает на события заказа orderID. Вызов unsubscribe закрывает канал; повторный вызов безопасен.
+func (h *Hub) Subscribe(orderID uuid.UUID) (events <-chan Event, unsubscribe func()) {
+	ch := make(chan Event, subscriptionBuffer)
+
+	h.mu.Lock()
+	if h.subscribers[orderID] == nil {
+		h.subscribers[orderID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[orderID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(orderID, ch)
+	}
+}
+
+func (h *Hub) Publish(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers[event.OrderID] {
+		select {
+		case ch <- event:
+		default:
+			log.Warn().Stringer("order_id", event.OrderID).Msg("tracking: subscriber is too slow, dropping subscription")
+			h.remove(event.OrderID, ch)
+		}
+	}
+}
+
+// DisconnectAll закрывает все подписки. Вызывается, когда часть событий могла быть потеряна
+// (например, при переподключении LISTEN), чтобы клиенты дочитали их из истории.
+func (h *Hub) DisconnectAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for orderID, subs := range h.subscribers {
+		for ch := range subs {
+			h.remove(orderID, ch)
+		}
+	}
+}
+
+// remove удаляет и закрывает канал подписчика. Вызывается под h.mu.
+func (h *Hub) remove(orderID uuid.UUID, ch chan Event) {
+	subs, ok := h.subscribers[orderID]
+	if !ok {
+		return
+	}
+	if _, ok := subs[ch]; !ok {
+		return
+	}
+	delete(subs, ch)
+	close(ch)
+	if len(subs) == 0 {
+		delete(h.subscribers, orderID)
+	}
+}
@@ -0,0 +1,62 @@
+package tracking
+
+import (
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub_PublishesToSubscribersOfOrder(t *testing.T) {
+	hub := NewHub()
+	orderID := uuid.Must(uuid.NewV4())
+
+	events, unsubscribe := hub.Subscribe(orderID)
+	defer unsubscribe()
+	other, unsubscribeOther := hub.Subscribe(uuid.Must(uuid.NewV4()))
+	defer unsubscribeOther()
+
+	hub.Publish(Event{ID: 7, OrderID: orderID})
+
+	event := <-events
+	assert.Equal(t, int64(7), event.ID)
+	assert.Empty(t, other)
+}
+
+func TestHub_DropsSlowSubscriber(t *testing.T) {
+	hub := NewHub()
+	orderID := uuid.Must(uuid.NewV4())
+
+	events, unsubscribe := hub.Subscribe(orderID)
+	defer unsubscribe()
+
+	for i := 0; i <= subscriptionBuffer; i++ {
+		hub.Publish(Event{ID: int64(i + 1), OrderID: orderID})
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Equal(t, subscriptionBuffer, received, "channel must be closed after the buffered events")
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestHub_DisconnectAll(t *testing.T) {
+	hub := NewHub()
+
+	first, unsubscribeFirst := hub.Subscribe(uuid.Must(uuid.NewV4()))
+	second, unsubscribeSecond := hub.Subscribe(uuid.Must(uuid.NewV4()))
+
+	hub.DisconnectAll()
+
+	_, open := <-first
+	require.False(t, open)
+	_, open = <-second
+	require.False(t, open)
+
+	// Отписка после DisconnectAll не должна паниковать
+	unsubscribeFirst()
+	unsubscribeSecond()
+}
@@ -0,0 +1,86 @@
+package tracking
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// statusChannel - канал NOTIFY, в который триггер на orders пишет каждую смену статуса.
+const statusChannel = "order_status_changed"
+
+// listenRetryDelay - пауза перед повторным подключением после потери LISTEN соединения.
+const listenRetryDelay = 2 * time.Second
+
+// Listener получает события из Postgres LISTEN/NOTIFY и публикует их в Hub.
+// Так события доходят до подписчиков на всех репликах, а не только на той, что изменила статус.
+type Listener struct {
+	db  *pgxpool.Pool
+	hub *Hub
+}
+
+func NewListener(db *pgxpool.Pool, hub *Hub) *Listener {
+	return &Listener{db: db, hub: hub}
+}
+
+// Run слушает уведомления и блокируется до отмены ctx, переподключаясь при ошибках.
+func (l *Listener) Run(ctx context.Context) {
+	log.Info().Str("channel", statusChannel).Msg("tracking: status listener started")
+
+	reconnect := false
+	for {
+		err := l.listen(ctx, reconnect)
+		if ctx.Err() != nil {
+			log.Info().Msg("tracking: status listener stopped")
+			return
+		}
+		log.Error().Err(err).Msg("tracking: status listener failed, reconnecting")
+		reconnect = true
+
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("tracking: status listener stopped")
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (l *Listener) listen(ctx context.Context, reconnect bool) error {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("tracking: failed to acquire connection for LISTEN: %w", err)
+	}
+	// Соединение с LISTEN не возвращаем в пул, чтобы его не получил обычный запрос
+	pgConn := conn.Hijack()
+	defer func() {
+		_ = pgConn.Close(context.Background())
+	}()
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+statusChannel); err != nil {
+		return fmt.Errorf("tracking: failed to LISTEN %s: %w", statusChannel, err)
+	}
+	if reconnect {
+		// Пока соединения не было, события могли пройти мимо. Отключаем подписчиков,
+		// чтобы они переподключились с Last-Event-ID и дочитали пропущенное из истории.
+		l.hub.DisconnectAll()
+	}
+
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("tracking: failed to wait for notification: %w", err)
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Error().Err(err).Str("payload", notification.Payload).Msg("tracking: failed to decode status notification")
+			continue
+		}
+		l.hub.Publish(event)
+	}
+}
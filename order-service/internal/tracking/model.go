@@ -0,0 +1,17 @@
+package tracking
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+// Event - запись истории статусов заказа. ID растёт монотонно и служит id события SSE.
+type Event struct {
+	ID        int64             `json:"id"`
+	OrderID   uuid.UUID         `json:"order_id"`
+	From      order.OrderStatus `json:"from,omitempty"` // Пусто для создания заказа
+	To        order.OrderStatus `json:"to"`
+	ChangedAt time.Time         `json:"changed_at"`
+}
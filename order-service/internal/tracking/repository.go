@@ -0,0 +1,52 @@
+package tracking
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repository interface {
+	// ListEvents возвращает события заказа с ID больше afterID по возрастанию ID.
+	ListEvents(ctx context.Context, orderID uuid.UUID, afterID int64) ([]Event, error)
+}
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) ListEvents(ctx context.Context, orderID uuid.UUID, afterID int64) ([]Event, error) {
+	query := `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, changed_at
+		FROM order_service.order_status_history
+		WHERE order_id = $1 AND id > $2
+		ORDER BY id
+	`
+
+	rows, err := r.db.Query(ctx, query, orderID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query status history for order %s: %w", orderID, err)
+	}
+	defer rows.Close()
+
+	events := make([]Event, 0)
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(&event.ID, &event.OrderID, &event.From, &event.To, &event.ChangedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan status history for order %s: %w", orderID, err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating status history for order %s: %w", orderID, err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,133 @@
+package tracking_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/tracking"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=order_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+func truncateTables(tb testing.TB, pool *pgxpool.Pool) {
+	tb.Helper()
+	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE order_service.order_status_history, order_service.order_items, order_service.orders CASCADE")
+	require.NoError(tb, err, "failed to truncate tables")
+}
+
+func createTestOrder(t *testing.T) *order.Order {
+	t.Helper()
+	o := &order.Order{
+		UserID: uuid.Must(uuid.NewV4()),
+		Status: order.StatusNew,
+		OrderItems: []order.OrderItem{
+			{ProductID: uuid.Must(uuid.NewV4()), Quantity: 1, PricePerUnit: 10},
+		},
+	}
+	_, err := order.NewRepository(testDB).CreateOrder(context.Background(), o)
+	require.NoError(t, err)
+	return o
+}
+
+func TestRepository_ListEvents_RecordsEveryStatusChange(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	orderRepo := order.NewRepository(testDB)
+	repo := tracking.NewRepository(testDB)
+
+	o := createTestOrder(t)
+	require.NoError(t, orderRepo.UpdateOrderStatus(ctx, o.ID, order.StatusProcessing))
+	require.NoError(t, orderRepo.UpdateOrderStatus(ctx, o.ID, order.StatusPaid))
+
+	events, err := repo.ListEvents(ctx, o.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, order.OrderStatus(""), events[0].From)
+	require.Equal(t, order.StatusNew, events[0].To)
+	require.Equal(t, order.StatusProcessing, events[1].From)
+	require.Equal(t, order.StatusPaid, events[2].To)
+
+	// Возобновление после второго события
+	resumed, err := repo.ListEvents(ctx, o.ID, events[1].ID)
+	require.NoError(t, err)
+	require.Len(t, resumed, 1)
+	require.Equal(t, events[2].ID, resumed[0].ID)
+}
+
+func TestListener_PublishesNotificationsToHub(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	o := createTestOrder(t)
+	hub := tracking.NewHub()
+	events, unsubscribe := hub.Subscribe(o.ID)
+	defer unsubscribe()
+
+	go tracking.NewListener(testDB, hub).Run(ctx)
+
+	// LISTEN устанавливается асинхронно, поэтому повторяем смену статуса, пока событие не придёт
+	statuses := []order.OrderStatus{order.StatusProcessing, order.StatusNew}
+	for i := 0; ; i++ {
+		_, err := testDB.Exec(ctx, "UPDATE order_service.orders SET status = $1 WHERE id = $2", statuses[i%2], o.ID)
+		require.NoError(t, err)
+
+		select {
+		case event := <-events:
+			require.Equal(t, o.ID, event.OrderID)
+			require.NotZero(t, event.ID)
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no notification received")
+		}
+	}
+}
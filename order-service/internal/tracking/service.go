@@ -0,0 +1,63 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+// Subscription - события заказа для одного клиента: сначала Backlog из истории, затем Live.
+// Live закрывается, если клиент отстал или события могли быть потеряны; тогда нужно подписаться заново.
+// В Live могут прийти события, уже вошедшие в Backlog, - их отбрасывают по ID.
+type Subscription struct {
+	Backlog []Event
+	Live    <-chan Event
+	Close   func()
+}
+
+type Service interface {
+	// Subscribe проверяет, что principal может видеть заказ, и подписывает на события с ID больше afterID.
+	// Чужой заказ неотличим от несуществующего: возвращается order.ErrOrderNotFound.
+	Subscribe(ctx context.Context, orderID uuid.UUID, principal *authz.Principal, afterID int64) (*Subscription, error)
+}
+
+type service struct {
+	repo     Repository
+	hub      *Hub
+	orderSvc order.Service
+}
+
+func NewService(repo Repository, hub *Hub, orderSvc order.Service) Service {
+	return &service{repo: repo, hub: hub, orderSvc: orderSvc}
+}
+
+func (s *service) Subscribe(ctx context.Context, orderID uuid.UUID, principal *authz.Principal, afterID int64) (*Subscription, error) {
+	currentOrder, err := s.orderSvc.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !principal.CanAccess(currentOrder.UserID) {
+		log.Warn().Stringer("order_id", orderID).Stringer("user_id", principal.UserID).Msg("service: attempt to track someone else's order")
+		return nil, order.ErrOrderNotFound
+	}
+
+	// Подписываемся до чтения истории, чтобы не потерять события между чтением и подпиской
+	live, unsubscribe := s.hub.Subscribe(orderID)
+
+	backlog, err := s.repo.ListEvents(ctx, orderID, afterID)
+	if err != nil {
+		unsubscribe()
+		if errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+		log.Error().Err(err).Stringer("order_id", orderID).Msg("service: failed to load order status history")
+		return nil, fmt.Errorf("service: failed to load order status history: %w", err)
+	}
+
+	return &Subscription{Backlog: backlog, Live: live, Close: unsubscribe}, nil
+}
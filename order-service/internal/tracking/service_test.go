@@ -0,0 +1,164 @@
+package tracking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/authz"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) ListEvents(ctx context.Context, orderID uuid.UUID, afterID int64) ([]Event, error) {
+	args := m.Called(ctx, orderID, afterID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Event), args.Error(1)
+}
+
+type MockOrderService struct {
+	mock.Mock
+}
+
+func (m *MockOrderService) CreateOrder(ctx context.Context, orderInput *order.Order) (*order.Order, error) {
+	args := m.Called(ctx, orderInput)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, id uuid.UUID) (*order.Order, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) GetOrdersByUserID(ctx context.Context, userID uuid.UUID) ([]order.Order, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) UpdateOrderStatus(ctx context.Context, orderID uuid.UUID, newStatus order.OrderStatus) error {
+	args := m.Called(ctx, orderID, newStatus)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) SetShippingLine(ctx context.Context, line *order.ShippingLine) (*order.Order, error) {
+	args := m.Called(ctx, line)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*order.Order), args.Error(1)
+}
+
+func (m *MockOrderService) FindStaleUnpaidOrderIDs(ctx context.Context, createdBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, createdBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderService) CancelUnpaidOrder(ctx context.Context, orderID uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderID, reason)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) BulkUpdateStatus(ctx context.Context, ids []uuid.UUID, newStatus order.OrderStatus) ([]order.BulkUpdateResult, error) {
+	args := m.Called(ctx, ids, newStatus)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]order.BulkUpdateResult), args.Error(1)
+}
+
+func (m *MockOrderService) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) AnonymizeUserOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockOrderService) NotifyStatusChange(ctx context.Context, orderID uuid.UUID, from, to order.OrderStatus) {
+	m.Called(ctx, orderID, from, to)
+}
+
+func TestService_Subscribe_ReturnsBacklogAndLiveEvents(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockOrders := new(MockOrderService)
+	hub := NewHub()
+	svc := NewService(mockRepo, hub, mockOrders)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+	backlog := []Event{{ID: 4, OrderID: orderID, From: order.StatusPaid, To: order.StatusShipped}}
+
+	mockOrders.On("GetOrderByID", ctx, orderID).Return(&order.Order{ID: orderID, UserID: userID}, nil).Once()
+	mockRepo.On("ListEvents", ctx, orderID, int64(3)).Return(backlog, nil).Once()
+
+	sub, err := svc.Subscribe(ctx, orderID, &authz.Principal{UserID: userID}, 3)
+	require.NoError(t, err)
+	defer sub.Close()
+	assert.Equal(t, backlog, sub.Backlog)
+
+	hub.Publish(Event{ID: 5, OrderID: orderID, To: order.StatusDelivered})
+	live := <-sub.Live
+	assert.Equal(t, int64(5), live.ID)
+
+	mockRepo.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestService_Subscribe_ForeignOrderLooksMissing(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockOrders := new(MockOrderService)
+	hub := NewHub()
+	svc := NewService(mockRepo, hub, mockOrders)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	mockOrders.On("GetOrderByID", ctx, orderID).Return(&order.Order{ID: orderID, UserID: uuid.Must(uuid.NewV4())}, nil).Once()
+
+	_, err := svc.Subscribe(ctx, orderID, &authz.Principal{UserID: uuid.Must(uuid.NewV4())}, 0)
+	require.ErrorIs(t, err, order.ErrOrderNotFound)
+	mockRepo.AssertNotCalled(t, "ListEvents", mock.Anything, mock.Anything, mock.Anything)
+	assert.Empty(t, hub.subscribers)
+}
+
+func TestService_Subscribe_HistoryErrorReleasesSubscription(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockOrders := new(MockOrderService)
+	hub := NewHub()
+	svc := NewService(mockRepo, hub, mockOrders)
+	ctx := context.Background()
+
+	orderID := uuid.Must(uuid.NewV4())
+	userID := uuid.Must(uuid.NewV4())
+	repoErr := errors.New("db is down")
+	mockOrders.On("GetOrderByID", ctx, orderID).Return(&order.Order{ID: orderID, UserID: userID}, nil).Once()
+	mockRepo.On("ListEvents", ctx, orderID, int64(0)).Return(nil, repoErr).Once()
+
+	_, err := svc.Subscribe(ctx, orderID, &authz.Principal{UserID: userID}, 0)
+	require.ErrorIs(t, err, repoErr)
+	assert.Empty(t, hub.subscribers)
+}
@@ -0,0 +1,70 @@
+// Package userclient - HTTP клиент user-service для проверки покупателя перед оформлением заказа.
+package userclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type Client struct {
+	baseURL    string
+	token      string // Токен внутренних маршрутов user-service
+	httpClient *http.Client
+}
+
+// New создаёт клиент user-service. baseURL - адрес HTTP API, например "http://user-service:8080".
+// token передаётся в маршруты /internal, пустой не передаётся.
+func New(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type emailVerificationResponse struct {
+	EmailVerified bool `json:"email_verified"`
+}
+
+// IsEmailVerified сообщает, подтвердил ли пользователь email. order.ErrCustomerNotFound, если пользователя нет или он удалён.
+func (c *Client) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	endpoint := c.baseURL + "/internal/users/" + url.PathEscape(userID.String()) + "/email-verification"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, fmt.Errorf("userclient: failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("userclient: request to user-service failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return false, order.ErrCustomerNotFound
+	default:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, fmt.Errorf("userclient: user-service responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload emailVerificationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return false, fmt.Errorf("userclient: failed to decode user-service response: %w", err)
+	}
+	return payload.EmailVerified, nil
+}
@@ -0,0 +1,52 @@
+package userclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/userclient"
+)
+
+func TestClient_IsEmailVerified(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/internal/users/"+userID.String()+"/email-verification", r.URL.Path)
+		assert.Equal(t, "Bearer internal-secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user_id":"` + userID.String() + `","email_verified":true}`))
+	}))
+	defer server.Close()
+
+	verified, err := userclient.New(server.URL+"/", "internal-secret", time.Second).IsEmailVerified(context.Background(), userID)
+	require.NoError(t, err)
+	assert.True(t, verified)
+}
+
+func TestClient_IsEmailVerified_UnknownUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	_, err := userclient.New(server.URL, "", time.Second).IsEmailVerified(context.Background(), uuid.Must(uuid.NewV4()))
+	require.ErrorIs(t, err, order.ErrCustomerNotFound)
+}
+
+func TestClient_IsEmailVerified_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Invalid or missing API token"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, err := userclient.New(server.URL, "wrong", time.Second).IsEmailVerified(context.Background(), uuid.Must(uuid.NewV4()))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, order.ErrCustomerNotFound)
+	assert.Contains(t, err.Error(), "401")
+}
@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "PENDING"
+	DeliverySucceeded DeliveryStatus = "SUCCEEDED"
+	DeliveryDead      DeliveryStatus = "DEAD" // Все попытки исчерпаны, доставка ждёт ручного повтора
+)
+
+// eventStatuses - статусы, переход в которые рассылается партнёрам. Тип события - "order.<статус>".
+var eventStatuses = []order.OrderStatus{
+	order.StatusProcessing,
+	order.StatusPaid,
+	order.StatusPartiallyShipped,
+	order.StatusShipped,
+	order.StatusDelivered,
+	order.StatusCancelled,
+}
+
+// EventTypeForStatus возвращает тип события для перехода заказа в status, например "order.shipped".
+func EventTypeForStatus(status order.OrderStatus) string {
+	return "order." + strings.ToLower(string(status))
+}
+
+// KnownEventTypes - типы событий, на которые можно подписаться.
+func KnownEventTypes() []string {
+	types := make([]string, 0, len(eventStatuses))
+	for _, status := range eventStatuses {
+		types = append(types, EventTypeForStatus(status))
+	}
+	return types
+}
+
+type Subscription struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"-" db:"secret"` // Никогда не отдаётся в API
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	Active     bool      `json:"active" db:"active"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type Delivery struct {
+	ID                 uuid.UUID       `json:"id" db:"id"`
+	SubscriptionID     uuid.UUID       `json:"subscription_id" db:"subscription_id"`
+	EventID            uuid.UUID       `json:"event_id" db:"event_id"`
+	EventType          string          `json:"event_type" db:"event_type"`
+	Payload            json.RawMessage `json:"payload" db:"payload"`
+	Status             DeliveryStatus  `json:"status" db:"status"`
+	Attempts           int             `json:"attempts" db:"attempts"`
+	NextAttemptAt      time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	LastError          string          `json:"last_error,omitempty" db:"last_error"`
+	LastResponseStatus int             `json:"last_response_status,omitempty" db:"last_response_status"`
+	DeliveredAt        *time.Time      `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
+
+	// Заполняются только при выборке доставки на отправку
+	URL            string    `json:"-" db:"-"`
+	Secret         string    `json:"-" db:"-"`
+	LeaseID        uuid.UUID `json:"-" db:"-"` // Закрепление за воркером; результат попытки сохраняется только с ним
+	LeaseExpiresAt time.Time `json:"-" db:"-"` // После этого момента доставку может взять другой воркер
+}
+
+// Attempt - запись журнала попыток доставки.
+type Attempt struct {
+	ID             int64     `json:"id" db:"id"`
+	DeliveryID     uuid.UUID `json:"delivery_id" db:"delivery_id"`
+	Attempt        int       `json:"attempt" db:"attempt"`
+	ResponseStatus int       `json:"response_status,omitempty" db:"response_status"` // 0, если ответа не было
+	Error          string    `json:"error,omitempty" db:"error"`
+	DurationMs     int64     `json:"duration_ms" db:"duration_ms"`
+	AttemptedAt    time.Time `json:"attempted_at" db:"attempted_at"`
+}
+
+// Event - тело запроса, которое получает партнёр.
+type Event struct {
+	ID        uuid.UUID          `json:"id"`
+	Type      string             `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      order.StatusChange `json:"data"`
+}
@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var webhookMetrics = expvar.NewMap("webhooks")
+
+// DefaultRelayBatchSize - сколько записей истории статусов обрабатывается в одной транзакции.
+const DefaultRelayBatchSize = 100
+
+// statusEventNamespace - пространство имён id событий. id выводится из записи истории,
+// поэтому у события один и тот же id, даже если запись обработают повторно.
+var statusEventNamespace = uuid.Must(uuid.FromString("70d6f5f8-1456-4745-9bc2-b81abf5871ee"))
+
+// Relay превращает смены статуса заказов в доставки по подпискам. Источник - order_status_history:
+// запись в ней появляется в одной транзакции со сменой статуса, поэтому событие не теряется
+// ни при падении, ни при остановке сервиса. Несколько реплик могут работать одновременно.
+type Relay struct {
+	repo         Repository
+	batchSize    int
+	pollInterval time.Duration
+}
+
+func NewRelay(repo Repository, batchSize int, pollInterval time.Duration) *Relay {
+	return &Relay{repo: repo, batchSize: batchSize, pollInterval: pollInterval}
+}
+
+// Run проверяет историю каждые pollInterval и блокируется до отмены ctx.
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Полная пачка значит, что в истории могут быть ещё необработанные записи
+		for {
+			changes, err := r.RelayOnce(ctx)
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					webhookMetrics.Add("errors", 1)
+					log.Error().Err(err).Msg("webhook: failed to enqueue deliveries")
+				}
+				break
+			}
+			if changes < r.batchSize {
+				break
+			}
+		}
+	}
+}
+
+// RelayOnce создаёт доставки для очередной пачки смен статуса и возвращает число обработанных записей истории.
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	changes, deliveries, err := r.repo.EnqueueStatusChanges(ctx, r.batchSize, newStatusEvent)
+	if err != nil {
+		return 0, err
+	}
+	webhookMetrics.Add("deliveries_enqueued", int64(deliveries))
+	return changes, nil
+}
+
+// newStatusEvent строит тело доставки по записи истории статусов.
+func newStatusEvent(historyID int64, change order.StatusChange) (*PendingEvent, error) {
+	event := Event{
+		ID:        uuid.NewV5(statusEventNamespace, strconv.FormatInt(historyID, 10)),
+		Type:      EventTypeForStatus(change.To),
+		CreatedAt: change.ChangedAt.UTC(),
+		Data:      change,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("webhook: failed to marshal event: %w", err)
+	}
+	return &PendingEvent{ID: event.ID, Type: event.Type, Payload: payload}, nil
+}
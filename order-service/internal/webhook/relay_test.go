@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+func TestRelay_RelayOnce_BuildsEventFromHistory(t *testing.T) {
+	mockRepo := new(MockRepository)
+	relay := NewRelay(mockRepo, 10, time.Second)
+	change := order.StatusChange{
+		OrderID:   uuid.Must(uuid.NewV4()),
+		From:      order.StatusProcessing,
+		To:        order.StatusPaid,
+		ChangedAt: time.Now().UTC(),
+	}
+
+	var built, rebuilt *PendingEvent
+	mockRepo.On("EnqueueStatusChanges", mock.Anything, 10, mock.Anything).
+		Run(func(args mock.Arguments) {
+			build := args.Get(2).(BuildEvent)
+			var err error
+			built, err = build(42, change)
+			require.NoError(t, err)
+			rebuilt, err = build(42, change)
+			require.NoError(t, err)
+		}).
+		Return(1, 2, nil).Once()
+
+	changes, err := relay.RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, changes)
+
+	require.NotNil(t, built)
+	assert.Equal(t, "order.paid", built.Type)
+	assert.Equal(t, built.ID, rebuilt.ID, "event ID must be stable for the same history entry")
+
+	var event Event
+	require.NoError(t, json.Unmarshal(built.Payload, &event))
+	assert.Equal(t, built.ID, event.ID)
+	assert.Equal(t, change.OrderID, event.Data.OrderID)
+	assert.Equal(t, order.StatusProcessing, event.Data.From)
+	assert.True(t, change.ChangedAt.Equal(event.CreatedAt))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRelay_RelayOnce_RepositoryError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	relay := NewRelay(mockRepo, 10, time.Second)
+	dbErr := errors.New("connection refused")
+	mockRepo.On("EnqueueStatusChanges", mock.Anything, 10, mock.Anything).Return(0, 0, dbErr).Once()
+
+	_, err := relay.RelayOnce(context.Background())
+	require.ErrorIs(t, err, dbErr)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRelay_Run_DrainsFullBatches(t *testing.T) {
+	mockRepo := new(MockRepository)
+	relay := NewRelay(mockRepo, 2, 10*time.Millisecond)
+
+	drained := make(chan struct{})
+	mockRepo.On("EnqueueStatusChanges", mock.Anything, 2, mock.Anything).Return(2, 2, nil).Twice()
+	mockRepo.On("EnqueueStatusChanges", mock.Anything, 2, mock.Anything).
+		Run(func(mock.Arguments) { close(drained) }).Return(1, 0, nil).Once()
+	mockRepo.On("EnqueueStatusChanges", mock.Anything, 2, mock.Anything).Return(0, 0, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		relay.Run(ctx)
+		close(stopped)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(2 * time.Second):
+		t.Fatal("relay did not drain the history")
+	}
+	cancel()
+	<-stopped
+}
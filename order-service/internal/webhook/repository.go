@@ -0,0 +1,421 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrLeaseLost            = errors.New("webhook delivery lease lost")
+)
+
+type Repository interface {
+	CreateSubscription(ctx context.Context, sub *Subscription) error
+	GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	// EnqueueStatusChanges в одной транзакции берёт до limit записей истории статусов, по которым ещё нет доставок,
+	// создаёт по событию из build доставку для каждой активной подписки и помечает записи обработанными.
+	// Возвращает число обработанных записей и созданных доставок.
+	EnqueueStatusChanges(ctx context.Context, limit int, build BuildEvent) (changes int, deliveries int, err error)
+	// ClaimDueDeliveries выбирает до limit ожидающих доставок, у которых подошло время попытки, и откладывает
+	// их следующую попытку на lease, чтобы другие воркеры (в том числе на других репликах) их не взяли.
+	// Каждая доставка получает новый LeaseID.
+	ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	// SaveAttempt записывает попытку в журнал и сохраняет новое состояние доставки одной транзакцией.
+	// Если доставку с тех пор закрепил другой воркер или поставили в очередь заново, ничего не сохраняет и возвращает ErrLeaseLost.
+	SaveAttempt(ctx context.Context, delivery *Delivery, attempt *Attempt) error
+	GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error)
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error)
+	ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error)
+	// Redeliver возвращает доставку в очередь с обнулённым счётчиком попыток.
+	Redeliver(ctx context.Context, id uuid.UUID) (*Delivery, error)
+}
+
+// PendingEvent - событие, по которому создаются доставки.
+type PendingEvent struct {
+	ID      uuid.UUID
+	Type    string
+	Payload []byte
+}
+
+// BuildEvent строит событие по записи истории статусов с идентификатором historyID.
+type BuildEvent func(historyID int64, change order.StatusChange) (*PendingEvent, error)
+
+type postgresRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewRepository(db *pgxpool.Pool) Repository {
+	return &postgresRepository{db: db}
+}
+
+const selectSubscriptionColumns = `
+	SELECT id, url, secret, event_types, active, created_at, updated_at
+	FROM order_service.webhook_subscriptions
+`
+
+const deliveryColumns = `
+	id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_error, last_response_status, delivered_at, created_at, updated_at
+`
+
+func (r *postgresRepository) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	if sub.ID == uuid.Nil {
+		genID, err := uuid.NewV4()
+		if err != nil {
+			return fmt.Errorf("repository: failed to generate subscription ID: %w", err)
+		}
+		sub.ID = genID
+	}
+
+	query := `
+		INSERT INTO order_service.webhook_subscriptions (id, url, secret, event_types, active)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at, updated_at
+	`
+	err := r.db.QueryRow(ctx, query, sub.ID, sub.URL, sub.Secret, sub.EventTypes, sub.Active).Scan(&sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("repository: failed to create webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	sub, err := scanSubscription(r.db.QueryRow(ctx, selectSubscriptionColumns+" WHERE id = $1", id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSubscriptionNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to get webhook subscription %s: %w", id, err)
+	}
+	return sub, nil
+}
+
+func (r *postgresRepository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	rows, err := r.db.Query(ctx, selectSubscriptionColumns+" ORDER BY created_at")
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := make([]Subscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan webhook subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (r *postgresRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM order_service.webhook_subscriptions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("repository: failed to delete webhook subscription %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) EnqueueStatusChanges(ctx context.Context, limit int, build BuildEvent) (changes int, deliveries int, err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Msg("Panic recovered during webhook EnqueueStatusChanges, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				log.Error().Err(rbErr).Msg("Failed to rollback transaction")
+			}
+		} else {
+			if cErr := tx.Commit(ctx); cErr != nil {
+				err = fmt.Errorf("repository: failed to commit transaction: %w", cErr)
+			}
+		}
+	}()
+
+	// Другая реплика пропускает уже взятые записи и обрабатывает следующие
+	selectChanges := `
+		SELECT id, order_id, COALESCE(from_status, ''), to_status, changed_at
+		FROM order_service.order_status_history
+		WHERE webhooks_enqueued_at IS NULL
+		ORDER BY id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	`
+	rows, err := tx.Query(ctx, selectChanges, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to query pending status changes: %w", err)
+	}
+	var ids []int64
+	var pending []order.StatusChange
+	for rows.Next() {
+		var id int64
+		var change order.StatusChange
+		if err := rows.Scan(&id, &change.OrderID, &change.From, &change.To, &change.ChangedAt); err != nil {
+			rows.Close()
+			return 0, 0, fmt.Errorf("repository: failed to scan pending status change: %w", err)
+		}
+		ids = append(ids, id)
+		pending = append(pending, change)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("repository: failed iterating pending status changes: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, 0, nil
+	}
+
+	enqueueDeliveries := `
+		INSERT INTO order_service.webhook_deliveries (id, subscription_id, event_id, event_type, payload, status)
+		SELECT gen_random_uuid(), id, $1, $2, $3, 'PENDING'
+		FROM order_service.webhook_subscriptions
+		WHERE active AND $2 = ANY(event_types)
+	`
+	for i, change := range pending {
+		event, err := build(ids[i], change)
+		if err != nil {
+			return 0, 0, err
+		}
+		tag, err := tx.Exec(ctx, enqueueDeliveries, event.ID, event.Type, event.Payload)
+		if err != nil {
+			return 0, 0, fmt.Errorf("repository: failed to enqueue webhook deliveries for event %s: %w", event.ID, err)
+		}
+		deliveries += int(tag.RowsAffected())
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE order_service.order_status_history SET webhooks_enqueued_at = NOW() WHERE id = ANY($1)", ids)
+	if err != nil {
+		return 0, 0, fmt.Errorf("repository: failed to mark status changes as enqueued: %w", err)
+	}
+	return len(ids), deliveries, nil
+}
+
+func (r *postgresRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE order_service.webhook_deliveries
+			SET next_attempt_at = NOW() + $2 * INTERVAL '1 millisecond', lease_id = gen_random_uuid()
+			WHERE id IN (
+				SELECT id FROM order_service.webhook_deliveries
+				WHERE status = 'PENDING' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING ` + deliveryColumns + `, lease_id
+		)
+		SELECT claimed.*, s.url, s.secret
+		FROM claimed
+		JOIN order_service.webhook_subscriptions s ON s.id = claimed.subscription_id
+	`
+	rows, err := r.db.Query(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]Delivery, 0, limit)
+	for rows.Next() {
+		d, err := scanDelivery(rows, true)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan claimed webhook delivery: %w", err)
+		}
+		d.LeaseExpiresAt = d.NextAttemptAt
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating claimed webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresRepository) SaveAttempt(ctx context.Context, delivery *Delivery, attempt *Attempt) (err error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("repository: failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.Error().Interface("panic_value", p).Stringer("delivery_id", delivery.ID).Msg("Panic recovered during webhook SaveAttempt, rolling back")
+			if rbErr := tx.Rollback(ctx); rbErr != nil {
+				log.Error().Err(rbErr).Stringer("delivery_id", delivery.ID).Msg("Failed to rollback transaction after panic")
+			}
+			panic(p)
+		} else if err != nil {
+			if rbErr := tx.Rollback(ctx); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				log.Error().Err(rbErr).Stringer("delivery_id", delivery.ID).Msg("Failed to rollback transaction")
+			}
+		} else {
+			if cErr := tx.Commit(ctx); cErr != nil {
+				err = fmt.Errorf("repository: failed to commit transaction: %w", cErr)
+			}
+		}
+	}()
+
+	insertAttempt := `
+		INSERT INTO order_service.webhook_delivery_attempts (delivery_id, attempt, response_status, error, duration_ms, attempted_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, insertAttempt,
+		attempt.DeliveryID, attempt.Attempt, attempt.ResponseStatus, attempt.Error, attempt.DurationMs, attempt.AttemptedAt,
+	).Scan(&attempt.ID)
+	if err != nil {
+		return fmt.Errorf("repository: failed to insert webhook attempt for delivery %s: %w", delivery.ID, err)
+	}
+
+	// Попытка засчитывается, только если доставка всё ещё закреплена за этим воркером и счётчик не сбросил Redeliver
+	updateDelivery := `
+		UPDATE order_service.webhook_deliveries
+		SET status = $1, attempts = $2, next_attempt_at = $3, last_error = $4,
+		    last_response_status = $5, delivered_at = $6, lease_id = NULL, updated_at = NOW()
+		WHERE id = $7 AND lease_id = $8 AND attempts = $2 - 1
+	`
+	tag, err := tx.Exec(ctx, updateDelivery,
+		string(delivery.Status), delivery.Attempts, delivery.NextAttemptAt, delivery.LastError,
+		delivery.LastResponseStatus, delivery.DeliveredAt, delivery.ID, delivery.LeaseID,
+	)
+	if err != nil {
+		return fmt.Errorf("repository: failed to update webhook delivery %s: %w", delivery.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	row := r.db.QueryRow(ctx, "SELECT "+deliveryColumns+" FROM order_service.webhook_deliveries WHERE id = $1", id)
+	d, err := scanDelivery(row, false)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to get webhook delivery %s: %w", id, err)
+	}
+	return d, nil
+}
+
+func (r *postgresRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error) {
+	query := "SELECT " + deliveryColumns + `
+		FROM order_service.webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, subscriptionID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query webhook deliveries for subscription %s: %w", subscriptionID, err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows, false)
+		if err != nil {
+			return nil, fmt.Errorf("repository: failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (r *postgresRepository) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error) {
+	query := `
+		SELECT id, delivery_id, attempt, response_status, error, duration_ms, attempted_at
+		FROM order_service.webhook_delivery_attempts
+		WHERE delivery_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, query, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("repository: failed to query webhook attempts for delivery %s: %w", deliveryID, err)
+	}
+	defer rows.Close()
+
+	attempts := make([]Attempt, 0)
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.Attempt, &a.ResponseStatus, &a.Error, &a.DurationMs, &a.AttemptedAt); err != nil {
+			return nil, fmt.Errorf("repository: failed to scan webhook attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: failed iterating webhook attempts: %w", err)
+	}
+	return attempts, nil
+}
+
+func (r *postgresRepository) Redeliver(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	query := `
+		UPDATE order_service.webhook_deliveries
+		SET status = 'PENDING', attempts = 0, next_attempt_at = NOW(), lease_id = NULL, updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + deliveryColumns
+	d, err := scanDelivery(r.db.QueryRow(ctx, query, id), false)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("repository: failed to requeue webhook delivery %s: %w", id, err)
+	}
+	return d, nil
+}
+
+func scanSubscription(row pgx.Row) (*Subscription, error) {
+	var sub Subscription
+	err := row.Scan(&sub.ID, &sub.URL, &sub.Secret, &sub.EventTypes, &sub.Active, &sub.CreatedAt, &sub.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// scanDelivery читает колонки deliveryColumns; withTarget - если за ними следуют lease_id, url и secret подписки.
+func scanDelivery(row pgx.Row, withTarget bool) (*Delivery, error) {
+	var d Delivery
+	var status string
+	var payload []byte
+	dest := []any{
+		&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &payload, &status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastError, &d.LastResponseStatus, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt,
+	}
+	if withTarget {
+		dest = append(dest, &d.LeaseID, &d.URL, &d.Secret)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	d.Status = DeliveryStatus(status)
+	d.Payload = payload
+	return &d, nil
+}
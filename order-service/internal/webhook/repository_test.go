@@ -0,0 +1,266 @@
+package webhook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/order"
+	"github.com/vasiliy-maslov/ecommerce-microservices/order-service/internal/webhook"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=order_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func truncateTables(tb testing.TB, pool *pgxpool.Pool) {
+	tb.Helper()
+	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE order_service.webhook_subscriptions, order_service.orders CASCADE")
+	require.NoError(tb, err, "failed to truncate tables")
+}
+
+func createTestSubscription(t *testing.T, repo webhook.Repository, active bool, eventTypes ...string) *webhook.Subscription {
+	t.Helper()
+	sub := &webhook.Subscription{
+		URL:        "https://partner.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: eventTypes,
+		Active:     active,
+	}
+	require.NoError(t, repo.CreateSubscription(context.Background(), sub))
+	return sub
+}
+
+// createTestOrder создаёт заказ в статусе status; триггер записывает создание в историю статусов.
+func createTestOrder(t *testing.T, status order.OrderStatus) uuid.UUID {
+	t.Helper()
+	orderID := uuid.Must(uuid.NewV4())
+	_, err := testDB.Exec(context.Background(),
+		"INSERT INTO order_service.orders (id, user_id, status) VALUES ($1, $2, $3)",
+		orderID, uuid.Must(uuid.NewV4()), string(status))
+	require.NoError(t, err)
+	return orderID
+}
+
+// testEvent строит событие с типом по новому статусу и телом {"type": ...}.
+func testEvent(_ int64, change order.StatusChange) (*webhook.PendingEvent, error) {
+	eventType := webhook.EventTypeForStatus(change.To)
+	return &webhook.PendingEvent{
+		ID:      uuid.Must(uuid.NewV4()),
+		Type:    eventType,
+		Payload: []byte(`{"type":"` + eventType + `"}`),
+	}, nil
+}
+
+// enqueueTestDelivery создаёт оплаченный заказ и доставки по его событию.
+func enqueueTestDelivery(t *testing.T, repo webhook.Repository) {
+	t.Helper()
+	createTestOrder(t, order.StatusPaid)
+	_, _, err := repo.EnqueueStatusChanges(context.Background(), 10, testEvent)
+	require.NoError(t, err)
+}
+
+func TestRepository_EnqueueStatusChanges_MatchesActiveSubscriptions(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	repo := webhook.NewRepository(testDB)
+
+	paid := createTestSubscription(t, repo, true, "order.paid", "order.shipped")
+	createTestSubscription(t, repo, true, "order.cancelled")
+	createTestSubscription(t, repo, false, "order.paid")
+	createTestOrder(t, order.StatusPaid)
+
+	changes, deliveries, err := repo.EnqueueStatusChanges(ctx, 10, testEvent)
+	require.NoError(t, err)
+	require.Equal(t, 1, changes)
+	require.Equal(t, 1, deliveries)
+
+	stored, err := repo.ListDeliveries(ctx, paid.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, webhook.DeliveryPending, stored[0].Status)
+	assert.JSONEq(t, `{"type":"order.paid"}`, string(stored[0].Payload))
+
+	// Обработанная запись истории не создаёт доставки повторно
+	changes, _, err = repo.EnqueueStatusChanges(ctx, 10, testEvent)
+	require.NoError(t, err)
+	assert.Zero(t, changes)
+}
+
+func TestRepository_EnqueueStatusChanges_BuildErrorKeepsHistoryPending(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	repo := webhook.NewRepository(testDB)
+
+	paid := createTestSubscription(t, repo, true, "order.paid")
+	createTestOrder(t, order.StatusPaid)
+
+	buildErr := errors.New("marshal failed")
+	_, _, err := repo.EnqueueStatusChanges(ctx, 10, func(int64, order.StatusChange) (*webhook.PendingEvent, error) {
+		return nil, buildErr
+	})
+	require.ErrorIs(t, err, buildErr)
+
+	changes, deliveries, err := repo.EnqueueStatusChanges(ctx, 10, testEvent)
+	require.NoError(t, err)
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, 1, deliveries)
+
+	stored, err := repo.ListDeliveries(ctx, paid.ID, 10)
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+func TestRepository_ClaimDueDeliveries_LeasesDeliveries(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	repo := webhook.NewRepository(testDB)
+
+	sub := createTestSubscription(t, repo, true, "order.paid")
+	enqueueTestDelivery(t, repo)
+
+	claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, sub.URL, claimed[0].URL)
+	assert.Equal(t, sub.Secret, claimed[0].Secret)
+
+	// Пока закрепление не истекло, доставку не получит другой воркер
+	again, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	assert.Empty(t, again)
+}
+
+func TestRepository_SaveAttempt_AndRedeliver(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	repo := webhook.NewRepository(testDB)
+
+	createTestSubscription(t, repo, true, "order.paid")
+	enqueueTestDelivery(t, repo)
+	claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+
+	delivery := claimed[0]
+	delivery.Attempts = 1
+	delivery.Status = webhook.DeliveryDead
+	delivery.LastError = "unexpected response status 500"
+	delivery.LastResponseStatus = 500
+	require.NoError(t, repo.SaveAttempt(ctx, &delivery, &webhook.Attempt{
+		DeliveryID:     delivery.ID,
+		Attempt:        1,
+		ResponseStatus: 500,
+		Error:          delivery.LastError,
+		DurationMs:     12,
+		AttemptedAt:    time.Now(),
+	}))
+
+	stored, err := repo.GetDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryDead, stored.Status)
+	assert.Equal(t, 500, stored.LastResponseStatus)
+
+	attempts, err := repo.ListAttempts(ctx, delivery.ID)
+	require.NoError(t, err)
+	require.Len(t, attempts, 1)
+	assert.Equal(t, 500, attempts[0].ResponseStatus)
+
+	requeued, err := repo.Redeliver(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryPending, requeued.Status)
+	assert.Zero(t, requeued.Attempts)
+
+	_, err = repo.Redeliver(ctx, uuid.Must(uuid.NewV4()))
+	require.ErrorIs(t, err, webhook.ErrDeliveryNotFound)
+}
+
+func TestRepository_SaveAttempt_RejectsLostLease(t *testing.T) {
+	t.Cleanup(func() { truncateTables(t, testDB) })
+	ctx := context.Background()
+	repo := webhook.NewRepository(testDB)
+
+	createTestSubscription(t, repo, true, "order.paid")
+	enqueueTestDelivery(t, repo)
+	claimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	require.NotEqual(t, uuid.Nil, claimed[0].LeaseID)
+
+	// Пока отправка шла, доставку поставили в очередь заново
+	_, err = repo.Redeliver(ctx, claimed[0].ID)
+	require.NoError(t, err)
+
+	delivery := claimed[0]
+	delivery.Attempts = 1
+	delivery.Status = webhook.DeliverySucceeded
+	err = repo.SaveAttempt(ctx, &delivery, &webhook.Attempt{
+		DeliveryID:  delivery.ID,
+		Attempt:     1,
+		DurationMs:  5,
+		AttemptedAt: time.Now(),
+	})
+	require.ErrorIs(t, err, webhook.ErrLeaseLost)
+
+	stored, err := repo.GetDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Equal(t, webhook.DeliveryPending, stored.Status)
+	attempts, err := repo.ListAttempts(ctx, delivery.ID)
+	require.NoError(t, err)
+	assert.Empty(t, attempts, "attempt of a lost lease must not be logged")
+
+	// Новое закрепление выдаёт другой LeaseID, старый больше не подходит
+	reclaimed, err := repo.ClaimDueDeliveries(ctx, 10, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, reclaimed, 1)
+	assert.NotEqual(t, claimed[0].LeaseID, reclaimed[0].LeaseID)
+	err = repo.SaveAttempt(ctx, &delivery, &webhook.Attempt{DeliveryID: delivery.ID, Attempt: 1, AttemptedAt: time.Now()})
+	require.ErrorIs(t, err, webhook.ErrLeaseLost)
+}
@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrInvalidSubscription = errors.New("invalid webhook subscription")
+	ErrUnknownEventType    = errors.New("unknown webhook event type")
+)
+
+// MinSecretLength - минимальная длина секрета подписи.
+const MinSecretLength = 16
+
+// defaultDeliveriesLimit - сколько последних доставок подписки отдаётся в журнале.
+const defaultDeliveriesLimit = 100
+
+// DeliveryDetails - доставка вместе с журналом её попыток.
+type DeliveryDetails struct {
+	Delivery
+	AttemptLog []Attempt `json:"attempt_log"`
+}
+
+type Service interface {
+	CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error)
+	GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]Subscription, error)
+	DeleteSubscription(ctx context.Context, id uuid.UUID) error
+	ListDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]Delivery, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*DeliveryDetails, error)
+	// Redeliver ставит доставку в очередь заново, в том числе из DEAD и SUCCEEDED.
+	Redeliver(ctx context.Context, id uuid.UUID) (*Delivery, error)
+}
+
+type service struct {
+	repo     Repository
+	resolver Resolver
+}
+
+// NewService создаёт сервис подписок. resolver проверяет, куда ведёт адрес получателя; nil - системный DNS.
+func NewService(repo Repository, resolver Resolver) Service {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &service{repo: repo, resolver: resolver}
+}
+
+func (s *service) CreateSubscription(ctx context.Context, sub *Subscription) (*Subscription, error) {
+	sub.URL = strings.TrimSpace(sub.URL)
+	target, err := url.Parse(sub.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidSubscription)
+	}
+	if err := checkHost(ctx, s.resolver, target.Hostname()); err != nil {
+		return nil, fmt.Errorf("%w: url must point to a public address: %w", ErrInvalidSubscription, err)
+	}
+	if len(sub.Secret) < MinSecretLength {
+		return nil, fmt.Errorf("%w: secret must be at least %d characters long", ErrInvalidSubscription, MinSecretLength)
+	}
+	if len(sub.EventTypes) == 0 {
+		return nil, fmt.Errorf("%w: at least one event type is required", ErrInvalidSubscription)
+	}
+
+	known := KnownEventTypes()
+	eventTypes := make([]string, 0, len(sub.EventTypes))
+	for _, eventType := range sub.EventTypes {
+		if !slices.Contains(known, eventType) {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownEventType, eventType)
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	sub.EventTypes = eventTypes
+	sub.Active = true
+
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		log.Error().Err(err).Str("url", sub.URL).Msg("service: failed to create webhook subscription")
+		return nil, fmt.Errorf("service: failed to create webhook subscription: %w", err)
+	}
+
+	log.Info().Stringer("subscription_id", sub.ID).Str("url", sub.URL).Strs("event_types", sub.EventTypes).Msg("service: webhook subscription created")
+	return sub, nil
+}
+
+func (s *service) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	sub, err := s.repo.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service: failed to get webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *service) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (s *service) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.DeleteSubscription(ctx, id); err != nil {
+		if errors.Is(err, ErrSubscriptionNotFound) {
+			return err
+		}
+		return fmt.Errorf("service: failed to delete webhook subscription: %w", err)
+	}
+	log.Info().Stringer("subscription_id", id).Msg("service: webhook subscription deleted")
+	return nil
+}
+
+func (s *service) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID) ([]Delivery, error) {
+	// Отличаем несуществующую подписку от подписки без доставок
+	if _, err := s.GetSubscription(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, subscriptionID, defaultDeliveriesLimit)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *service) GetDelivery(ctx context.Context, id uuid.UUID) (*DeliveryDetails, error) {
+	delivery, err := s.repo.GetDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service: failed to get webhook delivery: %w", err)
+	}
+
+	attempts, err := s.repo.ListAttempts(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to list webhook delivery attempts: %w", err)
+	}
+	return &DeliveryDetails{Delivery: *delivery, AttemptLog: attempts}, nil
+}
+
+func (s *service) Redeliver(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	delivery, err := s.repo.Redeliver(ctx, id)
+	if err != nil {
+		if errors.Is(err, ErrDeliveryNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("service: failed to redeliver webhook: %w", err)
+	}
+	log.Info().Stringer("delivery_id", id).Msg("service: webhook delivery requeued manually")
+	return delivery, nil
+}
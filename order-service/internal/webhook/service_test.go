@@ -0,0 +1,273 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateSubscription(ctx context.Context, sub *Subscription) error {
+	args := m.Called(ctx, sub)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetSubscription(ctx context.Context, id uuid.UUID) (*Subscription, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Subscription), args.Error(1)
+}
+
+func (m *MockRepository) ListSubscriptions(ctx context.Context) ([]Subscription, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Subscription), args.Error(1)
+}
+
+func (m *MockRepository) DeleteSubscription(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) EnqueueStatusChanges(ctx context.Context, limit int, build BuildEvent) (int, int, error) {
+	args := m.Called(ctx, limit, build)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *MockRepository) ClaimDueDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	args := m.Called(ctx, limit, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *MockRepository) SaveAttempt(ctx context.Context, delivery *Delivery, attempt *Attempt) error {
+	args := m.Called(ctx, delivery, attempt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetDelivery(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Delivery), args.Error(1)
+}
+
+func (m *MockRepository) ListDeliveries(ctx context.Context, subscriptionID uuid.UUID, limit int) ([]Delivery, error) {
+	args := m.Called(ctx, subscriptionID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Delivery), args.Error(1)
+}
+
+func (m *MockRepository) ListAttempts(ctx context.Context, deliveryID uuid.UUID) ([]Attempt, error) {
+	args := m.Called(ctx, deliveryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Attempt), args.Error(1)
+}
+
+func (m *MockRepository) Redeliver(ctx context.Context, id uuid.UUID) (*Delivery, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Delivery), args.Error(1)
+}
+
+type MockResolver struct {
+	mock.Mock
+}
+
+func (m *MockResolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	args := m.Called(ctx, network, host)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]netip.Addr), args.Error(1)
+}
+
+// newPublicResolver отвечает публичным адресом на любой хост.
+func newPublicResolver() *MockResolver {
+	resolver := new(MockResolver)
+	resolver.On("LookupNetIP", mock.Anything, "ip", mock.Anything).Return([]netip.Addr{netip.MustParseAddr("93.184.215.14")}, nil)
+	return resolver
+}
+
+func TestService_CreateSubscription_Success(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, newPublicResolver())
+
+	mockRepo.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(sub *Subscription) bool {
+		return sub.Active && len(sub.EventTypes) == 2
+	})).Return(nil).Once()
+
+	created, err := svc.CreateSubscription(context.Background(), &Subscription{
+		URL:        " https://partner.example.com/hooks ",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"order.paid", "order.shipped", "order.paid"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://partner.example.com/hooks", created.URL)
+	assert.Equal(t, []string{"order.paid", "order.shipped"}, created.EventTypes)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_CreateSubscription_Validation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		sub         Subscription
+		expectedErr error
+	}{
+		{
+			name:        "relative url",
+			sub:         Subscription{URL: "/hooks", Secret: "0123456789abcdef", EventTypes: []string{"order.paid"}},
+			expectedErr: ErrInvalidSubscription,
+		},
+		{
+			name:        "unsupported scheme",
+			sub:         Subscription{URL: "ftp://partner.example.com", Secret: "0123456789abcdef", EventTypes: []string{"order.paid"}},
+			expectedErr: ErrInvalidSubscription,
+		},
+		{
+			name:        "short secret",
+			sub:         Subscription{URL: "https://partner.example.com", Secret: "short", EventTypes: []string{"order.paid"}},
+			expectedErr: ErrInvalidSubscription,
+		},
+		{
+			name:        "no event types",
+			sub:         Subscription{URL: "https://partner.example.com", Secret: "0123456789abcdef"},
+			expectedErr: ErrInvalidSubscription,
+		},
+		{
+			name:        "unknown event type",
+			sub:         Subscription{URL: "https://partner.example.com", Secret: "0123456789abcdef", EventTypes: []string{"order.new"}},
+			expectedErr: ErrUnknownEventType,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			svc := NewService(mockRepo, newPublicResolver())
+
+			_, err := svc.CreateSubscription(context.Background(), &tc.sub)
+			require.ErrorIs(t, err, tc.expectedErr)
+			mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateSubscription_RejectsInternalTargets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		url      string
+		resolved string
+	}{
+		{name: "loopback literal", url: "http://127.0.0.1:8080/hooks"},
+		{name: "ipv6 loopback literal", url: "http://[::1]/hooks"},
+		{name: "metadata endpoint", url: "http://169.254.169.254/latest/meta-data"},
+		{name: "private literal", url: "https://10.0.0.5/hooks"},
+		{name: "unspecified literal", url: "http://0.0.0.0/hooks"},
+		{name: "host resolves to private", url: "https://partner.example.com/hooks", resolved: "192.168.1.10"},
+		{name: "host resolves to mapped loopback", url: "https://partner.example.com/hooks", resolved: "::ffff:127.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			resolver := new(MockResolver)
+			if tc.resolved != "" {
+				resolver.On("LookupNetIP", mock.Anything, "ip", "partner.example.com").
+					Return([]netip.Addr{netip.MustParseAddr("93.184.215.14"), netip.MustParseAddr(tc.resolved)}, nil).Once()
+			}
+			svc := NewService(mockRepo, resolver)
+
+			_, err := svc.CreateSubscription(context.Background(), &Subscription{
+				URL:        tc.url,
+				Secret:     "0123456789abcdef",
+				EventTypes: []string{"order.paid"},
+			})
+			require.ErrorIs(t, err, ErrInvalidSubscription)
+			require.ErrorIs(t, err, ErrForbiddenTarget)
+			mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+			resolver.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_CreateSubscription_UnresolvableHost(t *testing.T) {
+	mockRepo := new(MockRepository)
+	resolver := new(MockResolver)
+	resolver.On("LookupNetIP", mock.Anything, "ip", "missing.example.com").Return(nil, errors.New("no such host")).Once()
+	svc := NewService(mockRepo, resolver)
+
+	_, err := svc.CreateSubscription(context.Background(), &Subscription{
+		URL:        "https://missing.example.com/hooks",
+		Secret:     "0123456789abcdef",
+		EventTypes: []string{"order.paid"},
+	})
+	require.ErrorIs(t, err, ErrInvalidSubscription)
+	mockRepo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+}
+
+func TestService_ListDeliveries_SubscriptionNotFound(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil)
+	subID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("GetSubscription", mock.Anything, subID).Return(nil, ErrSubscriptionNotFound).Once()
+
+	_, err := svc.ListDeliveries(context.Background(), subID)
+	require.ErrorIs(t, err, ErrSubscriptionNotFound)
+	mockRepo.AssertNotCalled(t, "ListDeliveries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_GetDelivery_IncludesAttemptLog(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil)
+	deliveryID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("GetDelivery", mock.Anything, deliveryID).Return(&Delivery{ID: deliveryID, Status: DeliveryDead, Attempts: 2}, nil).Once()
+	mockRepo.On("ListAttempts", mock.Anything, deliveryID).Return([]Attempt{
+		{DeliveryID: deliveryID, Attempt: 1, ResponseStatus: 500},
+		{DeliveryID: deliveryID, Attempt: 2, Error: "connection refused"},
+	}, nil).Once()
+
+	details, err := svc.GetDelivery(context.Background(), deliveryID)
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryDead, details.Status)
+	assert.Len(t, details.AttemptLog, 2)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Redeliver_WrapsRepositoryError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, nil)
+	deliveryID := uuid.Must(uuid.NewV4())
+	dbErr := errors.New("connection reset")
+
+	mockRepo.On("Redeliver", mock.Anything, deliveryID).Return(nil, dbErr).Once()
+
+	_, err := svc.Redeliver(context.Background(), deliveryID)
+	require.ErrorIs(t, err, dbErr)
+	assert.Contains(t, err.Error(), "service:")
+}
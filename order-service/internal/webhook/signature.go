@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// Заголовки запроса доставки
+const (
+	HeaderDeliveryID = "X-Webhook-Id"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// signaturePrefix указывает алгоритм подписи в заголовке X-Webhook-Signature.
+const signaturePrefix = "sha256="
+
+// Sign возвращает значение заголовка X-Webhook-Signature: HMAC-SHA256 от "<timestamp>.<body>" в hex.
+// Метка времени входит в подпись, чтобы получатель мог отвергать старые перехваченные запросы.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись доставки. Нужна получателям и тестам.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign_Verify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("0123456789abcdef", 1700000000, body)
+
+	assert.Regexp(t, `^sha256=[0-9a-f]{64}$`, signature)
+	assert.True(t, Verify("0123456789abcdef", 1700000000, body, signature))
+	assert.False(t, Verify("another-secret-0", 1700000000, body, signature), "other secret")
+	assert.False(t, Verify("0123456789abcdef", 1700000001, body, signature), "timestamp is signed")
+	assert.False(t, Verify("0123456789abcdef", 1700000000, []byte(`{"id":"2"}`), signature), "body is signed")
+}
@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget - адрес получателя находится во внутренней сети.
+var ErrForbiddenTarget = errors.New("webhook target address is not allowed")
+
+// sharedAddressSpace - адреса операторского NAT (RFC 6598), снаружи они недоступны так же, как частные.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Resolver находит адреса хоста. Подходит *net.Resolver.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// isForbiddenAddr сообщает, ведёт ли адрес на сам сервер, в частную, link-local или служебную сеть.
+func isForbiddenAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsMulticast() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// checkHost разрешает хост и отклоняет его, если хотя бы один адрес ведёт во внутреннюю сеть.
+func checkHost(ctx context.Context, resolver Resolver, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if isForbiddenAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrForbiddenTarget, addr)
+		}
+		return nil
+	}
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %q: %w", host, err)
+	}
+	for _, addr := range addrs {
+		if isForbiddenAddr(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrForbiddenTarget, host, addr)
+		}
+	}
+	return nil
+}
+
+// NewTargetClient возвращает HTTP клиент для отправки доставок. Адрес проверяется в момент соединения,
+// поэтому ни смена DNS записи после регистрации, ни редирект не уводят запрос во внутреннюю сеть.
+func NewTargetClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isForbiddenAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenTarget, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// Через прокси проверялся бы адрес прокси, а не получателя
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
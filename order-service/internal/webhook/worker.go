@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxResponseBody - сколько байт ответа получателя читаем, прежде чем закрыть соединение.
+const maxResponseBody = 64 << 10
+
+type WorkerConfig struct {
+	Workers      int           // Число одновременных отправок
+	BatchSize    int           // Сколько доставок забирается из базы за раз
+	PollInterval time.Duration // Как часто проверять очередь
+	Timeout      time.Duration // Таймаут одного запроса к получателю
+	MaxAttempts  int           // После стольких неудачных попыток доставка переходит в DEAD
+	BackoffBase  time.Duration // Пауза после первой неудачи, дальше удваивается
+	BackoffMax   time.Duration
+}
+
+// WorkerPool отправляет доставки из базы получателям. Несколько реплик могут работать одновременно:
+// каждая доставка на время отправки закрепляется за одним воркером.
+type WorkerPool struct {
+	repo   Repository
+	client *http.Client
+	cfg    WorkerConfig
+}
+
+// NewWorkerPool создаёт пул воркеров. Без client запросы идут через NewTargetClient.
+func NewWorkerPool(repo Repository, client *http.Client, cfg WorkerConfig) *WorkerPool {
+	if client == nil {
+		client = NewTargetClient(cfg.Timeout)
+	}
+	return &WorkerPool{repo: repo, client: client, cfg: cfg}
+}
+
+// Run запускает воркеры и блокируется до отмены ctx. Прерванные остановкой отправки не засчитываются как попытки.
+func (p *WorkerPool) Run(ctx context.Context) {
+	log.Info().Int("workers", p.cfg.Workers).Int("max_attempts", p.cfg.MaxAttempts).Msg("webhook: worker pool started")
+
+	jobs := make(chan Delivery)
+	var wg sync.WaitGroup
+	for range p.cfg.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range jobs {
+				p.deliver(ctx, delivery)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+		log.Info().Msg("webhook: worker pool stopped")
+	}()
+
+	ticker := time.NewTicker(p.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deliveries, err := p.repo.ClaimDueDeliveries(ctx, p.cfg.BatchSize, p.lease())
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				webhookMetrics.Add("errors", 1)
+				log.Error().Err(err).Msg("webhook: failed to claim deliveries")
+			}
+			continue
+		}
+
+		for _, delivery := range deliveries {
+			select {
+			case jobs <- delivery:
+			case <-ctx.Done():
+				// Незапущенные доставки снова станут доступны, когда истечёт закрепление
+				return
+			}
+		}
+	}
+}
+
+// lease возвращает срок закрепления пачки. Доставки пачки ждут свободного воркера, поэтому последняя
+// начнётся не позже, чем через ceil(BatchSize/Workers) отправок, и должна успеть завершиться до конца закрепления.
+func (p *WorkerPool) lease() time.Duration {
+	rounds := (p.cfg.BatchSize + p.cfg.Workers - 1) / p.cfg.Workers
+	return time.Duration(rounds+1)*p.cfg.Timeout + p.cfg.PollInterval
+}
+
+// deliver выполняет одну попытку и сохраняет её результат.
+func (p *WorkerPool) deliver(ctx context.Context, delivery Delivery) {
+	start := time.Now()
+	if !delivery.LeaseExpiresAt.IsZero() && delivery.LeaseExpiresAt.Sub(start) < p.cfg.Timeout {
+		// Запрос может не уложиться в закрепление, и доставку параллельно отправит другой воркер
+		webhookMetrics.Add("leases_expired", 1)
+		log.Warn().Stringer("delivery_id", delivery.ID).Msg("webhook: delivery lease is about to expire, skipping attempt")
+		return
+	}
+	responseStatus, err := p.send(ctx, delivery)
+	if ctx.Err() != nil {
+		// Сервис останавливается - попытку не засчитываем, доставка вернётся в очередь по истечении закрепления
+		return
+	}
+
+	delivery.Attempts++
+	attempt := &Attempt{
+		DeliveryID:     delivery.ID,
+		Attempt:        delivery.Attempts,
+		ResponseStatus: responseStatus,
+		DurationMs:     time.Since(start).Milliseconds(),
+		AttemptedAt:    start,
+	}
+	delivery.LastResponseStatus = responseStatus
+
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		webhookMetrics.Add("deliveries_succeeded", 1)
+	case delivery.Attempts >= p.cfg.MaxAttempts:
+		attempt.Error = err.Error()
+		delivery.Status = DeliveryDead
+		delivery.LastError = err.Error()
+		webhookMetrics.Add("deliveries_dead", 1)
+		log.Warn().Err(err).Stringer("delivery_id", delivery.ID).Int("attempts", delivery.Attempts).Msg("webhook: delivery moved to dead letter")
+	default:
+		attempt.Error = err.Error()
+		delivery.Status = DeliveryPending
+		delivery.NextAttemptAt = time.Now().Add(p.backoff(delivery.Attempts))
+		delivery.LastError = err.Error()
+		webhookMetrics.Add("deliveries_failed", 1)
+	}
+
+	if err := p.repo.SaveAttempt(ctx, &delivery, attempt); err != nil {
+		if errors.Is(err, ErrLeaseLost) {
+			webhookMetrics.Add("leases_lost", 1)
+			log.Warn().Stringer("delivery_id", delivery.ID).Msg("webhook: delivery was requeued or claimed by another worker, attempt discarded")
+			return
+		}
+		webhookMetrics.Add("errors", 1)
+		log.Error().Err(err).Stringer("delivery_id", delivery.ID).Msg("webhook: failed to save delivery attempt")
+	}
+}
+
+// send отправляет доставку и возвращает код ответа (0, если ответа не было). Успех - любой 2xx.
+func (p *WorkerPool) send(ctx context.Context, delivery Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "order-service-webhooks/1")
+	req.Header.Set(HeaderDeliveryID, delivery.ID.String())
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Дочитываем тело, чтобы соединение вернулось в пул
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает паузу перед следующей попыткой: BackoffBase * 2^(attempts-1), но не больше BackoffMax.
+func (p *WorkerPool) backoff(attempts int) time.Duration {
+	delay := p.cfg.BackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.cfg.BackoffMax || delay <= 0 {
+			return p.cfg.BackoffMax
+		}
+	}
+	return min(delay, p.cfg.BackoffMax)
+}
@@ -0,0 +1,268 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef"
+
+func testWorkerConfig() WorkerConfig {
+	return WorkerConfig{
+		Workers:      2,
+		BatchSize:    10,
+		PollInterval: 10 * time.Millisecond,
+		Timeout:      time.Second,
+		MaxAttempts:  3,
+		BackoffBase:  time.Minute,
+		BackoffMax:   10 * time.Minute,
+	}
+}
+
+func newTestDelivery(url string, attempts int) Delivery {
+	return Delivery{
+		ID:        uuid.Must(uuid.NewV4()),
+		EventID:   uuid.Must(uuid.NewV4()),
+		EventType: "order.paid",
+		Payload:   []byte(`{"type":"order.paid"}`),
+		Status:    DeliveryPending,
+		Attempts:  attempts,
+		URL:       url,
+		Secret:    testSecret,
+	}
+}
+
+func TestWorkerPool_deliver_SignedRequestSucceeds(t *testing.T) {
+	var received atomic.Bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if err != nil || !Verify(testSecret, timestamp, body, r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "order.paid", r.Header.Get(HeaderEventType))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		received.Store(true)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, receiver.Client(), testWorkerConfig())
+	delivery := newTestDelivery(receiver.URL, 0)
+
+	mockRepo.On("SaveAttempt", mock.Anything, mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliverySucceeded && d.Attempts == 1 && d.DeliveredAt != nil && d.LastResponseStatus == http.StatusNoContent
+	}), mock.MatchedBy(func(a *Attempt) bool {
+		return a.Attempt == 1 && a.ResponseStatus == http.StatusNoContent && a.Error == ""
+	})).Return(nil).Once()
+
+	pool.deliver(context.Background(), delivery)
+
+	assert.True(t, received.Load(), "receiver rejected the signature")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_deliver_FailureSchedulesRetryWithBackoff(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, receiver.Client(), testWorkerConfig())
+	delivery := newTestDelivery(receiver.URL, 1) // Вторая попытка - пауза удваивается
+
+	var saved *Delivery
+	mockRepo.On("SaveAttempt", mock.Anything, mock.Anything, mock.MatchedBy(func(a *Attempt) bool {
+		return a.Attempt == 2 && a.ResponseStatus == http.StatusInternalServerError && a.Error != ""
+	})).Run(func(args mock.Arguments) { saved = args.Get(1).(*Delivery) }).Return(nil).Once()
+
+	before := time.Now()
+	pool.deliver(context.Background(), delivery)
+
+	require.NotNil(t, saved)
+	assert.Equal(t, DeliveryPending, saved.Status)
+	assert.Equal(t, 2, saved.Attempts)
+	assert.WithinDuration(t, before.Add(2*time.Minute), saved.NextAttemptAt, 5*time.Second)
+	assert.Contains(t, saved.LastError, "500")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_deliver_DeadAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, receiver.Client(), testWorkerConfig())
+	delivery := newTestDelivery(receiver.URL, 2) // Третья попытка из трёх
+
+	mockRepo.On("SaveAttempt", mock.Anything, mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryDead && d.Attempts == 3 && d.DeliveredAt == nil
+	}), mock.Anything).Return(nil).Once()
+
+	pool.deliver(context.Background(), delivery)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_deliver_ReceiverUnavailable(t *testing.T) {
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	url := receiver.URL
+	receiver.Close() // Соединение будет отклонено
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, &http.Client{Timeout: time.Second}, testWorkerConfig())
+
+	mockRepo.On("SaveAttempt", mock.Anything, mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryPending && d.LastResponseStatus == 0
+	}), mock.MatchedBy(func(a *Attempt) bool {
+		return a.ResponseStatus == 0 && a.Error != ""
+	})).Return(nil).Once()
+
+	pool.deliver(context.Background(), newTestDelivery(url, 0))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_deliver_DefaultClientRefusesInternalTarget(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, nil, testWorkerConfig())
+
+	mockRepo.On("SaveAttempt", mock.Anything, mock.MatchedBy(func(d *Delivery) bool {
+		return d.Status == DeliveryPending
+	}), mock.MatchedBy(func(a *Attempt) bool {
+		return a.ResponseStatus == 0 && strings.Contains(a.Error, ErrForbiddenTarget.Error())
+	})).Return(nil).Once()
+
+	pool.deliver(context.Background(), newTestDelivery(receiver.URL, 0))
+	assert.Zero(t, hits.Load())
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_backoff(t *testing.T) {
+	pool := NewWorkerPool(new(MockRepository), nil, testWorkerConfig())
+
+	assert.Equal(t, time.Minute, pool.backoff(1))
+	assert.Equal(t, 2*time.Minute, pool.backoff(2))
+	assert.Equal(t, 8*time.Minute, pool.backoff(4))
+	assert.Equal(t, 10*time.Minute, pool.backoff(5))
+	assert.Equal(t, 10*time.Minute, pool.backoff(100))
+}
+
+// metricValue возвращает текущее значение счётчика webhookMetrics.
+func metricValue(name string) string {
+	if v := webhookMetrics.Get(name); v != nil {
+		return v.String()
+	}
+	return "0"
+}
+
+func TestWorkerPool_lease_CoversWholeBatch(t *testing.T) {
+	cfg := testWorkerConfig()
+	cfg.Workers = 4
+	cfg.BatchSize = 16
+	cfg.Timeout = 10 * time.Second
+	cfg.PollInterval = 2 * time.Second
+	pool := NewWorkerPool(new(MockRepository), nil, cfg)
+
+	// Последняя доставка ждёт 3 отправки перед собой и сама длится не дольше Timeout
+	assert.Equal(t, 52*time.Second, pool.lease())
+}
+
+func TestWorkerPool_deliver_SkipsExpiringLease(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	cfg := testWorkerConfig()
+	pool := NewWorkerPool(mockRepo, receiver.Client(), cfg)
+
+	delivery := newTestDelivery(receiver.URL, 0)
+	delivery.LeaseExpiresAt = time.Now().Add(cfg.Timeout / 2)
+	pool.deliver(context.Background(), delivery)
+
+	assert.Zero(t, hits.Load())
+	mockRepo.AssertNotCalled(t, "SaveAttempt", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWorkerPool_deliver_LeaseLostIsNotAnError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	pool := NewWorkerPool(mockRepo, receiver.Client(), testWorkerConfig())
+	mockRepo.On("SaveAttempt", mock.Anything, mock.Anything, mock.Anything).Return(ErrLeaseLost).Once()
+
+	errorsBefore := metricValue("errors")
+	pool.deliver(context.Background(), newTestDelivery(receiver.URL, 0))
+
+	assert.Equal(t, errorsBefore, metricValue("errors"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestWorkerPool_Run_DeliversClaimedDeliveries(t *testing.T) {
+	var hits atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	mockRepo := new(MockRepository)
+	cfg := testWorkerConfig()
+	pool := NewWorkerPool(mockRepo, receiver.Client(), cfg)
+
+	deliveries := []Delivery{newTestDelivery(receiver.URL, 0), newTestDelivery(receiver.URL, 0)}
+	saved := make(chan struct{}, len(deliveries))
+	mockRepo.On("ClaimDueDeliveries", mock.Anything, cfg.BatchSize, mock.Anything).Return(deliveries, nil).Once()
+	mockRepo.On("ClaimDueDeliveries", mock.Anything, cfg.BatchSize, mock.Anything).Return([]Delivery{}, nil)
+	mockRepo.On("SaveAttempt", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) { saved <- struct{}{} }).Return(nil).Times(len(deliveries))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(stopped)
+	}()
+
+	for range deliveries {
+		select {
+		case <-saved:
+		case <-time.After(2 * time.Second):
+			t.Fatal("delivery was not attempted")
+		}
+	}
+	cancel()
+	<-stopped
+
+	assert.Equal(t, int32(2), hits.Load())
+	mockRepo.AssertExpectations(t)
+}
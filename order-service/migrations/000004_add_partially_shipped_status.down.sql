@@ -0,0 +1,12 @@
+ALTER TABLE order_service.orders DROP CONSTRAINT IF EXISTS orders_status_check;
+
+ALTER TABLE order_service.orders ADD CONSTRAINT orders_status_check CHECK (
+    status IN (
+        'NEW',
+        'PROCESSING',
+        'PAID',
+        'SHIPPED',
+        'DELIVERED',
+        'CANCELLED'
+    )
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_service.shipment_events;
+
+DROP TABLE IF EXISTS order_service.shipment_items;
+
+DROP TABLE IF EXISTS order_service.shipments;
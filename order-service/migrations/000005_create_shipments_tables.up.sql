@@ -0,0 +1,42 @@
+CREATE TABLE order_service.shipments (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES order_service.orders (id) ON DELETE CASCADE,
+    carrier VARCHAR(50) NOT NULL,
+    tracking_number VARCHAR(100) NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (
+        status IN (
+            'IN_TRANSIT',
+            'DELIVERED'
+        )
+    ),
+    shipped_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (carrier, tracking_number)
+);
+
+CREATE INDEX shipments_order_id_idx ON order_service.shipments (order_id);
+
+CREATE TABLE order_service.shipment_items (
+    id UUID PRIMARY KEY,
+    shipment_id UUID NOT NULL REFERENCES order_service.shipments (id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_service.order_items (id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX shipment_items_shipment_id_idx ON order_service.shipment_items (shipment_id);
+
+CREATE INDEX shipment_items_order_item_id_idx ON order_service.shipment_items (order_item_id);
+
+CREATE TABLE order_service.shipment_events (
+    id UUID PRIMARY KEY,
+    shipment_id UUID NOT NULL REFERENCES order_service.shipments (id) ON DELETE CASCADE,
+    event_type VARCHAR(50) NOT NULL,
+    location TEXT,
+    description TEXT,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX shipment_events_shipment_id_idx ON order_service.shipment_events (shipment_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_service.order_shipping_lines;
+
+ALTER TABLE order_service.order_items DROP COLUMN IF EXISTS weight_grams;
@@ -0,0 +1,14 @@
+ALTER TABLE order_service.order_items
+    ADD COLUMN weight_grams INTEGER NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+
+CREATE TABLE order_service.order_shipping_lines (
+    order_id UUID PRIMARY KEY REFERENCES order_service.orders (id) ON DELETE CASCADE,
+    option_id VARCHAR(100) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    carrier VARCHAR(50) NOT NULL,
+    service_name VARCHAR(100) NOT NULL,
+    price DECIMAL(10, 2) NOT NULL CHECK (price >= 0),
+    estimated_days INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
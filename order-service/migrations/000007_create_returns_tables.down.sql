@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_service.return_items;
+
+DROP TABLE IF EXISTS order_service.returns;
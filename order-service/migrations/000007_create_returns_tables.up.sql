@@ -0,0 +1,36 @@
+CREATE TABLE order_service.returns (
+    id UUID PRIMARY KEY,
+    order_id UUID NOT NULL REFERENCES order_service.orders (id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    status VARCHAR(50) NOT NULL CHECK (
+        status IN (
+            'REQUESTED',
+            'APPROVED',
+            'REJECTED',
+            'RECEIVED',
+            'REFUNDED'
+        )
+    ),
+    reason TEXT NOT NULL,
+    resolution_note TEXT NOT NULL DEFAULT '',
+    refund_amount DECIMAL(10, 2) NOT NULL DEFAULT 0.00,
+    requested_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    received_at TIMESTAMP WITH TIME ZONE,
+    refunded_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX returns_order_id_idx ON order_service.returns (order_id);
+
+CREATE INDEX returns_status_idx ON order_service.returns (status);
+
+CREATE TABLE order_service.return_items (
+    id UUID PRIMARY KEY,
+    return_id UUID NOT NULL REFERENCES order_service.returns (id) ON DELETE CASCADE,
+    order_item_id UUID NOT NULL REFERENCES order_service.order_items (id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0)
+);
+
+CREATE INDEX return_items_return_id_idx ON order_service.return_items (return_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS order_service.orders_status_created_at_idx;
+
+ALTER TABLE order_service.orders
+DROP COLUMN IF EXISTS cancellation_reason;
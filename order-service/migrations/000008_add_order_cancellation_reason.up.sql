@@ -0,0 +1,5 @@
+ALTER TABLE order_service.orders
+ADD COLUMN cancellation_reason VARCHAR(64);
+
+-- Индекс для поиска зависших неоплаченных заказов планировщиком
+CREATE INDEX orders_status_created_at_idx ON order_service.orders (status, created_at);
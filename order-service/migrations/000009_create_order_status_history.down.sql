@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS orders_status_history_update ON order_service.orders;
+
+DROP TRIGGER IF EXISTS orders_status_history_insert ON order_service.orders;
+
+DROP FUNCTION IF EXISTS order_service.record_order_status_change();
+
+DROP TABLE IF EXISTS order_service.order_status_history;
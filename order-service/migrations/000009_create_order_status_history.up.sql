@@ -0,0 +1,40 @@
+CREATE TABLE order_service.order_status_history (
+    id BIGSERIAL PRIMARY KEY, -- Используется как id события SSE для возобновления по Last-Event-ID
+    order_id UUID NOT NULL REFERENCES order_service.orders (id) ON DELETE CASCADE,
+    from_status VARCHAR(50), -- NULL для создания заказа
+    to_status VARCHAR(50) NOT NULL,
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX order_status_history_order_id_idx ON order_service.order_status_history (order_id, id);
+
+-- Каждая смена статуса записывается в историю и рассылается через NOTIFY в той же транзакции,
+-- поэтому событие видят все реплики и только после коммита.
+CREATE FUNCTION order_service.record_order_status_change() RETURNS TRIGGER AS $$
+DECLARE
+    entry order_service.order_status_history%ROWTYPE;
+BEGIN
+    INSERT INTO order_service.order_status_history (order_id, from_status, to_status)
+    VALUES (NEW.id, CASE WHEN TG_OP = 'UPDATE' THEN OLD.status END, NEW.status)
+    RETURNING * INTO entry;
+
+    PERFORM pg_notify('order_status_changed', json_build_object(
+        'id', entry.id,
+        'order_id', entry.order_id,
+        'from', COALESCE(entry.from_status, ''),
+        'to', entry.to_status,
+        'changed_at', entry.changed_at
+    )::text);
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_status_history_insert
+AFTER INSERT ON order_service.orders
+FOR EACH ROW EXECUTE FUNCTION order_service.record_order_status_change();
+
+CREATE TRIGGER orders_status_history_update
+AFTER UPDATE OF status ON order_service.orders
+FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION order_service.record_order_status_change();
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_service.webhook_delivery_attempts;
+
+DROP TABLE IF EXISTS order_service.webhook_deliveries;
+
+DROP TABLE IF EXISTS order_service.webhook_subscriptions;
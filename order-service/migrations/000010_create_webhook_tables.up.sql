@@ -0,0 +1,43 @@
+CREATE TABLE order_service.webhook_subscriptions (
+    id UUID PRIMARY KEY,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL, -- Ключ HMAC подписи доставок
+    event_types TEXT[] NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE order_service.webhook_deliveries (
+    id UUID PRIMARY KEY,
+    subscription_id UUID NOT NULL REFERENCES order_service.webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id UUID NOT NULL, -- Общий для всех доставок одного события
+    event_type VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'SUCCEEDED', 'DEAD')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    last_response_status INT NOT NULL DEFAULT 0,
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_deliveries_subscription_id_idx ON order_service.webhook_deliveries (subscription_id, created_at);
+
+-- Воркеры выбирают ожидающие доставки, у которых подошло время следующей попытки
+CREATE INDEX webhook_deliveries_due_idx ON order_service.webhook_deliveries (next_attempt_at) WHERE status = 'PENDING';
+
+-- Журнал всех попыток доставки
+CREATE TABLE order_service.webhook_delivery_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    delivery_id UUID NOT NULL REFERENCES order_service.webhook_deliveries (id) ON DELETE CASCADE,
+    attempt INT NOT NULL,
+    response_status INT NOT NULL DEFAULT 0, -- 0, если ответа не было
+    error TEXT NOT NULL DEFAULT '',
+    duration_ms BIGINT NOT NULL,
+    attempted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX webhook_delivery_attempts_delivery_id_idx ON order_service.webhook_delivery_attempts (delivery_id, id);
@@ -0,0 +1 @@
+ALTER TABLE order_service.orders DROP COLUMN IF EXISTS anonymized_at;
@@ -0,0 +1,2 @@
+-- Момент удаления персональных данных покупателя из заказа после удаления его аккаунта
+ALTER TABLE order_service.orders ADD COLUMN anonymized_at TIMESTAMP WITH TIME ZONE;
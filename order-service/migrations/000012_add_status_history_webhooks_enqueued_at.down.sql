@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS order_service.order_status_history_webhooks_pending_idx;
+
+ALTER TABLE order_service.order_status_history DROP COLUMN IF EXISTS webhooks_enqueued_at;
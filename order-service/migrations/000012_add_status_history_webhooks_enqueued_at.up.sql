@@ -0,0 +1,8 @@
+-- Доставки вебхуков создаются по истории статусов. Запись истории пишется в одной транзакции со сменой статуса,
+-- поэтому событие не теряется ни при падении, ни при остановке сервиса.
+ALTER TABLE order_service.order_status_history ADD COLUMN webhooks_enqueued_at TIMESTAMP WITH TIME ZONE;
+
+-- Смены статуса до этой миграции уже разосланы
+UPDATE order_service.order_status_history SET webhooks_enqueued_at = NOW();
+
+CREATE INDEX order_status_history_webhooks_pending_idx ON order_service.order_status_history (id) WHERE webhooks_enqueued_at IS NULL;
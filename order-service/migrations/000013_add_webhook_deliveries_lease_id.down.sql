@@ -0,0 +1 @@
+ALTER TABLE order_service.webhook_deliveries DROP COLUMN IF EXISTS lease_id;
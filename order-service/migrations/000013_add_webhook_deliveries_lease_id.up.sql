@@ -0,0 +1,2 @@
+-- Закрепление доставки за воркером: результат попытки сохраняется, только пока закрепление не перехвачено
+ALTER TABLE order_service.webhook_deliveries ADD COLUMN lease_id UUID;
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS orders_event_outbox_update ON order_service.orders;
+
+DROP TRIGGER IF EXISTS orders_event_outbox_insert ON order_service.orders;
+
+DROP FUNCTION IF EXISTS order_service.enqueue_order_event();
+
+DROP TABLE IF EXISTS order_service.event_outbox;
@@ -0,0 +1,47 @@
+-- Исходящие доменные события. Строку пишет триггер в той же транзакции, что и изменение заказа,
+-- а в брокер её отправляет events.Relay, поэтому событие не теряется, даже если брокер недоступен.
+CREATE TABLE order_service.event_outbox (
+    id BIGSERIAL PRIMARY KEY, -- Порядок отправки
+    event_id UUID NOT NULL DEFAULT gen_random_uuid(), -- id события; повторная отправка идёт с тем же id
+    event_type VARCHAR(64) NOT NULL,
+    subject TEXT NOT NULL,
+    data JSONB NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX event_outbox_pending_idx ON order_service.event_outbox (id) WHERE published_at IS NULL;
+
+-- Поля data совпадают с events.OrderCreatedData и events.OrderStatusChangedData
+CREATE FUNCTION order_service.enqueue_order_event() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO order_service.event_outbox (event_type, subject, data)
+        VALUES ('order.created', NEW.id::text, json_build_object(
+            'order_id', NEW.id,
+            'user_id', NEW.user_id,
+            'status', NEW.status,
+            'total_amount', NEW.total_amount
+        ));
+    ELSE
+        INSERT INTO order_service.event_outbox (event_type, subject, data)
+        VALUES ('order.status_changed', NEW.id::text, json_build_object(
+            'order_id', NEW.id,
+            'from', OLD.status,
+            'to', NEW.status,
+            'changed_at', NOW()
+        ));
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_event_outbox_insert
+AFTER INSERT ON order_service.orders
+FOR EACH ROW EXECUTE FUNCTION order_service.enqueue_order_event();
+
+CREATE TRIGGER orders_event_outbox_update
+AFTER UPDATE OF status ON order_service.orders
+FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION order_service.enqueue_order_event();
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS orders_anonymize_finished ON order_service.orders;
+
+DROP FUNCTION IF EXISTS order_service.anonymize_finished_order();
+
+DROP TABLE IF EXISTS order_service.deleted_customers;
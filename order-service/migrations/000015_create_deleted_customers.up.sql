@@ -0,0 +1,24 @@
+-- Покупатели, чьи аккаунты удалены окончательно. Заказ такого покупателя, ещё не завершённый к моменту
+-- обработки user.deleted, обезличивается триггером, как только переходит в конечный статус.
+CREATE TABLE order_service.deleted_customers (
+    user_id UUID PRIMARY KEY,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE FUNCTION order_service.anonymize_finished_order() RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.status IN ('DELIVERED', 'CANCELLED') AND NEW.anonymized_at IS NULL AND EXISTS (
+        SELECT 1 FROM order_service.deleted_customers WHERE user_id = NEW.user_id
+    ) THEN
+        NEW.shipping_address_text := '';
+        NEW.anonymized_at := NOW();
+    END IF;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER orders_anonymize_finished
+BEFORE UPDATE OF status ON order_service.orders
+FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status)
+EXECUTE FUNCTION order_service.anonymize_finished_order();
@@ -0,0 +1,4 @@
+// Package orderv1 содержит сгенерированный код gRPC API order-service.
+package orderv1
+
+//go:generate protoc -I ../../../../api/proto --go_out=../../../.. --go_opt=module=github.com/vasiliy-maslov/ecommerce-microservices/order-service --go-grpc_out=../../../.. --go-grpc_opt=module=github.com/vasiliy-maslov/ecommerce-microservices/order-service order/v1/order.proto
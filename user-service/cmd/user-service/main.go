@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,10 +14,26 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/config"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/db"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	userGrpc "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/grpc"
 	userHttp "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mailer"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/orderclient"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/scheduler"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/secretbox"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
 	userService "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/verification"
 )
 
 func main() {
@@ -37,17 +54,139 @@ func main() {
 	}
 	log.Info().Msg("Successfully conected to database")
 
+	broker, closeBroker, err := events.Connect(context.Background(), cfg.Events.NATSURL, cfg.Events.Stream, "user-service")
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to event broker")
+	}
+	defer closeBroker()
+
 	userRepository := userService.NewRepository(dbPool.Pool)
-	userSvc := userService.NewService(userRepository)
-	userHandler := userHttp.NewUserHandler(userSvc)
+	ordersClient := orderclient.New(cfg.OrderService.URL, cfg.Internal.Token, cfg.OrderService.Timeout)
+
+	userMailer, err := newMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up mailer")
+	}
+	verificationSvc := verification.NewService(verification.NewRepository(dbPool.Pool), userRepository, userMailer, verification.Config{
+		TTL:            cfg.Verification.TTL,
+		ResendCooldown: cfg.Verification.ResendCooldown,
+		LinkURL:        cfg.Verification.LinkURL,
+	})
+
+	passwordValidator, err := newPasswordValidator(cfg.PasswordPolicy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up password policy")
+	}
+
+	passwordHasher, err := newPasswordHasher(cfg.PasswordHash)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up password hashing")
+	}
+
+	lockoutSvc := lockout.NewService(lockout.NewRepository(dbPool.Pool), lockout.Config{
+		AccountDelayAfter: cfg.LoginLockout.AccountDelayAfter,
+		IP:                lockout.Limits{DelayAfter: cfg.LoginLockout.IPDelayAfter, LockAfter: cfg.LoginLockout.IPLockAfter},
+		BaseDelay:         cfg.LoginLockout.BaseDelay,
+		MaxDelay:          cfg.LoginLockout.MaxDelay,
+		LockoutDuration:   cfg.LoginLockout.LockoutDuration,
+		FailureWindow:     cfg.LoginLockout.FailureWindow,
+	})
+
+	userSvc := userService.NewService(userRepository, broker, ordersClient, verificationSvc, passwordValidator, passwordHasher, lockoutSvc, cfg.Accounts.DeletedRetention)
+	accessTokenSvc := accesstoken.NewService(accesstoken.NewRoleRepository(dbPool.Pool), accesstoken.NewImpersonationRepository(dbPool.Pool), cfg.AccessToken.Secret, cfg.AccessToken.TTL, cfg.AccessToken.ImpersonationTTL)
+
+	secretBox, err := secretbox.New(cfg.MFA.EncryptionKey)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to set up MFA secret encryption")
+	}
+	mfaSvc := mfa.NewService(mfa.NewRepository(dbPool.Pool), userSvc, secretBox, cfg.MFA.Issuer)
+
+	sessionSvc := session.NewService(session.NewRepository(dbPool.Pool), cfg.Auth.SessionTTL)
+	apiKeySvc := apikey.NewService(apikey.NewRepository(dbPool.Pool))
+	userHandler := userHttp.NewUserHandler(userSvc, sessionSvc, accessTokenSvc, apiKeySvc, cfg.Internal.Token)
+	authSvc := auth.NewService(userSvc, sessionSvc, auth.NewResetRepository(dbPool.Pool), userMailer, passwordValidator, passwordHasher, userRepository, lockoutSvc, mfaSvc, auth.NewChallengeRepository(dbPool.Pool), auth.Config{
+		ResetTTL:        cfg.Auth.ResetTTL,
+		ResetCooldown:   cfg.Auth.ResetCooldown,
+		ResetURL:        cfg.Auth.ResetURL,
+		MFAChallengeTTL: cfg.MFA.ChallengeTTL,
+	})
+	authHandler := userHttp.NewAuthHandler(authSvc)
+
+	accessTokenHandler := userHttp.NewAccessTokenHandler(accessTokenSvc, sessionSvc, cfg.Accounts.AdminToken)
+
+	var oidcHandler *userHttp.OIDCHandler
+	if cfg.OIDC.IssuerURL != "" {
+		oidcClient := oidc.NewClient(oidc.ClientConfig{
+			IssuerURL:    cfg.OIDC.IssuerURL,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  cfg.OIDC.RedirectURL,
+			Scopes:       cfg.OIDC.Scopes,
+		}, &http.Client{Timeout: cfg.OIDC.HTTPTimeout})
+		oidcSvc := oidc.NewService(oidcClient, cfg.OIDC.IssuerURL, oidc.NewRepository(dbPool.Pool), userSvc, authSvc, cfg.OIDC.StateTTL)
+		oidcHandler = userHttp.NewOIDCHandler(oidcSvc)
+	} else {
+		log.Info().Msg("OIDC_ISSUER_URL is not set, login via identity provider is disabled")
+	}
+
+	apiKeyHandler := userHttp.NewAPIKeyHandler(apiKeySvc, cfg.Accounts.AdminToken)
+
+	verificationHandler := userHttp.NewVerificationHandler(verificationSvc, userSvc, apiKeySvc, cfg.Internal.Token)
+
+	adminHandler := userHttp.NewAdminHandler(userSvc, lockoutSvc, apiKeySvc, cfg.Accounts.AdminToken)
+	mfaHandler := userHttp.NewMFAHandler(mfaSvc, sessionSvc, cfg.Accounts.AdminToken)
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	purger := scheduler.NewDeletedUserPurger(userSvc, scheduler.NewAdvisoryLocker(dbPool.Pool), cfg.Accounts.PurgeInterval, cfg.Accounts.PurgeBatchSize)
+	go purger.Run(backgroundCtx)
+	// Брокер в памяти не виден другим сервисам: без NATS события ждут в event_outbox, а не теряются
+	if cfg.Events.NATSURL != "" {
+		go events.NewRelay(
+			events.NewPostgresOutbox(dbPool.Pool, userService.OutboxTable, events.SourceUserService),
+			broker,
+			events.DefaultRelayBatchSize,
+			cfg.Events.RelayInterval,
+		).Run(backgroundCtx)
+	} else {
+		log.Warn().Msg("NATS_URL is not set, domain events are kept in event_outbox until a broker is configured")
+	}
+
+	exportStore, err := export.NewFileStore(cfg.Export.Dir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to prepare export directory")
+	}
+	exportRepository := export.NewRepository(dbPool.Pool)
+	exportHandler := userHttp.NewExportHandler(export.NewService(exportRepository, exportStore, userSvc), apiKeySvc, cfg.Accounts.AdminToken)
+	go export.NewWorker(exportRepository, exportStore, userSvc, ordersClient, export.WorkerConfig{
+		PollInterval: cfg.Export.PollInterval,
+		Timeout:      cfg.Export.Timeout,
+		TTL:          cfg.Export.TTL,
+		MaxAttempts:  cfg.Export.MaxAttempts,
+		BackoffBase:  cfg.Export.BackoffBase,
+		BackoffMax:   cfg.Export.BackoffMax,
+	}).Run(backgroundCtx)
 
 	router := chi.NewRouter()
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(userHttp.RealIP(cfg.App.TrustedProxies))
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 
+	// Метрики фоновых задач отдаются JSON'ом только по внутреннему токену
+	userHttp.NewDebugHandler(cfg.Internal.Token).RegisterRoutes(router)
 	userHandler.RegisterRoutes(router)
+	adminHandler.RegisterRoutes(router)
+	exportHandler.RegisterRoutes(router)
+	verificationHandler.RegisterRoutes(router)
+	authHandler.RegisterRoutes(router)
+	mfaHandler.RegisterRoutes(router)
+	apiKeyHandler.RegisterRoutes(router)
+	accessTokenHandler.RegisterRoutes(router)
+	if oidcHandler != nil {
+		oidcHandler.RegisterRoutes(router)
+	}
 
 	server := &http.Server{
 		Addr:         ":" + cfg.App.Port,
@@ -64,6 +203,19 @@ func main() {
 		}
 	}()
 
+	grpcServer := userGrpc.NewServer(userSvc, cfg.GRPC.RequestTimeout, cfg.GRPC.AuthToken)
+	grpcListener, err := net.Listen("tcp", ":"+cfg.GRPC.Port)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Could not listen on gRPC port %s", cfg.GRPC.Port)
+	}
+
+	go func() {
+		log.Info().Msgf("Starting gRPC server on port %s", cfg.GRPC.Port)
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			log.Fatal().Err(err).Msg("gRPC server failed")
+		}
+	}()
+
 	stopCh := make(chan os.Signal, 1)
 	signal.Notify(stopCh, syscall.SIGINT, syscall.SIGTERM)
 	<-stopCh
@@ -77,9 +229,75 @@ func main() {
 		log.Fatal().Err(err).Msgf("Server Shutdown Failed:%+v", err)
 	}
 
+	stopBackground()
+
+	grpcServer.GracefulStop()
+	log.Info().Msg("gRPC server stopped.")
+
 	dbPool.Close()
 
 	log.Info().Msg("HTTP server stopped.")
 
 	log.Info().Msg("User-service stopped gracefully.")
 }
+
+// newMailer выбирает способ отправки писем по MAIL_DRIVER.
+func newMailer(cfg config.MailConfig) (mailer.Mailer, error) {
+	switch cfg.Driver {
+	case "smtp":
+		return mailer.NewSMTPMailer(mailer.SMTPConfig{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.From,
+		}), nil
+	case "file":
+		return mailer.NewFileMailer(cfg.Dir, cfg.From)
+	default:
+		log.Warn().Msg("MAIL_DRIVER is log, emails are written to the log instead of being sent")
+		return mailer.NewLogMailer(), nil
+	}
+}
+
+// newPasswordValidator собирает политику паролей и загружает список скомпрометированных паролей, если он задан.
+func newPasswordValidator(cfg config.PasswordPolicyConfig) (*passwords.Validator, error) {
+	var breached *passwords.BreachedList
+	if cfg.BreachedListFile != "" {
+		var err error
+		breached, err = passwords.LoadBreachedList(cfg.BreachedListFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Str("file", cfg.BreachedListFile).Int("hashes", breached.Len()).Msg("Breached password list loaded")
+	}
+
+	return passwords.NewValidator(passwords.Policy{
+		MinLength:            cfg.MinLength,
+		MaxLength:            cfg.MaxLength,
+		RequireUppercase:     cfg.RequireUppercase,
+		RequireLowercase:     cfg.RequireLowercase,
+		RequireDigit:         cfg.RequireDigit,
+		RequireSymbol:        cfg.RequireSymbol,
+		DisallowPersonalInfo: cfg.DisallowPersonalInfo,
+	}, breached), nil
+}
+
+// newPasswordHasher создаёт хешер новых паролей с алгоритмом и параметрами из конфигурации.
+func newPasswordHasher(cfg config.PasswordHashConfig) (*passwords.Hasher, error) {
+	argon2Params := passwords.DefaultArgon2Params
+	argon2Params.Memory = uint32(cfg.Argon2MemoryKiB)
+	argon2Params.Iterations = uint32(cfg.Argon2Iterations)
+	argon2Params.Parallelism = uint8(cfg.Argon2Parallelism)
+
+	hasher, err := passwords.NewHasher(passwords.HashParams{
+		Algorithm:  cfg.Algorithm,
+		BcryptCost: cfg.BcryptCost,
+		Argon2:     argon2Params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Str("algorithm", cfg.Algorithm).Msg("Password hasher configured")
+	return hasher, nil
+}
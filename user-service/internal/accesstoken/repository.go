@@ -0,0 +1,155 @@
+package accesstoken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type RoleRepository interface {
+	// Roles возвращает роли пользователя по алфавиту. Пустой список - покупатель.
+	Roles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// SetRoles заменяет роли пользователя. user.ErrNotFound, если пользователя нет или он удалён.
+	SetRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+}
+
+type postgresRoleRepository struct {
+	db user.DB
+}
+
+func NewRoleRepository(db user.DB) RoleRepository {
+	return &postgresRoleRepository{db: db}
+}
+
+func (r *postgresRoleRepository) Roles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	query := `SELECT role FROM user_service.user_roles WHERE user_id = $1 ORDER BY role`
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	roles := []string{}
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan role of user %s: %w", userID, err)
+		}
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate roles of user %s: %w", userID, err)
+	}
+	return roles, nil
+}
+
+func (r *postgresRoleRepository) SetRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	// Один запрос: снятые роли удаляются, новые добавляются, уже выданные сохраняют время выдачи
+	query := `
+		WITH target AS (
+			SELECT id FROM user_service.users WHERE id = $1 AND deleted_at IS NULL
+		), removed AS (
+			DELETE FROM user_service.user_roles
+			WHERE user_id IN (SELECT id FROM target) AND NOT (role = ANY($2))
+		), granted AS (
+			INSERT INTO user_service.user_roles (user_id, role)
+			SELECT target.id, granted_role FROM target, UNNEST($2::TEXT[]) AS granted_role
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM target
+	`
+
+	// nil ушёл бы в запрос как NULL, и снятие всех ролей ничего бы не удалило
+	if roles == nil {
+		roles = []string{}
+	}
+
+	var found int
+	if err := r.db.QueryRow(ctx, query, userID, roles).Scan(&found); err != nil {
+		return fmt.Errorf("failed to set roles of user %s: %w", userID, err)
+	}
+	if found == 0 {
+		return user.ErrNotFound
+	}
+	return nil
+}
+
+// Impersonation - запись журнала: кто, от чьего имени, зачем и до какого времени получил токен.
+type Impersonation struct {
+	ID        uuid.UUID
+	ActorID   uuid.UUID
+	SubjectID uuid.UUID
+	Reason    string
+	IP        string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ImpersonationRepository interface {
+	// Record сохраняет запись журнала. user.ErrNotFound, если пользователя нет или он удалён.
+	Record(ctx context.Context, impersonation *Impersonation) error
+	// ListBySubject возвращает последние limit записей о пользователе, новые первыми.
+	ListBySubject(ctx context.Context, subjectID uuid.UUID, limit int) ([]Impersonation, error)
+}
+
+type postgresImpersonationRepository struct {
+	db user.DB
+}
+
+func NewImpersonationRepository(db user.DB) ImpersonationRepository {
+	return &postgresImpersonationRepository{db: db}
+}
+
+func (r *postgresImpersonationRepository) Record(ctx context.Context, impersonation *Impersonation) error {
+	// Проверка пользователя и запись в одном запросе, чтобы токен не выпускался для удалённого между ними
+	query := `
+		WITH target AS (
+			SELECT id FROM user_service.users WHERE id = $3 AND deleted_at IS NULL
+		)
+		INSERT INTO user_service.impersonations (id, actor_id, subject_id, reason, ip, expires_at)
+		SELECT $1, $2, target.id, $4, $5, $6 FROM target
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, impersonation.ID, impersonation.ActorID, impersonation.SubjectID,
+		impersonation.Reason, impersonation.IP, impersonation.ExpiresAt).Scan(&impersonation.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return user.ErrNotFound
+		}
+		return fmt.Errorf("failed to record impersonation of user %s by %s: %w", impersonation.SubjectID, impersonation.ActorID, err)
+	}
+	return nil
+}
+
+func (r *postgresImpersonationRepository) ListBySubject(ctx context.Context, subjectID uuid.UUID, limit int) ([]Impersonation, error) {
+	query := `
+		SELECT id, actor_id, subject_id, reason, ip, created_at, expires_at
+		FROM user_service.impersonations
+		WHERE subject_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, query, subjectID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list impersonations of user %s: %w", subjectID, err)
+	}
+	defer rows.Close()
+
+	impersonations := []Impersonation{}
+	for rows.Next() {
+		var i Impersonation
+		if err := rows.Scan(&i.ID, &i.ActorID, &i.SubjectID, &i.Reason, &i.IP, &i.CreatedAt, &i.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan impersonation of user %s: %w", subjectID, err)
+		}
+		impersonations = append(impersonations, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate impersonations of user %s: %w", subjectID, err)
+	}
+	return impersonations, nil
+}
@@ -0,0 +1,141 @@
+package accesstoken_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+func createUser(t *testing.T, email string) uuid.UUID {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV4())
+	_, err := user.NewRepository(testDB).Create(context.Background(), &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        email,
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.users WHERE id = $1", userID)
+		require.NoError(t, err)
+	})
+	return userID
+}
+
+func TestRoleRepository_SetRoles(t *testing.T) {
+	repo := accesstoken.NewRoleRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "accesstoken.repo.roles@example.com")
+
+	roles, err := repo.Roles(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	require.NoError(t, repo.SetRoles(ctx, userID, []string{accesstoken.RoleAdmin, accesstoken.RoleSupport}))
+	roles, err = repo.Roles(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{accesstoken.RoleAdmin, accesstoken.RoleSupport}, roles)
+
+	require.NoError(t, repo.SetRoles(ctx, userID, []string{accesstoken.RoleSupport}))
+	roles, err = repo.Roles(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{accesstoken.RoleSupport}, roles)
+
+	require.NoError(t, repo.SetRoles(ctx, userID, nil))
+	roles, err = repo.Roles(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+
+	require.ErrorIs(t, repo.SetRoles(ctx, uuid.Must(uuid.NewV4()), []string{accesstoken.RoleAdmin}), user.ErrNotFound)
+}
+
+func TestImpersonationRepository_RecordAndList(t *testing.T) {
+	repo := accesstoken.NewImpersonationRepository(testDB)
+	ctx := context.Background()
+	actorID := createUser(t, "accesstoken.repo.actor@example.com")
+	subjectID := createUser(t, "accesstoken.repo.subject@example.com")
+	t.Cleanup(func() {
+		_, err := testDB.Exec(ctx, "DELETE FROM user_service.impersonations WHERE subject_id = $1", subjectID)
+		require.NoError(t, err)
+	})
+
+	for _, reason := range []string{"first ticket", "second ticket"} {
+		impersonation := &accesstoken.Impersonation{
+			ID:        uuid.Must(uuid.NewV4()),
+			ActorID:   actorID,
+			SubjectID: subjectID,
+			Reason:    reason,
+			IP:        "10.0.0.1",
+			ExpiresAt: time.Now().Add(5 * time.Minute),
+		}
+		require.NoError(t, repo.Record(ctx, impersonation))
+		assert.False(t, impersonation.CreatedAt.IsZero())
+	}
+
+	history, err := repo.ListBySubject(ctx, subjectID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "second ticket", history[0].Reason)
+	assert.Equal(t, actorID, history[0].ActorID)
+
+	history, err = repo.ListBySubject(ctx, subjectID, 1)
+	require.NoError(t, err)
+	assert.Len(t, history, 1)
+
+	err = repo.Record(ctx, &accesstoken.Impersonation{ID: uuid.Must(uuid.NewV4()), ActorID: actorID, SubjectID: uuid.Must(uuid.NewV4()), ExpiresAt: time.Now()})
+	assert.ErrorIs(t, err, user.ErrNotFound)
+}
@@ -0,0 +1,271 @@
+// Package accesstoken выпускает короткоживущие токены доступа к другим сервисам по сессии пользователя.
+// Токен - JWT с подписью HS256 общим секретом сервисов; в нём ID пользователя и его роли.
+// Поддержка может получить токен от имени покупателя: в нём есть утверждение act с ID сотрудника.
+package accesstoken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Роли сотрудников. Поддержка и администраторы видят заказы всех покупателей.
+const (
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// Roles - все известные роли в порядке показа.
+var Roles = []string{RoleSupport, RoleAdmin}
+
+const (
+	// issuer и audience совпадают с ожиданиями order-service
+	issuer   = "user-service"
+	audience = "order-service"
+)
+
+// impersonationHistoryLimit - сколько последних записей журнала показывать администратору
+const impersonationHistoryLimit = 100
+
+var (
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrNotSupport - входить от имени пользователей может только поддержка
+	ErrNotSupport = errors.New("only support staff can impersonate users")
+	// ErrStaffSubject - токен от имени сотрудника дал бы его роли
+	ErrStaffSubject = errors.New("staff accounts cannot be impersonated")
+	// ErrInvalidToken - подпись, издатель, получатель или срок действия токена не подходят
+	ErrInvalidToken = errors.New("invalid access token")
+)
+
+// Token - выпущенный токен доступа.
+type Token struct {
+	Value     string
+	ExpiresAt time.Time
+}
+
+// Principal - владелец проверенного токена доступа.
+type Principal struct {
+	UserID  uuid.UUID
+	Roles   []string
+	ActorID uuid.UUID // Сотрудник поддержки, действующий от имени UserID; uuid.Nil в обычном токене
+}
+
+// IsImpersonated сообщает, что токен выдан поддержке от имени пользователя.
+func (p *Principal) IsImpersonated() bool {
+	return p.ActorID != uuid.Nil
+}
+
+type Service interface {
+	// Issue выпускает токен доступа пользователя с его текущими ролями.
+	Issue(ctx context.Context, userID uuid.UUID) (*Token, error)
+	Roles(ctx context.Context, userID uuid.UUID) ([]string, error)
+	// SetRoles заменяет роли пользователя. ErrUnknownRole, user.ErrNotFound.
+	// Уже выпущенные токены сохраняют прежние роли до истечения.
+	SetRoles(ctx context.Context, userID uuid.UUID, roles []string) error
+	// Impersonate выпускает сотруднику поддержки ActorID короткоживущий токен от имени SubjectID без ролей
+	// и записывает это в журнал. Заполняет ID, CreatedAt и ExpiresAt. ErrNotSupport, ErrStaffSubject, user.ErrNotFound.
+	Impersonate(ctx context.Context, impersonation *Impersonation) (*Token, error)
+	// Impersonations возвращает последние записи журнала о пользователе.
+	Impersonations(ctx context.Context, userID uuid.UUID) ([]Impersonation, error)
+	// Verify проверяет подпись и срок действия токена, выпущенного этим сервисом, и возвращает его владельца.
+	// ErrInvalidToken.
+	Verify(token string) (*Principal, error)
+}
+
+type service struct {
+	roles            RoleRepository
+	impersonations   ImpersonationRepository
+	secret           []byte
+	ttl              time.Duration
+	impersonationTTL time.Duration
+	now              func() time.Time
+}
+
+// NewService создаёт сервис токенов. secret - общий с order-service ключ подписи, ttl - срок жизни токена,
+// impersonationTTL - срок жизни токена поддержки от имени пользователя.
+func NewService(roles RoleRepository, impersonations ImpersonationRepository, secret []byte, ttl, impersonationTTL time.Duration) Service {
+	return &service{
+		roles:            roles,
+		impersonations:   impersonations,
+		secret:           secret,
+		ttl:              ttl,
+		impersonationTTL: impersonationTTL,
+		now:              time.Now,
+	}
+}
+
+type claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  string   `json:"aud"`
+	Roles     []string `json:"roles"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+	ID        string   `json:"jti,omitempty"` // ID записи журнала для токена поддержки
+	Actor     *actor   `json:"act,omitempty"` // Сотрудник, действующий от имени sub (RFC 8693)
+}
+
+type actor struct {
+	Subject string `json:"sub"`
+}
+
+func (s *service) Issue(ctx context.Context, userID uuid.UUID) (*Token, error) {
+	roles, err := s.roles.Roles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	expiresAt := now.Add(s.ttl)
+	value, err := s.sign(claims{
+		Issuer:    issuer,
+		Subject:   userID.String(),
+		Audience:  audience,
+		Roles:     roles,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Token{Value: value, ExpiresAt: expiresAt}, nil
+}
+
+func (s *service) Roles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	return s.roles.Roles(ctx, userID)
+}
+
+func (s *service) SetRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	for _, role := range roles {
+		if !slices.Contains(Roles, role) {
+			return fmt.Errorf("%w %q", ErrUnknownRole, role)
+		}
+	}
+	roles = slices.Compact(slices.Sorted(slices.Values(roles)))
+
+	if err := s.roles.SetRoles(ctx, userID, roles); err != nil {
+		return err
+	}
+	log.Info().Stringer("user_id", userID).Strs("roles", roles).Msg("User roles changed")
+	return nil
+}
+
+func (s *service) Impersonate(ctx context.Context, impersonation *Impersonation) (*Token, error) {
+	actorRoles, err := s.roles.Roles(ctx, impersonation.ActorID)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(actorRoles, RoleSupport) {
+		log.Warn().Stringer("actor_id", impersonation.ActorID).Stringer("user_id", impersonation.SubjectID).Msg("Impersonation denied: actor is not support staff")
+		return nil, ErrNotSupport
+	}
+	subjectRoles, err := s.roles.Roles(ctx, impersonation.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(subjectRoles) > 0 {
+		log.Warn().Stringer("actor_id", impersonation.ActorID).Stringer("user_id", impersonation.SubjectID).Msg("Impersonation denied: user is staff")
+		return nil, ErrStaffSubject
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation id: %w", err)
+	}
+	now := s.now()
+	impersonation.ID = id
+	impersonation.ExpiresAt = now.Add(s.impersonationTTL)
+
+	// Запись в журнал до выпуска: токен без записи не должен существовать
+	if err := s.impersonations.Record(ctx, impersonation); err != nil {
+		return nil, err
+	}
+	value, err := s.sign(claims{
+		Issuer:    issuer,
+		Subject:   impersonation.SubjectID.String(),
+		Audience:  audience,
+		Roles:     []string{},
+		IssuedAt:  now.Unix(),
+		ExpiresAt: impersonation.ExpiresAt.Unix(),
+		ID:        id.String(),
+		Actor:     &actor{Subject: impersonation.ActorID.String()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Stringer("impersonation_id", id).Stringer("actor_id", impersonation.ActorID).
+		Stringer("user_id", impersonation.SubjectID).Msg("Impersonation token issued")
+	return &Token{Value: value, ExpiresAt: impersonation.ExpiresAt}, nil
+}
+
+func (s *service) Impersonations(ctx context.Context, userID uuid.UUID) ([]Impersonation, error) {
+	return s.impersonations.ListBySubject(ctx, userID, impersonationHistoryLimit)
+}
+
+func (s *service) Verify(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	// Заголовок не читается: этот сервис подписывает только HS256, а другой алгоритм не даст совпадения подписи
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if c.Issuer != issuer || c.Audience != audience || s.now().Unix() >= c.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+
+	principal := &Principal{Roles: c.Roles}
+	if principal.UserID, err = uuid.FromString(c.Subject); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if c.Actor != nil {
+		if principal.ActorID, err = uuid.FromString(c.Actor.Subject); err != nil || principal.ActorID == uuid.Nil {
+			return nil, ErrInvalidToken
+		}
+	}
+	return principal, nil
+}
+
+// sign кодирует claims компактным JWS с подписью HS256.
+func (s *service) sign(c claims) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode access token header: %w", err)
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode access token claims: %w", err)
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,192 @@
+package accesstoken
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// memoryRoleRepository - RoleRepository в памяти; пользователи, которых нет в карте, считаются несуществующими.
+type memoryRoleRepository map[uuid.UUID][]string
+
+func (r memoryRoleRepository) Roles(_ context.Context, userID uuid.UUID) ([]string, error) {
+	return append([]string{}, r[userID]...), nil
+}
+
+func (r memoryRoleRepository) SetRoles(_ context.Context, userID uuid.UUID, roles []string) error {
+	if _, ok := r[userID]; !ok {
+		return user.ErrNotFound
+	}
+	r[userID] = roles
+	return nil
+}
+
+// memoryImpersonationRepository - журнал в памяти; пользователи, которых нет в users, считаются несуществующими.
+type memoryImpersonationRepository struct {
+	users   memoryRoleRepository
+	records []Impersonation
+}
+
+func (r *memoryImpersonationRepository) Record(_ context.Context, impersonation *Impersonation) error {
+	if _, ok := r.users[impersonation.SubjectID]; !ok {
+		return user.ErrNotFound
+	}
+	r.records = append(r.records, *impersonation)
+	return nil
+}
+
+func (r *memoryImpersonationRepository) ListBySubject(_ context.Context, subjectID uuid.UUID, limit int) ([]Impersonation, error) {
+	found := []Impersonation{}
+	for i := len(r.records) - 1; i >= 0 && len(found) < limit; i-- {
+		if r.records[i].SubjectID == subjectID {
+			found = append(found, r.records[i])
+		}
+	}
+	return found, nil
+}
+
+// decode проверяет подпись токена и возвращает его утверждения.
+func decode(t *testing.T, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	require.Len(t, parts, 3)
+	mac := hmac.New(sha256.New, testSecret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2], "signature")
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	var c map[string]any
+	require.NoError(t, json.Unmarshal(payload, &c))
+	return c
+}
+
+func TestService_Issue(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	clock := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	roles := memoryRoleRepository{userID: {RoleSupport}}
+	svc := NewService(roles, &memoryImpersonationRepository{users: roles}, testSecret, 15*time.Minute, 5*time.Minute).(*service)
+	svc.now = func() time.Time { return clock }
+
+	token, err := svc.Issue(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(15*time.Minute), token.ExpiresAt)
+
+	c := decode(t, token.Value)
+	assert.Equal(t, "user-service", c["iss"])
+	assert.Equal(t, "order-service", c["aud"])
+	assert.Equal(t, userID.String(), c["sub"])
+	assert.Equal(t, []any{RoleSupport}, c["roles"])
+	assert.EqualValues(t, clock.Unix(), c["iat"])
+	assert.EqualValues(t, clock.Add(15*time.Minute).Unix(), c["exp"])
+	assert.NotContains(t, c, "act")
+
+	principal, err := svc.Verify(token.Value)
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{UserID: userID, Roles: []string{RoleSupport}}, principal)
+	assert.False(t, principal.IsImpersonated())
+}
+
+func TestService_SetRoles(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	repo := memoryRoleRepository{userID: {}}
+	svc := NewService(repo, &memoryImpersonationRepository{users: repo}, testSecret, time.Minute, time.Minute)
+	ctx := context.Background()
+
+	require.NoError(t, svc.SetRoles(ctx, userID, []string{RoleSupport, RoleAdmin, RoleSupport}))
+	assert.Equal(t, []string{RoleAdmin, RoleSupport}, repo[userID])
+
+	assert.ErrorIs(t, svc.SetRoles(ctx, userID, []string{"root"}), ErrUnknownRole)
+	assert.ErrorIs(t, svc.SetRoles(ctx, uuid.Must(uuid.NewV4()), []string{RoleAdmin}), user.ErrNotFound)
+
+	require.NoError(t, svc.SetRoles(ctx, userID, nil))
+	roles, err := svc.Roles(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+}
+
+func TestService_Impersonate(t *testing.T) {
+	supportID, adminID, customerID := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	roles := memoryRoleRepository{supportID: {RoleSupport}, adminID: {RoleAdmin}, customerID: {}}
+	audit := &memoryImpersonationRepository{users: roles}
+	clock := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := NewService(roles, audit, testSecret, 15*time.Minute, 5*time.Minute).(*service)
+	svc.now = func() time.Time { return clock }
+	ctx := context.Background()
+
+	impersonation := &Impersonation{ActorID: supportID, SubjectID: customerID, Reason: "checkout fails for the customer", IP: "10.0.0.1"}
+	token, err := svc.Impersonate(ctx, impersonation)
+	require.NoError(t, err)
+	assert.Equal(t, clock.Add(5*time.Minute), token.ExpiresAt)
+
+	c := decode(t, token.Value)
+	assert.Equal(t, customerID.String(), c["sub"])
+	assert.Equal(t, map[string]any{"sub": supportID.String()}, c["act"])
+	assert.Equal(t, impersonation.ID.String(), c["jti"])
+	assert.Empty(t, c["roles"], "the token gives the customer's view, not the staff roles")
+	assert.EqualValues(t, clock.Add(5*time.Minute).Unix(), c["exp"])
+
+	principal, err := svc.Verify(token.Value)
+	require.NoError(t, err)
+	assert.Equal(t, customerID, principal.UserID)
+	assert.Equal(t, supportID, principal.ActorID)
+	assert.True(t, principal.IsImpersonated())
+
+	history, err := svc.Impersonations(ctx, customerID)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, *impersonation, history[0])
+
+	tests := map[string]struct {
+		actor, subject uuid.UUID
+		wantErr        error
+	}{
+		"customer as actor":          {actor: customerID, subject: customerID, wantErr: ErrNotSupport},
+		"admin without support role": {actor: adminID, subject: customerID, wantErr: ErrNotSupport},
+		"staff as subject":           {actor: supportID, subject: adminID, wantErr: ErrStaffSubject},
+		"self":                       {actor: supportID, subject: supportID, wantErr: ErrStaffSubject},
+		"unknown user":               {actor: supportID, subject: uuid.Must(uuid.NewV4()), wantErr: user.ErrNotFound},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.Impersonate(ctx, &Impersonation{ActorID: tt.actor, SubjectID: tt.subject, Reason: "checkout fails"})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	assert.Len(t, audit.records, 1, "refused attempts issue no token and leave no record")
+}
+
+func TestService_Verify_RejectsForeignAndExpiredTokens(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	roles := memoryRoleRepository{userID: {}}
+	clock := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	svc := NewService(roles, &memoryImpersonationRepository{users: roles}, testSecret, time.Minute, time.Minute).(*service)
+	svc.now = func() time.Time { return clock }
+
+	token, err := svc.Issue(context.Background(), userID)
+	require.NoError(t, err)
+
+	other := NewService(roles, &memoryImpersonationRepository{users: roles}, []byte("another-secret-another-secret-00"), time.Minute, time.Minute)
+	_, err = other.Verify(token.Value)
+	assert.ErrorIs(t, err, ErrInvalidToken, "foreign signature")
+	for _, bad := range []string{"", "session-token", token.Value + "x"} {
+		_, err := svc.Verify(bad)
+		assert.ErrorIs(t, err, ErrInvalidToken, bad)
+	}
+
+	clock = clock.Add(time.Minute)
+	_, err = svc.Verify(token.Value)
+	assert.ErrorIs(t, err, ErrInvalidToken, "expired")
+}
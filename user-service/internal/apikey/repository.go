@@ -0,0 +1,156 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// Key - выпущенный ключ API. Сам ключ не хранится, только его хеш.
+type Key struct {
+	ID         uuid.UUID
+	Name       string
+	Prefix     string
+	Hash       []byte
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// HasScope сообщает, разрешено ли ключу действие scope.
+func (k *Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+type Repository interface {
+	Create(ctx context.Context, key *Key) error
+	List(ctx context.Context) ([]Key, error)
+	// Get возвращает ключ по ID. ErrNotFound, если его нет.
+	Get(ctx context.Context, id uuid.UUID) (*Key, error)
+	// FindByHash возвращает ключ по хешу, в том числе истёкший. ErrNotFound, если его нет.
+	FindByHash(ctx context.Context, hash []byte) (*Key, error)
+	// Update меняет название, права и срок действия. ErrNotFound, если ключа нет.
+	Update(ctx context.Context, key *Key) error
+	// Touch запоминает время использования ключа.
+	Touch(ctx context.Context, id uuid.UUID, at time.Time) error
+	// Delete отзывает ключ. ErrNotFound, если его нет.
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+const keyColumns = "id, name, prefix, key_hash, scopes, created_at, expires_at, last_used_at"
+
+func scanKey(row pgx.Row) (*Key, error) {
+	var key Key
+	if err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Hash, &key.Scopes, &key.CreatedAt, &key.ExpiresAt, &key.LastUsedAt); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *postgresRepository) Create(ctx context.Context, key *Key) error {
+	query := `
+		INSERT INTO user_service.api_keys (id, name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	if err := r.db.QueryRow(ctx, query, key.ID, key.Name, key.Prefix, key.Hash, key.Scopes, key.ExpiresAt).Scan(&key.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create api key %s: %w", key.ID, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) List(ctx context.Context) ([]Key, error) {
+	query := "SELECT " + keyColumns + " FROM user_service.api_keys ORDER BY created_at, id"
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []Key{}
+	for rows.Next() {
+		key, err := scanKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, *key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate api keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (r *postgresRepository) Get(ctx context.Context, id uuid.UUID) (*Key, error) {
+	query := "SELECT " + keyColumns + " FROM user_service.api_keys WHERE id = $1"
+	key, err := scanKey(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get api key %s: %w", id, err)
+	}
+	return key, nil
+}
+
+func (r *postgresRepository) FindByHash(ctx context.Context, hash []byte) (*Key, error) {
+	query := "SELECT " + keyColumns + " FROM user_service.api_keys WHERE key_hash = $1"
+	key, err := scanKey(r.db.QueryRow(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to find api key: %w", err)
+	}
+	return key, nil
+}
+
+func (r *postgresRepository) Update(ctx context.Context, key *Key) error {
+	query := `
+		UPDATE user_service.api_keys
+		SET name = $2, scopes = $3, expires_at = $4
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, key.ID, key.Name, key.Scopes, key.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to update api key %s: %w", key.ID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `UPDATE user_service.api_keys SET last_used_at = $2 WHERE id = $1`
+	if _, err := r.db.Exec(ctx, query, id, at); err != nil {
+		return fmt.Errorf("failed to record use of api key %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	tag, err := r.db.Exec(ctx, "DELETE FROM user_service.api_keys WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete api key %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
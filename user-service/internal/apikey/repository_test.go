@@ -0,0 +1,118 @@
+package apikey_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func createKey(t *testing.T, repo apikey.Repository, name string, scopes []string) *apikey.Key {
+	t.Helper()
+	key := &apikey.Key{ID: uuid.Must(uuid.NewV4()), Name: name, Prefix: "usk_testtest", Hash: []byte("hash-" + name), Scopes: scopes}
+	require.NoError(t, repo.Create(context.Background(), key))
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.api_keys WHERE id = $1", key.ID)
+		require.NoError(t, err)
+	})
+	return key
+}
+
+func TestRepository_CreateFindUpdate(t *testing.T) {
+	repo := apikey.NewRepository(testDB)
+	ctx := context.Background()
+	key := createKey(t, repo, "apikey.repo.crud", []string{apikey.ScopeUsersRead, apikey.ScopeUsersWrite})
+	assert.False(t, key.CreatedAt.IsZero())
+
+	found, err := repo.FindByHash(ctx, key.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, []string{apikey.ScopeUsersRead, apikey.ScopeUsersWrite}, found.Scopes)
+	assert.Nil(t, found.ExpiresAt)
+	assert.Nil(t, found.LastUsedAt)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	found.Name, found.Scopes, found.ExpiresAt = "apikey.repo.crud.v2", []string{apikey.ScopeUsersWrite}, &expiresAt
+	require.NoError(t, repo.Update(ctx, found))
+	usedAt := time.Now().Truncate(time.Microsecond)
+	require.NoError(t, repo.Touch(ctx, key.ID, usedAt))
+
+	got, err := repo.Get(ctx, key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "apikey.repo.crud.v2", got.Name)
+	assert.Equal(t, []string{apikey.ScopeUsersWrite}, got.Scopes)
+	require.NotNil(t, got.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*got.ExpiresAt))
+	require.NotNil(t, got.LastUsedAt)
+	assert.True(t, usedAt.Equal(*got.LastUsedAt))
+
+	keys, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.True(t, slices.ContainsFunc(keys, func(k apikey.Key) bool { return k.ID == key.ID }))
+}
+
+func TestRepository_NotFound(t *testing.T) {
+	repo := apikey.NewRepository(testDB)
+	ctx := context.Background()
+	key := createKey(t, repo, "apikey.repo.delete", []string{apikey.ScopeUsersRead})
+
+	require.NoError(t, repo.Delete(ctx, key.ID))
+	require.ErrorIs(t, repo.Delete(ctx, key.ID), apikey.ErrNotFound)
+	_, err := repo.Get(ctx, key.ID)
+	require.ErrorIs(t, err, apikey.ErrNotFound)
+	_, err = repo.FindByHash(ctx, key.Hash)
+	require.ErrorIs(t, err, apikey.ErrNotFound)
+	require.ErrorIs(t, repo.Update(ctx, key), apikey.ErrNotFound)
+}
@@ -0,0 +1,191 @@
+// Package apikey выпускает ключи API для сервисов и партнёров. Ключ даёт только перечисленные в нём права.
+package apikey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+)
+
+var (
+	ErrNotFound      = errors.New("api key not found")
+	ErrInvalidKey    = errors.New("invalid or expired api key")
+	ErrUnknownScope  = errors.New("unknown api key scope")
+	ErrExpiryInPast  = errors.New("api key expiry must be in the future")
+	ErrScopeRequired = errors.New("api key must have at least one scope")
+)
+
+// Права, которые можно выдать ключу.
+const (
+	ScopeUsersRead  = "users:read"
+	ScopeUsersWrite = "users:write"
+)
+
+// Scopes - все известные права в порядке показа.
+var Scopes = []string{ScopeUsersRead, ScopeUsersWrite}
+
+const (
+	// keyPrefix отличает ключи сервиса от других секретов, например при поиске утечек в репозиториях
+	keyPrefix = "usk_"
+	// displayLength - сколько первых символов ключа хранится открыто, чтобы его можно было узнать в списке
+	displayLength = len(keyPrefix) + 8
+	// touchInterval - время использования обновляется не чаще, чтобы каждый запрос не писал в БД
+	touchInterval = time.Minute
+)
+
+// Params - изменяемые свойства ключа. Nil ExpiresAt - бессрочный ключ.
+type Params struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+type Service interface {
+	// Create выпускает ключ и возвращает его вместе с сохранёнными данными. Ключ показывается только здесь.
+	Create(ctx context.Context, params Params) (string, *Key, error)
+	List(ctx context.Context) ([]Key, error)
+	Get(ctx context.Context, id uuid.UUID) (*Key, error)
+	// Update меняет название, права и срок действия. Сам ключ не меняется.
+	Update(ctx context.Context, id uuid.UUID, params Params) (*Key, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	// Authenticate находит действующий ключ и отмечает его использование. ErrInvalidKey, если ключ неизвестен,
+	// отозван или истёк.
+	Authenticate(ctx context.Context, raw string) (*Key, error)
+}
+
+type service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+func NewService(repo Repository) Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+func (s *service) Create(ctx context.Context, params Params) (string, *Key, error) {
+	if err := s.validate(params); err != nil {
+		return "", nil, err
+	}
+
+	secret, _, err := tokens.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+	id, err := uuid.NewV4()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key id: %w", err)
+	}
+
+	raw := keyPrefix + secret
+	key := &Key{
+		ID:        id,
+		Name:      params.Name,
+		Prefix:    raw[:displayLength],
+		Hash:      tokens.Hash(raw),
+		Scopes:    normalizeScopes(params.Scopes),
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return "", nil, err
+	}
+
+	log.Info().Stringer("api_key_id", key.ID).Strs("scopes", key.Scopes).Msg("API key created")
+	return raw, key, nil
+}
+
+func (s *service) List(ctx context.Context) ([]Key, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *service) Get(ctx context.Context, id uuid.UUID) (*Key, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *service) Update(ctx context.Context, id uuid.UUID, params Params) (*Key, error) {
+	if err := s.validate(params); err != nil {
+		return nil, err
+	}
+
+	key, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key.Name = params.Name
+	key.Scopes = normalizeScopes(params.Scopes)
+	key.ExpiresAt = params.ExpiresAt
+	if err := s.repo.Update(ctx, key); err != nil {
+		return nil, err
+	}
+
+	log.Info().Stringer("api_key_id", key.ID).Strs("scopes", key.Scopes).Msg("API key updated")
+	return key, nil
+}
+
+func (s *service) Delete(ctx context.Context, id uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	log.Info().Stringer("api_key_id", id).Msg("API key revoked")
+	return nil
+}
+
+func (s *service) Authenticate(ctx context.Context, raw string) (*Key, error) {
+	if !strings.HasPrefix(raw, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	key, err := s.repo.FindByHash(ctx, tokens.Hash(raw))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return nil, ErrInvalidKey
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= touchInterval {
+		// Ошибка учёта не мешает запросу
+		if err := s.repo.Touch(ctx, key.ID, now); err != nil {
+			log.Error().Err(err).Stringer("api_key_id", key.ID).Msg("Failed to record api key use")
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+func (s *service) validate(params Params) error {
+	if len(params.Scopes) == 0 {
+		return ErrScopeRequired
+	}
+	for _, scope := range params.Scopes {
+		if !slices.Contains(Scopes, scope) {
+			return fmt.Errorf("%w: %q", ErrUnknownScope, scope)
+		}
+	}
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(s.now()) {
+		return ErrExpiryInPast
+	}
+	return nil
+}
+
+// normalizeScopes убирает повторы и упорядочивает права как в Scopes.
+func normalizeScopes(scopes []string) []string {
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range Scopes {
+		if slices.Contains(scopes, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,236 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, key *Key) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) List(ctx context.Context) ([]Key, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Key), args.Error(1)
+}
+
+func (m *MockRepository) Get(ctx context.Context, id uuid.UUID) (*Key, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Key), args.Error(1)
+}
+
+func (m *MockRepository) FindByHash(ctx context.Context, hash []byte) (*Key, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Key), args.Error(1)
+}
+
+func (m *MockRepository) Update(ctx context.Context, key *Key) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Touch(ctx context.Context, id uuid.UUID, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func TestService_Create_StoresOnlyHash(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo)
+	ctx := context.Background()
+
+	var stored *Key
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*apikey.Key")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*Key)
+	}).Return(nil).Once()
+
+	raw, key, err := svc.Create(ctx, Params{Name: "order-service", Scopes: []string{ScopeUsersWrite, ScopeUsersRead, ScopeUsersRead}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(raw, keyPrefix))
+	assert.True(t, strings.HasPrefix(raw, key.Prefix))
+	assert.Len(t, key.Prefix, displayLength)
+	assert.Equal(t, []string{ScopeUsersRead, ScopeUsersWrite}, key.Scopes, "scopes are deduplicated and ordered")
+	require.NotNil(t, stored)
+	assert.Equal(t, tokens.Hash(raw), stored.Hash)
+	assert.NotContains(t, string(stored.Hash), raw)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Authenticate(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	raw := keyPrefix + "secret"
+	lastUsed := testNow.Add(-10 * time.Second)
+	key := &Key{ID: uuid.Must(uuid.NewV4()), Scopes: []string{ScopeUsersRead}, LastUsedAt: &lastUsed}
+
+	mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(key, nil).Once()
+	mockRepo.On("FindByHash", ctx, mock.Anything).Return(nil, ErrNotFound)
+
+	found, err := svc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.True(t, found.HasScope(ScopeUsersRead))
+	assert.False(t, found.HasScope(ScopeUsersWrite))
+
+	for _, bad := range []string{"", "usk_unknown", "secret", raw + "x"} {
+		_, err := svc.Authenticate(ctx, bad)
+		assert.ErrorIs(t, err, ErrInvalidKey, bad)
+	}
+	// Ключ без префикса отклоняется без запроса к БД
+	mockRepo.AssertNotCalled(t, "FindByHash", ctx, tokens.Hash("secret"))
+	mockRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Authenticate_Expiry(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	raw := keyPrefix + "secret"
+
+	for _, tc := range []struct {
+		expiresAt time.Time
+		valid     bool
+	}{{testNow.Add(time.Minute), true}, {testNow, false}, {testNow.Add(-time.Minute), false}} {
+		expiresAt := tc.expiresAt
+		mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(&Key{ID: uuid.Must(uuid.NewV4()), ExpiresAt: &expiresAt, LastUsedAt: &testNow}, nil).Once()
+
+		_, err := svc.Authenticate(ctx, raw)
+		if tc.valid {
+			assert.NoError(t, err, expiresAt)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidKey, expiresAt)
+		}
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Authenticate_TouchesLastUsedAtMostOncePerInterval(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	raw := keyPrefix + "secret"
+	id := uuid.Must(uuid.NewV4())
+	recent := testNow.Add(-touchInterval + time.Second)
+	stale := testNow.Add(-touchInterval)
+
+	mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(&Key{ID: id, LastUsedAt: &recent}, nil).Once()
+	mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(&Key{ID: id, LastUsedAt: &stale}, nil).Once()
+	mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(&Key{ID: id}, nil).Once()
+	mockRepo.On("Touch", ctx, id, testNow).Return(nil).Twice()
+
+	found, err := svc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Equal(t, recent, *found.LastUsedAt)
+
+	for range 2 {
+		found, err := svc.Authenticate(ctx, raw)
+		require.NoError(t, err)
+		assert.Equal(t, testNow, *found.LastUsedAt)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Authenticate_TouchErrorDoesNotFailRequest(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	raw := keyPrefix + "secret"
+	key := &Key{ID: uuid.Must(uuid.NewV4())}
+
+	mockRepo.On("FindByHash", ctx, tokens.Hash(raw)).Return(key, nil).Once()
+	mockRepo.On("Touch", ctx, key.ID, testNow).Return(errors.New("connection refused")).Once()
+
+	found, err := svc.Authenticate(ctx, raw)
+	require.NoError(t, err)
+	assert.Nil(t, found.LastUsedAt)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_ValidatesParams(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	past := testNow.Add(-time.Minute)
+
+	_, _, err := svc.Create(ctx, Params{Name: "no scopes"})
+	require.ErrorIs(t, err, ErrScopeRequired)
+	_, _, err = svc.Create(ctx, Params{Name: "bad scope", Scopes: []string{"users:delete"}})
+	require.ErrorIs(t, err, ErrUnknownScope)
+	assert.Contains(t, err.Error(), "users:delete")
+	_, _, err = svc.Create(ctx, Params{Name: "orders", Scopes: []string{"orders:read"}})
+	require.ErrorIs(t, err, ErrUnknownScope, "order-service does not accept api keys")
+	_, _, err = svc.Create(ctx, Params{Name: "expired", Scopes: []string{ScopeUsersRead}, ExpiresAt: &past})
+	require.ErrorIs(t, err, ErrExpiryInPast)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_Update(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	key := &Key{ID: uuid.Must(uuid.NewV4()), Name: "partner", Prefix: "usk_12345678", Scopes: []string{ScopeUsersRead}}
+	expiresAt := testNow.Add(24 * time.Hour)
+
+	// Права меняются без перевыпуска ключа
+	mockRepo.On("Get", ctx, key.ID).Return(key, nil).Once()
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(updated *Key) bool {
+		return updated.Name == "partner-v2" && updated.Prefix == key.Prefix &&
+			assert.ObjectsAreEqual([]string{ScopeUsersWrite}, updated.Scopes) && updated.ExpiresAt.Equal(expiresAt)
+	})).Return(nil).Once()
+
+	updated, err := svc.Update(ctx, key.ID, Params{Name: "partner-v2", Scopes: []string{ScopeUsersWrite}, ExpiresAt: &expiresAt})
+	require.NoError(t, err)
+	assert.Equal(t, "partner-v2", updated.Name)
+	assert.Equal(t, []string{ScopeUsersWrite}, updated.Scopes)
+
+	ghost := uuid.Must(uuid.NewV4())
+	mockRepo.On("Get", ctx, ghost).Return(nil, ErrNotFound).Once()
+	_, err = svc.Update(ctx, ghost, Params{Name: "ghost", Scopes: []string{ScopeUsersRead}})
+	require.ErrorIs(t, err, ErrNotFound)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Delete(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo)
+	ctx := context.Background()
+	id := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("Delete", ctx, id).Return(nil).Once()
+	mockRepo.On("Delete", ctx, id).Return(ErrNotFound).Once()
+
+	require.NoError(t, svc.Delete(ctx, id))
+	require.ErrorIs(t, svc.Delete(ctx, id), ErrNotFound)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrInvalidResetToken   = errors.New("password reset token is invalid or expired")
+	ErrInvalidMFAChallenge = errors.New("two-factor login is invalid or expired, log in again")
+)
+
+// ResetToken - выпущенный токен сброса пароля. Сам токен не хранится, только его хеш.
+type ResetToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type ResetRepository interface {
+	// Issue сохраняет новый токен и отзывает все неиспользованные токены пользователя.
+	Issue(ctx context.Context, token *ResetToken) error
+	// LatestIssuedAt возвращает время выпуска последнего токена пользователя или nil, если токенов не было.
+	LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+	// FindUser возвращает владельца действующего токена, не гася его. ErrInvalidResetToken, как в Consume.
+	FindUser(ctx context.Context, hash []byte) (uuid.UUID, error)
+	// Consume гасит действующий токен, записывает новый хеш пароля и отзывает все сессии пользователя.
+	// ErrInvalidResetToken, если токен неизвестен, использован, истёк или пользователь неактивен.
+	Consume(ctx context.Context, hash []byte, passwordHash string) (uuid.UUID, error)
+}
+
+type postgresResetRepository struct {
+	db user.DB
+}
+
+func NewResetRepository(db user.DB) ResetRepository {
+	return &postgresResetRepository{db: db}
+}
+
+func (r *postgresResetRepository) Issue(ctx context.Context, token *ResetToken) error {
+	query := `
+		WITH revoked AS (
+			UPDATE user_service.password_reset_tokens
+			SET used_at = NOW()
+			WHERE user_id = $2 AND used_at IS NULL
+		)
+		INSERT INTO user_service.password_reset_tokens (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, token.ID, token.UserID, token.Hash, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to issue password reset token for user %s: %w", token.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresResetRepository) LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `SELECT MAX(created_at) FROM user_service.password_reset_tokens WHERE user_id = $1`
+
+	var issuedAt *time.Time
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&issuedAt); err != nil {
+		return nil, fmt.Errorf("failed to get last password reset token of user %s: %w", userID, err)
+	}
+	return issuedAt, nil
+}
+
+func (r *postgresResetRepository) FindUser(ctx context.Context, hash []byte) (uuid.UUID, error) {
+	query := `
+		SELECT t.user_id
+		FROM user_service.password_reset_tokens t
+		JOIN user_service.users u ON u.id = t.user_id
+		WHERE t.token_hash = $1
+			AND t.used_at IS NULL
+			AND t.expires_at > NOW()
+			AND u.deleted_at IS NULL
+			AND u.status = 'ACTIVE'
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, hash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidResetToken
+		}
+		return uuid.Nil, fmt.Errorf("failed to find password reset token: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *postgresResetRepository) Consume(ctx context.Context, hash []byte, passwordHash string) (uuid.UUID, error) {
+	// Один запрос: токен не может быть погашен без смены пароля, а пароль сменён без отзыва сессий
+	query := `
+		WITH consumed AS (
+			UPDATE user_service.password_reset_tokens t
+			SET used_at = NOW()
+			FROM user_service.users u
+			WHERE t.token_hash = $1
+				AND t.used_at IS NULL
+				AND t.expires_at > NOW()
+				AND u.id = t.user_id
+				AND u.deleted_at IS NULL
+				AND u.status = 'ACTIVE'
+			RETURNING t.user_id
+		), updated AS (
+			UPDATE user_service.users u
+			SET password_hash = $2, updated_at = NOW()
+			FROM consumed
+			WHERE u.id = consumed.user_id
+			RETURNING u.id
+		), revoked AS (
+			UPDATE user_service.sessions s
+			SET revoked_at = NOW()
+			FROM updated
+			WHERE s.user_id = updated.id AND s.revoked_at IS NULL
+		)
+		SELECT id FROM updated
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, hash, passwordHash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidResetToken
+		}
+		return uuid.Nil, fmt.Errorf("failed to consume password reset token: %w", err)
+	}
+	return userID, nil
+}
+
+// Challenge - вход, ожидающий код второго фактора. Токен выдаётся клиенту после верного пароля, хранится только его хеш.
+type Challenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Hash      []byte
+	ExpiresAt time.Time
+}
+
+type ChallengeRepository interface {
+	Create(ctx context.Context, challenge *Challenge) error
+	// Find возвращает пользователя действующего входа. ErrInvalidMFAChallenge, если вход неизвестен, завершён,
+	// истёк или неверных кодов было maxAttempts.
+	Find(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error)
+	// AddAttempt учитывает неверный код.
+	AddAttempt(ctx context.Context, hash []byte) error
+	// Consume завершает вход. ErrInvalidMFAChallenge, как в Find: один вход открывает не больше одной сессии.
+	Consume(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error)
+}
+
+type postgresChallengeRepository struct {
+	db user.DB
+}
+
+func NewChallengeRepository(db user.DB) ChallengeRepository {
+	return &postgresChallengeRepository{db: db}
+}
+
+func (r *postgresChallengeRepository) Create(ctx context.Context, challenge *Challenge) error {
+	query := `
+		INSERT INTO user_service.mfa_challenges (id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Exec(ctx, query, challenge.ID, challenge.UserID, challenge.Hash, challenge.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create mfa challenge for user %s: %w", challenge.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresChallengeRepository) Find(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error) {
+	query := `
+		SELECT user_id
+		FROM user_service.mfa_challenges
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, hash, maxAttempts).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidMFAChallenge
+		}
+		return uuid.Nil, fmt.Errorf("failed to find mfa challenge: %w", err)
+	}
+	return userID, nil
+}
+
+func (r *postgresChallengeRepository) AddAttempt(ctx context.Context, hash []byte) error {
+	query := `UPDATE user_service.mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1`
+	if _, err := r.db.Exec(ctx, query, hash); err != nil {
+		return fmt.Errorf("failed to record mfa challenge attempt: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresChallengeRepository) Consume(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error) {
+	query := `
+		UPDATE user_service.mfa_challenges
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW() AND attempts < $2
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, hash, maxAttempts).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidMFAChallenge
+		}
+		return uuid.Nil, fmt.Errorf("failed to consume mfa challenge: %w", err)
+	}
+	return userID, nil
+}
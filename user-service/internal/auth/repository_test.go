@@ -0,0 +1,206 @@
+package auth_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func createUser(t *testing.T, email string) uuid.UUID {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV4())
+	_, err := user.NewRepository(testDB).Create(context.Background(), &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        email,
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.users WHERE id = $1", userID)
+		require.NoError(t, err)
+	})
+	return userID
+}
+
+func issue(t *testing.T, repo auth.ResetRepository, userID uuid.UUID, hash string, expiresAt time.Time) {
+	t.Helper()
+	require.NoError(t, repo.Issue(context.Background(), &auth.ResetToken{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    userID,
+		Hash:      []byte(hash),
+		ExpiresAt: expiresAt,
+	}))
+}
+
+func startSession(t *testing.T, userID uuid.UUID, hash string) {
+	t.Helper()
+	require.NoError(t, session.NewRepository(testDB).Create(context.Background(), &session.Session{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, []byte(hash)))
+}
+
+func TestResetRepository_ConsumeChangesPasswordAndRevokesSessions(t *testing.T) {
+	repo := auth.NewResetRepository(testDB)
+	sessions := session.NewRepository(testDB)
+	users := user.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "reset.once@example.com")
+	otherID := createUser(t, "reset.other@example.com")
+
+	startSession(t, userID, "session-reset-1")
+	startSession(t, userID, "session-reset-2")
+	startSession(t, otherID, "session-reset-other")
+	issue(t, repo, userID, "reset-hash-once", time.Now().Add(time.Hour))
+
+	owner, err := repo.FindUser(ctx, []byte("reset-hash-once"))
+	require.NoError(t, err)
+	assert.Equal(t, userID, owner)
+
+	got, err := repo.Consume(ctx, []byte("reset-hash-once"), "new_hashed_password")
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	found, err := users.GetByID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "new_hashed_password", found.PasswordHash)
+
+	_, err = sessions.GetActive(ctx, []byte("session-reset-1"))
+	require.ErrorIs(t, err, session.ErrSessionNotFound)
+	_, err = sessions.GetActive(ctx, []byte("session-reset-2"))
+	require.ErrorIs(t, err, session.ErrSessionNotFound)
+	_, err = sessions.GetActive(ctx, []byte("session-reset-other"))
+	require.NoError(t, err)
+
+	_, err = repo.Consume(ctx, []byte("reset-hash-once"), "another_hashed_password")
+	require.ErrorIs(t, err, auth.ErrInvalidResetToken)
+	_, err = repo.FindUser(ctx, []byte("reset-hash-once"))
+	require.ErrorIs(t, err, auth.ErrInvalidResetToken)
+}
+
+func TestResetRepository_ConsumeRejectsExpiredAndRevoked(t *testing.T) {
+	repo := auth.NewResetRepository(testDB)
+	users := user.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "reset.expired@example.com")
+
+	issue(t, repo, userID, "reset-hash-expired", time.Now().Add(-time.Minute))
+	_, err := repo.Consume(ctx, []byte("reset-hash-expired"), "new_hashed_password")
+	require.ErrorIs(t, err, auth.ErrInvalidResetToken)
+
+	issue(t, repo, userID, "reset-hash-old", time.Now().Add(time.Hour))
+	issue(t, repo, userID, "reset-hash-new", time.Now().Add(time.Hour))
+	_, err = repo.Consume(ctx, []byte("reset-hash-old"), "new_hashed_password")
+	require.ErrorIs(t, err, auth.ErrInvalidResetToken)
+
+	found, err := users.GetByID(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, "hashed_password", found.PasswordHash)
+
+	issuedAt, err := repo.LatestIssuedAt(ctx, userID)
+	require.NoError(t, err)
+	assert.NotNil(t, issuedAt)
+}
+
+func TestChallengeRepository_LimitsAttemptsAndConsumesOnce(t *testing.T) {
+	repo := auth.NewChallengeRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "challenge.repo@example.com")
+
+	live := &auth.Challenge{ID: uuid.Must(uuid.NewV4()), UserID: userID, Hash: []byte("live-challenge-hash"), ExpiresAt: time.Now().Add(time.Minute)}
+	expired := &auth.Challenge{ID: uuid.Must(uuid.NewV4()), UserID: userID, Hash: []byte("expired-challenge-hash"), ExpiresAt: time.Now().Add(-time.Minute)}
+	require.NoError(t, repo.Create(ctx, live))
+	require.NoError(t, repo.Create(ctx, expired))
+
+	found, err := repo.Find(ctx, live.Hash, 2)
+	require.NoError(t, err)
+	assert.Equal(t, userID, found)
+
+	_, err = repo.Find(ctx, expired.Hash, 2)
+	require.ErrorIs(t, err, auth.ErrInvalidMFAChallenge)
+	_, err = repo.Find(ctx, []byte("unknown-challenge-hash"), 2)
+	require.ErrorIs(t, err, auth.ErrInvalidMFAChallenge)
+
+	// Одна неверная попытка из двух: вход ещё действует
+	require.NoError(t, repo.AddAttempt(ctx, live.Hash))
+	consumed, err := repo.Consume(ctx, live.Hash, 2)
+	require.NoError(t, err)
+	assert.Equal(t, userID, consumed)
+
+	_, err = repo.Consume(ctx, live.Hash, 2)
+	require.ErrorIs(t, err, auth.ErrInvalidMFAChallenge, "challenge must open only one session")
+}
+
+func TestChallengeRepository_RejectsAfterMaxAttempts(t *testing.T) {
+	repo := auth.NewChallengeRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "challenge.attempts@example.com")
+
+	challenge := &auth.Challenge{ID: uuid.Must(uuid.NewV4()), UserID: userID, Hash: []byte("guessed-challenge-hash"), ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, repo.Create(ctx, challenge))
+	for range 2 {
+		require.NoError(t, repo.AddAttempt(ctx, challenge.Hash))
+	}
+
+	_, err := repo.Find(ctx, challenge.Hash, 2)
+	require.ErrorIs(t, err, auth.ErrInvalidMFAChallenge)
+	_, err = repo.Consume(ctx, challenge.Hash, 2)
+	require.ErrorIs(t, err, auth.ErrInvalidMFAChallenge)
+}
@@ -0,0 +1,372 @@
+// Package auth отвечает за вход по паролю, выход и восстановление забытого пароля.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mailer"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// ErrInvalidCredentials не уточняет, что именно неверно: так по входу нельзя узнать, зарегистрирован ли email.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// maxMFAAttempts - сколько неверных кодов второго фактора допускается за один вход, потом нужно снова ввести пароль.
+const maxMFAAttempts = 5
+
+// Config задаёт срок жизни ссылок сброса пароля, частоту писем и время на ввод второго фактора.
+type Config struct {
+	ResetTTL        time.Duration // Сколько действует ссылка из письма
+	ResetCooldown   time.Duration // Минимальный интервал между письмами одному пользователю
+	ResetURL        string        // Страница ввода нового пароля; токен добавляется параметром token
+	MFAChallengeTTL time.Duration // Сколько после верного пароля можно ввести код второго фактора
+}
+
+// LoginResult - итог шага входа. Если у пользователя включён второй фактор, после пароля сессии ещё нет:
+// заполнены MFAToken и MFAExpiresAt, и вход завершает VerifyMFA.
+type LoginResult struct {
+	Token        string
+	Session      *session.Session
+	MFAToken     string
+	MFAExpiresAt time.Time
+}
+
+// MFAVerifier проверяет второй фактор.
+type MFAVerifier interface {
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// UserSource ищет пользователя по email при входе и запросе сброса, по ID - при проверке нового пароля.
+type UserSource interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*user.User, error)
+}
+
+// HashStore сохраняет хеш пароля, пересчитанный при входе с текущими параметрами хеширования.
+type HashStore interface {
+	ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error)
+}
+
+type Service interface {
+	// Login проверяет пароль и открывает сессию. ErrInvalidCredentials при любой ошибке входа,
+	// *lockout.LockedError, если попытки для email или IP клиента временно заблокированы.
+	Login(ctx context.Context, email, password string, client session.Client) (*LoginResult, error)
+	// VerifyMFA завершает вход кодом второго фактора. mfa.ErrInvalidCode при неверном коде,
+	// ErrInvalidMFAChallenge, если вход истёк, завершён или неверных кодов было слишком много.
+	VerifyMFA(ctx context.Context, mfaToken, code string, client session.Client) (*LoginResult, error)
+	// LoginVerified завершает вход пользователя, которого уже опознал внешний провайдер. Как и Login,
+	// при включённом втором факторе вместо сессии возвращает MFAToken для VerifyMFA.
+	LoginVerified(ctx context.Context, u *user.User, client session.Client) (*LoginResult, error)
+	Logout(ctx context.Context, token string) error
+	// ForgotPassword отправляет ссылку сброса, если email принадлежит активному пользователю.
+	// Для неизвестного email ошибка не возвращается.
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword гасит токен из ссылки, задаёт новый пароль, закрывает все сессии пользователя
+	// и обнуляет счётчик неудачных входов в его учётную запись.
+	ResetPassword(ctx context.Context, token, newPassword string) error
+}
+
+type service struct {
+	users      UserSource
+	sessions   session.Service
+	resets     ResetRepository
+	mailer     mailer.Mailer
+	passwords  user.PasswordValidator
+	hasher     user.PasswordHasher
+	hashes     HashStore
+	guard      user.AttemptGuard
+	mfa        MFAVerifier
+	challenges ChallengeRepository
+	cfg        Config
+
+	// dummyHash сравнивается с паролем, когда пользователь не найден, чтобы время ответа не выдавало наличие email.
+	// Создаётся текущим хешером, поэтому стоимость проверки совпадает с проверкой настоящего пароля.
+	dummyHash func() string
+}
+
+func NewService(users UserSource, sessions session.Service, resets ResetRepository, m mailer.Mailer, passwords user.PasswordValidator, hasher user.PasswordHasher, hashes HashStore, guard user.AttemptGuard, mfaVerifier MFAVerifier, challenges ChallengeRepository, cfg Config) Service {
+	s := &service{users: users, sessions: sessions, resets: resets, mailer: m, passwords: passwords, hasher: hasher, hashes: hashes, guard: guard, mfa: mfaVerifier, challenges: challenges, cfg: cfg}
+	s.dummyHash = sync.OnceValue(func() string {
+		hash, err := hasher.Hash("dummy-password")
+		if err != nil {
+			panic(fmt.Sprintf("failed to generate dummy password hash: %v", err))
+		}
+		return hash
+	})
+	return s
+}
+
+func (s *service) Login(ctx context.Context, email, password string, client session.Client) (*LoginResult, error) {
+	// Блокировка проверяется до поиска пользователя и одинакова для зарегистрированных и неизвестных email
+	if err := s.guard.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
+	u, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			_, _, _ = s.hasher.Verify(password, s.dummyHash())
+			return nil, s.loginFailed(ctx, email, client)
+		}
+		return nil, err
+	}
+
+	ok, needsRehash, err := s.hasher.Verify(password, u.PasswordHash)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", u.ID).Msg("Login failed: stored password hash cannot be verified")
+		return nil, ErrInvalidCredentials
+	}
+	if !ok {
+		log.Info().Stringer("user_id", u.ID).Msg("Login failed: wrong password")
+		return nil, s.loginFailed(ctx, email, client)
+	}
+	if u.Status != user.StatusActive {
+		log.Info().Stringer("user_id", u.ID).Str("status", string(u.Status)).Msg("Login failed: user is not active")
+		return nil, ErrInvalidCredentials
+	}
+	if needsRehash {
+		s.rehash(ctx, u, password)
+	}
+
+	return s.LoginVerified(ctx, u, client)
+}
+
+func (s *service) LoginVerified(ctx context.Context, u *user.User, client session.Client) (*LoginResult, error) {
+	mfaEnabled, err := s.mfa.Enabled(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		// Счётчик неудачных попыток сбрасывается только после второго фактора,
+		// иначе знание пароля позволяло бы перебирать коды без блокировки
+		return s.startChallenge(ctx, u.ID)
+	}
+
+	return s.startSession(ctx, u, client)
+}
+
+func (s *service) VerifyMFA(ctx context.Context, mfaToken, code string, client session.Client) (*LoginResult, error) {
+	if mfaToken == "" {
+		return nil, ErrInvalidMFAChallenge
+	}
+	hash := tokens.Hash(mfaToken)
+
+	userID, err := s.challenges.Find(ctx, hash, maxMFAAttempts)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+	if u.Status != user.StatusActive {
+		log.Info().Stringer("user_id", userID).Str("status", string(u.Status)).Msg("Login failed: user is not active")
+		return nil, ErrInvalidMFAChallenge
+	}
+	if err := s.guard.Check(ctx, u.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfa.Verify(ctx, userID, code); err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			log.Info().Stringer("user_id", userID).Msg("Login failed: wrong two-factor code")
+			if err := s.challenges.AddAttempt(ctx, hash); err != nil {
+				log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to record two-factor attempt")
+			}
+			if err := s.guard.RecordFailure(ctx, u.Email, client.IP); err != nil {
+				log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to record failed login attempt")
+			}
+		}
+		return nil, err
+	}
+
+	if _, err := s.challenges.Consume(ctx, hash, maxMFAAttempts); err != nil {
+		return nil, err
+	}
+	return s.startSession(ctx, u, client)
+}
+
+// startChallenge выдаёт токен, с которым клиент отправит код второго фактора.
+func (s *service) startChallenge(ctx context.Context, userID uuid.UUID) (*LoginResult, error) {
+	raw, hash, err := tokens.Generate()
+	if err != nil {
+		return nil, err
+	}
+	challengeID, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate mfa challenge id: %w", err)
+	}
+
+	challenge := &Challenge{
+		ID:        challengeID,
+		UserID:    userID,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(s.cfg.MFAChallengeTTL),
+	}
+	if err := s.challenges.Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+
+	log.Info().Stringer("user_id", userID).Msg("First factor accepted, waiting for two-factor code")
+	return &LoginResult{MFAToken: raw, MFAExpiresAt: challenge.ExpiresAt}, nil
+}
+
+func (s *service) startSession(ctx context.Context, u *user.User, client session.Client) (*LoginResult, error) {
+	if err := s.guard.RecordSuccess(ctx, u.Email); err != nil {
+		log.Error().Err(err).Stringer("user_id", u.ID).Msg("Failed to reset failed login attempts")
+	}
+
+	token, sess, err := s.sessions.Start(ctx, u.ID, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{Token: token, Session: sess}, nil
+}
+
+// loginFailed учитывает неверный пароль и возвращает ErrInvalidCredentials. Ошибка учёта только логируется,
+// чтобы сбой счётчика не менял ответ на неверный пароль.
+func (s *service) loginFailed(ctx context.Context, email string, client session.Client) error {
+	if err := s.guard.RecordFailure(ctx, email, client.IP); err != nil {
+		log.Error().Err(err).Msg("Failed to record failed login attempt")
+	}
+	return ErrInvalidCredentials
+}
+
+// rehash пересчитывает хеш пароля текущими параметрами. Вход уже успешен, поэтому ошибка только логируется:
+// пароль останется в старом формате до следующего входа.
+func (s *service) rehash(ctx context.Context, u *user.User, password string) {
+	newHash, err := s.hasher.Hash(password)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", u.ID).Msg("Failed to rehash password")
+		return
+	}
+	replaced, err := s.hashes.ReplacePasswordHash(ctx, u.ID, u.PasswordHash, newHash)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", u.ID).Msg("Failed to store rehashed password")
+		return
+	}
+	if replaced {
+		log.Info().Stringer("user_id", u.ID).Msg("Password rehashed with current parameters")
+	}
+}
+
+func (s *service) Logout(ctx context.Context, token string) error {
+	return s.sessions.End(ctx, token)
+}
+
+func (s *service) ForgotPassword(ctx context.Context, email string) error {
+	u, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			log.Info().Msg("Password reset requested for unknown email")
+			return nil
+		}
+		return err
+	}
+	if u.Status != user.StatusActive {
+		log.Info().Stringer("user_id", u.ID).Str("status", string(u.Status)).Msg("Password reset requested for inactive user")
+		return nil
+	}
+
+	issuedAt, err := s.resets.LatestIssuedAt(ctx, u.ID)
+	if err != nil {
+		return err
+	}
+	if issuedAt != nil && time.Since(*issuedAt) < s.cfg.ResetCooldown {
+		log.Info().Stringer("user_id", u.ID).Msg("Password reset email was sent recently, skipping")
+		return nil
+	}
+
+	raw, hash, err := tokens.Generate()
+	if err != nil {
+		return err
+	}
+	tokenID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to generate password reset token id: %w", err)
+	}
+
+	token := &ResetToken{
+		ID:        tokenID,
+		UserID:    u.ID,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(s.cfg.ResetTTL),
+	}
+	if err := s.resets.Issue(ctx, token); err != nil {
+		return err
+	}
+
+	if err := s.mailer.Send(ctx, s.message(u, raw, token.ExpiresAt)); err != nil {
+		return fmt.Errorf("failed to send password reset email to user '%s': %w", u.ID, err)
+	}
+
+	log.Info().Stringer("user_id", u.ID).Time("expires_at", token.ExpiresAt).Msg("Password reset email sent")
+	return nil
+}
+
+func (s *service) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if token == "" {
+		return ErrInvalidResetToken
+	}
+	hash := tokens.Hash(token)
+
+	// Политика проверяется до погашения токена: отклонённый пароль можно исправить по той же ссылке
+	userID, err := s.resets.FindUser(ctx, hash)
+	if err != nil {
+		return err
+	}
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.passwords.Validate(newPassword, u.Email, u.FirstName, u.LastName); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		return fmt.Errorf("internal error hashing password: %w", err)
+	}
+
+	userID, err = s.resets.Consume(ctx, hash, passwordHash)
+	if err != nil {
+		return err
+	}
+	// Владелец доказал доступ к почте: задержка входа, набранная чужими попытками, снимается
+	if err := s.guard.Unlock(ctx, u.Email); err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to reset failed login attempts after password reset")
+	}
+
+	log.Info().Stringer("user_id", userID).Msg("Password reset, all sessions revoked")
+	return nil
+}
+
+func (s *service) message(u *user.User, token string, expiresAt time.Time) mailer.Message {
+	separator := "?"
+	if strings.Contains(s.cfg.ResetURL, "?") {
+		separator = "&"
+	}
+	link := s.cfg.ResetURL + separator + "token=" + token
+
+	return mailer.Message{
+		To:      u.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hello, %s!\n\nSomeone requested a password reset for your account. To choose a new password, open this link:\n%s\n\n"+
+			"The link is valid until %s. If you did not request a reset, ignore this email: your password stays the same.\n",
+			u.FirstName, link, expiresAt.UTC().Format("2006-01-02 15:04 MST")),
+	}
+}
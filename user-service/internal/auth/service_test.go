@@ -0,0 +1,554 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mailer"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type MockUserSource struct {
+	mock.Mock
+}
+
+func (m *MockUserSource) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUserSource) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) Start(ctx context.Context, userID uuid.UUID, client session.Client) (string, *session.Session, error) {
+	args := m.Called(ctx, userID, client)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*session.Session), args.Error(2)
+}
+
+func (m *MockSessionService) Authenticate(ctx context.Context, token string) (*session.Session, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*session.Session), args.Error(1)
+}
+
+func (m *MockSessionService) End(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+type MockResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockResetRepository) Issue(ctx context.Context, token *ResetToken) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockResetRepository) LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockResetRepository) FindUser(ctx context.Context, hash []byte) (uuid.UUID, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockResetRepository) Consume(ctx context.Context, hash []byte, passwordHash string) (uuid.UUID, error) {
+	args := m.Called(ctx, hash, passwordHash)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+type MockHashStore struct {
+	mock.Mock
+}
+
+func (m *MockHashStore) ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error) {
+	args := m.Called(ctx, id, oldHash, newHash)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockAttemptGuard struct {
+	mock.Mock
+}
+
+func (m *MockAttemptGuard) Check(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) RecordFailure(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) RecordSuccess(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) Unlock(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+type MockMFAVerifier struct {
+	mock.Mock
+}
+
+func (m *MockMFAVerifier) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAVerifier) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+// noMFA возвращает проверку второго фактора для пользователей, у которых он не включён.
+func noMFA() *MockMFAVerifier {
+	verifier := new(MockMFAVerifier)
+	verifier.On("Enabled", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+	return verifier
+}
+
+type MockChallengeRepository struct {
+	mock.Mock
+}
+
+func (m *MockChallengeRepository) Create(ctx context.Context, challenge *Challenge) error {
+	args := m.Called(ctx, challenge)
+	return args.Error(0)
+}
+
+func (m *MockChallengeRepository) Find(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error) {
+	args := m.Called(ctx, hash, maxAttempts)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockChallengeRepository) AddAttempt(ctx context.Context, hash []byte) error {
+	args := m.Called(ctx, hash)
+	return args.Error(0)
+}
+
+func (m *MockChallengeRepository) Consume(ctx context.Context, hash []byte, maxAttempts int) (uuid.UUID, error) {
+	args := m.Called(ctx, hash, maxAttempts)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+// allowAllGuard возвращает защиту от подбора, которая пропускает любые попытки.
+func allowAllGuard() *MockAttemptGuard {
+	guard := new(MockAttemptGuard)
+	guard.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	guard.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	guard.On("RecordSuccess", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return guard
+}
+
+var testPasswords = passwords.NewValidator(passwords.Policy{MinLength: 8, MaxLength: 72, DisallowPersonalInfo: true}, nil)
+
+var testHasher, _ = passwords.NewHasher(passwords.HashParams{Algorithm: passwords.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+
+var testConfig = Config{
+	ResetTTL:        time.Hour,
+	ResetCooldown:   time.Minute,
+	ResetURL:        "http://shop.local/reset-password",
+	MFAChallengeTTL: 5 * time.Minute,
+}
+
+// newFileSink возвращает почтовый ящик в каталоге теста и функцию чтения отправленных писем.
+func newFileSink(t *testing.T) (mailer.Mailer, func() []string) {
+	t.Helper()
+	dir := t.TempDir()
+	sink, err := mailer.NewFileMailer(dir, "noreply@shop.local")
+	require.NoError(t, err)
+
+	return sink, func() []string {
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		require.NoError(t, err)
+		mails := make([]string, 0, len(files))
+		for _, file := range files {
+			content, err := os.ReadFile(file)
+			require.NoError(t, err)
+			mails = append(mails, string(content))
+		}
+		return mails
+	}
+}
+
+func hashPassword(t *testing.T, password string) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+var tokenPattern = regexp.MustCompile(`token=([A-Za-z0-9_-]+)`)
+
+func TestService_ForgotPassword_SendsResetLink(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockResets := new(MockResetRepository)
+	sink, sentMails := newFileSink(t)
+	svc := NewService(mockUsers, new(MockSessionService), mockResets, sink, testPasswords, testHasher, new(MockHashStore), allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", FirstName: "Jane", Status: user.StatusActive}
+
+	var issued *ResetToken
+	mockUsers.On("GetUserByEmail", ctx, u.Email).Return(u, nil).Once()
+	mockResets.On("LatestIssuedAt", ctx, u.ID).Return(nil, nil).Once()
+	mockResets.On("Issue", ctx, mock.AnythingOfType("*auth.ResetToken")).Run(func(args mock.Arguments) {
+		issued = args.Get(1).(*ResetToken)
+	}).Return(nil).Once()
+
+	require.NoError(t, svc.ForgotPassword(ctx, u.Email))
+
+	mails := sentMails()
+	require.Len(t, mails, 1)
+	assert.Contains(t, mails[0], "To: jane@example.com")
+	match := tokenPattern.FindStringSubmatch(mails[0])
+	require.NotNil(t, match)
+	assert.Equal(t, tokens.Hash(match[1]), issued.Hash)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiresAt, time.Minute)
+}
+
+func TestService_ForgotPassword_SilentForUnknownAndInactive(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockResets := new(MockResetRepository)
+	sink, sentMails := newFileSink(t)
+	svc := NewService(mockUsers, new(MockSessionService), mockResets, sink, testPasswords, testHasher, new(MockHashStore), allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+
+	mockUsers.On("GetUserByEmail", ctx, "ghost@example.com").Return(nil, user.ErrNotFound).Once()
+	mockUsers.On("GetUserByEmail", ctx, "blocked@example.com").
+		Return(&user.User{ID: uuid.Must(uuid.NewV4()), Status: user.StatusDeactivated}, nil).Once()
+
+	require.NoError(t, svc.ForgotPassword(ctx, "ghost@example.com"))
+	require.NoError(t, svc.ForgotPassword(ctx, "blocked@example.com"))
+
+	assert.Empty(t, sentMails())
+	mockResets.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+}
+
+func TestService_ForgotPassword_Cooldown(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockResets := new(MockResetRepository)
+	sink, sentMails := newFileSink(t)
+	svc := NewService(mockUsers, new(MockSessionService), mockResets, sink, testPasswords, testHasher, new(MockHashStore), allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Status: user.StatusActive}
+	recent := time.Now().Add(-10 * time.Second)
+
+	mockUsers.On("GetUserByEmail", ctx, u.Email).Return(u, nil).Once()
+	mockResets.On("LatestIssuedAt", ctx, u.ID).Return(&recent, nil).Once()
+
+	require.NoError(t, svc.ForgotPassword(ctx, u.Email))
+	assert.Empty(t, sentMails())
+	mockResets.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockResets := new(MockResetRepository)
+	mockGuard := new(MockAttemptGuard)
+	svc := NewService(mockUsers, new(MockSessionService), mockResets, mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), mockGuard, noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", FirstName: "Jane", Status: user.StatusActive}
+
+	// Сброс пароля снимает задержку входа, которую могли набрать чужие попытки
+	mockGuard.On("Unlock", ctx, u.Email).Return(nil).Once()
+
+	mockResets.On("FindUser", ctx, tokens.Hash("good-token")).Return(u.ID, nil).Once()
+	mockUsers.On("GetUserByID", ctx, u.ID).Return(u, nil).Once()
+	mockResets.On("Consume", ctx, tokens.Hash("good-token"), mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+	})).Return(u.ID, nil).Once()
+	mockResets.On("FindUser", ctx, tokens.Hash("used-token")).Return(uuid.Nil, ErrInvalidResetToken).Once()
+
+	require.NoError(t, svc.ResetPassword(ctx, "good-token", "new-password"))
+	require.ErrorIs(t, svc.ResetPassword(ctx, "used-token", "new-password"), ErrInvalidResetToken)
+	require.ErrorIs(t, svc.ResetPassword(ctx, "", "new-password"), ErrInvalidResetToken)
+	mockResets.AssertExpectations(t)
+	mockGuard.AssertExpectations(t)
+}
+
+func TestService_ResetPassword_PolicyViolationKeepsToken(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockResets := new(MockResetRepository)
+	svc := NewService(mockUsers, new(MockSessionService), mockResets, mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane.doe@example.com", FirstName: "Jane", Status: user.StatusActive}
+
+	mockResets.On("FindUser", ctx, tokens.Hash("good-token")).Return(u.ID, nil).Once()
+	mockUsers.On("GetUserByID", ctx, u.ID).Return(u, nil).Once()
+
+	err := svc.ResetPassword(ctx, "good-token", "jane.doe-forever")
+	var policyErr *passwords.PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	mockResets.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Login(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockSessions := new(MockSessionService)
+	mockHashes := new(MockHashStore)
+	svc := NewService(mockUsers, mockSessions, new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, mockHashes, allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	client := session.Client{UserAgent: "test", IP: "10.0.0.1"}
+	active := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", PasswordHash: hashPassword(t, "secret-pass"), Status: user.StatusActive}
+	blocked := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "blocked@example.com", PasswordHash: hashPassword(t, "secret-pass"), Status: user.StatusDeactivated}
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: active.ID}
+
+	mockUsers.On("GetUserByEmail", ctx, active.Email).Return(active, nil)
+	mockUsers.On("GetUserByEmail", ctx, blocked.Email).Return(blocked, nil)
+	mockUsers.On("GetUserByEmail", ctx, "ghost@example.com").Return(nil, user.ErrNotFound)
+	mockSessions.On("Start", ctx, active.ID, client).Return("session-token", sess, nil).Once()
+
+	result, err := svc.Login(ctx, active.Email, "secret-pass", client)
+	require.NoError(t, err)
+	assert.Equal(t, "session-token", result.Token)
+	assert.Equal(t, sess, result.Session)
+	assert.Empty(t, result.MFAToken)
+
+	_, err = svc.Login(ctx, active.Email, "wrong-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Login(ctx, blocked.Email, "secret-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Login(ctx, "ghost@example.com", "secret-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	mockSessions.AssertExpectations(t)
+	// Хеши созданы с текущими параметрами, пересчитывать нечего
+	mockHashes.AssertNotCalled(t, "ReplacePasswordHash", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Login_RehashesLegacyHash(t *testing.T) {
+	argon2Hasher, err := passwords.NewHasher(passwords.HashParams{
+		Algorithm: passwords.AlgorithmArgon2id,
+		Argon2:    passwords.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	require.NoError(t, err)
+
+	mockUsers := new(MockUserSource)
+	mockSessions := new(MockSessionService)
+	mockHashes := new(MockHashStore)
+	svc := NewService(mockUsers, mockSessions, new(MockResetRepository), mailer.NewLogMailer(), testPasswords, argon2Hasher, mockHashes, allowAllGuard(), noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	client := session.Client{UserAgent: "test", IP: "10.0.0.1"}
+
+	// Пароль сохранён в bcrypt до перехода на argon2id
+	legacyHash := hashPassword(t, "secret-pass")
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", PasswordHash: legacyHash, Status: user.StatusActive}
+	mockUsers.On("GetUserByEmail", ctx, u.Email).Return(u, nil)
+	mockSessions.On("Start", ctx, u.ID, client).Return("session-token", &session.Session{UserID: u.ID}, nil)
+	mockHashes.On("ReplacePasswordHash", ctx, u.ID, legacyHash, mock.MatchedBy(func(hash string) bool {
+		ok, needsRehash, err := argon2Hasher.Verify("secret-pass", hash)
+		return ok && !needsRehash && err == nil
+	})).Return(true, nil).Once()
+
+	_, err = svc.Login(ctx, u.Email, "secret-pass", client)
+	require.NoError(t, err)
+	mockHashes.AssertExpectations(t)
+
+	// Ошибка сохранения нового хеша не мешает войти
+	mockHashes.On("ReplacePasswordHash", ctx, u.ID, legacyHash, mock.Anything).Return(false, errors.New("db is down")).Once()
+	_, err = svc.Login(ctx, u.Email, "secret-pass", client)
+	require.NoError(t, err)
+
+	// Неверный пароль хеш не пересчитывает
+	_, err = svc.Login(ctx, u.Email, "wrong-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	mockHashes.AssertNumberOfCalls(t, "ReplacePasswordHash", 2)
+}
+
+func TestService_Login_AttemptGuard(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockSessions := new(MockSessionService)
+	mockGuard := new(MockAttemptGuard)
+	svc := NewService(mockUsers, mockSessions, new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), mockGuard, noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	client := session.Client{UserAgent: "test", IP: "10.0.0.1"}
+	active := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", PasswordHash: hashPassword(t, "secret-pass"), Status: user.StatusActive}
+
+	mockUsers.On("GetUserByEmail", ctx, active.Email).Return(active, nil)
+	mockUsers.On("GetUserByEmail", ctx, "ghost@example.com").Return(nil, user.ErrNotFound)
+	mockGuard.On("Check", ctx, mock.Anything, client.IP).Return(nil)
+
+	// Неверный пароль и неизвестный email учитываются одинаково
+	mockGuard.On("RecordFailure", ctx, active.Email, client.IP).Return(nil).Once()
+	mockGuard.On("RecordFailure", ctx, "ghost@example.com", client.IP).Return(nil).Once()
+	_, err := svc.Login(ctx, active.Email, "wrong-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = svc.Login(ctx, "ghost@example.com", "secret-pass", client)
+	require.ErrorIs(t, err, ErrInvalidCredentials)
+
+	// Успешный вход сбрасывает счётчик учётной записи
+	mockGuard.On("RecordSuccess", ctx, active.Email).Return(nil).Once()
+	mockSessions.On("Start", ctx, active.ID, client).Return("session-token", &session.Session{UserID: active.ID}, nil).Once()
+	_, err = svc.Login(ctx, active.Email, "secret-pass", client)
+	require.NoError(t, err)
+
+	mockGuard.AssertExpectations(t)
+}
+
+func TestService_Login_LockedSkipsPasswordCheck(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockGuard := new(MockAttemptGuard)
+	svc := NewService(mockUsers, new(MockSessionService), new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), mockGuard, noMFA(), new(MockChallengeRepository), testConfig)
+	ctx := context.Background()
+	client := session.Client{IP: "10.0.0.1"}
+	locked := &lockout.LockedError{RetryAfter: time.Minute}
+
+	mockGuard.On("Check", ctx, "jane@example.com", client.IP).Return(locked).Once()
+	mockGuard.On("Check", ctx, "ghost@example.com", client.IP).Return(locked).Once()
+
+	// Ответ одинаков для существующего и неизвестного email: пользователь даже не ищется
+	_, err := svc.Login(ctx, "jane@example.com", "secret-pass", client)
+	require.ErrorIs(t, err, lockout.ErrTooManyAttempts)
+	_, err = svc.Login(ctx, "ghost@example.com", "secret-pass", client)
+	require.ErrorIs(t, err, lockout.ErrTooManyAttempts)
+
+	mockUsers.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	mockGuard.AssertNotCalled(t, "RecordFailure", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Login_RequiresSecondFactor(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	mockSessions := new(MockSessionService)
+	mockMFA := new(MockMFAVerifier)
+	mockChallenges := new(MockChallengeRepository)
+	mockGuard := new(MockAttemptGuard)
+	svc := NewService(mockUsers, mockSessions, new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), mockGuard, mockMFA, mockChallenges, testConfig)
+	ctx := context.Background()
+	client := session.Client{UserAgent: "test", IP: "10.0.0.1"}
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", PasswordHash: hashPassword(t, "secret-pass"), Status: user.StatusActive}
+
+	mockUsers.On("GetUserByEmail", ctx, u.Email).Return(u, nil)
+	mockUsers.On("GetUserByID", ctx, u.ID).Return(u, nil)
+	mockGuard.On("Check", ctx, u.Email, client.IP).Return(nil)
+	mockMFA.On("Enabled", ctx, u.ID).Return(true, nil)
+
+	var issued *Challenge
+	mockChallenges.On("Create", ctx, mock.AnythingOfType("*auth.Challenge")).Run(func(args mock.Arguments) {
+		issued = args.Get(1).(*Challenge)
+	}).Return(nil).Once()
+
+	// Верный пароль не открывает сессию и не сбрасывает счётчик неудачных попыток
+	result, err := svc.Login(ctx, u.Email, "secret-pass", client)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.MFAToken)
+	assert.Empty(t, result.Token)
+	assert.Nil(t, result.Session)
+	assert.Equal(t, u.ID, issued.UserID)
+	assert.Equal(t, tokens.Hash(result.MFAToken), issued.Hash, "only the token hash is stored")
+	mockSessions.AssertNotCalled(t, "Start", mock.Anything, mock.Anything, mock.Anything)
+	mockGuard.AssertNotCalled(t, "RecordSuccess", mock.Anything, mock.Anything)
+
+	mockChallenges.On("Find", ctx, issued.Hash, maxMFAAttempts).Return(u.ID, nil)
+
+	// Неверный код учитывается и во входе, и в защите от подбора
+	mockMFA.On("Verify", ctx, u.ID, "000000").Return(mfa.ErrInvalidCode).Once()
+	mockChallenges.On("AddAttempt", ctx, issued.Hash).Return(nil).Once()
+	mockGuard.On("RecordFailure", ctx, u.Email, client.IP).Return(nil).Once()
+	_, err = svc.VerifyMFA(ctx, result.MFAToken, "000000", client)
+	require.ErrorIs(t, err, mfa.ErrInvalidCode)
+
+	mockMFA.On("Verify", ctx, u.ID, "123456").Return(nil).Once()
+	mockChallenges.On("Consume", ctx, issued.Hash, maxMFAAttempts).Return(u.ID, nil).Once()
+	mockGuard.On("RecordSuccess", ctx, u.Email).Return(nil).Once()
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: u.ID}
+	mockSessions.On("Start", ctx, u.ID, client).Return("session-token", sess, nil).Once()
+
+	result, err = svc.VerifyMFA(ctx, result.MFAToken, "123456", client)
+	require.NoError(t, err)
+	assert.Equal(t, "session-token", result.Token)
+	assert.Equal(t, sess, result.Session)
+
+	mockMFA.AssertExpectations(t)
+	mockChallenges.AssertExpectations(t)
+	mockGuard.AssertExpectations(t)
+}
+
+func TestService_LoginVerified(t *testing.T) {
+	mockSessions := new(MockSessionService)
+	mockMFA := new(MockMFAVerifier)
+	mockChallenges := new(MockChallengeRepository)
+	svc := NewService(new(MockUserSource), mockSessions, new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), allowAllGuard(), mockMFA, mockChallenges, testConfig)
+	ctx := context.Background()
+	client := session.Client{UserAgent: "test", IP: "10.0.0.1"}
+	plain := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Status: user.StatusActive}
+	protected := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "john@example.com", Status: user.StatusActive}
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: plain.ID}
+
+	mockMFA.On("Enabled", ctx, plain.ID).Return(false, nil).Once()
+	mockMFA.On("Enabled", ctx, protected.ID).Return(true, nil).Once()
+	mockSessions.On("Start", ctx, plain.ID, client).Return("session-token", sess, nil).Once()
+	mockChallenges.On("Create", ctx, mock.MatchedBy(func(challenge *Challenge) bool {
+		return challenge.UserID == protected.ID
+	})).Return(nil).Once()
+
+	result, err := svc.LoginVerified(ctx, plain, client)
+	require.NoError(t, err)
+	assert.Equal(t, sess, result.Session)
+
+	// Вход через внешний провайдер не обходит второй фактор
+	result, err = svc.LoginVerified(ctx, protected, client)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.MFAToken)
+	assert.Nil(t, result.Session)
+
+	mockMFA.AssertExpectations(t)
+	mockSessions.AssertExpectations(t)
+	mockChallenges.AssertExpectations(t)
+}
+
+func TestService_VerifyMFA_InvalidChallenge(t *testing.T) {
+	mockChallenges := new(MockChallengeRepository)
+	mockMFA := new(MockMFAVerifier)
+	svc := NewService(new(MockUserSource), new(MockSessionService), new(MockResetRepository), mailer.NewLogMailer(), testPasswords, testHasher, new(MockHashStore), allowAllGuard(), mockMFA, mockChallenges, testConfig)
+	ctx := context.Background()
+
+	_, err := svc.VerifyMFA(ctx, "", "123456", session.Client{})
+	require.ErrorIs(t, err, ErrInvalidMFAChallenge)
+
+	mockChallenges.On("Find", ctx, tokens.Hash("expired-token"), maxMFAAttempts).Return(uuid.Nil, ErrInvalidMFAChallenge).Once()
+	_, err = svc.VerifyMFA(ctx, "expired-token", "123456", session.Client{})
+	require.ErrorIs(t, err, ErrInvalidMFAChallenge)
+	mockMFA.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -1,15 +1,20 @@
 package config
 
 import (
+	"encoding/base64"
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type AppConfig struct {
-	Port string
+	Port           string
+	TrustedProxies []netip.Prefix // Прокси, чьим X-Forwarded-For и X-Real-IP можно верить. Пустой список - не верить никому
 }
 
 type PostgresConfig struct {
@@ -25,9 +30,154 @@ type PostgresConfig struct {
 	MigrationsPath  string
 }
 
+// GRPCConfig задаёт gRPC сервер, который работает рядом с HTTP API на отдельном порту.
+type GRPCConfig struct {
+	Port           string
+	RequestTimeout time.Duration // Максимальное время обработки одного вызова
+	AuthToken      string        `json:"-"` // Общий секрет внутренних клиентов, обязателен
+}
+
+// EventsConfig задаёт брокер доменных событий.
+type EventsConfig struct {
+	NATSURL       string        `json:"-"` // Пустой - события доставляются только внутри процесса. Может содержать учётные данные
+	Stream        string        // Поток JetStream, общий для всех сервисов
+	RelayInterval time.Duration // Как часто отправлять в брокер события из event_outbox
+}
+
+// OrderServiceConfig задаёт HTTP API order-service: проверку активных заказов и выгрузку заказов пользователя.
+type OrderServiceConfig struct {
+	URL     string
+	Timeout time.Duration
+}
+
+// InternalAPIConfig задаёт токен маршрутов /internal, которыми сервисы вызывают друг друга.
+type InternalAPIConfig struct {
+	Token string `json:"-"` // Общий для всех сервисов; с пустым маршруты /internal отвечают 503. Не попадает в лог конфигурации
+}
+
+// MailConfig задаёт отправку писем пользователям.
+type MailConfig struct {
+	Driver       string // smtp - почтовый сервер, file - файлы .eml в Dir, log - только запись в лог
+	From         string
+	Dir          string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string `json:"-"`
+}
+
+// EmailVerificationConfig задаёт ссылки подтверждения email.
+type EmailVerificationConfig struct {
+	TTL            time.Duration // Сколько действует ссылка из письма
+	ResendCooldown time.Duration // Минимальный интервал между письмами одному пользователю
+	LinkURL        string        // Страница подтверждения, к которой добавляется параметр token
+}
+
+// AuthConfig задаёт сессии, открываемые при входе, и ссылки сброса пароля.
+type AuthConfig struct {
+	SessionTTL    time.Duration // Сколько действует сессия после входа
+	ResetTTL      time.Duration // Сколько действует ссылка сброса пароля
+	ResetCooldown time.Duration // Минимальный интервал между письмами сброса одному пользователю
+	ResetURL      string        // Страница ввода нового пароля, к которой добавляется параметр token
+}
+
+// LoginLockoutConfig задаёт защиту от подбора пароля: пороги ошибок по учётной записи и по IP,
+// прогрессивную задержку и временную блокировку IP. Учётная запись только замедляется.
+type LoginLockoutConfig struct {
+	AccountDelayAfter int
+	IPDelayAfter      int
+	IPLockAfter       int
+	BaseDelay         time.Duration // Задержка после первой ошибки сверх порога, дальше удваивается до MaxDelay
+	MaxDelay          time.Duration
+	LockoutDuration   time.Duration // Блокировка IP
+	FailureWindow     time.Duration // Ошибки старше окна не учитываются
+}
+
+// MFAConfig задаёт второй фактор входа.
+type MFAConfig struct {
+	EncryptionKey []byte        `json:"-"` // AES-256 ключ шифрования секретов TOTP в БД. Не попадает в лог конфигурации
+	Issuer        string        // Название сервиса в приложении-аутентификаторе
+	ChallengeTTL  time.Duration // Сколько после пароля ждать код второго фактора
+}
+
+// AccessTokenConfig задаёт токены доступа к order-service, которые выдаются по сессии.
+type AccessTokenConfig struct {
+	Secret []byte        `json:"-"` // Общий с order-service ключ подписи HS256, не короче 32 байт. Не попадает в лог конфигурации
+	TTL    time.Duration // Срок жизни токена; роли в нём не обновляются до истечения
+	// ImpersonationTTL - срок жизни токена поддержки от имени пользователя, не больше часа
+	ImpersonationTTL time.Duration
+}
+
+// OIDCConfig задаёт вход через корпоративный OpenID Connect провайдер. Пустой IssuerURL отключает вход.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string `json:"-"` // Пустой для публичного клиента. Не попадает в лог конфигурации
+	RedirectURL  string // Адрес /auth/oidc/callback сервиса, зарегистрированный у провайдера
+	Scopes       []string
+	StateTTL     time.Duration // Сколько пользователь может провести на странице входа провайдера
+	HTTPTimeout  time.Duration // Таймаут запросов к провайдеру
+}
+
+// PasswordPolicyConfig задаёт требования к новым паролям при регистрации, смене и сбросе пароля.
+type PasswordPolicyConfig struct {
+	MinLength            int
+	MaxLength            int // Не больше 72: длиннее bcrypt не принимает
+	RequireUppercase     bool
+	RequireLowercase     bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool   // Запрещает email и имя пользователя внутри пароля
+	BreachedListFile     string // Файл SHA-1 хешей скомпрометированных паролей; пустой отключает проверку
+}
+
+// PasswordHashConfig задаёт алгоритм хеширования новых паролей. Хеши старого формата проверяются
+// и пересчитываются текущими параметрами при следующем входе пользователя.
+type PasswordHashConfig struct {
+	Algorithm         string // bcrypt или argon2id
+	BcryptCost        int
+	Argon2MemoryKiB   int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+// AccountsConfig задаёт жизненный цикл учётных записей и доступ к административным маршрутам.
+type AccountsConfig struct {
+	DeletedRetention time.Duration // Сколько удалённый пользователь может быть восстановлен, после чего удаляется окончательно
+	PurgeInterval    time.Duration // Как часто запускается очистка удалённых пользователей
+	PurgeBatchSize   int
+	AdminToken       string `json:"-"` // Токен административных маршрутов. Не попадает в лог конфигурации
+}
+
+// ExportConfig задаёт асинхронные выгрузки персональных данных.
+type ExportConfig struct {
+	Dir          string        // Каталог архивов, общий для всех реплик
+	TTL          time.Duration // Сколько хранится готовый архив
+	Timeout      time.Duration // Максимальное время сборки одного архива
+	PollInterval time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration // Пауза после первой временной ошибки, дальше удваивается
+	BackoffMax   time.Duration
+}
+
 type Config struct {
-	App      AppConfig
-	Postgres PostgresConfig
+	App            AppConfig
+	Postgres       PostgresConfig
+	GRPC           GRPCConfig
+	Events         EventsConfig
+	OrderService   OrderServiceConfig
+	Internal       InternalAPIConfig
+	Accounts       AccountsConfig
+	Export         ExportConfig
+	Mail           MailConfig
+	Verification   EmailVerificationConfig
+	Auth           AuthConfig
+	PasswordPolicy PasswordPolicyConfig
+	PasswordHash   PasswordHashConfig
+	LoginLockout   LoginLockoutConfig
+	MFA            MFAConfig
+	OIDC           OIDCConfig
+	AccessToken    AccessTokenConfig
 }
 
 func NewConfig() (*Config, error) {
@@ -40,6 +190,9 @@ func NewConfig() (*Config, error) {
 	if cfg.App.Port == "" {
 		return nil, errors.New("APP_PORT environment variable not set")
 	}
+	if cfg.App.TrustedProxies, err = prefixListEnv("TRUSTED_PROXIES"); err != nil {
+		return nil, err
+	}
 
 	// Загрузка конфигурации PostgreSQL
 	cfg.Postgres.Host = os.Getenv("DB_HOST")
@@ -116,5 +269,440 @@ func NewConfig() (*Config, error) {
 		cfg.Postgres.MigrationsPath = "/app/migrations"
 	}
 
+	// Загрузка конфигурации gRPC
+	cfg.GRPC.Port = os.Getenv("GRPC_PORT")
+	if cfg.GRPC.Port == "" {
+		cfg.GRPC.Port = "9081"
+	}
+
+	grpcTimeoutStr := os.Getenv("GRPC_REQUEST_TIMEOUT")
+	if grpcTimeoutStr == "" {
+		cfg.GRPC.RequestTimeout = 5 * time.Second
+	} else {
+		cfg.GRPC.RequestTimeout, err = time.ParseDuration(grpcTimeoutStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse GRPC_REQUEST_TIMEOUT '%s': %w", grpcTimeoutStr, err)
+		}
+	}
+
+	cfg.GRPC.AuthToken = os.Getenv("GRPC_AUTH_TOKEN")
+	if cfg.GRPC.AuthToken == "" {
+		return nil, errors.New("GRPC_AUTH_TOKEN must be set")
+	}
+
+	// Брокер событий
+	cfg.Events.NATSURL = os.Getenv("NATS_URL")
+	cfg.Events.Stream = os.Getenv("EVENTS_STREAM")
+	if cfg.Events.Stream == "" {
+		cfg.Events.Stream = "ECOMMERCE"
+	}
+	if cfg.Events.RelayInterval, err = positiveDurationEnv("EVENTS_RELAY_INTERVAL", time.Second); err != nil {
+		return nil, err
+	}
+
+	// Загрузка конфигурации order-service
+	cfg.OrderService.URL = os.Getenv("ORDER_SERVICE_URL")
+	if cfg.OrderService.URL == "" {
+		cfg.OrderService.URL = "http://order-service:8080"
+	}
+
+	orderTimeoutStr := os.Getenv("ORDER_SERVICE_TIMEOUT")
+	if orderTimeoutStr == "" {
+		cfg.OrderService.Timeout = 3 * time.Second
+	} else {
+		cfg.OrderService.Timeout, err = time.ParseDuration(orderTimeoutStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse ORDER_SERVICE_TIMEOUT '%s': %w", orderTimeoutStr, err)
+		}
+	}
+
+	cfg.Internal.Token = os.Getenv("INTERNAL_API_TOKEN")
+	if cfg.Internal.Token == "" {
+		return nil, errors.New("INTERNAL_API_TOKEN must be set")
+	}
+
+	// Жизненный цикл учётных записей
+	retentionStr := os.Getenv("USER_DELETED_RETENTION")
+	if retentionStr == "" {
+		cfg.Accounts.DeletedRetention = 30 * 24 * time.Hour
+	} else {
+		cfg.Accounts.DeletedRetention, err = time.ParseDuration(retentionStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse USER_DELETED_RETENTION '%s': %w", retentionStr, err)
+		}
+	}
+
+	purgeIntervalStr := os.Getenv("USER_PURGE_INTERVAL")
+	if purgeIntervalStr == "" {
+		cfg.Accounts.PurgeInterval = time.Hour
+	} else {
+		cfg.Accounts.PurgeInterval, err = time.ParseDuration(purgeIntervalStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse USER_PURGE_INTERVAL '%s': %w", purgeIntervalStr, err)
+		}
+		if cfg.Accounts.PurgeInterval <= 0 {
+			return nil, fmt.Errorf("USER_PURGE_INTERVAL must be positive, got '%s'", purgeIntervalStr)
+		}
+	}
+
+	purgeBatchStr := os.Getenv("USER_PURGE_BATCH_SIZE")
+	if purgeBatchStr == "" {
+		cfg.Accounts.PurgeBatchSize = 100
+	} else {
+		cfg.Accounts.PurgeBatchSize, err = strconv.Atoi(purgeBatchStr)
+		if err != nil || cfg.Accounts.PurgeBatchSize <= 0 {
+			return nil, fmt.Errorf("USER_PURGE_BATCH_SIZE must be a positive integer, got '%s'", purgeBatchStr)
+		}
+	}
+
+	cfg.Accounts.AdminToken = os.Getenv("ADMIN_API_TOKEN")
+	if cfg.Accounts.AdminToken == "" {
+		return nil, errors.New("ADMIN_API_TOKEN must be set")
+	}
+
+	// Выгрузки персональных данных
+	cfg.Export.Dir = os.Getenv("EXPORT_DIR")
+	if cfg.Export.Dir == "" {
+		cfg.Export.Dir = "/app/exports"
+	}
+
+	exportTTLStr := os.Getenv("EXPORT_TTL")
+	if exportTTLStr == "" {
+		cfg.Export.TTL = 24 * time.Hour
+	} else {
+		cfg.Export.TTL, err = time.ParseDuration(exportTTLStr)
+		if err != nil || cfg.Export.TTL <= 0 {
+			return nil, fmt.Errorf("EXPORT_TTL must be a positive duration, got '%s'", exportTTLStr)
+		}
+	}
+
+	exportTimeoutStr := os.Getenv("EXPORT_TIMEOUT")
+	if exportTimeoutStr == "" {
+		cfg.Export.Timeout = 10 * time.Minute
+	} else {
+		cfg.Export.Timeout, err = time.ParseDuration(exportTimeoutStr)
+		if err != nil || cfg.Export.Timeout <= 0 {
+			return nil, fmt.Errorf("EXPORT_TIMEOUT must be a positive duration, got '%s'", exportTimeoutStr)
+		}
+	}
+
+	exportPollStr := os.Getenv("EXPORT_POLL_INTERVAL")
+	if exportPollStr == "" {
+		cfg.Export.PollInterval = 5 * time.Second
+	} else {
+		cfg.Export.PollInterval, err = time.ParseDuration(exportPollStr)
+		if err != nil || cfg.Export.PollInterval <= 0 {
+			return nil, fmt.Errorf("EXPORT_POLL_INTERVAL must be a positive duration, got '%s'", exportPollStr)
+		}
+	}
+
+	exportAttemptsStr := os.Getenv("EXPORT_MAX_ATTEMPTS")
+	if exportAttemptsStr == "" {
+		cfg.Export.MaxAttempts = 3
+	} else {
+		cfg.Export.MaxAttempts, err = strconv.Atoi(exportAttemptsStr)
+		if err != nil || cfg.Export.MaxAttempts <= 0 {
+			return nil, fmt.Errorf("EXPORT_MAX_ATTEMPTS must be a positive integer, got '%s'", exportAttemptsStr)
+		}
+	}
+	if cfg.Export.BackoffBase, err = positiveDurationEnv("EXPORT_BACKOFF_BASE", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.Export.BackoffMax, err = positiveDurationEnv("EXPORT_BACKOFF_MAX", 10*time.Minute); err != nil {
+		return nil, err
+	}
+
+	// Отправка писем
+	cfg.Mail.Driver = os.Getenv("MAIL_DRIVER")
+	if cfg.Mail.Driver == "" {
+		cfg.Mail.Driver = "log"
+	}
+
+	cfg.Mail.From = os.Getenv("MAIL_FROM")
+	if cfg.Mail.From == "" {
+		cfg.Mail.From = "no-reply@example.com"
+	}
+
+	cfg.Mail.Dir = os.Getenv("MAIL_DIR")
+	if cfg.Mail.Dir == "" {
+		cfg.Mail.Dir = "/app/mail"
+	}
+
+	cfg.Mail.SMTPHost = os.Getenv("SMTP_HOST")
+	cfg.Mail.SMTPPort = os.Getenv("SMTP_PORT")
+	if cfg.Mail.SMTPPort == "" {
+		cfg.Mail.SMTPPort = "587"
+	}
+	cfg.Mail.SMTPUsername = os.Getenv("SMTP_USERNAME")
+	cfg.Mail.SMTPPassword = os.Getenv("SMTP_PASSWORD")
+
+	switch cfg.Mail.Driver {
+	case "smtp":
+		if cfg.Mail.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST environment variable not set, required for MAIL_DRIVER=smtp")
+		}
+	case "file", "log":
+	default:
+		return nil, fmt.Errorf("MAIL_DRIVER must be one of smtp, file, log, got '%s'", cfg.Mail.Driver)
+	}
+
+	// Подтверждение email
+	verificationTTLStr := os.Getenv("EMAIL_VERIFICATION_TTL")
+	if verificationTTLStr == "" {
+		cfg.Verification.TTL = 24 * time.Hour
+	} else {
+		cfg.Verification.TTL, err = time.ParseDuration(verificationTTLStr)
+		if err != nil || cfg.Verification.TTL <= 0 {
+			return nil, fmt.Errorf("EMAIL_VERIFICATION_TTL must be a positive duration, got '%s'", verificationTTLStr)
+		}
+	}
+
+	resendCooldownStr := os.Getenv("EMAIL_VERIFICATION_RESEND_COOLDOWN")
+	if resendCooldownStr == "" {
+		cfg.Verification.ResendCooldown = time.Minute
+	} else {
+		cfg.Verification.ResendCooldown, err = time.ParseDuration(resendCooldownStr)
+		if err != nil || cfg.Verification.ResendCooldown < 0 {
+			return nil, fmt.Errorf("EMAIL_VERIFICATION_RESEND_COOLDOWN must be a non-negative duration, got '%s'", resendCooldownStr)
+		}
+	}
+
+	cfg.Verification.LinkURL = os.Getenv("EMAIL_VERIFICATION_URL")
+	if cfg.Verification.LinkURL == "" {
+		cfg.Verification.LinkURL = "http://localhost:3000/verify-email"
+	}
+
+	// Вход и сброс пароля
+	sessionTTLStr := os.Getenv("SESSION_TTL")
+	if sessionTTLStr == "" {
+		cfg.Auth.SessionTTL = 7 * 24 * time.Hour
+	} else {
+		cfg.Auth.SessionTTL, err = time.ParseDuration(sessionTTLStr)
+		if err != nil || cfg.Auth.SessionTTL <= 0 {
+			return nil, fmt.Errorf("SESSION_TTL must be a positive duration, got '%s'", sessionTTLStr)
+		}
+	}
+
+	resetTTLStr := os.Getenv("PASSWORD_RESET_TTL")
+	if resetTTLStr == "" {
+		cfg.Auth.ResetTTL = time.Hour
+	} else {
+		cfg.Auth.ResetTTL, err = time.ParseDuration(resetTTLStr)
+		if err != nil || cfg.Auth.ResetTTL <= 0 {
+			return nil, fmt.Errorf("PASSWORD_RESET_TTL must be a positive duration, got '%s'", resetTTLStr)
+		}
+	}
+
+	resetCooldownStr := os.Getenv("PASSWORD_RESET_COOLDOWN")
+	if resetCooldownStr == "" {
+		cfg.Auth.ResetCooldown = time.Minute
+	} else {
+		cfg.Auth.ResetCooldown, err = time.ParseDuration(resetCooldownStr)
+		if err != nil || cfg.Auth.ResetCooldown < 0 {
+			return nil, fmt.Errorf("PASSWORD_RESET_COOLDOWN must be a non-negative duration, got '%s'", resetCooldownStr)
+		}
+	}
+
+	cfg.Auth.ResetURL = os.Getenv("PASSWORD_RESET_URL")
+	if cfg.Auth.ResetURL == "" {
+		cfg.Auth.ResetURL = "http://localhost:3000/reset-password"
+	}
+
+	// Политика паролей
+	if cfg.PasswordPolicy.MinLength, err = positiveIntEnv("PASSWORD_MIN_LENGTH", 8); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.MaxLength, err = positiveIntEnv("PASSWORD_MAX_LENGTH", 64); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.MaxLength < cfg.PasswordPolicy.MinLength || cfg.PasswordPolicy.MaxLength > 72 {
+		return nil, fmt.Errorf("PASSWORD_MAX_LENGTH must be between PASSWORD_MIN_LENGTH and 72, got %d", cfg.PasswordPolicy.MaxLength)
+	}
+	if cfg.PasswordPolicy.RequireUppercase, err = boolEnv("PASSWORD_REQUIRE_UPPERCASE", false); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.RequireLowercase, err = boolEnv("PASSWORD_REQUIRE_LOWERCASE", false); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.RequireDigit, err = boolEnv("PASSWORD_REQUIRE_DIGIT", false); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.RequireSymbol, err = boolEnv("PASSWORD_REQUIRE_SYMBOL", false); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordPolicy.DisallowPersonalInfo, err = boolEnv("PASSWORD_DISALLOW_PERSONAL_INFO", true); err != nil {
+		return nil, err
+	}
+	cfg.PasswordPolicy.BreachedListFile = os.Getenv("PASSWORD_BREACHED_LIST_FILE")
+
+	// Хеширование паролей
+	cfg.PasswordHash.Algorithm = os.Getenv("PASSWORD_HASH_ALGORITHM")
+	if cfg.PasswordHash.Algorithm == "" {
+		cfg.PasswordHash.Algorithm = "bcrypt"
+	}
+	if cfg.PasswordHash.Algorithm != "bcrypt" && cfg.PasswordHash.Algorithm != "argon2id" {
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be 'bcrypt' or 'argon2id', got '%s'", cfg.PasswordHash.Algorithm)
+	}
+	if cfg.PasswordHash.BcryptCost, err = positiveIntEnv("PASSWORD_BCRYPT_COST", 10); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordHash.BcryptCost < 4 || cfg.PasswordHash.BcryptCost > 31 {
+		return nil, fmt.Errorf("PASSWORD_BCRYPT_COST must be between 4 and 31, got %d", cfg.PasswordHash.BcryptCost)
+	}
+	if cfg.PasswordHash.Argon2MemoryKiB, err = positiveIntEnv("ARGON2_MEMORY_KIB", 64*1024); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordHash.Argon2Iterations, err = positiveIntEnv("ARGON2_ITERATIONS", 3); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordHash.Argon2Parallelism, err = positiveIntEnv("ARGON2_PARALLELISM", 2); err != nil {
+		return nil, err
+	}
+	if cfg.PasswordHash.Argon2Parallelism > 255 {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be at most 255, got %d", cfg.PasswordHash.Argon2Parallelism)
+	}
+
+	// Защита от подбора пароля
+	if cfg.LoginLockout.AccountDelayAfter, err = positiveIntEnv("LOGIN_ACCOUNT_DELAY_AFTER", 3); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.IPDelayAfter, err = positiveIntEnv("LOGIN_IP_DELAY_AFTER", 20); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.IPLockAfter, err = positiveIntEnv("LOGIN_IP_LOCK_AFTER", 100); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.IPLockAfter < cfg.LoginLockout.IPDelayAfter {
+		return nil, fmt.Errorf("LOGIN_IP_LOCK_AFTER must not be less than LOGIN_IP_DELAY_AFTER, got %d", cfg.LoginLockout.IPLockAfter)
+	}
+	if cfg.LoginLockout.BaseDelay, err = positiveDurationEnv("LOGIN_BASE_DELAY", time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.MaxDelay, err = positiveDurationEnv("LOGIN_MAX_DELAY", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.LockoutDuration, err = positiveDurationEnv("LOGIN_LOCKOUT_DURATION", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.LoginLockout.FailureWindow, err = positiveDurationEnv("LOGIN_FAILURE_WINDOW", 15*time.Minute); err != nil {
+		return nil, err
+	}
+
+	mfaKeyStr := os.Getenv("MFA_ENCRYPTION_KEY")
+	if mfaKeyStr == "" {
+		return nil, errors.New("MFA_ENCRYPTION_KEY environment variable not set")
+	}
+	cfg.MFA.EncryptionKey, err = base64.StdEncoding.DecodeString(mfaKeyStr)
+	if err != nil || len(cfg.MFA.EncryptionKey) != 32 {
+		return nil, errors.New("MFA_ENCRYPTION_KEY must be 32 bytes encoded in base64")
+	}
+	cfg.MFA.Issuer = os.Getenv("MFA_ISSUER")
+	if cfg.MFA.Issuer == "" {
+		cfg.MFA.Issuer = "E-commerce"
+	}
+	if cfg.MFA.ChallengeTTL, err = positiveDurationEnv("MFA_CHALLENGE_TTL", 5*time.Minute); err != nil {
+		return nil, err
+	}
+
+	cfg.AccessToken.Secret = []byte(os.Getenv("ACCESS_TOKEN_SECRET"))
+	if len(cfg.AccessToken.Secret) < 32 {
+		return nil, errors.New("ACCESS_TOKEN_SECRET must be set and at least 32 bytes long")
+	}
+	if cfg.AccessToken.TTL, err = positiveDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.AccessToken.ImpersonationTTL, err = positiveDurationEnv("IMPERSONATION_TTL", 10*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.AccessToken.ImpersonationTTL > time.Hour {
+		return nil, fmt.Errorf("IMPERSONATION_TTL must not exceed 1h, got %s", cfg.AccessToken.ImpersonationTTL)
+	}
+
+	cfg.OIDC.IssuerURL = os.Getenv("OIDC_ISSUER_URL")
+	if cfg.OIDC.IssuerURL != "" {
+		cfg.OIDC.ClientID = os.Getenv("OIDC_CLIENT_ID")
+		cfg.OIDC.RedirectURL = os.Getenv("OIDC_REDIRECT_URL")
+		if cfg.OIDC.ClientID == "" || cfg.OIDC.RedirectURL == "" {
+			return nil, errors.New("OIDC_CLIENT_ID and OIDC_REDIRECT_URL must be set when OIDC_ISSUER_URL is set")
+		}
+		cfg.OIDC.ClientSecret = os.Getenv("OIDC_CLIENT_SECRET")
+		cfg.OIDC.Scopes = strings.Fields(os.Getenv("OIDC_SCOPES"))
+		if len(cfg.OIDC.Scopes) == 0 {
+			cfg.OIDC.Scopes = []string{"openid", "email", "profile"}
+		}
+		if !slices.Contains(cfg.OIDC.Scopes, "openid") {
+			return nil, fmt.Errorf("OIDC_SCOPES must include openid, got '%s'", os.Getenv("OIDC_SCOPES"))
+		}
+		if cfg.OIDC.StateTTL, err = positiveDurationEnv("OIDC_STATE_TTL", 10*time.Minute); err != nil {
+			return nil, err
+		}
+		if cfg.OIDC.HTTPTimeout, err = positiveDurationEnv("OIDC_HTTP_TIMEOUT", 5*time.Second); err != nil {
+			return nil, err
+		}
+	}
+
 	return cfg, nil
 }
+
+// positiveDurationEnv читает положительную длительность из переменной окружения name, def - значение по умолчанию.
+func positiveDurationEnv(name string, def time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration, got '%s'", name, raw)
+	}
+	return value, nil
+}
+
+// positiveIntEnv читает положительное целое из переменной окружения name, def - значение по умолчанию.
+func positiveIntEnv(name string, def int) (int, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s '%s': %w", name, raw, err)
+	}
+	if value <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got '%s'", name, raw)
+	}
+	return value, nil
+}
+
+// prefixListEnv читает список подсетей через запятую из переменной окружения name. Адрес без маски - подсеть из одного адреса.
+func prefixListEnv(name string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, raw := range strings.Split(os.Getenv(name), ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		if addr, err := netip.ParseAddr(raw); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s entry '%s': %w", name, raw, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// boolEnv читает флаг из переменной окружения name, def - значение по умолчанию.
+func boolEnv(name string, def bool) (bool, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return def, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s '%s': %w", name, raw, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// Файлы архива выгрузки
+const (
+	manifestFile = "manifest.json"
+	profileFile  = "profile.json"
+	ordersFile   = "orders.ndjson" // Один заказ order-service на строку
+)
+
+// ProfileSource отдаёт профиль пользователя.
+type ProfileSource interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+// OrdersSource копирует в w заказы пользователя со всеми данными в формате NDJSON.
+type OrdersSource interface {
+	ExportUserOrders(ctx context.Context, userID uuid.UUID, w io.Writer) (int64, error)
+}
+
+type manifest struct {
+	JobID       uuid.UUID `json:"job_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Files       []string  `json:"files"`
+}
+
+// writeBundle пишет ZIP архив с профилем и заказами пользователя. Заказы копируются из order-service
+// прямо в архив, поэтому память не зависит от их числа.
+func writeBundle(ctx context.Context, w io.Writer, job *Job, profile *user.User, orders OrdersSource, generatedAt time.Time) error {
+	zw := zip.NewWriter(w)
+
+	err := writeJSONEntry(zw, manifestFile, generatedAt, manifest{
+		JobID:       job.ID,
+		UserID:      job.UserID,
+		GeneratedAt: generatedAt,
+		Files:       []string{profileFile, ordersFile},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeJSONEntry(zw, profileFile, generatedAt, profile); err != nil {
+		return err
+	}
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: ordersFile, Method: zip.Deflate, Modified: generatedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", ordersFile, err)
+	}
+	if _, err := orders.ExportUserOrders(ctx, job.UserID, entry); err != nil {
+		return fmt.Errorf("failed to export orders: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	return nil
+}
+
+func writeJSONEntry(zw *zip.Writer, name string, modified time.Time, payload any) error {
+	entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+	encoder := json.NewEncoder(entry)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(payload); err != nil {
+		return fmt.Errorf("failed to write %s to export: %w", name, err)
+	}
+	return nil
+}
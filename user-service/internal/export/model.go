@@ -0,0 +1,36 @@
+package export
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "PENDING"
+	JobRunning   JobStatus = "RUNNING"
+	JobSucceeded JobStatus = "SUCCEEDED"
+	JobFailed    JobStatus = "FAILED"
+	JobExpired   JobStatus = "EXPIRED" // Файл выгрузки удалён по истечении срока хранения
+)
+
+// Job - задача выгрузки персональных данных пользователя в ZIP архив.
+type Job struct {
+	ID          uuid.UUID  `json:"id"`
+	UserID      uuid.UUID  `json:"user_id"`
+	Status      JobStatus  `json:"status"`
+	Attempts    int        `json:"attempts"`
+	Error       string     `json:"error,omitempty"`
+	FileSize    int64      `json:"file_size,omitempty"` // Размер архива в байтах
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// Active сообщает, что задача ещё не завершена.
+func (j *Job) Active() bool {
+	return j.Status == JobPending || j.Status == JobRunning
+}
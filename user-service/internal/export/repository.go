@@ -0,0 +1,214 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrJobNotFound = errors.New("export job not found")
+	// ErrOrdersRejected - order-service отклонил запрос выгрузки, и повтор ничего не изменит.
+	ErrOrdersRejected = errors.New("order-service rejected the orders export")
+)
+
+type Repository interface {
+	Create(ctx context.Context, job *Job) error
+	Get(ctx context.Context, id uuid.UUID) (*Job, error)
+	// GetLatestForUser возвращает последнюю задачу пользователя. Если active, только незавершённую.
+	GetLatestForUser(ctx context.Context, userID uuid.UUID, active bool) (*Job, error)
+	// Claim берёт самую старую задачу из очереди и блокирует её на lease. Если задач нет, возвращает nil.
+	Claim(ctx context.Context, lease time.Duration) (*Job, error)
+	Complete(ctx context.Context, id uuid.UUID, fileSize int64, expiresAt time.Time) error
+	Fail(ctx context.Context, id uuid.UUID, reason string) error
+	// Retry возвращает задачу в очередь с причиной неудачи. Claim не возьмёт её раньше retryAt.
+	Retry(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time) error
+	// Expire помечает до limit готовых выгрузок с истёкшим сроком и возвращает их ID, чтобы удалить файлы.
+	Expire(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error)
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+const jobColumns = `id, user_id, status, attempts, error, file_size, created_at, started_at, completed_at, expires_at`
+
+func scanJob(row pgx.Row) (*Job, error) {
+	var job Job
+	err := row.Scan(
+		&job.ID,
+		&job.UserID,
+		&job.Status,
+		&job.Attempts,
+		&job.Error,
+		&job.FileSize,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.CompletedAt,
+		&job.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *postgresRepository) Create(ctx context.Context, job *Job) error {
+	query := `
+		INSERT INTO user_service.export_jobs (id, user_id, status)
+		VALUES ($1, $2, $3)
+		RETURNING created_at
+	`
+	if err := r.db.QueryRow(ctx, query, job.ID, job.UserID, job.Status).Scan(&job.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create export job for user %s: %w", job.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) Get(ctx context.Context, id uuid.UUID) (*Job, error) {
+	query := `SELECT ` + jobColumns + ` FROM user_service.export_jobs WHERE id = $1`
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get export job %s: %w", id, err)
+	}
+	return job, nil
+}
+
+func (r *postgresRepository) GetLatestForUser(ctx context.Context, userID uuid.UUID, active bool) (*Job, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM user_service.export_jobs
+		WHERE user_id = $1 AND (NOT $2 OR status IN ('PENDING', 'RUNNING'))
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, userID, active))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get latest export job for user %s: %w", userID, err)
+	}
+	return job, nil
+}
+
+func (r *postgresRepository) Claim(ctx context.Context, lease time.Duration) (*Job, error) {
+	query := `
+		UPDATE user_service.export_jobs
+		SET status = 'RUNNING',
+			attempts = attempts + 1,
+			started_at = NOW(),
+			locked_until = NOW() + $1 * INTERVAL '1 millisecond'
+		WHERE id = (
+			SELECT id FROM user_service.export_jobs
+			WHERE status IN ('PENDING', 'RUNNING') AND (locked_until IS NULL OR locked_until < NOW())
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.QueryRow(ctx, query, lease.Milliseconds()))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim export job: %w", err)
+	}
+	return job, nil
+}
+
+func (r *postgresRepository) Complete(ctx context.Context, id uuid.UUID, fileSize int64, expiresAt time.Time) error {
+	query := `
+		UPDATE user_service.export_jobs
+		SET status = 'SUCCEEDED', file_size = $2, error = '', completed_at = NOW(), expires_at = $3, locked_until = NULL
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, id, fileSize, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to complete export job %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+		UPDATE user_service.export_jobs
+		SET status = 'FAILED', error = $2, completed_at = NOW(), locked_until = NULL
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, id, reason)
+	if err != nil {
+		return fmt.Errorf("failed to mark export job %s as failed: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) Retry(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE user_service.export_jobs
+		SET status = 'PENDING', error = $2, locked_until = $3
+		WHERE id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, id, reason, retryAt)
+	if err != nil {
+		return fmt.Errorf("failed to schedule retry of export job %s: %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrJobNotFound
+	}
+	return nil
+}
+
+func (r *postgresRepository) Expire(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	query := `
+		UPDATE user_service.export_jobs
+		SET status = 'EXPIRED'
+		WHERE id IN (
+			SELECT id FROM user_service.export_jobs
+			WHERE status = 'SUCCEEDED' AND expires_at < $1
+			ORDER BY expires_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id
+	`
+	rows, err := r.db.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire export jobs: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired export job: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating expired export jobs: %w", err)
+	}
+	return ids, nil
+}
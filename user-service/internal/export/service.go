@@ -0,0 +1,99 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrJobNotReady = errors.New("export job is not completed")
+	ErrJobExpired  = errors.New("export file has expired")
+)
+
+type Service interface {
+	// RequestExport ставит выгрузку пользователя в очередь. Если незавершённая выгрузка уже есть, возвращает её.
+	RequestExport(ctx context.Context, userID uuid.UUID) (job *Job, created bool, err error)
+	GetJob(ctx context.Context, userID, jobID uuid.UUID) (*Job, error)
+	GetLatestJob(ctx context.Context, userID uuid.UUID) (*Job, error)
+	// OpenResult открывает архив готовой выгрузки. Вызывающий закрывает его.
+	OpenResult(ctx context.Context, userID, jobID uuid.UUID) (*Job, io.ReadSeekCloser, error)
+}
+
+type service struct {
+	repo     Repository
+	store    *FileStore
+	profiles ProfileSource
+}
+
+func NewService(repo Repository, store *FileStore, profiles ProfileSource) Service {
+	return &service{repo: repo, store: store, profiles: profiles}
+}
+
+func (s *service) RequestExport(ctx context.Context, userID uuid.UUID) (*Job, bool, error) {
+	// Пользователь должен существовать, иначе выгружать нечего
+	if _, err := s.profiles.GetUserByID(ctx, userID); err != nil {
+		return nil, false, err
+	}
+
+	active, err := s.repo.GetLatestForUser(ctx, userID, true)
+	if err == nil {
+		return active, false, nil
+	}
+	if !errors.Is(err, ErrJobNotFound) {
+		return nil, false, fmt.Errorf("failed to check active export of user '%s': %w", userID, err)
+	}
+
+	jobID, err := uuid.NewV4()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to generate export job id: %w", err)
+	}
+	job := &Job{ID: jobID, UserID: userID, Status: JobPending}
+	if err := s.repo.Create(ctx, job); err != nil {
+		return nil, false, fmt.Errorf("failed to create export job: %w", err)
+	}
+	log.Info().Stringer("user_id", userID).Stringer("job_id", job.ID).Msg("Data export requested")
+
+	return job, true, nil
+}
+
+func (s *service) GetJob(ctx context.Context, userID, jobID uuid.UUID) (*Job, error) {
+	job, err := s.repo.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	// Задача другого пользователя выглядит отсутствующей
+	if job.UserID != userID {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+func (s *service) GetLatestJob(ctx context.Context, userID uuid.UUID) (*Job, error) {
+	return s.repo.GetLatestForUser(ctx, userID, false)
+}
+
+func (s *service) OpenResult(ctx context.Context, userID, jobID uuid.UUID) (*Job, io.ReadSeekCloser, error) {
+	job, err := s.GetJob(ctx, userID, jobID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch job.Status {
+	case JobSucceeded:
+	case JobExpired:
+		return nil, nil, ErrJobExpired
+	default:
+		return nil, nil, ErrJobNotReady
+	}
+
+	file, err := s.store.Open(job.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return job, file, nil
+}
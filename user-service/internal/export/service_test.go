@@ -0,0 +1,181 @@
+package export
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, job *Job) error {
+	args := m.Called(ctx, job)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Get(ctx context.Context, id uuid.UUID) (*Job, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Job), args.Error(1)
+}
+
+func (m *MockRepository) GetLatestForUser(ctx context.Context, userID uuid.UUID, active bool) (*Job, error) {
+	args := m.Called(ctx, userID, active)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Job), args.Error(1)
+}
+
+func (m *MockRepository) Claim(ctx context.Context, lease time.Duration) (*Job, error) {
+	args := m.Called(ctx, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Job), args.Error(1)
+}
+
+func (m *MockRepository) Complete(ctx context.Context, id uuid.UUID, fileSize int64, expiresAt time.Time) error {
+	args := m.Called(ctx, id, fileSize, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	args := m.Called(ctx, id, reason)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Retry(ctx context.Context, id uuid.UUID, reason string, retryAt time.Time) error {
+	args := m.Called(ctx, id, reason, retryAt)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Expire(ctx context.Context, now time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+type MockProfileSource struct {
+	mock.Mock
+}
+
+func (m *MockProfileSource) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func newTestStore(t *testing.T) *FileStore {
+	t.Helper()
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+	return store
+}
+
+func TestService_RequestExport_CreatesJob(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	svc := NewService(mockRepo, newTestStore(t), mockProfiles)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockProfiles.On("GetUserByID", ctx, userID).Return(&user.User{ID: userID}, nil).Once()
+	mockRepo.On("GetLatestForUser", ctx, userID, true).Return(nil, ErrJobNotFound).Once()
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(job *Job) bool {
+		return job.UserID == userID && job.Status == JobPending && job.ID != uuid.Nil
+	})).Return(nil).Once()
+
+	job, created, err := svc.RequestExport(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, JobPending, job.Status)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_RequestExport_ReturnsActiveJob(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	svc := NewService(mockRepo, newTestStore(t), mockProfiles)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+	active := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobRunning}
+
+	mockProfiles.On("GetUserByID", ctx, userID).Return(&user.User{ID: userID}, nil).Once()
+	mockRepo.On("GetLatestForUser", ctx, userID, true).Return(active, nil).Once()
+
+	job, created, err := svc.RequestExport(ctx, userID)
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, active, job)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_RequestExport_UserNotFound(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	svc := NewService(mockRepo, newTestStore(t), mockProfiles)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockProfiles.On("GetUserByID", ctx, userID).Return(nil, user.ErrNotFound).Once()
+
+	_, _, err := svc.RequestExport(ctx, userID)
+	require.ErrorIs(t, err, user.ErrNotFound)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestService_GetJob_ForeignJobLooksMissing(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, newTestStore(t), new(MockProfileSource))
+	ctx := context.Background()
+	jobID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("Get", ctx, jobID).Return(&Job{ID: jobID, UserID: uuid.Must(uuid.NewV4())}, nil).Once()
+
+	_, err := svc.GetJob(ctx, uuid.Must(uuid.NewV4()), jobID)
+	require.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestService_OpenResult(t *testing.T) {
+	store := newTestStore(t)
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, store, new(MockProfileSource))
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	ready := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobSucceeded}
+	pending := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobPending}
+	expired := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobExpired}
+	require.NoError(t, os.WriteFile(filepath.Join(store.dir, ready.ID.String()+".zip"), []byte("zip"), 0o600))
+
+	mockRepo.On("Get", ctx, ready.ID).Return(ready, nil)
+	mockRepo.On("Get", ctx, pending.ID).Return(pending, nil)
+	mockRepo.On("Get", ctx, expired.ID).Return(expired, nil)
+
+	_, file, err := svc.OpenResult(ctx, userID, ready.ID)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, _, err = svc.OpenResult(ctx, userID, pending.ID)
+	require.ErrorIs(t, err, ErrJobNotReady)
+
+	_, _, err = svc.OpenResult(ctx, userID, expired.ID)
+	require.ErrorIs(t, err, ErrJobExpired)
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gofrs/uuid"
+)
+
+// FileStore хранит архивы выгрузок в каталоге. В нескольких репликах каталог должен быть общим.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create export directory %s: %w", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(jobID uuid.UUID) string {
+	return filepath.Join(s.dir, jobID.String()+".zip")
+}
+
+// Write записывает архив задачи через write. Файл появляется под своим именем только целиком,
+// поэтому недописанный архив нельзя скачать. Возвращает размер файла.
+func (s *FileStore) Write(jobID uuid.UUID, write func(w io.Writer) error) (int64, error) {
+	tmp, err := os.CreateTemp(s.dir, jobID.String()+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // После переименования ничего не удаляет
+
+	buffered := bufio.NewWriterSize(tmp, 64*1024)
+	if err := write(buffered); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := buffered.Flush(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to write export file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to sync export file: %w", err)
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return 0, fmt.Errorf("failed to stat export file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, fmt.Errorf("failed to close export file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(jobID)); err != nil {
+		return 0, fmt.Errorf("failed to publish export file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Open открывает готовый архив задачи. Вызывающий закрывает файл.
+func (s *FileStore) Open(jobID uuid.UUID) (*os.File, error) {
+	file, err := os.Open(s.path(jobID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrJobExpired
+		}
+		return nil, fmt.Errorf("failed to open export file: %w", err)
+	}
+	return file, nil
+}
+
+// Remove удаляет архив задачи. Отсутствующий файл не считается ошибкой.
+func (s *FileStore) Remove(jobID uuid.UUID) error {
+	if err := os.Remove(s.path(jobID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove export file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"io"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// expireBatchSize - сколько просроченных выгрузок удаляется за один проход.
+const expireBatchSize = 100
+
+var exportMetrics = expvar.NewMap("data_exports")
+
+type WorkerConfig struct {
+	PollInterval time.Duration
+	Timeout      time.Duration // Максимальное время сборки одного архива
+	TTL          time.Duration // Сколько хранится готовый архив
+	MaxAttempts  int           // Сколько раз задачу берут заново после временной ошибки или падения реплики
+	BackoffBase  time.Duration // Пауза после первой временной ошибки, дальше удваивается
+	BackoffMax   time.Duration
+}
+
+// Worker собирает архивы выгрузок из очереди и удаляет просроченные.
+type Worker struct {
+	repo     Repository
+	store    *FileStore
+	profiles ProfileSource
+	orders   OrdersSource
+	cfg      WorkerConfig
+}
+
+func NewWorker(repo Repository, store *FileStore, profiles ProfileSource, orders OrdersSource, cfg WorkerConfig) *Worker {
+	return &Worker{repo: repo, store: store, profiles: profiles, orders: orders, cfg: cfg}
+}
+
+// Run обрабатывает очередь по таймеру и блокируется до отмены ctx.
+func (w *Worker) Run(ctx context.Context) {
+	log.Info().Dur("poll_interval", w.cfg.PollInterval).Msg("export: worker started")
+
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("export: worker stopped")
+			return
+		case <-ticker.C:
+			if _, err := w.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msg("export: worker run failed")
+			}
+		}
+	}
+}
+
+// RunOnce удаляет просроченные архивы и собирает все задачи из очереди. Возвращает число обработанных задач.
+func (w *Worker) RunOnce(ctx context.Context) (int, error) {
+	if err := w.expire(ctx); err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for ctx.Err() == nil {
+		job, err := w.repo.Claim(ctx, w.cfg.Timeout+w.cfg.PollInterval)
+		if err != nil {
+			return processed, err
+		}
+		if job == nil {
+			break
+		}
+		w.process(ctx, job)
+		processed++
+	}
+	return processed, nil
+}
+
+func (w *Worker) process(ctx context.Context, job *Job) {
+	logger := log.With().Stringer("job_id", job.ID).Stringer("user_id", job.UserID).Int("attempt", job.Attempts).Logger()
+
+	if job.Attempts > w.cfg.MaxAttempts {
+		w.fail(ctx, job, "too many attempts")
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	profile, err := w.profiles.GetUserByID(jobCtx, job.UserID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			w.fail(ctx, job, "user not found")
+			return
+		}
+		w.handleError(ctx, job, err)
+		return
+	}
+
+	started := time.Now()
+	size, err := w.store.Write(job.ID, func(out io.Writer) error {
+		return writeBundle(jobCtx, out, job, profile, w.orders, started.UTC())
+	})
+	if err != nil {
+		w.handleError(ctx, job, err)
+		return
+	}
+
+	if err := w.repo.Complete(ctx, job.ID, size, time.Now().Add(w.cfg.TTL)); err != nil {
+		logger.Error().Err(err).Msg("export: failed to complete job")
+		return
+	}
+	exportMetrics.Add("succeeded", 1)
+	logger.Info().Int64("file_size", size).Dur("duration", time.Since(started)).Msg("export: job completed")
+}
+
+// handleError завершает задачу с ошибкой, если повтор не поможет или попытки кончились, иначе откладывает её.
+func (w *Worker) handleError(ctx context.Context, job *Job, err error) {
+	if ctx.Err() != nil {
+		// Сервис останавливается: задача вернётся в очередь, когда истечёт блокировка
+		log.Warn().Err(err).Stringer("job_id", job.ID).Msg("export: job interrupted by shutdown")
+		return
+	}
+	if errors.Is(err, ErrOrdersRejected) || job.Attempts >= w.cfg.MaxAttempts {
+		log.Error().Err(err).Stringer("job_id", job.ID).Int("attempt", job.Attempts).Msg("export: job failed")
+		w.fail(ctx, job, err.Error())
+		return
+	}
+
+	delay := w.backoff(job.Attempts)
+	log.Warn().Err(err).Stringer("job_id", job.ID).Int("attempt", job.Attempts).Dur("retry_in", delay).Msg("export: job failed, will retry")
+	exportMetrics.Add("retried", 1)
+	if err := w.repo.Retry(ctx, job.ID, err.Error(), time.Now().Add(delay)); err != nil {
+		log.Error().Err(err).Stringer("job_id", job.ID).Msg("export: failed to schedule job retry")
+	}
+}
+
+// backoff возвращает паузу перед следующей попыткой: BackoffBase * 2^(attempts-1), но не больше BackoffMax.
+func (w *Worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.BackoffBase
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= w.cfg.BackoffMax || delay <= 0 {
+			return w.cfg.BackoffMax
+		}
+	}
+	return min(delay, w.cfg.BackoffMax)
+}
+
+func (w *Worker) fail(ctx context.Context, job *Job, reason string) {
+	exportMetrics.Add("failed", 1)
+	if err := w.repo.Fail(ctx, job.ID, reason); err != nil {
+		log.Error().Err(err).Stringer("job_id", job.ID).Msg("export: failed to mark job as failed")
+	}
+}
+
+func (w *Worker) expire(ctx context.Context) error {
+	ids, err := w.repo.Expire(ctx, time.Now(), expireBatchSize)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := w.store.Remove(id); err != nil {
+			log.Error().Err(err).Stringer("job_id", id).Msg("export: failed to remove expired file")
+		}
+	}
+	exportMetrics.Add("expired", int64(len(ids)))
+	return nil
+}
@@ -0,0 +1,220 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// fakeOrders отдаёт заранее заданную выгрузку заказов или ошибку посреди потока.
+type fakeOrders struct {
+	ndjson string
+	err    error
+}
+
+func (f *fakeOrders) ExportUserOrders(_ context.Context, _ uuid.UUID, w io.Writer) (int64, error) {
+	n, err := io.Copy(w, strings.NewReader(f.ndjson))
+	if err != nil {
+		return n, err
+	}
+	return n, f.err
+}
+
+var testWorkerConfig = WorkerConfig{
+	PollInterval: time.Second,
+	Timeout:      time.Minute,
+	TTL:          24 * time.Hour,
+	MaxAttempts:  3,
+	BackoffBase:  time.Minute,
+	BackoffMax:   10 * time.Minute,
+}
+
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	reader, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		content, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestWorker_RunOnce_BuildsArchive(t *testing.T) {
+	store := newTestStore(t)
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	orders := &fakeOrders{ndjson: "{\"id\":\"order-1\"}\n{\"id\":\"order-2\"}\n"}
+	worker := NewWorker(mockRepo, store, mockProfiles, orders, testWorkerConfig)
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	job := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobRunning, Attempts: 1}
+	profile := &user.User{ID: userID, Email: "export@example.com", PasswordHash: "secret-hash"}
+
+	mockRepo.On("Expire", ctx, mock.AnythingOfType("time.Time"), expireBatchSize).Return([]uuid.UUID{}, nil).Once()
+	mockRepo.On("Claim", ctx, time.Minute+time.Second).Return(job, nil).Once()
+	mockRepo.On("Claim", ctx, time.Minute+time.Second).Return(nil, nil).Once()
+	mockProfiles.On("GetUserByID", mock.Anything, userID).Return(profile, nil).Once()
+	mockRepo.On("Complete", ctx, job.ID, mock.AnythingOfType("int64"), mock.MatchedBy(func(expiresAt time.Time) bool {
+		return time.Until(expiresAt) > 23*time.Hour
+	})).Return(nil).Once()
+
+	processed, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, processed)
+	mockRepo.AssertExpectations(t)
+
+	files := readZip(t, store.path(job.ID))
+	require.Contains(t, files, manifestFile)
+	assert.Equal(t, orders.ndjson, files[ordersFile])
+	assert.NotContains(t, files[profileFile], "secret-hash")
+
+	var exported user.User
+	require.NoError(t, json.Unmarshal([]byte(files[profileFile]), &exported))
+	assert.Equal(t, "export@example.com", exported.Email)
+}
+
+func TestWorker_RunOnce_OrdersStreamFails(t *testing.T) {
+	store := newTestStore(t)
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	orders := &fakeOrders{ndjson: "{\"id\":\"order-1\"}\n", err: errors.New("unexpected EOF")}
+	worker := NewWorker(mockRepo, store, mockProfiles, orders, testWorkerConfig)
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	job := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobRunning, Attempts: 1}
+
+	mockRepo.On("Expire", ctx, mock.Anything, expireBatchSize).Return([]uuid.UUID{}, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(job, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(nil, nil).Once()
+	mockProfiles.On("GetUserByID", mock.Anything, userID).Return(&user.User{ID: userID}, nil).Once()
+	mockRepo.On("Retry", ctx, job.ID, mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "unexpected EOF")
+	}), mock.MatchedBy(func(retryAt time.Time) bool {
+		return time.Until(retryAt) > 50*time.Second && time.Until(retryAt) <= time.Minute
+	})).Return(nil).Once()
+
+	_, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Fail", mock.Anything, mock.Anything, mock.Anything)
+
+	// Неполный архив не должен остаться в каталоге
+	entries, err := os.ReadDir(store.dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestWorker_RunOnce_LastAttemptFails(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	worker := NewWorker(mockRepo, newTestStore(t), mockProfiles, &fakeOrders{err: errors.New("connection reset")}, testWorkerConfig)
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	job := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobRunning, Attempts: testWorkerConfig.MaxAttempts}
+
+	mockRepo.On("Expire", ctx, mock.Anything, expireBatchSize).Return([]uuid.UUID{}, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(job, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(nil, nil).Once()
+	mockProfiles.On("GetUserByID", mock.Anything, userID).Return(&user.User{ID: userID}, nil).Once()
+	mockRepo.On("Fail", ctx, job.ID, mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "connection reset")
+	})).Return(nil).Once()
+
+	_, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Retry", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWorker_RunOnce_RejectedExportFailsAtOnce(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	orders := &fakeOrders{err: fmt.Errorf("%w: order-service responded 401", ErrOrdersRejected)}
+	worker := NewWorker(mockRepo, newTestStore(t), mockProfiles, orders, testWorkerConfig)
+	ctx := context.Background()
+
+	userID := uuid.Must(uuid.NewV4())
+	job := &Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: JobRunning, Attempts: 1}
+
+	mockRepo.On("Expire", ctx, mock.Anything, expireBatchSize).Return([]uuid.UUID{}, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(job, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(nil, nil).Once()
+	mockProfiles.On("GetUserByID", mock.Anything, userID).Return(&user.User{ID: userID}, nil).Once()
+	mockRepo.On("Fail", ctx, job.ID, mock.MatchedBy(func(reason string) bool {
+		return strings.Contains(reason, "401")
+	})).Return(nil).Once()
+
+	_, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Retry", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestWorker_Backoff(t *testing.T) {
+	worker := NewWorker(nil, nil, nil, nil, testWorkerConfig)
+	assert.Equal(t, time.Minute, worker.backoff(1))
+	assert.Equal(t, 4*time.Minute, worker.backoff(3))
+	assert.Equal(t, 10*time.Minute, worker.backoff(10))
+}
+
+func TestWorker_RunOnce_TooManyAttempts(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockProfiles := new(MockProfileSource)
+	worker := NewWorker(mockRepo, newTestStore(t), mockProfiles, &fakeOrders{}, testWorkerConfig)
+	ctx := context.Background()
+
+	job := &Job{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), Status: JobRunning, Attempts: 4}
+
+	mockRepo.On("Expire", ctx, mock.Anything, expireBatchSize).Return([]uuid.UUID{}, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(job, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(nil, nil).Once()
+	mockRepo.On("Fail", ctx, job.ID, "too many attempts").Return(nil).Once()
+
+	_, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+	mockProfiles.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+}
+
+func TestWorker_RunOnce_RemovesExpiredFiles(t *testing.T) {
+	store := newTestStore(t)
+	mockRepo := new(MockRepository)
+	worker := NewWorker(mockRepo, store, new(MockProfileSource), &fakeOrders{}, testWorkerConfig)
+	ctx := context.Background()
+
+	expiredID := uuid.Must(uuid.NewV4())
+	require.NoError(t, os.WriteFile(filepath.Join(store.dir, expiredID.String()+".zip"), []byte("zip"), 0o600))
+
+	mockRepo.On("Expire", ctx, mock.Anything, expireBatchSize).Return([]uuid.UUID{expiredID}, nil).Once()
+	mockRepo.On("Claim", ctx, mock.Anything).Return(nil, nil).Once()
+
+	_, err := worker.RunOnce(ctx)
+	require.NoError(t, err)
+	_, err = os.Stat(store.path(expiredID))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,92 @@
+package grpc
+
+import (
+	"context"
+	"crypto/subtle"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// LoggingInterceptor пишет в лог метод, код ответа и длительность каждого вызова.
+func LoggingInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		code := status.Code(err)
+		event := log.Info()
+		if code == codes.Internal || code == codes.Unknown {
+			event = log.Error().Err(err)
+		} else if err != nil {
+			event = log.Warn().Err(err)
+		}
+		event.
+			Str("grpc_method", info.FullMethod).
+			Str("grpc_code", code.String()).
+			Dur("duration", time.Since(start)).
+			Msg("gRPC request handled")
+
+		return resp, err
+	}
+}
+
+// RecoveryInterceptor превращает панику в обработчике в ответ codes.Internal, не роняя сервер.
+func RecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if p := recover(); p != nil {
+				log.Error().
+					Interface("panic_value", p).
+					Str("grpc_method", info.FullMethod).
+					Bytes("stack", debug.Stack()).
+					Msg("Panic recovered in gRPC handler")
+				err = status.Error(codes.Internal, "internal error")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// DeadlineInterceptor ограничивает время обработки вызова: если клиент не передал дедлайн
+// или передал больший, чем maxTimeout, используется maxTimeout.
+func DeadlineInterceptor(maxTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > maxTimeout {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, maxTimeout)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// AuthInterceptor пропускает только вызовы с метаданными "authorization: Bearer <token>".
+// Пустой token не отключает проверку: такие вызовы отклоняются все.
+func AuthInterceptor(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if token == "" {
+			return nil, status.Error(codes.Unavailable, "authentication is not configured")
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+		provided, found := strings.CutPrefix(values[0], "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,224 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	userv1 "github.com/vasiliy-maslov/ecommerce-microservices/user-service/pkg/api/user/v1"
+)
+
+// MaxGetUsersBatch - максимальное число ID в одном вызове GetUsers.
+const MaxGetUsersBatch = user.MaxBatchSize
+
+type UserServer struct {
+	userv1.UnimplementedUserServiceServer
+	service user.Service
+}
+
+func NewUserServer(service user.Service) *UserServer {
+	return &UserServer{service: service}
+}
+
+// NewServer создаёт gRPC сервер с интерцепторами и зарегистрированным UserService.
+// Каждый вызов должен нести authToken; без него сервер отклоняет все вызовы.
+func NewServer(service user.Service, requestTimeout time.Duration, authToken string) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			LoggingInterceptor(),
+			RecoveryInterceptor(), // Внутри логирования, чтобы паника попала в лог как codes.Internal
+			AuthInterceptor(authToken),
+			DeadlineInterceptor(requestTimeout),
+		),
+	)
+	userv1.RegisterUserServiceServer(server, NewUserServer(service))
+	return server
+}
+
+func (s *UserServer) CreateUser(ctx context.Context, req *userv1.CreateUserRequest) (*userv1.User, error) {
+	if strings.TrimSpace(req.GetFirstName()) == "" || strings.TrimSpace(req.GetLastName()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "first_name and last_name are required")
+	}
+	if !strings.Contains(req.GetEmail(), "@") {
+		return nil, status.Error(codes.InvalidArgument, "email is invalid")
+	}
+	// Длину и состав пароля проверяет политика паролей в сервисе
+	if req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "password is required")
+	}
+
+	created, err := s.service.CreateUser(ctx, &user.User{
+		FirstName:    req.GetFirstName(),
+		LastName:     req.GetLastName(),
+		Email:        req.GetEmail(),
+		PasswordHash: req.GetPassword(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoUser(created), nil
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *userv1.GetUserRequest) (*userv1.User, error) {
+	id, err := parseUserID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := s.service.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoUser(found), nil
+}
+
+func (s *UserServer) GetUserByEmail(ctx context.Context, req *userv1.GetUserByEmailRequest) (*userv1.User, error) {
+	if req.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	found, err := s.service.GetUserByEmail(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoUser(found), nil
+}
+
+func (s *UserServer) GetUsers(ctx context.Context, req *userv1.GetUsersRequest) (*userv1.GetUsersResponse, error) {
+	if len(req.GetIds()) > MaxGetUsersBatch {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d ids per request", MaxGetUsersBatch)
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.GetIds()))
+	for _, rawID := range req.GetIds() {
+		id, err := parseUserID(rawID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	found, missing, err := s.service.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	resp := &userv1.GetUsersResponse{
+		Users:      make([]*userv1.User, 0, len(found)),
+		MissingIds: make([]string, 0, len(missing)),
+	}
+	for i := range found {
+		resp.Users = append(resp.Users, toProtoUser(&found[i]))
+	}
+	for _, id := range missing {
+		resp.MissingIds = append(resp.MissingIds, id.String())
+	}
+
+	return resp, nil
+}
+
+func (s *UserServer) UpdateUser(ctx context.Context, req *userv1.UpdateUserRequest) (*userv1.User, error) {
+	id, err := parseUserID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.GetFirstName()) == "" || strings.TrimSpace(req.GetLastName()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "first_name and last_name are required")
+	}
+	if !strings.Contains(req.GetEmail(), "@") {
+		return nil, status.Error(codes.InvalidArgument, "email is invalid")
+	}
+	// Смена пароля требует текущий пароль, поэтому через обновление профиля не выполняется
+	if req.Password != nil {
+		return nil, status.Error(codes.InvalidArgument, "password cannot be changed with UpdateUser, use POST /users/{id}/password")
+	}
+
+	err = s.service.UpdateUser(ctx, &user.User{
+		ID:        id,
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     req.GetEmail(),
+	})
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	updated, err := s.service.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return toProtoUser(updated), nil
+}
+
+func (s *UserServer) DeleteUser(ctx context.Context, req *userv1.DeleteUserRequest) (*emptypb.Empty, error) {
+	id, err := parseUserID(req.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.service.DeleteUser(ctx, id); err != nil {
+		return nil, toStatusError(err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func parseUserID(raw string) (uuid.UUID, error) {
+	id, err := uuid.FromString(raw)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid user id %q", raw)
+	}
+	return id, nil
+}
+
+// toStatusError сопоставляет доменные ошибки с кодами gRPC. Внутренние ошибки не раскрываются клиенту.
+func toStatusError(err error) error {
+	var policyErr *passwords.PolicyError
+	switch {
+	case errors.As(err, &policyErr):
+		return status.Error(codes.InvalidArgument, policyErr.Error())
+	case errors.Is(err, user.ErrNotFound):
+		return status.Error(codes.NotFound, "user not found")
+	case errors.Is(err, user.ErrEmailExists):
+		return status.Error(codes.AlreadyExists, "email already exists")
+	case errors.Is(err, user.ErrCannotUpdateAdminUser):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, user.ErrUserHasActiveOrders):
+		return status.Error(codes.FailedPrecondition, "user has active orders")
+	case errors.Is(err, user.ErrActiveOrdersCheckFailed):
+		return status.Error(codes.Unavailable, "active orders check is unavailable")
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "request canceled")
+	default:
+		log.Error().Err(err).Msg("Unhandled error in gRPC user server")
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+func toProtoUser(u *user.User) *userv1.User {
+	return &userv1.User{
+		Id:        u.ID.String(),
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		UpdatedAt: timestamppb.New(u.UpdatedAt),
+	}
+}
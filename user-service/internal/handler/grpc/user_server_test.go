@@ -0,0 +1,294 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	userGrpc "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/grpc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	userv1 "github.com/vasiliy-maslov/ecommerce-microservices/user-service/pkg/api/user/v1"
+)
+
+type MockUserService struct {
+	mock.Mock
+}
+
+func (m *MockUserService) CreateUser(ctx context.Context, u *user.User) (*user.User, error) {
+	args := m.Called(ctx, u)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUserService) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUserService) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, []uuid.UUID, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).([]user.User), args.Get(1).([]uuid.UUID), args.Error(2)
+}
+
+func (m *MockUserService) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUserService) UpdateUser(ctx context.Context, u *user.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, id, currentSession uuid.UUID, currentPassword, newPassword, ip string) error {
+	args := m.Called(ctx, id, currentSession, currentPassword, newPassword, ip)
+	return args.Error(0)
+}
+
+func (m *MockUserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context, limit int) (int, error) {
+	args := m.Called(ctx, limit)
+	return args.Int(0), args.Error(1)
+}
+
+const testToken = "test-token"
+
+// newTestClient поднимает gRPC сервер поверх bufconn и возвращает клиента к нему.
+// Клиент передаёт testToken в каждом вызове.
+func newTestClient(t *testing.T, service user.Service, requestTimeout time.Duration) userv1.UserServiceClient {
+	t.Helper()
+	return dialTestServer(t, startTestServer(t, service, requestTimeout, testToken),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+testToken)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}),
+	)
+}
+
+func startTestServer(t *testing.T, service user.Service, requestTimeout time.Duration, authToken string) *bufconn.Listener {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := userGrpc.NewServer(service, requestTimeout, authToken)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener
+}
+
+func dialTestServer(t *testing.T, listener *bufconn.Listener, opts ...grpc.DialOption) userv1.UserServiceClient {
+	t.Helper()
+
+	opts = append(opts,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return userv1.NewUserServiceClient(conn)
+}
+
+func TestUserServer_Auth(t *testing.T) {
+	mockService := new(MockUserService)
+	client := dialTestServer(t, startTestServer(t, mockService, time.Second, testToken))
+	req := &userv1.DeleteUserRequest{Id: uuid.Must(uuid.NewV4()).String()}
+
+	_, err := client.DeleteUser(context.Background(), req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	badCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer wrong")
+	_, err = client.DeleteUser(badCtx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	mockService.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+}
+
+func TestUserServer_EmptyTokenRejectsEverything(t *testing.T) {
+	mockService := new(MockUserService)
+	client := dialTestServer(t, startTestServer(t, mockService, time.Second, ""))
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer ")
+	_, err := client.DeleteUser(ctx, &userv1.DeleteUserRequest{Id: uuid.Must(uuid.NewV4()).String()})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	mockService.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+}
+
+func TestUserServer_GetUser_Success(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, time.Second)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("GetUserByID", mock.Anything, userID).Return(&user.User{
+		ID:        userID,
+		FirstName: "Ivan",
+		LastName:  "Petrov",
+		Email:     "ivan@example.com",
+		CreatedAt: time.Now().UTC(),
+	}, nil).Once()
+
+	resp, err := client.GetUser(context.Background(), &userv1.GetUserRequest{Id: userID.String()})
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), resp.GetId())
+	assert.Equal(t, "ivan@example.com", resp.GetEmail())
+	mockService.AssertExpectations(t)
+}
+
+func TestUserServer_StatusCodeMapping(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, time.Second)
+	ctx := context.Background()
+
+	missingID := uuid.Must(uuid.NewV4())
+	mockService.On("GetUserByID", mock.Anything, missingID).Return(nil, user.ErrNotFound).Once()
+	_, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: missingID.String()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	mockService.On("CreateUser", mock.Anything, mock.Anything).Return(nil, user.ErrEmailExists).Once()
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{
+		FirstName: "Ivan",
+		LastName:  "Petrov",
+		Email:     "ivan@example.com",
+		Password:  "password123",
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = client.GetUser(ctx, &userv1.GetUserRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	mockService.On("CreateUser", mock.Anything, mock.Anything).
+		Return(nil, &passwords.PolicyError{Violations: []passwords.Violation{{Rule: passwords.RuleBreached}}}).Once()
+	_, err = client.CreateUser(ctx, &userv1.CreateUserRequest{
+		FirstName: "Ivan",
+		LastName:  "Petrov",
+		Email:     "ivan@example.com",
+		Password:  "password123",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), passwords.RuleBreached)
+}
+
+func TestUserServer_GetUsers_ReturnsMissingIDs(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, time.Second)
+
+	foundID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+	mockService.On("GetUsersByIDs", mock.Anything, []uuid.UUID{foundID, missingID}).
+		Return([]user.User{{ID: foundID, Email: "a@example.com"}}, []uuid.UUID{missingID}, nil).Once()
+
+	resp, err := client.GetUsers(context.Background(), &userv1.GetUsersRequest{Ids: []string{foundID.String(), missingID.String()}})
+	require.NoError(t, err)
+	require.Len(t, resp.GetUsers(), 1)
+	assert.Equal(t, foundID.String(), resp.GetUsers()[0].GetId())
+	assert.Equal(t, []string{missingID.String()}, resp.GetMissingIds())
+	mockService.AssertExpectations(t)
+}
+
+func TestUserServer_UpdateUser_RejectsPassword(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, time.Second)
+
+	password := "new-password"
+	_, err := client.UpdateUser(context.Background(), &userv1.UpdateUserRequest{
+		Id:        uuid.Must(uuid.NewV4()).String(),
+		FirstName: "Ivan",
+		LastName:  "Petrov",
+		Email:     "ivan@example.com",
+		Password:  &password,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
+
+func TestUserServer_RecoversFromPanic(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, time.Second)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("DeleteUser", mock.Anything, userID).Run(func(mock.Arguments) {
+		panic("boom")
+	}).Return(nil).Once()
+
+	_, err := client.DeleteUser(context.Background(), &userv1.DeleteUserRequest{Id: userID.String()})
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// Сервер продолжает обслуживать запросы после паники
+	mockService.On("GetUserByID", mock.Anything, userID).Return(&user.User{ID: userID}, nil).Once()
+	_, err = client.GetUser(context.Background(), &userv1.GetUserRequest{Id: userID.String()})
+	require.NoError(t, err)
+}
+
+func TestUserServer_AppliesServerDeadline(t *testing.T) {
+	mockService := new(MockUserService)
+	client := newTestClient(t, mockService, 50*time.Millisecond)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("GetUserByID", mock.Anything, userID).Run(func(args mock.Arguments) {
+		ctx := args.Get(0).(context.Context)
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok, "handler context must have a deadline")
+		assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 50*time.Millisecond)
+		<-ctx.Done()
+	}).Return(nil, context.DeadlineExceeded).Once()
+
+	// Клиент просит больше времени, чем разрешено серверу
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.GetUser(ctx, &userv1.GetUserRequest{Id: userID.String()})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
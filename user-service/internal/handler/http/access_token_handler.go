@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type AccessTokenResponse struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type SetRolesRequest struct {
+	Roles []string `json:"roles"`
+}
+
+type RolesResponse struct {
+	Roles []string `json:"roles"`
+}
+
+// ImpersonateRequest - запрос поддержки на токен от имени пользователя. Причина попадает в журнал.
+type ImpersonateRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Reason string    `json:"reason" validate:"required,min=10,max=500"`
+}
+
+type ImpersonationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	ActorID   uuid.UUID `json:"actor_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	Reason    string    `json:"reason"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AccessTokenHandler выдаёт владельцу сессии токен доступа к order-service и управляет ролями сотрудников.
+type AccessTokenHandler struct {
+	service    accesstoken.Service
+	sessions   session.Service
+	adminToken string
+	validate   *validator.Validate
+}
+
+func NewAccessTokenHandler(service accesstoken.Service, sessions session.Service, adminToken string) *AccessTokenHandler {
+	return &AccessTokenHandler{service: service, sessions: sessions, adminToken: adminToken, validate: validator.New()}
+}
+
+func (h *AccessTokenHandler) RegisterRoutes(router chi.Router) {
+	router.With(requireSession(h.sessions)).Post("/auth/token", h.handleIssue)
+	router.With(requireSession(h.sessions)).Post("/auth/impersonate", h.handleImpersonate)
+	router.With(requireBearerToken(h.adminToken)).Get("/admin/users/{id}/roles", h.handleGetRoles)
+	router.With(requireBearerToken(h.adminToken)).Put("/admin/users/{id}/roles", h.handleSetRoles)
+	router.With(requireBearerToken(h.adminToken)).Get("/admin/users/{id}/impersonations", h.handleListImpersonations)
+}
+
+// requireSelf пропускает только самого пользователя из параметра param - с токеном его сессии или токеном доступа.
+// Токен поддержки от имени пользователя (act) отклоняется. Сессия кладётся в контекст запроса.
+func requireSelf(sessions session.Service, tokens accesstoken.Service, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || token == "" {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or missing session or access token")
+				return
+			}
+
+			ctx := r.Context()
+			var principalID uuid.UUID
+			sess, err := sessions.Authenticate(ctx, token)
+			switch {
+			case err == nil:
+				principalID = sess.UserID
+				ctx = context.WithValue(ctx, sessionContextKey{}, sess)
+			case errors.Is(err, session.ErrSessionNotFound):
+				principal, err := tokens.Verify(token)
+				if err != nil {
+					respondWithError(w, http.StatusUnauthorized, "Invalid or missing session or access token")
+					return
+				}
+				if principal.IsImpersonated() {
+					log.Warn().Stringer("actor_id", principal.ActorID).Stringer("user_id", principal.UserID).
+						Str("method", r.Method).Str("path", r.URL.Path).Msg("Impersonation token used for a forbidden operation")
+					respondWithError(w, http.StatusForbidden, "This operation is not allowed while impersonating a user")
+					return
+				}
+				principalID = principal.UserID
+			default:
+				log.Error().Err(err).Msg("Failed to authenticate session")
+				respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to authenticate session"))
+				return
+			}
+
+			targetID, ok := parseIDParam(w, r, param)
+			if !ok {
+				return
+			}
+			if targetID != principalID {
+				log.Warn().Stringer("user_id", principalID).Stringer("target_id", targetID).
+					Str("method", r.Method).Str("path", r.URL.Path).Msg("Attempt to change another user's account")
+				respondWithError(w, http.StatusForbidden, "You can only change your own account")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func (h *AccessTokenHandler) handleIssue(w http.ResponseWriter, r *http.Request) {
+	sess := sessionFromContext(r.Context())
+
+	token, err := h.service.Issue(r.Context(), sess.UserID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", sess.UserID).Msg("Failed to issue access token via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to issue access token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, AccessTokenResponse{AccessToken: token.Value, TokenType: "Bearer", ExpiresAt: token.ExpiresAt})
+}
+
+func (h *AccessTokenHandler) handleImpersonate(w http.ResponseWriter, r *http.Request) {
+	sess := sessionFromContext(r.Context())
+
+	var requestPayload ImpersonateRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	impersonation := &accesstoken.Impersonation{
+		ActorID:   sess.UserID,
+		SubjectID: requestPayload.UserID,
+		Reason:    requestPayload.Reason,
+		IP:        clientFromRequest(r).IP,
+	}
+	token, err := h.service.Impersonate(r.Context(), impersonation)
+	if err != nil {
+		if !errors.Is(err, accesstoken.ErrNotSupport) && !errors.Is(err, accesstoken.ErrStaffSubject) && !errors.Is(err, user.ErrNotFound) {
+			log.Error().Err(err).Stringer("actor_id", sess.UserID).Stringer("user_id", requestPayload.UserID).Msg("Failed to impersonate user via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), accessTokenErrorMessage(err, "Failed to impersonate user"))
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, http.StatusOK, AccessTokenResponse{AccessToken: token.Value, TokenType: "Bearer", ExpiresAt: token.ExpiresAt})
+}
+
+func (h *AccessTokenHandler) handleListImpersonations(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	impersonations, err := h.service.Impersonations(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to list impersonations via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to list impersonations")
+		return
+	}
+
+	response := make([]ImpersonationResponse, 0, len(impersonations))
+	for _, i := range impersonations {
+		response = append(response, ImpersonationResponse{
+			ID:        i.ID,
+			ActorID:   i.ActorID,
+			UserID:    i.SubjectID,
+			Reason:    i.Reason,
+			IP:        i.IP,
+			CreatedAt: i.CreatedAt,
+			ExpiresAt: i.ExpiresAt,
+		})
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *AccessTokenHandler) handleGetRoles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	roles, err := h.service.Roles(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to get user roles via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to get user roles")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, RolesResponse{Roles: roles})
+}
+
+func (h *AccessTokenHandler) handleSetRoles(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload SetRolesRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	if err := h.service.SetRoles(r.Context(), userID, requestPayload.Roles); err != nil {
+		if !errors.Is(err, accesstoken.ErrUnknownRole) && !errors.Is(err, user.ErrNotFound) {
+			log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to set user roles via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), accessTokenErrorMessage(err, "Failed to set user roles"))
+		return
+	}
+
+	roles, err := h.service.Roles(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", userID).Msg("Failed to get user roles via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to get user roles")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, RolesResponse{Roles: roles})
+}
+
+func accessTokenErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, accesstoken.ErrUnknownRole):
+		return "Unknown role, expected any of: " + strings.Join(accesstoken.Roles, ", ")
+	case errors.Is(err, accesstoken.ErrNotSupport):
+		return "Only support staff can impersonate users"
+	case errors.Is(err, accesstoken.ErrStaffSubject):
+		return "Staff accounts cannot be impersonated"
+	case errors.Is(err, user.ErrNotFound):
+		return "User not found"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,255 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockAccessTokenService struct {
+	mock.Mock
+}
+
+func (m *MockAccessTokenService) Issue(ctx context.Context, userID uuid.UUID) (*accesstoken.Token, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*accesstoken.Token), args.Error(1)
+}
+
+func (m *MockAccessTokenService) Roles(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockAccessTokenService) SetRoles(ctx context.Context, userID uuid.UUID, roles []string) error {
+	args := m.Called(ctx, userID, roles)
+	return args.Error(0)
+}
+
+func (m *MockAccessTokenService) Impersonate(ctx context.Context, impersonation *accesstoken.Impersonation) (*accesstoken.Token, error) {
+	args := m.Called(ctx, impersonation)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*accesstoken.Token), args.Error(1)
+}
+
+func (m *MockAccessTokenService) Impersonations(ctx context.Context, userID uuid.UUID) ([]accesstoken.Impersonation, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]accesstoken.Impersonation), args.Error(1)
+}
+
+func (m *MockAccessTokenService) Verify(token string) (*accesstoken.Principal, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*accesstoken.Principal), args.Error(1)
+}
+
+const testSessionToken = "session-token"
+
+var testSessionID = uuid.Must(uuid.NewV4())
+
+// sessionFor возвращает сессии, в которых testSessionToken принадлежит userID, а другие токены не найдены.
+func sessionFor(userID uuid.UUID) *MockSessionService {
+	sessions := new(MockSessionService)
+	sessions.On("Authenticate", mock.Anything, testSessionToken).Return(&session.Session{ID: testSessionID, UserID: userID}, nil)
+	sessions.On("Authenticate", mock.Anything, mock.Anything).Return(nil, session.ErrSessionNotFound)
+	return sessions
+}
+
+func newAccessTokenRouter(service accesstoken.Service, sessions session.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewAccessTokenHandler(service, sessions, testAdminToken).RegisterRoutes(router)
+	return router
+}
+
+func TestAccessTokenHandler_Issue(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	mockSessions := new(MockSessionService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4())}
+	expiresAt := time.Date(2030, 1, 1, 0, 15, 0, 0, time.UTC)
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(sess, nil)
+	mockSessions.On("Authenticate", mock.Anything, "expired").Return(nil, session.ErrSessionNotFound)
+	mockService.On("Issue", mock.Anything, sess.UserID).Return(&accesstoken.Token{Value: "header.payload.signature", ExpiresAt: expiresAt}, nil).Once()
+	router := newAccessTokenRouter(mockService, mockSessions)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/token", ""))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.AccessTokenResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "header.payload.signature", body.AccessToken)
+	assert.Equal(t, "Bearer", body.TokenType)
+	assert.True(t, expiresAt.Equal(body.ExpiresAt))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/token", nil)
+	req.Header.Set("Authorization", "Bearer expired")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAccessTokenHandler_Roles(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	userID := uuid.Must(uuid.NewV4())
+	missing := uuid.Must(uuid.NewV4())
+	mockService.On("SetRoles", mock.Anything, userID, []string{"support"}).Return(nil).Once()
+	mockService.On("SetRoles", mock.Anything, userID, []string{"root"}).Return(accesstoken.ErrUnknownRole).Once()
+	mockService.On("SetRoles", mock.Anything, missing, []string{"admin"}).Return(user.ErrNotFound).Once()
+	mockService.On("Roles", mock.Anything, userID).Return([]string{"support"}, nil).Twice()
+	router := newAccessTokenRouter(mockService, new(MockSessionService))
+	path := "/admin/users/" + userID.String() + "/roles"
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPut, path, `{"roles":["support"]}`))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"roles":["support"]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, path))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"roles":["support"]}`, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPut, path, `{"roles":["root"]}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "support, admin")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPut, "/admin/users/"+missing.String()+"/roles", `{"roles":["admin"]}`))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAccessTokenHandler_Impersonate(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	mockSessions := new(MockSessionService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4())}
+	customerID := uuid.Must(uuid.NewV4())
+	staffID := uuid.Must(uuid.NewV4())
+	expiresAt := time.Date(2030, 1, 1, 0, 10, 0, 0, time.UTC)
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(sess, nil)
+	mockService.On("Impersonate", mock.Anything, mock.MatchedBy(func(i *accesstoken.Impersonation) bool {
+		return i.ActorID == sess.UserID && i.SubjectID == customerID && i.Reason == "cart is empty after login"
+	})).Return(&accesstoken.Token{Value: "header.payload.signature", ExpiresAt: expiresAt}, nil).Once()
+	mockService.On("Impersonate", mock.Anything, mock.MatchedBy(func(i *accesstoken.Impersonation) bool {
+		return i.SubjectID == staffID
+	})).Return(nil, accesstoken.ErrStaffSubject).Once()
+	router := newAccessTokenRouter(mockService, mockSessions)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/impersonate", `{"user_id":"`+customerID.String()+`","reason":"cart is empty after login"}`))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.AccessTokenResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "header.payload.signature", body.AccessToken)
+	assert.True(t, expiresAt.Equal(body.ExpiresAt))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/impersonate", `{"user_id":"`+staffID.String()+`","reason":"cart is empty after login"}`))
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/impersonate", `{"user_id":"`+customerID.String()+`","reason":"debug"}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "a reason is required for the audit log")
+	mockService.AssertExpectations(t)
+}
+
+func TestAccessTokenHandler_ListImpersonations(t *testing.T) {
+	mockService := new(MockAccessTokenService)
+	userID := uuid.Must(uuid.NewV4())
+	record := accesstoken.Impersonation{ID: uuid.Must(uuid.NewV4()), ActorID: uuid.Must(uuid.NewV4()), SubjectID: userID, Reason: "cart is empty after login", IP: "10.0.0.1"}
+	mockService.On("Impersonations", mock.Anything, userID).Return([]accesstoken.Impersonation{record}, nil).Once()
+	router := newAccessTokenRouter(mockService, new(MockSessionService))
+	path := "/admin/users/" + userID.String() + "/impersonations"
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, path))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body []userHandler.ImpersonationResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	require.Len(t, body, 1)
+	assert.Equal(t, record.ActorID, body[0].ActorID)
+	assert.Equal(t, userID, body[0].UserID)
+	assert.Equal(t, "cart is empty after login", body[0].Reason)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUserHandler_OnlySelfCanChangeAccount(t *testing.T) {
+	mockUsers := new(MockUserService)
+	mockTokens := new(MockAccessTokenService)
+	userID, supportID := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	mockTokens.On("Verify", "access-token").Return(&accesstoken.Principal{UserID: userID}, nil)
+	mockTokens.On("Verify", "staff-token").Return(&accesstoken.Principal{UserID: supportID, Roles: []string{accesstoken.RoleAdmin}}, nil)
+	mockTokens.On("Verify", "impersonation-token").Return(&accesstoken.Principal{UserID: userID, ActorID: supportID}, nil)
+	mockTokens.On("Verify", mock.Anything).Return(nil, accesstoken.ErrInvalidToken)
+	mockUsers.On("DeleteUser", mock.Anything, userID).Return(nil).Twice()
+	router := chi.NewRouter()
+	userHandler.NewUserHandler(mockUsers, sessionFor(userID), mockTokens, new(MockAPIKeyService), testInternalToken).RegisterRoutes(router)
+
+	passwordBody := `{"current_password":"Old-password-1","new_password":"New-password-1"}`
+	tests := map[string]struct {
+		method, path, body, token string
+		want                      int
+	}{
+		"own session deletes":             {method: http.MethodDelete, token: testSessionToken, want: http.StatusNoContent},
+		"own access token deletes":        {method: http.MethodDelete, token: "access-token", want: http.StatusNoContent},
+		"no token":                        {method: http.MethodDelete, want: http.StatusUnauthorized},
+		"unknown token":                   {method: http.MethodDelete, token: "forged-token", want: http.StatusUnauthorized},
+		"staff cannot delete others":      {method: http.MethodDelete, token: "staff-token", want: http.StatusForbidden},
+		"impersonation cannot delete":     {method: http.MethodDelete, token: "impersonation-token", want: http.StatusForbidden},
+		"impersonation cannot update":     {method: http.MethodPut, body: `{"first_name":"Eve","last_name":"Evil","email":"eve@example.com"}`, token: "impersonation-token", want: http.StatusForbidden},
+		"impersonation cannot change pwd": {method: http.MethodPost, path: "/password", body: passwordBody, token: "impersonation-token", want: http.StatusForbidden},
+		"other user cannot change pwd":    {method: http.MethodPost, path: "/password", body: passwordBody, token: "staff-token", want: http.StatusForbidden},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/users/"+userID.String()+tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			assert.Equal(t, tt.want, rr.Code)
+		})
+	}
+	mockUsers.AssertExpectations(t)
+	mockUsers.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+	mockUsers.AssertNotCalled(t, "ChangePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// AdminHandler обслуживает административные операции с учётными записями. Статус пользователя можно менять
+// и с ключом API с правом users:write.
+type AdminHandler struct {
+	service  user.Service
+	lockouts lockout.Service
+	keys     apikey.Service
+	token    string
+}
+
+// NewAdminHandler создаёт обработчик административных маршрутов. С пустым token маршруты отвечают 503.
+func NewAdminHandler(service user.Service, lockouts lockout.Service, keys apikey.Service, token string) *AdminHandler {
+	return &AdminHandler{service: service, lockouts: lockouts, keys: keys, token: token}
+}
+
+func (h *AdminHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/admin/users/{id}", func(r chi.Router) {
+		r.Use(requireTokenOrAPIKey(h.token, h.keys, apikey.ScopeUsersWrite))
+		r.Post("/deactivate", h.handleDeactivate)
+		r.Post("/reactivate", h.handleReactivate)
+		r.Post("/restore", h.handleRestore)
+		r.Post("/unlock", h.handleUnlock)
+	})
+	router.With(requireBearerToken(h.token)).Post("/admin/ips/{ip}/unlock", h.handleUnlockIP)
+}
+
+// requireBearerToken пропускает только запросы с заголовком "Authorization: Bearer <token>".
+// Пустой token не отключает проверку: все запросы получают 503.
+func requireBearerToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				respondWithError(w, http.StatusServiceUnavailable, "Authentication is not configured")
+				return
+			}
+			provided, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or missing API token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *AdminHandler) handleDeactivate(w http.ResponseWriter, r *http.Request) {
+	h.handleStatusChange(w, r, "deactivate", h.service.DeactivateUser)
+}
+
+func (h *AdminHandler) handleReactivate(w http.ResponseWriter, r *http.Request) {
+	h.handleStatusChange(w, r, "reactivate", h.service.ReactivateUser)
+}
+
+func (h *AdminHandler) handleRestore(w http.ResponseWriter, r *http.Request) {
+	h.handleStatusChange(w, r, "restore", h.service.RestoreUser)
+}
+
+func (h *AdminHandler) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	h.handleStatusChange(w, r, "unlock", h.service.UnlockUser)
+}
+
+func (h *AdminHandler) handleUnlockIP(w http.ResponseWriter, r *http.Request) {
+	ip := net.ParseIP(chi.URLParam(r, "ip"))
+	if ip == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid ip parameter")
+		return
+	}
+
+	if err := h.lockouts.UnlockIP(r.Context(), ip.String()); err != nil {
+		log.Error().Err(err).Str("ip", ip.String()).Msg("Failed to unlock ip via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to unlock ip")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) handleStatusChange(w http.ResponseWriter, r *http.Request, action string, change func(ctx context.Context, id uuid.UUID) error) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := uuid.FromString(idParam)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", idParam).Msg("Failed to parse id parameter from URL")
+
+		respondWithError(w, http.StatusBadRequest, "Invalid id parameter")
+		return
+	}
+
+	err = change(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Str("action", action).Msg("Failed to change user status via service")
+
+		var clientMessage string
+		switch {
+		case errors.Is(err, user.ErrNotFound) && action == "restore":
+			clientMessage = "Deleted user not found or retention period expired"
+		case errors.Is(err, user.ErrNotFound):
+			clientMessage = "User not found"
+		case errors.Is(err, user.ErrStatusConflict):
+			clientMessage = "User status does not allow to " + action
+		case errors.Is(err, user.ErrEmailExists):
+			clientMessage = "Email of the deleted user is already taken"
+		default:
+			clientMessage = "Failed to " + action + " user"
+		}
+
+		respondWithError(w, mapErrorToStatusCode(err), clientMessage)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
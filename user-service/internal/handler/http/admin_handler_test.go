@@ -0,0 +1,180 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+const testAdminToken = "admin-secret"
+
+type MockLockoutService struct {
+	mock.Mock
+}
+
+func (m *MockLockoutService) Check(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordFailure(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) RecordSuccess(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) Unlock(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockLockoutService) UnlockIP(ctx context.Context, ip string) error {
+	args := m.Called(ctx, ip)
+	return args.Error(0)
+}
+
+func newAdminRouter(service user.Service) *chi.Mux {
+	return newAdminRouterWithLockouts(service, new(MockLockoutService))
+}
+
+func newAdminRouterWithLockouts(service user.Service, lockouts lockout.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewAdminHandler(service, lockouts, new(MockAPIKeyService), testAdminToken).RegisterRoutes(router)
+	return router
+}
+
+func adminRequest(method, path string) *http.Request {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+func TestAdminHandler_Deactivate_Success(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("DeactivateUser", mock.Anything, userID).Return(nil).Once()
+
+	rr := httptest.NewRecorder()
+	newAdminRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+userID.String()+"/deactivate"))
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminHandler_Reactivate_StatusConflict(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("ReactivateUser", mock.Anything, userID).Return(user.ErrStatusConflict).Once()
+
+	rr := httptest.NewRecorder()
+	newAdminRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+userID.String()+"/reactivate"))
+
+	require.Equal(t, http.StatusConflict, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminHandler_Restore_RetentionExpired(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("RestoreUser", mock.Anything, userID).Return(user.ErrNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	newAdminRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+userID.String()+"/restore"))
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Contains(t, rr.Body.String(), "retention period expired")
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminHandler_RequiresToken(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/"+userID.String()+"/deactivate", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	rr := httptest.NewRecorder()
+	newAdminRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "DeactivateUser", mock.Anything, mock.Anything)
+}
+
+func TestAdminHandler_EmptyTokenFailsClosed(t *testing.T) {
+	mockService := new(MockUserService)
+	router := chi.NewRouter()
+	userHandler.NewAdminHandler(mockService, new(MockLockoutService), new(MockAPIKeyService), "").RegisterRoutes(router)
+
+	for _, header := range []string{"", "Bearer ", "Bearer anything"} {
+		req := httptest.NewRequest(http.MethodPost, "/admin/users/"+uuid.Must(uuid.NewV4()).String()+"/deactivate", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusServiceUnavailable, rr.Code, "header %q", header)
+	}
+	mockService.AssertNotCalled(t, "DeactivateUser", mock.Anything, mock.Anything)
+}
+
+func TestAdminHandler_InvalidUUID(t *testing.T) {
+	mockService := new(MockUserService)
+
+	rr := httptest.NewRecorder()
+	newAdminRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/not-a-uuid/restore"))
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "RestoreUser", mock.Anything, mock.Anything)
+}
+
+func TestAdminHandler_Unlock(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+	mockService.On("UnlockUser", mock.Anything, userID).Return(nil).Once()
+	mockService.On("UnlockUser", mock.Anything, missingID).Return(user.ErrNotFound).Once()
+	router := newAdminRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+userID.String()+"/unlock"))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+missingID.String()+"/unlock"))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAdminHandler_UnlockIP(t *testing.T) {
+	mockLockouts := new(MockLockoutService)
+	mockLockouts.On("UnlockIP", mock.Anything, "203.0.113.7").Return(nil).Once()
+	router := newAdminRouterWithLockouts(new(MockUserService), mockLockouts)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/ips/203.0.113.7/unlock"))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/ips/not-an-ip/unlock"))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	// Без токена администратора разблокировка недоступна
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/ips/203.0.113.7/unlock", nil))
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockLockouts.AssertExpectations(t)
+}
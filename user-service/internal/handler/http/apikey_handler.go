@@ -0,0 +1,209 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+)
+
+// APIKeyRequest задаёт ключ при выпуске и изменении. Без expires_at ключ бессрочный.
+type APIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func (p APIKeyRequest) params() apikey.Params {
+	return apikey.Params{Name: p.Name, Scopes: p.Scopes, ExpiresAt: p.ExpiresAt}
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// CreateAPIKeyResponse единственный раз показывает сам ключ.
+type CreateAPIKeyResponse struct {
+	Key    string         `json:"key"`
+	APIKey APIKeyResponse `json:"api_key"`
+}
+
+// APIKeyHandler обслуживает управление ключами API. Маршруты защищены токеном администратора.
+type APIKeyHandler struct {
+	service  apikey.Service
+	token    string
+	validate *validator.Validate
+}
+
+// NewAPIKeyHandler создаёт обработчик ключей API. Пустой token не отключает проверку: такие маршруты отвечают 503.
+func NewAPIKeyHandler(service apikey.Service, token string) *APIKeyHandler {
+	return &APIKeyHandler{service: service, token: token, validate: validator.New()}
+}
+
+func (h *APIKeyHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/admin/api-keys", func(r chi.Router) {
+		r.Use(requireBearerToken(h.token))
+		r.Post("/", h.handleCreate)
+		r.Get("/", h.handleList)
+		r.Get("/{id}", h.handleGet)
+		r.Put("/{id}", h.handleUpdate)
+		r.Delete("/{id}", h.handleDelete)
+	})
+}
+
+// requireTokenOrAPIKey пропускает запросы с "Authorization: Bearer <token>" или с "Authorization: ApiKey <key>",
+// если у ключа есть право scope. С пустым token запросы с Bearer получают 503, ключи API по-прежнему принимаются.
+func requireTokenOrAPIKey(token string, keys apikey.Service, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		bearer := requireBearerToken(token)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, found := strings.CutPrefix(r.Header.Get("Authorization"), "ApiKey ")
+			if !found {
+				bearer.ServeHTTP(w, r)
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), raw)
+			if err != nil {
+				if !errors.Is(err, apikey.ErrInvalidKey) {
+					log.Error().Err(err).Msg("Failed to authenticate api key")
+				}
+				respondWithError(w, mapErrorToStatusCode(err), apiKeyErrorMessage(err, "Failed to authenticate API key"))
+				return
+			}
+			if !key.HasScope(scope) {
+				log.Warn().Stringer("api_key_id", key.ID).Str("scope", scope).Msg("API key lacks required scope")
+				respondWithError(w, http.StatusForbidden, "API key does not have the "+scope+" scope")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (h *APIKeyHandler) handleCreate(w http.ResponseWriter, r *http.Request) {
+	var requestPayload APIKeyRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	raw, key, err := h.service.Create(r.Context(), requestPayload.params())
+	if err != nil {
+		h.respondWithServiceError(w, err, "Failed to create API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusCreated, CreateAPIKeyResponse{Key: raw, APIKey: toAPIKeyResponse(key)})
+}
+
+func (h *APIKeyHandler) handleList(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.service.List(r.Context())
+	if err != nil {
+		h.respondWithServiceError(w, err, "Failed to list API keys")
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		response = append(response, toAPIKeyResponse(&keys[i]))
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+func (h *APIKeyHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	key, err := h.service.Get(r.Context(), id)
+	if err != nil {
+		h.respondWithServiceError(w, err, "Failed to get API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toAPIKeyResponse(key))
+}
+
+func (h *APIKeyHandler) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	var requestPayload APIKeyRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	key, err := h.service.Update(r.Context(), id, requestPayload.params())
+	if err != nil {
+		h.respondWithServiceError(w, err, "Failed to update API key")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, toAPIKeyResponse(key))
+}
+
+func (h *APIKeyHandler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(r.Context(), id); err != nil {
+		h.respondWithServiceError(w, err, "Failed to delete API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *APIKeyHandler) respondWithServiceError(w http.ResponseWriter, err error, fallback string) {
+	status := mapErrorToStatusCode(err)
+	if status == http.StatusInternalServerError {
+		log.Error().Err(err).Msg(fallback + " via service")
+	}
+	respondWithError(w, status, apiKeyErrorMessage(err, fallback))
+}
+
+func toAPIKeyResponse(key *apikey.Key) APIKeyResponse {
+	return APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.Scopes,
+		CreatedAt:  key.CreatedAt,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
+
+func apiKeyErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, apikey.ErrNotFound):
+		return "API key not found"
+	case errors.Is(err, apikey.ErrInvalidKey):
+		return "Invalid or expired API key"
+	case errors.Is(err, apikey.ErrUnknownScope):
+		return "Unknown scope, allowed: " + strings.Join(apikey.Scopes, ", ")
+	case errors.Is(err, apikey.ErrScopeRequired):
+		return "At least one scope is required"
+	case errors.Is(err, apikey.ErrExpiryInPast):
+		return "Expiry must be in the future"
+	default:
+		return fallback
+	}
+}
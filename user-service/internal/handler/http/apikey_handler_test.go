@@ -0,0 +1,226 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+)
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) Create(ctx context.Context, params apikey.Params) (string, *apikey.Key, error) {
+	args := m.Called(ctx, params)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*apikey.Key), args.Error(2)
+}
+
+func (m *MockAPIKeyService) List(ctx context.Context) ([]apikey.Key, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]apikey.Key), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Get(ctx context.Context, id uuid.UUID) (*apikey.Key, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*apikey.Key), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Update(ctx context.Context, id uuid.UUID, params apikey.Params) (*apikey.Key, error) {
+	args := m.Called(ctx, id, params)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*apikey.Key), args.Error(1)
+}
+
+func (m *MockAPIKeyService) Delete(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, raw string) (*apikey.Key, error) {
+	args := m.Called(ctx, raw)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*apikey.Key), args.Error(1)
+}
+
+func newAPIKeyRouter(service apikey.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewAPIKeyHandler(service, testAdminToken).RegisterRoutes(router)
+	return router
+}
+
+func adminJSONRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+func TestAPIKeyHandler_Create(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := &apikey.Key{ID: uuid.Must(uuid.NewV4()), Name: "order-service", Prefix: "usk_abcdefgh", Scopes: []string{apikey.ScopeUsersRead}, ExpiresAt: &expiresAt}
+	mockService.On("Create", mock.Anything, mock.MatchedBy(func(params apikey.Params) bool {
+		return params.Name == "order-service" && params.ExpiresAt != nil && params.ExpiresAt.Equal(expiresAt)
+	})).Return("usk_abcdefgh-secret", key, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newAPIKeyRouter(mockService).ServeHTTP(rr, adminJSONRequest(http.MethodPost, "/admin/api-keys",
+		`{"name":"order-service","scopes":["users:read"],"expires_at":"2030-01-01T00:00:00Z"}`))
+
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var body userHandler.CreateAPIKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "usk_abcdefgh-secret", body.Key)
+	assert.Equal(t, key.ID, body.APIKey.ID)
+	assert.Equal(t, "usk_abcdefgh", body.APIKey.Prefix)
+	mockService.AssertExpectations(t)
+}
+
+func TestAPIKeyHandler_Create_Invalid(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	mockService.On("Create", mock.Anything, mock.Anything).Return("", nil, apikey.ErrUnknownScope).Once()
+	router := newAPIKeyRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPost, "/admin/api-keys", `{"name":"partner","scopes":["users:delete"]}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "users:read, users:write")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPost, "/admin/api-keys", `{"name":"partner","scopes":[]}`))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(`{"name":"partner","scopes":["users:read"]}`)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAPIKeyHandler_ListGetUpdateDelete(t *testing.T) {
+	mockService := new(MockAPIKeyService)
+	lastUsed := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	key := apikey.Key{ID: uuid.Must(uuid.NewV4()), Name: "partner", Prefix: "usk_12345678", Scopes: []string{apikey.ScopeUsersWrite}, LastUsedAt: &lastUsed}
+	missing := uuid.Must(uuid.NewV4())
+	mockService.On("List", mock.Anything).Return([]apikey.Key{key}, nil).Once()
+	mockService.On("Get", mock.Anything, key.ID).Return(&key, nil).Once()
+	mockService.On("Get", mock.Anything, missing).Return(nil, apikey.ErrNotFound).Once()
+	updated := key
+	updated.Name = "partner-v2"
+	mockService.On("Update", mock.Anything, key.ID, apikey.Params{Name: "partner-v2", Scopes: []string{"users:write"}}).Return(&updated, nil).Once()
+	mockService.On("Delete", mock.Anything, key.ID).Return(nil).Once()
+	router := newAPIKeyRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/api-keys"))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var list []userHandler.APIKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, key.ID, list[0].ID)
+	assert.NotContains(t, rr.Body.String(), `"key"`, "listing never exposes the secret")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/api-keys/"+key.ID.String()))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"last_used_at":"2025-06-01T12:00:00Z"`)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, "/admin/api-keys/"+missing.String()))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminJSONRequest(http.MethodPut, "/admin/api-keys/"+key.ID.String(), `{"name":"partner-v2","scopes":["users:write"]}`))
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "partner-v2")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodDelete, "/admin/api-keys/"+key.ID.String()))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestRequireTokenOrAPIKey(t *testing.T) {
+	mockUsers := new(MockUserService)
+	mockKeys := new(MockAPIKeyService)
+	userID := uuid.Must(uuid.NewV4())
+	mockUsers.On("DeactivateUser", mock.Anything, userID).Return(nil)
+	mockKeys.On("Authenticate", mock.Anything, "usk_writer").Return(&apikey.Key{ID: uuid.Must(uuid.NewV4()), Scopes: []string{apikey.ScopeUsersWrite}}, nil)
+	mockKeys.On("Authenticate", mock.Anything, "usk_reader").Return(&apikey.Key{ID: uuid.Must(uuid.NewV4()), Scopes: []string{apikey.ScopeUsersRead}}, nil)
+	mockKeys.On("Authenticate", mock.Anything, "usk_revoked").Return(nil, apikey.ErrInvalidKey)
+
+	router := chi.NewRouter()
+	userHandler.NewAdminHandler(mockUsers, new(MockLockoutService), mockKeys, testAdminToken).RegisterRoutes(router)
+	path := "/admin/users/" + userID.String() + "/deactivate"
+
+	for authorization, want := range map[string]int{
+		"Bearer " + testAdminToken: http.StatusNoContent,
+		"ApiKey usk_writer":        http.StatusNoContent,
+		"ApiKey usk_reader":        http.StatusForbidden,
+		"ApiKey usk_revoked":       http.StatusUnauthorized,
+		"Bearer usk_writer":        http.StatusUnauthorized,
+		"":                         http.StatusUnauthorized,
+	} {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, want, rr.Code, authorization)
+	}
+	mockUsers.AssertNumberOfCalls(t, "DeactivateUser", 2)
+}
+
+func TestRequireTokenOrAPIKey_EmptyTokenFailsClosed(t *testing.T) {
+	mockExports := new(MockExportService)
+	mockKeys := new(MockAPIKeyService)
+	userID := uuid.Must(uuid.NewV4())
+	mockExports.On("GetLatestJob", mock.Anything, userID).Return(&export.Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: export.JobPending}, nil).Once()
+	mockKeys.On("Authenticate", mock.Anything, "usk_reader").Return(&apikey.Key{ID: uuid.Must(uuid.NewV4()), Scopes: []string{apikey.ScopeUsersRead}}, nil).Once()
+
+	// Без настроенного токена ни один Bearer не проходит, ключи API с нужным правом работают
+	router := chi.NewRouter()
+	userHandler.NewExportHandler(mockExports, mockKeys, "").RegisterRoutes(router)
+	for authorization, want := range map[string]int{
+		"":                  http.StatusServiceUnavailable,
+		"Bearer ":           http.StatusServiceUnavailable,
+		"Bearer anything":   http.StatusServiceUnavailable,
+		"ApiKey usk_reader": http.StatusOK,
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/export", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, want, rr.Code, "authorization %q", authorization)
+	}
+	mockExports.AssertExpectations(t)
+	mockKeys.AssertExpectations(t)
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+)
+
+// forgotPasswordTimeout ограничивает фоновую отправку письма сброса после ответа клиенту.
+const forgotPasswordTimeout = 30 * time.Second
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+// MFAChallengeResponse возвращается вместо сессии, если у пользователя включён второй фактор.
+type MFAChallengeResponse struct {
+	MFARequired bool      `json:"mfa_required"`
+	MFAToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
+}
+
+// AuthHandler обслуживает вход, выход и восстановление пароля.
+type AuthHandler struct {
+	service  auth.Service
+	validate *validator.Validate
+}
+
+func NewAuthHandler(service auth.Service) *AuthHandler {
+	return &AuthHandler{service: service, validate: validator.New()}
+}
+
+func (h *AuthHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/auth/login", h.handleLogin)
+	router.Post("/auth/login/mfa", h.handleVerifyMFA)
+	router.Post("/auth/logout", h.handleLogout)
+	router.Post("/auth/password/forgot", h.handleForgotPassword)
+	router.Post("/auth/password/reset", h.handleResetPassword)
+}
+
+func (h *AuthHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	var requestPayload LoginRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	result, err := h.service.Login(r.Context(), requestPayload.Email, requestPayload.Password, clientFromRequest(r))
+	if err != nil {
+		if respondWithLockoutError(w, err) {
+			return
+		}
+		if !errors.Is(err, auth.ErrInvalidCredentials) {
+			log.Error().Err(err).Msg("Failed to login via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to login"))
+		return
+	}
+
+	if result.MFAToken != "" {
+		respondWithJSON(w, http.StatusOK, MFAChallengeResponse{MFARequired: true, MFAToken: result.MFAToken, ExpiresAt: result.MFAExpiresAt})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, LoginResponse{Token: result.Token, ExpiresAt: result.Session.ExpiresAt, UserID: result.Session.UserID})
+}
+
+func (h *AuthHandler) handleVerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var requestPayload VerifyMFARequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	result, err := h.service.VerifyMFA(r.Context(), requestPayload.MFAToken, requestPayload.Code, clientFromRequest(r))
+	if err != nil {
+		if respondWithLockoutError(w, err) {
+			return
+		}
+		if !errors.Is(err, mfa.ErrInvalidCode) && !errors.Is(err, auth.ErrInvalidMFAChallenge) {
+			log.Error().Err(err).Msg("Failed to verify second factor via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to login"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, LoginResponse{Token: result.Token, ExpiresAt: result.Session.ExpiresAt, UserID: result.Session.UserID})
+}
+
+func (h *AuthHandler) handleLogout(w http.ResponseWriter, r *http.Request) {
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found || token == "" {
+		respondWithError(w, http.StatusUnauthorized, "Invalid or missing session token")
+		return
+	}
+
+	if err := h.service.Logout(r.Context(), token); err != nil {
+		if !errors.Is(err, session.ErrSessionNotFound) {
+			log.Error().Err(err).Msg("Failed to logout via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to logout"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleForgotPassword всегда отвечает 202 до поиска пользователя: ни код, ни время ответа не выдают, зарегистрирован ли email.
+func (h *AuthHandler) handleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var requestPayload ForgotPasswordRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), forgotPasswordTimeout)
+	go func() {
+		defer cancel()
+		if err := h.service.ForgotPassword(ctx, requestPayload.Email); err != nil {
+			log.Error().Err(err).Msg("Failed to process password reset request")
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *AuthHandler) handleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var requestPayload ResetPasswordRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	if err := h.service.ResetPassword(r.Context(), requestPayload.Token, requestPayload.NewPassword); err != nil {
+		if respondWithPolicyError(w, "NewPassword", err) {
+			return
+		}
+		log.Warn().Err(err).Msg("Failed to reset password via service")
+		respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to reset password"))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// clientFromRequest берёт адрес клиента из RemoteAddr, который RealIP уже заменил на адрес из заголовков доверенного прокси.
+func clientFromRequest(r *http.Request) session.Client {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return session.Client{UserAgent: r.UserAgent(), IP: ip}
+}
+
+func authErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return "Invalid email or password"
+	case errors.Is(err, session.ErrSessionNotFound):
+		return "Invalid or missing session token"
+	case errors.Is(err, auth.ErrInvalidResetToken):
+		return "Password reset token is invalid or expired"
+	case errors.Is(err, auth.ErrInvalidMFAChallenge):
+		return "Login attempt is invalid or expired, sign in again"
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return "Invalid two-factor authentication code"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,300 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockAuthService struct {
+	mock.Mock
+}
+
+func (m *MockAuthService) Login(ctx context.Context, email, password string, client session.Client) (*auth.LoginResult, error) {
+	args := m.Called(ctx, email, password, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.LoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) VerifyMFA(ctx context.Context, mfaToken, code string, client session.Client) (*auth.LoginResult, error) {
+	args := m.Called(ctx, mfaToken, code, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.LoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) LoginVerified(ctx context.Context, u *user.User, client session.Client) (*auth.LoginResult, error) {
+	args := m.Called(ctx, u, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.LoginResult), args.Error(1)
+}
+
+func (m *MockAuthService) Logout(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ForgotPassword(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	args := m.Called(ctx, token, newPassword)
+	return args.Error(0)
+}
+
+func newAuthRouter(service auth.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewAuthHandler(service).RegisterRoutes(router)
+	return router
+}
+
+// newProxiedAuthRouter доверяет заголовкам прокси из 10.0.0.0/8.
+func newProxiedAuthRouter(service auth.Service) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(userHandler.RealIP([]netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}))
+	userHandler.NewAuthHandler(service).RegisterRoutes(router)
+	return router
+}
+
+func TestAuthHandler_Login_Success(t *testing.T) {
+	mockService := new(MockAuthService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), ExpiresAt: time.Now().Add(time.Hour)}
+	mockService.On("Login", mock.Anything, "jane@example.com", "secret-pass", session.Client{UserAgent: "test-agent", IP: "192.0.2.1"}).
+		Return(&auth.LoginResult{Token: "session-token", Session: sess}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"secret-pass"}`))
+	req.Header.Set("User-Agent", "test-agent")
+	newAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.LoginResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "session-token", body.Token)
+	assert.Equal(t, sess.UserID, body.UserID)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_InvalidCredentials(t *testing.T) {
+	mockService := new(MockAuthService)
+	mockService.On("Login", mock.Anything, "jane@example.com", "wrong", mock.Anything).Return(nil, auth.ErrInvalidCredentials).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"wrong"}`))
+	newAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid email or password")
+}
+
+func TestAuthHandler_Login_TooManyAttempts(t *testing.T) {
+	mockService := new(MockAuthService)
+	// Адрес клиента берётся из X-Forwarded-For, если запрос пришёл от доверенного прокси
+	mockService.On("Login", mock.Anything, "jane@example.com", "guess", session.Client{UserAgent: "test-agent", IP: "203.0.113.7"}).
+		Return(nil, &lockout.LockedError{RetryAfter: 1500 * time.Millisecond}).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"guess"}`))
+	req.RemoteAddr = "10.0.0.5:41000"
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	newProxiedAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), "Too many failed attempts")
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_ForwardedHeadersFromUntrustedPeerAreIgnored(t *testing.T) {
+	mockService := new(MockAuthService)
+	// Клиент не может сменить свой адрес заголовком и так обойти лимит по IP
+	mockService.On("Login", mock.Anything, "jane@example.com", "guess", mock.MatchedBy(func(client session.Client) bool {
+		return client.IP == "198.51.100.9"
+	})).Return(nil, auth.ErrInvalidCredentials).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"guess"}`))
+	req.RemoteAddr = "198.51.100.9:41000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7")
+	req.Header.Set("X-Real-IP", "203.0.113.8")
+	newProxiedAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_SpoofedForwardedForBehindProxy(t *testing.T) {
+	mockService := new(MockAuthService)
+	// Прокси дописывает адрес клиента в конец, всё левее прислал сам клиент
+	mockService.On("Login", mock.Anything, "jane@example.com", "guess", mock.MatchedBy(func(client session.Client) bool {
+		return client.IP == "198.51.100.9"
+	})).Return(nil, auth.ErrInvalidCredentials).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"guess"}`))
+	req.RemoteAddr = "10.0.0.5:41000"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 198.51.100.9")
+	newProxiedAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_Login_MFARequired(t *testing.T) {
+	mockService := new(MockAuthService)
+	expiresAt := time.Date(2026, 1, 1, 12, 5, 0, 0, time.UTC)
+	mockService.On("Login", mock.Anything, "jane@example.com", "secret-pass", mock.Anything).
+		Return(&auth.LoginResult{MFAToken: "mfa-token", MFAExpiresAt: expiresAt}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"email":"jane@example.com","password":"secret-pass"}`))
+	newAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.MFAChallengeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.True(t, body.MFARequired)
+	assert.Equal(t, "mfa-token", body.MFAToken)
+	assert.True(t, expiresAt.Equal(body.ExpiresAt))
+	assert.NotContains(t, rr.Body.String(), `"token"`, "no session before the second factor")
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_VerifyMFA(t *testing.T) {
+	mockService := new(MockAuthService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), ExpiresAt: time.Now().Add(time.Hour)}
+	mockService.On("VerifyMFA", mock.Anything, "mfa-token", "123456", mock.Anything).
+		Return(&auth.LoginResult{Token: "session-token", Session: sess}, nil).Once()
+	mockService.On("VerifyMFA", mock.Anything, "mfa-token", "000000", mock.Anything).Return(nil, mfa.ErrInvalidCode).Once()
+	mockService.On("VerifyMFA", mock.Anything, "stale-token", "123456", mock.Anything).Return(nil, auth.ErrInvalidMFAChallenge).Once()
+	router := newAuthRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"mfa-token","code":"123456"}`)))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.LoginResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "session-token", body.Token)
+	assert.Equal(t, sess.UserID, body.UserID)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"mfa-token","code":"000000"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid two-factor authentication code")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"stale-token","code":"123456"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), "sign in again")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/login/mfa", strings.NewReader(`{"mfa_token":"mfa-token"}`)))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_Logout(t *testing.T) {
+	mockService := new(MockAuthService)
+	mockService.On("Logout", mock.Anything, "session-token").Return(nil).Once()
+	mockService.On("Logout", mock.Anything, "revoked-token").Return(session.ErrSessionNotFound).Once()
+	router := newAuthRouter(mockService)
+
+	for token, expected := range map[string]int{
+		"session-token": http.StatusNoContent,
+		"revoked-token": http.StatusUnauthorized,
+	} {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, expected, rr.Code, token)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/logout", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestAuthHandler_ForgotPassword_AlwaysAccepted(t *testing.T) {
+	mockService := new(MockAuthService)
+	done := make(chan struct{})
+	mockService.On("ForgotPassword", mock.Anything, "ghost@example.com").
+		Run(func(mock.Arguments) { close(done) }).
+		Return(assert.AnError).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", strings.NewReader(`{"email":"ghost@example.com"}`))
+	newAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Empty(t, rr.Body.String())
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ForgotPassword was not called")
+	}
+}
+
+func TestAuthHandler_ForgotPassword_InvalidEmail(t *testing.T) {
+	mockService := new(MockAuthService)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/password/forgot", strings.NewReader(`{"email":"not-an-email"}`))
+	newAuthRouter(mockService).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "ForgotPassword", mock.Anything, mock.Anything)
+}
+
+func TestAuthHandler_ResetPassword(t *testing.T) {
+	mockService := new(MockAuthService)
+	mockService.On("ResetPassword", mock.Anything, "good-token", "new-password").Return(nil).Once()
+	mockService.On("ResetPassword", mock.Anything, "used-token", "new-password").Return(auth.ErrInvalidResetToken).Once()
+	mockService.On("ResetPassword", mock.Anything, "good-token", "short").
+		Return(&passwords.PolicyError{Violations: []passwords.Violation{{Rule: passwords.RuleMinLength, Message: "too short"}}}).
+		Once()
+	router := newAuthRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"good-token","new_password":"new-password"}`)))
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"used-token","new_password":"new-password"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid or expired")
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/password/reset", strings.NewReader(`{"token":"good-token","new_password":"short"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "NewPassword.min_length")
+	mockService.AssertExpectations(t)
+}
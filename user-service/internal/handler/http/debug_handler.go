@@ -0,0 +1,21 @@
+package http
+
+import (
+	"expvar"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DebugHandler отдаёт счётчики фоновых задач: пакеты регистрируют их через expvar.NewMap.
+type DebugHandler struct {
+	token string
+}
+
+// NewDebugHandler создаёт обработчик отладочных маршрутов. С пустым token маршруты отвечают 503.
+func NewDebugHandler(token string) *DebugHandler {
+	return &DebugHandler{token: token}
+}
+
+func (h *DebugHandler) RegisterRoutes(router chi.Router) {
+	router.With(requireBearerToken(h.token)).Handle("/debug/vars", expvar.Handler())
+}
@@ -0,0 +1,42 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+)
+
+func TestDebugHandler_RequiresToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", token: testInternalToken, header: "Bearer " + testInternalToken, wantStatus: http.StatusOK},
+		{name: "missing token", token: testInternalToken, wantStatus: http.StatusUnauthorized},
+		{name: "wrong token", token: testInternalToken, header: "Bearer wrong", wantStatus: http.StatusUnauthorized},
+		{name: "token not configured", header: "Bearer ", wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := chi.NewRouter()
+			userHandler.NewDebugHandler(tt.token).RegisterRoutes(router)
+
+			req := httptest.NewRequest(http.MethodGet, "/debug/vars", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
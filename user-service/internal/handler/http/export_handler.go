@@ -0,0 +1,158 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// ExportHandler обслуживает выгрузки персональных данных по запросу субъекта данных.
+// Маршруты вызывает служба поддержки, поэтому они защищены тем же токеном, что и административные,
+// или ключом API с правом users:read.
+type ExportHandler struct {
+	service export.Service
+	keys    apikey.Service
+	token   string
+}
+
+// NewExportHandler создаёт обработчик выгрузок. Пустой token не отключает проверку: такие маршруты отвечают 503.
+func NewExportHandler(service export.Service, keys apikey.Service, token string) *ExportHandler {
+	return &ExportHandler{service: service, keys: keys, token: token}
+}
+
+func (h *ExportHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/users/{id}/export", func(r chi.Router) {
+		r.Use(requireTokenOrAPIKey(h.token, h.keys, apikey.ScopeUsersRead))
+		r.Post("/", h.handleRequestExport)
+		r.Get("/", h.handleGetLatestExport)
+		r.Get("/{jobID}", h.handleGetExport)
+		r.Get("/{jobID}/download", h.handleDownloadExport)
+	})
+}
+
+// handleRequestExport ставит выгрузку в очередь и отвечает 202 со ссылкой на задачу для опроса статуса.
+func (h *ExportHandler) handleRequestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	job, created, err := h.service.RequestExport(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to request data export via service")
+		respondWithError(w, mapErrorToStatusCode(err), exportErrorMessage(err, "Failed to request data export"))
+		return
+	}
+	if !created {
+		log.Info().Str("user_id", userID.String()).Str("job_id", job.ID.String()).Msg("Data export is already in progress")
+	}
+
+	w.Header().Set("Location", "/users/"+userID.String()+"/export/"+job.ID.String())
+	respondWithJSON(w, http.StatusAccepted, job)
+}
+
+func (h *ExportHandler) handleGetLatestExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	job, err := h.service.GetLatestJob(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get latest data export via service")
+		respondWithError(w, mapErrorToStatusCode(err), exportErrorMessage(err, "Failed to get data export"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+func (h *ExportHandler) handleGetExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	jobID, ok := parseIDParam(w, r, "jobID")
+	if !ok {
+		return
+	}
+
+	job, err := h.service.GetJob(r.Context(), userID, jobID)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", jobID.String()).Msg("Failed to get data export via service")
+		respondWithError(w, mapErrorToStatusCode(err), exportErrorMessage(err, "Failed to get data export"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, job)
+}
+
+// handleDownloadExport отдаёт архив с диска потоком, поддерживая докачку через Range.
+func (h *ExportHandler) handleDownloadExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+	jobID, ok := parseIDParam(w, r, "jobID")
+	if !ok {
+		return
+	}
+
+	job, file, err := h.service.OpenResult(r.Context(), userID, jobID)
+	if err != nil {
+		log.Error().Err(err).Str("job_id", jobID.String()).Msg("Failed to open data export via service")
+		respondWithError(w, mapErrorToStatusCode(err), exportErrorMessage(err, "Failed to download data export"))
+		return
+	}
+	defer file.Close()
+
+	// Большой архив может скачиваться дольше WriteTimeout сервера
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Warn().Err(err).Msg("Failed to disable write deadline for data export download")
+	}
+
+	fileName := "user-" + userID.String() + "-export.zip"
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+	w.Header().Set("Cache-Control", "no-store")
+
+	var modified time.Time
+	if job.CompletedAt != nil {
+		modified = *job.CompletedAt
+	}
+	http.ServeContent(w, r, fileName, modified, file)
+}
+
+func parseIDParam(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	param := chi.URLParam(r, name)
+	id, err := uuid.FromString(param)
+	if err != nil {
+		log.Error().Err(err).Str(name, param).Msg("Failed to parse id parameter from URL")
+		respondWithError(w, http.StatusBadRequest, "Invalid "+name+" parameter")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func exportErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		return "User not found"
+	case errors.Is(err, export.ErrJobNotFound):
+		return "Data export not found"
+	case errors.Is(err, export.ErrJobNotReady):
+		return "Data export is not ready yet"
+	case errors.Is(err, export.ErrJobExpired):
+		return "Data export has expired, request a new one"
+	default:
+		return fallback
+	}
+}
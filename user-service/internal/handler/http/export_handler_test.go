@@ -0,0 +1,143 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockExportService struct {
+	mock.Mock
+}
+
+func (m *MockExportService) RequestExport(ctx context.Context, userID uuid.UUID) (*export.Job, bool, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, false, args.Error(2)
+	}
+	return args.Get(0).(*export.Job), args.Bool(1), args.Error(2)
+}
+
+func (m *MockExportService) GetJob(ctx context.Context, userID, jobID uuid.UUID) (*export.Job, error) {
+	args := m.Called(ctx, userID, jobID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*export.Job), args.Error(1)
+}
+
+func (m *MockExportService) GetLatestJob(ctx context.Context, userID uuid.UUID) (*export.Job, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*export.Job), args.Error(1)
+}
+
+func (m *MockExportService) OpenResult(ctx context.Context, userID, jobID uuid.UUID) (*export.Job, io.ReadSeekCloser, error) {
+	args := m.Called(ctx, userID, jobID)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*export.Job), args.Get(1).(io.ReadSeekCloser), args.Error(2)
+}
+
+// nopSeekCloser превращает strings.Reader в io.ReadSeekCloser.
+type nopSeekCloser struct {
+	*strings.Reader
+}
+
+func (nopSeekCloser) Close() error { return nil }
+
+func newExportRouter(service export.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewExportHandler(service, new(MockAPIKeyService), testAdminToken).RegisterRoutes(router)
+	return router
+}
+
+func TestExportHandler_RequestExport_Accepted(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+	job := &export.Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: export.JobPending}
+	mockService.On("RequestExport", mock.Anything, userID).Return(job, true, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/users/"+userID.String()+"/export"))
+
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/users/"+userID.String()+"/export/"+job.ID.String(), rr.Header().Get("Location"))
+	var body export.Job
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, export.JobPending, body.Status)
+	mockService.AssertExpectations(t)
+}
+
+func TestExportHandler_RequestExport_UserNotFound(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+	mockService.On("RequestExport", mock.Anything, userID).Return(nil, false, user.ErrNotFound).Once()
+
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodPost, "/users/"+userID.String()+"/export"))
+
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestExportHandler_Download_Success(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+	job := &export.Job{ID: uuid.Must(uuid.NewV4()), UserID: userID, Status: export.JobSucceeded}
+	mockService.On("OpenResult", mock.Anything, userID, job.ID).
+		Return(job, nopSeekCloser{strings.NewReader("zip-content")}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, adminRequest(http.MethodGet, "/users/"+userID.String()+"/export/"+job.ID.String()+"/download"))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+	assert.Equal(t, "zip-content", rr.Body.String())
+}
+
+func TestExportHandler_Download_NotReadyAndExpired(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+	pendingID := uuid.Must(uuid.NewV4())
+	expiredID := uuid.Must(uuid.NewV4())
+	mockService.On("OpenResult", mock.Anything, userID, pendingID).Return(nil, nil, export.ErrJobNotReady).Once()
+	mockService.On("OpenResult", mock.Anything, userID, expiredID).Return(nil, nil, export.ErrJobExpired).Once()
+	router := newExportRouter(mockService)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, "/users/"+userID.String()+"/export/"+pendingID.String()+"/download"))
+	require.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodGet, "/users/"+userID.String()+"/export/"+expiredID.String()+"/download"))
+	require.Equal(t, http.StatusGone, rr.Code)
+}
+
+func TestExportHandler_RequiresToken(t *testing.T) {
+	mockService := new(MockExportService)
+	userID := uuid.Must(uuid.NewV4())
+
+	rr := httptest.NewRecorder()
+	newExportRouter(mockService).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/users/"+userID.String()+"/export", nil))
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "GetLatestJob", mock.Anything, mock.Anything)
+}
@@ -4,11 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"reflect"
+	"strconv"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/verification"
 )
 
 type ValidationErrorResponse struct {
@@ -39,14 +52,105 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	}
 }
 
+// decodeAndValidate читает JSON тело запроса в payload и проверяет его. При ошибке сам отвечает клиенту и возвращает false.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, validate *validator.Validate, payload interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(payload); err != nil {
+		log.Error().Err(err).Msg("Failed to decode request body")
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return false
+	}
+
+	if err := validate.Struct(payload); err != nil {
+		var validationErrors validator.ValidationErrors
+		if errors.As(err, &validationErrors) {
+			respondWithJSON(w, http.StatusBadRequest, ValidationErrorResponse{
+				Error:   "Validation failed",
+				Details: formatValidationErrors(validationErrors),
+			})
+		} else {
+			log.Error().Err(err).Type("validation_error_type", err).Msg("Unexpected error type during validation")
+			respondWithError(w, http.StatusInternalServerError, "Internal validation error")
+		}
+		return false
+	}
+
+	return true
+}
+
+// respondWithPolicyError отвечает 400 с каждым нарушенным правилом политики паролей в Details под ключом "<field>.<rule>".
+// Возвращает false, если err не ошибка политики и ответ не отправлен.
+func respondWithPolicyError(w http.ResponseWriter, field string, err error) bool {
+	var policyErr *passwords.PolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	details := make(map[string]string, len(policyErr.Violations))
+	for _, violation := range policyErr.Violations {
+		details[field+"."+violation.Rule] = violation.Message
+	}
+	respondWithJSON(w, http.StatusBadRequest, ValidationErrorResponse{
+		Error:   "Password does not meet the policy",
+		Details: details,
+	})
+	return true
+}
+
+// respondWithLockoutError отвечает 429 с заголовком Retry-After, если попытки ввода пароля временно заблокированы.
+// Возвращает false, если err не блокировка и ответ не отправлен.
+func respondWithLockoutError(w http.ResponseWriter, err error) bool {
+	var lockedErr *lockout.LockedError
+	if !errors.As(err, &lockedErr) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+	respondWithError(w, http.StatusTooManyRequests, "Too many failed attempts, try again later")
+	return true
+}
+
 func mapErrorToStatusCode(err error) int {
 	switch {
-	case errors.Is(err, user.ErrNotFound):
+	case errors.Is(err, user.ErrNotFound), errors.Is(err, export.ErrJobNotFound), errors.Is(err, mfa.ErrNotEnrolled):
+		return http.StatusNotFound
+	case errors.Is(err, apikey.ErrNotFound):
 		return http.StatusNotFound
 	case errors.Is(err, user.ErrEmailExists):
 		return http.StatusConflict
-	case errors.Is(err, user.ErrCannotUpdateAdminUser):
+	case errors.Is(err, user.ErrCannotUpdateAdminUser), errors.Is(err, user.ErrWrongPassword):
+		return http.StatusForbidden
+	case errors.Is(err, user.ErrUserHasActiveOrders), errors.Is(err, user.ErrStatusConflict):
+		return http.StatusConflict
+	case errors.Is(err, oidc.ErrAccountNotFound):
+		return http.StatusForbidden
+	case errors.Is(err, oidc.ErrAlreadyLinked):
+		return http.StatusConflict
+	case errors.Is(err, export.ErrJobNotReady), errors.Is(err, verification.ErrAlreadyVerified), errors.Is(err, mfa.ErrAlreadyEnabled):
+		return http.StatusConflict
+	case errors.Is(err, verification.ErrInvalidToken), errors.Is(err, auth.ErrInvalidResetToken), errors.Is(err, user.ErrSamePassword):
+		return http.StatusBadRequest
+	case errors.Is(err, oidc.ErrInvalidState):
+		return http.StatusBadRequest
+	case errors.Is(err, apikey.ErrUnknownScope), errors.Is(err, apikey.ErrScopeRequired), errors.Is(err, apikey.ErrExpiryInPast):
+		return http.StatusBadRequest
+	case errors.Is(err, accesstoken.ErrUnknownRole):
+		return http.StatusBadRequest
+	case errors.Is(err, accesstoken.ErrNotSupport), errors.Is(err, accesstoken.ErrStaffSubject):
 		return http.StatusForbidden
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, session.ErrSessionNotFound):
+		return http.StatusUnauthorized
+	case errors.Is(err, auth.ErrInvalidMFAChallenge), errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, apikey.ErrInvalidKey):
+		return http.StatusUnauthorized
+	case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, verification.ErrResendTooSoon), errors.Is(err, lockout.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, export.ErrJobExpired):
+		return http.StatusGone
+	case errors.Is(err, user.ErrActiveOrdersCheckFailed):
+		return http.StatusServiceUnavailable
 	default:
 		return http.StatusInternalServerError
 	}
@@ -61,7 +165,13 @@ func formatValidationErrors(errs validator.ValidationErrors) map[string]string {
 		case "required":
 			msg = fmt.Sprintf("Field '%s' is required", err.Field())
 		case "min":
-			msg = fmt.Sprintf("Field '%s' must be at least %s characters long", err.Field(), err.Param())
+			if err.Kind() == reflect.Slice {
+				msg = fmt.Sprintf("Field '%s' must contain at least %s items", err.Field(), err.Param())
+			} else {
+				msg = fmt.Sprintf("Field '%s' must be at least %s characters long", err.Field(), err.Param())
+			}
+		case "max":
+			msg = fmt.Sprintf("Field '%s' must be at most %s characters long", err.Field(), err.Param())
 		case "email":
 			msg = fmt.Sprintf("Field '%s' must be a valid email address", err.Field())
 		// Добавьте другие case для других тегов, которые вы используете
@@ -0,0 +1,149 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+)
+
+type EnrollMFAResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type ConfirmMFARequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type ConfirmMFAResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type sessionContextKey struct{}
+
+// MFAHandler обслуживает подключение второго фактора владельцем сессии и его сброс администратором.
+type MFAHandler struct {
+	service    mfa.Service
+	sessions   session.Service
+	adminToken string
+	validate   *validator.Validate
+}
+
+func NewMFAHandler(service mfa.Service, sessions session.Service, adminToken string) *MFAHandler {
+	return &MFAHandler{service: service, sessions: sessions, adminToken: adminToken, validate: validator.New()}
+}
+
+func (h *MFAHandler) RegisterRoutes(router chi.Router) {
+	router.Route("/auth/mfa", func(r chi.Router) {
+		r.Use(requireSession(h.sessions))
+		r.Post("/enroll", h.handleEnroll)
+		r.Post("/confirm", h.handleConfirm)
+	})
+	router.With(requireBearerToken(h.adminToken)).Post("/admin/users/{id}/mfa/reset", h.handleReset)
+}
+
+// requireSession пропускает только запросы с действующим токеном сессии и кладёт сессию в контекст запроса.
+func requireSession(sessions session.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || token == "" {
+				respondWithError(w, http.StatusUnauthorized, "Invalid or missing session token")
+				return
+			}
+
+			sess, err := sessions.Authenticate(r.Context(), token)
+			if err != nil {
+				if !errors.Is(err, session.ErrSessionNotFound) {
+					log.Error().Err(err).Msg("Failed to authenticate session")
+				}
+				respondWithError(w, mapErrorToStatusCode(err), authErrorMessage(err, "Failed to authenticate session"))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey{}, sess)))
+		})
+	}
+}
+
+func sessionFromContext(ctx context.Context) *session.Session {
+	sess, _ := ctx.Value(sessionContextKey{}).(*session.Session)
+	return sess
+}
+
+func (h *MFAHandler) handleEnroll(w http.ResponseWriter, r *http.Request) {
+	sess := sessionFromContext(r.Context())
+
+	enrollment, err := h.service.BeginEnrollment(r.Context(), sess.UserID)
+	if err != nil {
+		if !errors.Is(err, mfa.ErrAlreadyEnabled) {
+			log.Error().Err(err).Str("user_id", sess.UserID.String()).Msg("Failed to begin two-factor enrollment via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), mfaErrorMessage(err, "Failed to begin two-factor enrollment"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, EnrollMFAResponse{Secret: enrollment.Secret, OTPAuthURI: enrollment.URI})
+}
+
+func (h *MFAHandler) handleConfirm(w http.ResponseWriter, r *http.Request) {
+	sess := sessionFromContext(r.Context())
+
+	var requestPayload ConfirmMFARequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	codes, err := h.service.ConfirmEnrollment(r.Context(), sess.UserID, requestPayload.Code)
+	if err != nil {
+		if !errors.Is(err, mfa.ErrInvalidCode) && !errors.Is(err, mfa.ErrNotEnrolled) && !errors.Is(err, mfa.ErrAlreadyEnabled) {
+			log.Error().Err(err).Str("user_id", sess.UserID.String()).Msg("Failed to confirm two-factor enrollment via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), mfaErrorMessage(err, "Failed to confirm two-factor enrollment"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, ConfirmMFAResponse{RecoveryCodes: codes})
+}
+
+func (h *MFAHandler) handleReset(w http.ResponseWriter, r *http.Request) {
+	idParam := chi.URLParam(r, "id")
+	userID, err := uuid.FromString(idParam)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", idParam).Msg("Failed to parse id parameter from URL")
+		respondWithError(w, http.StatusBadRequest, "Invalid id parameter")
+		return
+	}
+
+	if err := h.service.Reset(r.Context(), userID); err != nil {
+		if !errors.Is(err, mfa.ErrNotEnrolled) {
+			log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to reset two-factor authentication via service")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), mfaErrorMessage(err, "Failed to reset two-factor authentication"))
+		return
+	}
+
+	log.Info().Str("user_id", userID.String()).Msg("Two-factor authentication reset by admin")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func mfaErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, mfa.ErrAlreadyEnabled):
+		return "Two-factor authentication is already enabled"
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return "Two-factor authentication is not enabled"
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return "Invalid two-factor authentication code"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,187 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+)
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) BeginEnrollment(ctx context.Context, userID uuid.UUID) (*mfa.Enrollment, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*mfa.Enrollment), args.Error(1)
+}
+
+func (m *MockMFAService) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	args := m.Called(ctx, userID, code)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockMFAService) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Reset(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockSessionService struct {
+	mock.Mock
+}
+
+func (m *MockSessionService) Start(ctx context.Context, userID uuid.UUID, client session.Client) (string, *session.Session, error) {
+	args := m.Called(ctx, userID, client)
+	if args.Get(1) == nil {
+		return "", nil, args.Error(2)
+	}
+	return args.String(0), args.Get(1).(*session.Session), args.Error(2)
+}
+
+func (m *MockSessionService) Authenticate(ctx context.Context, token string) (*session.Session, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*session.Session), args.Error(1)
+}
+
+func (m *MockSessionService) End(ctx context.Context, token string) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func newMFARouter(service mfa.Service, sessions session.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewMFAHandler(service, sessions, testAdminToken).RegisterRoutes(router)
+	return router
+}
+
+func sessionRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer session-token")
+	return req
+}
+
+func TestMFAHandler_Enroll(t *testing.T) {
+	mockService := new(MockMFAService)
+	mockSessions := new(MockSessionService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4())}
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(sess, nil)
+	mockService.On("BeginEnrollment", mock.Anything, sess.UserID).
+		Return(&mfa.Enrollment{Secret: "JBSWY3DPEHPK3PXP", URI: "otpauth://totp/Shop:jane@example.com?secret=JBSWY3DPEHPK3PXP"}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newMFARouter(mockService, mockSessions).ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/mfa/enroll", ""))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.EnrollMFAResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", body.Secret)
+	assert.Contains(t, body.OTPAuthURI, "otpauth://totp/")
+	mockService.AssertExpectations(t)
+}
+
+func TestMFAHandler_Enroll_AlreadyEnabled(t *testing.T) {
+	mockService := new(MockMFAService)
+	mockSessions := new(MockSessionService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4())}
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(sess, nil)
+	mockService.On("BeginEnrollment", mock.Anything, sess.UserID).Return(nil, mfa.ErrAlreadyEnabled).Once()
+
+	rr := httptest.NewRecorder()
+	newMFARouter(mockService, mockSessions).ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/mfa/enroll", ""))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
+
+func TestMFAHandler_RequiresSession(t *testing.T) {
+	mockService := new(MockMFAService)
+	mockSessions := new(MockSessionService)
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(nil, session.ErrSessionNotFound).Once()
+	router := newMFARouter(mockService, mockSessions)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/mfa/enroll", ""))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/auth/mfa/confirm", strings.NewReader(`{"code":"123456"}`)))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	mockService.AssertNotCalled(t, "BeginEnrollment", mock.Anything, mock.Anything)
+	mockSessions.AssertExpectations(t)
+}
+
+func TestMFAHandler_Confirm(t *testing.T) {
+	mockService := new(MockMFAService)
+	mockSessions := new(MockSessionService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4())}
+	mockSessions.On("Authenticate", mock.Anything, "session-token").Return(sess, nil)
+	mockService.On("ConfirmEnrollment", mock.Anything, sess.UserID, "123456").Return([]string{"ABCDE-FGHIJ", "KLMNO-PQRST"}, nil).Once()
+	mockService.On("ConfirmEnrollment", mock.Anything, sess.UserID, "000000").Return(nil, mfa.ErrInvalidCode).Once()
+	router := newMFARouter(mockService, mockSessions)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/mfa/confirm", `{"code":"123456"}`))
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.ConfirmMFAResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, []string{"ABCDE-FGHIJ", "KLMNO-PQRST"}, body.RecoveryCodes)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, sessionRequest(http.MethodPost, "/auth/mfa/confirm", `{"code":"000000"}`))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Invalid two-factor authentication code")
+	mockService.AssertExpectations(t)
+}
+
+func TestMFAHandler_AdminReset(t *testing.T) {
+	mockService := new(MockMFAService)
+	enrolled := uuid.Must(uuid.NewV4())
+	missing := uuid.Must(uuid.NewV4())
+	mockService.On("Reset", mock.Anything, enrolled).Return(nil).Once()
+	mockService.On("Reset", mock.Anything, missing).Return(mfa.ErrNotEnrolled).Once()
+	router := newMFARouter(mockService, new(MockSessionService))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+enrolled.String()+"/mfa/reset"))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, adminRequest(http.MethodPost, "/admin/users/"+missing.String()+"/mfa/reset"))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/admin/users/"+enrolled.String()+"/mfa/reset", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc"
+)
+
+// oidcStateCookie привязывает вход к браузеру, который его начал: без него чужой state, подсунутый
+// по ссылке, завершил бы вход в учётную запись злоумышленника.
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler обслуживает вход через внешний OpenID Connect провайдер.
+type OIDCHandler struct {
+	service oidc.Service
+}
+
+func NewOIDCHandler(service oidc.Service) *OIDCHandler {
+	return &OIDCHandler{service: service}
+}
+
+func (h *OIDCHandler) RegisterRoutes(router chi.Router) {
+	router.Get("/auth/oidc/login", h.handleLogin)
+	router.Get("/auth/oidc/callback", h.handleCallback)
+}
+
+// handleLogin перенаправляет пользователя на страницу входа провайдера.
+func (h *OIDCHandler) handleLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, state, err := h.service.Begin(r.Context())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start oidc login via service")
+		respondWithError(w, http.StatusInternalServerError, "Failed to start sign-in with the identity provider")
+		return
+	}
+
+	// Lax: cookie уходит при возврате с провайдера, это переход верхнего уровня
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: state, Path: "/auth/oidc", HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// handleCallback завершает вход, когда провайдер вернул пользователя с кодом авторизации.
+func (h *OIDCHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/auth/oidc", MaxAge: -1, HttpOnly: true, Secure: true, SameSite: http.SameSiteLaxMode})
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		log.Info().Str("error", providerErr).Str("description", query.Get("error_description")).Msg("Identity provider rejected oidc login")
+		respondWithError(w, http.StatusBadRequest, "Sign-in was cancelled or rejected by the identity provider")
+		return
+	}
+	if query.Get("code") == "" {
+		respondWithError(w, http.StatusBadRequest, "Missing code parameter")
+		return
+	}
+
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(query.Get("state"))) != 1 {
+		log.Warn().Msg("Oidc login rejected: state does not match the browser that started it")
+		respondWithError(w, http.StatusBadRequest, oidcErrorMessage(oidc.ErrInvalidState, "Failed to login"))
+		return
+	}
+
+	result, err := h.service.Complete(r.Context(), query.Get("code"), query.Get("state"), clientFromRequest(r))
+	if err != nil {
+		if mapErrorToStatusCode(err) == http.StatusInternalServerError {
+			log.Error().Err(err).Msg("Failed to complete oidc login via service")
+		} else {
+			log.Warn().Err(err).Msg("Oidc login rejected")
+		}
+		respondWithError(w, mapErrorToStatusCode(err), oidcErrorMessage(err, "Failed to login"))
+		return
+	}
+
+	if result.MFAToken != "" {
+		respondWithJSON(w, http.StatusOK, MFAChallengeResponse{MFARequired: true, MFAToken: result.MFAToken, ExpiresAt: result.MFAExpiresAt})
+		return
+	}
+	respondWithJSON(w, http.StatusOK, LoginResponse{Token: result.Token, ExpiresAt: result.Session.ExpiresAt, UserID: result.Session.UserID})
+}
+
+func oidcErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, oidc.ErrInvalidState):
+		return "Sign-in attempt is invalid or expired, start again"
+	case errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidToken):
+		return "Identity provider response could not be verified"
+	case errors.Is(err, oidc.ErrAccountNotFound):
+		return "No active account matches this identity"
+	case errors.Is(err, oidc.ErrAlreadyLinked):
+		return "Account is already linked to another identity of this provider"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,169 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+)
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) Begin(ctx context.Context) (string, string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.String(1), args.Error(2)
+}
+
+func (m *MockOIDCService) Complete(ctx context.Context, code, state string, client session.Client) (*auth.LoginResult, error) {
+	args := m.Called(ctx, code, state, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.LoginResult), args.Error(1)
+}
+
+func newOIDCRouter(service oidc.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewOIDCHandler(service).RegisterRoutes(router)
+	return router
+}
+
+// callbackRequest возвращает запрос возврата с провайдера из браузера, который начал вход со state.
+func callbackRequest(query, state string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/callback?"+query, nil)
+	if state != "" {
+		req.AddCookie(&http.Cookie{Name: "oidc_state", Value: state})
+	}
+	return req
+}
+
+func TestOIDCHandler_Login_RedirectsToProvider(t *testing.T) {
+	mockService := new(MockOIDCService)
+	mockService.On("Begin", mock.Anything).Return("https://idp.example.com/authorize?state=abc", "abc", nil).Once()
+
+	rr := httptest.NewRecorder()
+	newOIDCRouter(mockService).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/oidc/login", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "https://idp.example.com/authorize?state=abc", rr.Header().Get("Location"))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	// state запоминается в браузере, который начал вход
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "oidc_state", cookies[0].Name)
+	assert.Equal(t, "abc", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	mockService.AssertExpectations(t)
+}
+
+func TestOIDCHandler_Callback(t *testing.T) {
+	mockService := new(MockOIDCService)
+	sess := &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), ExpiresAt: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)}
+	mockService.On("Complete", mock.Anything, "good-code", "state-1", mock.MatchedBy(func(client session.Client) bool {
+		return client.IP == "192.0.2.1"
+	})).Return(&auth.LoginResult{Token: "session-token", Session: sess}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newOIDCRouter(mockService).ServeHTTP(rr, callbackRequest("code=good-code&state=state-1", "state-1"))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.LoginResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, "session-token", body.Token)
+	assert.Equal(t, sess.UserID, body.UserID)
+
+	// Использованный state стирается из браузера
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "oidc_state", cookies[0].Name)
+	assert.Negative(t, cookies[0].MaxAge)
+	mockService.AssertExpectations(t)
+}
+
+func TestOIDCHandler_Callback_MFARequired(t *testing.T) {
+	mockService := new(MockOIDCService)
+	expiresAt := time.Date(2030, 1, 1, 0, 5, 0, 0, time.UTC)
+	mockService.On("Complete", mock.Anything, "good-code", "state-1", mock.Anything).
+		Return(&auth.LoginResult{MFAToken: "mfa-token", MFAExpiresAt: expiresAt}, nil).Once()
+
+	rr := httptest.NewRecorder()
+	newOIDCRouter(mockService).ServeHTTP(rr, callbackRequest("code=good-code&state=state-1", "state-1"))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	var body userHandler.MFAChallengeResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.True(t, body.MFARequired)
+	assert.Equal(t, "mfa-token", body.MFAToken)
+	assert.NotContains(t, rr.Body.String(), `"token"`, "no session before the second factor")
+	mockService.AssertExpectations(t)
+}
+
+func TestOIDCHandler_Callback_StateMustMatchBrowser(t *testing.T) {
+	mockService := new(MockOIDCService)
+	router := newOIDCRouter(mockService)
+
+	// Ссылка с чужим state, открытая в браузере жертвы, не завершает вход
+	for name, req := range map[string]*http.Request{
+		"no cookie":    callbackRequest("code=code&state=attacker-state", ""),
+		"other cookie": callbackRequest("code=code&state=attacker-state", "victim-state"),
+		"no state":     callbackRequest("code=code", "victim-state"),
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, name)
+		assert.Contains(t, rr.Body.String(), "start again", name)
+	}
+	mockService.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestOIDCHandler_Callback_Errors(t *testing.T) {
+	tests := map[string]struct {
+		err  error
+		want int
+	}{
+		"invalid state":    {err: oidc.ErrInvalidState, want: http.StatusBadRequest},
+		"invalid token":    {err: oidc.ErrInvalidToken, want: http.StatusUnauthorized},
+		"exchange failed":  {err: oidc.ErrExchange, want: http.StatusUnauthorized},
+		"no account":       {err: oidc.ErrAccountNotFound, want: http.StatusForbidden},
+		"already linked":   {err: oidc.ErrAlreadyLinked, want: http.StatusConflict},
+		"provider is down": {err: assert.AnError, want: http.StatusInternalServerError},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockService := new(MockOIDCService)
+			mockService.On("Complete", mock.Anything, "code", "state", mock.Anything).Return(nil, tt.err).Once()
+
+			rr := httptest.NewRecorder()
+			newOIDCRouter(mockService).ServeHTTP(rr, callbackRequest("code=code&state=state", "state"))
+			assert.Equal(t, tt.want, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	mockService := new(MockOIDCService)
+	router := newOIDCRouter(mockService)
+	for _, query := range []string{"error=access_denied&state=state", "state=state"} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, callbackRequest(query, "state"))
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+	mockService.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// RealIP заменяет RemoteAddr адресом клиента из X-Forwarded-For или X-Real-IP, но только если запрос пришёл
+// от доверенного прокси. Иначе заголовки игнорируются: их может подставить сам клиент, чтобы обойти лимиты по IP.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, ok := parseIP(r.RemoteAddr); ok && isTrusted(trusted, peer) {
+				if client, ok := forwardedClient(r, trusted); ok {
+					r.RemoteAddr = client.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClient идёт по X-Forwarded-For справа налево и возвращает первый адрес не из доверенных прокси:
+// левые записи добавил клиент, им верить нельзя. Без X-Forwarded-For берётся X-Real-IP.
+func forwardedClient(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		return parseIP(r.Header.Get("X-Real-IP"))
+	}
+	for _, hop := range slices.Backward(hops) {
+		addr, ok := parseIP(hop)
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if !isTrusted(trusted, addr) {
+			return addr, true
+		}
+	}
+	return netip.Addr{}, false
+}
+
+func isTrusted(trusted []netip.Prefix, addr netip.Addr) bool {
+	return slices.ContainsFunc(trusted, func(prefix netip.Prefix) bool { return prefix.Contains(addr) })
+}
+
+// parseIP разбирает адрес с портом или без него.
+func parseIP(raw string) (netip.Addr, bool) {
+	raw = strings.TrimSpace(raw)
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
@@ -11,6 +11,9 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/gofrs/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/accesstoken"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
 )
 
@@ -18,44 +21,74 @@ type CreateUserRequest struct {
 	FirstName string `json:"first_name" validate:"required,min=2"`
 	LastName  string `json:"last_name" validate:"required,min=2"`
 	Email     string `json:"email" validate:"required,email"`
-	Password  string `json:"password" validate:"required,min=8"`
+	Password  string `json:"password" validate:"required"` // Длину и состав проверяет политика паролей в сервисе
 }
 
+// UpdateUserRequest меняет только профиль. Пароль меняется через POST /users/{id}/password.
 type UpdateUserRequest struct {
-	FirstName string  `json:"first_name" validate:"required,min=2"`
-	LastName  string  `json:"last_name" validate:"required,min=2"`
-	Email     string  `json:"email" validate:"required,email"`
-	Password  *string `json:"password,omitempty" validate:"omitempty,min=8"`
+	FirstName string `json:"first_name" validate:"required,min=2"`
+	LastName  string `json:"last_name" validate:"required,min=2"`
+	Email     string `json:"email" validate:"required,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type GetUsersBatchRequest struct {
+	IDs []uuid.UUID `json:"ids" validate:"required,min=1"`
+}
+
+type GetUsersBatchResponse struct {
+	Users      []UserResponse `json:"users"`
+	MissingIDs []uuid.UUID    `json:"missing_ids"`
 }
 
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID            uuid.UUID `json:"id"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	Status        string    `json:"status"`
+	EmailVerified bool      `json:"email_verified"` // Пока email не подтверждён, пользователь не может оформлять заказы
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
 }
 
 type UserHandler struct {
-	service  user.Service
-	validate *validator.Validate
+	service       user.Service
+	sessions      session.Service
+	tokens        accesstoken.Service
+	keys          apikey.Service
+	internalToken string
+	validate      *validator.Validate
 }
 
-func NewUserHandler(service user.Service) *UserHandler {
+// NewUserHandler создаёт обработчик пользователей. Менять, удалять аккаунт и его пароль может только сам пользователь:
+// sessions и tokens проверяют его сессию или токен доступа. Пакетный запрос /users/batch принимает internalToken
+// или API-ключ с правом users:read; с пустым internalToken он отвечает 503.
+func NewUserHandler(service user.Service, sessions session.Service, tokens accesstoken.Service, keys apikey.Service, internalToken string) *UserHandler {
 	validate := validator.New()
 	return &UserHandler{
-		service:  service,
-		validate: validate,
+		service:       service,
+		sessions:      sessions,
+		tokens:        tokens,
+		keys:          keys,
+		internalToken: internalToken,
+		validate:      validate,
 	}
 }
 
 func (h *UserHandler) RegisterRoutes(router chi.Router) {
 	router.Post("/users", h.handleCreateUser)
+	router.With(requireTokenOrAPIKey(h.internalToken, h.keys, apikey.ScopeUsersRead)).Post("/users/batch", h.handleGetUsersBatch)
 	router.Get("/users/{id}", h.handleGetUserByID)
 	router.Get("/users/email/{email}", h.handleGetUserByEmail)
-	router.Put("/users/{id}", h.handleUpdateUser)
-	router.Delete("/users/{id}", h.handleDeleteUser)
+	self := router.With(requireSelf(h.sessions, h.tokens, "id"))
+	self.Put("/users/{id}", h.handleUpdateUser)
+	self.Post("/users/{id}/password", h.handleChangePassword)
+	self.Delete("/users/{id}", h.handleDeleteUser)
 }
 
 func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
@@ -98,6 +131,10 @@ func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 
 	createdUser, err := h.service.CreateUser(r.Context(), &domainUser)
 	if err != nil {
+		if respondWithPolicyError(w, "Password", err) {
+			return
+		}
+
 		log.Error().Err(err).Msg("Failed to create user via service")
 
 		statusCode := mapErrorToStatusCode(err)
@@ -115,12 +152,14 @@ func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	responsePayload := UserResponse{
-		ID:        createdUser.ID,
-		FirstName: createdUser.FirstName,
-		LastName:  createdUser.LastName,
-		Email:     createdUser.Email,
-		CreatedAt: createdUser.CreatedAt,
-		UpdatedAt: createdUser.UpdatedAt,
+		ID:            createdUser.ID,
+		FirstName:     createdUser.FirstName,
+		LastName:      createdUser.LastName,
+		Email:         createdUser.Email,
+		Status:        string(createdUser.Status),
+		EmailVerified: createdUser.EmailVerifiedAt != nil,
+		CreatedAt:     createdUser.CreatedAt,
+		UpdatedAt:     createdUser.UpdatedAt,
 	}
 
 	respondWithJSON(w, http.StatusCreated, responsePayload)
@@ -154,12 +193,75 @@ func (h *UserHandler) handleGetUserByID(w http.ResponseWriter, r *http.Request)
 	}
 
 	responsePayload := UserResponse{
-		ID:        foundUser.ID,
-		FirstName: foundUser.FirstName,
-		LastName:  foundUser.LastName,
-		Email:     foundUser.Email,
-		CreatedAt: foundUser.CreatedAt,
-		UpdatedAt: foundUser.UpdatedAt,
+		ID:            foundUser.ID,
+		FirstName:     foundUser.FirstName,
+		LastName:      foundUser.LastName,
+		Email:         foundUser.Email,
+		Status:        string(foundUser.Status),
+		EmailVerified: foundUser.EmailVerifiedAt != nil,
+		CreatedAt:     foundUser.CreatedAt,
+		UpdatedAt:     foundUser.UpdatedAt,
+	}
+
+	respondWithJSON(w, http.StatusOK, responsePayload)
+}
+
+func (h *UserHandler) handleGetUsersBatch(w http.ResponseWriter, r *http.Request) {
+	var requestPayload GetUsersBatchRequest
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&requestPayload)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to decode users batch request")
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	err = h.validate.Struct(requestPayload)
+	if err != nil {
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if ok {
+			details := formatValidationErrors(validationErrors)
+			errorResponse := ValidationErrorResponse{
+				Error:   "Validation failed",
+				Details: details,
+			}
+			respondWithJSON(w, http.StatusBadRequest, errorResponse)
+		} else {
+			log.Error().Err(err).Type("validation_error_type", err).Msg("Unexpected error type during validation")
+			respondWithError(w, http.StatusInternalServerError, "Internal validation error")
+		}
+		return
+	}
+
+	if len(requestPayload.IDs) > user.MaxBatchSize {
+		respondWithError(w, http.StatusBadRequest, fmt.Sprintf("At most %d ids per request", user.MaxBatchSize))
+		return
+	}
+
+	foundUsers, missingIDs, err := h.service.GetUsersByIDs(r.Context(), requestPayload.IDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get users by ids via service")
+		respondWithError(w, mapErrorToStatusCode(err), "Failed to get users")
+		return
+	}
+
+	responsePayload := GetUsersBatchResponse{
+		Users:      make([]UserResponse, 0, len(foundUsers)),
+		MissingIDs: missingIDs,
+	}
+	for _, foundUser := range foundUsers {
+		responsePayload.Users = append(responsePayload.Users, UserResponse{
+			ID:            foundUser.ID,
+			FirstName:     foundUser.FirstName,
+			LastName:      foundUser.LastName,
+			Email:         foundUser.Email,
+			Status:        string(foundUser.Status),
+			EmailVerified: foundUser.EmailVerifiedAt != nil,
+			CreatedAt:     foundUser.CreatedAt,
+			UpdatedAt:     foundUser.UpdatedAt,
+		})
 	}
 
 	respondWithJSON(w, http.StatusOK, responsePayload)
@@ -192,12 +294,14 @@ func (h *UserHandler) handleGetUserByEmail(w http.ResponseWriter, r *http.Reques
 	}
 
 	responsePayload := UserResponse{
-		ID:        foundUser.ID,
-		FirstName: foundUser.FirstName,
-		LastName:  foundUser.LastName,
-		Email:     foundUser.Email,
-		CreatedAt: foundUser.CreatedAt,
-		UpdatedAt: foundUser.UpdatedAt,
+		ID:            foundUser.ID,
+		FirstName:     foundUser.FirstName,
+		LastName:      foundUser.LastName,
+		Email:         foundUser.Email,
+		Status:        string(foundUser.Status),
+		EmailVerified: foundUser.EmailVerifiedAt != nil,
+		CreatedAt:     foundUser.CreatedAt,
+		UpdatedAt:     foundUser.UpdatedAt,
 	}
 
 	respondWithJSON(w, http.StatusOK, responsePayload)
@@ -249,10 +353,6 @@ func (h *UserHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		Email:     requestPayload.Email,
 	}
 
-	if requestPayload.Password != nil {
-		domainUser.PasswordHash = *requestPayload.Password
-	}
-
 	err = h.service.UpdateUser(r.Context(), &domainUser)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to update user via service")
@@ -276,6 +376,50 @@ func (h *UserHandler) handleUpdateUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *UserHandler) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var requestPayload ChangePasswordRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	// Сессия, из которой сменили пароль, остаётся открытой, остальные отзываются.
+	// С токеном доступа сессии нет, и отзываются все
+	currentSession := uuid.Nil
+	if sess := sessionFromContext(r.Context()); sess != nil {
+		currentSession = sess.ID
+	}
+	err := h.service.ChangePassword(r.Context(), userID, currentSession, requestPayload.CurrentPassword, requestPayload.NewPassword, clientFromRequest(r).IP)
+	if err != nil {
+		if respondWithPolicyError(w, "NewPassword", err) || respondWithLockoutError(w, err) {
+			return
+		}
+
+		var clientMessage string
+
+		switch {
+		case errors.Is(err, user.ErrNotFound):
+			clientMessage = "User not found"
+		case errors.Is(err, user.ErrWrongPassword):
+			clientMessage = "Current password is incorrect"
+		case errors.Is(err, user.ErrSamePassword):
+			clientMessage = "New password must differ from the current one"
+		default:
+			log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to change password via service")
+			clientMessage = "Failed to change password"
+		}
+
+		respondWithError(w, mapErrorToStatusCode(err), clientMessage)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *UserHandler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 	idParam := chi.URLParam(r, "id")
 	userID, err := uuid.FromString(idParam)
@@ -294,9 +438,14 @@ func (h *UserHandler) handleDeleteUser(w http.ResponseWriter, r *http.Request) {
 
 		var clientMessage string
 
-		if errors.Is(err, user.ErrNotFound) {
+		switch {
+		case errors.Is(err, user.ErrNotFound):
 			clientMessage = "User not found"
-		} else {
+		case errors.Is(err, user.ErrUserHasActiveOrders):
+			clientMessage = "User has active orders"
+		case errors.Is(err, user.ErrActiveOrdersCheckFailed):
+			clientMessage = "Unable to verify user's orders, try again later"
+		default:
 			clientMessage = "Failed to delete user"
 		}
 
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"          // Для http.StatusOK и др.
 	"net/http/httptest" // Для ResponseRecorder и NewRequest
+	"strings"
 	"testing"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
 )
 
@@ -41,6 +44,14 @@ func (m *MockUserService) GetUserByID(ctx context.Context, id uuid.UUID) (*user.
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
+func (m *MockUserService) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, []uuid.UUID, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).([]user.User), args.Get(1).([]uuid.UUID), args.Error(2)
+}
+
 func (m *MockUserService) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -54,14 +65,44 @@ func (m *MockUserService) UpdateUser(ctx context.Context, u *user.User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserService) ChangePassword(ctx context.Context, id, currentSession uuid.UUID, currentPassword, newPassword, ip string) error {
+	args := m.Called(ctx, id, currentSession, currentPassword, newPassword, ip)
+	return args.Error(0)
+}
+
 func (m *MockUserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockUserService) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context, limit int) (int, error) {
+	args := m.Called(ctx, limit)
+	return args.Int(0), args.Error(1)
+}
+
 func TestUserHandler_handleCreateUser_Success(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 
 	requestDTO := userHandler.CreateUserRequest{
 		FirstName: "Test",
@@ -114,7 +155,7 @@ func TestUserHandler_handleCreateUser_Success(t *testing.T) {
 
 func TestUserHandler_handleCreateUser_EmailExists(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 
 	requestDTO := userHandler.CreateUserRequest{
 		FirstName: "Test",
@@ -150,7 +191,7 @@ func TestUserHandler_handleCreateUser_EmailExists(t *testing.T) {
 
 func TestUserHandler_handleCreateUser_InvalidJSON(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 
 	invalidJsonString := `{"first_name": "Test", "last_name": "User", "email": "invalid@example.com" "password": "pass}`
 
@@ -173,7 +214,7 @@ func TestUserHandler_handleCreateUser_InvalidJSON(t *testing.T) {
 
 func TestUserHandler_handleCreateUser_ValidationError(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 
 	requestUser := userHandler.CreateUserRequest{
 		FirstName: "J",
@@ -206,20 +247,20 @@ func TestUserHandler_handleCreateUser_ValidationError(t *testing.T) {
 	assert.Contains(t, errorDetails["FirstName"], "Field 'FirstName' must be at least 2 characters long")
 	assert.Contains(t, errorDetails["LastName"], "Field 'LastName' is required")
 	assert.Contains(t, errorDetails["Email"], "Field 'Email' must be a valid email address")
-	assert.Contains(t, errorDetails["Password"], "Field 'Password' must be at least 8 characters long")
+	assert.NotContains(t, errorDetails, "Password") // Длину пароля проверяет политика паролей в сервисе
 
 	mockService.AssertNotCalled(t, "CreateUser", mock.Anything, mock.AnythingOfType("*user.User"))
 }
 
 func TestUserHandler_handleUpdateUser_InvalidJSON(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	invalidJsonString := `{"first_name": "Test", "last_name": "User", "email": "invalid@example.com" "password": "pass}`
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBufferString(invalidJsonString))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -239,9 +280,8 @@ func TestUserHandler_handleUpdateUser_InvalidJSON(t *testing.T) {
 
 func TestUserHandler_handleUpdateUser_ValidationError(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	requestUser := userHandler.UpdateUserRequest{
 		FirstName: "U",
@@ -253,6 +293,7 @@ func TestUserHandler_handleUpdateUser_ValidationError(t *testing.T) {
 	require.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -279,9 +320,8 @@ func TestUserHandler_handleUpdateUser_ValidationError(t *testing.T) {
 
 func TestUserHandler_handleUpdateUser_NotFound(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	requestUser := userHandler.UpdateUserRequest{
 		FirstName: "User",
@@ -297,6 +337,7 @@ func TestUserHandler_handleUpdateUser_NotFound(t *testing.T) {
 	require.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -317,9 +358,8 @@ func TestUserHandler_handleUpdateUser_NotFound(t *testing.T) {
 
 func TestUserHandler_handleUpdateUser_EmailExists(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	requestUser := userHandler.UpdateUserRequest{
 		FirstName: "User",
@@ -335,6 +375,7 @@ func TestUserHandler_handleUpdateUser_EmailExists(t *testing.T) {
 	require.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -355,7 +396,8 @@ func TestUserHandler_handleUpdateUser_EmailExists(t *testing.T) {
 
 func TestUserHandler_handleUpdateUser_InvalidUUID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	invalidID := "not-a-uuid"
 
@@ -368,6 +410,7 @@ func TestUserHandler_handleUpdateUser_InvalidUUID(t *testing.T) {
 	require.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+invalidID, bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -386,16 +429,15 @@ func TestUserHandler_handleUpdateUser_InvalidUUID(t *testing.T) {
 	mockService.AssertNotCalled(t, "UpdateUser")
 }
 
-func TestUserHandler_handleUpdateUser_Success_NoPasswordChange(t *testing.T) {
+func TestUserHandler_handleUpdateUser_Success(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	requestUser := userHandler.UpdateUserRequest{
 		FirstName: "User",
 		LastName:  "Test",
 		Email:     "mail@example.com",
-		Password:  nil,
 	}
 
 	mockService.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
@@ -412,6 +454,7 @@ func TestUserHandler_handleUpdateUser_Success_NoPasswordChange(t *testing.T) {
 	require.NoError(t, err)
 
 	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBuffer(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	req.Header.Set("Content-Type", "application/json")
 
 	rr := httptest.NewRecorder()
@@ -425,58 +468,82 @@ func TestUserHandler_handleUpdateUser_Success_NoPasswordChange(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
-func TestUserHandler_handleUpdateUser_Success_WithPasswordChange(t *testing.T) {
+func TestUserHandler_handleUpdateUser_PasswordNotAccepted(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
-	rawPassword := "new-password"
+	reqBody := `{"first_name":"User","last_name":"Test","email":"mail@example.com","password":"new-password"}`
+	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), strings.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
+	req.Header.Set("Content-Type", "application/json")
 
-	requestDTO := userHandler.UpdateUserRequest{
-		FirstName: "User",
-		LastName:  "Test",
-		Email:     "mail@example.com",
-		Password:  &rawPassword,
-	}
+	rr := httptest.NewRecorder()
 
-	mockService.On("UpdateUser", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
-		return u.ID == userID &&
-			u.FirstName == requestDTO.FirstName &&
-			u.LastName == requestDTO.LastName &&
-			u.Email == requestDTO.Email &&
-			u.PasswordHash == rawPassword
-	})).
-		Return(nil).
-		Once()
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
 
-	reqBody, err := json.Marshal(requestDTO)
-	require.NoError(t, err)
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
 
-	req := httptest.NewRequest(http.MethodPut, "/users/"+userID.String(), bytes.NewBuffer(reqBody))
-	req.Header.Set("Content-Type", "application/json")
+	mockService.AssertNotCalled(t, "UpdateUser", mock.Anything, mock.Anything)
+}
 
-	rr := httptest.NewRecorder()
+func TestUserHandler_handleChangePassword(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
+
+	mockService.On("ChangePassword", mock.Anything, userID, testSessionID, "old-password", "new-password", "192.0.2.1").Return(nil).Once()
+	mockService.On("ChangePassword", mock.Anything, userID, testSessionID, "guessed-password", "new-password", "192.0.2.1").Return(user.ErrWrongPassword).Once()
+	mockService.On("ChangePassword", mock.Anything, userID, testSessionID, "old-password", "old-password", "192.0.2.1").Return(user.ErrSamePassword).Once()
+	mockService.On("ChangePassword", mock.Anything, userID, testSessionID, "old-password", "short", "192.0.2.1").
+		Return(&passwords.PolicyError{Violations: []passwords.Violation{{Rule: passwords.RuleMinLength, Message: "too short"}}}).
+		Once()
+	mockService.On("ChangePassword", mock.Anything, userID, testSessionID, "brute-force", "new-password", "192.0.2.1").
+		Return(&lockout.LockedError{RetryAfter: 90 * time.Second}).
+		Once()
 
 	router := chi.NewRouter()
 	handler.RegisterRoutes(router)
 
-	router.ServeHTTP(rr, req)
-	require.Equal(t, http.StatusNoContent, rr.Code)
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		message string
+	}{
+		{"success", `{"current_password":"old-password","new_password":"new-password"}`, http.StatusNoContent, ""},
+		{"wrong current password", `{"current_password":"guessed-password","new_password":"new-password"}`, http.StatusForbidden, "Current password is incorrect"},
+		{"same password", `{"current_password":"old-password","new_password":"old-password"}`, http.StatusBadRequest, "must differ"},
+		{"short new password", `{"current_password":"old-password","new_password":"short"}`, http.StatusBadRequest, "NewPassword.min_length"},
+		{"too many attempts", `{"current_password":"brute-force","new_password":"new-password"}`, http.StatusTooManyRequests, "Too many failed attempts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/users/"+userID.String()+"/password", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+testSessionToken)
+			router.ServeHTTP(rr, req)
+			require.Equal(t, tt.status, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.message)
+		})
+	}
 
 	mockService.AssertExpectations(t)
 }
 
 func TestUserHandler_handleDeleteUser_Success(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	mockService.On("DeleteUser", mock.Anything, userID).
 		Return(nil).
 		Once()
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/"+userID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	rr := httptest.NewRecorder()
 
 	router := chi.NewRouter()
@@ -490,15 +557,15 @@ func TestUserHandler_handleDeleteUser_Success(t *testing.T) {
 
 func TestUserHandler_handleDeleteUser_NotFound(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
-
 	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 
 	mockService.On("DeleteUser", mock.Anything, userID).
 		Return(user.ErrNotFound).
 		Once()
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/"+userID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	rr := httptest.NewRecorder()
 
 	router := chi.NewRouter()
@@ -510,12 +577,37 @@ func TestUserHandler_handleDeleteUser_NotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestUserHandler_handleDeleteUser_HasActiveOrders(t *testing.T) {
+	mockService := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
+
+	mockService.On("DeleteUser", mock.Anything, userID).
+		Return(user.ErrUserHasActiveOrders).
+		Once()
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/"+userID.String(), nil)
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusConflict, rr.Code)
+	require.Contains(t, rr.Body.String(), "User has active orders")
+
+	mockService.AssertExpectations(t)
+}
+
 func TestUserHandler_handleDeleteUser_InvalidUUID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	userID := uuid.Must(uuid.NewV4())
+	handler := userHandler.NewUserHandler(mockService, sessionFor(userID), new(MockAccessTokenService), new(MockAPIKeyService), testInternalToken)
 	invalidID := "invalid_id"
 
 	req := httptest.NewRequest(http.MethodDelete, "/users/"+invalidID, nil)
+	req.Header.Set("Authorization", "Bearer "+testSessionToken)
 	rr := httptest.NewRecorder()
 
 	router := chi.NewRouter()
@@ -527,9 +619,38 @@ func TestUserHandler_handleDeleteUser_InvalidUUID(t *testing.T) {
 	mockService.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.AnythingOfType("uuid.UUID"))
 }
 
+func TestUserHandler_handleCreateUser_PasswordPolicy(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
+
+	mockService.On("CreateUser", mock.Anything, mock.AnythingOfType("*user.User")).
+		Return(nil, &passwords.PolicyError{Violations: []passwords.Violation{
+			{Rule: passwords.RuleMinLength, Message: "Password must be at least 8 characters long"},
+			{Rule: passwords.RuleBreached, Message: "Password has appeared in a data breach, choose another one"},
+		}}).
+		Once()
+
+	reqBody := `{"first_name":"John","last_name":"Doe","email":"john@example.com","password":"123456"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(reqBody))
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+	router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	var errorResponse userHandler.ValidationErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errorResponse))
+	assert.Equal(t, "Password does not meet the policy", errorResponse.Error)
+	assert.Equal(t, map[string]string{
+		"Password.min_length": "Password must be at least 8 characters long",
+		"Password.breached":   "Password has appeared in a data breach, choose another one",
+	}, errorResponse.Details)
+}
+
 func TestUserHandler_handleGetUserByID_Success(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	userID := uuid.Must(uuid.NewV4())
 
 	mockServiceReturnUser := user.User{
@@ -576,7 +697,7 @@ func TestUserHandler_handleGetUserByID_Success(t *testing.T) {
 
 func TestUserHandler_handleGetUserByID_NotFound(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	userID := uuid.Must(uuid.NewV4())
 
 	mockService.On("GetUserByID", mock.Anything, userID).
@@ -602,7 +723,7 @@ func TestUserHandler_handleGetUserByID_NotFound(t *testing.T) {
 
 func TestUserHandler_handleGetUserByID_InvalidUUID(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	invalidID := "not-a-uuid"
 
 	req := httptest.NewRequest(http.MethodGet, "/users/"+invalidID, nil)
@@ -624,7 +745,7 @@ func TestUserHandler_handleGetUserByID_InvalidUUID(t *testing.T) {
 
 func TestUserHandler_handleGetUserByEmail_Success(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	userID := uuid.Must(uuid.NewV4())
 	userEmail := "mail@example.com"
 
@@ -672,7 +793,7 @@ func TestUserHandler_handleGetUserByEmail_Success(t *testing.T) {
 
 func TestUserHandler_handleGetUserByEmail_NotFound(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	userEmail := "mail@example.com"
 
 	mockService.On("GetUserByEmail", mock.Anything, userEmail).
@@ -698,7 +819,7 @@ func TestUserHandler_handleGetUserByEmail_NotFound(t *testing.T) {
 
 func TestUserHandler_handleGetUserByEmail_EmptyEmailAsNotFound(t *testing.T) {
 	mockService := new(MockUserService)
-	handler := userHandler.NewUserHandler(mockService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
 	emptyEmail := ""
 
 	req := httptest.NewRequest(http.MethodGet, "/users/email/"+emptyEmail, nil)
@@ -711,3 +832,95 @@ func TestUserHandler_handleGetUserByEmail_EmptyEmailAsNotFound(t *testing.T) {
 
 	mockService.AssertNotCalled(t, "GetUserByEmail")
 }
+
+func TestUserHandler_handleGetUsersBatch_Success(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
+
+	foundID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+	mockService.On("GetUsersByIDs", mock.Anything, []uuid.UUID{foundID, missingID}).
+		Return([]user.User{{ID: foundID, FirstName: "User", LastName: "Test", Email: "mail@example.com"}}, []uuid.UUID{missingID}, nil).
+		Once()
+
+	body, err := json.Marshal(userHandler.GetUsersBatchRequest{IDs: []uuid.UUID{foundID, missingID}})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/users/batch", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var actualResponse userHandler.GetUsersBatchResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&actualResponse))
+	require.Len(t, actualResponse.Users, 1)
+	assert.Equal(t, foundID, actualResponse.Users[0].ID)
+	assert.Equal(t, "mail@example.com", actualResponse.Users[0].Email)
+	assert.Equal(t, []uuid.UUID{missingID}, actualResponse.MissingIDs)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUserHandler_handleGetUsersBatch_RequiresCredentials(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
+
+	body, err := json.Marshal(userHandler.GetUsersBatchRequest{IDs: []uuid.UUID{uuid.Must(uuid.NewV4())}})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/users/batch", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockService.AssertNotCalled(t, "GetUsersByIDs", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_handleGetUsersBatch_TooManyIDs(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
+
+	ids := make([]uuid.UUID, user.MaxBatchSize+1)
+	for i := range ids {
+		ids[i] = uuid.Must(uuid.NewV4())
+	}
+	body, err := json.Marshal(userHandler.GetUsersBatchRequest{IDs: ids})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/batch", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "GetUsersByIDs", mock.Anything, mock.Anything)
+}
+
+func TestUserHandler_handleGetUsersBatch_EmptyIDs(t *testing.T) {
+	mockService := new(MockUserService)
+	handler := userHandler.NewUserHandler(mockService, nil, nil, new(MockAPIKeyService), testInternalToken)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/batch", bytes.NewReader([]byte(`{"ids": []}`)))
+	req.Header.Set("Authorization", "Bearer "+testInternalToken)
+	rr := httptest.NewRecorder()
+
+	router := chi.NewRouter()
+	handler.RegisterRoutes(router)
+
+	router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	var errorResponse userHandler.ValidationErrorResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&errorResponse))
+	assert.Equal(t, "Field 'IDs' must contain at least 1 items", errorResponse.Details["IDs"])
+	mockService.AssertNotCalled(t, "GetUsersByIDs", mock.Anything, mock.Anything)
+}
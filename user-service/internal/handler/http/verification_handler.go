@@ -0,0 +1,120 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/apikey"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/verification"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// EmailVerificationResponse - ответ на проверку order-service перед оформлением заказа.
+type EmailVerificationResponse struct {
+	UserID          uuid.UUID  `json:"user_id"`
+	EmailVerified   bool       `json:"email_verified"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+}
+
+// VerificationHandler обслуживает подтверждение email. Маршрут /internal защищён токеном внутренних вызовов
+// или ключом API с правом users:read.
+type VerificationHandler struct {
+	service       verification.Service
+	users         user.Service
+	keys          apikey.Service
+	internalToken string
+	validate      *validator.Validate
+}
+
+// NewVerificationHandler создаёт обработчик подтверждения email. С пустым internalToken маршруты /internal отвечают 503.
+func NewVerificationHandler(service verification.Service, users user.Service, keys apikey.Service, internalToken string) *VerificationHandler {
+	return &VerificationHandler{
+		service:       service,
+		users:         users,
+		keys:          keys,
+		internalToken: internalToken,
+		validate:      validator.New(),
+	}
+}
+
+func (h *VerificationHandler) RegisterRoutes(router chi.Router) {
+	router.Post("/users/{id}/verify-email/resend", h.handleResend)
+	router.Post("/auth/verify-email", h.handleVerify)
+	router.With(requireTokenOrAPIKey(h.internalToken, h.keys, apikey.ScopeUsersRead)).Get("/internal/users/{id}/email-verification", h.handleGetVerification)
+}
+
+func (h *VerificationHandler) handleResend(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Resend(r.Context(), userID); err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to resend verification email via service")
+		respondWithError(w, mapErrorToStatusCode(err), verificationErrorMessage(err, "Failed to send verification email"))
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *VerificationHandler) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var requestPayload VerifyEmailRequest
+	if !decodeAndValidate(w, r, h.validate, &requestPayload) {
+		return
+	}
+
+	userID, err := h.service.Verify(r.Context(), requestPayload.Token)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to verify email via service")
+		respondWithError(w, mapErrorToStatusCode(err), verificationErrorMessage(err, "Failed to verify email"))
+		return
+	}
+
+	log.Info().Str("user_id", userID.String()).Msg("Email verified via HTTP")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *VerificationHandler) handleGetVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := parseIDParam(w, r, "id")
+	if !ok {
+		return
+	}
+
+	foundUser, err := h.users.GetUserByID(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("Failed to get user for email verification check")
+		respondWithError(w, mapErrorToStatusCode(err), verificationErrorMessage(err, "Failed to check email verification"))
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, EmailVerificationResponse{
+		UserID:          foundUser.ID,
+		EmailVerified:   foundUser.EmailVerifiedAt != nil,
+		EmailVerifiedAt: foundUser.EmailVerifiedAt,
+	})
+}
+
+func verificationErrorMessage(err error, fallback string) string {
+	switch {
+	case errors.Is(err, user.ErrNotFound):
+		return "User not found"
+	case errors.Is(err, verification.ErrAlreadyVerified):
+		return "Email is already verified"
+	case errors.Is(err, verification.ErrResendTooSoon):
+		return "Verification email was sent recently, try again later"
+	case errors.Is(err, verification.ErrInvalidToken):
+		return "Verification token is invalid or expired"
+	default:
+		return fallback
+	}
+}
@@ -0,0 +1,147 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	userHandler "github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/handler/http"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/verification"
+)
+
+const testInternalToken = "internal-secret"
+
+type MockVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockVerificationService) SendVerification(ctx context.Context, u *user.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockVerificationService) Resend(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockVerificationService) Verify(ctx context.Context, token string) (uuid.UUID, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func newVerificationRouter(service verification.Service, users user.Service) *chi.Mux {
+	router := chi.NewRouter()
+	userHandler.NewVerificationHandler(service, users, new(MockAPIKeyService), testInternalToken).RegisterRoutes(router)
+	return router
+}
+
+func TestVerificationHandler_Verify_Success(t *testing.T) {
+	mockService := new(MockVerificationService)
+	mockService.On("Verify", mock.Anything, "good-token").Return(uuid.Must(uuid.NewV4()), nil).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{"token":"good-token"}`))
+	newVerificationRouter(mockService, new(MockUserService)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestVerificationHandler_Verify_InvalidToken(t *testing.T) {
+	mockService := new(MockVerificationService)
+	mockService.On("Verify", mock.Anything, "used-token").Return(uuid.Nil, verification.ErrInvalidToken).Once()
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{"token":"used-token"}`))
+	newVerificationRouter(mockService, new(MockUserService)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid or expired")
+}
+
+func TestVerificationHandler_Verify_MissingToken(t *testing.T) {
+	mockService := new(MockVerificationService)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/auth/verify-email", strings.NewReader(`{}`))
+	newVerificationRouter(mockService, new(MockUserService)).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "Verify", mock.Anything, mock.Anything)
+}
+
+func TestVerificationHandler_Resend_ErrorMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{name: "sent", err: nil, wantCode: http.StatusAccepted},
+		{name: "already verified", err: verification.ErrAlreadyVerified, wantCode: http.StatusConflict},
+		{name: "too soon", err: verification.ErrResendTooSoon, wantCode: http.StatusTooManyRequests},
+		{name: "unknown user", err: user.ErrNotFound, wantCode: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockVerificationService)
+			userID := uuid.Must(uuid.NewV4())
+			mockService.On("Resend", mock.Anything, userID).Return(tt.err).Once()
+
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/users/"+userID.String()+"/verify-email/resend", nil)
+			newVerificationRouter(mockService, new(MockUserService)).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.wantCode, rr.Code)
+		})
+	}
+}
+
+func TestVerificationHandler_InternalCheck(t *testing.T) {
+	mockUsers := new(MockUserService)
+	verifiedAt := time.Now().UTC().Truncate(time.Second)
+	verifiedID := uuid.Must(uuid.NewV4())
+	pendingID := uuid.Must(uuid.NewV4())
+	mockUsers.On("GetUserByID", mock.Anything, verifiedID).Return(&user.User{ID: verifiedID, EmailVerifiedAt: &verifiedAt}, nil).Once()
+	mockUsers.On("GetUserByID", mock.Anything, pendingID).Return(&user.User{ID: pendingID}, nil).Once()
+	router := newVerificationRouter(new(MockVerificationService), mockUsers)
+
+	for id, want := range map[uuid.UUID]bool{verifiedID: true, pendingID: false} {
+		req := httptest.NewRequest(http.MethodGet, "/internal/users/"+id.String()+"/email-verification", nil)
+		req.Header.Set("Authorization", "Bearer "+testInternalToken)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+		var response userHandler.EmailVerificationResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+		assert.Equal(t, id, response.UserID)
+		assert.Equal(t, want, response.EmailVerified)
+	}
+	mockUsers.AssertExpectations(t)
+}
+
+func TestVerificationHandler_InternalCheckRequiresToken(t *testing.T) {
+	mockUsers := new(MockUserService)
+	userID := uuid.Must(uuid.NewV4())
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/internal/users/"+userID.String()+"/email-verification", nil)
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	newVerificationRouter(new(MockVerificationService), mockUsers).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusUnauthorized, rr.Code)
+	mockUsers.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+}
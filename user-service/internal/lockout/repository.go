@@ -0,0 +1,105 @@
+package lockout
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// Виды счётчиков неудачных попыток.
+const (
+	KindAccount = "account"
+	KindIP      = "ip"
+)
+
+// Key - счётчик неудачных попыток: учётная запись (email в нижнем регистре) или IP клиента.
+type Key struct {
+	Kind    string
+	Subject string
+}
+
+type Repository interface {
+	// BlockedUntil возвращает самый поздний срок блокировки среди keys, ещё не истёкший к now. Нулевое время, если блокировок нет.
+	BlockedUntil(ctx context.Context, keys []Key, now time.Time) (time.Time, error)
+	// AddFailure увеличивает счётчик и возвращает новое значение. Если прошлая ошибка была раньше windowStart, счёт начинается заново.
+	AddFailure(ctx context.Context, key Key, now, windowStart time.Time) (int, error)
+	// Block блокирует попытки до until. Более поздняя блокировка не сокращается.
+	Block(ctx context.Context, key Key, until time.Time) error
+	// Reset удаляет счётчик вместе с блокировкой.
+	Reset(ctx context.Context, key Key) error
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) BlockedUntil(ctx context.Context, keys []Key, now time.Time) (time.Time, error) {
+	kinds := make([]string, len(keys))
+	subjects := make([]string, len(keys))
+	for i, key := range keys {
+		kinds[i] = key.Kind
+		subjects[i] = key.Subject
+	}
+
+	query := `
+		SELECT MAX(f.blocked_until)
+		FROM user_service.login_failures f
+		JOIN UNNEST($1::text[], $2::text[]) AS k(kind, subject) ON k.kind = f.kind AND k.subject = f.subject
+		WHERE f.blocked_until > $3
+	`
+
+	var blockedUntil *time.Time
+	if err := r.db.QueryRow(ctx, query, kinds, subjects, now).Scan(&blockedUntil); err != nil {
+		return time.Time{}, fmt.Errorf("lockout: failed to check login blocks: %w", err)
+	}
+	if blockedUntil == nil {
+		return time.Time{}, nil
+	}
+	return *blockedUntil, nil
+}
+
+func (r *postgresRepository) AddFailure(ctx context.Context, key Key, now, windowStart time.Time) (int, error) {
+	query := `
+		INSERT INTO user_service.login_failures (kind, subject, failures, last_failed_at)
+		VALUES ($1, $2, 1, $3)
+		ON CONFLICT (kind, subject) DO UPDATE
+		SET failures = CASE
+				WHEN login_failures.last_failed_at < $4 THEN 1
+				ELSE login_failures.failures + 1
+			END,
+			last_failed_at = EXCLUDED.last_failed_at
+		RETURNING failures
+	`
+
+	var failures int
+	if err := r.db.QueryRow(ctx, query, key.Kind, key.Subject, now, windowStart).Scan(&failures); err != nil {
+		return 0, fmt.Errorf("lockout: failed to record failed attempt for %s: %w", key.Kind, err)
+	}
+	return failures, nil
+}
+
+func (r *postgresRepository) Block(ctx context.Context, key Key, until time.Time) error {
+	query := `
+		UPDATE user_service.login_failures
+		SET blocked_until = GREATEST(blocked_until, $3)
+		WHERE kind = $1 AND subject = $2
+	`
+	if _, err := r.db.Exec(ctx, query, key.Kind, key.Subject, until); err != nil {
+		return fmt.Errorf("lockout: failed to block %s: %w", key.Kind, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) Reset(ctx context.Context, key Key) error {
+	query := `DELETE FROM user_service.login_failures WHERE kind = $1 AND subject = $2`
+	if _, err := r.db.Exec(ctx, query, key.Kind, key.Subject); err != nil {
+		return fmt.Errorf("lockout: failed to reset %s: %w", key.Kind, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package lockout_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/lockout"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func cleanup(t *testing.T, keys ...lockout.Key) {
+	t.Helper()
+	t.Cleanup(func() {
+		for _, key := range keys {
+			_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.login_failures WHERE kind = $1 AND subject = $2", key.Kind, key.Subject)
+			require.NoError(t, err)
+		}
+	})
+}
+
+func TestRepository_AddFailureCountsWithinWindow(t *testing.T) {
+	repo := lockout.NewRepository(testDB)
+	ctx := context.Background()
+	key := lockout.Key{Kind: lockout.KindAccount, Subject: "lockout.window@example.com"}
+	cleanup(t, key)
+	now := time.Now()
+
+	for want := 1; want <= 3; want++ {
+		failures, err := repo.AddFailure(ctx, key, now, now.Add(-time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, want, failures)
+	}
+
+	// Прошлая ошибка вне окна: счёт начинается заново
+	later := now.Add(2 * time.Hour)
+	failures, err := repo.AddFailure(ctx, key, later, later.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, failures)
+}
+
+func TestRepository_BlockAndReset(t *testing.T) {
+	repo := lockout.NewRepository(testDB)
+	ctx := context.Background()
+	account := lockout.Key{Kind: lockout.KindAccount, Subject: "lockout.block@example.com"}
+	ip := lockout.Key{Kind: lockout.KindIP, Subject: "203.0.113.77"}
+	cleanup(t, account, ip)
+	now := time.Now().Truncate(time.Microsecond)
+
+	for _, key := range []lockout.Key{account, ip} {
+		_, err := repo.AddFailure(ctx, key, now, now.Add(-time.Hour))
+		require.NoError(t, err)
+	}
+	require.NoError(t, repo.Block(ctx, account, now.Add(time.Minute)))
+	require.NoError(t, repo.Block(ctx, ip, now.Add(time.Hour)))
+	// Более короткая блокировка не сокращает уже действующую
+	require.NoError(t, repo.Block(ctx, ip, now.Add(time.Second)))
+
+	until, err := repo.BlockedUntil(ctx, []lockout.Key{account, ip}, now)
+	require.NoError(t, err)
+	assert.True(t, until.Equal(now.Add(time.Hour)), until)
+
+	until, err = repo.BlockedUntil(ctx, []lockout.Key{account}, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.True(t, until.IsZero(), "expired block must be ignored")
+
+	require.NoError(t, repo.Reset(ctx, ip))
+	until, err = repo.BlockedUntil(ctx, []lockout.Key{account, ip}, now)
+	require.NoError(t, err)
+	assert.True(t, until.Equal(now.Add(time.Minute)), until)
+}
@@ -0,0 +1,167 @@
+// Package lockout защищает проверку пароля от подбора: считает неудачные попытки по учётной записи и по IP,
+// замедляет повторные попытки и временно блокирует IP после порога. Учётная запись не блокируется, только
+// замедляется: иначе любой, кто знает email, мог бы закрыть владельцу вход.
+package lockout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+var ErrTooManyAttempts = errors.New("too many failed attempts, try again later")
+
+// LockedError - попытка отклонена без проверки пароля. Сводится к ErrTooManyAttempts через errors.Is.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s (retry after %s)", ErrTooManyAttempts, e.RetryAfter)
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+// Limits - пороги одного вида счётчика.
+type Limits struct {
+	DelayAfter int // С этой ошибки подряд каждая следующая попытка ждёт всё дольше
+	LockAfter  int // На этой ошибке подряд попытки блокируются на LockoutDuration; 0 - не блокировать
+}
+
+// Config задаёт пороги и интервалы защиты от подбора.
+type Config struct {
+	AccountDelayAfter int           // С этой ошибки подряд попытки входа в учётную запись ждут всё дольше, не дольше MaxDelay
+	IP                Limits        // Обычно выше, чем для учётной записи: за одним адресом может быть много пользователей
+	BaseDelay         time.Duration // Задержка после первой ошибки сверх DelayAfter, дальше удваивается
+	MaxDelay          time.Duration
+	LockoutDuration   time.Duration // Блокировка IP после IP.LockAfter ошибок
+	FailureWindow     time.Duration // Ошибки старше окна не учитываются
+}
+
+type Service interface {
+	// Check отклоняет попытку с *LockedError, если заблокирована учётная запись или IP. Пустой ip не проверяется.
+	Check(ctx context.Context, account, ip string) error
+	// RecordFailure учитывает неверный пароль для учётной записи и IP. Учётная запись только замедляется, IP может быть заблокирован.
+	RecordFailure(ctx context.Context, account, ip string) error
+	// RecordSuccess сбрасывает счётчик учётной записи. Счётчик IP сбрасывается только по окну, иначе удачный
+	// вход в свою учётную запись обнулял бы перебор чужих с того же адреса.
+	RecordSuccess(ctx context.Context, account string) error
+	// Unlock снимает задержку учётной записи и обнуляет её счётчик. Вызывается и после сброса пароля.
+	Unlock(ctx context.Context, account string) error
+	// UnlockIP снимает блокировку IP и обнуляет его счётчик.
+	UnlockIP(ctx context.Context, ip string) error
+}
+
+type service struct {
+	repo Repository
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewService(repo Repository, cfg Config) Service {
+	return &service{repo: repo, cfg: cfg, now: time.Now}
+}
+
+// accountKey приводит email к одному виду. Счётчик ведётся и для незарегистрированных адресов,
+// поэтому блокировка не выдаёт, существует ли учётная запись.
+func accountKey(account string) Key {
+	return Key{Kind: KindAccount, Subject: strings.ToLower(strings.TrimSpace(account))}
+}
+
+// ipKey приводит адрес к каноническому виду, чтобы разные записи одного IPv6 адреса попадали в один счётчик.
+func ipKey(ip string) Key {
+	if parsed := net.ParseIP(ip); parsed != nil {
+		ip = parsed.String()
+	}
+	return Key{Kind: KindIP, Subject: ip}
+}
+
+func (s *service) keys(account, ip string) []Key {
+	keys := []Key{accountKey(account)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+func (s *service) Check(ctx context.Context, account, ip string) error {
+	now := s.now()
+	blockedUntil, err := s.repo.BlockedUntil(ctx, s.keys(account, ip), now)
+	if err != nil {
+		return err
+	}
+	if blockedUntil.IsZero() {
+		return nil
+	}
+	return &LockedError{RetryAfter: blockedUntil.Sub(now).Round(time.Second)}
+}
+
+func (s *service) RecordFailure(ctx context.Context, account, ip string) error {
+	now := s.now()
+	for _, key := range s.keys(account, ip) {
+		limits := Limits{DelayAfter: s.cfg.AccountDelayAfter}
+		if key.Kind == KindIP {
+			limits = s.cfg.IP
+		}
+
+		failures, err := s.repo.AddFailure(ctx, key, now, now.Add(-s.cfg.FailureWindow))
+		if err != nil {
+			return err
+		}
+		delay := s.delay(limits, failures)
+		if delay == 0 {
+			continue
+		}
+		if err := s.repo.Block(ctx, key, now.Add(delay)); err != nil {
+			return err
+		}
+		if limits.LockAfter > 0 && failures == limits.LockAfter {
+			log.Warn().Str("kind", key.Kind).Int("failures", failures).Dur("duration", delay).Msg("lockout: too many failed attempts, blocked")
+		}
+	}
+	return nil
+}
+
+// delay возвращает, на сколько заблокировать попытки после failures ошибок подряд: 0 до DelayAfter,
+// затем BaseDelay, удваиваясь до MaxDelay, и LockoutDuration начиная с LockAfter, если он задан.
+func (s *service) delay(limits Limits, failures int) time.Duration {
+	switch {
+	case limits.LockAfter > 0 && failures >= limits.LockAfter:
+		return s.cfg.LockoutDuration
+	case failures < limits.DelayAfter:
+		return 0
+	}
+
+	delay := s.cfg.BaseDelay
+	for i := limits.DelayAfter; i < failures && delay < s.cfg.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, s.cfg.MaxDelay)
+}
+
+func (s *service) RecordSuccess(ctx context.Context, account string) error {
+	return s.repo.Reset(ctx, accountKey(account))
+}
+
+func (s *service) Unlock(ctx context.Context, account string) error {
+	if err := s.repo.Reset(ctx, accountKey(account)); err != nil {
+		return err
+	}
+	log.Info().Msg("lockout: account unlocked")
+	return nil
+}
+
+func (s *service) UnlockIP(ctx context.Context, ip string) error {
+	if err := s.repo.Reset(ctx, ipKey(ip)); err != nil {
+		return err
+	}
+	log.Info().Str("ip", ip).Msg("lockout: ip unlocked")
+	return nil
+}
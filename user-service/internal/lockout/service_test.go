@@ -0,0 +1,262 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) BlockedUntil(ctx context.Context, keys []Key, now time.Time) (time.Time, error) {
+	args := m.Called(ctx, keys, now)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRepository) AddFailure(ctx context.Context, key Key, now, windowStart time.Time) (int, error) {
+	args := m.Called(ctx, key, now, windowStart)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) Block(ctx context.Context, key Key, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Reset(ctx context.Context, key Key) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+var testConfig = Config{
+	AccountDelayAfter: 3,
+	IP:                Limits{DelayAfter: 10, LockAfter: 20},
+	BaseDelay:         time.Second,
+	MaxDelay:          4 * time.Second,
+	LockoutDuration:   15 * time.Minute,
+	FailureWindow:     time.Hour,
+}
+
+// memoryRepository - Repository в памяти с той же семантикой, что у Postgres.
+type memoryRepository struct {
+	failures     map[Key]int
+	lastFailedAt map[Key]time.Time
+	blockedUntil map[Key]time.Time
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{failures: map[Key]int{}, lastFailedAt: map[Key]time.Time{}, blockedUntil: map[Key]time.Time{}}
+}
+
+func (r *memoryRepository) BlockedUntil(_ context.Context, keys []Key, now time.Time) (time.Time, error) {
+	var latest time.Time
+	for _, key := range keys {
+		if until := r.blockedUntil[key]; until.After(now) && until.After(latest) {
+			latest = until
+		}
+	}
+	return latest, nil
+}
+
+func (r *memoryRepository) AddFailure(_ context.Context, key Key, now, windowStart time.Time) (int, error) {
+	if last, ok := r.lastFailedAt[key]; ok && last.Before(windowStart) {
+		r.failures[key] = 0
+	}
+	r.failures[key]++
+	r.lastFailedAt[key] = now
+	return r.failures[key], nil
+}
+
+func (r *memoryRepository) Block(_ context.Context, key Key, until time.Time) error {
+	if until.After(r.blockedUntil[key]) {
+		r.blockedUntil[key] = until
+	}
+	return nil
+}
+
+func (r *memoryRepository) Reset(_ context.Context, key Key) error {
+	delete(r.failures, key)
+	delete(r.lastFailedAt, key)
+	delete(r.blockedUntil, key)
+	return nil
+}
+
+// newTestService возвращает сервис с часами, которые двигает тест.
+func newTestService(cfg Config) (*service, *memoryRepository, *time.Time) {
+	repo := newMemoryRepository()
+	clock := testNow
+	svc := NewService(repo, cfg).(*service)
+	svc.now = func() time.Time { return clock }
+	return svc, repo, &clock
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	require.ErrorIs(t, err, ErrTooManyAttempts)
+	return locked.RetryAfter
+}
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+var (
+	janeKey = Key{Kind: KindAccount, Subject: "jane@example.com"}
+	ipKey7  = Key{Kind: KindIP, Subject: "203.0.113.7"}
+)
+
+func TestService_RecordFailure_AccountIsOnlyDelayed(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, cfg: testConfig, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	windowStart := testNow.Add(-time.Hour)
+
+	// Первые ошибки не задерживают следующую попытку, дальше задержка удваивается до MaxDelay.
+	// Сколько бы ошибок ни набралось, учётная запись не блокируется на LockoutDuration
+	mockRepo.On("AddFailure", ctx, janeKey, testNow, windowStart).Return(2, nil).Once()
+	steps := []struct {
+		failures int
+		delay    time.Duration
+	}{{3, time.Second}, {4, 2 * time.Second}, {5, 4 * time.Second}, {1000, 4 * time.Second}}
+	for _, step := range steps {
+		mockRepo.On("AddFailure", ctx, janeKey, testNow, windowStart).Return(step.failures, nil).Once()
+		mockRepo.On("Block", ctx, janeKey, testNow.Add(step.delay)).Return(nil).Once()
+	}
+
+	for range 5 {
+		require.NoError(t, svc.RecordFailure(ctx, " JANE@example.com", ""))
+	}
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "Block", ctx, janeKey, testNow.Add(testConfig.LockoutDuration))
+}
+
+func TestService_RecordFailure_LocksIP(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, cfg: testConfig, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+
+	// Перебор разных учётных записей с одного адреса блокирует адрес
+	mockRepo.On("AddFailure", ctx, Key{Kind: KindAccount, Subject: "new@example.com"}, testNow, mock.Anything).Return(1, nil).Once()
+	mockRepo.On("AddFailure", ctx, ipKey7, testNow, mock.Anything).Return(testConfig.IP.LockAfter, nil).Once()
+	mockRepo.On("Block", ctx, ipKey7, testNow.Add(15*time.Minute)).Return(nil).Once()
+
+	require.NoError(t, svc.RecordFailure(ctx, "new@example.com", "203.0.113.7"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_RecordFailure_NormalizesIPv6(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, cfg: testConfig, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+
+	mockRepo.On("AddFailure", ctx, janeKey, testNow, mock.Anything).Return(1, nil).Once()
+	mockRepo.On("AddFailure", ctx, Key{Kind: KindIP, Subject: "2001:db8::1"}, testNow, mock.Anything).Return(1, nil).Once()
+
+	require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", "2001:0db8:0000::0001"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Check(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, cfg: testConfig, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+
+	mockRepo.On("BlockedUntil", ctx, []Key{janeKey}, testNow).Return(time.Time{}, nil).Once()
+	mockRepo.On("BlockedUntil", ctx, []Key{janeKey, ipKey7}, testNow).Return(testNow.Add(15*time.Minute), nil).Once()
+
+	require.NoError(t, svc.Check(ctx, "jane@example.com", ""))
+
+	err := svc.Check(ctx, "jane@example.com", "203.0.113.7")
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	require.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.Equal(t, 15*time.Minute, locked.RetryAfter)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Delay(t *testing.T) {
+	svc := &service{cfg: testConfig}
+	limits := Limits{DelayAfter: 1, LockAfter: 1000}
+
+	assert.Equal(t, time.Duration(0), svc.delay(limits, 0))
+	assert.Equal(t, time.Second, svc.delay(limits, 1))
+	assert.Equal(t, 4*time.Second, svc.delay(limits, 999))
+	assert.Equal(t, 15*time.Minute, svc.delay(limits, 1000))
+	assert.Equal(t, 4*time.Second, svc.delay(Limits{DelayAfter: 1}, 1000), "zero LockAfter must never lock")
+}
+
+func TestService_SuccessAndUnlockResetOnlyTheirCounter(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := &service{repo: mockRepo, cfg: testConfig, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+
+	// Удачный вход не сбрасывает счётчик IP, иначе свой вход обнулял бы перебор чужих учётных записей
+	mockRepo.On("Reset", ctx, janeKey).Return(nil).Twice()
+	mockRepo.On("Reset", ctx, ipKey7).Return(nil).Once()
+
+	require.NoError(t, svc.RecordSuccess(ctx, "jane@example.com"))
+	require.NoError(t, svc.Unlock(ctx, "Jane@Example.com"))
+	require.NoError(t, svc.UnlockIP(ctx, "203.0.113.7"))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_AccountDelayGrowsAndExpires(t *testing.T) {
+	svc, _, clock := newTestService(testConfig)
+	ctx := context.Background()
+
+	// Первые ошибки не задерживают следующую попытку
+	for range testConfig.AccountDelayAfter - 1 {
+		require.NoError(t, svc.Check(ctx, "jane@example.com", ""))
+		require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", ""))
+	}
+
+	// Дальше задержка удваивается до MaxDelay и не превращается в блокировку
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second} {
+		require.NoError(t, svc.Check(ctx, "jane@example.com", ""))
+		require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", ""))
+		assert.Equal(t, want, retryAfter(t, svc.Check(ctx, "JANE@example.com ", "")))
+		*clock = clock.Add(want)
+	}
+	require.NoError(t, svc.Check(ctx, "jane@example.com", ""))
+}
+
+func TestService_IPDelayThenLockoutExpires(t *testing.T) {
+	svc, _, clock := newTestService(testConfig)
+	ctx := context.Background()
+
+	// Перебор разных учётных записей с одного адреса сначала замедляет, затем блокирует адрес
+	for i := range testConfig.IP.LockAfter - 1 {
+		*clock = clock.Add(testConfig.MaxDelay)
+		require.NoError(t, svc.Check(ctx, "new@example.com", "203.0.113.7"))
+		require.NoError(t, svc.RecordFailure(ctx, string(rune('a'+i))+"@example.com", "203.0.113.7"))
+		if i+1 >= testConfig.IP.DelayAfter {
+			assert.LessOrEqual(t, retryAfter(t, svc.Check(ctx, "new@example.com", "203.0.113.7")), testConfig.MaxDelay)
+		}
+	}
+	*clock = clock.Add(testConfig.MaxDelay)
+	require.NoError(t, svc.RecordFailure(ctx, "last@example.com", "203.0.113.7"))
+	assert.Equal(t, 15*time.Minute, retryAfter(t, svc.Check(ctx, "new@example.com", "203.0.113.7")))
+	require.NoError(t, svc.Check(ctx, "new@example.com", "198.51.100.1"), "other addresses must not be blocked")
+
+	*clock = clock.Add(testConfig.LockoutDuration)
+	require.NoError(t, svc.Check(ctx, "new@example.com", "203.0.113.7"))
+}
+
+func TestService_FailureWindowRestartsCount(t *testing.T) {
+	svc, repo, clock := newTestService(testConfig)
+	ctx := context.Background()
+
+	require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", ""))
+	require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", ""))
+	*clock = clock.Add(2 * time.Hour)
+	require.NoError(t, svc.RecordFailure(ctx, "jane@example.com", ""))
+
+	assert.Equal(t, 1, repo.failures[janeKey])
+	require.NoError(t, svc.Check(ctx, "jane@example.com", ""))
+}
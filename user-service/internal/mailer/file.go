@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// FileMailer складывает письма в каталог файлами .eml вместо отправки. Для разработки и тестовых стендов.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("mailer: failed to create mail directory %s: %w", dir, err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), uuid.Must(uuid.NewV4()))
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, render(m.from, msg, now), 0o640); err != nil {
+		return fmt.Errorf("mailer: failed to write mail file: %w", err)
+	}
+
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("path", path).Msg("Mail written to file")
+	return nil
+}
+
+// LogMailer пишет письма в лог целиком. Текст может содержать одноразовые ссылки, поэтому только для локальной разработки.
+type LogMailer struct{}
+
+func NewLogMailer() LogMailer {
+	return LogMailer{}
+}
+
+func (LogMailer) Send(_ context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	log.Info().Str("to", msg.To).Str("subject", msg.Subject).Str("body", msg.Body).Msg("Mail not sent: log mailer")
+	return nil
+}
@@ -0,0 +1,28 @@
+// Package mailer отправляет служебные письма пользователям: подтверждение email и другие уведомления.
+package mailer
+
+import (
+	"context"
+	"errors"
+)
+
+// Message - текстовое письмо одному получателю.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer доставляет письма. Реализации безопасны для одновременного использования.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var ErrInvalidMessage = errors.New("mailer: message must have a recipient and a subject")
+
+func (m Message) validate() error {
+	if m.To == "" || m.Subject == "" {
+		return ErrInvalidMessage
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package mailer
+
+import (
+	"context"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMailer_Send(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: "2525", Username: "shop", Password: "secret", From: "Shop <no-reply@example.com>"})
+
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	m.send = func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error {
+		require.NotNil(t, auth)
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	err := m.Send(context.Background(), Message{To: "ann@example.com", Subject: "Hi\r\nBcc: evil@example.com", Body: "line 1\nline 2"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "smtp.example.com:2525", gotAddr)
+	assert.Equal(t, "no-reply@example.com", gotFrom)
+	assert.Equal(t, []string{"ann@example.com"}, gotTo)
+	raw := string(gotMsg)
+	assert.Contains(t, raw, "Subject: HiBcc: evil@example.com\r\n")
+	assert.NotContains(t, raw, "\r\nBcc:")
+	assert.True(t, strings.HasSuffix(raw, "\r\n\r\nline 1\r\nline 2"))
+}
+
+func TestSMTPMailer_RejectsMessageWithoutRecipient(t *testing.T) {
+	m := NewSMTPMailer(SMTPConfig{Host: "smtp.example.com", Port: "25"})
+	m.send = func(string, smtp.Auth, string, []string, []byte) error {
+		t.Fatal("message without recipient must not be sent")
+		return nil
+	}
+
+	require.ErrorIs(t, m.Send(context.Background(), Message{Subject: "Hi"}), ErrInvalidMessage)
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	m, err := NewFileMailer(dir, "no-reply@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, m.Send(context.Background(), Message{To: "ann@example.com", Subject: "Confirm", Body: "link"}))
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, ".eml", filepath.Ext(files[0].Name()))
+
+	content, err := os.ReadFile(filepath.Join(dir, files[0].Name()))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "To: ann@example.com\r\n")
+	assert.Contains(t, string(content), "Subject: Confirm\r\n")
+}
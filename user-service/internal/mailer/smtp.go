@@ -0,0 +1,74 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPConfig задаёт почтовый сервер для отправки писем.
+type SMTPConfig struct {
+	Host     string
+	Port     string
+	Username string // Пустой - сервер принимает письма без аутентификации
+	Password string
+	From     string // Адрес отправителя, например "Shop <no-reply@example.com>"
+}
+
+type SMTPMailer struct {
+	cfg  SMTPConfig
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPMailer(cfg SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, send: smtp.SendMail}
+}
+
+// Send отправляет письмо через SMTP. net/smtp не принимает контекст, поэтому ctx проверяется только перед отправкой.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+
+	addr := net.JoinHostPort(m.cfg.Host, m.cfg.Port)
+	if err := m.send(addr, auth, envelopeAddress(m.cfg.From), []string{msg.To}, render(m.cfg.From, msg, time.Now())); err != nil {
+		return fmt.Errorf("mailer: failed to send mail via %s: %w", addr, err)
+	}
+	return nil
+}
+
+// envelopeAddress извлекает адрес из "Имя <адрес>" для команды MAIL FROM.
+func envelopeAddress(from string) string {
+	if start := strings.LastIndex(from, "<"); start >= 0 {
+		if end := strings.LastIndex(from, ">"); end > start {
+			return from[start+1 : end]
+		}
+	}
+	return from
+}
+
+// render собирает письмо в формате RFC 5322. Переводы строк в заголовках вырезаются, чтобы нельзя было подставить свои заголовки.
+func render(from string, msg Message, date time.Time) []byte {
+	header := strings.NewReplacer("\r", "", "\n", "")
+	var b strings.Builder
+	b.WriteString("From: " + header.Replace(from) + "\r\n")
+	b.WriteString("To: " + header.Replace(msg.To) + "\r\n")
+	b.WriteString("Subject: " + header.Replace(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,152 @@
+package mfa
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+// Factor - TOTP пользователя. Secret зашифрован.
+type Factor struct {
+	UserID       uuid.UUID
+	Secret       []byte
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+}
+
+type Repository interface {
+	// SavePending сохраняет секрет незавершённой настройки, заменяя прежний неподтверждённый.
+	// ErrAlreadyEnabled, если второй фактор уже подтверждён.
+	SavePending(ctx context.Context, userID uuid.UUID, secret []byte) error
+	// Get возвращает фактор пользователя. ErrNotEnrolled, если настройка не начиналась.
+	Get(ctx context.Context, userID uuid.UUID) (*Factor, error)
+	// Confirm включает фактор, запоминает шаг первого кода и заменяет коды восстановления.
+	// ErrNotEnrolled, если неподтверждённого фактора нет.
+	Confirm(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes [][]byte) error
+	// UseStep принимает код шага step, только если он новее последнего принятого. false - код уже использован.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	// UseRecoveryCode гасит неиспользованный код восстановления. false, если такого кода нет.
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) (bool, error)
+	// Delete удаляет фактор и коды восстановления. ErrNotEnrolled, если удалять нечего.
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) SavePending(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	query := `
+		INSERT INTO user_service.user_mfa (user_id, secret_encrypted)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret_encrypted = EXCLUDED.secret_encrypted, created_at = NOW()
+		WHERE user_mfa.confirmed_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save pending mfa secret of user %s: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyEnabled
+	}
+	return nil
+}
+
+func (r *postgresRepository) Get(ctx context.Context, userID uuid.UUID) (*Factor, error) {
+	query := `
+		SELECT user_id, secret_encrypted, created_at, confirmed_at, last_used_step
+		FROM user_service.user_mfa
+		WHERE user_id = $1
+	`
+	var factor Factor
+	err := r.db.QueryRow(ctx, query, userID).Scan(&factor.UserID, &factor.Secret, &factor.CreatedAt, &factor.ConfirmedAt, &factor.LastUsedStep)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotEnrolled
+		}
+		return nil, fmt.Errorf("failed to get mfa of user %s: %w", userID, err)
+	}
+	return &factor, nil
+}
+
+func (r *postgresRepository) Confirm(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes [][]byte) error {
+	// Все части выполняются одним запросом: коды восстановления появляются только вместе с включением фактора
+	query := `
+		WITH confirmed AS (
+			UPDATE user_service.user_mfa
+			SET confirmed_at = NOW(), last_used_step = $2
+			WHERE user_id = $1 AND confirmed_at IS NULL
+			RETURNING user_id
+		), cleared AS (
+			DELETE FROM user_service.mfa_recovery_codes
+			WHERE user_id IN (SELECT user_id FROM confirmed)
+		), inserted AS (
+			INSERT INTO user_service.mfa_recovery_codes (user_id, code_hash)
+			SELECT c.user_id, h.code_hash
+			FROM confirmed c CROSS JOIN UNNEST($3::bytea[]) AS h(code_hash)
+		)
+		SELECT COUNT(*) FROM confirmed
+	`
+	var confirmed int
+	if err := r.db.QueryRow(ctx, query, userID, step, recoveryHashes).Scan(&confirmed); err != nil {
+		return fmt.Errorf("failed to confirm mfa of user %s: %w", userID, err)
+	}
+	if confirmed == 0 {
+		return ErrNotEnrolled
+	}
+	return nil
+}
+
+func (r *postgresRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	query := `
+		UPDATE user_service.user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND confirmed_at IS NOT NULL AND last_used_step < $2
+	`
+	tag, err := r.db.Exec(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to accept mfa code of user %s: %w", userID, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *postgresRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) (bool, error) {
+	query := `
+		UPDATE user_service.mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+	tag, err := r.db.Exec(ctx, query, userID, hash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code of user %s: %w", userID, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *postgresRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	query := `
+		WITH codes AS (
+			DELETE FROM user_service.mfa_recovery_codes WHERE user_id = $1
+		)
+		DELETE FROM user_service.user_mfa WHERE user_id = $1
+	`
+	tag, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete mfa of user %s: %w", userID, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotEnrolled
+	}
+	return nil
+}
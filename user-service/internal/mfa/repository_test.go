@@ -0,0 +1,137 @@
+package mfa_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mfa"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func createUser(t *testing.T, email string) uuid.UUID {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV4())
+	_, err := user.NewRepository(testDB).Create(context.Background(), &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        email,
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.users WHERE id = $1", userID)
+		require.NoError(t, err)
+	})
+	return userID
+}
+
+func TestRepository_EnrollmentLifecycle(t *testing.T) {
+	repo := mfa.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "mfa.lifecycle@example.com")
+
+	_, err := repo.Get(ctx, userID)
+	require.ErrorIs(t, err, mfa.ErrNotEnrolled)
+
+	require.NoError(t, repo.SavePending(ctx, userID, []byte("first-secret")))
+	// Повторная настройка до подтверждения заменяет секрет
+	require.NoError(t, repo.SavePending(ctx, userID, []byte("second-secret")))
+	factor, err := repo.Get(ctx, userID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("second-secret"), factor.Secret)
+	assert.Nil(t, factor.ConfirmedAt)
+
+	require.NoError(t, repo.Confirm(ctx, userID, 100, [][]byte{[]byte("code-1"), []byte("code-2")}))
+	require.ErrorIs(t, repo.Confirm(ctx, userID, 101, nil), mfa.ErrNotEnrolled, "confirmed factor cannot be confirmed again")
+	require.ErrorIs(t, repo.SavePending(ctx, userID, []byte("third-secret")), mfa.ErrAlreadyEnabled)
+
+	factor, err = repo.Get(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, factor.ConfirmedAt)
+	assert.Equal(t, int64(100), factor.LastUsedStep)
+	assert.Equal(t, []byte("second-secret"), factor.Secret)
+
+	require.NoError(t, repo.Delete(ctx, userID))
+	require.ErrorIs(t, repo.Delete(ctx, userID), mfa.ErrNotEnrolled)
+	used, err := repo.UseRecoveryCode(ctx, userID, []byte("code-1"))
+	require.NoError(t, err)
+	assert.False(t, used, "reset must drop recovery codes")
+}
+
+func TestRepository_CodesAreSingleUse(t *testing.T) {
+	repo := mfa.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "mfa.single-use@example.com")
+
+	require.NoError(t, repo.SavePending(ctx, userID, []byte("secret")))
+	used, err := repo.UseStep(ctx, userID, 200)
+	require.NoError(t, err)
+	assert.False(t, used, "unconfirmed factor must not accept codes")
+
+	require.NoError(t, repo.Confirm(ctx, userID, 100, [][]byte{[]byte("code-1")}))
+
+	for step, want := range map[int64]bool{100: false, 99: false, 101: true} {
+		used, err := repo.UseStep(ctx, userID, step)
+		require.NoError(t, err)
+		assert.Equal(t, want, used, step)
+	}
+
+	used, err = repo.UseRecoveryCode(ctx, userID, []byte("code-1"))
+	require.NoError(t, err)
+	assert.True(t, used)
+	used, err = repo.UseRecoveryCode(ctx, userID, []byte("code-1"))
+	require.NoError(t, err)
+	assert.False(t, used)
+}
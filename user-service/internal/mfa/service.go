@@ -0,0 +1,216 @@
+// Package mfa управляет вторым фактором входа: TOTP из приложения-аутентификатора и одноразовыми кодами восстановления.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/totp"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrNotEnrolled    = errors.New("two-factor authentication is not enabled")
+	ErrInvalidCode    = errors.New("invalid two-factor authentication code")
+)
+
+const (
+	RecoveryCodeCount = 10
+	// recoveryCodeBytes - 50 бит на код: вместе с лимитом попыток входа не перебрать
+	recoveryCodeBytes = 10 * 5 / 8
+	// clockSkew - сколько шагов TOTP до и после текущего принимается из-за расхождения часов телефона
+	clockSkew = 1
+)
+
+// Enrollment - данные для настройки приложения. Секрет показывается один раз и больше не выдаётся.
+type Enrollment struct {
+	Secret string // base32 для ручного ввода
+	URI    string // otpauth URI для QR-кода
+}
+
+// SecretBox шифрует секрет TOTP перед записью в БД. associated привязывает шифротекст к пользователю.
+type SecretBox interface {
+	Seal(plaintext, associated []byte) ([]byte, error)
+	Open(sealed, associated []byte) ([]byte, error)
+}
+
+// UserSource даёт email пользователя для подписи в приложении-аутентификаторе.
+type UserSource interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+type Service interface {
+	// BeginEnrollment выпускает новый секрет. Фактор не действует, пока ConfirmEnrollment не примет первый код.
+	BeginEnrollment(ctx context.Context, userID uuid.UUID) (*Enrollment, error)
+	// ConfirmEnrollment включает фактор по первому коду из приложения и возвращает коды восстановления.
+	// Коды показываются только здесь, в БД хранятся их хеши.
+	ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	// Enabled сообщает, нужен ли пользователю второй фактор при входе.
+	Enabled(ctx context.Context, userID uuid.UUID) (bool, error)
+	// Verify принимает код TOTP или код восстановления. Каждый код действует один раз. ErrInvalidCode при неверном коде.
+	Verify(ctx context.Context, userID uuid.UUID, code string) error
+	// Reset отключает второй фактор пользователя, например после потери телефона и кодов восстановления.
+	Reset(ctx context.Context, userID uuid.UUID) error
+}
+
+type service struct {
+	repo   Repository
+	users  UserSource
+	box    SecretBox
+	issuer string // Название сервиса в приложении-аутентификаторе
+	now    func() time.Time
+}
+
+func NewService(repo Repository, users UserSource, box SecretBox, issuer string) Service {
+	return &service{repo: repo, users: users, box: box, issuer: issuer, now: time.Now}
+}
+
+func (s *service) BeginEnrollment(ctx context.Context, userID uuid.UUID) (*Enrollment, error) {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := s.box.Seal(secret, userID.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SavePending(ctx, userID, sealed); err != nil {
+		return nil, err
+	}
+
+	log.Info().Stringer("user_id", userID).Msg("MFA enrollment started")
+	return &Enrollment{Secret: totp.EncodeSecret(secret), URI: totp.URI(s.issuer, u.Email, secret)}, nil
+}
+
+func (s *service) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	factor, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+	secret, err := s.box.Open(factor.Secret, userID.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt mfa secret of user '%s': %w", userID, err)
+	}
+
+	step, ok := totp.Validate(secret, code, s.now(), clockSkew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Confirm(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	log.Info().Stringer("user_id", userID).Msg("MFA enabled")
+	return codes, nil
+}
+
+func (s *service) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	factor, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ErrNotEnrolled) {
+			return false, nil
+		}
+		return false, err
+	}
+	return factor.ConfirmedAt != nil, nil
+}
+
+func (s *service) Verify(ctx context.Context, userID uuid.UUID, code string) error {
+	factor, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if factor.ConfirmedAt == nil {
+		return ErrNotEnrolled
+	}
+
+	if normalized := strings.ReplaceAll(strings.TrimSpace(code), " ", ""); len(normalized) == totp.Digits {
+		secret, err := s.box.Open(factor.Secret, userID.Bytes())
+		if err != nil {
+			return fmt.Errorf("failed to decrypt mfa secret of user '%s': %w", userID, err)
+		}
+		step, ok := totp.Validate(secret, normalized, s.now(), clockSkew)
+		if !ok {
+			return ErrInvalidCode
+		}
+		// Перехваченный код не пройдёт повторно, пока действует его шаг
+		accepted, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			log.Warn().Stringer("user_id", userID).Msg("MFA code reuse rejected")
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, tokens.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	log.Info().Stringer("user_id", userID).Msg("MFA recovery code used")
+	return nil
+}
+
+func (s *service) Reset(ctx context.Context, userID uuid.UUID) error {
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		return err
+	}
+	log.Info().Stringer("user_id", userID).Msg("MFA reset")
+	return nil
+}
+
+// recoveryEncoding - base32 без выравнивания: коды легко читать и вводить.
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// generateRecoveryCodes возвращает коды в виде "XXXXX-XXXXX" и их хеши.
+func generateRecoveryCodes() ([]string, [][]byte, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([][]byte, RecoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		code := recoveryEncoding.EncodeToString(raw)
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = tokens.Hash(code)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode убирает дефисы и пробелы и приводит код к верхнему регистру, как при выпуске.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
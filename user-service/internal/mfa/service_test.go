@@ -0,0 +1,252 @@
+package mfa
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/secretbox"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/totp"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) SavePending(ctx context.Context, userID uuid.UUID, secret []byte) error {
+	args := m.Called(ctx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Get(ctx context.Context, userID uuid.UUID) (*Factor, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Factor), args.Error(1)
+}
+
+func (m *MockRepository) Confirm(ctx context.Context, userID uuid.UUID, step int64, recoveryHashes [][]byte) error {
+	args := m.Called(ctx, userID, step, recoveryHashes)
+	return args.Error(0)
+}
+
+func (m *MockRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash []byte) (bool, error) {
+	args := m.Called(ctx, userID, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+type MockUserSource struct {
+	mock.Mock
+}
+
+func (m *MockUserSource) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+var testNow = time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+
+// sealedFactor возвращает подтверждённый фактор с новым секретом, зашифрованным box.
+func sealedFactor(t *testing.T, box SecretBox, userID uuid.UUID) (*Factor, []byte) {
+	t.Helper()
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	sealed, err := box.Seal(secret, userID.Bytes())
+	require.NoError(t, err)
+	confirmedAt := testNow.Add(-time.Hour)
+	return &Factor{UserID: userID, Secret: sealed, ConfirmedAt: &confirmedAt}, secret
+}
+
+func TestService_BeginEnrollment_StoresSealedSecret(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockUsers := new(MockUserSource)
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	svc := NewService(mockRepo, mockUsers, box, "Shop")
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	var sealed []byte
+	mockUsers.On("GetUserByID", ctx, userID).Return(&user.User{ID: userID, Email: "jane@example.com"}, nil).Once()
+	mockRepo.On("SavePending", ctx, userID, mock.Anything).Run(func(args mock.Arguments) {
+		sealed = args.Get(2).([]byte)
+	}).Return(nil).Once()
+
+	enrollment, err := svc.BeginEnrollment(ctx, userID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/Shop:jane@example.com?"), enrollment.URI)
+	assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+
+	// Секрет в хранилище зашифрован и привязан к пользователю
+	secret, err := recoveryEncoding.DecodeString(enrollment.Secret)
+	require.NoError(t, err)
+	assert.False(t, bytes.Contains(sealed, secret))
+	opened, err := box.Open(sealed, userID.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, secret, opened)
+	mockRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestService_BeginEnrollment_AlreadyEnabled(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockUsers := new(MockUserSource)
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	svc := NewService(mockRepo, mockUsers, box, "Shop")
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockUsers.On("GetUserByID", ctx, userID).Return(&user.User{ID: userID, Email: "jane@example.com"}, nil).Once()
+	mockRepo.On("SavePending", ctx, userID, mock.Anything).Return(ErrAlreadyEnabled).Once()
+
+	_, err = svc.BeginEnrollment(ctx, userID)
+	require.ErrorIs(t, err, ErrAlreadyEnabled)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_ConfirmEnrollment(t *testing.T) {
+	mockRepo := new(MockRepository)
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	svc := &service{repo: mockRepo, users: new(MockUserSource), box: box, issuer: "Shop", now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	pending, secret := sealedFactor(t, box, userID)
+	pending.ConfirmedAt = nil
+	step := totp.Step(testNow)
+
+	var hashes [][]byte
+	mockRepo.On("Get", ctx, userID).Return(pending, nil).Twice()
+	mockRepo.On("Confirm", ctx, userID, step, mock.Anything).Run(func(args mock.Arguments) {
+		hashes = args.Get(3).([][]byte)
+	}).Return(nil).Once()
+
+	_, err = svc.ConfirmEnrollment(ctx, userID, "000000")
+	require.ErrorIs(t, err, ErrInvalidCode)
+
+	codes, err := svc.ConfirmEnrollment(ctx, userID, totp.Code(secret, step))
+	require.NoError(t, err)
+	require.Len(t, codes, RecoveryCodeCount)
+	assert.Regexp(t, `^[A-Z2-7]{5}-[A-Z2-7]{5}$`, codes[0])
+
+	// В БД попадают только хеши кодов восстановления
+	require.Len(t, hashes, RecoveryCodeCount)
+	for i, code := range codes {
+		assert.Equal(t, tokens.Hash(normalizeRecoveryCode(code)), hashes[i])
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Enabled(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, new(MockUserSource), nil, "Shop")
+	ctx := context.Background()
+	confirmedAt := testNow
+
+	notEnrolled, pending, confirmed := uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4()), uuid.Must(uuid.NewV4())
+	mockRepo.On("Get", ctx, notEnrolled).Return(nil, ErrNotEnrolled).Once()
+	mockRepo.On("Get", ctx, pending).Return(&Factor{UserID: pending}, nil).Once()
+	mockRepo.On("Get", ctx, confirmed).Return(&Factor{UserID: confirmed, ConfirmedAt: &confirmedAt}, nil).Once()
+
+	// До подтверждения второй фактор при входе не требуется
+	for userID, want := range map[uuid.UUID]bool{notEnrolled: false, pending: false, confirmed: true} {
+		enabled, err := svc.Enabled(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, want, enabled)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_VerifyTOTP(t *testing.T) {
+	mockRepo := new(MockRepository)
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	svc := &service{repo: mockRepo, users: new(MockUserSource), box: box, issuer: "Shop", now: func() time.Time { return testNow }}
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	factor, secret := sealedFactor(t, box, userID)
+	step := totp.Step(testNow)
+	mockRepo.On("Get", ctx, userID).Return(factor, nil)
+	mockRepo.On("UseStep", ctx, userID, step).Return(true, nil).Once()
+	mockRepo.On("UseStep", ctx, userID, step).Return(false, nil).Once()
+	mockRepo.On("UseStep", ctx, userID, step-1).Return(true, nil).Once()
+
+	code := totp.Code(secret, step)
+	require.NoError(t, svc.Verify(ctx, userID, code))
+	require.ErrorIs(t, svc.Verify(ctx, userID, code), ErrInvalidCode, "codes are single-use")
+
+	// Код от отстающих часов принимается в пределах одного шага
+	require.NoError(t, svc.Verify(ctx, userID, totp.Code(secret, step-1)))
+	require.ErrorIs(t, svc.Verify(ctx, userID, totp.Code(secret, step-3)), ErrInvalidCode)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_VerifyRecoveryCode(t *testing.T) {
+	mockRepo := new(MockRepository)
+	box, err := secretbox.New(bytes.Repeat([]byte{7}, secretbox.KeySize))
+	require.NoError(t, err)
+	svc := NewService(mockRepo, new(MockUserSource), box, "Shop")
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	factor, _ := sealedFactor(t, box, userID)
+	mockRepo.On("Get", ctx, userID).Return(factor, nil)
+	// Регистр, дефис и пробелы не важны
+	mockRepo.On("UseRecoveryCode", ctx, userID, tokens.Hash("ABCDEFGHIJ")).Return(true, nil).Once()
+	mockRepo.On("UseRecoveryCode", ctx, userID, tokens.Hash("ABCDEFGHIJ")).Return(false, nil).Once()
+
+	require.NoError(t, svc.Verify(ctx, userID, "abcde-fghij"))
+	require.ErrorIs(t, svc.Verify(ctx, userID, "ABCDE FGHIJ"), ErrInvalidCode, "recovery codes are single-use")
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Verify_NotConfirmed(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, new(MockUserSource), nil, "Shop")
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("Get", ctx, userID).Return(&Factor{UserID: userID}, nil).Once()
+
+	require.ErrorIs(t, svc.Verify(ctx, userID, "123456"), ErrNotEnrolled)
+	mockRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_Reset(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, new(MockUserSource), nil, "Shop")
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("Delete", ctx, userID).Return(nil).Once()
+	mockRepo.On("Delete", ctx, userID).Return(ErrNotEnrolled).Once()
+
+	require.NoError(t, svc.Reset(ctx, userID))
+	require.ErrorIs(t, svc.Reset(ctx, userID), ErrNotEnrolled)
+	mockRepo.AssertExpectations(t)
+}
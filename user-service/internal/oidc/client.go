@@ -0,0 +1,276 @@
+// Package oidc реализует вход сотрудников через корпоративный OpenID Connect провайдер:
+// authorization code flow с PKCE, обнаружение настроек провайдера и проверку ID токена по его JWKS.
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid id token")
+	ErrExchange     = errors.New("authorization code exchange failed")
+)
+
+// ClientConfig задаёт приложение, зарегистрированное у провайдера.
+type ClientConfig struct {
+	IssuerURL    string // Должен совпадать с issuer из discovery и с iss в токенах
+	ClientID     string
+	ClientSecret string // Пустой - публичный клиент, который защищён только PKCE
+	RedirectURL  string
+	Scopes       []string
+}
+
+// Metadata - нужная часть документа /.well-known/openid-configuration.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims - проверенные утверждения ID токена.
+type Claims struct {
+	Issuer        string `json:"iss"`
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+	GivenName     string `json:"given_name"`
+	FamilyName    string `json:"family_name"`
+}
+
+// Client обращается к провайдеру. Настройки провайдера запрашиваются при первом использовании и кешируются.
+type Client struct {
+	cfg        ClientConfig
+	httpClient *http.Client
+	keys       *keySet
+	now        func() time.Time
+
+	mu       sync.Mutex
+	metadata *Metadata
+}
+
+func NewClient(cfg ClientConfig, httpClient *http.Client) *Client {
+	c := &Client{cfg: cfg, httpClient: httpClient, now: time.Now}
+	c.keys = newKeySet(c.fetchKeys, c.clock)
+	return c
+}
+
+func (c *Client) clock() time.Time {
+	return c.now()
+}
+
+// Discover возвращает настройки провайдера. Неудачный запрос не кешируется.
+func (c *Client) Discover(ctx context.Context) (*Metadata, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.metadata != nil {
+		return c.metadata, nil
+	}
+
+	var metadata Metadata
+	endpoint := strings.TrimRight(c.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	if err := c.getJSON(ctx, endpoint, &metadata); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	// Документ от другого issuer означает ошибку настройки или подмену
+	if metadata.Issuer != c.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match configured %q", metadata.Issuer, c.cfg.IssuerURL)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc: discovery document misses required endpoints")
+	}
+
+	c.metadata = &metadata
+	return c.metadata, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера. verifier - секрет PKCE, который потом передаётся в Exchange.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	metadata, err := c.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(metadata.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("oidc: invalid authorization endpoint: %w", err)
+	}
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", c.cfg.ClientID)
+	query.Set("redirect_uri", c.cfg.RedirectURL)
+	query.Set("scope", strings.Join(c.cfg.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", CodeChallenge(verifier))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+	return authURL.String(), nil
+}
+
+type tokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// Exchange меняет код авторизации на ID токен. Токен возвращается непроверенным, его проверяет Verify.
+func (c *Client) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	metadata, err := c.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.cfg.RedirectURL},
+		"code_verifier": {verifier},
+	}
+	if c.cfg.ClientSecret == "" {
+		form.Set("client_id", c.cfg.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("oidc: failed to build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if c.cfg.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(c.cfg.ClientID), url.QueryEscape(c.cfg.ClientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("oidc: token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var payload tokenResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&payload); err != nil {
+		return "", fmt.Errorf("oidc: failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%w: provider responded %d: %s %s", ErrExchange, resp.StatusCode, payload.Error, payload.ErrorDescription)
+	}
+	if payload.IDToken == "" {
+		return "", fmt.Errorf("%w: token response has no id_token", ErrExchange)
+	}
+	return payload.IDToken, nil
+}
+
+// Verify проверяет подпись, издателя, получателя, срок действия и nonce ID токена.
+func (c *Client) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	metadata, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := c.keys.verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Claims
+		Audience        audience `json:"aud"`
+		AuthorizedParty string   `json:"azp"`
+		ExpiresAt       int64    `json:"exp"`
+		IssuedAt        int64    `json:"iat"`
+		Nonce           string   `json:"nonce"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+
+	now := c.now()
+	switch {
+	case claims.Issuer != metadata.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	case !claims.Audience.contains(c.cfg.ClientID):
+		return nil, fmt.Errorf("%w: token is not issued for this client", ErrInvalidToken)
+	case len(claims.Audience) > 1 && claims.AuthorizedParty != c.cfg.ClientID:
+		return nil, fmt.Errorf("%w: unexpected authorized party %q", ErrInvalidToken, claims.AuthorizedParty)
+	case claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0).Add(clockLeeway)):
+		return nil, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case time.Unix(claims.IssuedAt, 0).After(now.Add(clockLeeway)):
+		return nil, fmt.Errorf("%w: token issued in the future", ErrInvalidToken)
+	case claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	case claims.Subject == "":
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	return &claims.Claims, nil
+}
+
+func (c *Client) fetchKeys(ctx context.Context) (*jsonWebKeySet, error) {
+	metadata, err := c.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var set jsonWebKeySet
+	if err := c.getJSON(ctx, metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc: failed to fetch jwks: %w", err)
+	}
+	return &set, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, endpoint string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s responded %d: %s", endpoint, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target)
+}
+
+// CodeChallenge - S256 преобразование секрета PKCE (RFC 7636).
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// audience - поле aud, которое может быть строкой или массивом строк.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a audience) contains(clientID string) bool {
+	return slices.Contains(a, clientID)
+}
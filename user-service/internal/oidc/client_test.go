@@ -0,0 +1,166 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc/oidctest"
+)
+
+const (
+	testClientID     = "shop"
+	testClientSecret = "shop-secret"
+	testRedirectURL  = "https://shop.example.com/auth/oidc/callback"
+)
+
+// newTestClient запускает провайдер и возвращает клиента, часы которого совпадают с часами провайдера и двигаются тестом.
+func newTestClient(t *testing.T) (*Client, *oidctest.Provider, *time.Time) {
+	t.Helper()
+	provider := oidctest.NewProvider(testClientID, testClientSecret)
+	t.Cleanup(provider.Close)
+
+	clock := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	provider.Now = func() time.Time { return clock }
+	client := NewClient(ClientConfig{
+		IssuerURL:    provider.Issuer(),
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+	}, provider.Server.Client())
+	client.now = func() time.Time { return clock }
+	return client, provider, &clock
+}
+
+// authorize проходит страницу входа провайдера и возвращает параметры, с которыми он перенаправил обратно.
+func authorize(t *testing.T, provider *oidctest.Provider, authURL string) url.Values {
+	t.Helper()
+	httpClient := provider.Server.Client()
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	resp, err := httpClient.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	return location.Query()
+}
+
+func TestClient_Discover_RejectsForeignIssuer(t *testing.T) {
+	provider := oidctest.NewProvider(testClientID, testClientSecret)
+	defer provider.Close()
+
+	client := NewClient(ClientConfig{IssuerURL: provider.Issuer() + "/tenant"}, provider.Server.Client())
+	_, err := client.Discover(context.Background())
+	assert.ErrorContains(t, err, "discovery")
+}
+
+func TestClient_AuthorizationCodeFlow(t *testing.T) {
+	client, provider, _ := newTestClient(t)
+	ctx := context.Background()
+	provider.Login(oidctest.Identity{Subject: "emp-1", Email: "jane@example.com", EmailVerified: true, GivenName: "Jane", FamilyName: "Doe"})
+
+	authURL, err := client.AuthCodeURL(ctx, "state-1", "nonce-1", "verifier-1")
+	require.NoError(t, err)
+	assert.Contains(t, authURL, "code_challenge_method=S256")
+	assert.NotContains(t, authURL, "verifier-1", "the PKCE secret never leaves the service")
+
+	params := authorize(t, provider, authURL)
+	assert.Equal(t, "state-1", params.Get("state"))
+
+	_, err = client.Exchange(ctx, params.Get("code"), "another-verifier")
+	assert.ErrorIs(t, err, ErrExchange, "a stolen code is useless without the verifier")
+
+	params = authorize(t, provider, authURL)
+	rawIDToken, err := client.Exchange(ctx, params.Get("code"), "verifier-1")
+	require.NoError(t, err)
+	_, err = client.Exchange(ctx, params.Get("code"), "verifier-1")
+	assert.ErrorIs(t, err, ErrExchange, "codes are single use")
+
+	claims, err := client.Verify(ctx, rawIDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, provider.Issuer(), claims.Issuer)
+	assert.Equal(t, "emp-1", claims.Subject)
+	assert.Equal(t, "jane@example.com", claims.Email)
+	assert.True(t, claims.EmailVerified)
+	assert.Equal(t, "Jane Doe", claims.Name)
+}
+
+func TestClient_Verify_RejectsInvalidTokens(t *testing.T) {
+	client, provider, clock := newTestClient(t)
+	ctx := context.Background()
+	identity := oidctest.Identity{Subject: "emp-1", Email: "jane@example.com", EmailVerified: true}
+
+	with := func(key string, value any) string {
+		claims := provider.Claims(identity, "nonce-1")
+		claims[key] = value
+		return provider.Sign(claims)
+	}
+	valid := provider.Sign(provider.Claims(identity, "nonce-1"))
+	parts := strings.Split(valid, ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + "."
+
+	tests := map[string]string{
+		"wrong audience":       with("aud", "another-client"),
+		"foreign issuer":       with("iss", "https://evil.example.com"),
+		"expired":              with("exp", clock.Add(-2*time.Minute).Unix()),
+		"issued in the future": with("iat", clock.Add(10*time.Minute).Unix()),
+		"foreign azp":          with("aud", []string{testClientID, "another-client"}),
+		"no subject":           with("sub", ""),
+		"alg none":             unsigned,
+		"tampered payload":     parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"admin"}`)) + "." + parts[2],
+		"malformed":            "not-a-token",
+	}
+	for name, token := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := client.Verify(ctx, token, "nonce-1")
+			assert.ErrorIs(t, err, ErrInvalidToken)
+		})
+	}
+
+	_, err := client.Verify(ctx, valid, "another-nonce")
+	assert.ErrorIs(t, err, ErrInvalidToken, "nonce mismatch")
+
+	multiAudience := provider.Claims(identity, "nonce-1")
+	multiAudience["aud"] = []string{testClientID, "another-client"}
+	multiAudience["azp"] = testClientID
+	_, err = client.Verify(ctx, provider.Sign(multiAudience), "nonce-1")
+	assert.NoError(t, err)
+}
+
+func TestClient_Verify_CachesKeysAndFollowsRotation(t *testing.T) {
+	client, provider, clock := newTestClient(t)
+	ctx := context.Background()
+	identity := oidctest.Identity{Subject: "emp-1"}
+
+	for range 3 {
+		_, err := client.Verify(ctx, provider.Sign(provider.Claims(identity, "")), "")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, provider.JWKSCalls(), "keys are cached")
+
+	// Токен с неизвестным kid сразу после загрузки ключей не вызывает новый запрос
+	provider.RotateKey()
+	_, err := client.Verify(ctx, provider.Sign(provider.Claims(identity, "")), "")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, 1, provider.JWKSCalls())
+
+	*clock = clock.Add(2 * time.Minute)
+	_, err = client.Verify(ctx, provider.Sign(provider.Claims(identity, "")), "")
+	require.NoError(t, err, "the new key is fetched once the refresh interval has passed")
+	assert.Equal(t, 2, provider.JWKSCalls())
+
+	*clock = clock.Add(2 * time.Hour)
+	_, err = client.Verify(ctx, provider.Sign(provider.Claims(identity, "")), "")
+	require.NoError(t, err)
+	assert.Equal(t, 3, provider.JWKSCalls(), "stale keys are refreshed")
+}
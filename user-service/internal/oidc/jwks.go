@@ -0,0 +1,147 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keysTTL - как долго ключи провайдера используются без повторного запроса JWKS
+	keysTTL = time.Hour
+	// minRefreshInterval ограничивает внеочередные запросы JWKS из-за токенов с неизвестным kid,
+	// чтобы поддельные токены не превращали сервис в источник нагрузки на провайдера
+	minRefreshInterval = time.Minute
+	// clockLeeway - допустимое расхождение часов сервиса и провайдера
+	clockLeeway = time.Minute
+)
+
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// keySet кеширует RSA ключи подписи провайдера. Ключ с незнакомым kid запрашивается заново:
+// так подхватывается смена ключей у провайдера без перезапуска сервиса.
+type keySet struct {
+	fetch func(ctx context.Context) (*jsonWebKeySet, error)
+	now   func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+func newKeySet(fetch func(ctx context.Context) (*jsonWebKeySet, error), now func() time.Time) *keySet {
+	return &keySet{fetch: fetch, now: now}
+}
+
+// verify проверяет подпись RS256 компактного JWS и возвращает его полезную нагрузку.
+func (s *keySet) verify(ctx context.Context, token string) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	// Алгоритм фиксирован: "none" и HS256 с открытым ключом в роли секрета не принимаются
+	if header.Algorithm != "RS256" {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+
+	key, err := s.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	return payload, nil
+}
+
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	fresh := s.keys != nil && now.Sub(s.fetchedAt) < keysTTL
+	if key, ok := s.keys[kid]; ok && fresh {
+		return key, nil
+	}
+	if s.keys != nil && now.Sub(s.fetchedAt) < minRefreshInterval {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+
+	set, err := s.fetch(ctx)
+	if err != nil {
+		// Провайдер недоступен: устаревший, но известный ключ лучше отказа во входе
+		if key, ok := s.keys[kid]; ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	s.keys = parseKeys(set)
+	s.fetchedAt = now
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+	}
+	return key, nil
+}
+
+// parseKeys берёт из JWKS ключи RSA для подписи. Остальные ключи пропускаются.
+func parseKeys(set *jsonWebKeySet) map[string]*rsa.PublicKey {
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		exponent := new(big.Int).SetBytes(e)
+		keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+	return keys
+}
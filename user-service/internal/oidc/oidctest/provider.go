@@ -0,0 +1,271 @@
+// Package oidctest - OpenID Connect провайдер в памяти для тестов: discovery, JWKS, страница входа и выдача токенов.
+// Работает на httptest.Server, поэтому тестам не нужна сеть.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Identity - пользователь, который вошёл у провайдера.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	GivenName     string
+	FamilyName    string
+}
+
+type grant struct {
+	redirectURI string
+	challenge   string
+	nonce       string
+	identity    Identity
+}
+
+// Provider выдаёт коды и ID токены одному зарегистрированному клиенту.
+type Provider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+	// Now - часы провайдера для iat и exp выдаваемых токенов
+	Now func() time.Time
+	// TokenTTL - срок действия выдаваемых ID токенов
+	TokenTTL time.Duration
+
+	mu        sync.Mutex
+	key       *rsa.PrivateKey
+	keyID     string
+	keyCount  int
+	identity  *Identity
+	grants    map[string]grant
+	jwksCalls int
+}
+
+// NewProvider запускает провайдер. Сервер останавливается вызовом Close.
+func NewProvider(clientID, clientSecret string) *Provider {
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Now:          time.Now,
+		TokenTTL:     time.Hour,
+		grants:       map[string]grant{},
+	}
+	p.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", p.handleDiscovery)
+	mux.HandleFunc("GET /jwks", p.handleJWKS)
+	mux.HandleFunc("GET /authorize", p.handleAuthorize)
+	mux.HandleFunc("POST /token", p.handleToken)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+// Issuer - адрес провайдера, он же iss в токенах.
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// Login задаёт пользователя, от имени которого страница входа выдаёт код.
+func (p *Provider) Login(identity Identity) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.identity = &identity
+}
+
+// RotateKey заменяет ключ подписи новым с другим kid. Старый ключ из JWKS пропадает.
+func (p *Provider) RotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to generate key: %v", err))
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyCount++
+	p.key = key
+	p.keyID = fmt.Sprintf("key-%d", p.keyCount)
+}
+
+// JWKSCalls - сколько раз клиенты запрашивали JWKS.
+func (p *Provider) JWKSCalls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.jwksCalls
+}
+
+// Sign подписывает произвольные утверждения текущим ключом, чтобы тесты могли собрать неправильный токен.
+func (p *Provider) Sign(claims map[string]any) string {
+	p.mu.Lock()
+	key, keyID := p.key, p.keyID
+	p.mu.Unlock()
+	return sign(key, keyID, claims)
+}
+
+// Claims возвращает утверждения ID токена, которые провайдер выдал бы identity.
+func (p *Provider) Claims(identity Identity, nonce string) map[string]any {
+	now := p.Now()
+	return map[string]any{
+		"iss":            p.Issuer(),
+		"sub":            identity.Subject,
+		"aud":            p.ClientID,
+		"exp":            now.Add(p.TokenTTL).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          nonce,
+		"email":          identity.Email,
+		"email_verified": identity.EmailVerified,
+		"given_name":     identity.GivenName,
+		"family_name":    identity.FamilyName,
+		"name":           identity.GivenName + " " + identity.FamilyName,
+	}
+}
+
+func (p *Provider) handleDiscovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) handleJWKS(w http.ResponseWriter, _ *http.Request) {
+	p.mu.Lock()
+	p.jwksCalls++
+	key, keyID := p.key, p.keyID
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}},
+	})
+}
+
+// handleAuthorize сразу перенаправляет обратно с кодом, как будто пользователь ввёл пароль у провайдера.
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("response_type") != "code" {
+		http.Error(w, "unknown client or response type", http.StatusBadRequest)
+		return
+	}
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "pkce is required", http.StatusBadRequest)
+		return
+	}
+	redirect, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	identity := p.identity
+	p.mu.Unlock()
+	if identity == nil {
+		http.Error(w, "no user logged in at the provider", http.StatusUnauthorized)
+		return
+	}
+
+	code := rand.Text()
+	p.mu.Lock()
+	p.grants[code] = grant{
+		redirectURI: redirect.String(),
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		identity:    *identity,
+	}
+	p.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request")
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		tokenError(w, http.StatusUnauthorized, "invalid_client")
+		return
+	}
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type")
+		return
+	}
+
+	// Код одноразовый: удаляется и при неудачной попытке
+	p.mu.Lock()
+	g, found := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	if !found || g.redirectURI != r.PostForm.Get("redirect_uri") {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(sum[:]) != g.challenge {
+		tokenError(w, http.StatusBadRequest, "invalid_grant")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": rand.Text(),
+		"token_type":   "Bearer",
+		"expires_in":   int(p.TokenTTL.Seconds()),
+		"id_token":     p.Sign(p.Claims(g.identity, g.nonce)),
+	})
+}
+
+func sign(key *rsa.PrivateKey, keyID string, claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(fmt.Sprintf("oidctest: failed to sign token: %v", err))
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func tokenError(w http.ResponseWriter, status int, code string) {
+	writeJSON(w, status, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, payload any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
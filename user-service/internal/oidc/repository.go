@@ -0,0 +1,131 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrIdentityNotFound = errors.New("external identity is not linked")
+	ErrAlreadyLinked    = errors.New("user is already linked to another account of this identity provider")
+)
+
+// Identity - учётная запись провайдера, привязанная к пользователю.
+type Identity struct {
+	Issuer      string
+	Subject     string
+	UserID      uuid.UUID
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+// LoginState - начатый вход через провайдера. Сам state не хранится, только его хеш.
+type LoginState struct {
+	Hash         []byte
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+type Repository interface {
+	// CreateState сохраняет начатый вход и удаляет истёкшие.
+	CreateState(ctx context.Context, state *LoginState) error
+	// ConsumeState гасит вход: по одному state можно вернуться с провайдера только один раз.
+	// ErrInvalidState, если state неизвестен, уже использован или истёк.
+	ConsumeState(ctx context.Context, hash []byte) (*LoginState, error)
+	// FindUser возвращает пользователя, к которому привязана учётная запись, и запоминает время входа.
+	// ErrIdentityNotFound, если она не привязана.
+	FindUser(ctx context.Context, issuer, subject string) (uuid.UUID, error)
+	// Link привязывает учётную запись к пользователю. ErrAlreadyLinked, если у пользователя уже есть
+	// другая учётная запись этого провайдера или эта привязана к другому пользователю.
+	Link(ctx context.Context, identity *Identity) error
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) CreateState(ctx context.Context, state *LoginState) error {
+	query := `
+		WITH purged AS (
+			DELETE FROM user_service.oidc_login_states WHERE expires_at <= NOW()
+		)
+		INSERT INTO user_service.oidc_login_states (state_hash, nonce, code_verifier, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := r.db.Exec(ctx, query, state.Hash, state.Nonce, state.CodeVerifier, state.ExpiresAt); err != nil {
+		return fmt.Errorf("failed to create oidc login state: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) ConsumeState(ctx context.Context, hash []byte) (*LoginState, error) {
+	// Истёкший state тоже удаляется, но не возвращается
+	query := `
+		WITH consumed AS (
+			DELETE FROM user_service.oidc_login_states
+			WHERE state_hash = $1
+			RETURNING state_hash, nonce, code_verifier, expires_at
+		)
+		SELECT state_hash, nonce, code_verifier, expires_at FROM consumed WHERE expires_at > NOW()
+	`
+
+	var state LoginState
+	err := r.db.QueryRow(ctx, query, hash).Scan(&state.Hash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidState
+		}
+		return nil, fmt.Errorf("failed to consume oidc login state: %w", err)
+	}
+	return &state, nil
+}
+
+func (r *postgresRepository) FindUser(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	query := `
+		UPDATE user_service.user_identities
+		SET last_login_at = NOW()
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, issuer, subject).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrIdentityNotFound
+		}
+		return uuid.Nil, fmt.Errorf("failed to find user of identity %s at %s: %w", subject, issuer, err)
+	}
+	return userID, nil
+}
+
+func (r *postgresRepository) Link(ctx context.Context, identity *Identity) error {
+	query := `
+		INSERT INTO user_service.user_identities (issuer, subject, user_id, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created_at, last_login_at
+	`
+	err := r.db.QueryRow(ctx, query, identity.Issuer, identity.Subject, identity.UserID, identity.Email).
+		Scan(&identity.CreatedAt, &identity.LastLoginAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return ErrAlreadyLinked
+		}
+		return fmt.Errorf("failed to link identity %s at %s to user %s: %w", identity.Subject, identity.Issuer, identity.UserID, err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package oidc_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/oidc"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+func createUser(t *testing.T, email string) uuid.UUID {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV4())
+	_, err := user.NewRepository(testDB).Create(context.Background(), &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        email,
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.users WHERE id = $1", userID)
+		require.NoError(t, err)
+	})
+	return userID
+}
+
+func TestRepository_ConsumeState(t *testing.T) {
+	repo := oidc.NewRepository(testDB)
+	ctx := context.Background()
+
+	state := &oidc.LoginState{Hash: []byte("oidc.repo.state"), Nonce: "nonce", CodeVerifier: "verifier", ExpiresAt: time.Now().Add(time.Minute)}
+	require.NoError(t, repo.CreateState(ctx, state))
+	expired := &oidc.LoginState{Hash: []byte("oidc.repo.expired"), Nonce: "nonce", CodeVerifier: "verifier", ExpiresAt: time.Now().Add(-time.Second)}
+	require.NoError(t, repo.CreateState(ctx, expired))
+
+	consumed, err := repo.ConsumeState(ctx, state.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, "nonce", consumed.Nonce)
+	assert.Equal(t, "verifier", consumed.CodeVerifier)
+
+	_, err = repo.ConsumeState(ctx, state.Hash)
+	assert.ErrorIs(t, err, oidc.ErrInvalidState)
+	_, err = repo.ConsumeState(ctx, expired.Hash)
+	assert.ErrorIs(t, err, oidc.ErrInvalidState)
+}
+
+func TestRepository_LinkAndFindUser(t *testing.T) {
+	repo := oidc.NewRepository(testDB)
+	ctx := context.Background()
+	const issuer = "https://idp.example.com"
+	userID := createUser(t, "oidc.repo.link@example.com")
+	otherID := createUser(t, "oidc.repo.other@example.com")
+
+	_, err := repo.FindUser(ctx, issuer, "oidc.repo.emp-1")
+	require.ErrorIs(t, err, oidc.ErrIdentityNotFound)
+
+	identity := &oidc.Identity{Issuer: issuer, Subject: "oidc.repo.emp-1", UserID: userID, Email: "oidc.repo.link@example.com"}
+	require.NoError(t, repo.Link(ctx, identity))
+	assert.False(t, identity.CreatedAt.IsZero())
+
+	found, err := repo.FindUser(ctx, issuer, "oidc.repo.emp-1")
+	require.NoError(t, err)
+	assert.Equal(t, userID, found)
+
+	// Тот же sub у другого провайдера - другая учётная запись
+	_, err = repo.FindUser(ctx, "https://other-idp.example.com", "oidc.repo.emp-1")
+	require.ErrorIs(t, err, oidc.ErrIdentityNotFound)
+
+	err = repo.Link(ctx, &oidc.Identity{Issuer: issuer, Subject: "oidc.repo.emp-1", UserID: otherID, Email: "oidc.repo.other@example.com"})
+	assert.ErrorIs(t, err, oidc.ErrAlreadyLinked)
+	err = repo.Link(ctx, &oidc.Identity{Issuer: issuer, Subject: "oidc.repo.emp-2", UserID: userID, Email: "oidc.repo.link@example.com"})
+	assert.ErrorIs(t, err, oidc.ErrAlreadyLinked)
+	require.NoError(t, repo.Link(ctx, &oidc.Identity{Issuer: "https://other-idp.example.com", Subject: "oidc.repo.emp-1", UserID: userID, Email: "oidc.repo.link@example.com"}))
+}
@@ -0,0 +1,178 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrInvalidState = errors.New("sign-in state is invalid or expired, start again")
+	// ErrAccountNotFound не уточняет причину: учётная запись не привязана, email не подтверждён провайдером
+	// или пользователь неактивен.
+	ErrAccountNotFound = errors.New("no active account matches this identity")
+)
+
+// Provider - обращения к провайдеру, которые нужны для входа. Реализуется Client.
+type Provider interface {
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	Exchange(ctx context.Context, code, verifier string) (string, error)
+	Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error)
+}
+
+// UserSource ищет пользователя по ID привязанной учётной записи и по email при первой привязке.
+type UserSource interface {
+	GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*user.User, error)
+}
+
+// Logins завершает вход опознанного пользователя так же, как вход по паролю: открывает сессию или,
+// если включён второй фактор, выдаёт токен для его проверки. Реализуется auth.Service.
+type Logins interface {
+	LoginVerified(ctx context.Context, u *user.User, client session.Client) (*auth.LoginResult, error)
+}
+
+type Service interface {
+	// Begin начинает вход и возвращает адрес страницы входа провайдера и state, который нужно
+	// запомнить в браузере и сверить при возврате.
+	Begin(ctx context.Context) (authURL, state string, err error)
+	// Complete завершает вход по коду и state, с которыми провайдер вернул пользователя. Если у пользователя
+	// включён второй фактор, сессии ещё нет и вход завершает auth.Service.VerifyMFA.
+	// Учётная запись провайдера при первом входе привязывается к пользователю с тем же email, если провайдер
+	// его подтвердил. ErrInvalidState, ErrInvalidToken, ErrExchange, ErrAccountNotFound или ErrAlreadyLinked.
+	Complete(ctx context.Context, code, state string, client session.Client) (*auth.LoginResult, error)
+}
+
+type service struct {
+	provider Provider
+	issuer   string
+	repo     Repository
+	users    UserSource
+	logins   Logins
+	stateTTL time.Duration // Сколько пользователь может провести на странице провайдера
+	now      func() time.Time
+}
+
+// NewService создаёт сервис входа. issuer - значение iss токенов провайдера, ключ привязанных учётных записей.
+func NewService(provider Provider, issuer string, repo Repository, users UserSource, logins Logins, stateTTL time.Duration) Service {
+	return &service{provider: provider, issuer: issuer, repo: repo, users: users, logins: logins, stateTTL: stateTTL, now: time.Now}
+}
+
+func (s *service) Begin(ctx context.Context) (string, string, error) {
+	state, stateHash, err := tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, _, err := tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, _, err := tokens.Generate()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := s.provider.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return "", "", err
+	}
+	loginState := &LoginState{Hash: stateHash, Nonce: nonce, CodeVerifier: verifier, ExpiresAt: s.now().Add(s.stateTTL)}
+	if err := s.repo.CreateState(ctx, loginState); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+func (s *service) Complete(ctx context.Context, code, state string, client session.Client) (*auth.LoginResult, error) {
+	if state == "" {
+		return nil, ErrInvalidState
+	}
+	loginState, err := s.repo.ConsumeState(ctx, tokens.Hash(state))
+	if err != nil {
+		return nil, err
+	}
+
+	rawIDToken, err := s.provider.Exchange(ctx, code, loginState.CodeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := s.provider.Verify(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := s.resolveUser(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// Провайдер заменяет только пароль, второй фактор проверяется как при обычном входе
+	result, err := s.logins.LoginVerified(ctx, u, client)
+	if err != nil {
+		return nil, err
+	}
+	log.Info().Stringer("user_id", u.ID).Str("issuer", claims.Issuer).Bool("mfa_required", result.MFAToken != "").Msg("User authenticated via oidc")
+	return result, nil
+}
+
+// resolveUser находит пользователя привязанной учётной записи или привязывает её при первом входе.
+func (s *service) resolveUser(ctx context.Context, claims *Claims) (*user.User, error) {
+	userID, err := s.repo.FindUser(ctx, s.issuer, claims.Subject)
+	if err == nil {
+		return s.activeUser(ctx, userID, claims)
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	// Неподтверждённый email мог указать кто угодно, по нему нельзя получить доступ к чужой учётной записи
+	if claims.Email == "" || !claims.EmailVerified {
+		log.Info().Str("subject", claims.Subject).Msg("Oidc login rejected: email is not verified by the provider")
+		return nil, ErrAccountNotFound
+	}
+	u, err := s.users.GetUserByEmail(ctx, claims.Email)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			log.Info().Str("subject", claims.Subject).Msg("Oidc login rejected: no user with this email")
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	if u.Status != user.StatusActive {
+		log.Info().Stringer("user_id", u.ID).Str("status", string(u.Status)).Msg("Oidc login rejected: user is not active")
+		return nil, ErrAccountNotFound
+	}
+
+	identity := &Identity{Issuer: s.issuer, Subject: claims.Subject, UserID: u.ID, Email: claims.Email}
+	if err := s.repo.Link(ctx, identity); err != nil {
+		if errors.Is(err, ErrAlreadyLinked) {
+			log.Warn().Stringer("user_id", u.ID).Str("subject", claims.Subject).Msg("Oidc login rejected: user is linked to another identity")
+		}
+		return nil, err
+	}
+	log.Info().Stringer("user_id", u.ID).Str("subject", claims.Subject).Msg("External identity linked")
+	return u, nil
+}
+
+func (s *service) activeUser(ctx context.Context, userID uuid.UUID, claims *Claims) (*user.User, error) {
+	u, err := s.users.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, fmt.Errorf("failed to get user of identity %s: %w", claims.Subject, err)
+	}
+	if u.Status != user.StatusActive {
+		log.Info().Stringer("user_id", u.ID).Str("status", string(u.Status)).Msg("Oidc login rejected: user is not active")
+		return nil, ErrAccountNotFound
+	}
+	return u, nil
+}
@@ -0,0 +1,283 @@
+package oidc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/auth"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/session"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockProvider struct {
+	mock.Mock
+}
+
+func (m *MockProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	args := m.Called(ctx, state, nonce, verifier)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProvider) Exchange(ctx context.Context, code, verifier string) (string, error) {
+	args := m.Called(ctx, code, verifier)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockProvider) Verify(ctx context.Context, rawIDToken, nonce string) (*Claims, error) {
+	args := m.Called(ctx, rawIDToken, nonce)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Claims), args.Error(1)
+}
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) CreateState(ctx context.Context, state *LoginState) error {
+	args := m.Called(ctx, state)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ConsumeState(ctx context.Context, hash []byte) (*LoginState, error) {
+	args := m.Called(ctx, hash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*LoginState), args.Error(1)
+}
+
+func (m *MockRepository) FindUser(ctx context.Context, issuer, subject string) (uuid.UUID, error) {
+	args := m.Called(ctx, issuer, subject)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockRepository) Link(ctx context.Context, identity *Identity) error {
+	args := m.Called(ctx, identity)
+	return args.Error(0)
+}
+
+type MockUserSource struct {
+	mock.Mock
+}
+
+func (m *MockUserSource) GetUserByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *MockUserSource) GetUserByEmail(ctx context.Context, email string) (*user.User, error) {
+	args := m.Called(ctx, email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+type MockLogins struct {
+	mock.Mock
+}
+
+func (m *MockLogins) LoginVerified(ctx context.Context, u *user.User, client session.Client) (*auth.LoginResult, error) {
+	args := m.Called(ctx, u, client)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*auth.LoginResult), args.Error(1)
+}
+
+const testIssuer = "https://idp.example.com"
+
+var testNow = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// expectCallback ожидает возврат с провайдера по state "state-1" с кодом "code", после которого провайдер отдаёт claims.
+func expectCallback(mockRepo *MockRepository, mockProvider *MockProvider, claims *Claims) {
+	mockRepo.On("ConsumeState", mock.Anything, tokens.Hash("state-1")).Return(&LoginState{Nonce: "nonce-1", CodeVerifier: "verifier-1"}, nil).Once()
+	mockProvider.On("Exchange", mock.Anything, "code", "verifier-1").Return("id-token", nil).Once()
+	mockProvider.On("Verify", mock.Anything, "id-token", "nonce-1").Return(claims, nil).Once()
+}
+
+func TestService_Begin_StoresOnlyStateHash(t *testing.T) {
+	mockProvider := new(MockProvider)
+	mockRepo := new(MockRepository)
+	svc := &service{provider: mockProvider, issuer: testIssuer, repo: mockRepo, stateTTL: 10 * time.Minute, now: func() time.Time { return testNow }}
+	ctx := context.Background()
+
+	var sentState, sentNonce, sentVerifier string
+	var stored *LoginState
+	mockProvider.On("AuthCodeURL", ctx, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		sentState, sentNonce, sentVerifier = args.String(1), args.String(2), args.String(3)
+	}).Return("https://idp.example.com/authorize", nil).Once()
+	mockRepo.On("CreateState", ctx, mock.AnythingOfType("*oidc.LoginState")).Run(func(args mock.Arguments) {
+		stored = args.Get(1).(*LoginState)
+	}).Return(nil).Once()
+
+	authURL, state, err := svc.Begin(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "https://idp.example.com/authorize", authURL)
+	assert.Equal(t, sentState, state)
+	require.NotNil(t, stored)
+	assert.Equal(t, tokens.Hash(state), stored.Hash)
+	assert.Equal(t, sentNonce, stored.Nonce)
+	assert.Equal(t, sentVerifier, stored.CodeVerifier)
+	assert.Equal(t, testNow.Add(10*time.Minute), stored.ExpiresAt)
+	assert.NotEqual(t, sentNonce, sentVerifier)
+	mockProvider.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Complete_LinksVerifiedEmailOnFirstLogin(t *testing.T) {
+	mockProvider := new(MockProvider)
+	mockRepo := new(MockRepository)
+	mockUsers := new(MockUserSource)
+	mockLogins := new(MockLogins)
+	svc := NewService(mockProvider, testIssuer, mockRepo, mockUsers, mockLogins, 10*time.Minute)
+	ctx := context.Background()
+	jane := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Status: user.StatusActive}
+	client := session.Client{IP: "10.0.0.1"}
+
+	expectCallback(mockRepo, mockProvider, &Claims{Issuer: testIssuer, Subject: "emp-1", Email: jane.Email, EmailVerified: true})
+	mockRepo.On("FindUser", ctx, testIssuer, "emp-1").Return(uuid.Nil, ErrIdentityNotFound).Once()
+	mockUsers.On("GetUserByEmail", ctx, jane.Email).Return(jane, nil).Once()
+	mockRepo.On("Link", ctx, &Identity{Issuer: testIssuer, Subject: "emp-1", UserID: jane.ID, Email: jane.Email}).Return(nil).Once()
+	result := &auth.LoginResult{Token: "session-token", Session: &session.Session{ID: uuid.Must(uuid.NewV4()), UserID: jane.ID}}
+	mockLogins.On("LoginVerified", ctx, jane, client).Return(result, nil).Once()
+
+	got, err := svc.Complete(ctx, "code", "state-1", client)
+	require.NoError(t, err)
+	assert.Equal(t, result, got)
+	mockProvider.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+	mockUsers.AssertExpectations(t)
+	mockLogins.AssertExpectations(t)
+}
+
+func TestService_Complete_LinkedIdentityStillNeedsSecondFactor(t *testing.T) {
+	mockProvider := new(MockProvider)
+	mockRepo := new(MockRepository)
+	mockUsers := new(MockUserSource)
+	mockLogins := new(MockLogins)
+	svc := NewService(mockProvider, testIssuer, mockRepo, mockUsers, mockLogins, 10*time.Minute)
+	ctx := context.Background()
+	jane := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Status: user.StatusActive}
+
+	// Вход идёт по привязке, даже если email у провайдера изменился
+	expectCallback(mockRepo, mockProvider, &Claims{Issuer: testIssuer, Subject: "emp-1", Email: "jane.doe@corp.example.com"})
+	mockRepo.On("FindUser", ctx, testIssuer, "emp-1").Return(jane.ID, nil).Once()
+	mockUsers.On("GetUserByID", ctx, jane.ID).Return(jane, nil).Once()
+	challenge := &auth.LoginResult{MFAToken: "mfa-token", MFAExpiresAt: testNow.Add(5 * time.Minute)}
+	mockLogins.On("LoginVerified", ctx, jane, session.Client{}).Return(challenge, nil).Once()
+
+	got, err := svc.Complete(ctx, "code", "state-1", session.Client{})
+	require.NoError(t, err)
+	assert.Equal(t, "mfa-token", got.MFAToken)
+	assert.Nil(t, got.Session, "no session before the second factor")
+	mockUsers.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	mockLogins.AssertExpectations(t)
+}
+
+func TestService_Complete_RejectsUnmatchedAccounts(t *testing.T) {
+	jane := &user.User{ID: uuid.Must(uuid.NewV4()), Email: "jane@example.com", Status: user.StatusActive}
+	deactivated := &user.User{ID: jane.ID, Email: jane.Email, Status: user.StatusDeactivated}
+
+	tests := map[string]struct {
+		claims  *Claims
+		prepare func(mockRepo *MockRepository, mockUsers *MockUserSource)
+		wantErr error
+	}{
+		"unverified email": {
+			claims: &Claims{Issuer: testIssuer, Subject: "emp-1", Email: jane.Email},
+			prepare: func(mockRepo *MockRepository, _ *MockUserSource) {
+				mockRepo.On("FindUser", mock.Anything, testIssuer, "emp-1").Return(uuid.Nil, ErrIdentityNotFound).Once()
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		"unknown email": {
+			claims: &Claims{Issuer: testIssuer, Subject: "emp-1", Email: "john@example.com", EmailVerified: true},
+			prepare: func(mockRepo *MockRepository, mockUsers *MockUserSource) {
+				mockRepo.On("FindUser", mock.Anything, testIssuer, "emp-1").Return(uuid.Nil, ErrIdentityNotFound).Once()
+				mockUsers.On("GetUserByEmail", mock.Anything, "john@example.com").Return(nil, user.ErrNotFound).Once()
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		"inactive user": {
+			claims: &Claims{Issuer: testIssuer, Subject: "emp-1", Email: jane.Email, EmailVerified: true},
+			prepare: func(mockRepo *MockRepository, mockUsers *MockUserSource) {
+				mockRepo.On("FindUser", mock.Anything, testIssuer, "emp-1").Return(uuid.Nil, ErrIdentityNotFound).Once()
+				mockUsers.On("GetUserByEmail", mock.Anything, jane.Email).Return(deactivated, nil).Once()
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		"inactive linked user": {
+			claims: &Claims{Issuer: testIssuer, Subject: "emp-1"},
+			prepare: func(mockRepo *MockRepository, mockUsers *MockUserSource) {
+				mockRepo.On("FindUser", mock.Anything, testIssuer, "emp-1").Return(jane.ID, nil).Once()
+				mockUsers.On("GetUserByID", mock.Anything, jane.ID).Return(deactivated, nil).Once()
+			},
+			wantErr: ErrAccountNotFound,
+		},
+		"user linked to another account": {
+			claims: &Claims{Issuer: testIssuer, Subject: "emp-2", Email: jane.Email, EmailVerified: true},
+			prepare: func(mockRepo *MockRepository, mockUsers *MockUserSource) {
+				mockRepo.On("FindUser", mock.Anything, testIssuer, "emp-2").Return(uuid.Nil, ErrIdentityNotFound).Once()
+				mockUsers.On("GetUserByEmail", mock.Anything, jane.Email).Return(jane, nil).Once()
+				mockRepo.On("Link", mock.Anything, mock.Anything).Return(ErrAlreadyLinked).Once()
+			},
+			wantErr: ErrAlreadyLinked,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			mockProvider := new(MockProvider)
+			mockRepo := new(MockRepository)
+			mockUsers := new(MockUserSource)
+			mockLogins := new(MockLogins)
+			svc := NewService(mockProvider, testIssuer, mockRepo, mockUsers, mockLogins, 10*time.Minute)
+			expectCallback(mockRepo, mockProvider, tt.claims)
+			tt.prepare(mockRepo, mockUsers)
+
+			_, err := svc.Complete(context.Background(), "code", "state-1", session.Client{})
+			assert.ErrorIs(t, err, tt.wantErr)
+			mockRepo.AssertExpectations(t)
+			mockUsers.AssertExpectations(t)
+			mockLogins.AssertNotCalled(t, "LoginVerified", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_Complete_InvalidStateOrToken(t *testing.T) {
+	mockProvider := new(MockProvider)
+	mockRepo := new(MockRepository)
+	mockLogins := new(MockLogins)
+	svc := NewService(mockProvider, testIssuer, mockRepo, new(MockUserSource), mockLogins, 10*time.Minute)
+	ctx := context.Background()
+
+	_, err := svc.Complete(ctx, "code", "", session.Client{})
+	require.ErrorIs(t, err, ErrInvalidState)
+	mockRepo.AssertNotCalled(t, "ConsumeState", mock.Anything, mock.Anything)
+
+	mockRepo.On("ConsumeState", ctx, tokens.Hash("forged-state")).Return(nil, ErrInvalidState).Once()
+	_, err = svc.Complete(ctx, "code", "forged-state", session.Client{})
+	require.ErrorIs(t, err, ErrInvalidState)
+	mockProvider.AssertNotCalled(t, "Exchange", mock.Anything, mock.Anything, mock.Anything)
+
+	mockRepo.On("ConsumeState", ctx, tokens.Hash("state-1")).Return(&LoginState{Nonce: "nonce-1", CodeVerifier: "verifier-1"}, nil).Once()
+	mockProvider.On("Exchange", ctx, "code", "verifier-1").Return("id-token", nil).Once()
+	mockProvider.On("Verify", ctx, "id-token", "nonce-1").Return(nil, ErrInvalidToken).Once()
+	_, err = svc.Complete(ctx, "code", "state-1", session.Client{})
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	mockRepo.AssertExpectations(t)
+	mockProvider.AssertExpectations(t)
+	mockLogins.AssertNotCalled(t, "LoginVerified", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,110 @@
+// Package orderclient - HTTP клиент order-service для проверок, которые user-service делает перед изменением пользователя.
+package orderclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+)
+
+type Client struct {
+	baseURL      string
+	token        string       // Токен внутренних маршрутов order-service
+	httpClient   *http.Client // Короткие запросы с общим таймаутом
+	streamClient *http.Client // Выгрузки: длительность ограничивает только ctx
+}
+
+// New создаёт клиент order-service. baseURL - адрес HTTP API, например "http://order-service:8080".
+// token передаётся в маршруты /internal, пустой не передаётся.
+func New(baseURL, token string, timeout time.Duration) *Client {
+	return &Client{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		token:        token,
+		httpClient:   &http.Client{Timeout: timeout},
+		streamClient: &http.Client{},
+	}
+}
+
+type activeOrdersResponse struct {
+	ActiveOrders int `json:"active_orders"`
+}
+
+// CountActiveOrders возвращает число незавершённых заказов пользователя.
+func (c *Client) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	endpoint := c.baseURL + "/internal/users/" + url.PathEscape(userID.String()) + "/active-orders"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("orderclient: failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("orderclient: request to order-service failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return 0, fmt.Errorf("orderclient: order-service responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var payload activeOrdersResponse
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return 0, fmt.Errorf("orderclient: failed to decode order-service response: %w", err)
+	}
+	return payload.ActiveOrders, nil
+}
+
+// ExportUserOrders копирует в w выгрузку всех заказов пользователя в формате NDJSON, не буферизуя её.
+// Ошибка означает, что в w могла попасть только часть выгрузки.
+func (c *Client) ExportUserOrders(ctx context.Context, userID uuid.UUID, w io.Writer) (int64, error) {
+	endpoint := c.baseURL + "/internal/users/" + url.PathEscape(userID.String()) + "/orders/export"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, fmt.Errorf("orderclient: failed to build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.streamClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("orderclient: export request to order-service failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("orderclient: order-service responded %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if rejected(resp.StatusCode) {
+			return 0, fmt.Errorf("%w: %w", export.ErrOrdersRejected, err)
+		}
+		return 0, err
+	}
+
+	// Оборванный order-service поток даёт ошибку чтения, а не тихий EOF
+	written, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return written, fmt.Errorf("orderclient: failed to read orders export: %w", err)
+	}
+	return written, nil
+}
+
+// rejected сообщает, что order-service отклонил сам запрос: повтор с теми же данными ответ не изменит.
+func rejected(statusCode int) bool {
+	return statusCode >= 400 && statusCode < 500 &&
+		statusCode != http.StatusRequestTimeout && statusCode != http.StatusTooManyRequests
+}
@@ -0,0 +1,102 @@
+package orderclient_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/export"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/orderclient"
+)
+
+func TestClient_CountActiveOrders(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/internal/users/"+userID.String()+"/active-orders", r.URL.Path)
+		assert.Equal(t, "Bearer internal-secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"user_id":"` + userID.String() + `","active_orders":3}`))
+	}))
+	defer server.Close()
+
+	count, err := orderclient.New(server.URL+"/", "internal-secret", time.Second).CountActiveOrders(context.Background(), userID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestClient_CountActiveOrders_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"Failed to count active orders"}`, http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	_, err := orderclient.New(server.URL, "", time.Second).CountActiveOrders(context.Background(), uuid.Must(uuid.NewV4()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func TestClient_CountActiveOrders_Unavailable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	baseURL := server.URL
+	server.Close()
+
+	_, err := orderclient.New(baseURL, "", time.Second).CountActiveOrders(context.Background(), uuid.Must(uuid.NewV4()))
+	require.Error(t, err)
+}
+
+func TestClient_ExportUserOrders(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/internal/users/"+userID.String()+"/orders/export", r.URL.Path)
+		assert.Equal(t, "Bearer internal-secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		_, _ = w.Write([]byte("{\"id\":\"1\"}\n{\"id\":\"2\"}\n"))
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	written, err := orderclient.New(server.URL, "internal-secret", time.Second).ExportUserOrders(context.Background(), userID, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), written)
+	assert.Equal(t, "{\"id\":\"1\"}\n{\"id\":\"2\"}\n", buf.String())
+}
+
+func TestClient_ExportUserOrders_AbortedStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("{\"id\":\"1\"}\n"))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer server.Close()
+
+	_, err := orderclient.New(server.URL, "", time.Second).ExportUserOrders(context.Background(), uuid.Must(uuid.NewV4()), &bytes.Buffer{})
+	require.Error(t, err)
+}
+
+func TestClient_ExportUserOrders_RejectedIsPermanent(t *testing.T) {
+	statuses := map[int]bool{
+		http.StatusUnauthorized:        true,
+		http.StatusNotFound:            true,
+		http.StatusTooManyRequests:     false,
+		http.StatusServiceUnavailable:  false,
+		http.StatusInternalServerError: false,
+	}
+	for status, permanent := range statuses {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, `{"error":"nope"}`, status)
+		}))
+
+		_, err := orderclient.New(server.URL, "", time.Second).ExportUserOrders(context.Background(), uuid.Must(uuid.NewV4()), &bytes.Buffer{})
+		server.Close()
+		require.Error(t, err)
+		assert.Equal(t, permanent, errors.Is(err, export.ErrOrdersRejected), "status %d", status)
+	}
+}
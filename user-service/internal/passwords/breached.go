@@ -0,0 +1,95 @@
+package passwords
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// prefixLength - длина префикса SHA-1, по которому список разбит на корзины, как в k-анонимном API Have I Been Pwned.
+// SHA-1 здесь - формат таких списков, а не защита: пароли в сервисе хранятся в bcrypt.
+const prefixLength = 5
+
+// BreachedList - локальный список скомпрометированных паролей в виде SHA-1 хешей.
+// Сами пароли в памяти и в файле не хранятся.
+type BreachedList struct {
+	buckets map[string]map[string]struct{} // Префикс хеша -> остатки хешей с этим префиксом
+	size    int
+}
+
+// LoadBreachedList читает список из файла. Формат описан в ParseBreachedList.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("passwords: failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	list, err := ParseBreachedList(file)
+	if err != nil {
+		return nil, fmt.Errorf("passwords: %s: %w", path, err)
+	}
+	return list, nil
+}
+
+// ParseBreachedList читает по одному SHA-1 хешу пароля в hex на строку.
+// Допускается счётчик через двоеточие ("HASH:COUNT", как в выгрузках Have I Been Pwned),
+// пустые строки и комментарии с #.
+func ParseBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{buckets: make(map[string]map[string]struct{})}
+
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: expected a %d-character SHA-1 hash", lineNumber, sha1.Size*2)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: hash is not hex: %w", lineNumber, err)
+		}
+
+		list.add(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) add(hash string) {
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	bucket, ok := l.buckets[prefix]
+	if !ok {
+		bucket = make(map[string]struct{})
+		l.buckets[prefix] = bucket
+	}
+	if _, exists := bucket[suffix]; !exists {
+		bucket[suffix] = struct{}{}
+		l.size++
+	}
+}
+
+// Len возвращает число различных хешей в списке.
+func (l *BreachedList) Len() int {
+	return l.size
+}
+
+// Contains сообщает, есть ли пароль в списке.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	_, found := l.buckets[hash[:prefixLength]][hash[prefixLength:]]
+	return found
+}
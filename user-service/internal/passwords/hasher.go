@@ -0,0 +1,162 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Алгоритмы хеширования новых паролей.
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var ErrUnknownHashFormat = errors.New("unknown password hash format")
+
+// Argon2Params - параметры Argon2id. Memory задаётся в КиБ.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params - параметры из рекомендаций OWASP: 64 МиБ, 3 прохода.
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+
+// HashParams задаёт, каким алгоритмом и с какими параметрами хешируются новые пароли.
+type HashParams struct {
+	Algorithm  string
+	BcryptCost int
+	Argon2     Argon2Params
+}
+
+// Hasher хеширует пароли текущими параметрами и проверяет хеши любого поддерживаемого формата.
+// Хеши самоописываемые: bcrypt хранит стоимость в "$2a$<cost>$...", Argon2id пишется в формате PHC
+// "$argon2id$v=19$m=<KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>".
+type Hasher struct {
+	params HashParams
+}
+
+func NewHasher(params HashParams) (*Hasher, error) {
+	switch params.Algorithm {
+	case AlgorithmBcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("passwords: bcrypt cost must be between %d and %d, got %d", bcrypt.MinCost, bcrypt.MaxCost, params.BcryptCost)
+		}
+	case AlgorithmArgon2id:
+		a := params.Argon2
+		if a.Memory == 0 || a.Iterations == 0 || a.Parallelism == 0 || a.SaltLength < 8 || a.KeyLength < 16 {
+			return nil, fmt.Errorf("passwords: invalid argon2id parameters %+v", a)
+		}
+	default:
+		return nil, fmt.Errorf("passwords: unknown hash algorithm '%s'", params.Algorithm)
+	}
+	return &Hasher{params: params}, nil
+}
+
+// Hash хеширует пароль текущим алгоритмом.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == AlgorithmArgon2id {
+		return hashArgon2id(password, h.params.Argon2)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("passwords: failed to hash password with bcrypt: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Verify сравнивает пароль с хешем. needsRehash - пароль верный, но хеш создан другим алгоритмом
+// или с другими параметрами и его стоит пересчитать текущими.
+func (h *Hasher) Verify(password, encoded string) (ok bool, needsRehash bool, err error) {
+	switch {
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, fmt.Errorf("passwords: failed to verify bcrypt hash: %w", err)
+		}
+		cost, err := bcrypt.Cost([]byte(encoded))
+		if err != nil {
+			return false, false, fmt.Errorf("passwords: failed to read bcrypt cost: %w", err)
+		}
+		return true, h.params.Algorithm != AlgorithmBcrypt || cost != h.params.BcryptCost, nil
+
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(encoded)
+		if err != nil {
+			return false, false, err
+		}
+		computed := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+		current := h.params.Argon2
+		outdated := h.params.Algorithm != AlgorithmArgon2id ||
+			params.Memory != current.Memory ||
+			params.Iterations != current.Iterations ||
+			params.Parallelism != current.Parallelism ||
+			uint32(len(salt)) != current.SaltLength ||
+			uint32(len(key)) != current.KeyLength
+		return true, outdated, nil
+
+	default:
+		return false, false, ErrUnknownHashFormat
+	}
+}
+
+func hashArgon2id(password string, p Argon2Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("passwords: failed to generate salt: %w", err)
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func decodeArgon2id(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("passwords: malformed argon2id hash: %w", ErrUnknownHashFormat)
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("passwords: unsupported argon2id version '%s': %w", parts[2], ErrUnknownHashFormat)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("passwords: malformed argon2id parameters '%s': %w", parts[3], ErrUnknownHashFormat)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, fmt.Errorf("passwords: malformed argon2id salt: %w", ErrUnknownHashFormat)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, fmt.Errorf("passwords: malformed argon2id key: %w", ErrUnknownHashFormat)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
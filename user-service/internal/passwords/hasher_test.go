@@ -0,0 +1,151 @@
+package passwords
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastArgon2 - дешёвые параметры, чтобы тесты не тратили 64 МиБ на каждый хеш.
+var fastArgon2 = Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestHasher(t *testing.T, params HashParams) *Hasher {
+	t.Helper()
+	hasher, err := NewHasher(params)
+	require.NoError(t, err)
+	return hasher
+}
+
+func TestHasher_HashAndVerify(t *testing.T) {
+	for _, params := range []HashParams{
+		{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost},
+		{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2},
+	} {
+		t.Run(params.Algorithm, func(t *testing.T) {
+			hasher := newTestHasher(t, params)
+
+			hash, err := hasher.Hash("correct-horse")
+			require.NoError(t, err)
+			assert.NotContains(t, hash, "correct-horse")
+
+			ok, needsRehash, err := hasher.Verify("correct-horse", hash)
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.False(t, needsRehash)
+
+			ok, _, err = hasher.Verify("wrong-horse", hash)
+			require.NoError(t, err)
+			assert.False(t, ok)
+
+			again, err := hasher.Hash("correct-horse")
+			require.NoError(t, err)
+			assert.NotEqual(t, hash, again, "hashes must be salted")
+		})
+	}
+}
+
+func TestHasher_Argon2idFormat(t *testing.T) {
+	hash, err := newTestHasher(t, HashParams{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2}).Hash("secret-pass")
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"), hash)
+	assert.Len(t, strings.Split(hash, "$"), 6)
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	oldBcrypt := newTestHasher(t, HashParams{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+	legacyHash, err := oldBcrypt.Hash("secret-pass")
+	require.NoError(t, err)
+
+	strongerBcrypt := newTestHasher(t, HashParams{Algorithm: AlgorithmBcrypt, BcryptCost: bcrypt.MinCost + 1})
+	ok, needsRehash, err := strongerBcrypt.Verify("secret-pass", legacyHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "bcrypt cost changed")
+
+	argon := newTestHasher(t, HashParams{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2})
+	ok, needsRehash, err = argon.Verify("secret-pass", legacyHash)
+	require.NoError(t, err)
+	assert.True(t, ok, "bcrypt hashes stay valid after switching to argon2id")
+	assert.True(t, needsRehash)
+
+	argonHash, err := argon.Hash("secret-pass")
+	require.NoError(t, err)
+	moreMemory := fastArgon2
+	moreMemory.Memory *= 2
+	ok, needsRehash, err = newTestHasher(t, HashParams{Algorithm: AlgorithmArgon2id, Argon2: moreMemory}).Verify("secret-pass", argonHash)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, needsRehash, "argon2id memory changed")
+
+	// Неверный пароль никогда не требует перехеширования
+	ok, needsRehash, err = argon.Verify("wrong-pass", legacyHash)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.False(t, needsRehash)
+}
+
+func TestHasher_RejectsUnknownAndMalformedHashes(t *testing.T) {
+	hasher := newTestHasher(t, HashParams{Algorithm: AlgorithmArgon2id, Argon2: fastArgon2})
+
+	for _, hash := range []string{
+		"",
+		"plain-text-password",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=18$m=1024,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHQ$a2V5a2V5a2V5a2V5",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$a2V5a2V5a2V5a2V5",
+	} {
+		_, _, err := hasher.Verify("secret-pass", hash)
+		require.ErrorIs(t, err, ErrUnknownHashFormat, hash)
+	}
+}
+
+func TestNewHasher_ValidatesParams(t *testing.T) {
+	_, err := NewHasher(HashParams{Algorithm: AlgorithmBcrypt, BcryptCost: 3})
+	require.Error(t, err)
+	_, err = NewHasher(HashParams{Algorithm: AlgorithmArgon2id, Argon2: Argon2Params{Memory: 1024}})
+	require.Error(t, err)
+	_, err = NewHasher(HashParams{Algorithm: "md5"})
+	require.Error(t, err)
+}
+
+// Бенчмарки помогают выбрать параметры: один хеш при входе должен занимать порядка 100-500 мс на целевом железе.
+//
+//	go test -run '^$' -bench Hasher ./internal/passwords/
+func BenchmarkHasher_Bcrypt(b *testing.B) {
+	for _, cost := range []int{10, 11, 12, 13, 14} {
+		b.Run(fmt.Sprintf("cost=%d", cost), func(b *testing.B) {
+			benchmarkHash(b, HashParams{Algorithm: AlgorithmBcrypt, BcryptCost: cost})
+		})
+	}
+}
+
+func BenchmarkHasher_Argon2id(b *testing.B) {
+	for _, p := range []Argon2Params{
+		{Memory: 19 * 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 46 * 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		DefaultArgon2Params,
+		{Memory: 128 * 1024, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32},
+	} {
+		b.Run(fmt.Sprintf("m=%dMiB,t=%d,p=%d", p.Memory/1024, p.Iterations, p.Parallelism), func(b *testing.B) {
+			benchmarkHash(b, HashParams{Algorithm: AlgorithmArgon2id, Argon2: p})
+		})
+	}
+}
+
+func benchmarkHash(b *testing.B, params HashParams) {
+	hasher, err := NewHasher(params)
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := hasher.Hash("correct-horse-battery-staple"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
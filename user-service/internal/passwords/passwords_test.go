@@ -0,0 +1,111 @@
+package passwords
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var policyErr *PolicyError
+	require.True(t, errors.As(err, &policyErr), "expected *PolicyError, got %v", err)
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, v := range policyErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestValidator_Validate(t *testing.T) {
+	validator := NewValidator(Policy{
+		MinLength:            10,
+		MaxLength:            64,
+		RequireUppercase:     true,
+		RequireLowercase:     true,
+		RequireDigit:         true,
+		RequireSymbol:        true,
+		DisallowPersonalInfo: true,
+	}, nil)
+	personal := []string{"ivan.petrov@example.com", "Ivan", "Petrov"}
+
+	tests := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{"valid", "Correct-Horse-42", nil},
+		{"too short", "Ab1-", []string{RuleMinLength}},
+		{"too long", "Aa1-" + strings.Repeat("x", 61), []string{RuleMaxLength}},
+		{"missing classes", "lowercaseonly", []string{RuleUppercase, RuleDigit, RuleSymbol}},
+		{"contains email local part", "Ivan.Petrov-2024", []string{RulePersonalInfo}},
+		{"contains last name", "xX-petrov-Xx-9", []string{RulePersonalInfo}},
+		{"multibyte over bcrypt limit", "Пароль-1" + strings.Repeat("ж", 33), []string{RuleMaxBytes}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ElementsMatch(t, tt.rules, violatedRules(t, validator.Validate(tt.password, personal...)))
+		})
+	}
+}
+
+func TestValidator_ShortPersonalPartsIgnored(t *testing.T) {
+	validator := NewValidator(Policy{MinLength: 8, DisallowPersonalInfo: true}, nil)
+
+	require.NoError(t, validator.Validate("bolivian-llama", "li@example.com", "Li"))
+}
+
+func TestValidator_Breached(t *testing.T) {
+	list, err := ParseBreachedList(strings.NewReader("# top passwords\n" + sha1Hex("password123") + ":2254650\n\n" + strings.ToLower(sha1Hex("qwerty12345")) + "\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, list.Len())
+
+	validator := NewValidator(Policy{MinLength: 8}, list)
+	assert.Equal(t, []string{RuleBreached}, violatedRules(t, validator.Validate("password123")))
+	assert.Equal(t, []string{RuleBreached}, violatedRules(t, validator.Validate("qwerty12345")))
+	require.NoError(t, validator.Validate("unlisted-passphrase"))
+}
+
+func TestParseBreachedList_RejectsMalformedLines(t *testing.T) {
+	_, err := ParseBreachedList(strings.NewReader(sha1Hex("ok") + "\nnot-a-hash\n"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, err = ParseBreachedList(strings.NewReader(strings.Repeat("Z", 40) + "\n"))
+	require.Error(t, err)
+}
+
+func TestLoadBreachedList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(path, []byte(sha1Hex("letmein!")+"\n"), 0o600))
+
+	list, err := LoadBreachedList(path)
+	require.NoError(t, err)
+	assert.True(t, list.Contains("letmein!"))
+	assert.False(t, list.Contains("letmein"))
+
+	_, err = LoadBreachedList(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestLoadBreachedList_BundledFile(t *testing.T) {
+	list, err := LoadBreachedList(filepath.Join("..", "..", "data", "breached-passwords.txt"))
+	require.NoError(t, err)
+	assert.True(t, list.Contains("password123"))
+	assert.False(t, list.Contains("correct-horse-battery-staple"))
+}
@@ -0,0 +1,145 @@
+// Package passwords проверяет новые пароли по политике сервиса и списку скомпрометированных паролей.
+package passwords
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BcryptMaxBytes - сколько байт пароля учитывает bcrypt. Более длинный пароль bcrypt отвергает.
+const BcryptMaxBytes = 72
+
+// Правила политики. Используются как ключи в ответе клиенту.
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleMaxBytes     = "max_bytes"
+	RuleUppercase    = "uppercase"
+	RuleLowercase    = "lowercase"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RulePersonalInfo = "personal_info"
+	RuleBreached     = "breached"
+)
+
+// minPersonalPartLength - части email и имени короче этого не проверяются, иначе под запрет попадут обычные слоги.
+const minPersonalPartLength = 3
+
+// Policy - требования к паролю. Длина считается в символах, а не в байтах.
+type Policy struct {
+	MinLength            int
+	MaxLength            int
+	RequireUppercase     bool
+	RequireLowercase     bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool // Пароль не может содержать email, его имя до @, имя или фамилию пользователя
+}
+
+// Violation - нарушенное правило политики.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError перечисляет все нарушенные правила, чтобы клиент мог исправить пароль за одну попытку.
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	rules := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		rules = append(rules, v.Rule)
+	}
+	return "password does not meet the policy: " + strings.Join(rules, ", ")
+}
+
+// Validator проверяет пароль по политике и, если задан список, по скомпрометированным паролям.
+type Validator struct {
+	policy   Policy
+	breached *BreachedList
+}
+
+// NewValidator создаёт проверку паролей. breached может быть nil - тогда список не проверяется.
+func NewValidator(policy Policy, breached *BreachedList) *Validator {
+	return &Validator{policy: policy, breached: breached}
+}
+
+// Validate возвращает *PolicyError со всеми нарушениями или nil.
+// personal - email и имена пользователя, которые не должны входить в пароль.
+func (v *Validator) Validate(password string, personal ...string) error {
+	var violations []Violation
+	add := func(rule, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < v.policy.MinLength {
+		add(RuleMinLength, "Password must be at least %d characters long", v.policy.MinLength)
+	}
+	if v.policy.MaxLength > 0 && length > v.policy.MaxLength {
+		add(RuleMaxLength, "Password must be at most %d characters long", v.policy.MaxLength)
+	}
+	if len(password) > BcryptMaxBytes {
+		add(RuleMaxBytes, "Password must be at most %d bytes long in UTF-8", BcryptMaxBytes)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if v.policy.RequireUppercase && !hasUpper {
+		add(RuleUppercase, "Password must contain an uppercase letter")
+	}
+	if v.policy.RequireLowercase && !hasLower {
+		add(RuleLowercase, "Password must contain a lowercase letter")
+	}
+	if v.policy.RequireDigit && !hasDigit {
+		add(RuleDigit, "Password must contain a digit")
+	}
+	if v.policy.RequireSymbol && !hasSymbol {
+		add(RuleSymbol, "Password must contain a symbol")
+	}
+
+	if v.policy.DisallowPersonalInfo && containsPersonalInfo(password, personal) {
+		add(RulePersonalInfo, "Password must not contain your email or name")
+	}
+	if v.breached != nil && v.breached.Contains(password) {
+		add(RuleBreached, "Password has appeared in a data breach, choose another one")
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsPersonalInfo(password string, personal []string) bool {
+	lowered := strings.ToLower(password)
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+		parts := []string{value}
+		// Для email проверяется и имя ящика: "ivan.petrov@example.com" -> "ivan.petrov"
+		if local, _, found := strings.Cut(value, "@"); found {
+			parts = append(parts, local)
+		}
+		for _, part := range parts {
+			if utf8.RuneCountInString(part) >= minPersonalPartLength && strings.Contains(lowered, part) {
+				return true
+			}
+		}
+	}
+	return false
+}
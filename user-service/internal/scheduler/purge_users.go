@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// purgeUsersLockKey - ключ advisory-блокировки задачи очистки удалённых пользователей.
+const purgeUsersLockKey int64 = 0x7573725f707267 // "usr_prg"
+
+var purgeUsersMetrics = expvar.NewMap("deleted_user_purger")
+
+// UserPurger окончательно удаляет пользователей, у которых истёк срок хранения после удаления.
+type UserPurger interface {
+	PurgeDeletedUsers(ctx context.Context, limit int) (int, error)
+}
+
+// DeletedUserPurger периодически удаляет из базы пользователей, удалённых раньше срока хранения.
+type DeletedUserPurger struct {
+	users     UserPurger
+	locker    Locker
+	interval  time.Duration
+	batchSize int
+}
+
+func NewDeletedUserPurger(users UserPurger, locker Locker, interval time.Duration, batchSize int) *DeletedUserPurger {
+	return &DeletedUserPurger{
+		users:     users,
+		locker:    locker,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run запускает задачу по таймеру и блокируется до отмены ctx.
+func (p *DeletedUserPurger) Run(ctx context.Context) {
+	log.Info().Dur("interval", p.interval).Msg("scheduler: deleted user purger started")
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info().Msg("scheduler: deleted user purger stopped")
+			return
+		case <-ticker.C:
+			if _, err := p.RunOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Error().Err(err).Msg("scheduler: deleted user purger run failed")
+			}
+		}
+	}
+}
+
+// RunOnce выполняет один проход, если эту задачу сейчас не выполняет другая реплика, и возвращает число удалённых пользователей.
+func (p *DeletedUserPurger) RunOnce(ctx context.Context) (int, error) {
+	release, acquired, err := p.locker.TryLock(ctx, purgeUsersLockKey)
+	if err != nil {
+		purgeUsersMetrics.Add("errors", 1)
+		return 0, err
+	}
+	if !acquired {
+		purgeUsersMetrics.Add("runs_skipped_locked", 1)
+		log.Debug().Msg("scheduler: deleted user purger is running on another replica, skipping")
+		return 0, nil
+	}
+	defer release()
+
+	purgeUsersMetrics.Add("runs", 1)
+
+	total := 0
+	for {
+		purged, err := p.users.PurgeDeletedUsers(ctx, p.batchSize)
+		total += purged
+		if err != nil {
+			purgeUsersMetrics.Add("errors", 1)
+			purgeUsersMetrics.Add("users_purged", int64(total))
+			return total, err
+		}
+
+		// Неполная пачка - больше нечего удалять
+		if purged < p.batchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	purgeUsersMetrics.Add("users_purged", int64(total))
+	if total > 0 {
+		log.Info().Int("purged", total).Msg("scheduler: deleted users purged")
+	}
+
+	return total, nil
+}
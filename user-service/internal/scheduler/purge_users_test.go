@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockUserPurger struct {
+	mock.Mock
+}
+
+func (m *MockUserPurger) PurgeDeletedUsers(ctx context.Context, limit int) (int, error) {
+	args := m.Called(ctx, limit)
+	return args.Int(0), args.Error(1)
+}
+
+// fakeLocker эмулирует advisory-блокировку, которую может держать другая реплика.
+type fakeLocker struct {
+	heldElsewhere bool
+	released      int
+}
+
+func (l *fakeLocker) TryLock(context.Context, int64) (func(), bool, error) {
+	if l.heldElsewhere {
+		return nil, false, nil
+	}
+	return func() { l.released++ }, true, nil
+}
+
+func TestDeletedUserPurger_RunOnce_PurgesInBatches(t *testing.T) {
+	mockUsers := new(MockUserPurger)
+	locker := &fakeLocker{}
+	purger := NewDeletedUserPurger(mockUsers, locker, time.Minute, 2)
+	ctx := context.Background()
+
+	mockUsers.On("PurgeDeletedUsers", ctx, 2).Return(2, nil).Once()
+	mockUsers.On("PurgeDeletedUsers", ctx, 2).Return(1, nil).Once()
+
+	purged, err := purger.RunOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.Equal(t, 1, locker.released)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestDeletedUserPurger_RunOnce_StopsOnError(t *testing.T) {
+	mockUsers := new(MockUserPurger)
+	locker := &fakeLocker{}
+	purger := NewDeletedUserPurger(mockUsers, locker, time.Minute, 2)
+	ctx := context.Background()
+
+	dbErr := errors.New("db is down")
+	mockUsers.On("PurgeDeletedUsers", ctx, 2).Return(0, dbErr).Once()
+
+	_, err := purger.RunOnce(ctx)
+	require.ErrorIs(t, err, dbErr)
+	assert.Equal(t, 1, locker.released)
+	mockUsers.AssertExpectations(t)
+}
+
+func TestDeletedUserPurger_RunOnce_LockHeldByAnotherReplica(t *testing.T) {
+	mockUsers := new(MockUserPurger)
+	purger := NewDeletedUserPurger(mockUsers, &fakeLocker{heldElsewhere: true}, time.Minute, 10)
+
+	purged, err := purger.RunOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+	mockUsers.AssertNotCalled(t, "PurgeDeletedUsers", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,65 @@
+// Package secretbox шифрует небольшие секреты перед записью в БД (AES-256-GCM).
+// Ключ хранится в конфигурации, а не в БД: выгрузка таблицы без ключа секретов не раскрывает.
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize - длина ключа AES-256.
+const KeySize = 32
+
+// version - первый байт шифротекста. Позволит сменить алгоритм или ключ, не теряя старые записи.
+const version byte = 1
+
+var ErrDecrypt = errors.New("secretbox: failed to decrypt secret")
+
+type Box struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secretbox: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("secretbox: failed to create gcm: %w", err)
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal шифрует plaintext. associated привязывает шифротекст к записи (например, к ID пользователя):
+// скопированный в чужую строку секрет не расшифруется.
+func (b *Box) Seal(plaintext, associated []byte) ([]byte, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("secretbox: failed to generate nonce: %w", err)
+	}
+
+	out := make([]byte, 0, 1+len(nonce)+len(plaintext)+b.aead.Overhead())
+	out = append(out, version)
+	out = append(out, nonce...)
+	return b.aead.Seal(out, nonce, plaintext, associated), nil
+}
+
+// Open расшифровывает результат Seal. ErrDecrypt при чужом ключе, подмене данных или другом associated.
+func (b *Box) Open(sealed, associated []byte) ([]byte, error) {
+	nonceSize := b.aead.NonceSize()
+	if len(sealed) < 1+nonceSize || sealed[0] != version {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := b.aead.Open(nil, sealed[1:1+nonceSize], sealed[1+nonceSize:], associated)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,57 @@
+package secretbox
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBox(t *testing.T, fill byte) *Box {
+	t.Helper()
+	box, err := New(bytes.Repeat([]byte{fill}, KeySize))
+	require.NoError(t, err)
+	return box
+}
+
+func TestBox_SealOpen(t *testing.T) {
+	box := newTestBox(t, 1)
+
+	sealed, err := box.Seal([]byte("totp-secret"), []byte("user-1"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(sealed), "totp-secret")
+
+	opened, err := box.Open(sealed, []byte("user-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "totp-secret", string(opened))
+
+	again, err := box.Seal([]byte("totp-secret"), []byte("user-1"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonce must be random")
+}
+
+func TestBox_OpenRejectsTampering(t *testing.T) {
+	box := newTestBox(t, 1)
+	sealed, err := box.Seal([]byte("totp-secret"), []byte("user-1"))
+	require.NoError(t, err)
+
+	_, err = box.Open(sealed, []byte("user-2"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = newTestBox(t, 2).Open(sealed, []byte("user-1"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-1] ^= 0xff
+	_, err = box.Open(tampered, []byte("user-1"))
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = box.Open([]byte{version}, nil)
+	require.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestNew_RejectsShortKey(t *testing.T) {
+	_, err := New([]byte("short"))
+	require.Error(t, err)
+}
@@ -0,0 +1,98 @@
+// Package session хранит сессии пользователей, открытые при входе по паролю.
+// Все сессии пользователя отзываются при сбросе пароля (см. auth).
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var ErrSessionNotFound = errors.New("session not found or expired")
+
+// Session - открытая сессия. Токен сессии знает только клиент, в БД хранится его хеш.
+type Session struct {
+	ID        uuid.UUID  `json:"id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	UserAgent string     `json:"user_agent"`
+	IP        string     `json:"ip"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Client описывает, откуда открыта сессия.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+type Repository interface {
+	Create(ctx context.Context, s *Session, tokenHash []byte) error
+	// GetActive возвращает неотозванную и неистёкшую сессию активного пользователя.
+	GetActive(ctx context.Context, tokenHash []byte) (*Session, error)
+	Revoke(ctx context.Context, tokenHash []byte) error
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) Create(ctx context.Context, s *Session, tokenHash []byte) error {
+	query := `
+		INSERT INTO user_service.sessions (id, user_id, token_hash, user_agent, ip, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, s.ID, s.UserID, tokenHash, s.UserAgent, s.IP, s.ExpiresAt).Scan(&s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session for user %s: %w", s.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) GetActive(ctx context.Context, tokenHash []byte) (*Session, error) {
+	// Сессии удалённых и заблокированных пользователей не действуют, даже если не отозваны
+	query := `
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.expires_at, s.revoked_at
+		FROM user_service.sessions s
+		JOIN user_service.users u ON u.id = s.user_id
+		WHERE s.token_hash = $1
+			AND s.revoked_at IS NULL
+			AND s.expires_at > NOW()
+			AND u.deleted_at IS NULL
+			AND u.status = 'ACTIVE'
+	`
+
+	var s Session
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(&s.ID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.ExpiresAt, &s.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *postgresRepository) Revoke(ctx context.Context, tokenHash []byte) error {
+	query := `UPDATE user_service.sessions SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL`
+
+	tag, err := r.db.Exec(ctx, query, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
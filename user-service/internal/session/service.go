@@ -0,0 +1,67 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+)
+
+type Service interface {
+	// Start открывает сессию и возвращает её токен. Токен больше нигде не сохраняется.
+	Start(ctx context.Context, userID uuid.UUID, client Client) (string, *Session, error)
+	// Authenticate находит действующую сессию по токену. ErrSessionNotFound, если её нет.
+	Authenticate(ctx context.Context, token string) (*Session, error)
+	End(ctx context.Context, token string) error
+}
+
+type service struct {
+	repo Repository
+	ttl  time.Duration // Срок жизни сессии с момента входа
+}
+
+func NewService(repo Repository, ttl time.Duration) Service {
+	return &service{repo: repo, ttl: ttl}
+}
+
+func (s *service) Start(ctx context.Context, userID uuid.UUID, client Client) (string, *Session, error) {
+	token, hash, err := tokens.Generate()
+	if err != nil {
+		return "", nil, err
+	}
+	sessionID, err := uuid.NewV4()
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	sess := &Session{
+		ID:        sessionID,
+		UserID:    userID,
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.repo.Create(ctx, sess, hash); err != nil {
+		return "", nil, err
+	}
+
+	log.Info().Stringer("user_id", userID).Stringer("session_id", sess.ID).Msg("Session started")
+	return token, sess, nil
+}
+
+func (s *service) Authenticate(ctx context.Context, token string) (*Session, error) {
+	if token == "" {
+		return nil, ErrSessionNotFound
+	}
+	return s.repo.GetActive(ctx, tokens.Hash(token))
+}
+
+func (s *service) End(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrSessionNotFound
+	}
+	return s.repo.Revoke(ctx, tokens.Hash(token))
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, s *Session, tokenHash []byte) error {
+	args := m.Called(ctx, s, tokenHash)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetActive(ctx context.Context, tokenHash []byte) (*Session, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Session), args.Error(1)
+}
+
+func (m *MockRepository) Revoke(ctx context.Context, tokenHash []byte) error {
+	args := m.Called(ctx, tokenHash)
+	return args.Error(0)
+}
+
+func TestService_Start_StoresOnlyHash(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, time.Hour)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	var storedHash []byte
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(s *Session) bool {
+		return s.UserID == userID && s.IP == "10.0.0.1" && time.Until(s.ExpiresAt) > 59*time.Minute
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		storedHash = args.Get(2).([]byte)
+	}).Return(nil).Once()
+
+	token, sess, err := svc.Start(ctx, userID, Client{UserAgent: "curl", IP: "10.0.0.1"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.NotEqual(t, uuid.Nil, sess.ID)
+	assert.True(t, bytes.Equal(tokens.Hash(token), storedHash))
+	assert.NotContains(t, string(storedHash), token)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Authenticate(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, time.Hour)
+	ctx := context.Background()
+	sess := &Session{ID: uuid.Must(uuid.NewV4())}
+
+	mockRepo.On("GetActive", ctx, tokens.Hash("good")).Return(sess, nil).Once()
+	mockRepo.On("GetActive", ctx, tokens.Hash("revoked")).Return(nil, ErrSessionNotFound).Once()
+
+	found, err := svc.Authenticate(ctx, "good")
+	require.NoError(t, err)
+	assert.Equal(t, sess, found)
+
+	_, err = svc.Authenticate(ctx, "revoked")
+	require.ErrorIs(t, err, ErrSessionNotFound)
+
+	_, err = svc.Authenticate(ctx, "")
+	require.ErrorIs(t, err, ErrSessionNotFound)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,29 @@
+// Package tokens выпускает случайные одноразовые токены для ссылок из писем и сессий.
+// В БД хранится только хеш токена: утечка таблицы не даёт им воспользоваться.
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// size - длина случайной части токена. 256 бит не перебрать за время жизни токена.
+const size = 32
+
+// Generate возвращает токен для передачи пользователю и его хеш для хранения.
+func Generate() (string, []byte, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, Hash(token), nil
+}
+
+// Hash - SHA-256 токена. Соль не нужна: токен случайный и длинный, а не выбран человеком.
+func Hash(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,72 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret - ключ из тестовых векторов RFC 6238 для SHA1.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// В RFC коды 8-значные, здесь сравниваются их последние 6 цифр
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		assert.Equal(t, want, Code(rfcSecret, Step(time.Unix(unix, 0))), unix)
+	}
+}
+
+func TestValidate_AllowsClockSkew(t *testing.T) {
+	now := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	code := Code(rfcSecret, Step(now))
+
+	step, ok := Validate(rfcSecret, code, now, 1)
+	require.True(t, ok)
+	assert.Equal(t, Step(now), step)
+
+	// Код из приложения с отстающими часами
+	_, ok = Validate(rfcSecret, code, now.Add(Period), 1)
+	assert.True(t, ok)
+	_, ok = Validate(rfcSecret, code, now.Add(2*Period), 1)
+	assert.False(t, ok)
+
+	_, ok = Validate(rfcSecret, code[:3]+" "+code[3:], now, 1)
+	assert.True(t, ok, "spaces are ignored")
+	_, ok = Validate(rfcSecret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Shop", "jane@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", parsed.Scheme)
+	assert.Equal(t, "totp", parsed.Host)
+	assert.Equal(t, "/Shop:jane@example.com", parsed.Path)
+	assert.Equal(t, EncodeSecret(rfcSecret), parsed.Query().Get("secret"))
+	assert.Equal(t, "Shop", parsed.Query().Get("issuer"))
+	assert.False(t, strings.Contains(parsed.Query().Get("secret"), "="), "secret must not be padded")
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	require.NoError(t, err)
+	second, err := GenerateSecret()
+	require.NoError(t, err)
+
+	assert.Len(t, first, SecretSize)
+	assert.NotEqual(t, first, second)
+}
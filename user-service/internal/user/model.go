@@ -6,13 +6,25 @@ import (
 	"github.com/gofrs/uuid"
 )
 
+// Status - состояние учётной записи.
+type Status string
+
+const (
+	StatusActive      Status = "ACTIVE"
+	StatusDeactivated Status = "DEACTIVATED" // Заблокирован администратором, данные сохраняются
+	StatusDeleted     Status = "DELETED"     // Удалён, но может быть восстановлен до окончания срока хранения
+)
+
 // User представляет структуру данных пользователя.
 type User struct {
-	ID           uuid.UUID `json:"id" db:"id"`                 // ID пользователя
-	FirstName    string    `json:"first_name" db:"first_name"` // Имя
-	LastName     string    `json:"last_name" db:"last_name"`   // Фамилия
-	Email        string    `json:"email" db:"email"`           // Электронная почта
-	PasswordHash string    `json:"-" db:"password_hash"`       // Пароль (не возвращаем в ответах)
-	CreatedAt    time.Time `json:"created_at" db:"created_at"` // Время создания
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"` // Время обновления
+	ID              uuid.UUID  `json:"id" db:"id"`                                         // ID пользователя
+	FirstName       string     `json:"first_name" db:"first_name"`                         // Имя
+	LastName        string     `json:"last_name" db:"last_name"`                           // Фамилия
+	Email           string     `json:"email" db:"email"`                                   // Электронная почта
+	PasswordHash    string     `json:"-" db:"password_hash"`                               // Пароль (не возвращаем в ответах)
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`                         // Время создания
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`                         // Время обновления
+	Status          Status     `json:"status" db:"status"`                                 // Состояние учётной записи
+	DeletedAt       *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`               // Время мягкого удаления
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty" db:"email_verified_at"` // Время подтверждения текущего email; nil - не подтверждён
 }
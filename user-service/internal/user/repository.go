@@ -17,15 +17,36 @@ var (
 	ErrNotFound              = errors.New("user not found")
 	ErrEmailExists           = errors.New("email already exists")
 	ErrCannotUpdateAdminUser = errors.New("can not update admin")
+	ErrStatusConflict        = errors.New("user status does not allow this operation")
 )
 
+// OutboxTable - таблица, в которую PurgeDeleted пишет события user.deleted.
+var OutboxTable = pgx.Identifier{"user_service", "event_outbox"}
+
 // Репозиторий для работы с пользователями.
 type Repository interface {
 	Create(ctx context.Context, user *User) (uuid.UUID, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// GetByIDs возвращает найденных пользователей из ids одним запросом. Порядок не гарантируется.
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error)
 	GetByEmail(ctx context.Context, email string) (*User, error)
+	// Update меняет профиль пользователя. Пароль меняется только через UpdatePassword.
 	Update(ctx context.Context, user *User) error
+	// UpdatePassword записывает новый хеш пароля неудалённого пользователя и тем же запросом отзывает
+	// все его сессии, кроме keepSession. uuid.Nil отзывает все.
+	UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string, keepSession uuid.UUID) error
+	// ReplacePasswordHash заменяет хеш, только если он всё ещё равен oldHash: пересчёт хеша при входе
+	// не должен перезаписать пароль, сменённый параллельно. false, если хеш уже другой.
+	ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error)
+	// Delete помечает пользователя удалённым. Строка остаётся в таблице до очистки PurgeDeleted.
 	Delete(ctx context.Context, id uuid.UUID) error
+	// UpdateStatus переводит неудалённого пользователя из состояния from в to. ErrStatusConflict, если состояние уже другое.
+	UpdateStatus(ctx context.Context, id uuid.UUID, from, to Status) error
+	// Restore снимает отметку об удалении, если пользователь удалён не раньше deletedAfter.
+	Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error
+	// PurgeDeleted окончательно удаляет до limit пользователей, удалённых раньше deletedBefore, и возвращает их ID.
+	// Событие user.deleted для каждого из них записывается в OutboxTable тем же запросом.
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error)
 }
 
 type DB interface {
@@ -93,9 +114,12 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 			email,
 			password_hash,
 			created_at,
-			updated_at
+			updated_at,
+			status,
+			deleted_at,
+			email_verified_at
 		FROM user_service.users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	row := r.db.QueryRow(ctx, query, id)
@@ -108,6 +132,9 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 		&user.PasswordHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Status,
+		&user.DeletedAt,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -122,6 +149,58 @@ func (r *repository) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
 	return &user, nil
 }
 
+func (r *repository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]User, error) {
+	query := `
+		SELECT
+			id,
+			first_name,
+			last_name,
+			email,
+			password_hash,
+			created_at,
+			updated_at,
+			status,
+			deleted_at,
+			email_verified_at
+		FROM user_service.users
+		WHERE id = ANY($1) AND deleted_at IS NULL
+	`
+
+	rows, err := r.db.Query(ctx, query, ids)
+	if err != nil {
+		log.Error().Err(err).Int("ids_count", len(ids)).Msg("Failed to query users by ids")
+		return nil, fmt.Errorf("failed to query users by ids: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]User, 0, len(ids))
+	for rows.Next() {
+		var user User
+		err := rows.Scan(
+			&user.ID,
+			&user.FirstName,
+			&user.LastName,
+			&user.Email,
+			&user.PasswordHash,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.Status,
+			&user.DeletedAt,
+			&user.EmailVerifiedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user by ids: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating users by ids: %w", err)
+	}
+
+	return users, nil
+}
+
 func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
 		SELECT
@@ -131,9 +210,12 @@ func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error
 			email,
 			password_hash,
 			created_at,
-			updated_at
+			updated_at,
+			status,
+			deleted_at,
+			email_verified_at
 		FROM user_service.users
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	row := r.db.QueryRow(ctx, query, email)
@@ -146,6 +228,9 @@ func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error
 		&user.PasswordHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.Status,
+		&user.DeletedAt,
+		&user.EmailVerifiedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -161,23 +246,23 @@ func (r *repository) GetByEmail(ctx context.Context, email string) (*User, error
 }
 
 func (r *repository) Update(ctx context.Context, user *User) error {
+	// Новый email ещё не подтверждён, поэтому при смене адреса отметка о подтверждении снимается
 	query := `
 		UPDATE user_service.users
 		SET 
 			first_name = $1,
 			last_name = $2,
+			email_verified_at = CASE WHEN email = $3 THEN email_verified_at END,
 			email = $3,
-			password_hash = $4,
-			updated_at = $5
+			updated_at = $4
 		WHERE
-			id = $6
+			id = $5 AND deleted_at IS NULL
 	`
 
 	tag, err := r.db.Exec(ctx, query,
 		user.FirstName,
 		user.LastName,
 		user.Email,
-		user.PasswordHash,
 		time.Now(),
 		user.ID,
 	)
@@ -201,12 +286,61 @@ func (r *repository) Update(ctx context.Context, user *User) error {
 	return nil
 }
 
+func (r *repository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string, keepSession uuid.UUID) error {
+	// Один запрос: пароль не может смениться без отзыва сессий, открытых со старым паролем
+	query := `
+		WITH updated AS (
+			UPDATE user_service.users
+			SET password_hash = $1, updated_at = NOW()
+			WHERE id = $2 AND deleted_at IS NULL
+			RETURNING id
+		), revoked AS (
+			UPDATE user_service.sessions s
+			SET revoked_at = NOW()
+			FROM updated
+			WHERE s.user_id = updated.id AND s.revoked_at IS NULL AND s.id <> $3
+		)
+		SELECT COUNT(*) FROM updated
+	`
+
+	var updated int
+	err := r.db.QueryRow(ctx, query, passwordHash, id, keepSession).Scan(&updated)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", id).Msg("Failed to update user password")
+		return fmt.Errorf("failed to update password of user %s: %w", id, err)
+	}
+
+	if updated == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error) {
+	query := `
+		UPDATE user_service.users
+		SET password_hash = $3, updated_at = NOW()
+		WHERE id = $1 AND password_hash = $2 AND deleted_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, oldHash, newHash)
+	if err != nil {
+		log.Error().Err(err).Stringer("user_id", id).Msg("Failed to replace user password hash")
+		return false, fmt.Errorf("failed to replace password hash of user %s: %w", id, err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
 func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `
-		DELETE FROM user_service.users WHERE id = $1
+		UPDATE user_service.users
+		SET status = $2, deleted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
-	tag, err := r.db.Exec(ctx, query, id)
+	tag, err := r.db.Exec(ctx, query, id, StatusDeleted)
 	if err != nil {
 		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to delete user")
 
@@ -219,3 +353,105 @@ func (r *repository) Delete(ctx context.Context, id uuid.UUID) error {
 
 	return nil
 }
+
+func (r *repository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to Status) error {
+	query := `
+		UPDATE user_service.users
+		SET status = $3, updated_at = NOW()
+		WHERE id = $1 AND status = $2 AND deleted_at IS NULL
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, from, to)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to update user status")
+
+		return fmt.Errorf("failed to update status of user %s: %w", id.String(), err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		// Отличаем отсутствующего пользователя от пользователя в другом состоянии
+		var exists bool
+		err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM user_service.users WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check user %s existence: %w", id.String(), err)
+		}
+		if !exists {
+			return ErrNotFound
+		}
+		return ErrStatusConflict
+	}
+
+	return nil
+}
+
+func (r *repository) Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error {
+	query := `
+		UPDATE user_service.users
+		SET status = $3, deleted_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL AND deleted_at > $2
+	`
+
+	tag, err := r.db.Exec(ctx, query, id, deletedAfter, StatusActive)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			// Email удалённого пользователя уже занял другой пользователь
+			if pgErr.Code == pgerrcode.UniqueViolation {
+				return ErrEmailExists
+			}
+		}
+
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to restore user")
+
+		return fmt.Errorf("failed to restore user %s: %w", id.String(), err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *repository) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	// Поля data совпадают с events.UserDeletedData
+	query := `
+		WITH purged AS (
+			DELETE FROM user_service.users
+			WHERE id IN (
+				SELECT id FROM user_service.users
+				WHERE deleted_at IS NOT NULL AND deleted_at < $1
+				ORDER BY deleted_at
+				LIMIT $2
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id
+		), queued AS (
+			INSERT INTO user_service.event_outbox (event_type, subject, data)
+			SELECT 'user.deleted', id::text, json_build_object('id', id) FROM purged
+		)
+		SELECT id FROM purged
+	`
+
+	rows, err := r.db.Query(ctx, query, deletedBefore, limit)
+	if err != nil {
+		log.Error().Err(err).Time("deleted_before", deletedBefore).Msg("Failed to purge deleted users")
+		return nil, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0, limit)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan purged user id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed iterating purged users: %w", err)
+	}
+
+	return ids, nil
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
 )
 
@@ -99,7 +100,7 @@ func TestMain(m *testing.M) {
 
 func truncateUsersTable(tb testing.TB, pool *pgxpool.Pool) {
 	tb.Helper()
-	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE user_service.users RESTART IDENTITY CASCADE")
+	_, err := pool.Exec(context.Background(), "TRUNCATE TABLE user_service.users, user_service.event_outbox RESTART IDENTITY CASCADE")
 	require.NoError(tb, err, "failed to truncate users table")
 }
 
@@ -205,6 +206,36 @@ func TestUserRepository_GetById_NotFound(t *testing.T) {
 	require.Nil(t, foundUser)
 }
 
+func TestUserRepository_GetByIDs(t *testing.T) {
+	repo := user.NewRepository(testDB)
+
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	firstID := uuid.Must(uuid.NewV4())
+	secondID := uuid.Must(uuid.NewV4())
+	for i, id := range []uuid.UUID{firstID, secondID} {
+		_, err := repo.Create(context.Background(), &user.User{
+			ID:           id,
+			FirstName:    "Test",
+			LastName:     "User",
+			Email:        fmt.Sprintf("batch%d@example.com", i),
+			PasswordHash: "hashed_password",
+		})
+		require.NoError(t, err)
+	}
+
+	missingID := uuid.Must(uuid.NewV4())
+	users, err := repo.GetByIDs(context.Background(), []uuid.UUID{firstID, missingID, secondID})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	foundIDs := []uuid.UUID{users[0].ID, users[1].ID}
+	require.ElementsMatch(t, []uuid.UUID{firstID, secondID}, foundIDs)
+	require.False(t, users[0].CreatedAt.IsZero())
+}
+
 func TestUserRepository_GetByEmail_Success(t *testing.T) {
 	repo := user.NewRepository(testDB)
 
@@ -302,6 +333,40 @@ func TestUserRepository_Update_Success(t *testing.T) {
 	require.True(t, foundUser.UpdatedAt.After(foundUser.CreatedAt), "UpdatedAt should be after CreatedAt")
 }
 
+func TestUserRepository_Update_EmailChangeResetsVerification(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	account := user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.verified@example.com",
+		PasswordHash: "hashed_password",
+	}
+	_, err := repo.Create(ctx, &account)
+	require.NoError(t, err)
+	_, err = testDB.Exec(ctx, "UPDATE user_service.users SET email_verified_at = NOW() WHERE id = $1", userID)
+	require.NoError(t, err)
+
+	// Смена имени не трогает подтверждение
+	account.FirstName = "Renamed"
+	require.NoError(t, repo.Update(ctx, &account))
+	found, err := repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, found.EmailVerifiedAt)
+
+	account.Email = "test.unverified@example.com"
+	require.NoError(t, repo.Update(ctx, &account))
+	found, err = repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Nil(t, found.EmailVerifiedAt)
+}
+
 func TestUserRepository_Update_NotFound(t *testing.T) {
 	repo := user.NewRepository(testDB)
 
@@ -361,6 +426,93 @@ func TestUserRepository_Update_EmailExists(t *testing.T) {
 	require.ErrorIs(t, err, user.ErrEmailExists)
 }
 
+func TestUserRepository_UpdatePassword(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	account := user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.password@example.com",
+		PasswordHash: "hashed_password",
+	}
+	_, err := repo.Create(ctx, &account)
+	require.NoError(t, err)
+
+	insertSession := func() uuid.UUID {
+		id := uuid.Must(uuid.NewV4())
+		_, err := testDB.Exec(ctx, `
+			INSERT INTO user_service.sessions (id, user_id, token_hash, expires_at)
+			VALUES ($1, $2, $3, NOW() + INTERVAL '1 hour')
+		`, id, userID, id.Bytes())
+		require.NoError(t, err)
+		return id
+	}
+	currentSession := insertSession()
+	otherSession := insertSession()
+
+	require.NoError(t, repo.UpdatePassword(ctx, userID, "new_hashed_password", currentSession))
+	found, err := repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "new_hashed_password", found.PasswordHash)
+
+	// Остальные сессии отозваны тем же запросом, текущая осталась открытой
+	revoked := func(id uuid.UUID) bool {
+		var revokedAt *time.Time
+		require.NoError(t, testDB.QueryRow(ctx, "SELECT revoked_at FROM user_service.sessions WHERE id = $1", id).Scan(&revokedAt))
+		return revokedAt != nil
+	}
+	require.False(t, revoked(currentSession))
+	require.True(t, revoked(otherSession))
+
+	// Обновление профиля не затрагивает пароль
+	account.PasswordHash = ""
+	account.FirstName = "Renamed"
+	require.NoError(t, repo.Update(ctx, &account))
+	found, err = repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "new_hashed_password", found.PasswordHash)
+
+	err = repo.UpdatePassword(ctx, uuid.Must(uuid.NewV4()), "new_hashed_password", uuid.Nil)
+	require.ErrorIs(t, err, user.ErrNotFound)
+}
+
+func TestUserRepository_ReplacePasswordHash(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	_, err := repo.Create(ctx, &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.rehash@example.com",
+		PasswordHash: "legacy_hash",
+	})
+	require.NoError(t, err)
+
+	replaced, err := repo.ReplacePasswordHash(ctx, userID, "legacy_hash", "upgraded_hash")
+	require.NoError(t, err)
+	require.True(t, replaced)
+
+	// Хеш уже другой (например, пароль сменили параллельно) - замена не выполняется
+	replaced, err = repo.ReplacePasswordHash(ctx, userID, "legacy_hash", "stale_hash")
+	require.NoError(t, err)
+	require.False(t, replaced)
+
+	found, err := repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, "upgraded_hash", found.PasswordHash)
+}
+
 func TestUserRepository_Delete_Success(t *testing.T) {
 	repo := user.NewRepository(testDB)
 
@@ -402,3 +554,121 @@ func TestUserRepository_Delete_NotFound(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorIs(t, err, user.ErrNotFound)
 }
+
+func TestUserRepository_SoftDelete_RestoreAndPurge(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	deletedUser := user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.softdelete@example.com",
+		PasswordHash: "hashed_password",
+	}
+	_, err := repo.Create(ctx, &deletedUser)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, userID))
+	require.ErrorIs(t, repo.Delete(ctx, userID), user.ErrNotFound, "already deleted user must look missing")
+
+	_, err = repo.GetByEmail(ctx, deletedUser.Email)
+	require.ErrorIs(t, err, user.ErrNotFound)
+	found, err := repo.GetByIDs(ctx, []uuid.UUID{userID})
+	require.NoError(t, err)
+	require.Empty(t, found)
+
+	// Срок хранения истёк: восстановить нельзя
+	require.ErrorIs(t, repo.Restore(ctx, userID, time.Now().Add(time.Minute)), user.ErrNotFound)
+
+	require.NoError(t, repo.Restore(ctx, userID, time.Now().Add(-time.Hour)))
+	restored, err := repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, user.StatusActive, restored.Status)
+	require.Nil(t, restored.DeletedAt)
+
+	require.NoError(t, repo.Delete(ctx, userID))
+	purged, err := repo.PurgeDeleted(ctx, time.Now().Add(-time.Hour), 10)
+	require.NoError(t, err)
+	require.Empty(t, purged, "recently deleted user must be kept")
+
+	purged, err = repo.PurgeDeleted(ctx, time.Now().Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{userID}, purged)
+	require.ErrorIs(t, repo.Restore(ctx, userID, time.Now().Add(-time.Hour)), user.ErrNotFound)
+
+	// user.deleted записан в outbox вместе с удалением
+	broker := events.NewMemoryBroker()
+	outbox := events.NewPostgresOutbox(testDB, user.OutboxTable, events.SourceUserService)
+	published, err := outbox.PublishPending(ctx, 10, broker.Publish)
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+
+	deleted := broker.PublishedOfType(events.TypeUserDeleted)
+	require.Len(t, deleted, 1)
+	require.Equal(t, userID.String(), deleted[0].Subject)
+	var data events.UserDeletedData
+	require.NoError(t, deleted[0].DecodeData(&data))
+	require.Equal(t, userID, data.ID)
+}
+
+func TestUserRepository_Restore_EmailTaken(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	deletedUser := user.User{
+		ID:           uuid.Must(uuid.NewV4()),
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.reused@example.com",
+		PasswordHash: "hashed_password",
+	}
+	_, err := repo.Create(ctx, &deletedUser)
+	require.NoError(t, err)
+	require.NoError(t, repo.Delete(ctx, deletedUser.ID))
+
+	// Email удалённого пользователя свободен для регистрации
+	newUser := deletedUser
+	newUser.ID = uuid.Must(uuid.NewV4())
+	_, err = repo.Create(ctx, &newUser)
+	require.NoError(t, err)
+
+	require.ErrorIs(t, repo.Restore(ctx, deletedUser.ID, time.Now().Add(-time.Hour)), user.ErrEmailExists)
+}
+
+func TestUserRepository_UpdateStatus(t *testing.T) {
+	repo := user.NewRepository(testDB)
+	ctx := context.Background()
+
+	t.Cleanup(func() {
+		truncateUsersTable(t, testDB)
+	})
+
+	userID := uuid.Must(uuid.NewV4())
+	_, err := repo.Create(ctx, &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "test.status@example.com",
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.UpdateStatus(ctx, userID, user.StatusActive, user.StatusDeactivated))
+	require.ErrorIs(t, repo.UpdateStatus(ctx, userID, user.StatusActive, user.StatusDeactivated), user.ErrStatusConflict)
+
+	found, err := repo.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.Equal(t, user.StatusDeactivated, found.Status)
+
+	require.ErrorIs(t, repo.UpdateStatus(ctx, uuid.Must(uuid.NewV4()), user.StatusActive, user.StatusDeactivated), user.ErrNotFound)
+}
@@ -4,38 +4,123 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gofrs/uuid"
 	"github.com/rs/zerolog/log"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
 )
 
+// MaxBatchSize - максимальное число ID в одном пакетном запросе пользователей.
+const MaxBatchSize = 500
+
 type Service interface {
 	CreateUser(ctx context.Context, user *User) (*User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// GetUsersByIDs возвращает найденных пользователей и ID, которых нет, в порядке ids. Повторы в ids учитываются один раз.
+	GetUsersByIDs(ctx context.Context, ids []uuid.UUID) (found []User, missing []uuid.UUID, err error)
 	GetUserByEmail(ctx context.Context, email string) (*User, error)
 	UpdateUser(ctx context.Context, user *User) error
+	// ChangePassword задаёт новый пароль, если currentPassword совпадает с текущим, и отзывает все сессии
+	// пользователя, кроме currentSession. Неверный текущий пароль учитывается защитой от подбора вместе с ip клиента.
+	ChangePassword(ctx context.Context, id, currentSession uuid.UUID, currentPassword, newPassword, ip string) error
+	// DeleteUser помечает пользователя удалённым. До окончания срока хранения его можно восстановить через RestoreUser.
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeactivateUser(ctx context.Context, id uuid.UUID) error
+	ReactivateUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) error
+	// UnlockUser снимает блокировку входа после неудачных попыток ввода пароля.
+	UnlockUser(ctx context.Context, id uuid.UUID) error
+	// PurgeDeletedUsers окончательно удаляет до limit пользователей с истёкшим сроком хранения и возвращает их число.
+	PurgeDeletedUsers(ctx context.Context, limit int) (int, error)
+}
+
+var (
+	ErrUserHasActiveOrders     = errors.New("user has active orders")
+	ErrActiveOrdersCheckFailed = errors.New("failed to check user's active orders")
+	ErrWrongPassword           = errors.New("current password is incorrect")
+	ErrSamePassword            = errors.New("new password must differ from the current one")
+)
+
+// ActiveOrdersChecker сообщает, сколько у пользователя незавершённых заказов (от NEW до SHIPPED).
+type ActiveOrdersChecker interface {
+	CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// VerificationSender отправляет новому пользователю письмо со ссылкой подтверждения email.
+type VerificationSender interface {
+	SendVerification(ctx context.Context, user *User) error
+}
+
+// PasswordValidator проверяет новый пароль по политике паролей. personal - email и имена, которые не должны входить в пароль.
+type PasswordValidator interface {
+	Validate(password string, personal ...string) error
+}
+
+// PasswordHasher хеширует пароли и проверяет их по сохранённому хешу.
+// needsRehash - пароль верный, но хеш создан устаревшим алгоритмом или параметрами.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, hash string) (ok, needsRehash bool, err error)
+}
+
+// AttemptGuard ограничивает подбор пароля. account - email, по которому ведётся счётчик учётной записи.
+type AttemptGuard interface {
+	Check(ctx context.Context, account, ip string) error
+	RecordFailure(ctx context.Context, account, ip string) error
+	RecordSuccess(ctx context.Context, account string) error
+	Unlock(ctx context.Context, account string) error
 }
 
 type service struct {
-	repo Repository
+	repo      Repository
+	publisher events.EventPublisher // Доменные события для других сервисов
+	orders    ActiveOrdersChecker   // Проверка перед удалением пользователя
+	verifier  VerificationSender    // Письмо подтверждения после регистрации
+	passwords PasswordValidator     // Политика паролей при регистрации и смене пароля
+	hasher    PasswordHasher        // Алгоритм и параметры хеширования паролей
+	guard     AttemptGuard          // Защита проверки текущего пароля от подбора
+	retention time.Duration         // Сколько удалённый пользователь хранится и может быть восстановлен
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, publisher events.EventPublisher, orders ActiveOrdersChecker, verifier VerificationSender, passwords PasswordValidator, hasher PasswordHasher, guard AttemptGuard, retention time.Duration) Service {
+	return &service{repo: repo, publisher: publisher, orders: orders, verifier: verifier, passwords: passwords, hasher: hasher, guard: guard, retention: retention}
+}
+
+// publish отправляет событие в брокер. Изменение уже сохранено, поэтому ошибка публикации только логируется.
+func (s *service) publish(ctx context.Context, eventType string, userID uuid.UUID, data any) {
+	event, err := events.New(events.SourceUserService, eventType, userID.String(), data)
+	if err == nil {
+		err = s.publisher.Publish(ctx, event)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("event_type", eventType).Str("user_id", userID.String()).Msg("Failed to publish user event")
+	}
+}
+
+func userEventData(user *User) events.UserData {
+	return events.UserData{
+		ID:        user.ID,
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+	}
 }
 
 func (s *service) CreateUser(ctx context.Context, user *User) (*User, error) {
 	if user.PasswordHash == "" {
 		return nil, errors.New("password cannot be empty")
 	}
-	hashPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(user.PasswordHash), bcrypt.DefaultCost)
+	// До хеширования в PasswordHash лежит пароль в открытом виде
+	if err := s.passwords.Validate(user.PasswordHash, user.Email, user.FirstName, user.LastName); err != nil {
+		return nil, err
+	}
+	passwordHash, err := s.hasher.Hash(user.PasswordHash)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to generate hash password")
 		return nil, fmt.Errorf("internal error hashing password: %w", err)
 	}
-	user.PasswordHash = string(hashPasswordBytes)
+	user.PasswordHash = passwordHash
 
 	createdID, err := s.repo.Create(ctx, user)
 	if err != nil {
@@ -47,6 +132,13 @@ func (s *service) CreateUser(ctx context.Context, user *User) (*User, error) {
 	}
 
 	user.ID = createdID
+	user.Status = StatusActive
+	s.publish(ctx, events.TypeUserCreated, user.ID, userEventData(user))
+
+	// Пользователь уже создан; если письмо не ушло, его можно запросить повторно
+	if err := s.verifier.SendVerification(ctx, user); err != nil {
+		log.Error().Err(err).Str("user_id", user.ID.String()).Msg("Failed to send verification email")
+	}
 
 	return user, nil
 }
@@ -65,6 +157,44 @@ func (s *service) GetUserByID(ctx context.Context, id uuid.UUID) (*User, error)
 	return user, nil
 }
 
+func (s *service) GetUsersByIDs(ctx context.Context, ids []uuid.UUID) ([]User, []uuid.UUID, error) {
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	if len(unique) == 0 {
+		return []User{}, []uuid.UUID{}, nil
+	}
+
+	users, err := s.repo.GetByIDs(ctx, unique)
+	if err != nil {
+		log.Error().Err(err).Int("ids_count", len(unique)).Msg("Failed to get users by ids in repository")
+		return nil, nil, fmt.Errorf("failed to get users by ids: %w", err)
+	}
+
+	byID := make(map[uuid.UUID]User, len(users))
+	for _, u := range users {
+		byID[u.ID] = u
+	}
+
+	found := make([]User, 0, len(users))
+	missing := make([]uuid.UUID, 0)
+	for _, id := range unique {
+		if u, ok := byID[id]; ok {
+			found = append(found, u)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return found, missing, nil
+}
+
 func (s *service) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
@@ -80,16 +210,6 @@ func (s *service) GetUserByEmail(ctx context.Context, email string) (*User, erro
 }
 
 func (s *service) UpdateUser(ctx context.Context, user *User) error {
-	if user.PasswordHash != "" {
-		newPassword, err := bcrypt.GenerateFromPassword([]byte(user.PasswordHash), bcrypt.DefaultCost)
-		if err != nil {
-			log.Error().Err(err).Msg("Failed to generate hash password")
-			return fmt.Errorf("failed to generate hash password: %w", err)
-		}
-
-		user.PasswordHash = string(newPassword)
-	}
-
 	err := s.repo.Update(ctx, user)
 	if err != nil {
 		if errors.Is(err, ErrEmailExists) {
@@ -99,12 +219,78 @@ func (s *service) UpdateUser(ctx context.Context, user *User) error {
 		log.Error().Err(err).Type("update_user_type", &user).Msg("Failed to update user")
 		return fmt.Errorf("failed to update user by id '%s': %w", user.ID.String(), err)
 	}
+	s.publish(ctx, events.TypeUserUpdated, user.ID, userEventData(user))
 
 	return nil
 }
 
+func (s *service) ChangePassword(ctx context.Context, id, currentSession uuid.UUID, currentPassword, newPassword, ip string) error {
+	if newPassword == "" {
+		return errors.New("password cannot be empty")
+	}
+
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.guard.Check(ctx, user.Email, ip); err != nil {
+		return err
+	}
+
+	ok, _, err := s.hasher.Verify(currentPassword, user.PasswordHash)
+	if err != nil {
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to verify current password")
+		return fmt.Errorf("failed to verify password of user '%s': %w", id, err)
+	}
+	if !ok {
+		log.Info().Str("user_id", id.String()).Msg("Password change rejected: wrong current password")
+		if err := s.guard.RecordFailure(ctx, user.Email, ip); err != nil {
+			log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to record failed password attempt")
+		}
+		return ErrWrongPassword
+	}
+	if err := s.guard.RecordSuccess(ctx, user.Email); err != nil {
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to reset failed password attempts")
+	}
+	// Текущий пароль уже проверен, поэтому повтор можно определить простым сравнением
+	if newPassword == currentPassword {
+		return ErrSamePassword
+	}
+	if err := s.passwords.Validate(newPassword, user.Email, user.FirstName, user.LastName); err != nil {
+		return err
+	}
+
+	passwordHash, err := s.hasher.Hash(newPassword)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to generate hash password")
+		return fmt.Errorf("internal error hashing password: %w", err)
+	}
+
+	if err := s.repo.UpdatePassword(ctx, id, passwordHash, currentSession); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return ErrNotFound
+		}
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to update password in repository")
+		return fmt.Errorf("failed to change password of user '%s': %w", id, err)
+	}
+
+	log.Info().Str("user_id", id.String()).Msg("Password changed")
+	return nil
+}
+
 func (s *service) DeleteUser(ctx context.Context, id uuid.UUID) error {
-	err := s.repo.Delete(ctx, id)
+	// Пока заказ не доставлен, его нельзя лишить покупателя. Если order-service недоступен, удаление не выполняется.
+	activeOrders, err := s.orders.CountActiveOrders(ctx, id)
+	if err != nil {
+		log.Error().Err(err).Str("delete_user_id_received", id.String()).Msg("Failed to check active orders before deleting user")
+		return fmt.Errorf("%w: %w", ErrActiveOrdersCheckFailed, err)
+	}
+	if activeOrders > 0 {
+		log.Info().Str("user_id", id.String()).Int("active_orders", activeOrders).Msg("User deletion blocked by active orders")
+		return ErrUserHasActiveOrders
+	}
+
+	err = s.repo.Delete(ctx, id)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			return ErrNotFound
@@ -113,6 +299,69 @@ func (s *service) DeleteUser(ctx context.Context, id uuid.UUID) error {
 		log.Error().Err(err).Str("delete_user_id_received", id.String()).Msg("Failed to delete user")
 		return fmt.Errorf("failed to delete user by id '%s': %w", id, err)
 	}
+	s.publish(ctx, events.TypeUserStatusChanged, id, events.UserStatusChangedData{ID: id, To: string(StatusDeleted)})
+
+	return nil
+}
+
+func (s *service) DeactivateUser(ctx context.Context, id uuid.UUID) error {
+	return s.changeStatus(ctx, id, StatusActive, StatusDeactivated)
+}
+
+func (s *service) ReactivateUser(ctx context.Context, id uuid.UUID) error {
+	return s.changeStatus(ctx, id, StatusDeactivated, StatusActive)
+}
+
+func (s *service) changeStatus(ctx context.Context, id uuid.UUID, from, to Status) error {
+	err := s.repo.UpdateStatus(ctx, id, from, to)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrStatusConflict) {
+			return err
+		}
+
+		log.Error().Err(err).Str("user_id", id.String()).Str("status", string(to)).Msg("Failed to change user status")
+		return fmt.Errorf("failed to change status of user '%s' to %s: %w", id, to, err)
+	}
+	s.publish(ctx, events.TypeUserStatusChanged, id, events.UserStatusChangedData{ID: id, From: string(from), To: string(to)})
+
+	return nil
+}
+
+func (s *service) UnlockUser(ctx context.Context, id uuid.UUID) error {
+	user, err := s.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.guard.Unlock(ctx, user.Email); err != nil {
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to unlock user login")
+		return fmt.Errorf("failed to unlock user '%s': %w", id, err)
+	}
 
+	log.Info().Str("user_id", id.String()).Msg("User login unlocked")
 	return nil
 }
+
+func (s *service) RestoreUser(ctx context.Context, id uuid.UUID) error {
+	err := s.repo.Restore(ctx, id, time.Now().Add(-s.retention))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrEmailExists) {
+			return err
+		}
+
+		log.Error().Err(err).Str("user_id", id.String()).Msg("Failed to restore user")
+		return fmt.Errorf("failed to restore user '%s': %w", id, err)
+	}
+	s.publish(ctx, events.TypeUserStatusChanged, id, events.UserStatusChangedData{ID: id, From: string(StatusDeleted), To: string(StatusActive)})
+
+	return nil
+}
+
+func (s *service) PurgeDeletedUsers(ctx context.Context, limit int) (int, error) {
+	ids, err := s.repo.PurgeDeleted(ctx, time.Now().Add(-s.retention), limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+
+	// user.deleted уже записан в outbox тем же запросом, его отправит events.Relay
+	return len(ids), nil
+}
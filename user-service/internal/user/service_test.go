@@ -2,6 +2,8 @@ package user_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -9,6 +11,8 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/events"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/passwords"
 	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -31,6 +35,14 @@ func (m *MockUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*user.U
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]user.User, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]user.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*user.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -44,15 +56,104 @@ func (m *MockUserRepository) Update(ctx context.Context, u *user.User) error {
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, passwordHash string, keepSession uuid.UUID) error {
+	args := m.Called(ctx, id, passwordHash, keepSession)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ReplacePasswordHash(ctx context.Context, id uuid.UUID, oldHash, newHash string) (bool, error) {
+	args := m.Called(ctx, id, oldHash, newHash)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateStatus(ctx context.Context, id uuid.UUID, from, to user.Status) error {
+	args := m.Called(ctx, id, from, to)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Restore(ctx context.Context, id uuid.UUID, deletedAfter time.Time) error {
+	args := m.Called(ctx, id, deletedAfter)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time, limit int) ([]uuid.UUID, error) {
+	args := m.Called(ctx, deletedBefore, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uuid.UUID), args.Error(1)
+}
+
+// testRetention - срок хранения удалённых пользователей в тестах сервиса.
+const testRetention = 30 * 24 * time.Hour
+
+type MockAttemptGuard struct {
+	mock.Mock
+}
+
+func (m *MockAttemptGuard) Check(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) RecordFailure(ctx context.Context, account, ip string) error {
+	args := m.Called(ctx, account, ip)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) RecordSuccess(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+func (m *MockAttemptGuard) Unlock(ctx context.Context, account string) error {
+	args := m.Called(ctx, account)
+	return args.Error(0)
+}
+
+// allowAllGuard возвращает защиту от подбора, которая пропускает любые попытки.
+func allowAllGuard() *MockAttemptGuard {
+	guard := new(MockAttemptGuard)
+	guard.On("Check", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	guard.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	guard.On("RecordSuccess", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return guard
+}
+
+// testPasswords - политика паролей в тестах сервиса.
+var testPasswords = passwords.NewValidator(passwords.Policy{MinLength: 8, MaxLength: 72, DisallowPersonalInfo: true}, nil)
+
+// testHasher - bcrypt с минимальной стоимостью, чтобы тесты не тратили время на хеширование.
+var testHasher, _ = passwords.NewHasher(passwords.HashParams{Algorithm: passwords.AlgorithmBcrypt, BcryptCost: bcrypt.MinCost})
+
+type MockActiveOrdersChecker struct {
+	mock.Mock
+}
+
+func (m *MockActiveOrdersChecker) CountActiveOrders(ctx context.Context, userID uuid.UUID) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+type MockVerificationSender struct {
+	mock.Mock
+}
+
+func (m *MockVerificationSender) SendVerification(ctx context.Context, u *user.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
 func TestUserService_CreateUser_Success(t *testing.T) {
 	// Arrange
-	mockRepo := new(MockUserRepository)      // Создаем экземпляр мока
-	userService := user.NewService(mockRepo) // Внедряем мок в сервис
+	mockRepo := new(MockUserRepository) // Создаем экземпляр мока
+	mockVerifier := new(MockVerificationSender)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), mockVerifier, testPasswords, testHasher, allowAllGuard(), testRetention) // Внедряем мок в сервис
 
 	testUser := &user.User{
 		FirstName:    "Test",
@@ -68,6 +169,9 @@ func TestUserService_CreateUser_Success(t *testing.T) {
 	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).
 		Return(expectedID, nil).
 		Once() // Ожидаем, что вызов будет только один раз
+	mockVerifier.On("SendVerification", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
+		return u.ID == expectedID && u.EmailVerifiedAt == nil
+	})).Return(nil).Once()
 
 	// Act
 	createdUser, err := userService.CreateUser(context.Background(), testUser)
@@ -84,11 +188,32 @@ func TestUserService_CreateUser_Success(t *testing.T) {
 
 	// Проверяем, что все ожидания мока были выполнены
 	mockRepo.AssertExpectations(t)
+	mockVerifier.AssertExpectations(t)
+}
+
+func TestUserService_CreateUser_VerificationEmailFailureDoesNotFailRegistration(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockVerifier := new(MockVerificationSender)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), mockVerifier, testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	expectedID := uuid.Must(uuid.NewV4())
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).Return(expectedID, nil).Once()
+	mockVerifier.On("SendVerification", mock.Anything, mock.AnythingOfType("*user.User")).Return(errors.New("smtp is down")).Once()
+
+	createdUser, err := userService.CreateUser(context.Background(), &user.User{
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "mail-down@example.com",
+		PasswordHash: "somepassword",
+	})
+	require.NoError(t, err)
+	require.Equal(t, expectedID, createdUser.ID)
+	mockVerifier.AssertExpectations(t)
 }
 
 func TestUserService_CreateUser_EmailExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	testUser := user.User{
 		FirstName:    "Test",
@@ -108,9 +233,27 @@ func TestUserService_CreateUser_EmailExists(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUserService_CreateUser_PolicyViolation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockVerifier := new(MockVerificationSender)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), mockVerifier, testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	createdUser, err := userService.CreateUser(context.Background(), &user.User{
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "policy@example.com",
+		PasswordHash: "short",
+	})
+	var policyErr *passwords.PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	require.Nil(t, createdUser)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	mockVerifier.AssertNotCalled(t, "SendVerification", mock.Anything, mock.Anything)
+}
+
 func TestUserService_GetUserByID_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
@@ -139,7 +282,7 @@ func TestUserService_GetUserByID_Success(t *testing.T) {
 
 func TestUserService_GetUserByID_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
@@ -156,7 +299,7 @@ func TestUserService_GetUserByID_NotFound(t *testing.T) {
 
 func TestUserService_GetUserByEmail_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 	userEmail := "getbyid@example.com"
@@ -186,7 +329,7 @@ func TestUserService_GetUserByEmail_Success(t *testing.T) {
 
 func TestUserService_GetUserByEmail_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userEmail := "getbyid@example.com"
 
@@ -203,7 +346,7 @@ func TestUserService_GetUserByEmail_NotFound(t *testing.T) {
 
 func TestUserService_UpdateUser_Success_NoPasswordChange(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
@@ -223,38 +366,160 @@ func TestUserService_UpdateUser_Success_NoPasswordChange(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-func TestUserService_UpdateUser_Success_WithPasswordChange(t *testing.T) {
+func TestUserService_ChangePassword_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
+	currentSession := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
 
-	rawPassword := "newpassword123"
-	userToUpdate := user.User{
-		ID:           userID,
-		FirstName:    "Test_Updated",
-		LastName:     "User_Updated",
-		Email:        "getbyid@example.com",
-		PasswordHash: rawPassword,
-	}
+	// Сессия, из которой меняют пароль, передаётся в репозиторий, чтобы её не отозвали вместе с остальными
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, PasswordHash: string(currentHash)}, nil).Once()
+	mockRepo.On("UpdatePassword", mock.Anything, userID, mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+	}), currentSession).
+		Return(nil).
+		Once()
 
-	mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *user.User) bool {
-		return u.ID == userToUpdate.ID &&
-			u.PasswordHash != rawPassword &&
-			u.PasswordHash != "" &&
-			bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(rawPassword)) == nil
-	})).
+	err = userService.ChangePassword(context.Background(), userID, currentSession, "old-password", "new-password", "10.0.0.1")
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_ChangePassword_UsesCurrentHashAlgorithm(t *testing.T) {
+	argon2Hasher, err := passwords.NewHasher(passwords.HashParams{
+		Algorithm: passwords.AlgorithmArgon2id,
+		Argon2:    passwords.Argon2Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	require.NoError(t, err)
+
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, argon2Hasher, allowAllGuard(), testRetention)
+
+	// Старый пароль сохранён в bcrypt, новый записывается в argon2id
+	userID := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, PasswordHash: string(currentHash)}, nil).Once()
+	mockRepo.On("UpdatePassword", mock.Anything, userID, mock.MatchedBy(func(hash string) bool {
+		ok, needsRehash, err := argon2Hasher.Verify("new-password", hash)
+		return strings.HasPrefix(hash, "$argon2id$") && ok && !needsRehash && err == nil
+	}), uuid.Nil).
 		Return(nil).
 		Once()
 
-	err := userService.UpdateUser(context.Background(), &userToUpdate)
+	err = userService.ChangePassword(context.Background(), userID, uuid.Nil, "old-password", "new-password", "10.0.0.1")
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestUserService_ChangePassword_WrongCurrentPassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockGuard := new(MockAttemptGuard)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, mockGuard, testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, Email: "ivan@example.com", PasswordHash: string(currentHash)}, nil).Once()
+	mockGuard.On("Check", mock.Anything, "ivan@example.com", "10.0.0.1").Return(nil).Once()
+	mockGuard.On("RecordFailure", mock.Anything, "ivan@example.com", "10.0.0.1").Return(nil).Once()
+
+	err = userService.ChangePassword(context.Background(), userID, uuid.Nil, "guessed-password", "new-password", "10.0.0.1")
+	require.ErrorIs(t, err, user.ErrWrongPassword)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockGuard.AssertExpectations(t)
+}
+
+func TestUserService_ChangePassword_Locked(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockGuard := new(MockAttemptGuard)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, mockGuard, testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	locked := errors.New("locked")
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, Email: "ivan@example.com", PasswordHash: string(currentHash)}, nil).Once()
+	mockGuard.On("Check", mock.Anything, "ivan@example.com", "10.0.0.1").Return(locked).Once()
+
+	// Даже верный пароль не проверяется, пока попытки заблокированы
+	err = userService.ChangePassword(context.Background(), userID, uuid.Nil, "old-password", "new-password", "10.0.0.1")
+	require.ErrorIs(t, err, locked)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockGuard.AssertNotCalled(t, "RecordSuccess", mock.Anything, mock.Anything)
+}
+
+func TestUserService_UnlockUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockGuard := new(MockAttemptGuard)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, mockGuard, testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, Email: "ivan@example.com"}, nil).Once()
+	mockGuard.On("Unlock", mock.Anything, "ivan@example.com").Return(nil).Once()
+	require.NoError(t, userService.UnlockUser(context.Background(), userID))
+
+	missingID := uuid.Must(uuid.NewV4())
+	mockRepo.On("GetByID", mock.Anything, missingID).Return(nil, user.ErrNotFound).Once()
+	require.ErrorIs(t, userService.UnlockUser(context.Background(), missingID), user.ErrNotFound)
+
+	mockGuard.AssertExpectations(t)
+}
+
+func TestUserService_ChangePassword_SamePassword(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mockRepo.On("GetByID", mock.Anything, userID).Return(&user.User{ID: userID, PasswordHash: string(currentHash)}, nil).Once()
+
+	err = userService.ChangePassword(context.Background(), userID, uuid.Nil, "old-password", "old-password", "10.0.0.1")
+	require.ErrorIs(t, err, user.ErrSamePassword)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_ChangePassword_PolicyViolation(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	currentHash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mockRepo.On("GetByID", mock.Anything, userID).
+		Return(&user.User{ID: userID, Email: "ivan.petrov@example.com", PasswordHash: string(currentHash)}, nil).
+		Once()
+
+	err = userService.ChangePassword(context.Background(), userID, uuid.Nil, "old-password", "ivan.petrov-2025", "10.0.0.1")
+	var policyErr *passwords.PolicyError
+	require.ErrorAs(t, err, &policyErr)
+	require.Equal(t, passwords.RulePersonalInfo, policyErr.Violations[0].Rule)
+	mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUserService_ChangePassword_UserNotFound(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("GetByID", mock.Anything, userID).Return(nil, user.ErrNotFound).Once()
+
+	err := userService.ChangePassword(context.Background(), userID, uuid.Nil, "old-password", "new-password", "10.0.0.1")
+	require.ErrorIs(t, err, user.ErrNotFound)
+}
+
 func TestUserService_UpdateUser_EmailExists(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
@@ -278,7 +543,7 @@ func TestUserService_UpdateUser_EmailExists(t *testing.T) {
 
 func TestUserService_UpdateUser_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
@@ -302,10 +567,12 @@ func TestUserService_UpdateUser_NotFound(t *testing.T) {
 
 func TestUserService_DeleteUser_Success(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	mockOrders := new(MockActiveOrdersChecker)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), mockOrders, new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
+	mockOrders.On("CountActiveOrders", mock.Anything, userID).Return(0, nil).Once()
 	mockRepo.On("Delete", mock.Anything, userID).
 		Return(nil).
 		Once()
@@ -313,14 +580,151 @@ func TestUserService_DeleteUser_Success(t *testing.T) {
 	err := userService.DeleteUser(context.Background(), userID)
 	require.NoError(t, err)
 	mockRepo.AssertExpectations(t)
+	mockOrders.AssertExpectations(t)
+}
+
+func TestUserService_DeleteUser_HasActiveOrders(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrders := new(MockActiveOrdersChecker)
+	broker := events.NewMemoryBroker()
+	userService := user.NewService(mockRepo, broker, mockOrders, new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockOrders.On("CountActiveOrders", mock.Anything, userID).Return(2, nil).Once()
+
+	err := userService.DeleteUser(context.Background(), userID)
+	require.ErrorIs(t, err, user.ErrUserHasActiveOrders)
+	require.Empty(t, broker.Published())
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUserService_DeleteUser_ActiveOrdersCheckFailed(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrders := new(MockActiveOrdersChecker)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), mockOrders, new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockOrders.On("CountActiveOrders", mock.Anything, userID).Return(0, errors.New("connection refused")).Once()
+
+	// Без ответа order-service удаление не выполняется
+	err := userService.DeleteUser(context.Background(), userID)
+	require.ErrorIs(t, err, user.ErrActiveOrdersCheckFailed)
+	mockRepo.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+}
+
+func TestUserService_PublishesEvents(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	mockOrders := new(MockActiveOrdersChecker)
+	broker := events.NewMemoryBroker()
+	mockVerifier := new(MockVerificationSender)
+	userService := user.NewService(mockRepo, broker, mockOrders, mockVerifier, testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).Return(userID, nil).Once()
+	mockVerifier.On("SendVerification", mock.Anything, mock.AnythingOfType("*user.User")).Return(nil).Once()
+	mockOrders.On("CountActiveOrders", mock.Anything, userID).Return(0, nil).Once()
+	mockRepo.On("Delete", mock.Anything, userID).Return(nil).Once()
+	mockRepo.On("PurgeDeleted", mock.Anything, mock.AnythingOfType("time.Time"), 10).Return([]uuid.UUID{userID}, nil).Once()
+
+	_, err := userService.CreateUser(context.Background(), &user.User{
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "events@example.com",
+		PasswordHash: "somepassword",
+	})
+	require.NoError(t, err)
+	require.NoError(t, userService.DeleteUser(context.Background(), userID))
+	purged, err := userService.PurgeDeletedUsers(context.Background(), 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, purged)
+
+	// user.deleted записывает в outbox сам PurgeDeleted, сервис его не публикует
+	published := broker.Published()
+	require.Len(t, published, 2)
+	require.Equal(t, events.TypeUserCreated, published[0].Type)
+	require.Equal(t, events.SourceUserService, published[0].Source)
+	require.Equal(t, userID.String(), published[0].Subject)
+
+	var created events.UserData
+	require.NoError(t, published[0].DecodeData(&created))
+	require.Equal(t, "events@example.com", created.Email)
+	require.NotContains(t, string(published[0].Data), "password")
+
+	// Мягкое удаление меняет только состояние, user.deleted публикуется при окончательном удалении
+	var softDeleted events.UserStatusChangedData
+	require.Equal(t, events.TypeUserStatusChanged, published[1].Type)
+	require.NoError(t, published[1].DecodeData(&softDeleted))
+	require.Equal(t, string(user.StatusDeleted), softDeleted.To)
+}
+
+func TestUserService_DeactivateAndReactivateUser(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	broker := events.NewMemoryBroker()
+	userService := user.NewService(mockRepo, broker, new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("UpdateStatus", mock.Anything, userID, user.StatusActive, user.StatusDeactivated).Return(nil).Once()
+	mockRepo.On("UpdateStatus", mock.Anything, userID, user.StatusDeactivated, user.StatusActive).Return(nil).Once()
+
+	require.NoError(t, userService.DeactivateUser(context.Background(), userID))
+	require.NoError(t, userService.ReactivateUser(context.Background(), userID))
+
+	published := broker.PublishedOfType(events.TypeUserStatusChanged)
+	require.Len(t, published, 2)
+	var deactivated events.UserStatusChangedData
+	require.NoError(t, published[0].DecodeData(&deactivated))
+	require.Equal(t, events.UserStatusChangedData{ID: userID, From: "ACTIVE", To: "DEACTIVATED"}, deactivated)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_DeactivateUser_StatusConflict(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	broker := events.NewMemoryBroker()
+	userService := user.NewService(mockRepo, broker, new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("UpdateStatus", mock.Anything, userID, user.StatusActive, user.StatusDeactivated).Return(user.ErrStatusConflict).Once()
+
+	err := userService.DeactivateUser(context.Background(), userID)
+	require.ErrorIs(t, err, user.ErrStatusConflict)
+	require.Empty(t, broker.Published())
+}
+
+func TestUserService_RestoreUser_WithinRetention(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	before := time.Now().Add(-testRetention)
+	mockRepo.On("Restore", mock.Anything, userID, mock.MatchedBy(func(deletedAfter time.Time) bool {
+		return !deletedAfter.Before(before) && deletedAfter.Before(time.Now().Add(-testRetention+time.Minute))
+	})).Return(nil).Once()
+
+	require.NoError(t, userService.RestoreUser(context.Background(), userID))
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_RestoreUser_RetentionExpired(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	userID := uuid.Must(uuid.NewV4())
+	mockRepo.On("Restore", mock.Anything, userID, mock.AnythingOfType("time.Time")).Return(user.ErrNotFound).Once()
+
+	err := userService.RestoreUser(context.Background(), userID)
+	require.ErrorIs(t, err, user.ErrNotFound)
 }
 
 func TestUserService_DeleteUser_NotFound(t *testing.T) {
 	mockRepo := new(MockUserRepository)
-	userService := user.NewService(mockRepo)
+	mockOrders := new(MockActiveOrdersChecker)
+	broker := events.NewMemoryBroker()
+	userService := user.NewService(mockRepo, broker, mockOrders, new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
 
 	userID := uuid.Must(uuid.NewV4())
 
+	mockOrders.On("CountActiveOrders", mock.Anything, userID).Return(0, nil).Once()
+
 	mockRepo.On("Delete", mock.Anything, userID).
 		Return(user.ErrNotFound).
 		Once()
@@ -328,5 +732,39 @@ func TestUserService_DeleteUser_NotFound(t *testing.T) {
 	err := userService.DeleteUser(context.Background(), userID)
 	require.Error(t, err)
 	require.ErrorIs(t, err, user.ErrNotFound)
+	require.Empty(t, broker.Published(), "failed deletion must not publish an event")
 	mockRepo.AssertExpectations(t)
 }
+
+func TestUserService_GetUsersByIDs_FoundAndMissing(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+	ctx := context.Background()
+
+	firstID := uuid.Must(uuid.NewV4())
+	secondID := uuid.Must(uuid.NewV4())
+	missingID := uuid.Must(uuid.NewV4())
+
+	// Репозиторий возвращает пользователей в произвольном порядке, повторяющийся ID запрашивается один раз
+	mockRepo.On("GetByIDs", ctx, []uuid.UUID{firstID, missingID, secondID}).
+		Return([]user.User{{ID: secondID}, {ID: firstID}}, nil).Once()
+
+	found, missing, err := userService.GetUsersByIDs(ctx, []uuid.UUID{firstID, missingID, secondID, firstID})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	require.Equal(t, firstID, found[0].ID)
+	require.Equal(t, secondID, found[1].ID)
+	require.Equal(t, []uuid.UUID{missingID}, missing)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_GetUsersByIDs_Empty(t *testing.T) {
+	mockRepo := new(MockUserRepository)
+	userService := user.NewService(mockRepo, events.NewMemoryBroker(), new(MockActiveOrdersChecker), new(MockVerificationSender), testPasswords, testHasher, allowAllGuard(), testRetention)
+
+	found, missing, err := userService.GetUsersByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	require.Empty(t, found)
+	require.Empty(t, missing)
+	mockRepo.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,103 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var ErrInvalidToken = errors.New("verification token is invalid or expired")
+
+// Token - выпущенный токен подтверждения. Сам токен не хранится, только его хеш.
+type Token struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Email     string
+	Hash      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type Repository interface {
+	// Issue сохраняет новый токен и отзывает все неиспользованные токены пользователя.
+	Issue(ctx context.Context, token *Token) error
+	// LatestIssuedAt возвращает время выпуска последнего токена пользователя или nil, если токенов не было.
+	LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error)
+	// Consume гасит действующий токен и отмечает email его владельца подтверждённым.
+	// ErrInvalidToken, если токен неизвестен, использован, истёк или выпущен для прежнего email.
+	Consume(ctx context.Context, hash []byte) (uuid.UUID, error)
+}
+
+type postgresRepository struct {
+	db user.DB
+}
+
+func NewRepository(db user.DB) Repository {
+	return &postgresRepository{db: db}
+}
+
+func (r *postgresRepository) Issue(ctx context.Context, token *Token) error {
+	// Отзыв и вставка в одном запросе: у пользователя не бывает двух действующих токенов
+	query := `
+		WITH revoked AS (
+			UPDATE user_service.email_verification_tokens
+			SET used_at = NOW()
+			WHERE user_id = $2 AND used_at IS NULL
+		)
+		INSERT INTO user_service.email_verification_tokens (id, user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`
+	err := r.db.QueryRow(ctx, query, token.ID, token.UserID, token.Email, token.Hash, token.ExpiresAt).Scan(&token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to issue verification token for user %s: %w", token.UserID, err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	query := `SELECT MAX(created_at) FROM user_service.email_verification_tokens WHERE user_id = $1`
+
+	var issuedAt *time.Time
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&issuedAt); err != nil {
+		return nil, fmt.Errorf("failed to get last verification token of user %s: %w", userID, err)
+	}
+	return issuedAt, nil
+}
+
+func (r *postgresRepository) Consume(ctx context.Context, hash []byte) (uuid.UUID, error) {
+	// Токен гасится, только если адрес пользователя не менялся после отправки письма
+	query := `
+		WITH consumed AS (
+			UPDATE user_service.email_verification_tokens t
+			SET used_at = NOW()
+			FROM user_service.users u
+			WHERE t.token_hash = $1
+				AND t.used_at IS NULL
+				AND t.expires_at > NOW()
+				AND u.id = t.user_id
+				AND u.email = t.email
+				AND u.deleted_at IS NULL
+			RETURNING t.user_id
+		)
+		UPDATE user_service.users u
+		SET email_verified_at = COALESCE(u.email_verified_at, NOW()), updated_at = NOW()
+		FROM consumed
+		WHERE u.id = consumed.user_id
+		RETURNING u.id
+	`
+
+	var userID uuid.UUID
+	if err := r.db.QueryRow(ctx, query, hash).Scan(&userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return uuid.Nil, ErrInvalidToken
+		}
+		return uuid.Nil, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+	return userID, nil
+}
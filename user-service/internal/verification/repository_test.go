@@ -0,0 +1,159 @@
+package verification_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/verification"
+)
+
+var testDB *pgxpool.Pool
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func TestMain(m *testing.M) {
+	connStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s search_path=user_service",
+		getEnv("DB_HOST_TEST", "localhost"),
+		getEnv("DB_PORT_TEST", "5432"),
+		getEnv("DB_USER_TEST", "postgres"),
+		getEnv("DB_PASSWORD_TEST", "123456"),
+		getEnv("DB_NAME_TEST", "ecommerce_db"),
+		getEnv("DB_SSLMODE_TEST", "disable"),
+	)
+
+	poolConfig, err := pgxpool.ParseConfig(connStr)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse test database config")
+	}
+	poolConfig.MaxConns = 5
+
+	connectCtx, connectCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer connectCancel()
+
+	testDB, err = pgxpool.NewWithConfig(connectCtx, poolConfig)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to connect to test database")
+	}
+	if err = testDB.Ping(connectCtx); err != nil {
+		testDB.Close()
+		log.Fatal().Err(err).Msg("Failed to ping test database")
+	}
+
+	exitCode := m.Run()
+
+	testDB.Close()
+	os.Exit(exitCode)
+}
+
+func createUser(t *testing.T, email string) uuid.UUID {
+	t.Helper()
+	userID := uuid.Must(uuid.NewV4())
+	_, err := user.NewRepository(testDB).Create(context.Background(), &user.User{
+		ID:           userID,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        email,
+		PasswordHash: "hashed_password",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_, err := testDB.Exec(context.Background(), "DELETE FROM user_service.users WHERE id = $1", userID)
+		require.NoError(t, err)
+	})
+	return userID
+}
+
+func issue(t *testing.T, repo verification.Repository, userID uuid.UUID, email string, hash string, expiresAt time.Time) {
+	t.Helper()
+	require.NoError(t, repo.Issue(context.Background(), &verification.Token{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    userID,
+		Email:     email,
+		Hash:      []byte(hash),
+		ExpiresAt: expiresAt,
+	}))
+}
+
+func TestRepository_ConsumeIsSingleUse(t *testing.T) {
+	repo := verification.NewRepository(testDB)
+	users := user.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "verify.once@example.com")
+
+	issue(t, repo, userID, "verify.once@example.com", "hash-once", time.Now().Add(time.Hour))
+
+	got, err := repo.Consume(ctx, []byte("hash-once"))
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	found, err := users.GetByID(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, found.EmailVerifiedAt)
+
+	_, err = repo.Consume(ctx, []byte("hash-once"))
+	require.ErrorIs(t, err, verification.ErrInvalidToken)
+}
+
+func TestRepository_IssueRevokesPreviousTokens(t *testing.T) {
+	repo := verification.NewRepository(testDB)
+	ctx := context.Background()
+	userID := createUser(t, "verify.revoke@example.com")
+
+	issue(t, repo, userID, "verify.revoke@example.com", "hash-old", time.Now().Add(time.Hour))
+	issue(t, repo, userID, "verify.revoke@example.com", "hash-new", time.Now().Add(time.Hour))
+
+	issuedAt, err := repo.LatestIssuedAt(ctx, userID)
+	require.NoError(t, err)
+	require.NotNil(t, issuedAt)
+
+	_, err = repo.Consume(ctx, []byte("hash-old"))
+	require.ErrorIs(t, err, verification.ErrInvalidToken)
+	_, err = repo.Consume(ctx, []byte("hash-new"))
+	require.NoError(t, err)
+}
+
+func TestRepository_ConsumeRejectsExpiredAndStaleEmail(t *testing.T) {
+	repo := verification.NewRepository(testDB)
+	users := user.NewRepository(testDB)
+	ctx := context.Background()
+
+	expiredUser := createUser(t, "verify.expired@example.com")
+	issue(t, repo, expiredUser, "verify.expired@example.com", "hash-expired", time.Now().Add(-time.Minute))
+	_, err := repo.Consume(ctx, []byte("hash-expired"))
+	require.ErrorIs(t, err, verification.ErrInvalidToken)
+
+	// Ссылка отправлена на прежний адрес: после смены email она не должна подтверждать новый
+	movedUser := createUser(t, "verify.before@example.com")
+	issue(t, repo, movedUser, "verify.before@example.com", "hash-moved", time.Now().Add(time.Hour))
+	require.NoError(t, users.Update(ctx, &user.User{
+		ID:           movedUser,
+		FirstName:    "Test",
+		LastName:     "User",
+		Email:        "verify.after@example.com",
+		PasswordHash: "hashed_password",
+	}))
+	_, err = repo.Consume(ctx, []byte("hash-moved"))
+	require.ErrorIs(t, err, verification.ErrInvalidToken)
+
+	found, err := users.GetByID(ctx, movedUser)
+	require.NoError(t, err)
+	assert.Nil(t, found.EmailVerifiedAt)
+
+	none, err := repo.LatestIssuedAt(ctx, uuid.Must(uuid.NewV4()))
+	require.NoError(t, err)
+	assert.Nil(t, none)
+}
@@ -0,0 +1,137 @@
+// Package verification подтверждает email пользователей одноразовыми ссылками из письма.
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mailer"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+var (
+	ErrAlreadyVerified = errors.New("email is already verified")
+	ErrResendTooSoon   = errors.New("verification email was sent recently, try again later")
+)
+
+// Config задаёт срок жизни ссылок и частоту повторной отправки.
+type Config struct {
+	TTL            time.Duration // Сколько действует ссылка из письма
+	ResendCooldown time.Duration // Минимальный интервал между письмами одному пользователю
+	LinkURL        string        // Страница подтверждения; токен добавляется параметром token
+}
+
+// UserSource читает пользователя, которому отправляется письмо.
+type UserSource interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*user.User, error)
+}
+
+type Service interface {
+	// SendVerification выпускает новую ссылку и отправляет её на текущий email пользователя. Прежние ссылки перестают действовать.
+	SendVerification(ctx context.Context, u *user.User) error
+	// Resend повторяет письмо по запросу пользователя не чаще, чем раз в ResendCooldown.
+	Resend(ctx context.Context, userID uuid.UUID) error
+	// Verify гасит токен из ссылки и возвращает ID пользователя, чей email подтверждён.
+	Verify(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+type service struct {
+	repo   Repository
+	users  UserSource
+	mailer mailer.Mailer
+	cfg    Config
+}
+
+func NewService(repo Repository, users UserSource, m mailer.Mailer, cfg Config) Service {
+	return &service{repo: repo, users: users, mailer: m, cfg: cfg}
+}
+
+func (s *service) SendVerification(ctx context.Context, u *user.User) error {
+	if u.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	raw, hash, err := tokens.Generate()
+	if err != nil {
+		return err
+	}
+	tokenID, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token id: %w", err)
+	}
+
+	token := &Token{
+		ID:        tokenID,
+		UserID:    u.ID,
+		Email:     u.Email,
+		Hash:      hash,
+		ExpiresAt: time.Now().Add(s.cfg.TTL),
+	}
+	if err := s.repo.Issue(ctx, token); err != nil {
+		return err
+	}
+
+	// Токен уже сохранён: если письмо не ушло, пользователь запросит новое, и этот токен будет отозван
+	if err := s.mailer.Send(ctx, s.message(u, raw, token.ExpiresAt)); err != nil {
+		return fmt.Errorf("failed to send verification email to user '%s': %w", u.ID, err)
+	}
+
+	log.Info().Stringer("user_id", u.ID).Time("expires_at", token.ExpiresAt).Msg("Verification email sent")
+	return nil
+}
+
+func (s *service) Resend(ctx context.Context, userID uuid.UUID) error {
+	u, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	issuedAt, err := s.repo.LatestIssuedAt(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if issuedAt != nil && time.Since(*issuedAt) < s.cfg.ResendCooldown {
+		return ErrResendTooSoon
+	}
+
+	return s.SendVerification(ctx, u)
+}
+
+func (s *service) Verify(ctx context.Context, token string) (uuid.UUID, error) {
+	if token == "" {
+		return uuid.Nil, ErrInvalidToken
+	}
+
+	userID, err := s.repo.Consume(ctx, tokens.Hash(token))
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	log.Info().Stringer("user_id", userID).Msg("Email verified")
+	return userID, nil
+}
+
+func (s *service) message(u *user.User, token string, expiresAt time.Time) mailer.Message {
+	separator := "?"
+	if strings.Contains(s.cfg.LinkURL, "?") {
+		separator = "&"
+	}
+	link := s.cfg.LinkURL + separator + "token=" + token
+
+	return mailer.Message{
+		To:      u.Email,
+		Subject: "Confirm your email address",
+		Body: fmt.Sprintf("Hello, %s!\n\nConfirm your email address by opening this link:\n%s\n\n"+
+			"The link is valid until %s. If you did not create an account, ignore this email.\n",
+			u.FirstName, link, expiresAt.UTC().Format("2006-01-02 15:04 MST")),
+	}
+}
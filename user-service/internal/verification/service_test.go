@@ -0,0 +1,185 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/mailer"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/tokens"
+	"github.com/vasiliy-maslov/ecommerce-microservices/user-service/internal/user"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Issue(ctx context.Context, token *Token) error {
+	args := m.Called(ctx, token)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LatestIssuedAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *MockRepository) Consume(ctx context.Context, hash []byte) (uuid.UUID, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+type MockUserSource struct {
+	mock.Mock
+}
+
+func (m *MockUserSource) GetByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+// fakeMailer запоминает отправленные письма.
+type fakeMailer struct {
+	sent []mailer.Message
+	err  error
+}
+
+func (m *fakeMailer) Send(_ context.Context, msg mailer.Message) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+var testConfig = Config{TTL: time.Hour, ResendCooldown: time.Minute, LinkURL: "https://shop.example.com/verify?lang=en"}
+
+// tokenFromLink достаёт токен из ссылки в письме.
+func tokenFromLink(t *testing.T, body string) string {
+	t.Helper()
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link, err := url.Parse(line)
+			require.NoError(t, err)
+			return link.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in mail body %q", body)
+	return ""
+}
+
+func TestService_SendVerification_StoresOnlyTokenHash(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mail := &fakeMailer{}
+	svc := NewService(mockRepo, new(MockUserSource), mail, testConfig)
+	ctx := context.Background()
+	u := &user.User{ID: uuid.Must(uuid.NewV4()), FirstName: "Ann", Email: "ann@example.com"}
+
+	var issued *Token
+	mockRepo.On("Issue", ctx, mock.AnythingOfType("*verification.Token")).Run(func(args mock.Arguments) {
+		issued = args.Get(1).(*Token)
+	}).Return(nil).Once()
+
+	require.NoError(t, svc.SendVerification(ctx, u))
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, "ann@example.com", mail.sent[0].To)
+
+	token := tokenFromLink(t, mail.sent[0].Body)
+	require.NotEmpty(t, token)
+	assert.Equal(t, tokens.Hash(token), issued.Hash)
+	assert.NotContains(t, string(issued.Hash), token)
+	assert.Equal(t, u.ID, issued.UserID)
+	assert.Equal(t, u.Email, issued.Email)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiresAt, time.Minute)
+	assert.Contains(t, mail.sent[0].Body, "lang=en&token=")
+}
+
+func TestService_SendVerification_AlreadyVerified(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, new(MockUserSource), &fakeMailer{}, testConfig)
+	verifiedAt := time.Now()
+
+	err := svc.SendVerification(context.Background(), &user.User{ID: uuid.Must(uuid.NewV4()), EmailVerifiedAt: &verifiedAt})
+	require.ErrorIs(t, err, ErrAlreadyVerified)
+	mockRepo.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+}
+
+func TestService_SendVerification_MailerError(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mailErr := errors.New("smtp is down")
+	svc := NewService(mockRepo, new(MockUserSource), &fakeMailer{err: mailErr}, testConfig)
+	mockRepo.On("Issue", mock.Anything, mock.Anything).Return(nil).Once()
+
+	err := svc.SendVerification(context.Background(), &user.User{ID: uuid.Must(uuid.NewV4()), Email: "ann@example.com"})
+	require.ErrorIs(t, err, mailErr)
+}
+
+func TestService_Resend_Cooldown(t *testing.T) {
+	mockRepo := new(MockRepository)
+	mockUsers := new(MockUserSource)
+	mail := &fakeMailer{}
+	svc := NewService(mockRepo, mockUsers, mail, testConfig)
+	ctx := context.Background()
+
+	recentID := uuid.Must(uuid.NewV4())
+	recent := time.Now().Add(-10 * time.Second)
+	mockUsers.On("GetByID", ctx, recentID).Return(&user.User{ID: recentID, Email: "recent@example.com"}, nil).Once()
+	mockRepo.On("LatestIssuedAt", ctx, recentID).Return(&recent, nil).Once()
+
+	require.ErrorIs(t, svc.Resend(ctx, recentID), ErrResendTooSoon)
+	assert.Empty(t, mail.sent)
+
+	oldID := uuid.Must(uuid.NewV4())
+	old := time.Now().Add(-2 * time.Minute)
+	mockUsers.On("GetByID", ctx, oldID).Return(&user.User{ID: oldID, Email: "old@example.com"}, nil).Once()
+	mockRepo.On("LatestIssuedAt", ctx, oldID).Return(&old, nil).Once()
+	mockRepo.On("Issue", ctx, mock.Anything).Return(nil).Once()
+
+	require.NoError(t, svc.Resend(ctx, oldID))
+	require.Len(t, mail.sent, 1)
+	assert.Equal(t, "old@example.com", mail.sent[0].To)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestService_Resend_UnknownUser(t *testing.T) {
+	mockUsers := new(MockUserSource)
+	svc := NewService(new(MockRepository), mockUsers, &fakeMailer{}, testConfig)
+	userID := uuid.Must(uuid.NewV4())
+	mockUsers.On("GetByID", mock.Anything, userID).Return(nil, user.ErrNotFound).Once()
+
+	require.ErrorIs(t, svc.Resend(context.Background(), userID), user.ErrNotFound)
+}
+
+func TestService_Verify(t *testing.T) {
+	mockRepo := new(MockRepository)
+	svc := NewService(mockRepo, new(MockUserSource), &fakeMailer{}, testConfig)
+	ctx := context.Background()
+	userID := uuid.Must(uuid.NewV4())
+
+	mockRepo.On("Consume", ctx, tokens.Hash("good")).Return(userID, nil).Once()
+	mockRepo.On("Consume", ctx, tokens.Hash("used")).Return(uuid.Nil, ErrInvalidToken).Once()
+
+	got, err := svc.Verify(ctx, "good")
+	require.NoError(t, err)
+	assert.Equal(t, userID, got)
+
+	_, err = svc.Verify(ctx, "used")
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	_, err = svc.Verify(ctx, "")
+	require.ErrorIs(t, err, ErrInvalidToken)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,11 @@
+-- Перед откатом удалённые пользователи удаляются окончательно, иначе уникальность email не восстановить
+DELETE FROM user_service.users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS user_service.users_deleted_at_idx;
+DROP INDEX IF EXISTS user_service.users_email_idx;
+CREATE UNIQUE INDEX users_email_idx ON user_service.users (email);
+ALTER TABLE user_service.users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+ALTER TABLE user_service.users
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,11 @@
+ALTER TABLE user_service.users
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'DEACTIVATED', 'DELETED')),
+    ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE; -- Время мягкого удаления, NULL у неудалённых пользователей
+
+-- Email освобождается после удаления, поэтому уникальность проверяется только среди неудалённых пользователей
+ALTER TABLE user_service.users DROP CONSTRAINT users_email_key;
+DROP INDEX user_service.users_email_idx;
+CREATE UNIQUE INDEX users_email_idx ON user_service.users (email) WHERE deleted_at IS NULL;
+
+-- Задача очистки выбирает пользователей, у которых истёк срок хранения
+CREATE INDEX users_deleted_at_idx ON user_service.users (deleted_at) WHERE deleted_at IS NOT NULL;
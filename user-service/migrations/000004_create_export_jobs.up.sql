@@ -0,0 +1,21 @@
+-- Выгрузки персональных данных по запросу субъекта данных
+CREATE TABLE user_service.export_jobs (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL, -- Без внешнего ключа: выгрузка живёт до истечения срока, даже если пользователя уже удалили
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PENDING', 'RUNNING', 'SUCCEEDED', 'FAILED', 'EXPIRED')),
+    attempts INT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    file_size BIGINT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP WITH TIME ZONE, -- Пока не истекло, задачу выполняет другая реплика
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE -- После этого файл выгрузки удаляется
+);
+
+CREATE INDEX export_jobs_user_id_idx ON user_service.export_jobs (user_id, created_at DESC);
+
+-- Воркер выбирает задачи в очереди и задачи, реплика которых перестала продлевать блокировку
+CREATE INDEX export_jobs_queue_idx ON user_service.export_jobs (created_at) WHERE status IN ('PENDING', 'RUNNING');
+
+CREATE INDEX export_jobs_expires_at_idx ON user_service.export_jobs (expires_at) WHERE status = 'SUCCEEDED';
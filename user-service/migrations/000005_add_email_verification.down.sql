@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_service.email_verification_tokens;
+
+ALTER TABLE user_service.users DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,18 @@
+ALTER TABLE user_service.users
+    ADD COLUMN email_verified_at TIMESTAMP WITH TIME ZONE; -- NULL, пока пользователь не подтвердил текущий email
+
+-- Пользователи, зарегистрированные до появления подтверждения, считаются подтверждёнными, иначе они потеряют возможность заказывать
+UPDATE user_service.users SET email_verified_at = created_at;
+
+-- Хранится только SHA-256 токена: утечка таблицы не даёт подтвердить чужой адрес
+CREATE TABLE user_service.email_verification_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    email VARCHAR NOT NULL, -- Адрес, на который отправлено письмо. После смены email токен недействителен
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE -- Токен одноразовый; отзыв при повторной отправке тоже заполняет это поле
+);
+
+CREATE INDEX email_verification_tokens_user_id_idx ON user_service.email_verification_tokens (user_id, created_at DESC);
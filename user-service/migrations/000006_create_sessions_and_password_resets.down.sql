@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_service.password_reset_tokens;
+DROP TABLE IF EXISTS user_service.sessions;
@@ -0,0 +1,24 @@
+-- Сессии пользователей. Клиент получает токен при входе, в БД хранится только его SHA-256
+CREATE TABLE user_service.sessions (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    revoked_at TIMESTAMP WITH TIME ZONE -- Выход, сброс или смена пароля
+);
+
+CREATE INDEX sessions_user_id_idx ON user_service.sessions (user_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE user_service.password_reset_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE -- Токен одноразовый; выпуск нового тоже гасит прежние
+);
+
+CREATE INDEX password_reset_tokens_user_id_idx ON user_service.password_reset_tokens (user_id, created_at DESC);
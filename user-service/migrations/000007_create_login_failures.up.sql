@@ -0,0 +1,9 @@
+-- Неудачные попытки ввода пароля по учётной записи и по IP. Хранятся в БД, чтобы счётчики были общими для всех реплик
+CREATE TABLE user_service.login_failures (
+    kind VARCHAR(16) NOT NULL, -- account: email в нижнем регистре, ip: адрес клиента
+    subject TEXT NOT NULL,
+    failures INTEGER NOT NULL,
+    last_failed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    blocked_until TIMESTAMP WITH TIME ZONE, -- До этого момента попытки отклоняются без проверки пароля
+    PRIMARY KEY (kind, subject)
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_service.mfa_challenges;
+DROP TABLE IF EXISTS user_service.mfa_recovery_codes;
+DROP TABLE IF EXISTS user_service.user_mfa;
@@ -0,0 +1,27 @@
+-- Второй фактор TOTP. Секрет зашифрован ключом из конфигурации (MFA_ENCRYPTION_KEY)
+CREATE TABLE user_service.user_mfa (
+    user_id UUID PRIMARY KEY REFERENCES user_service.users (id) ON DELETE CASCADE,
+    secret_encrypted BYTEA NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    confirmed_at TIMESTAMP WITH TIME ZONE, -- NULL, пока пользователь не ввёл первый код; до этого вход без второго фактора
+    last_used_step BIGINT NOT NULL DEFAULT 0 -- Шаг последнего принятого кода: один код нельзя использовать дважды
+);
+
+-- Коды восстановления на случай потери приложения. Хранится только SHA-256, каждый код одноразовый
+CREATE TABLE user_service.mfa_recovery_codes (
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    code_hash BYTEA NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- Незавершённые входы: пароль верен, ждём код второго фактора
+CREATE TABLE user_service.mfa_challenges (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    token_hash BYTEA NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0, -- Неверные коды; после предела вход начинается заново с пароля
+    used_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,11 @@
+-- Ключи API для сервисов и партнёров. Хранится только SHA-256 ключа, сам ключ показывается один раз при выпуске
+CREATE TABLE user_service.api_keys (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL, -- Начало ключа, по которому его узнают в списке
+    key_hash BYTEA NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE, -- NULL - бессрочный
+    last_used_at TIMESTAMP WITH TIME ZONE
+);
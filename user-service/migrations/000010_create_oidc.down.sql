@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_service.oidc_login_states;
+DROP TABLE IF EXISTS user_service.user_identities;
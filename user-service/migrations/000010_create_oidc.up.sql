@@ -0,0 +1,21 @@
+-- Учётные записи внешнего OpenID Connect провайдера, привязанные к пользователям.
+-- sub уникален только в пределах issuer, поэтому ключ составной
+CREATE TABLE user_service.user_identities (
+    issuer TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL, -- Email из ID токена на момент привязки
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject),
+    UNIQUE (issuer, user_id) -- У пользователя не больше одной учётной записи каждого провайдера
+);
+
+-- Начатые входы через провайдера. Хранится хеш state; nonce и секрет PKCE нужны после возврата с провайдера
+CREATE TABLE user_service.oidc_login_states (
+    state_hash BYTEA PRIMARY KEY,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
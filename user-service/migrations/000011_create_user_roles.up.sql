@@ -0,0 +1,7 @@
+-- Роли сотрудников. Пользователь без ролей - покупатель
+CREATE TABLE user_service.user_roles (
+    user_id UUID NOT NULL REFERENCES user_service.users (id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('support', 'admin')),
+    granted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
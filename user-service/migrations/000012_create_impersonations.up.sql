@@ -0,0 +1,12 @@
+-- Журнал входов поддержки от имени пользователей. Без внешних ключей: записи переживают удаление сотрудника и пользователя
+CREATE TABLE user_service.impersonations (
+    id UUID PRIMARY KEY, -- Он же jti выпущенного токена
+    actor_id UUID NOT NULL, -- Сотрудник поддержки
+    subject_id UUID NOT NULL, -- Пользователь, от имени которого выпущен токен
+    reason TEXT NOT NULL,
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX impersonations_subject_id_idx ON user_service.impersonations (subject_id, created_at DESC);
@@ -0,0 +1,13 @@
+-- Исходящие доменные события. Строку пишет тот же запрос, что и изменение пользователя,
+-- а в брокер её отправляет events.Relay, поэтому событие не теряется, даже если брокер недоступен.
+CREATE TABLE user_service.event_outbox (
+    id BIGSERIAL PRIMARY KEY, -- Порядок отправки
+    event_id UUID NOT NULL DEFAULT gen_random_uuid(), -- id события; повторная отправка идёт с тем же id
+    event_type VARCHAR(64) NOT NULL,
+    subject TEXT NOT NULL,
+    data JSONB NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX event_outbox_pending_idx ON user_service.event_outbox (id) WHERE published_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE user_service.api_keys DROP CONSTRAINT IF EXISTS api_keys_scopes_check;
@@ -0,0 +1,8 @@
+-- order-service не принимает ключи API, права orders:* ничего не давали
+UPDATE user_service.api_keys
+SET scopes = array_remove(array_remove(scopes, 'orders:read'), 'orders:write')
+WHERE scopes && ARRAY['orders:read', 'orders:write'];
+
+ALTER TABLE user_service.api_keys ADD CONSTRAINT api_keys_scopes_check CHECK (
+    scopes <@ ARRAY['users:read', 'users:write']::TEXT[]
+);
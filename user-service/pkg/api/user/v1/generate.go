@@ -0,0 +1,4 @@
+// Package userv1 содержит сгенерированный код gRPC API user-service.
+package userv1
+
+//go:generate protoc -I ../../../../api/proto --go_out=../../../.. --go_opt=module=github.com/vasiliy-maslov/ecommerce-microservices/user-service --go-grpc_out=../../../.. --go-grpc_opt=module=github.com/vasiliy-maslov/ecommerce-microservices/user-service user/v1/user.proto